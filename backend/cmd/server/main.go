@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,9 +18,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/strmsync/strmsync/internal/domain/model"
+	domainrepo "github.com/strmsync/strmsync/internal/domain/repository"
 	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
 	"github.com/strmsync/strmsync/internal/infra/db/repository"
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
 	"github.com/strmsync/strmsync/internal/pkg/logger"
 	"github.com/strmsync/strmsync/internal/pkg/requestid"
 	"github.com/strmsync/strmsync/internal/queue"
@@ -58,6 +60,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 数据库迁移命令：strmsync migrate status|up|down
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(cfg, os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// 初始化日志系统
 	if err := logger.InitLogger(cfg.Log.Level, cfg.Log.Path, logger.RotateConfig{
 		MaxSizeMB:  cfg.Log.Rotate.MaxSizeMB,
@@ -86,7 +93,8 @@ func main() {
 		zap.String("frontend_version", frontendVersion),
 		zap.String("host", cfg.Server.Host),
 		zap.Int("port", cfg.Server.Port),
-		zap.String("db_path", cfg.Database.Path),
+		zap.String("db_driver", cfg.Database.Driver),
+		zap.String("db_path", cfg.Database.Describe()),
 		zap.String("log_level", cfg.Log.Level),
 		zap.String("log_path", cfg.Log.Path),
 		zap.Bool("log_to_db", cfg.Log.ToDB),
@@ -94,13 +102,15 @@ func main() {
 		zap.Int("log_sql_slow_ms", cfg.Log.SQLSlowMs))
 
 	// 初始化数据库
-	if err := dbpkg.InitWithConfig(cfg.Database.Path, &cfg.Log); err != nil {
+	if err := dbpkg.InitWithConfig(cfg.Database, &cfg.Log); err != nil {
 		logger.LogError("数据库初始化失败", zap.Error(err))
 		os.Exit(1)
 	}
 	defer dbpkg.Close()
 
-	logger.LogInfo("数据库初始化成功", zap.String("path", cfg.Database.Path))
+	logger.LogInfo("数据库初始化成功",
+		zap.String("driver", cfg.Database.Driver),
+		zap.String("path", cfg.Database.Describe()))
 
 	// 获取数据库连接
 	db, err := dbpkg.GetDB()
@@ -109,6 +119,45 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 日志写入数据库，供日志查询与实时追踪使用
+	logger.SetLogToDBEnabled(cfg.Log.ToDB, 0)
+	if cfg.Log.ToDB {
+		if err := logger.AttachDBWriter(db, cfg.Log.Level); err != nil {
+			logger.LogWarn("启用数据库日志失败", zap.Error(err))
+		}
+	}
+	logRepo, err := repository.NewGormLogRepository(db)
+	if err != nil {
+		logger.LogError("LogRepository 初始化失败", zap.Error(err))
+		os.Exit(1)
+	}
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go runLogRetention(retentionCtx, logRepo, cfg.Log.Retention, logger.With(zap.String("component", "log_retention")))
+	runStatsRepo, err := repository.NewGormRunStatsRepository(db)
+	if err != nil {
+		logger.LogError("RunStatsRepository 初始化失败", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// 数据库备份（仅 SQLite）
+	var backupService httphandlers.BackupService
+	backupManager, err := dbpkg.NewBackupManager(db, cfg.Database, cfg.Backup, cfg.Security.EncryptionKey)
+	switch {
+	case err == nil:
+		backupService = backupManager
+		go func() {
+			if err := backupManager.Run(retentionCtx); err != nil {
+				logger.LogError("数据库定时备份启动失败", zap.Error(err))
+			}
+		}()
+	case errors.Is(err, dbpkg.ErrBackupUnsupported):
+		logger.LogInfo("当前数据库驱动不支持内置备份，请使用数据库自带的备份工具", zap.String("driver", cfg.Database.Driver))
+	default:
+		logger.LogError("备份管理器初始化失败", zap.Error(err))
+		os.Exit(1)
+	}
+
 	// 设置Gin模式
 	if cfg.Log.Level == "debug" {
 		gin.SetMode(gin.DebugMode)
@@ -122,6 +171,9 @@ func main() {
 		logger.LogError("SyncQueue 初始化失败", zap.Error(err))
 		os.Exit(1)
 	}
+	if backupManager != nil {
+		backupManager.SetClaimPauser(queue)
+	}
 
 	// 初始化共享的 Repository（scheduler 和 worker 共享）
 	jobRepo, err := repository.NewGormJobRepository(db)
@@ -144,6 +196,16 @@ func main() {
 		logger.LogError("TaskRunEventRepository 初始化失败", zap.Error(err))
 		os.Exit(1)
 	}
+	metaHashRepo, err := worker.NewGormMetaHashRepository(db)
+	if err != nil {
+		logger.LogError("MetaHashRepository 初始化失败", zap.Error(err))
+		os.Exit(1)
+	}
+	mappingRepo, err := worker.NewGormStrmMappingRepository(db)
+	if err != nil {
+		logger.LogError("StrmMappingRepository 初始化失败", zap.Error(err))
+		os.Exit(1)
+	}
 
 	// 初始化 Scheduler
 	cronScheduler, err := scheduler.NewScheduler(scheduler.SchedulerConfig{
@@ -156,10 +218,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 清理程序异常退出时遗留的 running 状态任务
-	if err := cleanupInterruptedTasks(context.Background(), db, logger.With(zap.String("component", "cleanup"))); err != nil {
-		logger.LogWarn("清理中断任务失败", zap.Error(err))
-	}
+	// 每次执行的独立日志与调试信息：<日志目录>/runs/<执行记录ID>/
+	logDir, _ := logger.ResolveLogFilePath(cfg.Log.Path)
+	runLogDir := filepath.Join(logDir, "runs")
+	go runTaskRunRollup(retentionCtx, runStatsRepo, cfg.History, runLogDir, logger.With(zap.String("component", "run_rollup")))
 
 	// 初始化 Worker
 	workerPool, err := worker.NewWorker(worker.WorkerConfig{
@@ -168,13 +230,24 @@ func main() {
 		DataServers:   dataServerRepo, // 使用共享的 Repository
 		TaskRuns:      taskRunRepo,
 		TaskRunEvents: taskRunEventRepo,
+		MetaHashes:    metaHashRepo,
+		Mappings:      mappingRepo,
 		Logger:        logger.With(zap.String("component", "worker")),
+		RunLogDir:     runLogDir,
+		WorkerID:      cfg.Worker.ID,
+		Group:         cfg.Worker.Group,
+		Concurrency:   cfg.Worker.Concurrency,
+		LeaseDuration: time.Duration(cfg.Worker.LeaseSeconds) * time.Second,
+		Fsync:         cfg.Worker.Fsync,
 	})
 	if err != nil {
 		logger.LogError("Worker 初始化失败", zap.Error(err))
 		os.Exit(1)
 	}
 
+	// 清理上次异常退出遗留在目标目录中的临时文件
+	go cleanupStaleTempFiles(retentionCtx, jobRepo, logger.With(zap.String("component", "temp_cleanup")))
+
 	// 启动 Scheduler/Worker
 	startCtx := context.Background()
 	if err := cronScheduler.Start(startCtx); err != nil {
@@ -187,12 +260,19 @@ func main() {
 	}
 
 	// 创建HTTP服务器
-	router := setupRouter(db, cfg.Log.Path, cronScheduler, queue)
+	router := setupRouter(db, logRepo, runStatsRepo, runLogDir, cronScheduler, queue, workerPool, backupService, httphandlers.HealthOptions{
+		MaxPendingTasks:     int64(cfg.Health.MaxPendingTasks),
+		MaxPendingAge:       time.Duration(cfg.Health.MaxPendingAgeSeconds) * time.Second,
+		ServerCheckTTL:      time.Duration(cfg.Health.ServerCheckTTLSeconds) * time.Second,
+		ServerCheckTimeout:  time.Duration(cfg.Health.ServerCheckTimeoutSeconds) * time.Second,
+		RequireDataServers:  cfg.Health.RequireDataServers,
+		RequireMediaServers: cfg.Health.RequireMediaServers,
+	})
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
 	srv := &http.Server{
 		Addr:           addr,
-		Handler:        router,
+		Handler:        streamingHandler(router),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1MB
@@ -220,7 +300,8 @@ func main() {
 
 	logger.LogInfo("服务器关闭中...")
 
-	// 关闭日志数据库写入worker
+	// 关闭日志数据库写入worker与保留策略
+	stopRetention()
 	logger.ShutdownLogDBWriter()
 
 	// 优雅关闭：各组件独立超时，顺序为 Scheduler -> HTTP -> Worker
@@ -366,7 +447,7 @@ func logSystemInfo(cfg *dbpkg.Config) {
 		os.Getpid(),
 		workDir,
 		logFile,
-		cfg.Database.Path)
+		cfg.Database.Describe())
 
 	sysLogger.Info(message,
 		zap.String("version", appVersion),
@@ -378,11 +459,12 @@ func logSystemInfo(cfg *dbpkg.Config) {
 		zap.String("work_dir", workDir),
 		zap.String("log_dir", logDir),
 		zap.String("log_file", logFile),
-		zap.String("db_path", cfg.Database.Path))
+		zap.String("db_driver", cfg.Database.Driver),
+		zap.String("db_path", cfg.Database.Describe()))
 }
 
 // setupRouter 配置路由 (最小可用版本)
-func setupRouter(db *gorm.DB, logDir string, scheduler httphandlers.JobScheduler, queue httphandlers.TaskQueue) *gin.Engine {
+func setupRouter(db *gorm.DB, logRepo *repository.GormLogRepository, runStatsRepo *repository.GormRunStatsRepository, runLogDir string, scheduler httphandlers.JobScheduler, queue httphandlers.TaskQueue, workers httphandlers.WorkerStatusProvider, backups httphandlers.BackupService, healthOpts httphandlers.HealthOptions) *gin.Engine {
 	router := gin.New()
 
 	// 中间件
@@ -394,25 +476,38 @@ func setupRouter(db *gorm.DB, logDir string, scheduler httphandlers.JobScheduler
 	logger := logger.With(zap.String("module", "api"))
 
 	// 创建处理器
-	logHandler := httphandlers.NewLogHandler(logDir, logger)
+	logHandler := httphandlers.NewLogHandler(logRepo, logger)
 	settingHandler := httphandlers.NewSettingHandler(db, logger)
 	fileHandler := httphandlers.NewFileHandler(db, logger)
 	dataServerHandler := httphandlers.NewDataServerHandler(db, logger)
 	mediaServerHandler := httphandlers.NewMediaServerHandler(db, logger)
 	serverTypeHandler := httphandlers.NewServerTypeHandler()
-	jobHandler := httphandlers.NewJobHandler(db, logger, scheduler, queue)
-	taskRunHandler := httphandlers.NewTaskRunHandler(db, logger, queue)
+	layouts, _ := workers.(httphandlers.LayoutPreviewer)
+	jobHandler := httphandlers.NewJobHandler(db, logger, scheduler, queue, layouts)
+	taskRunHandler := httphandlers.NewTaskRunHandler(db, logger, queue, runLogDir)
+	runStatsHandler := httphandlers.NewRunStatsHandler(db, runStatsRepo, logger)
+	workerNodeHandler := httphandlers.NewWorkerNodeHandler(db, logger)
+
+	// 调度器状态与队列积压为可选能力
+	schedulerStatus, _ := scheduler.(httphandlers.SchedulerStatusProvider)
+	queueBacklog, _ := queue.(httphandlers.QueueBacklogProvider)
+	healthHandler := httphandlers.NewHealthHandler(db, logger, workers, schedulerStatus, queueBacklog, healthOpts)
+	schedulerReloader, _ := scheduler.(httphandlers.SchedulerReloader)
+	backupHandler := httphandlers.NewBackupHandler(backups, schedulerReloader, logger)
 
 	// API路由组
 	api := router.Group("/api")
 	{
 		// 健康检查
 		api.GET("/health", healthCheckHandler)
+		api.GET("/health/live", healthHandler.Live)
+		api.GET("/health/ready", healthHandler.Ready)
 
 		// 日志查询
 		logs := api.Group("/logs")
 		{
 			logs.GET("", logHandler.ListLogs)
+			logs.GET("/stream", logHandler.StreamLogs)
 			logs.POST("/cleanup", logHandler.CleanupLogs)
 		}
 
@@ -471,6 +566,8 @@ func setupRouter(db *gorm.DB, logDir string, scheduler httphandlers.JobScheduler
 			jobs.DELETE("/:id", jobHandler.DeleteJob)
 			jobs.POST("/:id/run", jobHandler.RunJob)
 			jobs.POST("/:id/stop", jobHandler.StopJob)
+			jobs.POST("/:id/rollback", jobHandler.RollbackJob)
+			jobs.GET("/:id/layout-preview", jobHandler.PreviewJobLayout)
 			jobs.PUT("/:id/enable", jobHandler.EnableJob)
 			jobs.PUT("/:id/disable", jobHandler.DisableJob)
 		}
@@ -481,10 +578,31 @@ func setupRouter(db *gorm.DB, logDir string, scheduler httphandlers.JobScheduler
 			runs.GET("", taskRunHandler.ListTaskRuns)
 			runs.GET("/:id", taskRunHandler.GetTaskRun)
 			runs.GET("/:id/events", taskRunHandler.ListRunEvents)
+			runs.GET("/:id/items", taskRunHandler.ListRunMediaItems)
+			runs.GET("/:id/log", taskRunHandler.GetRunLog)
+			runs.GET("/:id/bundle", taskRunHandler.DownloadRunBundle)
 			runs.POST("/:id/cancel", taskRunHandler.CancelRun)
+			runs.POST("/:id/resume", taskRunHandler.ResumeRun)
 			runs.POST("/batch-delete", taskRunHandler.BatchDeleteTaskRuns)
 			runs.DELETE("/:id", taskRunHandler.DeleteTaskRun)
 			runs.GET("/stats", taskRunHandler.GetRunStats)
+			runs.GET("/trends", runStatsHandler.GetRunTrends)
+		}
+
+		// Worker 注册表
+		workerNodes := api.Group("/workers")
+		{
+			workerNodes.GET("", workerNodeHandler.ListWorkers)
+			workerNodes.DELETE("/:id", workerNodeHandler.DeleteWorker)
+		}
+
+		// 数据库备份
+		backupRoutes := api.Group("/backups")
+		{
+			backupRoutes.GET("", backupHandler.ListBackups)
+			backupRoutes.POST("", backupHandler.CreateBackup)
+			backupRoutes.GET("/:name/download", backupHandler.DownloadBackup)
+			backupRoutes.POST("/:name/restore", backupHandler.RestoreBackup)
 		}
 	}
 
@@ -643,7 +761,6 @@ func healthCheckHandler(c *gin.Context) {
 		"database":         dbStatus,
 		"version":          appVersion,
 		"frontend_version": frontendVersion,
-		"note":             "Minimal version during refactoring",
 	})
 }
 
@@ -665,6 +782,8 @@ func describeAPIAction(method string, path string) string {
 		return "系统日志：查询"
 	case method == http.MethodPost && path == "/api/logs/cleanup":
 		return "系统日志：清理"
+	case method == http.MethodGet && path == "/api/logs/stream":
+		return "系统日志：实时追踪"
 	case method == http.MethodGet && path == "/api/settings":
 		return "系统设置：查询"
 	case method == http.MethodPut && path == "/api/settings":
@@ -727,14 +846,30 @@ func describeAPIAction(method string, path string) string {
 		return "执行历史：列表"
 	case method == http.MethodGet && path == "/api/runs/stats":
 		return "执行历史：统计"
+	case method == http.MethodGet && path == "/api/runs/trends":
+		return "执行历史：趋势"
 	case method == http.MethodPost && strings.HasSuffix(path, "/cancel") && strings.HasPrefix(path, "/api/runs/"):
 		return "执行历史：取消"
+	case method == http.MethodPost && strings.HasSuffix(path, "/resume") && strings.HasPrefix(path, "/api/runs/"):
+		return "执行历史：断点续传"
 	case method == http.MethodPost && path == "/api/runs/batch-delete":
 		return "执行历史：批量删除"
 	case method == http.MethodDelete && strings.HasPrefix(path, "/api/runs/"):
 		return "执行历史：删除"
 	case method == http.MethodGet && strings.HasPrefix(path, "/api/runs/"):
 		return "执行历史：详情"
+	case method == http.MethodGet && path == "/api/workers":
+		return "Worker：列表"
+	case method == http.MethodDelete && strings.HasPrefix(path, "/api/workers/"):
+		return "Worker：移除"
+	case method == http.MethodGet && path == "/api/backups":
+		return "数据库备份：列表"
+	case method == http.MethodPost && path == "/api/backups":
+		return "数据库备份：创建"
+	case method == http.MethodGet && strings.HasPrefix(path, "/api/backups/") && strings.HasSuffix(path, "/download"):
+		return "数据库备份：下载"
+	case method == http.MethodPost && strings.HasPrefix(path, "/api/backups/") && strings.HasSuffix(path, "/restore"):
+		return "数据库备份：恢复"
 	default:
 		return ""
 	}
@@ -803,68 +938,192 @@ func serveIndexHTML(c *gin.Context) {
 	c.File(filepath.Join(webStaticsPath, "index.html"))
 }
 
-// cleanupInterruptedTasks 清理程序异常退出时遗留的 running 状态任务
-func cleanupInterruptedTasks(ctx context.Context, db *gorm.DB, log *zap.Logger) error {
-	if ctx == nil {
-		ctx = context.Background()
+// logRetentionInterval 数据库日志保留策略的执行间隔
+const logRetentionInterval = time.Hour
+
+// runLogRetention 定期按保留策略清理数据库日志（启动时立即执行一次）
+func runLogRetention(ctx context.Context, logs *repository.GormLogRepository, policy dbpkg.LogRetentionConfig, log *zap.Logger) {
+	if policy.Days <= 0 && policy.MaxRows <= 0 {
+		return
 	}
 
-	// 查询所有 running 状态的任务
-	var tasks []model.TaskRun
-	if err := db.WithContext(ctx).
-		Where("status = ?", "running").
-		Find(&tasks).Error; err != nil {
-		return fmt.Errorf("query interrupted tasks: %w", err)
+	ticker := time.NewTicker(logRetentionInterval)
+	defer ticker.Stop()
+	for {
+		var deleted int64
+		if policy.Days > 0 {
+			n, err := logs.DeleteBefore(ctx, time.Now().AddDate(0, 0, -policy.Days))
+			deleted += n
+			if err != nil && ctx.Err() == nil {
+				log.Warn("按保留天数清理日志失败", zap.Error(err))
+			}
+		}
+		if policy.MaxRows > 0 {
+			n, err := logs.TrimToLimit(ctx, int64(policy.MaxRows))
+			deleted += n
+			if err != nil && ctx.Err() == nil {
+				log.Warn("按保留条数清理日志失败", zap.Error(err))
+			}
+		}
+		if deleted > 0 {
+			log.Info("已按保留策略清理日志",
+				zap.Int64("deleted", deleted),
+				zap.Int("retention_days", policy.Days),
+				zap.Int("retention_max_rows", policy.MaxRows))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
+
+// staleTempFileAge 启动清理时临时文件的最短存在时间，避开其他实例正在写入的文件
+const staleTempFileAge = 10 * time.Minute
 
-	if len(tasks) == 0 {
-		return nil
+// cleanupStaleTempFiles 删除启用任务目标目录下原子写入遗留的临时文件
+func cleanupStaleTempFiles(ctx context.Context, jobs *repository.GormJobRepository, log *zap.Logger) {
+	list, err := jobs.ListEnabledJobs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn("查询任务失败，跳过临时文件清理", zap.Error(err))
+		}
+		return
 	}
 
-	log.Info("检测到中断任务，开始清理", zap.Int("count", len(tasks)))
+	seen := make(map[string]struct{}, len(list))
+	for _, job := range list {
+		root := strings.TrimSpace(job.TargetPath)
+		if root == "" || worker.JobUsesRemoteWriter(job) {
+			continue
+		}
+		if _, ok := seen[root]; ok {
+			continue
+		}
+		seen[root] = struct{}{}
+		if ctx.Err() != nil {
+			return
+		}
 
-	now := time.Now()
-	for _, task := range tasks {
-		// 计算执行时长
-		duration := int64(0)
-		if !task.StartedAt.IsZero() {
-			duration = int64(now.Sub(task.StartedAt).Seconds())
+		removed, err := fileutil.CleanupTempFiles(root, staleTempFileAge)
+		if err != nil {
+			log.Warn("清理临时文件失败", zap.String("target_path", root), zap.Error(err))
+		}
+		if removed > 0 {
+			log.Info("已清理遗留的临时文件", zap.String("target_path", root), zap.Int("removed", removed))
 		}
+	}
+}
 
-		// 更新任务状态为 failed
-		updates := map[string]any{
-			"status":        "failed",
-			"ended_at":      now,
-			"duration":      duration,
-			"failure_kind":  "interrupted",
-			"error_message": "程序异常退出，任务中断",
+// runRollupInterval 执行统计汇总与执行历史清理的执行间隔
+const runRollupInterval = time.Hour
+
+// runHistoryBatchPause 执行历史清理每批之间的停顿，让出数据库写锁给任务领取与状态更新
+const runHistoryBatchPause = 200 * time.Millisecond
+
+// runTaskRunRollup 定期将已结束时间桶的执行统计写入汇总表，随后按保留策略清理执行历史
+// （启动时立即执行一次）
+//
+// 清理只删除已汇总的记录，删除执行记录后趋势统计仍然保留。
+func runTaskRunRollup(ctx context.Context, stats *repository.GormRunStatsRepository, policy dbpkg.HistoryConfig, runLogDir string, log *zap.Logger) {
+	ticker := time.NewTicker(runRollupInterval)
+	defer ticker.Stop()
+	for {
+		written, err := stats.Rollup(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Warn("汇总执行统计失败", zap.Error(err))
+		} else {
+			if written > 0 {
+				log.Info("已汇总执行统计", zap.Int("rows", written))
+			}
+			pruneRunHistory(ctx, stats, policy, runLogDir, log)
 		}
 
-		if err := db.WithContext(ctx).
-			Model(&model.TaskRun{}).
-			Where("id = ?", task.ID).
-			Updates(updates).Error; err != nil {
-			log.Warn("清理中断任务失败",
-				zap.Uint("task_id", task.ID),
-				zap.Uint("job_id", task.JobID),
-				zap.Error(err))
-			continue
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneRunHistory 按保留策略分批清理执行记录、事件明细与执行日志目录
+func pruneRunHistory(ctx context.Context, stats *repository.GormRunStatsRepository, policy dbpkg.HistoryConfig, runLogDir string, log *zap.Logger) {
+	var runs, events int64
+	if policy.EventDays > 0 {
+		n, err := stats.PruneEvents(ctx, time.Now().AddDate(0, 0, -policy.EventDays))
+		events += n
+		if err != nil && ctx.Err() == nil {
+			log.Warn("按保留天数清理执行事件失败", zap.Error(err))
 		}
+	}
 
-		// 回写 Job 状态为 error
-		if err := db.WithContext(ctx).
-			Model(&model.Job{}).
-			Where("id = ?", task.JobID).
-			Update("status", "error").Error; err != nil {
-			log.Warn("更新任务状态失败",
-				zap.Uint("job_id", task.JobID),
-				zap.Error(err))
+	retention := domainrepo.RunRetentionPolicy{
+		KeepPerJob:     policy.KeepPerJob,
+		EventDays:      policy.EventDays,
+		KeepLastFailed: policy.KeepLastFailed,
+	}
+	for policy.KeepPerJob > 0 && ctx.Err() == nil {
+		ids, n, err := stats.PruneRuns(ctx, retention, policy.BatchSize)
+		runs += int64(len(ids))
+		events += n
+		for _, id := range ids {
+			if runLogDir == "" {
+				continue
+			}
+			if err := os.RemoveAll(logger.RunLogDir(runLogDir, id)); err != nil {
+				log.Warn("删除执行记录日志失败", zap.Error(err), zap.Uint("run_id", id))
+			}
 		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("按保留数量清理执行记录失败", zap.Error(err))
+			}
+			break
+		}
+		if len(ids) < policy.BatchSize {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(runHistoryBatchPause):
+		}
+	}
 
-		log.Info("已清理中断任务",
-			zap.Uint("task_id", task.ID),
-			zap.Uint("job_id", task.JobID))
+	if runs > 0 || events > 0 {
+		log.Info("已按保留策略清理执行历史",
+			zap.Int64("runs", runs),
+			zap.Int64("events", events),
+			zap.Int("keep_per_job", policy.KeepPerJob),
+			zap.Int("event_days", policy.EventDays),
+			zap.Bool("keep_last_failed", policy.KeepLastFailed))
 	}
+}
+
+// streamingHandler 为长连接、大文件下载与数据库备份接口取消服务器写超时
+//
+// http.Server 的 WriteTimeout 对所有请求生效，会截断 SSE 连接与较大的下载；
+// gin 的 ResponseWriter 不支持 Unwrap，因此在进入路由前对原始连接设置。
+func streamingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStreamingPath(r.URL.Path) {
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	return nil
+// isStreamingPath 判断是否为不受写超时限制的接口
+func isStreamingPath(path string) bool {
+	if path == "/api/logs/stream" {
+		return true
+	}
+	// 备份、恢复与下载备份的耗时随数据库大小增长
+	if strings.HasPrefix(path, "/api/backups") {
+		return true
+	}
+	return strings.HasPrefix(path, "/api/runs/") &&
+		(strings.HasSuffix(path, "/log") || strings.HasSuffix(path, "/bundle"))
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
+)
+
+const migrateUsage = `用法: strmsync migrate <命令> [版本]
+
+命令:
+  status           查看迁移状态
+  up [版本]        执行待执行的迁移（默认执行到最新版本）
+  down [版本]      回滚版本号大于指定版本的迁移（默认回滚最近一个）
+
+SQLite 在执行或回滚迁移前会备份数据库文件（<DB_PATH>.pre-migrate-<时间>.bak）。
+`
+
+// runMigrateCommand 执行 migrate 子命令，返回进程退出码
+//
+// 服务启动时会自动执行待执行的迁移；该命令用于查看状态、升级前预先迁移或回滚。
+func runMigrateCommand(cfg *dbpkg.Config, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+	command := args[0]
+	if command != "status" && command != "up" && command != "down" {
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+	target := -1
+	if len(args) == 2 {
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			fmt.Fprintf(stderr, "无效的版本号: %q\n", args[1])
+			return 2
+		}
+		target = version
+	}
+
+	conn, err := dbpkg.Connect(cfg.Database, &cfg.Log)
+	if err != nil {
+		fmt.Fprintf(stderr, "连接数据库失败: %v\n", err)
+		return 1
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	migrator, err := dbpkg.NewSchemaMigrator(conn, cfg.Database)
+	if err != nil {
+		fmt.Fprintf(stderr, "创建迁移执行器失败: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	var result *dbpkg.MigrationResult
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "查询迁移状态失败: %v\n", err)
+			return 1
+		}
+		printMigrationStatuses(stdout, statuses)
+		return 0
+	case "up":
+		if target < 0 {
+			target = 0
+		}
+		result, err = migrator.Up(ctx, target)
+	case "down":
+		if target < 0 {
+			target = previousMigrationVersion(ctx, migrator)
+		}
+		result, err = migrator.Down(ctx, target)
+	}
+
+	if result != nil {
+		if result.BackupPath != "" {
+			fmt.Fprintf(stdout, "已备份数据库: %s\n", result.BackupPath)
+		}
+		for _, item := range result.Migrations {
+			action := "已执行"
+			if command == "down" {
+				action = "已回滚"
+			}
+			fmt.Fprintf(stdout, "%s %04d_%s\n", action, item.Version, item.Name)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "迁移失败: %v\n", err)
+		return 1
+	}
+	if result == nil || len(result.Migrations) == 0 {
+		fmt.Fprintln(stdout, "没有需要执行的迁移")
+	}
+	return 0
+}
+
+// previousMigrationVersion 返回最近一个已执行迁移之前的版本（down 默认只回滚一个）
+func previousMigrationVersion(ctx context.Context, migrator *dbpkg.SchemaMigrator) int {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return migrator.LatestVersion()
+	}
+	previous, last := 0, -1
+	for _, item := range statuses {
+		if item.Applied {
+			if last >= 0 {
+				previous = last
+			}
+			last = item.Version
+		}
+	}
+	if last < 0 {
+		return 0
+	}
+	return previous
+}
+
+// printMigrationStatuses 以表格输出迁移状态
+func printMigrationStatuses(w io.Writer, statuses []dbpkg.MigrationStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "版本\t名称\t状态\t执行时间")
+	for _, item := range statuses {
+		state := "待执行"
+		switch {
+		case item.Unknown:
+			state = "未知（由更新的版本执行）"
+		case item.Applied && item.Skipped:
+			state = "已执行（当前数据库不适用）"
+		case item.Applied:
+			state = "已执行"
+		}
+		appliedAt := "-"
+		if item.AppliedAt != nil {
+			appliedAt = item.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", item.Version, item.Name, state, appliedAt)
+	}
+	_ = tw.Flush()
+}
@@ -160,6 +160,7 @@ type SyncPlanItem struct {
 	StreamURL      string        // 流媒体URL（写入strm文件的内容，Kind=Strm时使用）
 	Size           int64         // 源文件大小
 	ModTime        time.Time     // 源文件修改时间
+	MediaItem      string        // 所属媒体条目（视频路径去掉扩展名，用于按条目汇总事件）
 }
 
 // TaskRunSummary 任务执行摘要
@@ -3,6 +3,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/strmsync/strmsync/internal/app/ports"
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
 	"go.uber.org/zap"
 )
 
@@ -145,26 +147,16 @@ func (g *Generator) createOrUpdateStrm(ctx context.Context, item *ports.SyncPlan
 
 	startTime := time.Now()
 
-	// 1. 确保目标目录存在
-	targetDir := filepath.Dir(item.TargetStrmPath)
-	if err := os.MkdirAll(targetDir, 0o755); err != nil {
-		return fmt.Errorf("create target directory %s: %w", targetDir, err)
-	}
-
-	// 2. 写入strm文件
-	if err := os.WriteFile(item.TargetStrmPath, []byte(item.StreamURL), 0o644); err != nil {
+	// 写入同目录临时文件后原子重命名，并保持与源文件一致的修改时间
+	err := fileutil.WriteFile(item.TargetStrmPath, []byte(item.StreamURL), fileutil.WriteOptions{ModTime: item.ModTime})
+	if errors.Is(err, fileutil.ErrModTime) {
+		g.logger.Warn("设置STRM文件时间失败",
+			zap.String("path", item.TargetStrmPath),
+			zap.Error(err))
+	} else if err != nil {
 		return fmt.Errorf("write strm file %s: %w", item.TargetStrmPath, err)
 	}
 
-	// 3. 设置文件修改时间（可选，保持与源文件一致）
-	if !item.ModTime.IsZero() {
-		if err := os.Chtimes(item.TargetStrmPath, item.ModTime, item.ModTime); err != nil {
-			g.logger.Warn("设置STRM文件时间失败",
-				zap.String("path", item.TargetStrmPath),
-				zap.Error(err))
-		}
-	}
-
 	elapsed := time.Since(startTime)
 	g.logger.Info(fmt.Sprintf("STRM文件写入成功：%s", item.TargetStrmPath),
 		zap.String("target", item.TargetStrmPath),
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/app/ports"
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+	"github.com/strmsync/strmsync/internal/pkg/imageutil"
+	"go.uber.org/zap"
+)
+
+// maxArtworkBuffer 图片处理时读入内存的源文件大小上限，超过时按原样复制
+const maxArtworkBuffer = 64 << 20
+
+// DefaultArtworkExtensions 默认处理的图片扩展名
+func DefaultArtworkExtensions() []string {
+	return []string{".jpg", ".jpeg", ".png"}
+}
+
+// ArtworkOptions 元数据图片处理选项
+//
+// 匹配的图片在写入目标目录前按 Image 缩放/重新编码；无法处理的图片（如 WebP）原样写入。
+type ArtworkOptions struct {
+	Image   imageutil.Options
+	Exts    []string // 处理的扩展名（小写，含点），为空时使用 DefaultArtworkExtensions
+	MaxSize int64    // 源图片大小上限（字节，超过时不复制；0 表示不限制）
+}
+
+// Match 判断源文件是否需要图片处理
+func (o *ArtworkOptions) Match(sourcePath string) bool {
+	if o == nil {
+		return false
+	}
+	exts := o.Exts
+	if len(exts) == 0 {
+		exts = DefaultArtworkExtensions()
+	}
+	ext := strings.ToLower(filepath.Ext(sourcePath))
+	for _, item := range exts {
+		if item == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// TooLarge 判断源图片是否超过大小上限
+func (o *ArtworkOptions) TooLarge(size int64) bool {
+	return o != nil && o.MaxSize > 0 && size > o.MaxSize
+}
+
+// WithArtwork 设置元数据图片处理选项（nil 表示不处理）
+func WithArtwork(opts *ArtworkOptions) MetadataReplicatorOption {
+	return func(r *MetadataReplicator) {
+		r.artwork = opts
+	}
+}
+
+// processArtwork 读取源图片、处理后写入目标文件，返回读取的源文件字节数
+func (r *MetadataReplicator) processArtwork(ctx context.Context, item *ports.SyncPlanItem) (int64, error) {
+	if r.artwork.TooLarge(item.Size) {
+		return 0, fmt.Errorf("artwork %s exceeds size limit (%d > %d)", item.SourcePath, item.Size, r.artwork.MaxSize)
+	}
+
+	startTime := time.Now()
+	data, err := r.readSource(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+
+	out, changed, err := imageutil.Process(data, r.artwork.Image)
+	if err != nil {
+		// 无法处理的图片按原样写入，不影响元数据同步
+		r.logger.Warn("图片处理失败，按原样写入",
+			zap.String("source", item.SourcePath),
+			zap.Error(err))
+		out = data
+	}
+
+	dstPath := item.TargetMetaPath
+	_, err = fileutil.WriteFileFrom(dstPath, bytes.NewReader(out), r.writeOptions(item.ModTime))
+	if err = r.checkWriteErr(dstPath, err); err != nil {
+		return 0, fmt.Errorf("write artwork %s: %w", dstPath, err)
+	}
+
+	r.logger.Info(fmt.Sprintf("图片处理完成：%s -> %s", item.SourcePath, dstPath),
+		zap.String("source", item.SourcePath),
+		zap.String("dst", dstPath),
+		zap.Bool("changed", changed),
+		zap.Int("src_bytes", len(data)),
+		zap.Int("dst_bytes", len(out)),
+		zap.Duration("elapsed", time.Since(startTime)))
+
+	return int64(len(data)), nil
+}
+
+// readSource 按复制策略将源文件读入内存
+//
+// 与 copyOrDownload 相同：挂载模式读取访问路径，API 模式优先下载，失败再回退到访问路径。
+func (r *MetadataReplicator) readSource(ctx context.Context, item *ports.SyncPlanItem) ([]byte, error) {
+	if !r.preferMount {
+		buf := &limitedBuffer{limit: maxArtworkBuffer}
+		err := r.fs.Download(ctx, item.SourcePath, buf)
+		if err == nil {
+			return buf.Bytes(), nil
+		}
+		accessPath, err2 := r.fs.ResolveAccessPath(ctx, item.SourcePath)
+		if err2 != nil {
+			return nil, fmt.Errorf("download %s: %w", item.SourcePath, err)
+		}
+		r.logger.Debug("API下载失败，读取访问路径（回退）",
+			zap.String("source", item.SourcePath),
+			zap.String("access_path", accessPath),
+			zap.Error(err))
+		return readFileLimited(accessPath)
+	}
+
+	accessPath, err := r.fs.ResolveAccessPath(ctx, item.SourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve access path: %w", err)
+	}
+	return readFileLimited(accessPath)
+}
+
+// readFileLimited 读取本地文件（不超过 maxArtworkBuffer）
+func readFileLimited(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("source file not found: %s", path)
+		}
+		return nil, fmt.Errorf("open source %s: %w", path, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxArtworkBuffer+1))
+	if err != nil {
+		return nil, fmt.Errorf("read source %s: %w", path, err)
+	}
+	if len(data) > maxArtworkBuffer {
+		return nil, fmt.Errorf("source %s exceeds %d bytes", path, maxArtworkBuffer)
+	}
+	return data, nil
+}
+
+// errBufferFull 下载内容超过 maxArtworkBuffer
+var errBufferFull = fmt.Errorf("artwork exceeds %d bytes", maxArtworkBuffer)
+
+// limitedBuffer 超过容量上限时返回错误的内存缓冲
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errBufferFull
+	}
+	return b.Buffer.Write(p)
+}
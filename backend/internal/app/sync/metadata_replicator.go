@@ -3,15 +3,19 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/strmsync/strmsync/internal/app/ports"
 	"github.com/strmsync/strmsync/internal/infra/filesystem"
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
 	"go.uber.org/zap"
 )
 
@@ -23,8 +27,14 @@ type MetadataReplicator struct {
 	// 策略配置
 	preferMount bool // true=优先挂载路径复制，false=优先API下载
 	eventSink   MetaEventSink
+	fsync       bool // 写入后是否 fsync
+	concurrency int  // 并发处理数（<=0 时使用数据服务器的并发下载数）
+	artwork     *ArtworkOptions
 }
 
+// resumeMinSize 启用断点续传的最小文件大小，较小的文件（海报、NFO 等）直接整体重新下载
+const resumeMinSize = 8 << 20
+
 // MetaEvent 元数据处理事件
 type MetaEvent struct {
 	Op           string
@@ -32,6 +42,8 @@ type MetaEvent struct {
 	SourcePath   string
 	TargetPath   string
 	ErrorMessage string
+	MediaItem    string // 所属媒体条目
+	Bytes        int64  // 传输字节数（复制/下载成功时）
 }
 
 // MetaEventSink 元数据事件回调
@@ -56,6 +68,22 @@ func WithEventSink(sink MetaEventSink) MetadataReplicatorOption {
 	}
 }
 
+// WithFsync 设置写入元数据文件后是否 fsync
+func WithFsync(enabled bool) MetadataReplicatorOption {
+	return func(r *MetadataReplicator) {
+		r.fsync = enabled
+	}
+}
+
+// WithConcurrency 设置并发处理数（默认使用数据服务器的并发下载数）
+//
+// 下载的实际并发仍受同一服务器共享的下载限制器约束。
+func WithConcurrency(n int) MetadataReplicatorOption {
+	return func(r *MetadataReplicator) {
+		r.concurrency = n
+	}
+}
+
 // NewMetadataReplicator 创建元数据复制器
 func NewMetadataReplicator(fs filesystem.Client, targetRoot string, logger *zap.Logger, opts ...MetadataReplicatorOption) ports.MetadataReplicator {
 	absRoot, err := filepath.Abs(targetRoot)
@@ -77,71 +105,100 @@ func NewMetadataReplicator(fs filesystem.Client, targetRoot string, logger *zap.
 	for _, opt := range opts {
 		opt(r)
 	}
+	if r.concurrency <= 0 {
+		r.concurrency = 1
+		if pool, ok := fs.(filesystem.DownloadPool); ok {
+			r.concurrency = pool.DownloadConcurrency()
+		}
+	}
 
 	r.logger.Info("创建元数据复制器",
 		zap.String("target_root", r.targetRoot),
-		zap.Bool("prefer_mount", r.preferMount))
+		zap.Bool("prefer_mount", r.preferMount),
+		zap.Int("concurrency", r.concurrency),
+		zap.Bool("artwork", r.artwork != nil))
 
 	return r
 }
 
 // Apply 执行元数据文件复制/下载（创建/更新/删除元数据文件）
+//
+// 计划项由 concurrency 个 goroutine 并行处理。
 func (r *MetadataReplicator) Apply(ctx context.Context, items <-chan ports.SyncPlanItem) (succeeded int, failed int, err error) {
-	r.logger.Info("开始处理元数据计划项")
+	r.logger.Info("开始处理元数据计划项", zap.Int("concurrency", r.concurrency))
 	startTime := time.Now()
 
-	for {
-		select {
-		case <-ctx.Done():
-			elapsed := time.Since(startTime)
-			r.logger.Warn("元数据复制被取消",
-				zap.Int("succeeded", succeeded),
-				zap.Int("failed", failed),
-				zap.Duration("elapsed", elapsed),
-				zap.Error(ctx.Err()))
-			return succeeded, failed, ctx.Err()
-
-		case item, ok := <-items:
-			if !ok {
-				// 通道关闭，所有项目已处理完成
-				elapsed := time.Since(startTime)
-				r.logger.Info("元数据复制完成",
-					zap.Int("succeeded", succeeded),
-					zap.Int("failed", failed),
-					zap.Duration("elapsed", elapsed))
-				return succeeded, failed, nil
+	var okCount, failCount atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-items:
+					if !ok {
+						// 通道关闭，所有项目已处理完成
+						return
+					}
+					// 只处理元数据类型的计划项
+					if item.Kind != ports.PlanItemMetadata {
+						r.logger.Debug("跳过非元数据计划项",
+							zap.String("kind", item.Kind.String()),
+							zap.String("source_path", item.SourcePath))
+						continue
+					}
+					if r.process(ctx, &item) {
+						okCount.Add(1)
+					} else {
+						failCount.Add(1)
+					}
+				}
 			}
+		}()
+	}
+	wg.Wait()
 
-			// 只处理元数据类型的计划项
-			if item.Kind != ports.PlanItemMetadata {
-				r.logger.Debug("跳过非元数据计划项",
-					zap.String("kind", item.Kind.String()),
-					zap.String("source_path", item.SourcePath))
-				continue
-			}
+	succeeded, failed = int(okCount.Load()), int(failCount.Load())
+	elapsed := time.Since(startTime)
+	if ctx.Err() != nil {
+		r.logger.Warn("元数据复制被取消",
+			zap.Int("succeeded", succeeded),
+			zap.Int("failed", failed),
+			zap.Duration("elapsed", elapsed),
+			zap.Error(ctx.Err()))
+		return succeeded, failed, ctx.Err()
+	}
+	r.logger.Info("元数据复制完成",
+		zap.Int("succeeded", succeeded),
+		zap.Int("failed", failed),
+		zap.Duration("elapsed", elapsed))
+	return succeeded, failed, nil
+}
 
-			// 处理单个元数据项
-			if err := r.applyItem(ctx, &item); err != nil {
-				r.logger.Error("元数据项处理失败",
-					zap.String("op", item.Op.String()),
-					zap.String("source_path", item.SourcePath),
-					zap.String("target_path", item.TargetMetaPath),
-					zap.Error(err))
-				r.emitMetaEvent(ctx, &item, "failed", err.Error())
-				failed++
-			} else {
-				r.logger.Debug("元数据项处理成功",
-					zap.String("op", item.Op.String()),
-					zap.String("source_path", item.SourcePath),
-					zap.String("target_path", item.TargetMetaPath))
-				r.emitMetaEvent(ctx, &item, "success", "")
-				succeeded++
-			}
-		}
-	}
+// process 处理单个元数据计划项并发送事件，返回是否成功
+func (r *MetadataReplicator) process(ctx context.Context, item *ports.SyncPlanItem) bool {
+	written, err := r.applyItem(ctx, item)
+	if err != nil {
+		r.logger.Error("元数据项处理失败",
+			zap.String("op", item.Op.String()),
+			zap.String("source_path", item.SourcePath),
+			zap.String("target_path", item.TargetMetaPath),
+			zap.Error(err))
+		r.emitMetaEvent(ctx, item, "failed", err.Error(), 0)
+		return false
+	}
+	r.logger.Debug("元数据项处理成功",
+		zap.String("op", item.Op.String()),
+		zap.String("source_path", item.SourcePath),
+		zap.String("target_path", item.TargetMetaPath))
+	r.emitMetaEvent(ctx, item, "success", "", written)
+	return true
 }
 
-func (r *MetadataReplicator) emitMetaEvent(ctx context.Context, item *ports.SyncPlanItem, status string, errMsg string) {
+func (r *MetadataReplicator) emitMetaEvent(ctx context.Context, item *ports.SyncPlanItem, status string, errMsg string, written int64) {
 	if r == nil || r.eventSink == nil || item == nil {
 		return
 	}
@@ -160,38 +217,44 @@ func (r *MetadataReplicator) emitMetaEvent(ctx context.Context, item *ports.Sync
 		SourcePath:   item.SourcePath,
 		TargetPath:   item.TargetMetaPath,
 		ErrorMessage: errMsg,
+		MediaItem:    item.MediaItem,
+		Bytes:        written,
 	})
 }
 
-// applyItem 处理单个元数据计划项
-func (r *MetadataReplicator) applyItem(ctx context.Context, item *ports.SyncPlanItem) error {
+// applyItem 处理单个元数据计划项，返回写入的字节数
+func (r *MetadataReplicator) applyItem(ctx context.Context, item *ports.SyncPlanItem) (int64, error) {
 	// 验证目标路径
 	if err := r.validatePath(item.TargetMetaPath); err != nil {
-		return fmt.Errorf("validate target path: %w", err)
+		return 0, fmt.Errorf("validate target path: %w", err)
 	}
 
 	switch item.Op {
 	case ports.SyncOpCreate, ports.SyncOpUpdate:
 		return r.copyOrDownload(ctx, item)
 	case ports.SyncOpDelete:
-		return r.deleteMeta(item.TargetMetaPath)
+		return 0, r.deleteMeta(item.TargetMetaPath)
 	default:
-		return fmt.Errorf("unknown operation: %v", item.Op)
+		return 0, fmt.Errorf("unknown operation: %v", item.Op)
 	}
 }
 
-// copyOrDownload 根据策略选择复制或下载
-func (r *MetadataReplicator) copyOrDownload(ctx context.Context, item *ports.SyncPlanItem) error {
+// copyOrDownload 根据策略选择复制或下载，返回写入的字节数
+func (r *MetadataReplicator) copyOrDownload(ctx context.Context, item *ports.SyncPlanItem) (int64, error) {
 	r.logger.Debug("开始复制/下载元数据文件",
 		zap.String("source", item.SourcePath),
 		zap.String("target", item.TargetMetaPath),
 		zap.Bool("prefer_mount", r.preferMount))
 
+	if r.artwork.Match(item.SourcePath) && item.Size <= maxArtworkBuffer {
+		return r.processArtwork(ctx, item)
+	}
+
 	if r.preferMount {
 		// 策略1: 本地模式，仅使用访问路径复制
 		accessPath, err := r.fs.ResolveAccessPath(ctx, item.SourcePath)
 		if err != nil {
-			return fmt.Errorf("resolve access path: %w", err)
+			return 0, fmt.Errorf("resolve access path: %w", err)
 		}
 		r.logger.Debug("使用访问路径复制",
 			zap.String("access_path", accessPath),
@@ -200,7 +263,8 @@ func (r *MetadataReplicator) copyOrDownload(ctx context.Context, item *ports.Syn
 	}
 
 	// 策略2: API 模式，优先下载，失败再尝试访问路径复制
-	if err := r.downloadToFile(ctx, item.SourcePath, item.TargetMetaPath, item.ModTime); err != nil {
+	written, err := r.downloadToFile(ctx, item)
+	if err != nil {
 		r.logger.Debug("API下载失败，尝试访问路径复制",
 			zap.String("source", item.SourcePath),
 			zap.Error(err))
@@ -212,74 +276,39 @@ func (r *MetadataReplicator) copyOrDownload(ctx context.Context, item *ports.Syn
 			return r.copyLocal(accessPath, item.TargetMetaPath, item.ModTime)
 		}
 
-		return err
+		return 0, err
 	}
-	return nil
+	return written, nil
 }
 
 // copyLocal 从本地挂载路径复制文件
-func (r *MetadataReplicator) copyLocal(srcPath, dstPath string, modTime time.Time) error {
+func (r *MetadataReplicator) copyLocal(srcPath, dstPath string, modTime time.Time) (int64, error) {
 	r.logger.Debug("本地复制开始",
 		zap.String("src", srcPath),
 		zap.String("dst", dstPath))
 
 	startTime := time.Now()
 
-	// 确保目标目录存在
-	dstDir := filepath.Dir(dstPath)
-	if err := os.MkdirAll(dstDir, 0o755); err != nil {
-		return fmt.Errorf("create target directory %s: %w", dstDir, err)
-	}
-
 	// 打开源文件
 	srcFile, err := os.Open(srcPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("source file not found: %s", srcPath)
+			return 0, fmt.Errorf("source file not found: %s", srcPath)
 		}
-		return fmt.Errorf("open source %s: %w", srcPath, err)
+		return 0, fmt.Errorf("open source %s: %w", srcPath, err)
 	}
 	defer srcFile.Close()
 
 	// 获取源文件信息
 	srcInfo, err := srcFile.Stat()
 	if err != nil {
-		return fmt.Errorf("stat source %s: %w", srcPath, err)
+		return 0, fmt.Errorf("stat source %s: %w", srcPath, err)
 	}
 
-	// 创建临时文件
-	tmpPath := dstPath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("create temp file %s: %w", tmpPath, err)
-	}
-
-	// 复制文件内容
-	written, err := io.Copy(tmpFile, srcFile)
-	if err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("copy %s -> %s: %w", srcPath, tmpPath, err)
-	}
-
-	if err := tmpFile.Close(); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("close temp file %s: %w", tmpPath, err)
-	}
-
-	// 原子性地重命名临时文件为目标文件
-	if err := os.Rename(tmpPath, dstPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("rename %s -> %s: %w", tmpPath, dstPath, err)
-	}
-
-	// 设置修改时间
-	if !modTime.IsZero() {
-		if err := os.Chtimes(dstPath, modTime, modTime); err != nil {
-			r.logger.Warn("设置文件时间失败",
-				zap.String("path", dstPath),
-				zap.Error(err))
-		}
+	// 写入同目录临时文件后原子重命名
+	written, err := fileutil.WriteFileFrom(dstPath, srcFile, r.writeOptions(modTime))
+	if err = r.checkWriteErr(dstPath, err); err != nil {
+		return 0, fmt.Errorf("copy %s -> %s: %w", srcPath, dstPath, err)
 	}
 
 	elapsed := time.Since(startTime)
@@ -290,59 +319,43 @@ func (r *MetadataReplicator) copyLocal(srcPath, dstPath string, modTime time.Tim
 		zap.Int64("src_size", srcInfo.Size()),
 		zap.Duration("elapsed", elapsed))
 
-	return nil
+	return written, nil
 }
 
-// downloadToFile 通过API下载文件
-func (r *MetadataReplicator) downloadToFile(ctx context.Context, remotePath, dstPath string, modTime time.Time) error {
+// downloadToFile 通过API下载文件，返回本次传输的字节数
+//
+// 数据源支持 Range 且文件不小于 resumeMinSize 时使用断点续传：
+// 中断的下载保留部分文件，下次同步从断点继续。
+func (r *MetadataReplicator) downloadToFile(ctx context.Context, item *ports.SyncPlanItem) (int64, error) {
+	remotePath, dstPath := item.SourcePath, item.TargetMetaPath
 	r.logger.Debug("API下载开始",
 		zap.String("remote", remotePath),
 		zap.String("dst", dstPath))
 
 	startTime := time.Now()
 
-	// 确保目标目录存在
-	dstDir := filepath.Dir(dstPath)
-	if err := os.MkdirAll(dstDir, 0o755); err != nil {
-		return fmt.Errorf("create target directory %s: %w", dstDir, err)
-	}
-
-	// 创建临时文件
-	tmpPath := dstPath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("create temp file %s: %w", tmpPath, err)
-	}
-
-	// 下载文件内容
-	if err := r.fs.Download(ctx, remotePath, tmpFile); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("download %s: %w", remotePath, err)
-	}
-
-	// 获取下载的文件大小
-	tmpInfo, _ := tmpFile.Stat()
-	written := tmpInfo.Size()
-
-	if err := tmpFile.Close(); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("close temp file %s: %w", tmpPath, err)
-	}
-
-	// 原子性地重命名临时文件为目标文件
-	if err := os.Rename(tmpPath, dstPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("rename %s -> %s: %w", tmpPath, dstPath, err)
+	var written int64
+	var err error
+	if ranger, ok := r.fs.(filesystem.RangeDownloader); ok && ranger.SupportsRange() && item.Size >= resumeMinSize {
+		// 部分文件按源文件大小与修改时间区分版本，源文件变化后重新下载
+		version := fmt.Sprintf("%d@%d", item.Size, item.ModTime.UnixNano())
+		written, err = fileutil.WriteFileResumable(dstPath, version, item.Size, func(w io.Writer, offset int64) error {
+			if offset > 0 {
+				r.logger.Info(fmt.Sprintf("断点续传：%s", remotePath),
+					zap.String("remote", remotePath),
+					zap.Int64("offset", offset),
+					zap.Int64("size", item.Size))
+			}
+			return ranger.DownloadRange(ctx, remotePath, offset, w)
+		}, r.writeOptions(item.ModTime))
+	} else {
+		// 下载到同目录临时文件后原子重命名，下载中断不会留下半截文件
+		written, err = fileutil.WriteFileFunc(dstPath, func(w io.Writer) error {
+			return r.fs.Download(ctx, remotePath, w)
+		}, r.writeOptions(item.ModTime))
 	}
-
-	// 设置修改时间
-	if !modTime.IsZero() {
-		if err := os.Chtimes(dstPath, modTime, modTime); err != nil {
-			r.logger.Warn("设置文件时间失败",
-				zap.String("path", dstPath),
-				zap.Error(err))
-		}
+	if err = r.checkWriteErr(dstPath, err); err != nil {
+		return 0, fmt.Errorf("download %s: %w", remotePath, err)
 	}
 
 	elapsed := time.Since(startTime)
@@ -352,7 +365,23 @@ func (r *MetadataReplicator) downloadToFile(ctx context.Context, remotePath, dst
 		zap.Int64("bytes", written),
 		zap.Duration("elapsed", elapsed))
 
-	return nil
+	return written, nil
+}
+
+// writeOptions 元数据文件的原子写入选项
+func (r *MetadataReplicator) writeOptions(modTime time.Time) fileutil.WriteOptions {
+	return fileutil.WriteOptions{Sync: r.fsync, ModTime: modTime}
+}
+
+// checkWriteErr 将修改时间设置失败降级为警告（文件内容已写入）
+func (r *MetadataReplicator) checkWriteErr(dstPath string, err error) error {
+	if errors.Is(err, fileutil.ErrModTime) {
+		r.logger.Warn("设置文件时间失败",
+			zap.String("path", dstPath),
+			zap.Error(err))
+		return nil
+	}
+	return err
 }
 
 // deleteMeta 删除元数据文件
@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/app/ports"
+	"github.com/strmsync/strmsync/internal/infra/filesystem"
+	"github.com/strmsync/strmsync/internal/pkg/imageutil"
+	"go.uber.org/zap"
+)
+
+// rangeFS 支持断点续传的假数据源（首次下载 failAfter 字节后中断）
+type rangeFS struct {
+	filesystem.Client
+	content   []byte
+	failAfter int
+
+	mu      gosync.Mutex
+	offsets []int64
+}
+
+func (f *rangeFS) Download(ctx context.Context, remotePath string, w io.Writer) error {
+	return f.DownloadRange(ctx, remotePath, 0, w)
+}
+
+func (f *rangeFS) DownloadRange(_ context.Context, _ string, offset int64, w io.Writer) error {
+	f.mu.Lock()
+	f.offsets = append(f.offsets, offset)
+	first := len(f.offsets) == 1
+	f.mu.Unlock()
+	if first && f.failAfter > 0 {
+		_, _ = w.Write(f.content[:f.failAfter])
+		return errors.New("connection reset")
+	}
+	_, err := w.Write(f.content[offset:])
+	return err
+}
+
+func (f *rangeFS) ResolveAccessPath(context.Context, string) (string, error) {
+	return "", errors.New("access_path not configured")
+}
+
+func (f *rangeFS) SupportsRange() bool      { return true }
+func (f *rangeFS) DownloadConcurrency() int { return 3 }
+
+// recordingSink 记录元数据事件
+type recordingSink struct {
+	mu     gosync.Mutex
+	events []MetaEvent
+}
+
+func (s *recordingSink) OnMetaEvent(_ context.Context, event MetaEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func TestMetadataReplicator_ResumesLargeDownload(t *testing.T) {
+	root := t.TempDir()
+	content := bytes.Repeat([]byte("x"), resumeMinSize+1024)
+	src := &rangeFS{content: content, failAfter: 4096}
+	sink := &recordingSink{}
+	r := NewMetadataReplicator(src, root, zap.NewNop(), WithPreferMount(false), WithEventSink(sink)).(*MetadataReplicator)
+	if r.concurrency != 3 {
+		t.Fatalf("expected concurrency from data source, got %d", r.concurrency)
+	}
+
+	item := ports.SyncPlanItem{
+		Op:             ports.SyncOpCreate,
+		Kind:           ports.PlanItemMetadata,
+		SourcePath:     "/Movies/A/trailer.mkv",
+		TargetMetaPath: filepath.Join(root, "Movies", "A", "trailer.mkv"),
+		Size:           int64(len(content)),
+		ModTime:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	run := func() (int, int) {
+		items := make(chan ports.SyncPlanItem, 1)
+		items <- item
+		close(items)
+		ok, failed, err := r.Apply(context.Background(), items)
+		if err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		return ok, failed
+	}
+
+	if ok, failed := run(); ok != 0 || failed != 1 {
+		t.Fatalf("expected first download to fail, got ok=%d failed=%d", ok, failed)
+	}
+	if ok, failed := run(); ok != 1 || failed != 0 {
+		t.Fatalf("expected resumed download to succeed, got ok=%d failed=%d", ok, failed)
+	}
+	if len(src.offsets) != 2 || src.offsets[1] != 4096 {
+		t.Fatalf("expected resume from offset 4096, got %v", src.offsets)
+	}
+	data, err := os.ReadFile(item.TargetMetaPath)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("unexpected downloaded content (len=%d) err=%v", len(data), err)
+	}
+	last := sink.events[len(sink.events)-1]
+	if last.Status != "success" || last.Bytes != int64(len(content)-4096) {
+		t.Fatalf("expected transferred bytes %d, got %+v", len(content)-4096, last)
+	}
+}
+
+func TestMetadataReplicator_AppliesItemsConcurrently(t *testing.T) {
+	root := t.TempDir()
+	src := &rangeFS{content: []byte("<movie/>")}
+	sink := &recordingSink{}
+	r := NewMetadataReplicator(src, root, zap.NewNop(), WithPreferMount(false), WithEventSink(sink), WithConcurrency(4))
+
+	items := make(chan ports.SyncPlanItem)
+	go func() {
+		defer close(items)
+		for _, name := range []string{"a.nfo", "b.nfo", "c.nfo", "d.nfo", "e.nfo"} {
+			items <- ports.SyncPlanItem{
+				Op:             ports.SyncOpCreate,
+				Kind:           ports.PlanItemMetadata,
+				SourcePath:     "/" + name,
+				TargetMetaPath: filepath.Join(root, name),
+				Size:           8,
+			}
+		}
+		items <- ports.SyncPlanItem{Kind: ports.PlanItemStrm}
+	}()
+
+	ok, failed, err := r.Apply(context.Background(), items)
+	if err != nil || ok != 5 || failed != 0 {
+		t.Fatalf("unexpected result ok=%d failed=%d err=%v", ok, failed, err)
+	}
+	var total int64
+	for _, event := range sink.events {
+		total += event.Bytes
+	}
+	if total != 5*8 {
+		t.Fatalf("expected 40 transferred bytes, got %d", total)
+	}
+}
+
+func TestMetadataReplicator_ResizesArtwork(t *testing.T) {
+	root := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	src := &rangeFS{content: buf.Bytes()}
+	sink := &recordingSink{}
+	artwork := &ArtworkOptions{Image: imageutil.Options{MaxWidth: 50}, MaxSize: 1 << 20}
+	r := NewMetadataReplicator(src, root, zap.NewNop(), WithPreferMount(false), WithEventSink(sink), WithArtwork(artwork))
+
+	items := make(chan ports.SyncPlanItem, 2)
+	items <- ports.SyncPlanItem{
+		Op:             ports.SyncOpCreate,
+		Kind:           ports.PlanItemMetadata,
+		SourcePath:     "/Movies/A/fanart.png",
+		TargetMetaPath: filepath.Join(root, "fanart.png"),
+		Size:           int64(buf.Len()),
+	}
+	items <- ports.SyncPlanItem{
+		Op:             ports.SyncOpCreate,
+		Kind:           ports.PlanItemMetadata,
+		SourcePath:     "/Movies/A/poster.png",
+		TargetMetaPath: filepath.Join(root, "poster.png"),
+		Size:           2 << 20,
+	}
+	close(items)
+
+	ok, failed, err := r.Apply(context.Background(), items)
+	if err != nil || ok != 1 || failed != 1 {
+		t.Fatalf("unexpected result ok=%d failed=%d err=%v", ok, failed, err)
+	}
+	f, err := os.Open(filepath.Join(root, "fanart.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil || format != "png" || cfg.Width != 50 || cfg.Height != 25 {
+		t.Fatalf("unexpected artwork %s %dx%d err=%v", format, cfg.Width, cfg.Height, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "poster.png")); !os.IsNotExist(err) {
+		t.Fatalf("oversized artwork should not be written, err=%v", err)
+	}
+	for _, event := range sink.events {
+		if event.Status == "success" && event.Bytes != int64(buf.Len()) {
+			t.Fatalf("expected source bytes %d, got %+v", buf.Len(), event)
+		}
+	}
+}
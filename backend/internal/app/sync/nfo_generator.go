@@ -0,0 +1,236 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+	"github.com/strmsync/strmsync/internal/pkg/medianame"
+	"go.uber.org/zap"
+)
+
+// generatedNFOMarker 生成的 NFO 中的标记注释
+//
+// 只有带标记的 NFO 会被更新，用户或媒体服务器写入的 NFO 不会被覆盖。
+const generatedNFOMarker = "<!-- generated by strmsync -->"
+
+// NFOItem 需要生成 NFO 的视频文件
+type NFOItem struct {
+	SourcePath string // 远端视频路径
+	TargetPath string // NFO 目标路径（与 STRM 同名）
+	Size       int64  // 视频大小
+	MediaItem  string // 所属媒体条目
+}
+
+// NFOGenerator 为缺少 NFO 的视频生成最小化的 Kodi/Emby NFO
+//
+// 标题、年份、季集与分辨率从文件名解析（pkg/medianame），
+// 电影生成 <movie>，剧集生成 <episodedetails>，并记录视频大小与远端路径。
+type NFOGenerator struct {
+	logger    *zap.Logger
+	eventSink MetaEventSink
+	fsync     bool
+	dryRun    bool
+}
+
+// NFOGeneratorOption NFO 生成器配置选项
+type NFOGeneratorOption func(*NFOGenerator)
+
+// WithNFOEventSink 设置元数据事件回调
+func WithNFOEventSink(sink MetaEventSink) NFOGeneratorOption {
+	return func(g *NFOGenerator) {
+		g.eventSink = sink
+	}
+}
+
+// WithNFOFsync 设置写入后是否 fsync
+func WithNFOFsync(enabled bool) NFOGeneratorOption {
+	return func(g *NFOGenerator) {
+		g.fsync = enabled
+	}
+}
+
+// WithNFODryRun 设置干运行（只发送事件，不写入文件）
+func WithNFODryRun(enabled bool) NFOGeneratorOption {
+	return func(g *NFOGenerator) {
+		g.dryRun = enabled
+	}
+}
+
+// NewNFOGenerator 创建 NFO 生成器
+func NewNFOGenerator(logger *zap.Logger, opts ...NFOGeneratorOption) *NFOGenerator {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	g := &NFOGenerator{logger: logger}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Generate 为每个视频生成 NFO，返回新建、更新与失败的数量
+//
+// 目标位置已有 NFO 时：带生成标记且内容变化则更新，否则跳过。
+func (g *NFOGenerator) Generate(ctx context.Context, items []NFOItem) (created, updated, failed int) {
+	for i := range items {
+		if ctx.Err() != nil {
+			return created, updated, failed
+		}
+		item := &items[i]
+		content, err := RenderNFO(medianame.ParsePath(item.SourcePath), item.SourcePath, item.Size)
+		if err != nil {
+			failed++
+			g.emit(ctx, item, "generate", "failed", err.Error())
+			continue
+		}
+
+		op := "generate"
+		existing, err := os.ReadFile(item.TargetPath)
+		switch {
+		case err == nil && !bytes.Contains(existing, []byte(generatedNFOMarker)):
+			g.emit(ctx, item, "skip", "skipped", "skip_existing")
+			continue
+		case err == nil && bytes.Equal(existing, content):
+			g.emit(ctx, item, "skip", "skipped", "unchanged")
+			continue
+		case err == nil:
+			op = "update"
+		case !os.IsNotExist(err):
+			failed++
+			g.logger.Warn("读取已有 NFO 失败",
+				zap.String("path", item.TargetPath),
+				zap.Error(err))
+			g.emit(ctx, item, op, "failed", err.Error())
+			continue
+		}
+
+		if g.dryRun {
+			g.emit(ctx, item, op, "skipped", "dry_run")
+			continue
+		}
+		if err := fileutil.WriteFile(item.TargetPath, content, fileutil.WriteOptions{Sync: g.fsync}); err != nil {
+			failed++
+			g.logger.Warn("写入 NFO 失败",
+				zap.String("path", item.TargetPath),
+				zap.Error(err))
+			g.emit(ctx, item, op, "failed", err.Error())
+			continue
+		}
+		if op == "update" {
+			updated++
+		} else {
+			created++
+		}
+		g.logger.Debug("生成 NFO",
+			zap.String("source", item.SourcePath),
+			zap.String("target", item.TargetPath))
+		g.emit(ctx, item, op, "success", "")
+	}
+	return created, updated, failed
+}
+
+func (g *NFOGenerator) emit(ctx context.Context, item *NFOItem, op, status, errMsg string) {
+	if g.eventSink == nil {
+		return
+	}
+	g.eventSink.OnMetaEvent(ctx, MetaEvent{
+		Op:           op,
+		Status:       status,
+		SourcePath:   item.SourcePath,
+		TargetPath:   item.TargetPath,
+		ErrorMessage: errMsg,
+		MediaItem:    item.MediaItem,
+	})
+}
+
+// nfoVideo 视频流信息（Kodi fileinfo/streamdetails/video）
+type nfoVideo struct {
+	Width  int `xml:"width,omitempty"`
+	Height int `xml:"height,omitempty"`
+}
+
+type nfoFileInfo struct {
+	Video nfoVideo `xml:"streamdetails>video"`
+}
+
+type movieNFO struct {
+	XMLName  xml.Name     `xml:"movie"`
+	Title    string       `xml:"title"`
+	Year     int          `xml:"year,omitempty"`
+	FileInfo *nfoFileInfo `xml:"fileinfo,omitempty"`
+	Path     string       `xml:"filenameandpath"`
+	Size     int64        `xml:"filesize,omitempty"`
+}
+
+type episodeNFO struct {
+	XMLName   xml.Name     `xml:"episodedetails"`
+	ShowTitle string       `xml:"showtitle,omitempty"`
+	Season    int          `xml:"season"`
+	Episode   int          `xml:"episode"`
+	Year      int          `xml:"year,omitempty"`
+	FileInfo  *nfoFileInfo `xml:"fileinfo,omitempty"`
+	Path      string       `xml:"filenameandpath"`
+	Size      int64        `xml:"filesize,omitempty"`
+}
+
+// RenderNFO 根据文件名解析结果生成 NFO 内容
+func RenderNFO(info medianame.Info, sourcePath string, size int64) ([]byte, error) {
+	var fileInfo *nfoFileInfo
+	if width, height := resolutionSize(info.Resolution); width > 0 {
+		fileInfo = &nfoFileInfo{Video: nfoVideo{Width: width, Height: height}}
+	}
+
+	var doc any
+	if info.IsEpisode() {
+		doc = episodeNFO{
+			ShowTitle: info.Title,
+			Season:    info.Season,
+			Episode:   info.Episode,
+			Year:      info.Year,
+			FileInfo:  fileInfo,
+			Path:      sourcePath,
+			Size:      size,
+		}
+	} else {
+		doc = movieNFO{
+			Title:    info.Title,
+			Year:     info.Year,
+			FileInfo: fileInfo,
+			Path:     sourcePath,
+			Size:     size,
+		}
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal nfo: %w", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	buf.WriteString(generatedNFOMarker + "\n")
+	buf.Write(body)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// resolutionSize 分辨率标签对应的画面尺寸
+func resolutionSize(resolution string) (int, int) {
+	switch resolution {
+	case "2160p":
+		return 3840, 2160
+	case "1080p", "1080i":
+		return 1920, 1080
+	case "720p":
+		return 1280, 720
+	case "576p":
+		return 720, 576
+	case "480p":
+		return 720, 480
+	default:
+		return 0, 0
+	}
+}
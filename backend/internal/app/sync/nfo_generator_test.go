@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/strmsync/strmsync/internal/pkg/medianame"
+	"go.uber.org/zap"
+)
+
+func TestRenderNFO_MovieAndEpisode(t *testing.T) {
+	movie, err := RenderNFO(medianame.Parse("Inception.2010.1080p.mkv"), "/movies/Inception.2010.1080p.mkv", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		generatedNFOMarker,
+		"<movie>", "<title>Inception</title>", "<year>2010</year>",
+		"<width>1920</width>", "<filenameandpath>/movies/Inception.2010.1080p.mkv</filenameandpath>",
+		"<filesize>1024</filesize>",
+	} {
+		if !strings.Contains(string(movie), want) {
+			t.Errorf("movie nfo missing %q:\n%s", want, movie)
+		}
+	}
+
+	episode, err := RenderNFO(medianame.Parse("Show.S02E05.mkv"), "/tv/Show.S02E05.mkv", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<episodedetails>", "<showtitle>Show</showtitle>", "<season>2</season>", "<episode>5</episode>"} {
+		if !strings.Contains(string(episode), want) {
+			t.Errorf("episode nfo missing %q:\n%s", want, episode)
+		}
+	}
+	if strings.Contains(string(episode), "<fileinfo>") {
+		t.Errorf("unknown resolution should omit fileinfo:\n%s", episode)
+	}
+}
+
+func TestNFOGenerator_KeepsForeignNFO(t *testing.T) {
+	root := t.TempDir()
+	foreign := filepath.Join(root, "a.nfo")
+	if err := os.WriteFile(foreign, []byte("<movie><title>Custom</title></movie>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	items := []NFOItem{
+		{SourcePath: "/a.mkv", TargetPath: foreign},
+		{SourcePath: "/b.2019.mkv", TargetPath: filepath.Join(root, "b.2019.nfo"), Size: 10},
+	}
+	sink := &recordingSink{}
+	g := NewNFOGenerator(zap.NewNop(), WithNFOEventSink(sink))
+
+	created, updated, failed := g.Generate(context.Background(), items)
+	if created != 1 || updated != 0 || failed != 0 {
+		t.Fatalf("unexpected result created=%d updated=%d failed=%d", created, updated, failed)
+	}
+	if data, _ := os.ReadFile(foreign); !strings.Contains(string(data), "Custom") {
+		t.Fatalf("foreign nfo overwritten: %s", data)
+	}
+
+	// 源文件大小变化时更新生成的 NFO，未变化时跳过
+	items[1].Size = 20
+	if created, updated, _ := g.Generate(context.Background(), items); created != 0 || updated != 1 {
+		t.Fatalf("expected update, got created=%d updated=%d", created, updated)
+	}
+	if created, updated, _ := g.Generate(context.Background(), items); created != 0 || updated != 0 {
+		t.Fatalf("expected unchanged, got created=%d updated=%d", created, updated)
+	}
+	last := sink.events[len(sink.events)-1]
+	if last.Op != "skip" || last.ErrorMessage != "unchanged" {
+		t.Fatalf("unexpected last event %+v", last)
+	}
+}
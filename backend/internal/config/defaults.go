@@ -5,6 +5,7 @@ package config
 const (
 	DefaultServerPort              = 6786
 	DefaultServerHost              = "0.0.0.0"
+	DefaultDBDriver                = "sqlite"
 	DefaultDBPath                  = "data.db"
 	DefaultLogLevel                = "info"
 	DefaultLogPath                 = "logs"
@@ -15,6 +16,8 @@ const (
 	DefaultLogRotateMaxBackups     = 7
 	DefaultLogRotateMaxAgeDays     = 30
 	DefaultLogRotateCompress       = true
+	DefaultLogRetentionDays        = 30
+	DefaultLogRetentionMaxRows     = 500000
 	DefaultEncryptionKey           = ""
 	DefaultScannerConcurrency      = 20
 	DefaultScannerBatchSize        = 500
@@ -27,6 +30,31 @@ const (
 	DefaultNotifierRetryBaseMs     = 1000
 	DefaultNotifierDebounceSeconds = 5
 	DefaultNotifierScope           = "global"
+
+	DefaultHealthMaxPendingTasks           = 0
+	DefaultHealthMaxPendingAgeSeconds      = 0
+	DefaultHealthServerCheckTTLSeconds     = 60
+	DefaultHealthServerCheckTimeoutSeconds = 5
+	DefaultHealthRequireDataServers        = false
+	DefaultHealthRequireMediaServers       = false
+
+	DefaultWorkerGroup        = ""
+	DefaultWorkerConcurrency  = 4
+	DefaultWorkerLeaseSeconds = 30
+	DefaultWriteFsync         = false
+
+	DefaultBackupEnabled   = false
+	DefaultBackupDirName   = "backups"
+	DefaultBackupCron      = "0 3 * * *"
+	DefaultBackupKeepCount = 7
+	DefaultBackupKeepDays  = 30
+	DefaultBackupCompress  = true
+	DefaultBackupEncrypt   = false
+
+	DefaultRunRetentionPerJob         = 200
+	DefaultRunRetentionEventDays      = 30
+	DefaultRunRetentionKeepLastFailed = true
+	DefaultRunRetentionBatchSize      = 500
 )
 
 var defaultMediaExtensions = []string{
@@ -25,6 +25,7 @@ type DataServer struct {
 	APIRate             int       `gorm:"not null;default:0" json:"api_rate"`               // 接口速率（每秒请求数，0=使用全局）
 	APIRetryMax         int       `gorm:"not null;default:0" json:"api_retry_max"`          // 接口重试次数（0=使用全局）
 	APIRetryIntervalSec int       `gorm:"not null;default:0" json:"api_retry_interval_sec"` // 接口重试间隔（秒，0=使用全局）
+	MaxConcurrentRuns   int       `gorm:"not null;default:0" json:"max_concurrent_runs"`    // 同时执行的任务数上限（0=默认1）
 	CreatedAt           time.Time `json:"created_at"`                                       // 创建时间
 	UpdatedAt           time.Time `json:"updated_at"`                                       // 更新时间
 }
@@ -53,28 +54,32 @@ type MediaServer struct {
 // Job 任务配置模型
 // 用于配置STRM生成任务的所有参数
 type Job struct {
-	ID            uint       `gorm:"primaryKey" json:"id"`
-	Name          string     `gorm:"uniqueIndex;not null" json:"name"`                      // 任务名称（唯一）
-	Enabled       bool       `gorm:"not null;default:true" json:"enabled"`                  // 是否启用
-	Cron          string     `gorm:"type:text" json:"cron"`                                 // Cron表达式(可选,用于定时调度)
-	WatchMode     string     `gorm:"not null;default:'local'" json:"watch_mode"`            // 监控模式: local/api
-	SourcePath    string     `gorm:"not null" json:"source_path"`                           // 监控目录
-	RemoteRoot    string     `gorm:"type:text" json:"remote_root"`                          // 远程根目录（CD2/OpenList，API起点）
-	TargetPath    string     `gorm:"not null" json:"target_path"`                           // 目的目录(STRM输出)
-	STRMPath      string     `gorm:"not null" json:"strm_path"`                             // STRM文件内容路径
-	DataServerID  *uint      `gorm:"index" json:"data_server_id"`                           // 数据服务器ID(可空)
-	MediaServerID *uint      `gorm:"index" json:"media_server_id"`                          // 媒体服务器ID(可空)
-	Options       string     `gorm:"type:text" json:"options"`                              // JSON扩展选项
-	Status        string     `gorm:"default:'idle'" json:"status"`                          // 状态: idle/running/error
-	LastRunAt     *time.Time `json:"last_run_at"`                                           // 最后执行时间
-	ErrorMessage  string     `gorm:"type:text" json:"error_message"`                        // 错误信息
-	CreatedAt     time.Time  `gorm:"index:idx_jobs_created_at,sort:desc" json:"created_at"` // 创建时间
-	UpdatedAt     time.Time  `json:"updated_at"`                                            // 更新时间
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Name              string     `gorm:"uniqueIndex;not null" json:"name"`                      // 任务名称（唯一）
+	Enabled           bool       `gorm:"not null;default:true" json:"enabled"`                  // 是否启用
+	Cron              string     `gorm:"type:text" json:"cron"`                                 // Cron表达式(可选,用于定时调度)
+	WatchMode         string     `gorm:"not null;default:'local'" json:"watch_mode"`            // 监控模式: local/api
+	SourcePath        string     `gorm:"not null" json:"source_path"`                           // 监控目录
+	RemoteRoot        string     `gorm:"type:text" json:"remote_root"`                          // 远程根目录（CD2/OpenList，API起点）
+	TargetPath        string     `gorm:"not null" json:"target_path"`                           // 目的目录(STRM输出)
+	STRMPath          string     `gorm:"not null" json:"strm_path"`                             // STRM文件内容路径
+	DataServerID      *uint      `gorm:"index" json:"data_server_id"`                           // 数据服务器ID(可空)
+	MediaServerID     *uint      `gorm:"index" json:"media_server_id"`                          // 媒体服务器ID(可空)
+	Options           string     `gorm:"type:text" json:"options"`                              // JSON扩展选项
+	MaxConcurrentRuns int        `gorm:"not null;default:0" json:"max_concurrent_runs"`         // 同时执行的数量上限（0=默认1）
+	WorkerGroup       string     `gorm:"size:64;not null;default:''" json:"worker_group"`       // 执行的Worker分组（空=任意Worker）
+	Status            string     `gorm:"default:'idle'" json:"status"`                          // 状态: idle/running/error
+	LastRunAt         *time.Time `json:"last_run_at"`                                           // 最后执行时间
+	ErrorMessage      string     `gorm:"type:text" json:"error_message"`                        // 错误信息
+	CreatedAt         time.Time  `gorm:"index:idx_jobs_created_at,sort:desc" json:"created_at"` // 创建时间
+	UpdatedAt         time.Time  `json:"updated_at"`                                            // 更新时间
 
 	// 关联关系
-	DataServer  *DataServer  `gorm:"foreignKey:DataServerID" json:"data_server,omitempty"`                    // 关联的数据服务器
-	MediaServer *MediaServer `gorm:"foreignKey:MediaServerID" json:"media_server,omitempty"`                  // 关联的媒体服务器
-	TaskRuns    []TaskRun    `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"task_runs,omitempty"` // 执行记录列表
+	DataServer  *DataServer    `gorm:"foreignKey:DataServerID" json:"data_server,omitempty"`                    // 关联的数据服务器
+	MediaServer *MediaServer   `gorm:"foreignKey:MediaServerID" json:"media_server,omitempty"`                  // 关联的媒体服务器
+	TaskRuns    []TaskRun      `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"task_runs,omitempty"` // 执行记录列表
+	MetaHashes  []MetaFileHash `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"-"`                   // 元数据哈希记录
+	Mappings    []StrmMapping  `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"-"`                   // 输出布局映射
 }
 
 // TaskRun 任务执行记录模型
@@ -87,6 +92,7 @@ type Job struct {
 // - MaxAttempts: 最大重试次数
 // - DedupKey: 去重键（唯一索引，防止重复入队）
 // - WorkerID: 执行的 Worker ID
+// - LeaseExpiresAt: 执行租约到期时间（过期后由其他 Worker 回收）
 // - FailureKind: 失败类型（retryable/permanent/cancelled）
 type TaskRun struct {
 	ID                 uint       `gorm:"primaryKey" json:"id"`
@@ -98,6 +104,7 @@ type TaskRun struct {
 	MaxAttempts        int        `gorm:"not null;default:3" json:"max_attempts"`                                                      // 最大重试次数
 	DedupKey           string     `gorm:"uniqueIndex;not null" json:"dedup_key"`                                                       // 去重键
 	WorkerID           string     `gorm:"index" json:"worker_id"`                                                                      // 执行的Worker ID
+	LeaseExpiresAt     *time.Time `gorm:"index" json:"lease_expires_at"`                                                               // 执行租约到期时间（Worker 心跳续期）
 	FailureKind        string     `gorm:"index" json:"failure_kind"`                                                                   // 失败类型: retryable/permanent/cancelled
 	StartedAt          time.Time  `gorm:"index" json:"started_at"`                                                                     // 开始时间
 	EndedAt            *time.Time `json:"ended_at"`                                                                                    // 结束时间
@@ -110,13 +117,17 @@ type TaskRun struct {
 	UpdatedFiles       int        `gorm:"default:0" json:"updated_files"`                                                              // 更新STRM数
 	SkippedFiles       int        `gorm:"default:0" json:"skipped_files"`                                                              // 跳过STRM数
 	FilteredFiles      int        `gorm:"default:0" json:"filtered_files"`                                                             // 过滤文件数
+	MovedFiles         int        `gorm:"default:0" json:"moved_files"`                                                                // 因远端移动/重命名而移动的STRM数
 	MetaTotalFiles     int        `gorm:"default:0" json:"meta_total_files"`                                                           // 元数据总数
 	MetaCreatedFiles   int        `gorm:"default:0" json:"meta_created_files"`                                                         // 元数据新建
 	MetaUpdatedFiles   int        `gorm:"default:0" json:"meta_updated_files"`                                                         // 元数据更新
 	MetaProcessedFiles int        `gorm:"default:0" json:"meta_processed_files"`                                                       // 元数据已处理
 	MetaFailedFiles    int        `gorm:"default:0" json:"meta_failed_files"`                                                          // 元数据失败
+	MetaBytes          int64      `gorm:"default:0" json:"meta_bytes"`                                                                 // 元数据传输字节数（复制/下载）
+	APIErrors          int        `gorm:"default:0" json:"api_errors"`                                                                 // 数据服务器API调用失败次数
 	ErrorMessage       string     `gorm:"type:text" json:"error_message"`                                                              // 错误信息
 	Payload            string     `gorm:"type:text" json:"payload"`                                                                    // JSON执行参数
+	Checkpoint         string     `gorm:"type:text" json:"checkpoint"`                                                                 // JSON同步断点（用于断点续传）
 
 	// 关联关系
 	Job *Job `gorm:"foreignKey:JobID" json:"job,omitempty"` // 关联的任务
@@ -129,14 +140,84 @@ type TaskRunEvent struct {
 	TaskRunID    uint      `gorm:"index;not null" json:"task_run_id"`
 	JobID        uint      `gorm:"index;not null" json:"job_id"`
 	Kind         string    `gorm:"index;not null" json:"kind"`   // strm/meta
-	Op           string    `gorm:"index;not null" json:"op"`     // create/update/delete/copy/skip
+	Op           string    `gorm:"index;not null" json:"op"`     // create/update/delete/copy/skip/move
 	Status       string    `gorm:"index;not null" json:"status"` // success/failed/skipped
 	SourcePath   string    `gorm:"type:text" json:"source_path"`
 	TargetPath   string    `gorm:"type:text" json:"target_path"`
 	ErrorMessage string    `gorm:"type:text" json:"error_message"`
+	MediaItem    string    `gorm:"index" json:"media_item"`        // 所属媒体条目（视频路径去掉扩展名）
+	PreviousPath string    `gorm:"type:text" json:"previous_path"` // 移动前的路径（仅 move 事件）
+	Bytes        int64     `gorm:"default:0" json:"bytes"`         // 传输字节数（仅元数据复制/下载成功事件）
 	CreatedAt    time.Time `gorm:"index" json:"created_at"`
 }
 
+// TaskRunRollup 执行记录统计汇总
+// 按任务与时间桶（小时/天）汇总已结束的执行记录，用于趋势查询；
+// 原始执行记录被清理后仍保留汇总数据
+type TaskRunRollup struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	JobID        uint      `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:1" json:"job_id"`       // 关联任务ID
+	Granularity  string    `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:2" json:"-"`            // 时间粒度: hour/day
+	BucketStart  time.Time `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:3;index" json:"bucket"` // 时间桶起点（本地时区）
+	Runs         int64     `gorm:"default:0" json:"runs"`                                                           // 已结束的执行次数
+	Completed    int64     `gorm:"default:0" json:"completed"`                                                      // 成功次数
+	Failed       int64     `gorm:"default:0" json:"failed"`                                                         // 失败次数
+	Cancelled    int64     `gorm:"default:0" json:"cancelled"`                                                      // 取消次数
+	DurationP50  int64     `gorm:"default:0" json:"duration_p50"`                                                   // 执行时长中位数(秒)
+	DurationP95  int64     `gorm:"default:0" json:"duration_p95"`                                                   // 执行时长P95(秒)
+	CreatedFiles int64     `gorm:"default:0" json:"created_files"`                                                  // 新建STRM数
+	UpdatedFiles int64     `gorm:"default:0" json:"updated_files"`                                                  // 更新STRM数
+	DeletedFiles int64     `gorm:"default:0" json:"deleted_files"`                                                  // 删除STRM数
+	FailedFiles  int64     `gorm:"default:0" json:"failed_files"`                                                   // 失败文件数
+	MetaBytes    int64     `gorm:"default:0" json:"meta_bytes"`                                                     // 元数据传输字节数
+	APIErrors    int64     `gorm:"default:0" json:"api_errors"`                                                     // 数据服务器API调用失败次数
+	UpdatedAt    time.Time `json:"-"`                                                                               // 汇总时间
+}
+
+// MetaFileHash 元数据文件哈希记录
+// 记录目标元数据文件最近一次同步时对应的源内容哈希，用于基于内容的变更检测
+//
+// Size/ModTime 为写入完成后目标文件的状态，用于发现目标文件被外部修改
+type MetaFileHash struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      uint      `gorm:"not null;uniqueIndex:idx_meta_file_hashes_job_target,priority:1" json:"job_id"`      // 关联任务ID
+	TargetPath string    `gorm:"not null;uniqueIndex:idx_meta_file_hashes_job_target,priority:2" json:"target_path"` // 目标文件路径
+	SourceHash string    `gorm:"not null" json:"source_hash"`                                                        // 源文件哈希（算法:值）
+	Size       int64     `gorm:"default:0" json:"size"`                                                              // 目标文件大小
+	ModTime    time.Time `json:"mod_time"`                                                                           // 目标文件修改时间
+	UpdatedAt  time.Time `json:"updated_at"`                                                                         // 更新时间
+}
+
+// StrmMapping 远端文件与 STRM 的对应关系
+// 记录远端文件对应的 STRM 输出路径（媒体布局据此使重命名后的远端文件沿用原输出路径，
+// 并在下次同步时保持冲突处理结果稳定），以及移动检测使用的远端文件签名
+type StrmMapping struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      uint      `gorm:"not null;uniqueIndex:idx_strm_mappings_job_source,priority:1" json:"job_id"`      // 关联任务ID
+	SourcePath string    `gorm:"not null;uniqueIndex:idx_strm_mappings_job_source,priority:2" json:"source_path"` // 远端文件路径
+	OutputPath string    `gorm:"not null" json:"output_path"`                                                     // 输出相对路径（不含 .strm 扩展名）
+	Size       int64     `gorm:"default:0" json:"size"`                                                           // 远端文件大小（移动检测签名）
+	ModTime    time.Time `json:"mod_time"`                                                                        // 远端修改时间（移动检测签名）
+	FileID     string    `json:"file_id"`                                                                         // 远端文件稳定标识（PickCode 或内容哈希）
+	UpdatedAt  time.Time `json:"updated_at"`                                                                      // 更新时间
+}
+
+// WorkerNode Worker 实例注册信息
+// 多个实例共享同一数据库时，每个实例的 Worker 池定期写入心跳；
+// 心跳超过 ExpiresAt 未更新视为离线
+type WorkerNode struct {
+	ID          string     `gorm:"primaryKey;size:128" json:"id"`                  // Worker ID
+	Group       string     `gorm:"column:worker_group;size:64;index" json:"group"` // Worker 分组（领取同分组或未分组的任务）
+	Hostname    string     `json:"hostname"`                                       // 主机名
+	Concurrency int        `gorm:"default:0" json:"concurrency"`                   // 并发数
+	Reserved    int        `gorm:"default:0" json:"reserved"`                      // 为高优先级任务预留的槽位数
+	InFlight    int        `gorm:"default:0" json:"in_flight"`                     // 正在执行的任务数
+	StartedAt   time.Time  `json:"started_at"`                                     // 启动时间
+	HeartbeatAt time.Time  `json:"heartbeat_at"`                                   // 最近心跳时间
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at"`                        // 心跳有效期
+	StoppedAt   *time.Time `json:"stopped_at"`                                     // 正常停止时间
+}
+
 // LogEntry 日志记录模型
 type LogEntry struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
@@ -146,6 +227,7 @@ type LogEntry struct {
 	RequestID  *string   `gorm:"index" json:"request_id,omitempty"`                                  // 请求ID
 	UserAction *string   `gorm:"index" json:"user_action,omitempty"`                                 // 用户操作
 	JobID      *uint     `gorm:"index" json:"job_id,omitempty"`                                      // 关联的任务ID
+	RunID      *uint     `gorm:"index" json:"run_id,omitempty"`                                      // 关联的执行记录ID（TaskRun）
 	CreatedAt  time.Time `gorm:"index:idx_logs_level_created_at,priority:2;index" json:"created_at"` // 创建时间
 }
 
@@ -171,6 +253,9 @@ func (MediaServer) TableName() string  { return "media_servers" }
 func (Job) TableName() string          { return "jobs" }
 func (TaskRun) TableName() string      { return "task_runs" }
 func (TaskRunEvent) TableName() string { return "task_run_events" }
+func (MetaFileHash) TableName() string { return "meta_file_hashes" }
+func (StrmMapping) TableName() string  { return "strm_mappings" }
+func (WorkerNode) TableName() string   { return "worker_nodes" }
 func (LogEntry) TableName() string     { return "logs" }
 func (Setting) TableName() string      { return "settings" }
 
@@ -6,6 +6,7 @@
 //
 //   - DataServerRepository: 数据服务器仓储接口
 //   - JobRepository: Job 仓储接口
+//   - LogRepository: 日志仓储接口
 //
 // # 设计原则
 //
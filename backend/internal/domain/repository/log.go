@@ -0,0 +1,33 @@
+// Package repository 定义领域层的Repository接口
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+)
+
+// LogFilter 日志查询条件（零值字段表示不过滤）
+type LogFilter struct {
+	Levels    []string  // 日志级别（任一匹配）
+	Module    string    // 模块名称
+	JobID     *uint     // 关联的任务ID
+	RunID     *uint     // 关联的执行记录ID
+	RequestID string    // 请求ID
+	Search    string    // 消息全文搜索关键字（空格分隔，需全部命中）
+	StartAt   time.Time // 起始时间（含）
+	EndAt     time.Time // 结束时间（含）
+}
+
+// LogRepository 日志仓储接口
+//
+// 日志按 ID 单调递增写入，ID 同时作为游标：
+// List 按 ID 降序翻页（beforeID 之前），ListAfter 按 ID 升序追踪新日志（afterID 之后）。
+type LogRepository interface {
+	List(ctx context.Context, filter LogFilter, beforeID uint, offset, limit int) ([]model.LogEntry, error)
+	Count(ctx context.Context, filter LogFilter) (int64, error)
+	ListAfter(ctx context.Context, filter LogFilter, afterID uint, limit int) ([]model.LogEntry, error)
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	TrimToLimit(ctx context.Context, maxRows int64) (int64, error)
+}
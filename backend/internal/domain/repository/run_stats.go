@@ -0,0 +1,40 @@
+// Package repository 定义领域层的Repository接口
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+)
+
+// 趋势统计的时间粒度
+const (
+	RunTrendHour = "hour"
+	RunTrendDay  = "day"
+)
+
+// RunTrendFilter 执行趋势查询条件
+type RunTrendFilter struct {
+	Granularity string    // 时间粒度: hour/day
+	JobID       *uint     // 关联的任务ID（为空表示全部任务）
+	From        time.Time // 起始时间（含）
+	To          time.Time // 结束时间（不含）
+}
+
+// RunStatsRepository 执行统计仓储接口
+//
+// 趋势按任务与时间桶汇总已结束的执行记录（以开始时间归桶）。
+// 已汇总的时间桶从 task_run_rollups 读取，其余从 task_runs 与 task_run_events 实时计算。
+type RunStatsRepository interface {
+	Trends(ctx context.Context, filter RunTrendFilter) ([]model.TaskRunRollup, error)
+	Rollup(ctx context.Context, now time.Time) (int, error)
+}
+
+// RunRetentionPolicy 执行历史保留策略
+// 值为 0 表示不按该条件清理；执行中与等待执行的记录始终保留
+type RunRetentionPolicy struct {
+	KeepPerJob     int  // 每个任务保留最近的已结束执行记录数
+	EventDays      int  // 事件明细保留天数
+	KeepLastFailed bool // 始终保留每个任务最近一次失败的执行记录
+}
@@ -0,0 +1,180 @@
+// Package syncengine 提供 STRM 同步引擎实现
+package syncengine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultCheckpointInterval 默认断点保存间隔（按已完成文件数）
+const defaultCheckpointInterval = 100
+
+// resumeCheckpoint 返回对本次同步生效的断点（无效时返回 nil）
+func (e *Engine) resumeCheckpoint(remoteRoot string) *SyncCheckpoint {
+	cp := e.opts.ResumeFrom
+	if cp == nil || cp.IsZero() {
+		return nil
+	}
+	if cp.RemoteRoot != remoteRoot {
+		e.logger.Warn("断点远端根路径不一致，忽略断点",
+			zap.String("checkpoint_root", cp.RemoteRoot),
+			zap.String("remote_root", remoteRoot))
+		return nil
+	}
+	e.logger.Info("从断点恢复同步",
+		zap.String("last_path", cp.LastPath),
+		zap.Int64("processed_files", cp.ProcessedFiles))
+	return cp
+}
+
+// checkpointTracker 追踪已完成文件的连续前缀并定期保存断点
+//
+// 文件按遍历顺序编号后并发处理，完成顺序不确定；只有当某个编号之前的
+// 所有文件都已完成时，断点才会推进到该位置。处理失败的文件冻结断点：
+// 断点停在第一个失败的文件之前，恢复时从它开始重新处理。追踪器只保留尚未结束的
+// 文件路径，内存占用与在途文件数成正比，而不是与目录树规模成正比。
+type checkpointTracker struct {
+	sink       CheckpointSink
+	remoteRoot string
+	interval   int
+
+	mu        sync.Mutex
+	skipped   int64          // 因断点续传跳过的文件数
+	paths     map[int]string // 已编号但尚未结束的文件路径
+	done      map[int]bool   // 已结束的文件（true 成功，false 失败）
+	added     int            // 已编号的文件数
+	next      int            // 第一个未结束文件的编号
+	frozen    bool           // 已越过失败的文件，断点不再推进
+	prefix    int            // 断点覆盖的文件数
+	lastPath  string         // 断点覆盖的最后一个路径
+	lastSaved int            // 上次保存时的 prefix
+}
+
+// newCheckpointTracker 创建断点追踪器；未配置 CheckpointSink 时返回 nil
+func (e *Engine) newCheckpointTracker(remoteRoot string) *checkpointTracker {
+	if e.opts.CheckpointSink == nil {
+		return nil
+	}
+	interval := e.opts.CheckpointInterval
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+	return &checkpointTracker{
+		sink:       e.opts.CheckpointSink,
+		remoteRoot: remoteRoot,
+		interval:   interval,
+		paths:      make(map[int]string),
+		done:       make(map[int]bool),
+	}
+}
+
+// skip 记录一个因断点续传跳过的文件
+func (t *checkpointTracker) skip() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.skipped++
+	t.mu.Unlock()
+}
+
+// add 为待处理文件编号，返回编号
+func (t *checkpointTracker) add(path string) int {
+	if t == nil {
+		return -1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	index := t.added
+	t.paths[index] = path
+	t.added++
+	return index
+}
+
+// markDone 标记编号为 index 的文件已完成，并在前缀推进足够多时保存断点
+func (t *checkpointTracker) markDone(ctx context.Context, index int) {
+	if t == nil || index < 0 {
+		return
+	}
+
+	t.mu.Lock()
+	t.done[index] = true
+	t.advanceLocked()
+	var (
+		cp   SyncCheckpoint
+		save bool
+	)
+	if t.prefix-t.lastSaved >= t.interval {
+		cp = t.snapshotLocked()
+		t.lastSaved = t.prefix
+		save = true
+	}
+	t.mu.Unlock()
+
+	if save {
+		t.sink.SaveCheckpoint(ctx, cp)
+	}
+}
+
+// markFailed 标记编号为 index 的文件处理失败，断点不会越过它
+func (t *checkpointTracker) markFailed(index int) {
+	if t == nil || index < 0 {
+		return
+	}
+	t.mu.Lock()
+	t.done[index] = false
+	t.advanceLocked()
+	t.mu.Unlock()
+}
+
+// advanceLocked 越过已结束的连续前缀（调用方需持有锁）
+func (t *checkpointTracker) advanceLocked() {
+	for {
+		ok, ended := t.done[t.next]
+		if !ended {
+			return
+		}
+		if !ok {
+			t.frozen = true
+		}
+		if !t.frozen {
+			t.lastPath = t.paths[t.next]
+			t.prefix++
+		}
+		delete(t.done, t.next)
+		delete(t.paths, t.next)
+		t.next++
+	}
+}
+
+// flush 保存当前断点（处理结束或被取消时调用）
+func (t *checkpointTracker) flush(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if t.prefix == 0 || t.prefix == t.lastSaved {
+		t.mu.Unlock()
+		return
+	}
+	cp := t.snapshotLocked()
+	t.lastSaved = t.prefix
+	t.mu.Unlock()
+
+	// 取消后仍需落盘断点，使用不可取消的 context
+	t.sink.SaveCheckpoint(context.WithoutCancel(ctx), cp)
+}
+
+// snapshotLocked 生成当前断点（调用方需持有锁）
+func (t *checkpointTracker) snapshotLocked() SyncCheckpoint {
+	return SyncCheckpoint{
+		RemoteRoot:     t.remoteRoot,
+		LastPath:       t.lastPath,
+		ProcessedFiles: t.skipped + int64(t.prefix),
+		UpdatedAt:      time.Now(),
+	}
+}
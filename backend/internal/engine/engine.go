@@ -7,12 +7,13 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
 	"go.uber.org/zap"
 )
 
@@ -46,6 +47,9 @@ func (e *Engine) emitStrmEvent(ctx context.Context, event StrmEvent) {
 	if e == nil || e.opts.EventSink == nil {
 		return
 	}
+	if event.MediaItem == "" && event.SourcePath != "" {
+		event.MediaItem = MediaItemKey(event.SourcePath)
+	}
 	e.opts.EventSink.OnStrmEvent(ctx, event)
 }
 
@@ -54,9 +58,9 @@ func (e *Engine) emitStrmEvent(ctx context.Context, event StrmEvent) {
 // 注意：此接口是 syncengine 内部定义，与 strmwriter.StrmWriter 功能相同
 // 未来可以统一，当前为了避免循环依赖而分开定义
 //
-// 重要约束：Writer 实现必须对应本地文件系统，因为 Engine 会使用 os.Stat
-// 来获取文件的 ModTime 以进行增量更新判定。如果未来需要支持非本地 Writer
-// （如 S3、远程文件系统），需要扩展 Writer 接口增加 Stat 方法。
+// 引擎对输出目录的所有访问（增量判定、孤儿清理、移动检测、附属文件清理）
+// 都通过 Writer 完成，不直接访问本地文件系统，因此 Writer 可以对应远程存储（如 WebDAV）。
+// 路径统一为 OutputRoot 之下的路径，由实现映射到实际存储位置。
 type Writer interface {
 	// Read 读取 STRM 文件内容
 	Read(ctx context.Context, path string) (string, error)
@@ -66,6 +70,28 @@ type Writer interface {
 
 	// Delete 删除 STRM 文件
 	Delete(ctx context.Context, path string) error
+
+	// Stat 获取文件或目录的元信息
+	//
+	// 文件不存在时返回可被 errors.Is(err, fs.ErrNotExist) 识别的错误。
+	// 无法保留修改时间的实现返回零值 ModTime，引擎将仅按内容判定是否更新。
+	Stat(ctx context.Context, path string) (fs.FileInfo, error)
+
+	// Walk 遍历 root 下的文件和目录，语义与 filepath.WalkDir 相同
+	//
+	// root 不存在时以 fs.ErrNotExist 调用一次 fn；fn 返回 fs.SkipDir/fs.SkipAll 时跳过目录/终止遍历。
+	Walk(ctx context.Context, root string, fn fs.WalkDirFunc) error
+}
+
+// DirWriter Writer 的可选能力：目录级操作
+//
+// 引擎通过类型断言检测。未实现时关闭移动检测，且不删除空目录与附属目录（extrafanart 等）。
+type DirWriter interface {
+	// Rename 移动文件或目录，自动创建目标父目录
+	Rename(ctx context.Context, from, to string) error
+
+	// RemoveDir 删除目录；recursive 为 false 时仅删除空目录，目录非空时返回错误
+	RemoveDir(ctx context.Context, path string, recursive bool) error
 }
 
 // NewEngine 创建同步引擎
@@ -112,13 +138,14 @@ func NewEngine(driver Driver, writer Writer, logger *zap.Logger, opts EngineOpti
 // RunOnce 执行一次完整的同步流程
 //
 // 工作流程：
-//  1. 扫描远程文件列表（使用 Driver.List）
-//  2. 过滤文件（根据扩展名）
-//  3. 并发处理每个文件：
+//  1. 流式扫描远程文件（使用 Driver.ListStream，扫描与处理同时进行）
+//  2. 过滤文件（根据扩展名），跳过断点之前已完成的文件
+//  3. 通过有界队列并发处理每个文件（配置 CheckpointSink 时定期保存断点）：
 //     a. 构建 STRM 内容（使用 Driver.BuildStrmInfo）
 //     b. 比对现有内容（使用 Driver.CompareStrm）
 //     c. 写入/更新文件（使用 Writer）
-//  4. 收集统计信息并返回
+//  4. 移动检测（可选）：扫描结束后，将来自远端移动/重命名的新文件转换为本地移动
+//  5. 孤儿清理（可选）并收集统计信息
 //
 // 参数：
 //   - ctx: 上下文，用于取消
@@ -144,32 +171,120 @@ func (e *Engine) RunOnce(ctx context.Context, remotePath string) (SyncStats, err
 		zap.Int("max_concurrency", e.opts.MaxConcurrency),
 		zap.Bool("dry_run", e.opts.DryRun))
 
-	// 步骤1: 扫描远程文件列表
-	entries, err := e.scanRemoteFiles(ctx, remotePath)
-	if err != nil {
-		return stats, fmt.Errorf("扫描远程文件失败: %w", err)
+	// 步骤1-4: 流式扫描远程文件，边扫描边过滤、处理（扫描与处理并行）
+	resume := e.resumeCheckpoint(remotePath)
+	tracker := e.newCheckpointTracker(remotePath)
+
+	// 移动检测：扫描前按记录的签名索引本地 STRM
+	if e.opts.MoveIndex != nil {
+		e.opts.MoveIndex.begin()
+	}
+	var moves *moveDetector
+	if _, ok := e.writer.(DirWriter); e.opts.DetectMoves && !ok {
+		e.logger.Debug("写入器不支持移动，跳过移动检测")
+	} else if e.opts.DetectMoves && e.opts.MoveIndex == nil {
+		e.logger.Debug("未提供签名记录，跳过移动检测")
+	} else if e.opts.DetectMoves {
+		detector, err := e.newMoveDetector(ctx)
+		if err != nil {
+			e.logger.Warn("初始化移动检测失败，按新文件处理", zap.Error(err))
+		} else {
+			moves = detector
+		}
 	}
 
-	e.logger.Info("扫描完成",
-		zap.Int64("total_files", stats.TotalFiles),
-		zap.Int64("total_dirs", stats.TotalDirs))
+	// 孤儿清理、移动检测与签名记录需要完整的远端索引，仅在启用时收集过滤后的文件
+	collectFiles := e.opts.EnableOrphanCleanup || e.opts.MoveIndex != nil
+	var files []RemoteEntry
 
-	// 步骤2: 过滤文件
-	files := e.filterFiles(entries, &stats, remotePath)
-	e.logger.Info("文件过滤完成",
-		zap.Int("matched_files", len(files)),
-		zap.Int64("filtered_files", stats.FilteredFiles))
+	// 输出路径需等完整扫描后确定的文件（见 ClaimingLayout）
+	claims, _ := e.opts.Layout.(ClaimingLayout)
+	var unclaimed []streamItem
 
-	// 步骤3: 并发处理文件
-	if err := e.processFiles(ctx, files, &stats); err != nil {
+	source := func(ctx context.Context, emit func(RemoteEntry) error) error {
+		err := e.scanRemote(ctx, remotePath, func(entry RemoteEntry) error {
+			if !e.acceptEntry(entry, &stats, remotePath) {
+				return nil
+			}
+			// 输出布局：按扫描顺序分配输出路径，同名冲突的归属不受并发处理顺序影响
+			claimed := true
+			if claims != nil {
+				claimed = claims.Claim(entry.Path)
+			} else if e.opts.Layout != nil {
+				e.opts.Layout.OutputPath(entry.Path)
+			}
+			if collectFiles {
+				files = append(files, entry)
+			}
+			// 断点续传：跳过已完成前缀中的文件
+			if resume != nil && ComparePaths(entry.Path, resume.LastPath) <= 0 {
+				atomic.AddInt64(&stats.ResumedFiles, 1)
+				tracker.skip()
+				return nil
+			}
+			// 输出路径被尚未出现的文件占用：扫描结束后再处理
+			if !claimed {
+				unclaimed = append(unclaimed, streamItem{index: tracker.add(entry.Path), entry: entry})
+				return nil
+			}
+			// 可能由移动产生的新文件：扫描结束后再处理
+			if moves != nil && moves.hold(ctx, e, entry, tracker) {
+				return nil
+			}
+			return emit(entry)
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", errScanFailed, err)
+		}
+
+		e.logger.Info("扫描完成",
+			zap.Int64("total_files", atomic.LoadInt64(&stats.TotalFiles)),
+			zap.Int64("total_dirs", atomic.LoadInt64(&stats.TotalDirs)),
+			zap.Int64("filtered_files", atomic.LoadInt64(&stats.FilteredFiles)),
+			zap.Int64("resumed_files", atomic.LoadInt64(&stats.ResumedFiles)))
+		return nil
+	}
+
+	err := e.processStream(ctx, source, &stats, tracker)
+	if err == nil && claims != nil {
+		claims.Settle()
+	}
+
+	// 注意：使用过滤后的文件列表构建索引，确保扩展名过滤规则变化后能清理旧 STRM
+	var remoteIndex map[string]struct{}
+	var idxErr error
+	if err == nil && collectFiles {
+		remoteIndex, idxErr = e.buildRemoteIndex(files)
+		if idxErr != nil {
+			remoteIndex = nil
+		} else if e.opts.MoveIndex != nil {
+			e.opts.MoveIndex.prune()
+		}
+	}
+
+	// 步骤5: 移动检测（可选）与延后分配输出路径的文件
+	if err == nil && moves != nil {
+		if idxErr != nil {
+			e.logger.Warn("构建远端索引失败，跳过移动检测",
+				zap.Error(idxErr))
+		}
+		unclaimed = moves.holdDeferred(ctx, e, unclaimed)
+		err = e.finishMoves(ctx, moves, remoteIndex, &stats, tracker)
+	}
+	if err == nil && len(unclaimed) > 0 {
+		err = e.processDeferred(ctx, unclaimed, &stats, tracker)
+	}
+	tracker.flush(ctx)
+	if err != nil {
+		if errors.Is(err, errScanFailed) {
+			return stats, err
+		}
 		return stats, fmt.Errorf("处理文件失败: %w", err)
 	}
 
-	// 步骤4: 孤儿文件清理（可选）
+	// 步骤6: 孤儿文件清理（可选）
 	if e.opts.EnableOrphanCleanup {
 		e.logger.Info("开始清理孤儿文件")
-		// 注意：使用过滤后的文件列表构建索引，确保扩展名过滤规则变化后能清理旧 STRM
-		remoteIndex, idxErr := e.buildRemoteIndex(files)
 		if idxErr != nil {
 			e.logger.Warn("构建远端索引失败，跳过孤儿清理",
 				zap.Error(idxErr))
@@ -184,7 +299,7 @@ func (e *Engine) RunOnce(ctx context.Context, remotePath string) (SyncStats, err
 		}
 	}
 
-	// 步骤5: 统计完成
+	// 步骤7: 统计完成
 	stats.EndTime = time.Now()
 	stats.Duration = stats.EndTime.Sub(stats.StartTime)
 
@@ -197,6 +312,10 @@ func (e *Engine) RunOnce(ctx context.Context, remotePath string) (SyncStats, err
 		zap.Int64("skipped_unchanged", stats.SkippedUnchanged),
 		zap.Int64("failed", stats.FailedFiles),
 		zap.Int64("deleted_orphans", stats.DeletedOrphans),
+		zap.Int64("deleted_sidecars", stats.DeletedSidecars),
+		zap.Int64("moved", stats.MovedFiles),
+		zap.Int64("moved_sidecars", stats.MovedSidecars),
+		zap.Int64("resumed", stats.ResumedFiles),
 		zap.Duration("duration", stats.Duration))
 
 	return stats, nil
@@ -205,9 +324,11 @@ func (e *Engine) RunOnce(ctx context.Context, remotePath string) (SyncStats, err
 // RunIncremental 仅处理特定事件对应的文件（增量同步）
 //
 // 工作流程：
-// 1. 处理删除事件（计算输出路径并删除）
+// 1. 收集新增/更新事件
 // 2. 过滤新增/更新事件（扩展名过滤）
-// 3. 并发处理文件（复用 processFile）
+// 3. 启用移动检测时，将签名匹配的删除 + 新增事件配对为远端移动
+// 4. 处理删除事件（移动的文件移动 STRM 与附属文件，其余计算输出路径并删除）
+// 5. 并发处理文件（复用 processFile）
 //
 // 参数：
 //   - ctx: 上下文，用于取消
@@ -262,38 +383,61 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 		return "", fmt.Errorf("事件路径为空")
 	}
 
-	// removeEmptyParents 尝试删除空父目录（仅限 OutputRoot 之下）
-	// 注意：这里直接使用 os.Remove 而不是 writer.Delete，因为 writer.Delete 专用于文件
-	removeEmptyParents := func(outputPath string) {
-		rootAbs, err := filepath.Abs(e.opts.OutputRoot)
-		if err != nil {
-			return
+	// 步骤1: 收集新增/更新事件并转换为 RemoteEntry
+	entries := make([]RemoteEntry, 0, len(events))
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
 		}
-		dir := filepath.Dir(outputPath)
-		for {
-			absDir, err := filepath.Abs(dir)
-			if err != nil {
-				return
+		switch event.Type {
+		case DriverEventCreate, DriverEventUpdate:
+			if event.IsDir {
+				atomic.AddInt64(&stats.TotalDirs, 1)
+				continue
 			}
-			// 已到达或超出 OutputRoot，停止
-			rel, err := filepath.Rel(rootAbs, absDir)
+			path, err := resolveEventPath(event)
 			if err != nil {
-				return
+				atomic.AddInt64(&stats.FailedFiles, 1)
+				appendError("", err)
+				continue
 			}
-			if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
-				return
+			entries = append(entries, RemoteEntry{
+				Path:    path,
+				Name:    filepath.Base(path),
+				Size:    event.Size,
+				ModTime: event.ModTime,
+				IsDir:   false,
+			})
+		case DriverEventDelete:
+			continue
+		default:
+			atomic.AddInt64(&stats.FailedFiles, 1)
+			appendError("", fmt.Errorf("未处理的事件类型: %s", event.Type.String()))
+		}
+	}
+
+	// 步骤2: 过滤文件（按扩展名）
+	files := e.filterFiles(entries, &stats, "")
+	e.logger.Info("增量文件过滤完成",
+		zap.Int("matched_files", len(files)),
+		zap.Int64("filtered_files", stats.FilteredFiles))
+
+	// 步骤3: 同一批事件中签名匹配的删除 + 新增视为远端移动（见 pairRenames）
+	var renames map[string]RemoteEntry
+	if _, ok := e.writer.(DirWriter); ok && e.opts.DetectMoves && e.opts.MoveIndex != nil {
+		var deleted []string
+		for _, event := range events {
+			if event.Type != DriverEventDelete || event.IsDir {
+				continue
 			}
-			// 尝试删除目录（仅当为空时成功）
-			// 如果目录非空、不存在或其他错误，均停止删除
-			if err := os.Remove(dir); err != nil {
-				return
+			if path, err := resolveEventPath(event); err == nil {
+				deleted = append(deleted, path)
 			}
-			// 成功删除，继续向上
-			dir = filepath.Dir(dir)
 		}
+		renames = e.pairRenames(ctx, deleted, files)
 	}
 
-	// 步骤1: 处理删除事件
+	// 步骤4: 处理删除事件（移动的文件移动 STRM，其余删除 STRM）
 	for _, event := range events {
 		if ctx.Err() != nil {
 			return stats, ctx.Err()
@@ -329,6 +473,11 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 				continue
 			}
 
+			// 远端移动：移动 STRM 与附属文件，失败时按删除处理
+			if entry, ok := renames[path]; ok && e.moveRenamed(ctx, path, outputPath, entry, &stats) {
+				continue
+			}
+
 			// Dry Run 模式 - 只统计，不实际删除
 			if e.opts.DryRun {
 				e.logger.Debug("Dry Run: 删除 STRM 文件",
@@ -341,6 +490,9 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 					TargetPath:   outputPath,
 					ErrorMessage: "dry_run",
 				})
+				if e.opts.CleanupSidecars {
+					e.removeSidecars(ctx, filepath.Dir(outputPath), []string{filepath.Base(outputPath)}, true, &stats)
+				}
 				continue
 			}
 
@@ -369,42 +521,16 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 				SourcePath: path,
 				TargetPath: outputPath,
 			})
-			removeEmptyParents(outputPath)
-		case DriverEventCreate, DriverEventUpdate:
-			// 交由后续处理
-			continue
-		default:
-			atomic.AddInt64(&stats.FailedFiles, 1)
-			appendError("", fmt.Errorf("未处理的事件类型: %s", event.Type.String()))
-		}
-	}
-
-	// 步骤2: 收集新增/更新事件并转换为 RemoteEntry
-	entries := make([]RemoteEntry, 0, len(events))
-	for _, event := range events {
-		if ctx.Err() != nil {
-			return stats, ctx.Err()
-		}
-		switch event.Type {
-		case DriverEventCreate, DriverEventUpdate:
-			if event.IsDir {
-				atomic.AddInt64(&stats.TotalDirs, 1)
-				continue
+			if e.opts.MoveIndex != nil {
+				e.opts.MoveIndex.forget(path)
 			}
-			path, err := resolveEventPath(event)
-			if err != nil {
-				atomic.AddInt64(&stats.FailedFiles, 1)
-				appendError("", err)
-				continue
+			// 视频被删除时，附属文件一并删除
+			if e.opts.CleanupSidecars {
+				e.removeSidecars(ctx, filepath.Dir(outputPath), []string{filepath.Base(outputPath)}, false, &stats)
 			}
-			entries = append(entries, RemoteEntry{
-				Path:    path,
-				Name:    filepath.Base(path),
-				Size:    event.Size,
-				ModTime: event.ModTime,
-				IsDir:   false,
-			})
-		case DriverEventDelete:
+			e.removeEmptyParents(ctx, outputPath)
+		case DriverEventCreate, DriverEventUpdate:
+			// 交由后续处理
 			continue
 		default:
 			atomic.AddInt64(&stats.FailedFiles, 1)
@@ -412,16 +538,13 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 		}
 	}
 
-	// 步骤3: 过滤文件（按扩展名）
-	files := e.filterFiles(entries, &stats, "")
-	e.logger.Info("增量文件过滤完成",
-		zap.Int("matched_files", len(files)),
-		zap.Int64("filtered_files", stats.FilteredFiles))
-
-	// 步骤4: 并发处理文件（复用 processFile）
+	// 步骤5: 并发处理文件（复用 processFile）
 	if err := e.processFiles(ctx, files, &stats); err != nil {
 		return stats, fmt.Errorf("处理文件失败: %w", err)
 	}
+	if e.opts.MoveIndex != nil && !e.opts.DryRun {
+		e.recordOutputs(files)
+	}
 
 	stats.EndTime = time.Now()
 	stats.Duration = stats.EndTime.Sub(stats.StartTime)
@@ -435,69 +558,61 @@ func (e *Engine) RunIncremental(ctx context.Context, events []EngineEvent) (Sync
 		zap.Int64("skipped_unchanged", stats.SkippedUnchanged),
 		zap.Int64("failed", stats.FailedFiles),
 		zap.Int64("deleted_orphans", stats.DeletedOrphans),
+		zap.Int64("deleted_sidecars", stats.DeletedSidecars),
+		zap.Int64("moved", stats.MovedFiles),
+		zap.Int64("moved_sidecars", stats.MovedSidecars),
 		zap.Duration("duration", stats.Duration))
 
 	return stats, nil
 }
 
-// scanRemoteFiles 扫描远程文件列表
-func (e *Engine) scanRemoteFiles(ctx context.Context, remotePath string) ([]RemoteEntry, error) {
-	opt := ListOptions{
-		Recursive: true,
-		MaxDepth:  100, // 默认最大深度100层，避免无限递归
-	}
-	if e.opts.ListOverride != nil {
-		return e.opts.ListOverride(ctx, remotePath, opt)
-	}
-	entries, err := e.driver.List(ctx, remotePath, opt)
-	if err != nil {
-		return nil, err
-	}
-	return entries, nil
-}
-
 // filterFiles 根据配置过滤文件
 func (e *Engine) filterFiles(entries []RemoteEntry, stats *SyncStats, remoteRoot string) []RemoteEntry {
 	var files []RemoteEntry
-
 	for _, entry := range entries {
-		// 统计目录和文件
-		if entry.IsDir {
-			atomic.AddInt64(&stats.TotalDirs, 1)
-			continue
+		if e.acceptEntry(entry, stats, remoteRoot) {
+			files = append(files, entry)
 		}
-		atomic.AddInt64(&stats.TotalFiles, 1)
+	}
+	return files
+}
 
-		// 排除目录过滤
-		if IsExcludedPath(remoteRoot, entry.Path, e.opts.ExcludeDirs) {
-			atomic.AddInt64(&stats.FilteredFiles, 1)
-			continue
-		}
+// acceptEntry 判断单个条目是否需要处理，并更新扫描统计
+func (e *Engine) acceptEntry(entry RemoteEntry, stats *SyncStats, remoteRoot string) bool {
+	// 统计目录和文件
+	if entry.IsDir {
+		atomic.AddInt64(&stats.TotalDirs, 1)
+		return false
+	}
+	atomic.AddInt64(&stats.TotalFiles, 1)
 
-		// 检查扩展名过滤
-		if len(e.opts.FileExtensions) > 0 {
-			matched := false
-			for _, ext := range e.opts.FileExtensions {
-				if strings.HasSuffix(strings.ToLower(entry.Name), strings.ToLower(ext)) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				atomic.AddInt64(&stats.FilteredFiles, 1)
-				continue
+	// 排除目录过滤
+	if IsExcludedPath(remoteRoot, entry.Path, e.opts.ExcludeDirs) {
+		atomic.AddInt64(&stats.FilteredFiles, 1)
+		return false
+	}
+
+	// 检查扩展名过滤
+	if len(e.opts.FileExtensions) > 0 {
+		matched := false
+		for _, ext := range e.opts.FileExtensions {
+			if strings.HasSuffix(strings.ToLower(entry.Name), strings.ToLower(ext)) {
+				matched = true
+				break
 			}
 		}
-
-		if e.opts.MinFileSize > 0 && entry.Size > 0 && entry.Size < e.opts.MinFileSize {
+		if !matched {
 			atomic.AddInt64(&stats.FilteredFiles, 1)
-			continue
+			return false
 		}
+	}
 
-		files = append(files, entry)
+	if e.opts.MinFileSize > 0 && entry.Size > 0 && entry.Size < e.opts.MinFileSize {
+		atomic.AddInt64(&stats.FilteredFiles, 1)
+		return false
 	}
 
-	return files
+	return true
 }
 
 // applyMountPathMapping 应用挂载路径映射（系统级基线转换）
@@ -532,71 +647,14 @@ func applyStrmReplaceRules(input string, rules []StrmReplaceRule) string {
 
 // processFiles 并发处理文件列表
 func (e *Engine) processFiles(ctx context.Context, files []RemoteEntry, stats *SyncStats) error {
-	// 创建信号量控制并发数
-	sem := make(chan struct{}, e.opts.MaxConcurrency)
-	var wg sync.WaitGroup
-	var mu sync.Mutex // 保护 stats.Errors
-
-	// 使用可取消的子 context，确保所有 goroutine 能收到取消信号
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	for _, file := range files {
-		// 检查 context 取消（主循环级别）
-		if ctx.Err() != nil {
-			cancel() // 通知所有 goroutine 停止
-			break    // 退出循环，等待已启动的 goroutine
-		}
-
-		wg.Add(1)
-		go func(entry RemoteEntry) {
-			defer wg.Done()
-
-			// 获取信号量（可能被 context 取消中断）
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				return // Context 已取消，提前退出
-			}
-
-			// 再次检查 context（在获取信号量后）
-			if ctx.Err() != nil {
-				return
-			}
-
-			// 处理单个文件
-			if err := e.processFile(ctx, entry, stats); err != nil {
-				atomic.AddInt64(&stats.FailedFiles, 1)
-
-				// 记录错误（限制最多100个）
-				mu.Lock()
-				if len(stats.Errors) < 100 {
-					stats.Errors = append(stats.Errors, SyncError{
-						FilePath: entry.Path,
-						Error:    err.Error(),
-						Time:     time.Now(),
-					})
-				}
-				mu.Unlock()
-
-				e.logger.Warn("处理文件失败",
-					zap.String("path", entry.Path),
-					zap.Error(err))
-			} else {
-				atomic.AddInt64(&stats.ProcessedFiles, 1)
+	return e.processStream(ctx, func(ctx context.Context, emit func(RemoteEntry) error) error {
+		for _, file := range files {
+			if err := emit(file); err != nil {
+				return err
 			}
-		}(file)
-	}
-
-	// 等待所有 goroutine 完成
-	wg.Wait()
-
-	// 检查是否因取消而退出
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-	return nil
+		}
+		return nil
+	}, stats, nil)
 }
 
 // normalizeModTime 统一修改时间到 UTC 并按精度截断
@@ -765,11 +823,9 @@ func (e *Engine) processFile(ctx context.Context, entry RemoteEntry, stats *Sync
 	}
 
 	// 步骤4: 获取本地文件元信息（存在性 + ModTime）
-	// 注意：这里直接使用 os.Stat 是基于 Writer 对应本地文件系统的假设
-	// 这样可以获取精确的 ModTime 用于增量更新判定
 	localExists := false
 	localModTime := time.Time{}
-	if info, err := os.Stat(outputPath); err == nil {
+	if info, err := e.writer.Stat(ctx, outputPath); err == nil {
 		localExists = true
 		localModTime = info.ModTime()
 	} else if !isNotExist(err) {
@@ -885,7 +941,15 @@ func (e *Engine) processFile(ctx context.Context, entry RemoteEntry, stats *Sync
 	if !localExists {
 		op = "create"
 	}
-	if err := e.writer.Write(ctx, outputPath, expectedContent, entry.ModTime); err != nil {
+	err = e.writer.Write(ctx, outputPath, expectedContent, entry.ModTime)
+	if errors.Is(err, fileutil.ErrModTime) {
+		// 内容已写入，仅修改时间未能设置：记录警告，按成功计数
+		e.logger.Warn("STRM 文件已写入，但设置修改时间失败",
+			zap.String("path", outputPath),
+			zap.Error(err))
+		err = nil
+	}
+	if err != nil {
 		e.emitStrmEvent(ctx, StrmEvent{
 			Op:           op,
 			Status:       "failed",
@@ -931,6 +995,7 @@ func (e *Engine) processFile(ctx context.Context, entry RemoteEntry, stats *Sync
 // 规则：
 // - 远程路径：/media/movies/folder/file.mp4
 // - 输出路径：<OutputRoot>/media/movies/folder/file.strm
+// - 设置 Layout 时：<OutputRoot>/<Layout.OutputPath>.strm
 //
 // 安全性：
 // - 防止路径逃逸（使用 path.Clean 规范化）
@@ -943,20 +1008,27 @@ func (e *Engine) processFile(ctx context.Context, entry RemoteEntry, stats *Sync
 //   - string: 输出文件路径（本地路径格式，跨平台）
 //   - error: 路径无效或逃逸时返回错误
 func (e *Engine) calculateOutputPath(remotePath string) (string, error) {
-	// 安全性：使用 path.Clean 规范化 Unix 路径
-	cleanPath := filepath.ToSlash(remotePath) // 确保是 Unix 路径
-	cleanPath = filepath.Clean("/" + cleanPath)
-	cleanPath = strings.TrimPrefix(cleanPath, "/")
-
-	// 转换为本地路径格式
-	cleanPath = filepath.FromSlash(cleanPath)
-
-	// 替换扩展名为 .strm
-	ext := filepath.Ext(cleanPath)
-	if ext != "" {
-		cleanPath = strings.TrimSuffix(cleanPath, ext) + ".strm"
+	var cleanPath string
+	if e.opts.Layout != nil {
+		// 布局路径不含扩展名（标题中可能有点号），直接追加 .strm
+		cleanPath = path.Clean("/" + filepath.ToSlash(e.opts.Layout.OutputPath(remotePath)))
+		cleanPath = filepath.FromSlash(strings.TrimPrefix(cleanPath, "/")) + ".strm"
 	} else {
-		cleanPath = cleanPath + ".strm"
+		// 安全性：使用 path.Clean 规范化 Unix 路径
+		cleanPath = filepath.ToSlash(remotePath) // 确保是 Unix 路径
+		cleanPath = filepath.Clean("/" + cleanPath)
+		cleanPath = strings.TrimPrefix(cleanPath, "/")
+
+		// 转换为本地路径格式
+		cleanPath = filepath.FromSlash(cleanPath)
+
+		// 替换扩展名为 .strm
+		ext := filepath.Ext(cleanPath)
+		if ext != "" {
+			cleanPath = strings.TrimSuffix(cleanPath, ext) + ".strm"
+		} else {
+			cleanPath = cleanPath + ".strm"
+		}
 	}
 
 	// 使用 filepath.Join 拼接路径
@@ -989,7 +1061,7 @@ func (e *Engine) calculateOutputPath(remotePath string) (string, error) {
 // 并构建一个快照索引（map）。此索引用于快速判断哪些本地 STRM 文件是孤儿。
 //
 // 使用基于输出相对路径的索引比逐个调用 Driver.Stat 高效得多，
-// 特别是在处理大量文件时避免了大量的远程 API 调用。
+// 特别是在处理大量文件时避免了大量的远程 API 调用。设置 MoveIndex 时同时记录每个文件的签名。
 //
 // 参数：
 //   - entries: 远端文件列表
@@ -1048,6 +1120,9 @@ func (e *Engine) buildRemoteIndex(entries []RemoteEntry) (map[string]struct{}, e
 		// 统一使用 Unix 路径格式作为索引键
 		rel = filepath.ToSlash(rel)
 		index[rel] = struct{}{}
+		if e.opts.MoveIndex != nil {
+			e.opts.MoveIndex.record(entry, strings.TrimSuffix(rel, ".strm"))
+		}
 	}
 
 	return index, firstErr
@@ -1066,7 +1141,7 @@ func (e *Engine) buildRemoteIndex(entries []RemoteEntry) (map[string]struct{}, e
 // - 支持 DryRun 模式（只记录不删除）
 // - 路径逃逸检测
 // - 错误不中断整体流程（部分失败记录日志）
-// - 只删除 .strm 文件（大小写不敏感）
+// - 只删除 .strm 文件（大小写不敏感）；启用 CleanupSidecars 时还会删除孤儿 STRM 的附属文件
 //
 // 参数：
 //   - ctx: 上下文，用于取消
@@ -1082,9 +1157,12 @@ func (e *Engine) CleanOrphans(ctx context.Context, remoteIndex map[string]struct
 
 	dryRun := e.opts.DryRun || e.opts.OrphanCleanupDryRun
 	var firstErr error
+	// 按目录记录已删除的孤儿 STRM，遍历结束后统一清理附属文件
+	// （遍历过程中删除 extrafanart 等目录会干扰 WalkDir）
+	removedByDir := make(map[string][]string)
 
-	// 使用 filepath.WalkDir 遍历输出目录
-	walkErr := filepath.WalkDir(e.opts.OutputRoot, func(path string, d fs.DirEntry, err error) error {
+	// 通过 Writer 遍历输出目录
+	walkErr := e.writer.Walk(ctx, e.opts.OutputRoot, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			wrapped := fmt.Errorf("访问路径失败: %w", err)
 			if firstErr == nil {
@@ -1150,6 +1228,7 @@ func (e *Engine) CleanOrphans(ctx context.Context, remoteIndex map[string]struct
 			e.logger.Debug("Dry Run: 删除孤儿 STRM 文件",
 				zap.String("path", path))
 			atomic.AddInt64(&stats.DeletedOrphans, 1)
+			removedByDir[filepath.Dir(path)] = append(removedByDir[filepath.Dir(path)], d.Name())
 			return nil
 		}
 
@@ -1168,9 +1247,19 @@ func (e *Engine) CleanOrphans(ctx context.Context, remoteIndex map[string]struct
 		atomic.AddInt64(&stats.DeletedOrphans, 1)
 		e.logger.Debug("删除孤儿 STRM 文件",
 			zap.String("path", path))
+		removedByDir[filepath.Dir(path)] = append(removedByDir[filepath.Dir(path)], d.Name())
 		return nil
 	})
 
+	if walkErr == nil && e.opts.CleanupSidecars {
+		for dir, names := range removedByDir {
+			if ctx.Err() != nil {
+				break
+			}
+			e.removeSidecars(ctx, dir, names, dryRun, stats)
+		}
+	}
+
 	if walkErr != nil {
 		return fmt.Errorf("清理孤儿文件失败: %w", walkErr)
 	}
@@ -1180,6 +1269,41 @@ func (e *Engine) CleanOrphans(ctx context.Context, remoteIndex map[string]struct
 	return nil
 }
 
+// removeEmptyParents 尝试删除空父目录（仅限 OutputRoot 之下）
+// 注意：writer.Delete 专用于文件，目录通过可选的 DirWriter 删除；Writer 不支持时不清理
+func (e *Engine) removeEmptyParents(ctx context.Context, outputPath string) {
+	dirs, ok := e.writer.(DirWriter)
+	if !ok {
+		return
+	}
+	rootAbs, err := filepath.Abs(e.opts.OutputRoot)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(outputPath)
+	for {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return
+		}
+		// 已到达或超出 OutputRoot，停止
+		rel, err := filepath.Rel(rootAbs, absDir)
+		if err != nil {
+			return
+		}
+		if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+		// 尝试删除目录（仅当为空时成功）
+		// 如果目录非空、不存在或其他错误，均停止删除
+		if err := dirs.RemoveDir(ctx, dir, false); err != nil {
+			return
+		}
+		// 成功删除，继续向上
+		dir = filepath.Dir(dir)
+	}
+}
+
 // isNotExist 判断错误是否表示文件不存在
 //
 // 支持：
@@ -3,8 +3,12 @@ package syncengine_test
 
 import (
 	"context"
+	"errors"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/strmsync/strmsync/internal/strmwriter"
 	"github.com/strmsync/strmsync/internal/engine"
 	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
 
 	// 注册 filesystem providers
 	_ "github.com/strmsync/strmsync/internal/infra/filesystem/local"
@@ -360,3 +365,905 @@ func TestEngineContextCancel(t *testing.T) {
 		t.Errorf("期望 context.Canceled 错误，got: %v", err)
 	}
 }
+
+// checkpointRecorder 记录引擎保存的断点
+type checkpointRecorder struct {
+	mu    sync.Mutex
+	saved []syncengine.SyncCheckpoint
+}
+
+func (r *checkpointRecorder) SaveCheckpoint(_ context.Context, cp syncengine.SyncCheckpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, cp)
+}
+
+func (r *checkpointRecorder) last() (syncengine.SyncCheckpoint, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.saved) == 0 {
+		return syncengine.SyncCheckpoint{}, false
+	}
+	return r.saved[len(r.saved)-1], true
+}
+
+// TestEngineCheckpointResume 测试断点保存与恢复
+func TestEngineCheckpointResume(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	for _, name := range []string{"a.mp4", "b.mp4", "c.mp4"} {
+		if err := os.WriteFile(filepath.Join(tmpSrc, name), []byte("test"), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	cfg := filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	}
+	client, _ := filesystem.NewClient(cfg)
+	driver, _ := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	writer, _ := strmwriter.NewLocalWriter(tmpDst)
+	logger := zap.NewNop()
+
+	// 首次执行：完整同步并记录断点
+	recorder := &checkpointRecorder{}
+	engine, _ := syncengine.NewEngine(driver, writer, logger, syncengine.EngineOptions{
+		OutputRoot:         tmpDst,
+		MaxConcurrency:     2,
+		FileExtensions:     []string{".mp4"},
+		CheckpointSink:     recorder,
+		CheckpointInterval: 1,
+	})
+	if _, err := engine.RunOnce(context.Background(), "/"); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	cp, ok := recorder.last()
+	if !ok {
+		t.Fatal("未保存断点")
+	}
+	if cp.LastPath != "/c.mp4" || cp.ProcessedFiles != 3 || cp.RemoteRoot != "/" {
+		t.Errorf("断点不正确: %+v", cp)
+	}
+
+	// 从 b.mp4 之后恢复：仅处理 c.mp4
+	for _, name := range []string{"a.strm", "b.strm", "c.strm"} {
+		_ = os.Remove(filepath.Join(tmpDst, name))
+	}
+	engine, _ = syncengine.NewEngine(driver, writer, logger, syncengine.EngineOptions{
+		OutputRoot:     tmpDst,
+		FileExtensions: []string{".mp4"},
+		ResumeFrom: &syncengine.SyncCheckpoint{
+			RemoteRoot: "/",
+			LastPath:   "/b.mp4",
+		},
+	})
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("恢复同步失败: %v", err)
+	}
+	if stats.ResumedFiles != 2 {
+		t.Errorf("ResumedFiles = %d, want 2", stats.ResumedFiles)
+	}
+	if stats.CreatedFiles != 1 {
+		t.Errorf("CreatedFiles = %d, want 1", stats.CreatedFiles)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDst, "a.strm")); !os.IsNotExist(err) {
+		t.Errorf("断点之前的文件不应被重新处理")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDst, "c.strm")); err != nil {
+		t.Errorf("断点之后的文件应被处理: %v", err)
+	}
+}
+
+// failingWriter 写入指定 STRM 时失败，写入 cancelOn 后取消同步
+type failingWriter struct {
+	syncengine.Writer
+	failOn   string
+	cancelOn string
+	cancel   context.CancelFunc
+}
+
+func (w *failingWriter) Write(ctx context.Context, path string, content string, modTime time.Time) error {
+	if filepath.Base(path) == w.failOn {
+		return errors.New("write failed")
+	}
+	err := w.Writer.Write(ctx, path, content, modTime)
+	if filepath.Base(path) == w.cancelOn {
+		w.cancel()
+	}
+	return err
+}
+
+// TestEngineCheckpointRetriesFailedFiles 测试失败的文件不计入断点，恢复时重新处理
+func TestEngineCheckpointRetriesFailedFiles(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	for _, name := range []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4"} {
+		if err := os.WriteFile(filepath.Join(tmpSrc, name), []byte("test"), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	client, _ := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	driver, _ := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	local, _ := strmwriter.NewLocalWriter(tmpDst)
+	logger := zap.NewNop()
+
+	// 首次执行：b.mp4 写入失败，c.mp4 完成后同步被中断
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recorder := &checkpointRecorder{}
+	engine, _ := syncengine.NewEngine(driver, &failingWriter{Writer: local, failOn: "b.strm", cancelOn: "c.strm", cancel: cancel}, logger, syncengine.EngineOptions{
+		OutputRoot:         tmpDst,
+		MaxConcurrency:     1,
+		FileExtensions:     []string{".mp4"},
+		CheckpointSink:     recorder,
+		CheckpointInterval: 1,
+	})
+	if _, err := engine.RunOnce(ctx, "/"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望同步被取消，got: %v", err)
+	}
+	cp, ok := recorder.last()
+	if !ok || cp.LastPath != "/a.mp4" || cp.ProcessedFiles != 1 {
+		t.Fatalf("断点应停在失败的文件之前: %+v (saved=%v)", cp, ok)
+	}
+
+	// 恢复：失败的 b.mp4 重新处理
+	engine, _ = syncengine.NewEngine(driver, local, logger, syncengine.EngineOptions{
+		OutputRoot:     tmpDst,
+		MaxConcurrency: 1,
+		FileExtensions: []string{".mp4"},
+		ResumeFrom:     &cp,
+	})
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("恢复同步失败: %v", err)
+	}
+	if stats.ResumedFiles != 1 || stats.FailedFiles != 0 {
+		t.Errorf("resumed=%d failed=%d, want 1/0", stats.ResumedFiles, stats.FailedFiles)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDst, "b.strm")); err != nil {
+		t.Errorf("失败的文件应在恢复时重新处理: %v", err)
+	}
+}
+
+// TestComparePaths 测试遍历顺序比较
+func TestComparePaths(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"/a", "/a", 0},
+		{"/a", "/a/x", -1},
+		{"/a/x", "/a b", -1}, // 逐段比较：目录 a 的子树整体排在 "a b" 之前
+		{"/a b", "/a/x", 1},
+		{"/b", "/a/z/z", 1},
+		{"a/b/", "/a/b", 0},
+	}
+	for _, c := range cases {
+		if got := syncengine.ComparePaths(c.a, c.b); got != c.want {
+			t.Errorf("ComparePaths(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+// TestIsSidecarOf 测试附属文件识别
+func TestIsSidecarOf(t *testing.T) {
+	cases := []struct {
+		video, name string
+		want        bool
+	}{
+		{"movie.mkv", "movie.nfo", true},
+		{"movie.mkv", "movie.zh.srt", true},
+		{"movie.mkv", "Movie.chs.forced.ass", true},
+		{"movie.strm", "movie-poster.jpg", true},
+		{"movie.mkv", "movie-trailer.mkv", false},
+		{"movie.mkv", "movie-unknown.jpg", false},
+		{"movie.mkv", "movie2.nfo", false},
+		{"movie.mkv", "movie.mkv", false},
+		{"movie.mkv", "movie.part 2.srt", false},
+	}
+	for _, c := range cases {
+		if got := syncengine.IsSidecarOf(c.video, c.name); got != c.want {
+			t.Errorf("IsSidecarOf(%q, %q) = %v, want %v", c.video, c.name, got, c.want)
+		}
+	}
+}
+
+// TestMediaIndex 测试附属文件归属媒体条目
+func TestMediaIndex(t *testing.T) {
+	idx := syncengine.NewMediaIndex([]syncengine.RemoteEntry{
+		{Path: "/movies/A/A.mkv", Name: "A.mkv"},
+		{Path: "/tv/S1/e1.mkv", Name: "e1.mkv"},
+		{Path: "/tv/S1/e1.part2.mkv", Name: "e1.part2.mkv"},
+		{Path: "/tv/S1/e2.mkv", Name: "e2.mkv"},
+	}, []string{".mkv"})
+
+	cases := map[string]string{
+		"/movies/A/A.mkv":              "/movies/A/A",
+		"/movies/A/A.en.srt":           "/movies/A/A",
+		"/movies/A/folder.jpg":         "/movies/A/A", // 目录内唯一视频
+		"/movies/A/extrafanart/f1.jpg": "/movies/A/A",
+		"/tv/S1/e1.part2.nfo":          "/tv/S1/e1.part2", // 取最长匹配
+		"/tv/S1/e2-thumb.jpg":          "/tv/S1/e2",
+		"/tv/S1/poster.jpg":            "/tv/S1", // 多个视频：归属目录
+		"/tv/S1/unrelated.nfo":         "/tv/S1/unrelated",
+	}
+	for p, want := range cases {
+		if got := idx.MediaItemOf(p); got != want {
+			t.Errorf("MediaItemOf(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+// TestEngineOrphanCleanupRemovesSidecars 测试孤儿清理时一并删除附属文件
+func TestEngineOrphanCleanupRemovesSidecars(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	mustWrite := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "keep", "keep.mp4"))
+	mustWrite(filepath.Join(tmpDst, "keep", "keep.nfo"))
+	mustWrite(filepath.Join(tmpDst, "gone", "gone.strm"))
+	mustWrite(filepath.Join(tmpDst, "gone", "gone.zh.srt"))
+	mustWrite(filepath.Join(tmpDst, "gone", "gone-poster.jpg"))
+	mustWrite(filepath.Join(tmpDst, "gone", "folder.jpg"))
+	mustWrite(filepath.Join(tmpDst, "gone", "extrafanart", "1.jpg"))
+	mustWrite(filepath.Join(tmpDst, "gone", "notes.txt"))
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewLocalWriter(tmpDst)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+		OutputRoot:          tmpDst,
+		MaxConcurrency:      2,
+		FileExtensions:      []string{".mp4"},
+		EnableOrphanCleanup: true,
+		CleanupSidecars:     true,
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if stats.DeletedOrphans != 1 {
+		t.Errorf("DeletedOrphans = %d, want 1", stats.DeletedOrphans)
+	}
+	if stats.DeletedSidecars != 4 {
+		t.Errorf("DeletedSidecars = %d, want 4", stats.DeletedSidecars)
+	}
+
+	for _, gone := range []string{"gone.zh.srt", "gone-poster.jpg", "folder.jpg", "extrafanart"} {
+		if _, err := os.Stat(filepath.Join(tmpDst, "gone", gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", gone)
+		}
+	}
+	for _, kept := range []string{filepath.Join("gone", "notes.txt"), filepath.Join("keep", "keep.nfo"), filepath.Join("keep", "keep.strm")} {
+		if _, err := os.Stat(filepath.Join(tmpDst, kept)); err != nil {
+			t.Errorf("%s should be kept: %v", kept, err)
+		}
+	}
+}
+
+// strmEventRecorder 记录 STRM 事件
+type strmEventRecorder struct {
+	mu     sync.Mutex
+	events []syncengine.StrmEvent
+}
+
+func (r *strmEventRecorder) OnStrmEvent(_ context.Context, event syncengine.StrmEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestEngineDetectMovesRenamedFolder(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	mustWrite := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "Old", "movie.mp4"))
+	mustWrite(filepath.Join(tmpSrc, "Other", "movie.mp4"))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(tmpSrc, "Old", "movie.mp4"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewLocalWriter(tmpDst)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	recorder := &strmEventRecorder{}
+	engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+		OutputRoot:          tmpDst,
+		MaxConcurrency:      2,
+		FileExtensions:      []string{".mp4"},
+		EnableOrphanCleanup: true,
+		CleanupSidecars:     true,
+		DetectMoves:         true,
+		MoveIndex:           syncengine.NewMoveIndex(nil),
+		EventSink:           recorder,
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+
+	if _, err := engine.RunOnce(context.Background(), "/"); err != nil {
+		t.Fatalf("首次同步失败: %v", err)
+	}
+	// 模拟刮削生成的元数据
+	mustWrite(filepath.Join(tmpDst, "Old", "movie.zh.srt"))
+	mustWrite(filepath.Join(tmpDst, "Old", "movie-poster.jpg"))
+	mustWrite(filepath.Join(tmpDst, "Old", "folder.jpg"))
+	mustWrite(filepath.Join(tmpDst, "Old", "extrafanart", "1.jpg"))
+
+	// 云盘中重命名目录（修改时间不变）
+	if err := os.Rename(filepath.Join(tmpSrc, "Old"), filepath.Join(tmpSrc, "New")); err != nil {
+		t.Fatal(err)
+	}
+	recorder.events = nil
+
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if stats.MovedFiles != 1 {
+		t.Errorf("MovedFiles = %d, want 1", stats.MovedFiles)
+	}
+	if stats.MovedSidecars != 4 {
+		t.Errorf("MovedSidecars = %d, want 4", stats.MovedSidecars)
+	}
+	if stats.CreatedFiles != 0 || stats.DeletedOrphans != 0 || stats.DeletedSidecars != 0 {
+		t.Errorf("unexpected create/delete: created=%d deleted=%d sidecars=%d",
+			stats.CreatedFiles, stats.DeletedOrphans, stats.DeletedSidecars)
+	}
+
+	for _, name := range []string{"movie.strm", "movie.zh.srt", "movie-poster.jpg", "folder.jpg", filepath.Join("extrafanart", "1.jpg")} {
+		if _, err := os.Stat(filepath.Join(tmpDst, "New", name)); err != nil {
+			t.Errorf("%s should be moved: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDst, "Old")); !os.IsNotExist(err) {
+		t.Errorf("old directory should be removed")
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDst, "New", "movie.strm"))
+	if err != nil || !strings.Contains(string(content), "New") {
+		t.Errorf("moved STRM should point to the new path, got %q (%v)", content, err)
+	}
+
+	var moveEvent *syncengine.StrmEvent
+	for i, event := range recorder.events {
+		if event.Op == "move" && !event.Sidecar {
+			moveEvent = &recorder.events[i]
+		}
+	}
+	if moveEvent == nil {
+		t.Fatalf("move event not emitted: %+v", recorder.events)
+	}
+	if moveEvent.PreviousPath != filepath.Join(tmpDst, "Old", "movie.strm") || moveEvent.SourcePath != "/New/movie.mp4" {
+		t.Errorf("unexpected move event: %+v", *moveEvent)
+	}
+}
+
+func TestEngineDetectMovesRequiresMatchingSignature(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	mustWrite := func(p, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "Show A", "01.mp4"), "x")
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewLocalWriter(tmpDst)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	index := syncengine.NewMoveIndex(nil)
+	engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+		OutputRoot:          tmpDst,
+		MaxConcurrency:      2,
+		FileExtensions:      []string{".mp4"},
+		EnableOrphanCleanup: true,
+		DetectMoves:         true,
+		MoveIndex:           index,
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+	if _, err := engine.RunOnce(context.Background(), "/"); err != nil {
+		t.Fatalf("首次同步失败: %v", err)
+	}
+	if record := index.Records()["/Show A/01.mp4"]; record.Output != "Show A/01" || record.Size != 1 {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+
+	// 同名、同修改时间但大小不同的另一个文件：不是移动
+	if err := os.RemoveAll(filepath.Join(tmpSrc, "Show A")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(filepath.Join(tmpSrc, "Show B", "01.mp4"), "xy")
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if stats.MovedFiles != 0 || stats.CreatedFiles != 1 || stats.DeletedOrphans != 1 {
+		t.Errorf("moved=%d created=%d orphans=%d, want 0/1/1", stats.MovedFiles, stats.CreatedFiles, stats.DeletedOrphans)
+	}
+	records := index.Records()
+	if _, ok := records["/Show A/01.mp4"]; ok || len(records) != 1 {
+		t.Errorf("records should follow the remote files: %+v", records)
+	}
+
+	// 两个孤儿 STRM 的签名相同：无法确定来源，不移动
+	mustWrite(filepath.Join(tmpSrc, "C1", "01.mp4"), "xy")
+	if _, err := engine.RunOnce(context.Background(), "/"); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	for _, dir := range []string{"Show B", "C1"} {
+		if err := os.Rename(filepath.Join(tmpSrc, dir), filepath.Join(tmpSrc, dir+" (moved)")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "Show B (moved)", "01.mp4"), "xy")
+	mustWrite(filepath.Join(tmpSrc, "C1 (moved)", "01.mp4"), "xy")
+	stats, err = engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if stats.MovedFiles != 0 || stats.CreatedFiles != 2 || stats.DeletedOrphans != 2 {
+		t.Errorf("moved=%d created=%d orphans=%d, want 0/2/2", stats.MovedFiles, stats.CreatedFiles, stats.DeletedOrphans)
+	}
+}
+
+func TestEngineRunIncrementalMovesRenamedFile(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	mustWrite := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "Show A", "01.mp4"))
+	mustWrite(filepath.Join(tmpSrc, "Other", "02.mp4"))
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewLocalWriter(tmpDst)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	index := syncengine.NewMoveIndex(nil)
+	engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+		OutputRoot:      tmpDst,
+		MaxConcurrency:  2,
+		FileExtensions:  []string{".mp4"},
+		CleanupSidecars: true,
+		DetectMoves:     true,
+		MoveIndex:       index,
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+	if _, err := engine.RunOnce(context.Background(), "/"); err != nil {
+		t.Fatalf("首次同步失败: %v", err)
+	}
+	mustWrite(filepath.Join(tmpDst, "Show A", "01.zh.srt"))
+	mustWrite(filepath.Join(tmpDst, "Other", "02.nfo"))
+
+	// 驱动把目录重命名报告为删除 + 新增；同一批中 02.mp4 被真正删除
+	if err := os.Rename(filepath.Join(tmpSrc, "Show A"), filepath.Join(tmpSrc, "Show B")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := engine.RunIncremental(context.Background(), []syncengine.EngineEvent{
+		{Type: syncengine.DriverEventDelete, AbsPath: "/Show A/01.mp4"},
+		{Type: syncengine.DriverEventDelete, AbsPath: "/Other/02.mp4"},
+		{Type: syncengine.DriverEventCreate, AbsPath: "/Show B/01.mp4", Size: 1, ModTime: modTime},
+	})
+	if err != nil {
+		t.Fatalf("增量同步失败: %v", err)
+	}
+	if stats.MovedFiles != 1 || stats.MovedSidecars != 1 {
+		t.Errorf("moved=%d sidecars=%d, want 1/1", stats.MovedFiles, stats.MovedSidecars)
+	}
+	if stats.CreatedFiles != 0 || stats.DeletedOrphans != 1 || stats.DeletedSidecars != 1 {
+		t.Errorf("created=%d deleted=%d sidecars=%d, want 0/1/1",
+			stats.CreatedFiles, stats.DeletedOrphans, stats.DeletedSidecars)
+	}
+	for _, name := range []string{"01.strm", "01.zh.srt"} {
+		if _, err := os.Stat(filepath.Join(tmpDst, "Show B", name)); err != nil {
+			t.Errorf("%s should be moved: %v", name, err)
+		}
+	}
+	for _, dir := range []string{"Show A", "Other"} {
+		if _, err := os.Stat(filepath.Join(tmpDst, dir)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", dir)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDst, "Show B", "01.strm"))
+	if err != nil || !strings.Contains(string(content), "Show B") {
+		t.Errorf("moved STRM should point to the new path, got %q (%v)", content, err)
+	}
+	records := index.Records()
+	if record, ok := records["/Show B/01.mp4"]; !ok || record.Output != "Show B/01" || len(records) != 1 {
+		t.Errorf("records should follow the events: %+v", records)
+	}
+}
+
+// TestEngineWebDAVWriter 测试写入远程 WebDAV 目标（增量判定与孤儿清理均通过 Writer 完成）
+func TestEngineWebDAVWriter(t *testing.T) {
+	tmpSrc := t.TempDir()
+	storage := t.TempDir()
+
+	mustWrite := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "A", "a.mp4"))
+	mustWrite(filepath.Join(tmpSrc, "B", "b.mp4"))
+	old := time.Now().Add(-time.Hour)
+	for _, p := range []string{filepath.Join(tmpSrc, "A", "a.mp4"), filepath.Join(tmpSrc, "B", "b.mp4")} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(&webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(storage),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer server.Close()
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewWebDAVWriter("/strm", strmwriter.WebDAVConfig{URL: server.URL + "/dav"})
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+		OutputRoot:          "/strm",
+		MaxConcurrency:      2,
+		FileExtensions:      []string{".mp4"},
+		EnableOrphanCleanup: true,
+		CleanupSidecars:     true,
+		DetectMoves:         true,
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+
+	stats, err := engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("首次同步失败: %v", err)
+	}
+	if stats.CreatedFiles != 2 || stats.FailedFiles != 0 {
+		t.Fatalf("created=%d failed=%d, want 2/0", stats.CreatedFiles, stats.FailedFiles)
+	}
+	if _, err := os.Stat(filepath.Join(storage, "strm", "A", "a.strm")); err != nil {
+		t.Fatalf("STRM 未上传: %v", err)
+	}
+
+	// 远端已删除的文件及其附属文件
+	mustWrite(filepath.Join(storage, "strm", "Gone", "gone.strm"))
+	mustWrite(filepath.Join(storage, "strm", "Gone", "gone.nfo"))
+	mustWrite(filepath.Join(storage, "strm", "Gone", "extrafanart", "1.jpg"))
+
+	stats, err = engine.RunOnce(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	// WebDAV 服务不保留修改时间：内容未变时不应重写
+	if stats.CreatedFiles != 0 || stats.UpdatedFiles != 0 {
+		t.Errorf("created=%d updated=%d, want 0/0", stats.CreatedFiles, stats.UpdatedFiles)
+	}
+	if stats.DeletedOrphans != 1 || stats.DeletedSidecars != 2 {
+		t.Errorf("orphans=%d sidecars=%d, want 1/2", stats.DeletedOrphans, stats.DeletedSidecars)
+	}
+	for _, gone := range []string{"gone.strm", "gone.nfo", "extrafanart"} {
+		if _, err := os.Stat(filepath.Join(storage, "strm", "Gone", gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", gone)
+		}
+	}
+}
+
+func TestMediaLayoutCollisions(t *testing.T) {
+	layout := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true}, map[string]string{
+		"/old/Movie.2019.mkv": "Movies/Movie (2019)/Movie (2019)",
+		"/z/Film.2020.mkv":    "Movies/Film (2020)/Film (2020)",
+	})
+
+	// 映射的源文件本次尚未出现：等待完整扫描，不抢占已有映射
+	if layout.Claim("/new/Movie.2019.1080p.mkv") {
+		t.Error("claim should wait for the unseen owner")
+	}
+	// 新副本排在已有映射的源文件之前
+	if layout.Claim("/a/Film.2020.REMUX.mkv") {
+		t.Error("claim should wait for the unseen owner")
+	}
+	if !layout.Claim("/z/Film.2020.mkv") {
+		t.Error("mapped source should keep its path")
+	}
+	layout.Settle()
+
+	// 扫描结束后占用者仍未出现（已重命名）：新文件接管其输出路径
+	if got := layout.OutputPath("/new/Movie.2019.1080p.mkv"); got != "Movies/Movie (2019)/Movie (2019)" {
+		t.Errorf("takeover = %q", got)
+	}
+	// 占用者存在：已有映射保持不变，副本追加原文件名
+	if got := layout.OutputPath("/z/Film.2020.mkv"); got != "Movies/Film (2020)/Film (2020)" {
+		t.Errorf("existing = %q", got)
+	}
+	if got := layout.OutputPath("/a/Film.2020.REMUX.mkv"); got != "Movies/Film (2020)/Film (2020) - Film.2020.REMUX" {
+		t.Errorf("duplicate = %q", got)
+	}
+	// 已出现的占用者保留路径，冲突的文件追加原文件名
+	if got := layout.OutputPath("/dup/Movie (2019).mkv"); got != "Movies/Movie (2019)/Movie (2019) - Movie (2019)" {
+		t.Errorf("collision = %q", got)
+	}
+	if got := layout.OutputPath("/new/Movie.2019.1080p.mkv"); got != "Movies/Movie (2019)/Movie (2019)" {
+		t.Errorf("repeat = %q", got)
+	}
+	if got := layout.OutputPath("/tv/Show/Season 2/Show.S02E05.mkv"); got != "Shows/Show/Season 02/Show - S02E05" {
+		t.Errorf("episode = %q", got)
+	}
+	if !layout.IsEpisodeOutput("Shows/Show/Season 02/Show - S02E05") || layout.IsEpisodeOutput("Movies/Movie (2019)/Movie (2019)") {
+		t.Error("IsEpisodeOutput mismatch")
+	}
+	// 无法解析标题时保持镜像路径
+	if got := layout.OutputPath("/S01E02.mkv"); got != "S01E02" {
+		t.Errorf("mirror = %q", got)
+	}
+
+	mappings := layout.Mappings(true)
+	if _, ok := mappings["/old/Movie.2019.mkv"]; ok {
+		t.Error("replaced mapping should be dropped")
+	}
+	if len(mappings) != 6 {
+		t.Errorf("mappings = %v", mappings)
+	}
+}
+
+func TestEngineMediaLayoutTracksRenames(t *testing.T) {
+	tmpSrc := t.TempDir()
+	tmpDst := t.TempDir()
+
+	mustWrite := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(filepath.Join(tmpSrc, "dl", "Inception.2010.1080p.BluRay.x264.mkv"))
+	mustWrite(filepath.Join(tmpSrc, "tv", "Breaking.Bad", "Season 1", "Breaking.Bad.S01E02.720p.mkv"))
+
+	client, err := filesystem.NewClient(filesystem.Config{
+		Type:      filesystem.TypeLocal,
+		MountPath: tmpSrc,
+		STRMMode:  filesystem.STRMModeMount,
+	})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	driver, err := filesystem.NewAdapter(client, syncengine.DriverLocal)
+	if err != nil {
+		t.Fatalf("创建驱动器失败: %v", err)
+	}
+	writer, err := strmwriter.NewLocalWriter(tmpDst)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	run := func(layout *syncengine.MediaLayout) syncengine.SyncStats {
+		t.Helper()
+		engine, err := syncengine.NewEngine(driver, writer, zap.NewNop(), syncengine.EngineOptions{
+			OutputRoot:          tmpDst,
+			MaxConcurrency:      2,
+			FileExtensions:      []string{".mkv"},
+			EnableOrphanCleanup: true,
+			Layout:              layout,
+		})
+		if err != nil {
+			t.Fatalf("创建引擎失败: %v", err)
+		}
+		stats, err := engine.RunOnce(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("同步失败: %v", err)
+		}
+		return stats
+	}
+
+	first := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true}, nil)
+	run(first)
+	movie := filepath.Join(tmpDst, "Movies", "Inception (2010)", "Inception (2010).strm")
+	for _, p := range []string{movie, filepath.Join(tmpDst, "Shows", "Breaking Bad", "Season 01", "Breaking Bad - S01E02.strm")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("STRM 未按布局生成: %v", err)
+		}
+	}
+
+	// 远端重命名：STRM 路径不变，内容指向新文件
+	if err := os.Rename(filepath.Join(tmpSrc, "dl"), filepath.Join(tmpSrc, "movies")); err != nil {
+		t.Fatal(err)
+	}
+	renamed := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true}, first.Mappings(true))
+	stats := run(renamed)
+	if stats.DeletedOrphans != 0 {
+		t.Errorf("DeletedOrphans = %d, want 0", stats.DeletedOrphans)
+	}
+	content, err := os.ReadFile(movie)
+	if err != nil {
+		t.Fatalf("读取 STRM 失败: %v", err)
+	}
+	if !strings.Contains(string(content), "movies") {
+		t.Errorf("STRM 内容未更新: %s", content)
+	}
+
+	// 新副本排在已有文件之前：已有 STRM 不改名，副本追加原文件名
+	mustWrite(filepath.Join(tmpSrc, "a", "Inception.2010.REMUX.mkv"))
+	stats = run(syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true}, renamed.Mappings(true)))
+	if stats.CreatedFiles != 1 || stats.UpdatedFiles != 0 || stats.DeletedOrphans != 0 {
+		t.Errorf("created=%d updated=%d orphans=%d, want 1/0/0", stats.CreatedFiles, stats.UpdatedFiles, stats.DeletedOrphans)
+	}
+	if content, err := os.ReadFile(movie); err != nil || !strings.Contains(string(content), "movies") {
+		t.Errorf("已有 STRM 被改写: %s (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDst, "Movies", "Inception (2010)", "Inception (2010) - Inception.2010.REMUX.strm")); err != nil {
+		t.Errorf("副本 STRM 未生成: %v", err)
+	}
+}
+
+func TestMediaLayoutVersionsAndParts(t *testing.T) {
+	organized := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true, Versions: true, Parts: true}, nil)
+	mirrored := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Versions: true, Parts: true}, nil)
+	cases := []struct {
+		layout *syncengine.MediaLayout
+		source string
+		want   string
+	}{
+		{organized, "/m/Movie.2019.2160p.mkv", "Movies/Movie (2019)/Movie (2019) - 2160p"},
+		{organized, "/m/Movie.2019.1080p.mkv", "Movies/Movie (2019)/Movie (2019) - 1080p"},
+		{organized, "/m/Heat (1995)/CD1/heat.avi", "Movies/Heat (1995)/Heat (1995)-part1"},
+		{organized, "/m/Heat (1995)/CD2/heat.avi", "Movies/Heat (1995)/Heat (1995)-part2"},
+		{organized, "/tv/Show.S01E01.1080p.mkv", "Shows/Show/Season 01/Show - S01E01"},
+		{mirrored, "/dl/Movie.2160p.mkv", "dl/Movie - 2160p"},
+		{mirrored, "/dl/Heat.1995.cd2.avi", "dl/Heat (1995)-part2"},
+		{mirrored, "/dl/Heat (1995)/CD1/heat.avi", "dl/Heat (1995)/Heat (1995)-part1"},
+		{mirrored, "/dl/home_video.mp4", "dl/home_video"},
+		{mirrored, "/tv/Show.S01E01.1080p.mkv", "tv/Show.S01E01.1080p"},
+	}
+	for _, tc := range cases {
+		if got := tc.layout.OutputPath(tc.source); got != tc.want {
+			t.Errorf("OutputPath(%s) = %q, want %q", tc.source, got, tc.want)
+		}
+	}
+
+	// 后加入的同分辨率版本排在已有版本之前：已有 STRM 不改名
+	existing := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true, Versions: true}, map[string]string{
+		"/z/Movie.2019.1080p.mkv": "Movies/Movie (2019)/Movie (2019) - 1080p",
+	})
+	existing.Claim("/a/Movie.2019.1080p.REMUX.mkv")
+	existing.Claim("/z/Movie.2019.1080p.mkv")
+	existing.Settle()
+	if got := existing.OutputPath("/z/Movie.2019.1080p.mkv"); got != "Movies/Movie (2019)/Movie (2019) - 1080p" {
+		t.Errorf("existing version = %q", got)
+	}
+	if got := existing.OutputPath("/a/Movie.2019.1080p.REMUX.mkv"); got != "Movies/Movie (2019)/Movie (2019) - 1080p - Movie.2019.1080p.REMUX" {
+		t.Errorf("new version = %q", got)
+	}
+
+	// 关闭多版本命名后，已保存的映射不再沿用
+	plain := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Parts: true}, mirrored.Mappings(true))
+	if got := plain.OutputPath("/dl/Movie.2160p.mkv"); got != "dl/Movie.2160p" {
+		t.Errorf("versions disabled = %q", got)
+	}
+}
@@ -57,6 +57,29 @@ type Driver interface {
 	// - Returns wrapped error with path context
 	List(ctx context.Context, path string, opt ListOptions) ([]RemoteEntry, error)
 
+	// ListStream walks remote entries under the given path and invokes fn for each one.
+	//
+	// Parameters:
+	// - ctx: Context for cancellation and timeout
+	// - path: Remote path to list (empty or "/" for root)
+	// - opt: List options (recursive, max depth)
+	// - fn: Callback invoked once per entry; returning an error stops the walk
+	//
+	// Returns:
+	// - error: The first error from listing or from fn
+	//
+	// Behavior:
+	// - Entries are delivered while traversal is still in progress
+	// - Delivery order is depth-first pre-order with siblings sorted by name,
+	//   consistent with ComparePaths (required for checkpoint resume)
+	// - fn is invoked serially; a blocking fn applies backpressure to traversal
+	// - Directory listing may run in parallel with bounded concurrency per server
+	//
+	// Error Handling:
+	// - Returns context.Canceled if ctx is cancelled
+	// - Returns wrapped error with path context
+	ListStream(ctx context.Context, path string, opt ListOptions, fn func(RemoteEntry) error) error
+
 	// Watch subscribes to file change events for the given path.
 	//
 	// Parameters:
@@ -0,0 +1,299 @@
+// Package syncengine 提供 STRM 同步引擎实现
+package syncengine
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/strmsync/strmsync/internal/pkg/medianame"
+)
+
+// OutputLayout 输出布局：将远端文件映射为输出相对路径（可选，见 EngineOptions.Layout）
+//
+// 未设置时输出路径镜像远端目录结构。RunOnce 扫描时按远端顺序对每个文件调用一次 OutputPath
+// （实现 ClaimingLayout 时调用 Claim），实现可据此稳定地处理同名冲突；
+// 之后的处理、移动检测与孤儿索引会再次调用，必须返回相同结果。
+type OutputLayout interface {
+	// OutputPath 返回远端文件对应的输出相对路径（Unix 格式，不含 .strm 扩展名）
+	OutputPath(remotePath string) string
+}
+
+// ClaimingLayout 可选扩展：输出路径可能需要等完整扫描后才能确定的布局（MediaLayout 实现）
+//
+// RunOnce 扫描时按远端顺序对每个文件调用 Claim；返回 false 的文件延后处理，
+// 扫描成功完成后调用一次 Settle，之后再对这些文件调用 OutputPath。
+type ClaimingLayout interface {
+	OutputLayout
+
+	// Claim 为远端文件分配输出路径；路径被本次扫描尚未出现的文件占用时返回 false（等待 Settle）
+	Claim(remotePath string) bool
+
+	// Settle 完整扫描后调用：释放本次未出现的文件占用的路径，并为等待中的文件分配路径
+	Settle()
+}
+
+// 媒体布局的默认输出目录
+const (
+	DefaultMoviesDir = "Movies"
+	DefaultShowsDir  = "Shows"
+)
+
+// MediaLayoutOptions 媒体布局选项
+type MediaLayoutOptions struct {
+	// Organize 是否按电影/剧集整理目录（false 时保持远端目录结构，只按 Versions/Parts 调整文件名）
+	Organize bool
+
+	// MoviesDir/ShowsDir 电影与剧集目录（相对输出根目录，默认 Movies/Shows，仅 Organize 时使用）
+	MoviesDir string
+	ShowsDir  string
+
+	// Versions 多版本命名：电影文件名带分辨率时命名为 Title (Year) - 2160p（Emby/Jellyfin 多版本约定）
+	Versions bool
+
+	// Parts 分段命名：cd1/part1 等分段文件命名为 Title (Year)-part1（Emby/Jellyfin 堆叠约定）
+	Parts bool
+}
+
+// MediaLayout 按文件名解析结果整理输出路径（电影/剧集、多版本、分段）
+//
+// 规则（标题、年份、季集、分辨率与分段由 pkg/medianame 解析）：
+//   - 电影：<Movies>/Title (Year)/Title (Year)
+//   - 剧集：<Shows>/Title/Season 01/Title - S01E02
+//   - 多版本：Title (Year) - 2160p；分段：Title (Year)-part1（分段目录 CD1/ 内的文件放入上级目录）
+//   - 不整理目录时保持远端目录，只有多版本或分段文件改名
+//   - 无法解析标题的文件保持镜像路径
+//
+// 多版本标签按单个文件名确定，不依赖同目录的其他版本：之后新增版本时已有 STRM 不会改名。
+//
+// 不同远端文件解析出相同的输出路径时，先出现的文件保留该路径，其余追加原文件名（Title (Year) - 原名）。
+// 已分配的路径（含从映射表加载的）在解析结果不变时保持不变，不会被扫描顺序靠前的新文件（副本、新版本）抢占：
+// 占用者在本次扫描中尚未出现时，新文件等待完整扫描（Claim/Settle）。扫描结束后占用者仍未出现
+// （已删除或重命名）才由新文件接管其输出路径，远端重命名后 STRM 路径（以及媒体服务器中的条目）保持不变；
+// 占用者仍存在时新文件追加原文件名。
+type MediaLayout struct {
+	opts MediaLayoutOptions
+
+	mu       sync.Mutex
+	bySource map[string]string   // 远端路径 -> 输出相对路径
+	byOutput map[string]string   // 输出相对路径（小写）-> 远端路径
+	seen     map[string]struct{} // 本次运行中出现过的远端路径
+	pending  []string            // 等待 Settle 分配路径的远端文件（按扫描顺序）
+	settled  bool
+}
+
+// NewMediaLayout 创建媒体布局
+//
+// mappings 为上次运行保存的映射（远端路径 -> 输出相对路径），可为 nil。
+func NewMediaLayout(opts MediaLayoutOptions, mappings map[string]string) *MediaLayout {
+	opts.MoviesDir = normalizeLayoutDir(opts.MoviesDir, DefaultMoviesDir)
+	opts.ShowsDir = normalizeLayoutDir(opts.ShowsDir, DefaultShowsDir)
+	l := &MediaLayout{
+		opts:     opts,
+		bySource: make(map[string]string, len(mappings)),
+		byOutput: make(map[string]string, len(mappings)),
+		seen:     make(map[string]struct{}),
+	}
+	for source, output := range mappings {
+		if source == "" || output == "" {
+			continue
+		}
+		if _, taken := l.byOutput[layoutKey(output)]; taken {
+			continue
+		}
+		l.assign(source, output)
+	}
+	return l
+}
+
+// OutputPath 实现 OutputLayout
+//
+// 未经 Claim 的文件不等待：路径被尚未出现的文件占用时直接追加原文件名。
+func (l *MediaLayout) OutputPath(remotePath string) string {
+	output, _ := l.resolve(remotePath, false)
+	return output
+}
+
+// Claim 实现 ClaimingLayout
+func (l *MediaLayout) Claim(remotePath string) bool {
+	_, ok := l.resolve(remotePath, true)
+	return ok
+}
+
+// Settle 实现 ClaimingLayout
+func (l *MediaLayout) Settle() {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.settled = true
+	for source := range l.bySource {
+		if _, ok := l.seen[source]; !ok {
+			l.release(source)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, source := range pending {
+		l.resolve(source, false)
+	}
+}
+
+// resolve 分配输出路径；wait 为 true 且路径被本次尚未出现的文件占用时记为等待并返回 false
+func (l *MediaLayout) resolve(remotePath string, wait bool) (string, bool) {
+	candidate := l.candidate(remotePath)
+	stem := sanitizeName(strings.TrimSuffix(path.Base(remotePath), path.Ext(remotePath)))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seen[remotePath] = struct{}{}
+	if current, ok := l.bySource[remotePath]; ok {
+		// 解析结果不变时保持已分配的路径（包括冲突时追加了原名的路径）
+		if current == candidate || strings.HasPrefix(current, candidate+" - "+stem) {
+			return current, true
+		}
+		l.release(remotePath)
+	}
+
+	output := candidate
+	for n := 1; ; n++ {
+		owner, taken := l.byOutput[layoutKey(output)]
+		if !taken || owner == remotePath {
+			break
+		}
+		if _, active := l.seen[owner]; !active && wait && !l.settled {
+			// 占用者本次尚未出现：可能稍后出现（新文件是副本），也可能已重命名或删除，等待完整扫描
+			l.pending = append(l.pending, remotePath)
+			return "", false
+		}
+		if n == 1 && stem != "" {
+			output = candidate + " - " + stem
+		} else {
+			output = fmt.Sprintf("%s - %s (%d)", candidate, stem, n)
+		}
+	}
+	l.assign(remotePath, output)
+	return output, true
+}
+
+// Mappings 返回当前的映射（远端路径 -> 输出相对路径）
+//
+// seenOnly 为 true 时只返回本次运行中出现过的远端文件（完整扫描后用于清理已删除文件的映射；
+// Settle 之后未出现的映射已被释放）。
+func (l *MediaLayout) Mappings(seenOnly bool) map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make(map[string]string, len(l.bySource))
+	for source, output := range l.bySource {
+		if _, ok := l.seen[source]; seenOnly && !ok {
+			continue
+		}
+		result[source] = output
+	}
+	return result
+}
+
+// IsEpisodeOutput 判断输出相对路径是否位于整理后的剧集目录（<Shows>/Title/Season 01/...）
+func (l *MediaLayout) IsEpisodeOutput(output string) bool {
+	if !l.opts.Organize {
+		return false
+	}
+	rest, ok := strings.CutPrefix(output, l.opts.ShowsDir+"/")
+	return ok && strings.Count(rest, "/") == 2
+}
+
+// candidate 根据文件名解析结果计算输出路径（不考虑冲突）
+func (l *MediaLayout) candidate(remotePath string) string {
+	info := medianame.ParsePath(remotePath)
+	title := sanitizeName(info.Title)
+	if title == "" {
+		return mirrorOutputPath(remotePath)
+	}
+
+	var dir, name string
+	if info.IsEpisode() {
+		name = fmt.Sprintf("%s - S%02dE%02d", title, info.Season, info.Episode)
+		dir = path.Join(l.opts.ShowsDir, title, fmt.Sprintf("Season %02d", info.Season))
+	} else {
+		name = title
+		if info.Year > 0 {
+			name = fmt.Sprintf("%s (%d)", title, info.Year)
+		}
+		dir = path.Join(l.opts.MoviesDir, name)
+	}
+
+	suffix := l.nameSuffix(info)
+	if !l.opts.Organize {
+		if suffix == "" {
+			return mirrorOutputPath(remotePath)
+		}
+		// 保持远端目录；分段目录（CD1/）内的文件放入上级目录，与其他分段堆叠
+		dir = path.Dir(mirrorOutputPath(remotePath))
+		if _, ok := medianame.ParsePartDir(path.Base(dir)); ok && info.Part > 0 && l.opts.Parts {
+			dir = path.Dir(dir)
+		}
+	}
+	return path.Join(dir, name+suffix)
+}
+
+// nameSuffix 多版本与分段的文件名后缀（如 " - 2160p"、"-part1"）
+func (l *MediaLayout) nameSuffix(info medianame.Info) string {
+	suffix := ""
+	if l.opts.Versions && !info.IsEpisode() && info.Resolution != "" {
+		suffix = " - " + info.Resolution
+	}
+	if l.opts.Parts && info.Part > 0 {
+		suffix += fmt.Sprintf("-part%d", info.Part)
+	}
+	return suffix
+}
+
+func (l *MediaLayout) assign(source, output string) {
+	l.bySource[source] = output
+	l.byOutput[layoutKey(output)] = source
+}
+
+func (l *MediaLayout) release(source string) {
+	output, ok := l.bySource[source]
+	if !ok {
+		return
+	}
+	delete(l.bySource, source)
+	if l.byOutput[layoutKey(output)] == source {
+		delete(l.byOutput, layoutKey(output))
+	}
+}
+
+// layoutKey 输出路径的冲突判断键（忽略大小写，兼容大小写不敏感的文件系统）
+func layoutKey(output string) string {
+	return strings.ToLower(output)
+}
+
+// mirrorOutputPath 镜像远端路径（去掉扩展名）
+func mirrorOutputPath(remotePath string) string {
+	clean := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(remotePath)), "/")
+	return strings.TrimSuffix(clean, path.Ext(clean))
+}
+
+// normalizeLayoutDir 规范化布局目录（相对路径，Unix 格式）
+func normalizeLayoutDir(dir, fallback string) string {
+	dir = strings.Trim(path.Clean("/"+filepath.ToSlash(strings.TrimSpace(dir))), "/")
+	if dir == "" {
+		return fallback
+	}
+	return dir
+}
+
+// sanitizeName 去掉文件名中的非法字符（Windows/SMB 不允许的字符与控制字符）
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimRight(strings.TrimSpace(name), ". ")
+}
@@ -0,0 +1,623 @@
+// Package syncengine 提供 STRM 同步引擎实现
+package syncengine
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 移动检测
+//
+// 云盘中重命名/移动目录后远端路径全部变化：按常规流程处理时，新路径会生成全新的 STRM，
+// 旧 STRM 作为孤儿被删除，媒体服务器随之丢失观看记录并重新刮削。
+//
+// 本地 STRM 只有文件名与修改时间，同名、同修改时间的不同文件（例如批量导入后不同目录下的 01.mkv）
+// 无法区分，因此每个 STRM 对应的远端文件签名记录在 MoveIndex 中：有稳定标识（115 PickCode、内容哈希）时
+// 按标识匹配，否则按"文件名 + 大小 + 修改时间"匹配。新出现的远端文件若与某个孤儿 STRM 唯一匹配，
+// 就把旧 STRM 及其附属文件（字幕、NFO、海报等）移动到新位置，而不是重新生成；没有签名记录的 STRM 不参与匹配。
+//
+// 只有扫描完成后才能确定哪些 STRM 是孤儿，所以命中签名的新文件延后到扫描结束再处理。
+
+// MoveRecord 已生成 STRM 的记录：输出路径与对应远端文件的签名
+type MoveRecord struct {
+	// Output STRM 输出相对路径（Unix 格式，不含 .strm 扩展名）
+	Output string
+
+	// Size/ModTime 远端文件大小与修改时间
+	Size    int64
+	ModTime time.Time
+
+	// FileID 远端文件的稳定标识（"pickcode:" 前缀的 115 PickCode 或 RemoteEntry.Hash），驱动不提供时为空
+	FileID string
+}
+
+// MoveIndex 移动检测签名记录（见 EngineOptions.MoveIndex）
+//
+// RunOnce 扫描前按记录的签名索引本地 STRM，完整扫描后记录每个远端文件当前的签名并清除已不存在的文件；
+// RunIncremental 随新增、更新与删除事件维护记录。调用方通过 Records 持久化，下次同步时传入 NewMoveIndex。
+type MoveIndex struct {
+	mu      sync.Mutex
+	records map[string]MoveRecord // 远端路径 -> 记录
+	seen    map[string]struct{}   // 本次 RunOnce 记录过的远端路径
+}
+
+// NewMoveIndex 创建移动检测签名记录
+//
+// records 为上次保存的记录（远端路径 -> 记录），可为 nil。
+func NewMoveIndex(records map[string]MoveRecord) *MoveIndex {
+	m := &MoveIndex{
+		records: make(map[string]MoveRecord, len(records)),
+		seen:    make(map[string]struct{}),
+	}
+	for source, record := range records {
+		if source != "" && record.Output != "" {
+			m.records[source] = record
+		}
+	}
+	return m
+}
+
+// Records 返回当前的记录（远端路径 -> 记录）
+func (m *MoveIndex) Records() map[string]MoveRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]MoveRecord, len(m.records))
+	for source, record := range m.records {
+		result[source] = record
+	}
+	return result
+}
+
+// begin 开始一次 RunOnce：清空本次记录标记
+func (m *MoveIndex) begin() {
+	m.mu.Lock()
+	m.seen = make(map[string]struct{})
+	m.mu.Unlock()
+}
+
+// record 记录远端文件当前对应的 STRM 与签名
+func (m *MoveIndex) record(entry RemoteEntry, output string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[entry.Path] = MoveRecord{
+		Output:  output,
+		Size:    entry.Size,
+		ModTime: entry.ModTime,
+		FileID:  entryFileID(entry),
+	}
+	m.seen[entry.Path] = struct{}{}
+}
+
+// lookup 返回远端文件的记录
+func (m *MoveIndex) lookup(source string) (MoveRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[source]
+	return record, ok
+}
+
+// forget 删除远端文件的记录
+func (m *MoveIndex) forget(source string) {
+	m.mu.Lock()
+	delete(m.records, source)
+	m.mu.Unlock()
+}
+
+// prune 完整扫描后删除本次未出现的远端文件的记录
+func (m *MoveIndex) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for source := range m.records {
+		if _, ok := m.seen[source]; !ok {
+			delete(m.records, source)
+		}
+	}
+}
+
+// byStrm 按 STRM 输出相对路径（Unix 格式，含 .strm 扩展名）索引记录的签名
+func (m *MoveIndex) byStrm() map[string]moveSignature {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string]moveSignature, len(m.records))
+	for source, record := range m.records {
+		result[record.Output+".strm"] = moveSignature{
+			name:    path.Base(source),
+			size:    record.Size,
+			modTime: record.ModTime,
+			fileID:  record.FileID,
+		}
+	}
+	return result
+}
+
+// entryFileID 远端文件的稳定标识（PickCode 优先，其次内容哈希）
+func entryFileID(entry RemoteEntry) string {
+	if entry.PickCode != "" {
+		return "pickcode:" + entry.PickCode
+	}
+	return entry.Hash
+}
+
+// moveSignature 移动检测签名
+type moveSignature struct {
+	name    string
+	size    int64
+	modTime time.Time
+	fileID  string
+}
+
+// entrySignature 远端文件的签名
+func entrySignature(entry RemoteEntry) moveSignature {
+	return moveSignature{
+		name:    path.Base(entry.Path),
+		size:    entry.Size,
+		modTime: entry.ModTime,
+		fileID:  entryFileID(entry),
+	}
+}
+
+// attrKey 按"文件名 + 大小 + 修改时间"的签名键（文件名大小写不敏感，修改时间精确到秒）
+func (s moveSignature) attrKey() string {
+	if s.modTime.IsZero() {
+		return ""
+	}
+	return strings.ToLower(s.name) + "|" + strconv.FormatInt(s.size, 10) + "|" + strconv.FormatInt(s.modTime.Unix(), 10)
+}
+
+// key 新文件的查找键：有稳定标识时只按标识匹配
+func (s moveSignature) key() string {
+	if s.fileID != "" {
+		return "id|" + s.fileID
+	}
+	return s.attrKey()
+}
+
+// keys 本地 STRM 的索引键：有稳定标识时同时按属性索引（增量事件等来源不带标识）
+func (s moveSignature) keys() []string {
+	var keys []string
+	if s.fileID != "" {
+		keys = append(keys, "id|"+s.fileID)
+	}
+	if key := s.attrKey(); key != "" {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// moveDetector 记录本地 STRM 签名与延后处理的新文件
+type moveDetector struct {
+	local    map[string][]string // 签名键 -> 本地 STRM 路径
+	deferred []streamItem        // 延后处理的新文件（已占用断点编号）
+}
+
+// movePlan 一次 STRM 移动
+type movePlan struct {
+	from  string
+	to    string
+	entry RemoteEntry
+}
+
+// newMoveDetector 扫描输出目录，按记录的签名索引现有 STRM 文件
+func (e *Engine) newMoveDetector(ctx context.Context) (*moveDetector, error) {
+	d := &moveDetector{local: make(map[string][]string)}
+	signatures := e.opts.MoveIndex.byStrm()
+	if len(signatures) == 0 {
+		return d, nil
+	}
+	err := e.writer.Walk(ctx, e.opts.OutputRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// 输出目录不存在（首次同步）或个别目录不可读时忽略
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".strm") {
+			return nil
+		}
+		rel, err := filepath.Rel(e.opts.OutputRoot, path)
+		if err != nil {
+			return nil
+		}
+		signature, ok := signatures[filepath.ToSlash(rel)]
+		if !ok {
+			return nil
+		}
+		for _, key := range signature.keys() {
+			d.local[key] = append(d.local[key], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描本地 STRM 失败: %w", err)
+	}
+	return d, nil
+}
+
+// hold 判断新文件是否可能由移动产生，是则延后处理并返回 true
+//
+// 延后的文件占用断点编号但暂不完成，避免断点越过尚未处理的文件。
+func (d *moveDetector) hold(ctx context.Context, e *Engine, entry RemoteEntry, tracker *checkpointTracker) bool {
+	if !d.matches(ctx, e, entry) {
+		return false
+	}
+	d.deferred = append(d.deferred, streamItem{index: tracker.add(entry.Path), entry: entry})
+	return true
+}
+
+// holdDeferred 将其他原因延后（已占用断点编号）且可能由移动产生的文件转入移动检测，返回其余文件
+func (d *moveDetector) holdDeferred(ctx context.Context, e *Engine, items []streamItem) []streamItem {
+	rest := items[:0]
+	for _, item := range items {
+		if d.matches(ctx, e, item.entry) {
+			d.deferred = append(d.deferred, item)
+			continue
+		}
+		rest = append(rest, item)
+	}
+	return rest
+}
+
+// matches 判断新文件的签名是否命中本地 STRM 且目标 STRM 尚不存在
+func (d *moveDetector) matches(ctx context.Context, e *Engine, entry RemoteEntry) bool {
+	key := entrySignature(entry).key()
+	if key == "" {
+		return false
+	}
+	if _, ok := d.local[key]; !ok {
+		return false
+	}
+	outputPath, err := e.calculateOutputPath(entry.Path)
+	if err != nil {
+		return false
+	}
+	// 目标 STRM 已存在：常规更新，不是移动
+	_, err = e.writer.Stat(ctx, outputPath)
+	return isNotExist(err)
+}
+
+// finishMoves 扫描完成后执行移动，再处理延后的文件
+//
+// remoteIndex 为 nil（构建失败）时不移动，延后的文件按新文件处理。
+func (e *Engine) finishMoves(ctx context.Context, d *moveDetector, remoteIndex map[string]struct{}, stats *SyncStats, tracker *checkpointTracker) error {
+	if len(d.deferred) == 0 {
+		return nil
+	}
+	if remoteIndex != nil {
+		e.applyMoves(ctx, d, remoteIndex, stats)
+	}
+	return e.processDeferred(ctx, d.deferred, stats, tracker)
+}
+
+// applyMoves 将唯一匹配孤儿 STRM 的新文件转换为移动
+//
+// 以下情况放弃移动（回退为新建 + 孤儿清理）：
+//   - 签名对应多个孤儿 STRM
+//   - 多个新文件匹配同一个孤儿 STRM
+func (e *Engine) applyMoves(ctx context.Context, d *moveDetector, remoteIndex map[string]struct{}, stats *SyncStats) {
+	var plans []movePlan
+	claims := make(map[string]int)
+	for _, item := range d.deferred {
+		to, err := e.calculateOutputPath(item.entry.Path)
+		if err != nil {
+			continue
+		}
+		var from string
+		matches := 0
+		for _, candidate := range d.local[entrySignature(item.entry).key()] {
+			if e.inRemoteIndex(candidate, remoteIndex) {
+				continue
+			}
+			from = candidate
+			matches++
+		}
+		if matches != 1 {
+			continue
+		}
+		claims[from]++
+		plans = append(plans, movePlan{from: from, to: to, entry: item.entry})
+	}
+
+	dryRun := e.opts.DryRun
+	// 旧目录 -> 已移动的 STRM 文件名 -> 新 STRM 路径
+	movedByDir := make(map[string]map[string]string)
+	for _, plan := range plans {
+		if ctx.Err() != nil {
+			return
+		}
+		if claims[plan.from] > 1 {
+			e.logger.Debug("多个新文件匹配同一 STRM，放弃移动",
+				zap.String("path", plan.from))
+			continue
+		}
+		event := StrmEvent{SourcePath: plan.entry.Path}
+		if !e.movePath(ctx, plan.from, plan.to, event, dryRun) {
+			continue
+		}
+		atomic.AddInt64(&stats.MovedFiles, 1)
+
+		dir := filepath.Dir(plan.from)
+		if movedByDir[dir] == nil {
+			movedByDir[dir] = make(map[string]string)
+		}
+		movedByDir[dir][filepath.Base(plan.from)] = plan.to
+	}
+
+	for dir, moved := range movedByDir {
+		if ctx.Err() != nil {
+			return
+		}
+		e.moveSidecars(ctx, dir, moved, dryRun, stats)
+		if !dryRun {
+			for name := range moved {
+				e.removeEmptyParents(ctx, filepath.Join(dir, name))
+				break
+			}
+		}
+	}
+}
+
+// pairRenames 将同一批增量事件中签名唯一匹配的删除与新增配对为移动（旧远端路径 -> 新文件）
+//
+// 驱动不提供重命名事件，远端移动/重命名表现为旧路径删除 + 新路径新增；删除事件只有路径，
+// 签名取自 MoveIndex 中旧路径的记录。以下情况不配对（按删除 + 新建处理）：
+//   - 旧路径没有签名记录
+//   - 新文件匹配多个删除事件，或多个新文件匹配同一删除事件
+//   - 新文件的 STRM 已存在
+func (e *Engine) pairRenames(ctx context.Context, deleted []string, created []RemoteEntry) map[string]RemoteEntry {
+	index := make(map[string][]string) // 签名键 -> 旧远端路径
+	seen := make(map[string]struct{}, len(deleted))
+	for _, source := range deleted {
+		if _, ok := seen[source]; ok {
+			continue
+		}
+		seen[source] = struct{}{}
+		record, ok := e.opts.MoveIndex.lookup(source)
+		if !ok {
+			continue
+		}
+		signature := moveSignature{
+			name:    path.Base(source),
+			size:    record.Size,
+			modTime: record.ModTime,
+			fileID:  record.FileID,
+		}
+		for _, key := range signature.keys() {
+			index[key] = append(index[key], source)
+		}
+	}
+	if len(index) == 0 {
+		return nil
+	}
+
+	renames := make(map[string]RemoteEntry)
+	claims := make(map[string]int)
+	for _, entry := range created {
+		key := entrySignature(entry).key()
+		sources := index[key]
+		if key == "" || len(sources) != 1 {
+			continue
+		}
+		outputPath, err := e.calculateOutputPath(entry.Path)
+		if err != nil {
+			continue
+		}
+		// 目标 STRM 已存在：常规更新，不是移动
+		if _, err := e.writer.Stat(ctx, outputPath); !isNotExist(err) {
+			continue
+		}
+		claims[sources[0]]++
+		renames[sources[0]] = entry
+	}
+	for source, count := range claims {
+		if count > 1 {
+			e.logger.Debug("多个新文件匹配同一删除事件，放弃移动",
+				zap.String("path", source))
+			delete(renames, source)
+		}
+	}
+	return renames
+}
+
+// moveRenamed 将远端移动前的 STRM 及其附属文件移动到新文件的输出位置，返回是否成功
+//
+// STRM 内容随后由新文件的常规处理更新。
+func (e *Engine) moveRenamed(ctx context.Context, source, from string, entry RemoteEntry, stats *SyncStats) bool {
+	to, err := e.calculateOutputPath(entry.Path)
+	if err != nil {
+		return false
+	}
+	dryRun := e.opts.DryRun
+	if !e.movePath(ctx, from, to, StrmEvent{SourcePath: entry.Path}, dryRun) {
+		return false
+	}
+	atomic.AddInt64(&stats.MovedFiles, 1)
+	e.moveSidecars(ctx, filepath.Dir(from), map[string]string{filepath.Base(from): to}, dryRun, stats)
+	if !dryRun {
+		e.removeEmptyParents(ctx, from)
+	}
+	e.opts.MoveIndex.forget(source)
+	return true
+}
+
+// recordOutputs 在 MoveIndex 中记录增量处理的远端文件
+func (e *Engine) recordOutputs(files []RemoteEntry) {
+	for _, entry := range files {
+		outputPath, err := e.calculateOutputPath(entry.Path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(e.opts.OutputRoot, outputPath)
+		if err != nil {
+			continue
+		}
+		e.opts.MoveIndex.record(entry, strings.TrimSuffix(filepath.ToSlash(rel), ".strm"))
+	}
+}
+
+// inRemoteIndex 判断本地 STRM 是否仍对应远端文件
+func (e *Engine) inRemoteIndex(strmPath string, remoteIndex map[string]struct{}) bool {
+	rel, err := filepath.Rel(e.opts.OutputRoot, strmPath)
+	if err != nil {
+		return true
+	}
+	_, ok := remoteIndex[filepath.ToSlash(rel)]
+	return ok
+}
+
+// moveSidecars 将已移动 STRM 的附属文件移动到新目录
+//
+// 同名附属文件跟随各自的 STRM；目录级附属文件（folder.jpg、extrafanart/ 等）
+// 仅在目录内已无 STRM 且全部移动到同一目录时跟随。新位置已有同名文件时保留旧文件，
+// 由孤儿清理按 CleanupSidecars 处理。
+func (e *Engine) moveSidecars(ctx context.Context, dir string, moved map[string]string, dryRun bool, stats *SyncStats) {
+	items, err := e.readDir(ctx, dir)
+	if err != nil {
+		if !isNotExist(err) {
+			e.logger.Warn("读取附属文件目录失败",
+				zap.String("dir", dir),
+				zap.Error(err))
+		}
+		return
+	}
+
+	var remaining []string
+	for _, item := range items {
+		if item.IsDir() || !strings.EqualFold(filepath.Ext(item.Name()), ".strm") {
+			continue
+		}
+		if _, ok := moved[item.Name()]; !ok {
+			remaining = append(remaining, item.Name())
+		}
+	}
+
+	// owner 返回附属文件所属的已移动 STRM（没有或被剩余 STRM 更精确匹配时返回空）
+	owner := func(name string) string {
+		best := ""
+		for strm := range moved {
+			if IsSidecarOf(strm, name) && len(strm) > len(best) {
+				best = strm
+			}
+		}
+		if best == "" {
+			return ""
+		}
+		for _, strm := range remaining {
+			if len(strm) > len(best) && IsSidecarOf(strm, name) {
+				return ""
+			}
+		}
+		return best
+	}
+
+	folderDest := ""
+	if len(remaining) == 0 {
+		for _, to := range moved {
+			dest := filepath.Dir(to)
+			if folderDest != "" && folderDest != dest {
+				folderDest = ""
+				break
+			}
+			folderDest = dest
+		}
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		name := item.Name()
+		if strings.EqualFold(filepath.Ext(name), ".strm") {
+			continue
+		}
+
+		var strm, destDir string
+		switch {
+		case item.IsDir():
+			if folderDest == "" || !IsFolderSidecarDir(name) {
+				continue
+			}
+			destDir = folderDest
+		case IsFolderSidecar(name) && folderDest != "":
+			destDir = folderDest
+		default:
+			if strm = owner(name); strm == "" {
+				continue
+			}
+			destDir = filepath.Dir(moved[strm])
+		}
+		if strm == "" && len(moved) == 1 {
+			for only := range moved {
+				strm = only
+			}
+		}
+
+		target := filepath.Join(destDir, name)
+		if _, err := e.writer.Stat(ctx, target); err == nil {
+			continue
+		}
+		mediaItem := e.mediaItemForOutput(destDir)
+		if strm != "" {
+			mediaItem = MediaItemKey(e.mediaItemForOutput(moved[strm]))
+		}
+		event := StrmEvent{MediaItem: mediaItem, Sidecar: true}
+		if e.movePath(ctx, filepath.Join(dir, name), target, event, dryRun) {
+			atomic.AddInt64(&stats.MovedSidecars, 1)
+		}
+	}
+}
+
+// movePath 移动文件或目录并发送 move 事件，返回是否成功
+//
+// 通过 DirWriter 移动（移动检测仅在 Writer 支持时启用）；
+// 新旧路径都在 OutputRoot 之下，不会跨文件系统。
+func (e *Engine) movePath(ctx context.Context, from, to string, event StrmEvent, dryRun bool) bool {
+	event.Op = "move"
+	event.TargetPath = to
+	event.PreviousPath = from
+
+	if dryRun {
+		e.logger.Debug("Dry Run: 移动文件",
+			zap.String("from", from),
+			zap.String("to", to))
+		event.Status = "skipped"
+		event.ErrorMessage = "dry_run"
+		e.emitStrmEvent(ctx, event)
+		return true
+	}
+
+	var err error
+	if dirs, ok := e.writer.(DirWriter); ok {
+		err = dirs.Rename(ctx, from, to)
+	} else {
+		err = fmt.Errorf("写入器不支持移动: %w", ErrNotSupported)
+	}
+	if err != nil {
+		e.logger.Warn("移动文件失败",
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Error(err))
+		event.Status = "failed"
+		event.ErrorMessage = err.Error()
+		e.emitStrmEvent(ctx, event)
+		return false
+	}
+
+	e.logger.Debug("移动文件",
+		zap.String("from", from),
+		zap.String("to", to))
+	event.Status = "success"
+	e.emitStrmEvent(ctx, event)
+	return true
+}
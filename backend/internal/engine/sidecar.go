@@ -0,0 +1,373 @@
+// Package syncengine 提供 STRM 同步引擎实现
+package syncengine
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// 附属文件（sidecar）识别规则
+//
+// 与视频同名的附属文件：
+//   - movie.nfo / movie.jpg
+//   - movie.zh.srt / movie.chs.forced.ass（语言、forced/default 等标签）
+//   - movie-poster.jpg / movie-fanart.jpg（Kodi/Emby 图片命名）
+//
+// 目录级附属文件（目录内只有一个视频时归属该视频，否则归属目录）：
+//   - movie.nfo、folder.jpg、poster.jpg、fanart.jpg 等
+//   - extrafanart/、extrathumbs/ 目录下的图片
+var (
+	sidecarSubtitleExts = map[string]struct{}{
+		".srt": {}, ".ass": {}, ".ssa": {}, ".sub": {}, ".idx": {},
+		".vtt": {}, ".sup": {}, ".smi": {},
+	}
+	sidecarImageExts = map[string]struct{}{
+		".jpg": {}, ".jpeg": {}, ".png": {}, ".webp": {}, ".bmp": {}, ".tbn": {}, ".gif": {},
+	}
+	sidecarOtherExts = map[string]struct{}{
+		".nfo": {}, ".xml": {}, ".txt": {},
+	}
+	sidecarArtworkTypes = map[string]struct{}{
+		"poster": {}, "fanart": {}, "thumb": {}, "landscape": {}, "banner": {},
+		"clearlogo": {}, "clearart": {}, "logo": {}, "disc": {}, "discart": {},
+		"backdrop": {}, "keyart": {}, "characterart": {},
+	}
+	folderSidecarStems = map[string]struct{}{
+		"movie": {}, "folder": {}, "cover": {}, "default": {}, "poster": {},
+		"fanart": {}, "backdrop": {}, "background": {}, "banner": {}, "landscape": {},
+		"logo": {}, "clearlogo": {}, "clearart": {}, "disc": {}, "discart": {}, "thumb": {},
+	}
+	folderSidecarDirs = map[string]struct{}{
+		"extrafanart": {}, "extrathumbs": {},
+	}
+)
+
+// maxSidecarTagLen 单个标签（如 zh-Hans、forced）的最大长度
+const maxSidecarTagLen = 16
+
+// isSidecarExt 判断扩展名（小写，含点）是否可能是附属文件
+func isSidecarExt(ext string) bool {
+	if _, ok := sidecarSubtitleExts[ext]; ok {
+		return true
+	}
+	if _, ok := sidecarImageExts[ext]; ok {
+		return true
+	}
+	_, ok := sidecarOtherExts[ext]
+	return ok
+}
+
+// IsSidecarOf 判断 name 是否是视频 videoName 的同名附属文件
+//
+// 两者均为文件名（不含目录），比较时忽略大小写。
+// videoName 可以是原始视频名，也可以是对应的 .strm 文件名。
+func IsSidecarOf(videoName, name string) bool {
+	stem := strings.TrimSuffix(videoName, path.Ext(videoName))
+	if stem == "" || len(name) <= len(stem) || !strings.EqualFold(name[:len(stem)], stem) {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if !isSidecarExt(ext) {
+		return false
+	}
+	rest := strings.TrimSuffix(name[len(stem):], path.Ext(name))
+
+	switch {
+	case rest == "":
+		// movie.nfo / movie.srt
+		return true
+	case rest[0] == '.':
+		// movie.zh.srt / movie.zh-Hans.forced.ass
+		for _, tag := range strings.Split(rest[1:], ".") {
+			if !isSidecarTag(tag) {
+				return false
+			}
+		}
+		return true
+	case rest[0] == '-':
+		// movie-poster.jpg
+		if _, ok := sidecarImageExts[ext]; !ok {
+			return false
+		}
+		_, ok := sidecarArtworkTypes[strings.ToLower(rest[1:])]
+		return ok
+	default:
+		return false
+	}
+}
+
+// isSidecarTag 判断文件名中的单个标签是否合法（语言代码、forced、default 等）
+func isSidecarTag(tag string) bool {
+	if tag == "" || len(tag) > maxSidecarTagLen {
+		return false
+	}
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFolderSidecar 判断文件名是否是目录级附属文件（folder.jpg、movie.nfo 等）
+func IsFolderSidecar(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	if _, ok := sidecarImageExts[ext]; !ok && ext != ".nfo" {
+		return false
+	}
+	_, ok := folderSidecarStems[strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))]
+	return ok
+}
+
+// IsFolderSidecarDir 判断目录名是否是目录级附属目录（extrafanart 等）
+func IsFolderSidecarDir(name string) bool {
+	_, ok := folderSidecarDirs[strings.ToLower(name)]
+	return ok
+}
+
+// MediaItemKey 返回视频文件对应的媒体条目标识（去掉扩展名的路径）
+func MediaItemKey(videoPath string) string {
+	return strings.TrimSuffix(videoPath, path.Ext(videoPath))
+}
+
+// MediaIndex 按目录索引视频文件，用于将附属文件归属到所属媒体条目
+type MediaIndex struct {
+	videos map[string][]string // 目录 -> 视频文件名
+}
+
+// NewMediaIndex 根据远端文件列表构建媒体索引
+//
+// videoExts 为视频扩展名列表（小写，含点），例如 [".mkv", ".mp4"]
+func NewMediaIndex(entries []RemoteEntry, videoExts []string) *MediaIndex {
+	extSet := make(map[string]struct{}, len(videoExts))
+	for _, ext := range videoExts {
+		extSet[strings.ToLower(ext)] = struct{}{}
+	}
+	idx := &MediaIndex{videos: make(map[string][]string)}
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		if _, ok := extSet[strings.ToLower(path.Ext(entry.Name))]; !ok {
+			continue
+		}
+		dir := path.Dir(entry.Path)
+		idx.videos[dir] = append(idx.videos[dir], entry.Name)
+	}
+	return idx
+}
+
+// MediaItemOf 返回文件所属的媒体条目标识
+//
+// 归属规则：
+//  1. 视频文件本身：去掉扩展名的路径
+//  2. 同名附属文件：所属视频（多个视频匹配时取文件名最长者，例如 movie.part1 优先于 movie）
+//  3. 目录级附属文件（含 extrafanart/ 下的文件）：目录内只有一个视频时归属该视频，否则归属目录
+//  4. 其他文件：去掉扩展名的路径
+func (m *MediaIndex) MediaItemOf(filePath string) string {
+	dir := path.Dir(filePath)
+	name := path.Base(filePath)
+
+	if m != nil {
+		best := ""
+		for _, video := range m.videos[dir] {
+			if video == name {
+				return MediaItemKey(filePath)
+			}
+			if IsSidecarOf(video, name) && len(video) > len(best) {
+				best = video
+			}
+		}
+		if best != "" {
+			return MediaItemKey(path.Join(dir, best))
+		}
+
+		folderDir := ""
+		if IsFolderSidecarDir(path.Base(dir)) {
+			folderDir = path.Dir(dir)
+		} else if IsFolderSidecar(name) {
+			folderDir = dir
+		}
+		if folderDir != "" {
+			if videos := m.videos[folderDir]; len(videos) == 1 {
+				return MediaItemKey(path.Join(folderDir, videos[0]))
+			}
+			return folderDir
+		}
+	}
+	return MediaItemKey(filePath)
+}
+
+// removeSidecars 删除同一输出目录下已删除 STRM 的附属文件
+//
+// 参数：
+//   - dir: 输出目录（本地路径）
+//   - removed: 该目录下已删除（或 DryRun 下将删除）的 STRM 文件名
+//   - dryRun: 只统计不删除
+//
+// 规则：
+//   - 同名附属文件随 STRM 删除；若目录中剩余的其他 STRM 匹配更长的文件名（例如 movie.part1.nfo），则保留
+//   - 目录内不再有 STRM 时，删除目录级附属文件与 extrafanart 等目录
+func (e *Engine) removeSidecars(ctx context.Context, dir string, removed []string, dryRun bool, stats *SyncStats) {
+	if len(removed) == 0 {
+		return
+	}
+	items, err := e.readDir(ctx, dir)
+	if err != nil {
+		if !isNotExist(err) {
+			e.logger.Warn("读取附属文件目录失败",
+				zap.String("dir", dir),
+				zap.Error(err))
+		}
+		return
+	}
+
+	removedSet := make(map[string]struct{}, len(removed))
+	for _, name := range removed {
+		removedSet[name] = struct{}{}
+	}
+	var remaining []string
+	for _, item := range items {
+		if item.IsDir() || !strings.EqualFold(filepath.Ext(item.Name()), ".strm") {
+			continue
+		}
+		if _, ok := removedSet[item.Name()]; !ok {
+			remaining = append(remaining, item.Name())
+		}
+	}
+
+	// owner 返回附属文件所属的已删除 STRM（没有或被剩余 STRM 更精确匹配时返回空）
+	owner := func(name string) string {
+		best := ""
+		for _, strm := range removed {
+			if IsSidecarOf(strm, name) && len(strm) > len(best) {
+				best = strm
+			}
+		}
+		if best == "" {
+			return ""
+		}
+		for _, strm := range remaining {
+			if len(strm) > len(best) && IsSidecarOf(strm, name) {
+				return ""
+			}
+		}
+		return best
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		name := item.Name()
+		if strings.EqualFold(filepath.Ext(name), ".strm") {
+			continue
+		}
+
+		var strm string
+		switch {
+		case item.IsDir():
+			if len(remaining) > 0 || !IsFolderSidecarDir(name) {
+				continue
+			}
+		case IsFolderSidecar(name) && len(remaining) == 0:
+			// 目录级附属文件：目录内已无 STRM
+		default:
+			if strm = owner(name); strm == "" {
+				continue
+			}
+		}
+		// 目录级附属文件只有一个 STRM 被删除时归属该条目，否则归属目录
+		if strm == "" && len(removed) == 1 {
+			strm = removed[0]
+		}
+		mediaItem := e.mediaItemForOutput(dir)
+		if strm != "" {
+			mediaItem = MediaItemKey(e.mediaItemForOutput(filepath.Join(dir, strm)))
+		}
+		e.deleteSidecar(ctx, filepath.Join(dir, name), item.IsDir(), mediaItem, dryRun, stats)
+	}
+}
+
+// deleteSidecar 删除单个附属文件或附属目录并发送事件
+func (e *Engine) deleteSidecar(ctx context.Context, target string, isDir bool, mediaItem string, dryRun bool, stats *SyncStats) {
+	event := StrmEvent{
+		Op:         "delete",
+		TargetPath: target,
+		MediaItem:  mediaItem,
+		Sidecar:    true,
+	}
+
+	if dryRun {
+		e.logger.Debug("Dry Run: 删除附属文件", zap.String("path", target))
+		atomic.AddInt64(&stats.DeletedSidecars, 1)
+		event.Status = "skipped"
+		event.ErrorMessage = "dry_run"
+		e.emitStrmEvent(ctx, event)
+		return
+	}
+
+	var err error
+	if isDir {
+		// 附属目录（extrafanart 等）只包含图片，直接整体删除
+		dirs, ok := e.writer.(DirWriter)
+		if !ok {
+			e.logger.Debug("写入器不支持删除目录，保留附属目录", zap.String("path", target))
+			return
+		}
+		err = dirs.RemoveDir(ctx, target, true)
+	} else {
+		err = e.writer.Delete(ctx, target)
+	}
+	if err != nil && !isNotExist(err) {
+		e.logger.Warn("删除附属文件失败",
+			zap.String("path", target),
+			zap.Error(err))
+		event.Status = "failed"
+		event.ErrorMessage = err.Error()
+		e.emitStrmEvent(ctx, event)
+		return
+	}
+
+	atomic.AddInt64(&stats.DeletedSidecars, 1)
+	e.logger.Debug("删除附属文件", zap.String("path", target))
+	event.Status = "success"
+	e.emitStrmEvent(ctx, event)
+}
+
+// mediaItemForOutput 将输出路径转换为远端风格的路径（以 "/" 开头，相对 OutputRoot）
+//
+// STRM 输出路径与远端路径一一对应（仅扩展名不同），因此对 STRM 路径再取
+// MediaItemKey 即与 MediaItemKey(远端路径) 一致；无法计算时返回空字符串。
+func (e *Engine) mediaItemForOutput(outputPath string) string {
+	rel, err := filepath.Rel(e.opts.OutputRoot, outputPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return path.Clean("/" + filepath.ToSlash(rel))
+}
+
+// readDir 通过 Writer 列出目录的直接子项
+func (e *Engine) readDir(ctx context.Context, dir string) ([]fs.DirEntry, error) {
+	var items []fs.DirEntry
+	err := e.writer.Walk(ctx, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		items = append(items, d)
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return items, err
+}
@@ -0,0 +1,198 @@
+// Package syncengine 提供 STRM 同步引擎实现
+package syncengine
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errScanFailed 标记远程扫描阶段的错误（用于区分扫描失败与处理失败）
+var errScanFailed = errors.New("扫描远程文件失败")
+
+// ComparePaths 按遍历顺序比较两个远端路径
+//
+// 逐段比较（而不是整串比较），使结果与"深度优先前序、同级按名称排序"的
+// 遍历顺序一致：父目录排在子项之前，同一目录的子树连续排列。
+//
+// 返回值：a 在前返回 -1，相同返回 0，a 在后返回 1
+func ComparePaths(a, b string) int {
+	a = strings.Trim(a, "/")
+	b = strings.Trim(b, "/")
+	for {
+		if a == b {
+			return 0
+		}
+		if a == "" {
+			return -1
+		}
+		if b == "" {
+			return 1
+		}
+		segA, restA, _ := strings.Cut(a, "/")
+		segB, restB, _ := strings.Cut(b, "/")
+		if segA != segB {
+			return strings.Compare(segA, segB)
+		}
+		a, b = restA, restB
+	}
+}
+
+// sortEntriesByPath 按遍历顺序排序，保证断点前缀语义稳定
+func sortEntriesByPath(entries []RemoteEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return ComparePaths(entries[i].Path, entries[j].Path) < 0
+	})
+}
+
+// scanRemote 流式扫描远程文件
+//
+// 优先使用 ListOverride（一次性返回后排序回调），否则使用 Driver.ListStream，
+// 在遍历过程中逐个回调，调用方可以边扫描边处理。
+func (e *Engine) scanRemote(ctx context.Context, remotePath string, fn func(RemoteEntry) error) error {
+	opt := ListOptions{
+		Recursive: true,
+		MaxDepth:  100, // 默认最大深度100层，避免无限递归
+	}
+	if e.opts.ListOverride == nil {
+		return e.driver.ListStream(ctx, remotePath, opt, fn)
+	}
+
+	entries, err := e.opts.ListOverride(ctx, remotePath, opt)
+	if err != nil {
+		return err
+	}
+	sortEntriesByPath(entries)
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamSource 待处理文件来源：按顺序调用 emit 投递文件
+type streamSource func(ctx context.Context, emit func(RemoteEntry) error) error
+
+// streamItem 带编号的待处理文件
+type streamItem struct {
+	index int
+	entry RemoteEntry
+}
+
+// processStream 使用固定大小的 worker 池处理文件流
+//
+// source 在当前 goroutine 中运行，通过有界 channel 投递文件：
+// worker 全忙时 emit 阻塞，从而对目录遍历形成背压。
+// tracker 非 nil 时，每个文件处理成功后标记完成以推进断点。
+//
+// 返回：source 的错误优先；否则在被取消时返回 ctx.Err()
+func (e *Engine) processStream(ctx context.Context, source streamSource, stats *SyncStats, tracker *checkpointTracker) error {
+	return e.processItems(ctx, func(ctx context.Context, send func(streamItem) error) error {
+		return source(ctx, func(entry RemoteEntry) error {
+			return send(streamItem{index: tracker.add(entry.Path), entry: entry})
+		})
+	}, stats, tracker)
+}
+
+// processItems 使用固定大小的 worker 池处理已编号的文件（processStream 与 processDeferred 共用）
+func (e *Engine) processItems(ctx context.Context, source func(ctx context.Context, send func(streamItem) error) error, stats *SyncStats, tracker *checkpointTracker) error {
+	// 使用可取消的子 context，确保所有 goroutine 能收到取消信号
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := make(chan streamItem, e.opts.MaxConcurrency*2)
+	var wg sync.WaitGroup
+	var mu sync.Mutex // 保护 stats.Errors
+
+	for i := 0; i < e.opts.MaxConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				// Context 已取消：丢弃剩余文件
+				if ctx.Err() != nil {
+					continue
+				}
+				e.processStreamItem(ctx, item, stats, tracker, &mu)
+			}
+		}()
+	}
+
+	send := func(item streamItem) error {
+		select {
+		case items <- item:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	srcErr := source(ctx, send)
+	close(items)
+	if srcErr != nil {
+		cancel() // 通知所有 worker 停止
+	}
+	wg.Wait()
+
+	if srcErr != nil {
+		return srcErr
+	}
+	// 检查是否因取消而退出
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// processDeferred 处理扫描期间延后的文件（已占用断点编号），处理成功的文件推进断点
+func (e *Engine) processDeferred(ctx context.Context, items []streamItem, stats *SyncStats, tracker *checkpointTracker) error {
+	return e.processItems(ctx, func(ctx context.Context, send func(streamItem) error) error {
+		for _, item := range items {
+			if err := send(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, stats, tracker)
+}
+
+// processStreamItem 处理单个文件并记录结果
+func (e *Engine) processStreamItem(ctx context.Context, item streamItem, stats *SyncStats, tracker *checkpointTracker, mu *sync.Mutex) {
+	entry := item.entry
+	if err := e.processFile(ctx, entry, stats); err != nil {
+		atomic.AddInt64(&stats.FailedFiles, 1)
+
+		// 记录错误（限制最多100个）
+		mu.Lock()
+		if len(stats.Errors) < 100 {
+			stats.Errors = append(stats.Errors, SyncError{
+				FilePath: entry.Path,
+				Error:    err.Error(),
+				Time:     time.Now(),
+			})
+		}
+		mu.Unlock()
+
+		e.logger.Warn("处理文件失败",
+			zap.String("path", entry.Path),
+			zap.Error(err))
+		// 失败的文件不计入断点：断点停在它之前，恢复时重新处理
+		if ctx.Err() == nil {
+			tracker.markFailed(item.index)
+		}
+		return
+	}
+	atomic.AddInt64(&stats.ProcessedFiles, 1)
+
+	// 因取消而中断的文件不计入断点，恢复时需重新处理
+	if ctx.Err() == nil {
+		tracker.markDone(ctx, item.index)
+	}
+}
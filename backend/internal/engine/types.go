@@ -84,6 +84,16 @@ type RemoteEntry struct {
 
 	// IsDir 表示此条目是否为目录
 	IsDir bool
+
+	// Hash 是远端提供的内容哈希（格式为 "算法:十六进制值"，例如 "sha1:ab12..."）
+	//
+	// 仅部分驱动提供（例如 CloudDrive2），不支持时为空字符串。
+	Hash string
+
+	// PickCode 是 115 云盘文件的 PickCode
+	//
+	// 仅当驱动声明 DriverCapability.PickCode 且文件位于 115 云盘时存在。
+	PickCode string
 }
 
 // DriverEventType 枚举文件变更事件类型
@@ -339,6 +349,9 @@ type StrmEvent struct {
 	SourcePath   string
 	TargetPath   string
 	ErrorMessage string
+	MediaItem    string // 所属媒体条目（视频路径去掉扩展名），为空时按 SourcePath 推导
+	Sidecar      bool   // 是否为附属文件（字幕、NFO、海报等）事件
+	PreviousPath string // 移动前的路径（仅 move 事件）
 }
 
 // StrmEventSink 处理 STRM 事件回调
@@ -346,6 +359,31 @@ type StrmEventSink interface {
 	OnStrmEvent(ctx context.Context, event StrmEvent)
 }
 
+// SyncCheckpoint 全量同步断点
+//
+// 引擎在 RunOnce 中按遍历顺序（见 ComparePaths）投递文件，因此"已完成"的工作总是
+// 该顺序下的一个前缀：LastPath 及其之前的文件（以及完全落在该前缀内的目录子树）
+// 均已处理完毕，恢复执行时可以直接跳过。
+//
+// 注意：处理失败的文件同样视为已完成（避免单个坏文件阻塞断点推进），
+// 由下一次常规同步重新处理；断点仅对相同的 RemoteRoot 生效。
+type SyncCheckpoint struct {
+	RemoteRoot     string    `json:"remote_root"`     // 同步的远端根路径
+	LastPath       string    `json:"last_path"`       // 已完成前缀中的最后一个远端路径
+	ProcessedFiles int64     `json:"processed_files"` // 已完成前缀包含的文件数
+	UpdatedAt      time.Time `json:"updated_at"`      // 断点更新时间
+}
+
+// IsZero 判断断点是否为空
+func (c SyncCheckpoint) IsZero() bool {
+	return c.LastPath == ""
+}
+
+// CheckpointSink 处理断点保存回调
+type CheckpointSink interface {
+	SaveCheckpoint(ctx context.Context, checkpoint SyncCheckpoint)
+}
+
 // EngineOptions 引擎配置选项
 //
 // 这些选项控制同步引擎的行为，包括并发控制、
@@ -392,6 +430,26 @@ type EngineOptions struct {
 	// 启用后只记录将要删除的文件，不实际删除
 	OrphanCleanupDryRun bool
 
+	// CleanupSidecars 删除 STRM 时是否一并删除其附属文件（默认：false）
+	// 包括同名字幕/NFO/海报；目录内不再有 STRM 时还会删除 folder.jpg、extrafanart/ 等目录级附属文件
+	// 作用于增量删除事件与孤儿清理
+	CleanupSidecars bool
+
+	// DetectMoves 是否检测远端移动/重命名（默认：false）
+	// 启用后，新出现的远端文件若与某个孤儿 STRM 记录的签名（稳定标识，或文件名、大小与修改时间）唯一匹配，
+	// 会移动已有的 STRM 及其附属文件，而不是新建 STRM 再删除旧文件；增量同步中同一批事件的删除与新增按同样的签名配对。
+	// 需要同时设置 MoveIndex
+	DetectMoves bool
+
+	// MoveIndex 移动检测签名记录（可选）
+	// 设置后 RunOnce 与 RunIncremental 记录每个 STRM 对应的远端文件签名，调用方负责跨次同步保存；
+	// 未设置时不检测移动
+	MoveIndex *MoveIndex
+
+	// Layout 输出布局（可选）
+	// 未设置时输出路径镜像远端目录结构；设置 MediaLayout 时按解析出的标题、年份、季集整理为电影/剧集目录
+	Layout OutputLayout
+
 	// MountPathMapping 挂载路径映射（可选）
 	// 用于将访问路径转换为挂载路径，在用户替换规则之前执行
 	// 这是系统级的基线转换，确保路径统一
@@ -410,6 +468,17 @@ type EngineOptions struct {
 	// ListOverride 远端扫描自定义实现（可选）
 	// 返回的 RemoteEntry.Path 必须使用远端虚拟路径格式（以 "/" 开头）
 	ListOverride func(ctx context.Context, remotePath string, opt ListOptions) ([]RemoteEntry, error)
+
+	// ResumeFrom 断点续传起点（可选）
+	// 非空且 RemoteRoot 与本次同步一致时，RunOnce 会跳过断点之前已完成的文件
+	ResumeFrom *SyncCheckpoint
+
+	// CheckpointSink 断点保存回调（可选）
+	// RunOnce 在已完成前缀推进时按 CheckpointInterval 回调，处理结束（含取消）时再回调一次
+	CheckpointSink CheckpointSink
+
+	// CheckpointInterval 断点保存间隔（按已完成文件数，默认：100）
+	CheckpointInterval int
 }
 
 // SyncStats 同步统计信息
@@ -434,6 +503,10 @@ type SyncStats struct {
 	SkippedUnchanged int64 // 因内容和时间均未变化而跳过的文件数
 	FailedFiles      int64 // 处理失败的文件数
 	DeletedOrphans   int64 // 删除的孤儿文件数
+	DeletedSidecars  int64 // 随 STRM 一起删除的附属文件数
+	MovedFiles       int64 // 因远端移动/重命名而移动的 STRM 文件数
+	MovedSidecars    int64 // 随 STRM 一起移动的附属文件数
+	ResumedFiles     int64 // 因断点续传而跳过的文件数
 
 	// 时间统计
 	StartTime time.Time     // 开始时间
@@ -0,0 +1,624 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/robfig/cron/v3"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/pkg/crypto"
+	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 备份相关错误
+var (
+	// ErrBackupUnsupported 当前数据库驱动不支持内置备份
+	ErrBackupUnsupported = errors.New("仅 SQLite 数据库支持内置备份")
+	// ErrBackupNotFound 备份文件不存在或名称无效
+	ErrBackupNotFound = errors.New("备份不存在")
+	// ErrBackupBusy 有任务正在执行，不能恢复备份
+	ErrBackupBusy = errors.New("有任务正在执行")
+)
+
+// 备份原因
+const (
+	BackupReasonScheduled  = "scheduled"   // 定时备份
+	BackupReasonManual     = "manual"      // 手动备份
+	BackupReasonPreRestore = "pre-restore" // 恢复前自动备份
+)
+
+// backupTimeLayout 备份文件名中的时间格式
+const backupTimeLayout = "20060102-150405.000"
+
+// backupRestorePollInterval 恢复时等待其他连接释放数据库锁的间隔
+const backupRestorePollInterval = 100 * time.Millisecond
+
+// backupNamePattern 备份文件名：strmsync-<时间>-<原因>.db[.gz][.enc]
+var backupNamePattern = regexp.MustCompile(`^strmsync-(\d{8}-\d{6}\.\d{3})-([a-z][a-z0-9-]{0,31})\.db(\.gz)?(\.enc)?$`)
+
+// backupReasonPattern 备份原因只允许小写字母、数字与连字符
+var backupReasonPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
+
+// BackupInfo 备份文件信息
+type BackupInfo struct {
+	Name       string    `json:"name"`       // 文件名（同时作为备份标识）
+	Size       int64     `json:"size"`       // 文件大小（字节）
+	Reason     string    `json:"reason"`     // 备份原因: scheduled/manual/pre-restore
+	Compressed bool      `json:"compressed"` // 是否 gzip 压缩
+	Encrypted  bool      `json:"encrypted"`  // 是否加密
+	CreatedAt  time.Time `json:"created_at"` // 备份时间
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Backup     BackupInfo        `json:"backup"`      // 恢复的备份
+	PreRestore *BackupInfo       `json:"pre_restore"` // 恢复前自动创建的备份
+	Migrations []MigrationStatus `json:"migrations"`  // 恢复后补执行的迁移
+}
+
+// BackupManager SQLite 数据库备份管理器
+//
+// 备份使用 VACUUM INTO 生成一致性快照，不需要停止服务；可选 gzip 压缩与
+// AES-GCM 加密（使用 ENCRYPTION_KEY）。恢复使用 SQLite 在线备份 API
+// 将备份内容写回正在使用的数据库，恢复前会自动备份当前数据库。
+type BackupManager struct {
+	db            *gorm.DB
+	dbCfg         DatabaseConfig
+	cfg           BackupConfig
+	encryptionKey string
+	mu            sync.Mutex // 串行执行备份、清理与恢复
+	claims        ClaimPauser
+	log           *zap.Logger
+}
+
+// ClaimPauser 可暂停任务领取的队列（由 syncqueue.SyncQueue 实现）
+type ClaimPauser interface {
+	// PauseClaims 暂停领取并等待进行中的领取完成，调用 resume 恢复
+	PauseClaims() (resume func())
+}
+
+// SetClaimPauser 设置恢复备份期间暂停领取的任务队列
+//
+// 恢复期间暂停领取，确保检查"没有执行中的任务"之后不会有新任务开始执行；
+// 未设置时只检查执行中的任务数。
+func (m *BackupManager) SetClaimPauser(claims ClaimPauser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims = claims
+}
+
+// NewBackupManager 创建备份管理器
+//
+// 参数：
+//   - db: GORM 数据库连接（不能为 nil）
+//   - dbCfg: 数据库配置（驱动必须为 sqlite）
+//   - cfg: 备份配置
+//   - encryptionKey: 加密密钥（cfg.Encrypt 为 true 时不能为空）
+//
+// 返回：
+//   - *BackupManager: 备份管理器
+//   - error: 驱动不是 sqlite 时返回 ErrBackupUnsupported
+func NewBackupManager(db *gorm.DB, dbCfg DatabaseConfig, cfg BackupConfig, encryptionKey string) (*BackupManager, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	dbCfg.Driver = NormalizeDriver(dbCfg.Driver)
+	if dbCfg.Driver != DriverSQLite {
+		return nil, ErrBackupUnsupported
+	}
+	if strings.TrimSpace(cfg.Dir) == "" {
+		return nil, errors.New("备份目录不能为空")
+	}
+	if cfg.Encrypt && strings.TrimSpace(encryptionKey) == "" {
+		return nil, errors.New("启用备份加密时加密密钥不能为空")
+	}
+	return &BackupManager{
+		db:            db,
+		dbCfg:         dbCfg,
+		cfg:           cfg,
+		encryptionKey: encryptionKey,
+		log:           logger.With(zap.String("component", "backup")),
+	}, nil
+}
+
+// Run 按 Cron 表达式定时备份并清理过期备份，直到 ctx 取消
+// 未启用定时备份时立即返回
+func (m *BackupManager) Run(ctx context.Context) error {
+	if !m.cfg.Enabled {
+		return nil
+	}
+	scheduler := cron.New()
+	if _, err := scheduler.AddFunc(m.cfg.Cron, func() {
+		if _, err := m.Create(ctx, BackupReasonScheduled); err != nil {
+			if ctx.Err() == nil {
+				m.log.Error("定时备份数据库失败", zap.Error(err))
+			}
+			return
+		}
+		if _, err := m.Prune(ctx); err != nil {
+			m.log.Warn("清理过期备份失败", zap.Error(err))
+		}
+	}); err != nil {
+		return fmt.Errorf("无效的备份 Cron 表达式 %q: %w", m.cfg.Cron, err)
+	}
+
+	m.log.Info("数据库定时备份已启用",
+		zap.String("cron", m.cfg.Cron),
+		zap.String("dir", m.cfg.Dir),
+		zap.Int("keep_count", m.cfg.KeepCount),
+		zap.Int("keep_days", m.cfg.KeepDays))
+	scheduler.Start()
+	<-ctx.Done()
+	<-scheduler.Stop().Done()
+	return nil
+}
+
+// Create 立即备份数据库
+// reason 只允许小写字母、数字与连字符（如 manual、scheduled）
+func (m *BackupManager) Create(ctx context.Context, reason string) (*BackupInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(ctx, reason)
+}
+
+// List 返回备份列表（按时间倒序）
+func (m *BackupManager) List(ctx context.Context) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []BackupInfo{}, nil
+		}
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, ok := parseBackupName(entry.Name())
+		if !ok {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		info.Size = stat.Size()
+		backups = append(backups, info)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// Path 返回备份文件的完整路径（名称无效或文件不存在时返回 ErrBackupNotFound）
+func (m *BackupManager) Path(name string) (string, error) {
+	info, err := m.find(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(m.cfg.Dir, info.Name), nil
+}
+
+// Prune 按保留份数与天数删除旧备份，返回删除数量
+// 最新的一份备份始终保留
+func (m *BackupManager) Prune(ctx context.Context) (int, error) {
+	if m.cfg.KeepCount <= 0 && m.cfg.KeepDays <= 0 {
+		return 0, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backups, err := m.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().AddDate(0, 0, -m.cfg.KeepDays)
+	deleted := 0
+	for i, backup := range backups {
+		if i == 0 {
+			continue
+		}
+		expired := m.cfg.KeepDays > 0 && backup.CreatedAt.Before(cutoff)
+		if !expired && (m.cfg.KeepCount <= 0 || i < m.cfg.KeepCount) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.cfg.Dir, backup.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("删除备份 %s 失败: %w", backup.Name, err)
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		m.log.Info("已按保留策略清理备份",
+			zap.Int("deleted", deleted),
+			zap.Int("keep_count", m.cfg.KeepCount),
+			zap.Int("keep_days", m.cfg.KeepDays))
+	}
+	return deleted, nil
+}
+
+// Restore 使用指定备份覆盖当前数据库
+//
+// 流程：暂停领取任务并检查没有执行中的任务 → 解压/解密到临时文件并校验完整性与结构版本 →
+// 备份当前数据库 → 通过在线备份 API 写回 → 补执行备份之后新增的迁移。
+// 备份的结构版本高于程序支持的版本时拒绝恢复；任务领取在恢复结束后才恢复。
+func (m *BackupManager) Restore(ctx context.Context, name string) (*RestoreResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, err := m.find(name)
+	if err != nil {
+		return nil, err
+	}
+	// 整个恢复与迁移期间暂停领取，避免检查执行中任务数后又有任务开始执行
+	if m.claims != nil {
+		resume := m.claims.PauseClaims()
+		defer resume()
+	}
+	var running int64
+	if err := m.db.WithContext(ctx).Model(&model.TaskRun{}).Where("status = ?", "running").Count(&running).Error; err != nil {
+		return nil, fmt.Errorf("查询执行中的任务失败: %w", err)
+	}
+	if running > 0 {
+		return nil, fmt.Errorf("%w（%d 个），请等待完成或取消后再恢复", ErrBackupBusy, running)
+	}
+
+	snapshot := filepath.Join(m.cfg.Dir, info.Name+".restore")
+	defer os.Remove(snapshot)
+	if err := m.extract(info, snapshot); err != nil {
+		return nil, err
+	}
+	version, err := inspectBackup(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := NewSchemaMigrator(m.db, m.dbCfg)
+	if err != nil {
+		return nil, err
+	}
+	if version > migrator.LatestVersion() {
+		return nil, fmt.Errorf("备份的数据库结构版本（%d）高于程序支持的版本（%d），请升级程序后再恢复", version, migrator.LatestVersion())
+	}
+
+	preRestore, err := m.create(ctx, BackupReasonPreRestore)
+	if err != nil {
+		return nil, fmt.Errorf("恢复前备份当前数据库失败: %w", err)
+	}
+	if err := restoreSQLite(ctx, m.db, snapshot); err != nil {
+		return nil, fmt.Errorf("恢复数据库失败（恢复前的备份：%s）: %w", preRestore.Name, err)
+	}
+
+	result := &RestoreResult{Backup: *info, PreRestore: preRestore}
+	migrated, err := migrator.Up(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("恢复后迁移数据库失败（恢复前的备份：%s）: %w", preRestore.Name, err)
+	}
+	result.Migrations = migrated.Migrations
+	m.log.Info("数据库已从备份恢复",
+		zap.String("backup", info.Name),
+		zap.String("pre_restore", preRestore.Name),
+		zap.Int("migrations", len(result.Migrations)))
+	return result, nil
+}
+
+// create 备份数据库（调用方需持有 m.mu）
+func (m *BackupManager) create(ctx context.Context, reason string) (*BackupInfo, error) {
+	if !backupReasonPattern.MatchString(reason) {
+		return nil, fmt.Errorf("无效的备份原因: %q", reason)
+	}
+	name := fmt.Sprintf("strmsync-%s-%s.db", time.Now().Format(backupTimeLayout), reason)
+	if m.cfg.Compress {
+		name += ".gz"
+	}
+	if m.cfg.Encrypt {
+		name += ".enc"
+	}
+	dest := filepath.Join(m.cfg.Dir, name)
+
+	snapshot := dest + ".tmp"
+	if err := vacuumInto(m.db.WithContext(ctx), snapshot); err != nil {
+		return nil, err
+	}
+	defer os.Remove(snapshot)
+	if err := m.encodeFile(snapshot, dest); err != nil {
+		return nil, err
+	}
+
+	info, err := m.find(name)
+	if err != nil {
+		return nil, err
+	}
+	m.log.Info("数据库备份完成",
+		zap.String("name", info.Name),
+		zap.String("reason", reason),
+		zap.Int64("size", info.Size))
+	return info, nil
+}
+
+// find 校验备份名称并返回文件信息
+func (m *BackupManager) find(name string) (*BackupInfo, error) {
+	info, ok := parseBackupName(name)
+	if !ok {
+		return nil, ErrBackupNotFound
+	}
+	stat, err := os.Stat(filepath.Join(m.cfg.Dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrBackupNotFound
+		}
+		return nil, fmt.Errorf("读取备份文件失败: %w", err)
+	}
+	if stat.IsDir() {
+		return nil, ErrBackupNotFound
+	}
+	info.Size = stat.Size()
+	return &info, nil
+}
+
+// encodeFile 将快照按配置压缩、加密后写入 dest（先写临时文件再重命名）
+func (m *BackupManager) encodeFile(snapshot, dest string) error {
+	partial := dest + ".partial"
+	if !m.cfg.Compress && !m.cfg.Encrypt {
+		if err := os.Chmod(snapshot, 0o600); err != nil {
+			return fmt.Errorf("设置备份文件权限失败: %w", err)
+		}
+		if err := os.Rename(snapshot, dest); err != nil {
+			return fmt.Errorf("保存备份文件失败: %w", err)
+		}
+		return nil
+	}
+
+	src, err := os.Open(snapshot)
+	if err != nil {
+		return fmt.Errorf("打开数据库快照失败: %w", err)
+	}
+	defer src.Close()
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建备份文件失败: %w", err)
+	}
+	if err := m.encode(out, src); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return fmt.Errorf("写入备份文件失败: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("写入备份文件失败: %w", err)
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("保存备份文件失败: %w", err)
+	}
+	return nil
+}
+
+// encode 压缩并加密 src 写入 dst
+func (m *BackupManager) encode(dst io.Writer, src io.Reader) error {
+	if !m.cfg.Encrypt {
+		return compressTo(dst, src, m.cfg.Compress)
+	}
+
+	// 压缩结果通过管道流入加密，不落临时文件
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := compressTo(pw, src, m.cfg.Compress)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	err := crypto.EncryptStream(dst, pr, m.encryptionKey)
+	pr.CloseWithError(err)
+	if compressErr := <-done; err == nil {
+		err = compressErr
+	}
+	return err
+}
+
+// extract 将备份解密、解压到 dest
+func (m *BackupManager) extract(info *BackupInfo, dest string) error {
+	if info.Encrypted && strings.TrimSpace(m.encryptionKey) == "" {
+		return errors.New("备份已加密，但未配置加密密钥")
+	}
+	src, err := os.Open(filepath.Join(m.cfg.Dir, info.Name))
+	if err != nil {
+		return fmt.Errorf("打开备份文件失败: %w", err)
+	}
+	defer src.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer out.Close()
+
+	var reader io.Reader = src
+	if info.Encrypted {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(crypto.DecryptStream(pw, src, m.encryptionKey))
+		}()
+		defer pr.Close()
+		reader = pr
+	}
+	if info.Compressed {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("解压备份失败: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		return fmt.Errorf("读取备份失败: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// compressTo 将 src 写入 dst（compress 为 true 时使用 gzip 压缩）
+func compressTo(dst io.Writer, src io.Reader, compress bool) error {
+	if !compress {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// parseBackupName 从文件名解析备份信息
+func parseBackupName(name string) (BackupInfo, bool) {
+	match := backupNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return BackupInfo{}, false
+	}
+	createdAt, err := time.ParseInLocation(backupTimeLayout, match[1], time.Local)
+	if err != nil {
+		return BackupInfo{}, false
+	}
+	return BackupInfo{
+		Name:       name,
+		Reason:     match[2],
+		Compressed: match[3] != "",
+		Encrypted:  match[4] != "",
+		CreatedAt:  createdAt,
+	}, true
+}
+
+// inspectBackup 校验备份文件的完整性并返回其结构版本（迁移版本化之前的备份为 0）
+func inspectBackup(ctx context.Context, path string) (int, error) {
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return 0, fmt.Errorf("打开备份失败: %w", err)
+	}
+	defer conn.Close()
+
+	var check string
+	if err := conn.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&check); err != nil {
+		return 0, fmt.Errorf("备份不是有效的数据库: %w", err)
+	}
+	if check != "ok" {
+		return 0, fmt.Errorf("备份完整性校验失败: %s", check)
+	}
+
+	var tables int
+	if err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables); err != nil {
+		return 0, fmt.Errorf("读取备份结构失败: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	var version sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("读取备份结构版本失败: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// restoreSQLite 使用 SQLite 在线备份 API 将 src 文件的内容写入正在使用的数据库
+//
+// 其他连接持有读锁时等待其释放；写入完成后连接池中的其他连接会读取到新内容。
+func restoreSQLite(ctx context.Context, db *gorm.DB, src string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	destConn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer destConn.Close()
+
+	srcDB, err := sql.Open("sqlite3", src)
+	if err != nil {
+		return fmt.Errorf("打开备份失败: %w", err)
+	}
+	defer srcDB.Close()
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("打开备份失败: %w", err)
+	}
+	defer srcConn.Close()
+
+	return srcConn.Raw(func(srcRaw any) error {
+		return destConn.Raw(func(destRaw any) error {
+			source, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected sqlite connection type %T", srcRaw)
+			}
+			dest, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected sqlite connection type %T", destRaw)
+			}
+			backup, err := dest.Backup("main", source, "main")
+			if err != nil {
+				return fmt.Errorf("sqlite backup init: %w", err)
+			}
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					backup.Finish()
+					return fmt.Errorf("sqlite backup step: %w", err)
+				}
+				if done {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(backupRestorePollInterval):
+				}
+			}
+			if err := backup.Finish(); err != nil {
+				return fmt.Errorf("sqlite backup finish: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+// isMemorySQLite 判断 SQLite 路径是否为内存数据库
+func isMemorySQLite(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory") || strings.HasPrefix(path, "file::memory:")
+}
+
+// vacuumInto 使用 VACUUM INTO 将 SQLite 数据库一致性地复制到 dest
+//
+// 复制期间不阻塞读取，写入在复制完成前等待；dest 已存在时返回错误。
+func vacuumInto(db *gorm.DB, dest string) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("备份文件已存在: %s", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("创建备份目录失败: %w", err)
+	}
+	if err := db.Exec("VACUUM INTO ?", dest).Error; err != nil {
+		_ = os.Remove(dest)
+		return fmt.Errorf("vacuum into %s: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"gorm.io/gorm"
+)
+
+// newTestBackupManager 创建已迁移的临时数据库与备份管理器
+func newTestBackupManager(t *testing.T, cfg BackupConfig) (*BackupManager, DatabaseConfig) {
+	t.Helper()
+	conn, dbCfg := connectTestSQLite(t)
+	m, err := NewSchemaMigrator(conn, dbCfg)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	if _, err := m.Up(context.Background(), 0); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	cfg.Dir = filepath.Join(filepath.Dir(dbCfg.Path), "backups")
+	manager, err := NewBackupManager(conn, dbCfg, cfg, "test-encryption-key")
+	if err != nil {
+		t.Fatalf("new backup manager: %v", err)
+	}
+	return manager, dbCfg
+}
+
+func TestBackupManager_CreateAndRestore(t *testing.T) {
+	for _, cfg := range []BackupConfig{
+		{},
+		{Compress: true},
+		{Compress: true, Encrypt: true},
+	} {
+		manager, _ := newTestBackupManager(t, cfg)
+		ctx := context.Background()
+		conn := manager.db
+
+		job := model.Job{Name: "电影", SourcePath: "/src", TargetPath: "/dst", STRMPath: "/src"}
+		if err := conn.Create(&job).Error; err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		backup, err := manager.Create(ctx, BackupReasonManual)
+		if err != nil {
+			t.Fatalf("create backup %+v: %v", cfg, err)
+		}
+		if backup.Compressed != cfg.Compress || backup.Encrypted != cfg.Encrypt || backup.Size == 0 {
+			t.Fatalf("unexpected backup info for %+v: %+v", cfg, backup)
+		}
+		if cfg.Encrypt {
+			data, _ := os.ReadFile(filepath.Join(manager.cfg.Dir, backup.Name))
+			if strings.Contains(string(data), "SQLite format") {
+				t.Fatalf("expected encrypted backup")
+			}
+		}
+
+		// 备份后的修改在恢复后消失
+		if err := conn.Model(&job).Update("name", "剧集").Error; err != nil {
+			t.Fatalf("rename job: %v", err)
+		}
+		if err := conn.Create(&model.Job{Name: "动画", SourcePath: "/a", TargetPath: "/b", STRMPath: "/a"}).Error; err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+
+		result, err := manager.Restore(ctx, backup.Name)
+		if err != nil {
+			t.Fatalf("restore %+v: %v", cfg, err)
+		}
+		if result.PreRestore == nil || result.PreRestore.Reason != BackupReasonPreRestore {
+			t.Fatalf("expected pre-restore backup, got %+v", result.PreRestore)
+		}
+		var jobs []model.Job
+		if err := conn.Order("id").Find(&jobs).Error; err != nil {
+			t.Fatalf("list jobs: %v", err)
+		}
+		if len(jobs) != 1 || jobs[0].Name != "电影" {
+			t.Fatalf("unexpected jobs after restore: %+v", jobs)
+		}
+
+		backups, err := manager.List(ctx)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(backups) != 2 || backups[0].Name != result.PreRestore.Name {
+			t.Fatalf("unexpected backups: %+v", backups)
+		}
+	}
+}
+
+func TestBackupManager_RestoreRefusals(t *testing.T) {
+	manager, _ := newTestBackupManager(t, BackupConfig{Compress: true})
+	ctx := context.Background()
+	conn := manager.db
+
+	for _, name := range []string{"../data.db", "strmsync-20260101-030000.000-manual.db"} {
+		if _, err := manager.Path(name); !errors.Is(err, ErrBackupNotFound) {
+			t.Fatalf("expected not found for %q, got %v", name, err)
+		}
+	}
+
+	backup, err := manager.Create(ctx, BackupReasonManual)
+	if err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+
+	// 有执行中的任务时拒绝恢复
+	job := model.Job{Name: "电影", SourcePath: "/src", TargetPath: "/dst", STRMPath: "/src"}
+	if err := conn.Create(&job).Error; err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	run := model.TaskRun{JobID: job.ID, Status: "running"}
+	if err := conn.Create(&run).Error; err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if _, err := manager.Restore(ctx, backup.Name); !errors.Is(err, ErrBackupBusy) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+	if err := conn.Model(&run).Update("status", "completed").Error; err != nil {
+		t.Fatalf("finish run: %v", err)
+	}
+
+	// 损坏的备份在覆盖前被拒绝
+	path, _ := manager.Path(backup.Name)
+	if err := os.WriteFile(path, []byte("not a backup"), 0o600); err != nil {
+		t.Fatalf("corrupt backup: %v", err)
+	}
+	if _, err := manager.Restore(ctx, backup.Name); err == nil {
+		t.Fatalf("expected corrupt backup to be rejected")
+	}
+	var count int64
+	conn.Model(&model.Job{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected database untouched, got %d jobs", count)
+	}
+}
+
+// recordingPauser 记录暂停与恢复领取的次数
+type recordingPauser struct {
+	paused  bool
+	pauses  int
+	resumes int
+}
+
+func (p *recordingPauser) PauseClaims() func() {
+	p.paused = true
+	p.pauses++
+	return func() {
+		p.paused = false
+		p.resumes++
+	}
+}
+
+func TestBackupManager_RestorePausesClaims(t *testing.T) {
+	manager, _ := newTestBackupManager(t, BackupConfig{})
+	ctx := context.Background()
+	conn := manager.db
+
+	backup, err := manager.Create(ctx, BackupReasonManual)
+	if err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+	pauser := &recordingPauser{}
+	manager.SetClaimPauser(pauser)
+
+	// 恢复期间领取保持暂停，迁移完成后才恢复
+	conn.Callback().Query().Before("gorm:query").Register("test:assert_paused", func(tx *gorm.DB) {
+		if !pauser.paused {
+			t.Errorf("query %s ran while claims were not paused", tx.Statement.Table)
+		}
+	})
+	if _, err := manager.Restore(ctx, backup.Name); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	conn.Callback().Query().Remove("test:assert_paused")
+	if pauser.pauses != 1 || pauser.resumes != 1 || pauser.paused {
+		t.Fatalf("expected one pause and resume, got %+v", pauser)
+	}
+
+	// 有执行中的任务时拒绝恢复，并恢复领取
+	job := model.Job{Name: "电影", SourcePath: "/src", TargetPath: "/dst", STRMPath: "/src"}
+	if err := conn.Create(&job).Error; err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if err := conn.Create(&model.TaskRun{JobID: job.ID, Status: "running"}).Error; err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if _, err := manager.Restore(ctx, backup.Name); !errors.Is(err, ErrBackupBusy) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+	if pauser.pauses != 2 || pauser.resumes != 2 || pauser.paused {
+		t.Fatalf("expected claims resumed after refusal, got %+v", pauser)
+	}
+}
+
+func TestBackupManager_Prune(t *testing.T) {
+	manager, _ := newTestBackupManager(t, BackupConfig{KeepCount: 3, KeepDays: 10})
+	if err := os.MkdirAll(manager.cfg.Dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	now := time.Now()
+	var names []string
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour, 20 * 24 * time.Hour} {
+		name := "strmsync-" + now.Add(-age).Format(backupTimeLayout) + "-scheduled.db.gz"
+		names = append(names, name)
+		if err := os.WriteFile(filepath.Join(manager.cfg.Dir, name), []byte("x"), 0o600); err != nil {
+			t.Fatalf("write backup: %v", err)
+		}
+	}
+	// 非备份文件不受影响
+	if err := os.WriteFile(filepath.Join(manager.cfg.Dir, "notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	deleted, err := manager.Prune(context.Background())
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 deleted, got %d", deleted)
+	}
+	backups, _ := manager.List(context.Background())
+	if len(backups) != 3 || backups[0].Name != names[0] || backups[2].Name != names[2] {
+		t.Fatalf("unexpected remaining backups: %+v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(manager.cfg.Dir, "notes.txt")); err != nil {
+		t.Fatalf("expected unrelated file kept: %v", err)
+	}
+
+	// 最新的备份即使过期也保留
+	for _, b := range backups {
+		_ = os.Remove(filepath.Join(manager.cfg.Dir, b.Name))
+	}
+	if deleted, err := manager.Prune(context.Background()); err != nil || deleted != 0 {
+		t.Fatalf("expected empty prune, got %d err=%v", deleted, err)
+	}
+	if err := os.WriteFile(filepath.Join(manager.cfg.Dir, names[4]), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	if deleted, err := manager.Prune(context.Background()); err != nil || deleted != 0 {
+		t.Fatalf("expected newest backup kept, got %d deleted err=%v", deleted, err)
+	}
+}
+
+func TestNewBackupManager_RequiresSQLite(t *testing.T) {
+	conn, _ := connectTestSQLite(t)
+	_, err := NewBackupManager(conn, DatabaseConfig{Driver: DriverPostgres, DSN: "postgres://x"}, BackupConfig{Dir: t.TempDir()}, "key")
+	if !errors.Is(err, ErrBackupUnsupported) {
+		t.Fatalf("expected unsupported error, got %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/robfig/cron/v3"
 	appconfig "github.com/strmsync/strmsync/internal/config"
 )
 
@@ -23,6 +24,10 @@ type Config struct {
 	Security SecurityConfig // 安全配置
 	Scanner  ScannerConfig  // 扫描服务配置
 	Notifier NotifierConfig // 通知服务配置
+	Health   HealthConfig   // 健康检查配置
+	Worker   WorkerConfig   // 任务执行器配置
+	Backup   BackupConfig   // 数据库备份配置
+	History  HistoryConfig  // 执行历史保留配置
 }
 
 // ServerConfig HTTP服务器设置
@@ -33,17 +38,20 @@ type ServerConfig struct {
 
 // DatabaseConfig 数据库设置
 type DatabaseConfig struct {
-	Path string // 数据库文件路径
+	Driver string // 数据库驱动：sqlite/postgres/mysql
+	Path   string // 数据库文件路径（sqlite）
+	DSN    string // 连接字符串（postgres/mysql）
 }
 
 // LogConfig 日志设置
 type LogConfig struct {
-	Level     string          // 日志级别
-	Path      string          // 日志目录
-	ToDB      bool            // 是否写入数据库
-	SQL       bool            // 是否启用SQL日志
-	SQLSlowMs int             // SQL慢查询阈值（毫秒，0表示记录所有）
-	Rotate    LogRotateConfig // 日志分割与压缩配置
+	Level     string             // 日志级别
+	Path      string             // 日志目录
+	ToDB      bool               // 是否写入数据库
+	SQL       bool               // 是否启用SQL日志
+	SQLSlowMs int                // SQL慢查询阈值（毫秒，0表示记录所有）
+	Rotate    LogRotateConfig    // 日志分割与压缩配置
+	Retention LogRetentionConfig // 数据库日志保留策略
 }
 
 // LogRotateConfig 日志分割与压缩配置
@@ -54,6 +62,13 @@ type LogRotateConfig struct {
 	Compress   bool // 是否压缩旧日志
 }
 
+// LogRetentionConfig 数据库日志保留策略
+// 值为 0 表示不按该条件清理
+type LogRetentionConfig struct {
+	Days    int // 保留天数
+	MaxRows int // 最多保留条数
+}
+
 // SecurityConfig 安全相关设置
 type SecurityConfig struct {
 	EncryptionKey string // 加密密钥
@@ -78,8 +93,50 @@ type NotifierConfig struct {
 	Scope           string // 通知范围：global/source
 }
 
+// HealthConfig 健康检查（就绪探针）设置
+// 阈值为 0 表示不检查
+type HealthConfig struct {
+	MaxPendingTasks           int  // 等待领取的任务数上限
+	MaxPendingAgeSeconds      int  // 最早待执行任务的最长等待时间（秒）
+	ServerCheckTTLSeconds     int  // 服务器连接测试结果缓存时间（秒）
+	ServerCheckTimeoutSeconds int  // 单个服务器连接测试超时（秒）
+	RequireDataServers        bool // 启用的数据服务器连接失败时是否判定未就绪
+	RequireMediaServers       bool // 启用的媒体服务器连接失败时是否判定未就绪
+}
+
+// WorkerConfig 任务执行器（Worker 池）设置
+// 多个实例共享同一数据库时，每个实例需使用不同的 ID
+type WorkerConfig struct {
+	ID           string // Worker 标识（默认使用主机名）
+	Group        string // Worker 分组（只执行未分组或同分组的任务）
+	Concurrency  int    // 并发执行的任务数
+	LeaseSeconds int    // 执行租约时长（秒）
+	Fsync        bool   // 写入 STRM 与元数据文件后是否 fsync
+}
+
+// BackupConfig 数据库自动备份设置（仅 SQLite）
+// 保留数量与天数为 0 表示不按该条件清理，最新的备份始终保留
+type BackupConfig struct {
+	Enabled   bool   // 是否启用定时备份
+	Dir       string // 备份目录（默认为数据库所在目录下的 backups）
+	Cron      string // 备份时间（标准 5 段 Cron 表达式）
+	KeepCount int    // 最多保留份数
+	KeepDays  int    // 保留天数
+	Compress  bool   // 是否 gzip 压缩
+	Encrypt   bool   // 是否使用 ENCRYPTION_KEY 加密
+}
+
+// HistoryConfig 执行历史（task_runs/task_run_events）保留策略
+// 值为 0 表示不按该条件清理；执行中与等待执行的记录始终保留
+type HistoryConfig struct {
+	KeepPerJob     int  // 每个任务保留最近的执行记录数
+	EventDays      int  // 事件明细保留天数
+	KeepLastFailed bool // 始终保留每个任务最近一次失败的执行记录
+	BatchSize      int  // 每批删除的执行记录数
+}
+
 // LoadFromEnv 从环境变量加载配置
-// 环境变量示例：PORT, LOG_LEVEL, DB_PATH, ENCRYPTION_KEY
+// 环境变量示例：PORT, LOG_LEVEL, DB_DRIVER, DB_PATH, ENCRYPTION_KEY
 func LoadFromEnv() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
@@ -87,7 +144,9 @@ func LoadFromEnv() (*Config, error) {
 			Host: getEnv("HOST", appconfig.DefaultServerHost),
 		},
 		Database: DatabaseConfig{
-			Path: resolveDBPath(),
+			Driver: NormalizeDriver(getEnv("DB_DRIVER", appconfig.DefaultDBDriver)),
+			Path:   resolveDBPath(),
+			DSN:    strings.TrimSpace(getEnv("DB_DSN", "")),
 		},
 		Log: LogConfig{
 			Level:     getEnv("LOG_LEVEL", appconfig.DefaultLogLevel),
@@ -101,6 +160,10 @@ func LoadFromEnv() (*Config, error) {
 				MaxAgeDays: getEnvInt("LOG_ROTATE_MAX_AGE_DAYS", appconfig.DefaultLogRotateMaxAgeDays),
 				Compress:   getEnvBool("LOG_ROTATE_COMPRESS", appconfig.DefaultLogRotateCompress),
 			},
+			Retention: LogRetentionConfig{
+				Days:    getEnvInt("LOG_RETENTION_DAYS", appconfig.DefaultLogRetentionDays),
+				MaxRows: getEnvInt("LOG_RETENTION_MAX_ROWS", appconfig.DefaultLogRetentionMaxRows),
+			},
 		},
 		Security: SecurityConfig{
 			EncryptionKey: getEnv("ENCRYPTION_KEY", appconfig.DefaultEncryptionKey),
@@ -120,7 +183,37 @@ func LoadFromEnv() (*Config, error) {
 			DebounceSeconds: getEnvInt("NOTIFIER_DEBOUNCE", appconfig.DefaultNotifierDebounceSeconds),
 			Scope:           getEnv("NOTIFIER_SCOPE", appconfig.DefaultNotifierScope),
 		},
+		Health: HealthConfig{
+			MaxPendingTasks:           getEnvInt("HEALTH_MAX_PENDING_TASKS", appconfig.DefaultHealthMaxPendingTasks),
+			MaxPendingAgeSeconds:      getEnvInt("HEALTH_MAX_PENDING_AGE", appconfig.DefaultHealthMaxPendingAgeSeconds),
+			ServerCheckTTLSeconds:     getEnvInt("HEALTH_SERVER_CHECK_TTL", appconfig.DefaultHealthServerCheckTTLSeconds),
+			ServerCheckTimeoutSeconds: getEnvInt("HEALTH_SERVER_CHECK_TIMEOUT", appconfig.DefaultHealthServerCheckTimeoutSeconds),
+			RequireDataServers:        getEnvBool("HEALTH_REQUIRE_DATA_SERVERS", appconfig.DefaultHealthRequireDataServers),
+			RequireMediaServers:       getEnvBool("HEALTH_REQUIRE_MEDIA_SERVERS", appconfig.DefaultHealthRequireMediaServers),
+		},
+		Worker: WorkerConfig{
+			ID:           resolveWorkerID(),
+			Group:        strings.TrimSpace(getEnv("WORKER_GROUP", appconfig.DefaultWorkerGroup)),
+			Concurrency:  getEnvInt("WORKER_CONCURRENCY", appconfig.DefaultWorkerConcurrency),
+			LeaseSeconds: getEnvInt("WORKER_LEASE_SECONDS", appconfig.DefaultWorkerLeaseSeconds),
+			Fsync:        getEnvBool("WRITE_FSYNC", appconfig.DefaultWriteFsync),
+		},
+		Backup: BackupConfig{
+			Enabled:   getEnvBool("BACKUP_ENABLED", appconfig.DefaultBackupEnabled),
+			Cron:      strings.TrimSpace(getEnv("BACKUP_CRON", appconfig.DefaultBackupCron)),
+			KeepCount: getEnvInt("BACKUP_KEEP", appconfig.DefaultBackupKeepCount),
+			KeepDays:  getEnvInt("BACKUP_KEEP_DAYS", appconfig.DefaultBackupKeepDays),
+			Compress:  getEnvBool("BACKUP_COMPRESS", appconfig.DefaultBackupCompress),
+			Encrypt:   getEnvBool("BACKUP_ENCRYPT", appconfig.DefaultBackupEncrypt),
+		},
+		History: HistoryConfig{
+			KeepPerJob:     getEnvInt("RUN_RETENTION_PER_JOB", appconfig.DefaultRunRetentionPerJob),
+			EventDays:      getEnvInt("RUN_RETENTION_EVENT_DAYS", appconfig.DefaultRunRetentionEventDays),
+			KeepLastFailed: getEnvBool("RUN_RETENTION_KEEP_LAST_FAILED", appconfig.DefaultRunRetentionKeepLastFailed),
+			BatchSize:      getEnvInt("RUN_RETENTION_BATCH_SIZE", appconfig.DefaultRunRetentionBatchSize),
+		},
 	}
+	cfg.Backup.Dir = resolveBackupDir(cfg.Database.Path)
 
 	if err := Validate(cfg); err != nil {
 		return nil, err
@@ -149,6 +242,26 @@ func resolveDBPath() string {
 	return filepath.Join(filepath.Dir(execPath), appconfig.DefaultDBPath)
 }
 
+// resolveBackupDir 返回备份目录：优先 BACKUP_DIR，其次数据库所在目录下的 backups
+func resolveBackupDir(dbPath string) string {
+	if dir := strings.TrimSpace(getEnv("BACKUP_DIR", "")); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(dbPath), appconfig.DefaultBackupDirName)
+}
+
+// resolveWorkerID 返回 Worker 标识：优先 WORKER_ID，其次主机名（容器内即容器ID）
+func resolveWorkerID() string {
+	if id := strings.TrimSpace(getEnv("WORKER_ID", "")); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(hostname)
+}
+
 // getEnvInt 获取整数类型环境变量，如果不存在或无效则返回默认值
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -185,8 +298,17 @@ func Validate(cfg *Config) error {
 	}
 
 	// 数据库验证
-	if strings.TrimSpace(cfg.Database.Path) == "" {
-		return errors.New("数据库路径不能为空")
+	switch NormalizeDriver(cfg.Database.Driver) {
+	case DriverSQLite:
+		if strings.TrimSpace(cfg.Database.Path) == "" {
+			return errors.New("数据库路径不能为空")
+		}
+	case DriverPostgres, DriverMySQL:
+		if strings.TrimSpace(cfg.Database.DSN) == "" {
+			return fmt.Errorf("数据库驱动 %s 需要设置连接字符串（DB_DSN）", cfg.Database.Driver)
+		}
+	default:
+		return fmt.Errorf("数据库驱动必须是 sqlite|postgres|mysql 之一，当前值: %q", cfg.Database.Driver)
 	}
 
 	// 日志验证
@@ -212,6 +334,67 @@ func Validate(cfg *Config) error {
 	if cfg.Log.Rotate.MaxAgeDays < 0 {
 		return fmt.Errorf("日志保留天数不能为负数，当前值: %d", cfg.Log.Rotate.MaxAgeDays)
 	}
+	if cfg.Log.Retention.Days < 0 {
+		return fmt.Errorf("数据库日志保留天数不能为负数，当前值: %d", cfg.Log.Retention.Days)
+	}
+	if cfg.Log.Retention.MaxRows < 0 {
+		return fmt.Errorf("数据库日志保留条数不能为负数，当前值: %d", cfg.Log.Retention.MaxRows)
+	}
+
+	// 健康检查验证
+	if cfg.Health.MaxPendingTasks < 0 {
+		return fmt.Errorf("待执行任务数阈值不能为负数，当前值: %d", cfg.Health.MaxPendingTasks)
+	}
+	if cfg.Health.MaxPendingAgeSeconds < 0 {
+		return fmt.Errorf("待执行任务等待时间阈值不能为负数，当前值: %d", cfg.Health.MaxPendingAgeSeconds)
+	}
+	if cfg.Health.ServerCheckTTLSeconds < 0 {
+		return fmt.Errorf("服务器连接测试缓存时间不能为负数，当前值: %d", cfg.Health.ServerCheckTTLSeconds)
+	}
+	if cfg.Health.ServerCheckTimeoutSeconds < 0 {
+		return fmt.Errorf("服务器连接测试超时不能为负数，当前值: %d", cfg.Health.ServerCheckTimeoutSeconds)
+	}
+
+	// Worker 验证
+	if len(cfg.Worker.ID) > 128 {
+		return fmt.Errorf("Worker ID 长度不能超过128，当前值: %q", cfg.Worker.ID)
+	}
+	if len(cfg.Worker.Group) > 64 {
+		return fmt.Errorf("Worker 分组长度不能超过64，当前值: %q", cfg.Worker.Group)
+	}
+	if cfg.Worker.Concurrency <= 0 {
+		return fmt.Errorf("Worker 并发数必须为正数，当前值: %d", cfg.Worker.Concurrency)
+	}
+	if cfg.Worker.LeaseSeconds < 3 {
+		return fmt.Errorf("Worker 执行租约时长不能小于3秒，当前值: %d", cfg.Worker.LeaseSeconds)
+	}
+
+	// 备份验证
+	if cfg.Backup.Enabled {
+		if NormalizeDriver(cfg.Database.Driver) != DriverSQLite {
+			return fmt.Errorf("自动备份仅支持 SQLite 数据库，当前驱动: %s", cfg.Database.Driver)
+		}
+		if _, err := cron.ParseStandard(cfg.Backup.Cron); err != nil {
+			return fmt.Errorf("备份 Cron 表达式无效: %q: %w", cfg.Backup.Cron, err)
+		}
+	}
+	if cfg.Backup.KeepCount < 0 {
+		return fmt.Errorf("备份保留份数不能为负数，当前值: %d", cfg.Backup.KeepCount)
+	}
+	if cfg.Backup.KeepDays < 0 {
+		return fmt.Errorf("备份保留天数不能为负数，当前值: %d", cfg.Backup.KeepDays)
+	}
+
+	// 执行历史保留验证
+	if cfg.History.KeepPerJob < 0 {
+		return fmt.Errorf("每个任务保留的执行记录数不能为负数，当前值: %d", cfg.History.KeepPerJob)
+	}
+	if cfg.History.EventDays < 0 {
+		return fmt.Errorf("执行事件保留天数不能为负数，当前值: %d", cfg.History.EventDays)
+	}
+	if cfg.History.BatchSize <= 0 {
+		return fmt.Errorf("执行历史清理批次大小必须为正数，当前值: %d", cfg.History.BatchSize)
+	}
 
 	// 安全验证
 	if strings.TrimSpace(cfg.Security.EncryptionKey) == "" {
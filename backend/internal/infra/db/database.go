@@ -1,4 +1,4 @@
-// Package database 提供SQLite连接设置和迁移工具
+// Package database 提供数据库连接设置和迁移工具（SQLite/PostgreSQL/MySQL）
 package db
 
 import (
@@ -14,7 +14,6 @@ import (
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/pkg/logger"
 	"go.uber.org/zap"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 )
@@ -24,21 +23,74 @@ var (
 	dbInst *gorm.DB
 )
 
-// InitWithConfig 打开SQLite数据库，配置连接池，并运行迁移
+// InitWithConfig 打开数据库，配置连接池，运行迁移并设置为全局实例
 // 允许传入已加载/验证的日志配置，避免重复读取环境变量
-// dbPath 是SQLite文件路径，例如："data.db"
+// dbCfg 是数据库配置（驱动为 sqlite 时使用 Path，否则使用 DSN）
 // logCfg 是必填的日志配置
-func InitWithConfig(dbPath string, logCfg *LogConfig) error {
-	if strings.TrimSpace(dbPath) == "" {
-		return errors.New("数据库路径为空")
+func InitWithConfig(dbCfg DatabaseConfig, logCfg *LogConfig) error {
+	conn, err := Open(dbCfg, logCfg)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	dbInst = conn
+	mu.Unlock()
+
+	return nil
+}
+
+// Open 打开数据库，配置连接池并执行待执行的版本化迁移，不修改全局实例
+func Open(dbCfg DatabaseConfig, logCfg *LogConfig) (*gorm.DB, error) {
+	conn, err := Connect(dbCfg, logCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewSchemaMigrator(conn, dbCfg)
+	if err == nil {
+		var result *MigrationResult
+		result, err = migrator.Up(context.Background(), 0)
+		if err == nil && len(result.Migrations) > 0 {
+			logger.LogInfo(fmt.Sprintf("数据库迁移完成，当前版本 %d", migrator.LatestVersion()),
+				zap.Int("applied", len(result.Migrations)),
+				zap.String("backup", result.BackupPath))
+		}
+	}
+	if err != nil {
+		if sqlDB, dbErr := conn.DB(); dbErr == nil {
+			_ = sqlDB.Close()
+		}
+		return nil, err
 	}
+	return conn, nil
+}
+
+// Connect 打开数据库并配置连接池，不执行迁移（用于迁移命令行）
+func Connect(dbCfg DatabaseConfig, logCfg *LogConfig) (*gorm.DB, error) {
 	if logCfg == nil {
-		return errors.New("日志配置为空")
+		return nil, errors.New("日志配置为空")
+	}
+	driver := NormalizeDriver(dbCfg.Driver)
+	dbCfg.Driver = driver
+	switch driver {
+	case DriverSQLite:
+		if strings.TrimSpace(dbCfg.Path) == "" {
+			return nil, errors.New("数据库路径为空")
+		}
+		dir := filepath.Dir(dbCfg.Path)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+		}
+	default:
+		if strings.TrimSpace(dbCfg.DSN) == "" {
+			return nil, errors.New("数据库连接字符串为空")
+		}
 	}
 
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("创建数据库目录失败: %w", err)
+	dialector, err := newDialector(dbCfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// 配置 GORM Logger：忽略 ErrRecordNotFound 以避免日志污染
@@ -57,122 +109,27 @@ func InitWithConfig(dbPath string, logCfg *LogConfig) error {
 		},
 	}
 
-	conn, err := gorm.Open(sqlite.Open(dbPath), gormConfig)
+	conn, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
-		return fmt.Errorf("打开SQLite失败: %w", err)
+		return nil, fmt.Errorf("打开数据库失败（%s）: %w", driver, err)
 	}
 
 	sqlDB, err := conn.DB()
 	if err != nil {
-		return fmt.Errorf("获取底层数据库失败: %w", err)
-	}
-
-	// SQLite连接池配置（安全默认值）
-	sqlDB.SetMaxOpenConns(10)
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)
-
-	// 迁移前检查：如果Job表已存在，检查是否有重复名称
-	// 这样可以在AutoMigrate尝试创建uniqueIndex之前给出清晰的错误提示
-	if conn.Migrator().HasTable(model.Job{}) {
-		if err := checkJobNameDuplicates(conn); err != nil {
-			return fmt.Errorf("数据完整性检查失败: %w", err)
-		}
+		return nil, fmt.Errorf("获取底层数据库失败: %w", err)
 	}
 
-	// 自动迁移所有表
-	if err := conn.AutoMigrate(
-		model.DataServer{},
-		model.MediaServer{},
-		model.Job{},
-		model.TaskRun{},
-		model.TaskRunEvent{},
-		model.LogEntry{},
-		model.Setting{},
-	); err != nil {
-		// 迁移失败时给出友好提示，可能是数据重复导致
-		return fmt.Errorf("自动迁移失败: %w（如遇到唯一约束错误，请检查jobs表是否有重复的name字段）", err)
-	}
-	if err := backfillJobRemoteRoot(conn); err != nil {
-		return fmt.Errorf("回填远程根目录失败: %w", err)
-	}
-	if err := dropJobRemotePathColumn(conn); err != nil {
-		return fmt.Errorf("清理旧远程路径列失败: %w", err)
-	}
-
-	mu.Lock()
-	dbInst = conn
-	mu.Unlock()
-
-	return nil
-}
-
-func backfillJobRemoteRoot(db *gorm.DB) error {
-	if db == nil {
-		return errors.New("db is nil")
-	}
-
-	type tableColumn struct {
-		Name string `gorm:"column:name"`
-	}
-	var columns []tableColumn
-	if err := db.Raw("PRAGMA table_info(jobs)").Scan(&columns).Error; err != nil {
-		return fmt.Errorf("query jobs table info: %w", err)
-	}
-
-	hasRemotePath := false
-	hasRemoteRoot := false
-	for _, col := range columns {
-		switch strings.ToLower(strings.TrimSpace(col.Name)) {
-		case "remote_path":
-			hasRemotePath = true
-		case "remote_root":
-			hasRemoteRoot = true
-		}
-	}
-	if !hasRemotePath || !hasRemoteRoot {
-		return nil
-	}
-
-	res := db.Exec(`UPDATE jobs
-SET remote_root = remote_path
-WHERE (remote_root IS NULL OR remote_root = '')
-  AND remote_path IS NOT NULL
-  AND remote_path <> ''`)
-	if res.Error != nil {
-		return fmt.Errorf("update jobs remote_root: %w", res.Error)
-	}
-	return nil
-}
-
-func dropJobRemotePathColumn(db *gorm.DB) error {
-	if db == nil {
-		return errors.New("db is nil")
-	}
-
-	type tableColumn struct {
-		Name string `gorm:"column:name"`
-	}
-	var columns []tableColumn
-	if err := db.Raw("PRAGMA table_info(jobs)").Scan(&columns).Error; err != nil {
-		return fmt.Errorf("query jobs table info: %w", err)
-	}
-
-	hasRemotePath := false
-	for _, col := range columns {
-		if strings.EqualFold(strings.TrimSpace(col.Name), "remote_path") {
-			hasRemotePath = true
-			break
-		}
-	}
-	if !hasRemotePath {
-		return nil
+	// 连接池配置（安全默认值）
+	if driver == DriverSQLite {
+		sqlDB.SetMaxOpenConns(10)
+		sqlDB.SetMaxIdleConns(5)
+	} else {
+		sqlDB.SetMaxOpenConns(25)
+		sqlDB.SetMaxIdleConns(10)
 	}
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
 
-	if err := db.Exec("ALTER TABLE jobs DROP COLUMN remote_path").Error; err != nil {
-		return fmt.Errorf("drop jobs.remote_path: %w", err)
-	}
-	return nil
+	return conn, nil
 }
 
 // checkJobNameDuplicates 检查Job表中是否存在重复的name字段
@@ -187,7 +144,7 @@ func checkJobNameDuplicates(db *gorm.DB) error {
 	if err := db.Model(model.Job{}).
 		Select("name, COUNT(*) as count").
 		Group("name").
-		Having("count > 1").
+		Having("COUNT(*) > 1").
 		Find(&duplicates).Error; err != nil {
 		return fmt.Errorf("检查重复名称失败: %w", err)
 	}
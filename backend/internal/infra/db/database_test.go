@@ -0,0 +1,195 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"gorm.io/gorm"
+)
+
+// openTestDB 打开数据库并在测试结束时关闭
+func openTestDB(t *testing.T, cfg DatabaseConfig) *gorm.DB {
+	t.Helper()
+	conn, err := Open(cfg, &LogConfig{})
+	if err != nil {
+		t.Fatalf("open %s: %v", cfg.Driver, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// testDatabaseConfigs 返回待测试的数据库：SQLite 总是测试，
+// PostgreSQL/MySQL 仅在设置 TEST_POSTGRES_DSN / TEST_MYSQL_DSN 时测试
+func testDatabaseConfigs(t *testing.T) map[string]DatabaseConfig {
+	t.Helper()
+	configs := map[string]DatabaseConfig{
+		DriverSQLite: {Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "data.db")},
+	}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		configs[DriverPostgres] = DatabaseConfig{Driver: DriverPostgres, DSN: dsn}
+	}
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		configs[DriverMySQL] = DatabaseConfig{Driver: DriverMySQL, DSN: dsn}
+	}
+	return configs
+}
+
+func TestOpen_MigratesLegacyRemotePath(t *testing.T) {
+	for name, cfg := range testDatabaseConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			conn := openTestDB(t, cfg)
+			if !conn.Migrator().HasTable(&model.WorkerNode{}) {
+				t.Fatalf("expected worker_nodes table")
+			}
+			if got := conn.Migrator().HasTable(LogSearchTable); got != (name == DriverSQLite) {
+				t.Fatalf("log search index exists=%v on %s", got, name)
+			}
+
+			// 模拟引入版本化迁移之前的旧数据库（jobs.remote_path 列、没有迁移记录），
+			// 重新打开后回填 remote_root 并删除旧列
+			conn.Exec("DELETE FROM jobs WHERE name = ?", "legacy")
+			if err := conn.Migrator().DropTable(&SchemaMigration{}); err != nil {
+				t.Fatalf("drop schema_migrations: %v", err)
+			}
+			if err := conn.Exec("ALTER TABLE jobs ADD COLUMN remote_path VARCHAR(255)").Error; err != nil {
+				t.Fatalf("add remote_path: %v", err)
+			}
+			job := model.Job{Name: "legacy", SourcePath: "/src", TargetPath: "/dst", STRMPath: "/src"}
+			if err := conn.Create(&job).Error; err != nil {
+				t.Fatalf("create job: %v", err)
+			}
+			if err := conn.Exec("UPDATE jobs SET remote_path = ?, remote_root = '' WHERE id = ?", "/cloud/movies", job.ID).Error; err != nil {
+				t.Fatalf("set remote_path: %v", err)
+			}
+
+			conn = openTestDB(t, cfg)
+			if conn.Migrator().HasColumn(&model.Job{}, "remote_path") {
+				t.Fatalf("expected remote_path to be dropped")
+			}
+			var reloaded model.Job
+			if err := conn.First(&reloaded, job.ID).Error; err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if reloaded.RemoteRoot != "/cloud/movies" {
+				t.Fatalf("expected remote_root backfilled, got %q", reloaded.RemoteRoot)
+			}
+			conn.Delete(&reloaded)
+		})
+	}
+}
+
+func TestOpen_RejectsDuplicateJobNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	conn := openTestDB(t, DatabaseConfig{Driver: DriverSQLite, Path: path})
+	if err := conn.Migrator().DropTable(&SchemaMigration{}); err != nil {
+		t.Fatalf("drop schema_migrations: %v", err)
+	}
+	if err := conn.Migrator().DropIndex(&model.Job{}, "Name"); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := conn.Create(&model.Job{Name: "dup", SourcePath: "/a", TargetPath: "/b", STRMPath: "/a"}).Error; err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+	}
+
+	_, err := Open(DatabaseConfig{Driver: DriverSQLite, Path: path}, &LogConfig{})
+	if err == nil || !strings.Contains(err.Error(), "'dup'(2次)") {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestValidate_DatabaseDriver(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			Server:   ServerConfig{Port: 6786, Host: "0.0.0.0"},
+			Database: DatabaseConfig{Driver: DriverSQLite, Path: "data.db"},
+			Log:      LogConfig{Level: "info", Path: "logs", Rotate: LogRotateConfig{MaxSizeMB: 10}},
+			Security: SecurityConfig{EncryptionKey: "key"},
+			Worker:   WorkerConfig{ID: "w1", Concurrency: 1, LeaseSeconds: 30},
+			History:  HistoryConfig{BatchSize: 500},
+		}
+	}
+	if err := Validate(base()); err != nil {
+		t.Fatalf("expected sqlite config valid: %v", err)
+	}
+
+	cfg := base()
+	cfg.Database = DatabaseConfig{Driver: DriverPostgres}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "DB_DSN") {
+		t.Fatalf("expected missing DSN error, got %v", err)
+	}
+	cfg.Database.DSN = "postgres://strm:secret@db:5432/strmsync"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected postgres config valid: %v", err)
+	}
+
+	cfg.Database = DatabaseConfig{Driver: "oracle", DSN: "x"}
+	if err := Validate(cfg); err == nil {
+		t.Fatalf("expected unsupported driver error")
+	}
+}
+
+func TestNormalizeDriver(t *testing.T) {
+	cases := map[string]string{
+		"":           DriverSQLite,
+		"SQLite3":    DriverSQLite,
+		"postgresql": DriverPostgres,
+		" pgsql ":    DriverPostgres,
+		"MariaDB":    DriverMySQL,
+		"oracle":     "oracle",
+	}
+	for in, want := range cases {
+		if got := NormalizeDriver(in); got != want {
+			t.Fatalf("NormalizeDriver(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDatabaseConfig_DescribeRedactsPassword(t *testing.T) {
+	cases := []DatabaseConfig{
+		{Driver: DriverPostgres, DSN: "postgres://strm:secret@db:5432/strmsync?sslmode=disable"},
+		{Driver: DriverPostgres, DSN: "host=db user=strm password=secret dbname=strmsync"},
+		{Driver: DriverMySQL, DSN: "strm:secret@tcp(db:3306)/strmsync"},
+	}
+	for _, cfg := range cases {
+		got := cfg.Describe()
+		if strings.Contains(got, "secret") || !strings.Contains(got, "db") {
+			t.Fatalf("unexpected description for %q: %q", cfg.DSN, got)
+		}
+	}
+	if got := (DatabaseConfig{Driver: DriverSQLite, Path: "/data/data.db"}).Describe(); got != "/data/data.db" {
+		t.Fatalf("unexpected sqlite description: %q", got)
+	}
+}
+
+func TestNormalizeMySQLDSN(t *testing.T) {
+	dsn, err := normalizeMySQLDSN("strm:secret@tcp(db:3306)/strmsync?charset=utf8mb4")
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	for _, want := range []string{"parseTime=true", "loc=Local", "sql_mode=", "charset=utf8mb4"} {
+		if !strings.Contains(dsn, want) {
+			t.Fatalf("expected %q in %q", want, dsn)
+		}
+	}
+
+	dsn, err = normalizeMySQLDSN("strm:secret@tcp(db:3306)/strmsync?loc=UTC&sql_mode=TRADITIONAL")
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if strings.Contains(dsn, "loc=Local") || strings.Contains(dsn, "NO_ENGINE_SUBSTITUTION") {
+		t.Fatalf("expected user loc/sql_mode kept, got %q", dsn)
+	}
+
+	if _, err := normalizeMySQLDSN("not a dsn"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动（DB_DRIVER）
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// mysqlStringSize MySQL 中未指定长度的字符串列使用的 varchar 长度
+//
+// 带索引的列必须有长度；utf8mb4 下 512 字符（2048 字节）不超过 InnoDB 索引上限 3072 字节。
+const mysqlStringSize = 512
+
+// mysqlSQLMode 连接使用的 sql_mode：保留严格模式，但允许零值时间
+// （未开始执行的任务 started_at 为 Go 零值时间）
+const mysqlSQLMode = "'STRICT_TRANS_TABLES,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION'"
+
+// NormalizeDriver 规范化驱动名称，空值表示 SQLite
+func NormalizeDriver(driver string) string {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", "sqlite", "sqlite3":
+		return DriverSQLite
+	case "postgres", "postgresql", "pgsql":
+		return DriverPostgres
+	case "mysql", "mariadb":
+		return DriverMySQL
+	default:
+		return strings.ToLower(strings.TrimSpace(driver))
+	}
+}
+
+// newDialector 根据数据库配置创建 GORM 方言
+func newDialector(cfg DatabaseConfig) (gorm.Dialector, error) {
+	switch NormalizeDriver(cfg.Driver) {
+	case DriverSQLite:
+		return sqlite.Open(cfg.Path), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	case DriverMySQL:
+		dsn, err := normalizeMySQLDSN(cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.New(mysql.Config{
+			DSN:               dsn,
+			DefaultStringSize: mysqlStringSize,
+		}), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %q", cfg.Driver)
+	}
+}
+
+// normalizeMySQLDSN 补全 MySQL 连接参数
+//
+// 时间列需要 parseTime；未指定 loc 时使用本地时区（与 SQLite 一致）；
+// 未指定 sql_mode 时使用 mysqlSQLMode。
+func normalizeMySQLDSN(dsn string) (string, error) {
+	parsed, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("解析MySQL DSN失败: %w", err)
+	}
+	parsed.ParseTime = true
+	if !strings.Contains(dsn, "loc=") {
+		parsed.Loc = time.Local
+	}
+	if parsed.Params == nil {
+		parsed.Params = map[string]string{}
+	}
+	if _, ok := parsed.Params["sql_mode"]; !ok {
+		parsed.Params["sql_mode"] = mysqlSQLMode
+	}
+	return parsed.FormatDSN(), nil
+}
+
+// pgPasswordPattern 匹配 PostgreSQL 键值对形式 DSN 中的密码
+var pgPasswordPattern = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// Describe 返回可写入日志的数据库位置（不含密码）
+func (c DatabaseConfig) Describe() string {
+	switch NormalizeDriver(c.Driver) {
+	case DriverSQLite:
+		return c.Path
+	case DriverPostgres:
+		if u, err := url.Parse(c.DSN); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+		return pgPasswordPattern.ReplaceAllString(c.DSN, "${1}xxxxx")
+	case DriverMySQL:
+		if parsed, err := mysqldriver.ParseDSN(c.DSN); err == nil {
+			return fmt.Sprintf("%s(%s)/%s", parsed.Net, parsed.Addr, parsed.DBName)
+		}
+		return DriverMySQL
+	default:
+		return c.Driver
+	}
+}
@@ -6,12 +6,18 @@
 //
 //   - 从环境变量加载数据库配置
 //   - 创建和管理数据库连接（单例模式）
-//   - 自动执行数据库迁移
+//   - 版本化数据库迁移（schema_migrations 记录版本，启动时自动执行，支持回滚）
+//   - SQLite 定时备份、保留策略与在线恢复（BackupManager）
 //   - 提供事务支持
 //
 // # 配置项
 //
+//   - DB_DRIVER: 数据库驱动（sqlite/postgres/mysql，默认 sqlite）
 //   - DB_PATH: SQLite 数据库文件路径
+//   - DB_DSN: PostgreSQL/MySQL 连接字符串
+//   - BACKUP_ENABLED/BACKUP_DIR/BACKUP_CRON: SQLite 定时备份
+//   - BACKUP_KEEP/BACKUP_KEEP_DAYS: 备份保留份数与天数
+//   - BACKUP_COMPRESS/BACKUP_ENCRYPT: 备份压缩与加密
 //
 // # 使用示例
 //
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// LogSearchTable 日志全文索引表名（FTS4 外部内容表，内容来自 logs）
+const LogSearchTable = "logs_fts"
+
+// ensureLogSearchIndex 为 logs 表建立全文索引并用触发器保持同步
+//
+// 首次创建时从 logs 表重建索引；SQLite 未编译 FTS 支持时返回错误，
+// 调用方可忽略，日志搜索会回退为 LIKE 匹配。
+func ensureLogSearchIndex(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	exists := db.Migrator().HasTable(LogSearchTable)
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS logs_fts USING fts4(content="logs", message, tokenize=unicode61)`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_ai AFTER INSERT ON logs BEGIN
+			INSERT INTO logs_fts(docid, message) VALUES (new.id, new.message);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_bd BEFORE DELETE ON logs BEGIN
+			DELETE FROM logs_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_bu BEFORE UPDATE ON logs BEGIN
+			DELETE FROM logs_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_au AFTER UPDATE ON logs BEGIN
+			INSERT INTO logs_fts(docid, message) VALUES (new.id, new.message);
+		END`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("create log search index: %w", err)
+		}
+	}
+
+	if !exists {
+		if err := db.Exec(`INSERT INTO logs_fts(logs_fts) VALUES('rebuild')`).Error; err != nil {
+			return fmt.Errorf("rebuild log search index: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropLogSearchIndex 删除日志全文索引及同步触发器
+func dropLogSearchIndex(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	for _, stmt := range []string{
+		`DROP TRIGGER IF EXISTS logs_fts_ai`,
+		`DROP TRIGGER IF EXISTS logs_fts_bd`,
+		`DROP TRIGGER IF EXISTS logs_fts_bu`,
+		`DROP TRIGGER IF EXISTS logs_fts_au`,
+		`DROP TABLE IF EXISTS logs_fts`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("drop log search index: %w", err)
+		}
+	}
+	return nil
+}
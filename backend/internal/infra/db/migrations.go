@@ -0,0 +1,255 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// schemaMigrations 内置迁移列表（按版本号递增）
+//
+// 迁移只使用 GORM Migrator 与标准 SQL，SQLite/PostgreSQL/MySQL 通用；
+// 数据库特有的迁移通过 Drivers 限定。新增字段或表时追加迁移
+// （例如 tx.Migrator().AddColumn 或对单个模型 AutoMigrate），不要修改已有迁移。
+// 创建表的迁移使用 schema_v1.go 中的冻结结构，新库与升级库按同样的步骤得到同样的结构。
+var schemaMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      migrateBaseline,
+	},
+	{
+		Version: 2,
+		Name:    "job_remote_root",
+		Up:      migrateJobRemoteRoot,
+		Down:    revertJobRemoteRoot,
+	},
+	{
+		Version: 3,
+		Name:    "log_search_index",
+		Drivers: []string{DriverSQLite},
+		Up:      migrateLogSearchIndex,
+		Down:    dropLogSearchIndex,
+	},
+	{
+		Version: 4,
+		Name:    "task_run_meta_bytes",
+		Up:      migrateTaskRunMetaBytes,
+		Down:    revertTaskRunMetaBytes,
+	},
+	{
+		Version: 5,
+		Name:    "strm_mappings",
+		Up:      migrateStrmMappings,
+		Down:    dropStrmMappings,
+	},
+	{
+		Version: 6,
+		Name:    "task_run_moved_files",
+		Up:      migrateTaskRunMovedFiles,
+		Down:    revertTaskRunMovedFiles,
+	},
+	{
+		Version: 7,
+		Name:    "strm_mapping_signature",
+		Up:      migrateStrmMappingSignature,
+		Down:    revertStrmMappingSignature,
+	},
+}
+
+// migrateBaseline 按版本 1 的结构创建全部表（已有数据库只补齐缺失的列与索引）
+func migrateBaseline(tx *gorm.DB) error {
+	// 迁移前检查：如果Job表已存在，检查是否有重复名称
+	// 这样可以在AutoMigrate尝试创建uniqueIndex之前给出清晰的错误提示
+	if tx.Migrator().HasTable(v1Job{}) {
+		if err := checkJobNameDuplicates(tx); err != nil {
+			return fmt.Errorf("数据完整性检查失败: %w", err)
+		}
+	}
+
+	if err := tx.AutoMigrate(
+		v1DataServer{},
+		v1MediaServer{},
+		v1Job{},
+		v1TaskRun{},
+		v1TaskRunEvent{},
+		v1TaskRunRollup{},
+		v1MetaFileHash{},
+		v1WorkerNode{},
+		v1LogEntry{},
+		v1Setting{},
+	); err != nil {
+		// 迁移失败时给出友好提示，可能是数据重复导致
+		return fmt.Errorf("自动迁移失败: %w（如遇到唯一约束错误，请检查jobs表是否有重复的name字段）", err)
+	}
+	return nil
+}
+
+// migrateJobRemoteRoot 将旧版本的 jobs.remote_path 迁移到 remote_root 并删除旧列
+func migrateJobRemoteRoot(tx *gorm.DB) error {
+	if err := backfillJobRemoteRoot(tx); err != nil {
+		return fmt.Errorf("回填远程根目录失败: %w", err)
+	}
+	if err := dropJobRemotePathColumn(tx); err != nil {
+		return fmt.Errorf("清理旧远程路径列失败: %w", err)
+	}
+	return nil
+}
+
+// revertJobRemoteRoot 恢复 jobs.remote_path 列（内容取自 remote_root）
+func revertJobRemoteRoot(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.Job{}, "remote_path") {
+		if err := tx.Exec("ALTER TABLE jobs ADD COLUMN remote_path TEXT").Error; err != nil {
+			return fmt.Errorf("add jobs.remote_path: %w", err)
+		}
+	}
+	if err := tx.Exec("UPDATE jobs SET remote_path = remote_root").Error; err != nil {
+		return fmt.Errorf("restore jobs.remote_path: %w", err)
+	}
+	return nil
+}
+
+// migrateLogSearchIndex 创建日志全文索引
+//
+// SQLite 未编译 FTS 支持时只记录警告，日志搜索回退为 LIKE 匹配，不影响启动。
+func migrateLogSearchIndex(tx *gorm.DB) error {
+	if err := ensureLogSearchIndex(tx); err != nil {
+		logger.LogWarn("创建日志全文索引失败", zap.Error(err))
+	}
+	return nil
+}
+
+// migrateTaskRunMetaBytes 为 task_runs 添加元数据传输字节数列
+func migrateTaskRunMetaBytes(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&model.TaskRun{}, "MetaBytes") {
+		return nil
+	}
+	if err := tx.Migrator().AddColumn(&model.TaskRun{}, "MetaBytes"); err != nil {
+		return fmt.Errorf("add task_runs.meta_bytes: %w", err)
+	}
+	return nil
+}
+
+// revertTaskRunMetaBytes 删除 task_runs.meta_bytes 列
+func revertTaskRunMetaBytes(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.TaskRun{}, "MetaBytes") {
+		return nil
+	}
+	if err := tx.Migrator().DropColumn(&model.TaskRun{}, "MetaBytes"); err != nil {
+		return fmt.Errorf("drop task_runs.meta_bytes: %w", err)
+	}
+	return nil
+}
+
+// migrateStrmMappings 按版本 5 的结构创建输出布局映射表
+func migrateStrmMappings(tx *gorm.DB) error {
+	if tx.Migrator().HasTable(&v5StrmMapping{}) {
+		return nil
+	}
+	if err := tx.Migrator().CreateTable(&v5StrmMapping{}); err != nil {
+		return fmt.Errorf("create strm_mappings: %w", err)
+	}
+	return nil
+}
+
+// dropStrmMappings 删除输出布局映射表
+func dropStrmMappings(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&model.StrmMapping{}) {
+		return nil
+	}
+	if err := tx.Migrator().DropTable(&model.StrmMapping{}); err != nil {
+		return fmt.Errorf("drop strm_mappings: %w", err)
+	}
+	return nil
+}
+
+// migrateTaskRunMovedFiles 为 task_runs 添加移动 STRM 数列
+func migrateTaskRunMovedFiles(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&model.TaskRun{}, "MovedFiles") {
+		return nil
+	}
+	if err := tx.Migrator().AddColumn(&model.TaskRun{}, "MovedFiles"); err != nil {
+		return fmt.Errorf("add task_runs.moved_files: %w", err)
+	}
+	return nil
+}
+
+// revertTaskRunMovedFiles 删除 task_runs.moved_files 列
+func revertTaskRunMovedFiles(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.TaskRun{}, "MovedFiles") {
+		return nil
+	}
+	if err := tx.Migrator().DropColumn(&model.TaskRun{}, "MovedFiles"); err != nil {
+		return fmt.Errorf("drop task_runs.moved_files: %w", err)
+	}
+	return nil
+}
+
+// strmMappingSignatureFields strm_mappings 中移动检测签名的字段
+var strmMappingSignatureFields = []string{"Size", "ModTime", "FileID"}
+
+// migrateStrmMappingSignature 为 strm_mappings 添加远端文件签名列
+func migrateStrmMappingSignature(tx *gorm.DB) error {
+	for _, field := range strmMappingSignatureFields {
+		if tx.Migrator().HasColumn(&model.StrmMapping{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&model.StrmMapping{}, field); err != nil {
+			return fmt.Errorf("add strm_mappings.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// revertStrmMappingSignature 删除 strm_mappings 的远端文件签名列
+func revertStrmMappingSignature(tx *gorm.DB) error {
+	for _, field := range strmMappingSignatureFields {
+		if !tx.Migrator().HasColumn(&model.StrmMapping{}, field) {
+			continue
+		}
+		if err := tx.Migrator().DropColumn(&model.StrmMapping{}, field); err != nil {
+			return fmt.Errorf("drop strm_mappings.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func backfillJobRemoteRoot(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&model.Job{}, "remote_path") || !migrator.HasColumn(&model.Job{}, "remote_root") {
+		return nil
+	}
+
+	res := db.Exec(`UPDATE jobs
+SET remote_root = remote_path
+WHERE (remote_root IS NULL OR remote_root = '')
+  AND remote_path IS NOT NULL
+  AND remote_path <> ''`)
+	if res.Error != nil {
+		return fmt.Errorf("update jobs remote_root: %w", res.Error)
+	}
+	return nil
+}
+
+func dropJobRemotePathColumn(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	if !db.Migrator().HasColumn(&model.Job{}, "remote_path") {
+		return nil
+	}
+
+	if err := db.Exec("ALTER TABLE jobs DROP COLUMN remote_path").Error; err != nil {
+		return fmt.Errorf("drop jobs.remote_path: %w", err)
+	}
+	return nil
+}
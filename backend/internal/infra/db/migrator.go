@@ -0,0 +1,425 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 迁移相关错误定义
+var (
+	// ErrMigrationLocked 其他实例正在执行迁移且在等待时间内未释放迁移锁
+	ErrMigrationLocked = errors.New("数据库迁移锁被占用")
+
+	// ErrIrreversibleMigration 迁移没有提供回滚步骤
+	ErrIrreversibleMigration = errors.New("迁移不可回滚")
+)
+
+// 迁移锁参数
+const (
+	migrationLockID      = 1                // 迁移锁行ID（全局唯一一行）
+	migrationLockWait    = 2 * time.Minute  // 等待其他实例释放迁移锁的最长时间
+	migrationLockStale   = 30 * time.Minute // 超过该时间未释放的锁视为进程异常退出遗留
+	migrationLockBackoff = 500 * time.Millisecond
+)
+
+// Migration 版本化数据库迁移
+//
+// 版本号严格递增且发布后不可修改；新增或变更表结构时追加新的迁移，
+// 不要修改已发布迁移的内容。
+type Migration struct {
+	Version int                     // 版本号（从 1 开始递增）
+	Name    string                  // 迁移名称
+	Drivers []string                // 仅在这些数据库上执行（为空表示全部），其他数据库只记录版本
+	Up      func(tx *gorm.DB) error // 升级步骤
+	Down    func(tx *gorm.DB) error // 回滚步骤（nil 表示不可回滚）
+}
+
+// appliesTo 判断迁移是否需要在指定数据库上执行
+func (m Migration) appliesTo(driver string) bool {
+	if len(m.Drivers) == 0 {
+		return true
+	}
+	for _, d := range m.Drivers {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"` // 版本号
+	Name      string    `gorm:"size:128;not null" json:"name"`                 // 迁移名称
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`                    // 执行时间
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+// schemaMigrationLock 迁移锁（只有一行，插入成功即获得锁）
+type schemaMigrationLock struct {
+	ID       int       `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string    `gorm:"size:128;not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (schemaMigrationLock) TableName() string { return "schema_migration_lock" }
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`    // 版本号
+	Name      string     `json:"name"`       // 迁移名称
+	Applied   bool       `json:"applied"`    // 是否已执行
+	AppliedAt *time.Time `json:"applied_at"` // 执行时间
+	Skipped   bool       `json:"skipped"`    // 不适用于当前数据库（只记录版本）
+	Unknown   bool       `json:"unknown"`    // 数据库中存在但程序中未定义（数据库由更新的版本迁移）
+}
+
+// MigrationResult 执行迁移的结果
+type MigrationResult struct {
+	Migrations []MigrationStatus // 本次执行（或回滚）的迁移
+	BackupPath string            // 迁移前的备份文件（未备份时为空）
+}
+
+// SchemaMigrator 版本化迁移执行器
+//
+// 迁移按版本号顺序执行，每个迁移与其版本记录在同一事务中提交
+// （MySQL 的 DDL 会隐式提交，失败时可能需要根据备份恢复）。
+// 多个实例同时启动时通过 schema_migration_lock 表互斥。
+// SQLite 在有待执行的迁移时先备份数据库文件。
+type SchemaMigrator struct {
+	db         *gorm.DB
+	cfg        DatabaseConfig
+	migrations []Migration
+	owner      string
+	lockWait   time.Duration
+	log        *zap.Logger
+}
+
+// NewSchemaMigrator 创建迁移执行器
+//
+// 参数：
+//   - db: GORM 数据库连接（不能为 nil）
+//   - cfg: 数据库配置（用于判断驱动与备份路径）
+//
+// 返回：
+//   - *SchemaMigrator: 迁移执行器（使用内置迁移列表）
+//   - error: db 为 nil 时返回错误
+func NewSchemaMigrator(db *gorm.DB, cfg DatabaseConfig) (*SchemaMigrator, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	cfg.Driver = NormalizeDriver(cfg.Driver)
+	return newSchemaMigrator(db, cfg, schemaMigrations), nil
+}
+
+// newSchemaMigrator 使用指定的迁移列表创建迁移执行器
+func newSchemaMigrator(db *gorm.DB, cfg DatabaseConfig, migrations []Migration) *SchemaMigrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	hostname, _ := os.Hostname()
+	return &SchemaMigrator{
+		db:         db,
+		cfg:        cfg,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s/%d/%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		lockWait:   migrationLockWait,
+		log:        logger.With(zap.String("component", "migrator")),
+	}
+}
+
+// LatestVersion 返回程序定义的最新迁移版本
+func (m *SchemaMigrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status 返回所有迁移的执行状态（按版本号升序）
+func (m *SchemaMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.statuses(applied), nil
+}
+
+// Up 执行版本号不大于 target 的待执行迁移（target <= 0 表示全部）
+//
+// 数据库已由更新的程序迁移（存在未知版本）时拒绝执行，避免旧程序写坏新结构。
+func (m *SchemaMigrator) Up(ctx context.Context, target int) (*MigrationResult, error) {
+	if target <= 0 {
+		target = m.LatestVersion()
+	}
+	result := &MigrationResult{}
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for version := range applied {
+			if version > m.LatestVersion() {
+				return fmt.Errorf("数据库结构版本（%d）高于程序支持的版本（%d），请升级程序", version, m.LatestVersion())
+			}
+		}
+
+		var pending []Migration
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+				pending = append(pending, migration)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if result.BackupPath, err = m.backup(ctx, "pre-migrate"); err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := m.apply(ctx, migration); err != nil {
+				return err
+			}
+			result.Migrations = append(result.Migrations, m.status(migration, true))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Down 回滚版本号大于 target 的已执行迁移（按版本号降序）
+//
+// 任一迁移不可回滚时在执行前返回 ErrIrreversibleMigration，不做任何修改。
+func (m *SchemaMigrator) Down(ctx context.Context, target int) (*MigrationResult, error) {
+	if target < 0 {
+		target = 0
+	}
+	result := &MigrationResult{}
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		var rollback []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+				continue
+			}
+			if migration.Down == nil && migration.appliesTo(m.cfg.Driver) {
+				return fmt.Errorf("%w: %04d_%s", ErrIrreversibleMigration, migration.Version, migration.Name)
+			}
+			rollback = append(rollback, migration)
+		}
+		for version := range applied {
+			if version > m.LatestVersion() && version > target {
+				return fmt.Errorf("数据库包含程序未定义的迁移版本 %d，无法回滚", version)
+			}
+		}
+		if len(rollback) == 0 {
+			return nil
+		}
+
+		if result.BackupPath, err = m.backup(ctx, "pre-rollback"); err != nil {
+			return err
+		}
+		for _, migration := range rollback {
+			if err := m.revert(ctx, migration); err != nil {
+				return err
+			}
+			result.Migrations = append(result.Migrations, m.status(migration, false))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// apply 执行单个迁移并记录版本
+func (m *SchemaMigrator) apply(ctx context.Context, migration Migration) error {
+	start := time.Now()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if migration.appliesTo(m.cfg.Driver) && migration.Up != nil {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&SchemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("执行迁移 %04d_%s 失败: %w", migration.Version, migration.Name, err)
+	}
+	m.log.Info(fmt.Sprintf("执行数据库迁移 %04d_%s", migration.Version, migration.Name),
+		zap.Int("version", migration.Version),
+		zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// revert 回滚单个迁移并删除版本记录
+func (m *SchemaMigrator) revert(ctx context.Context, migration Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if migration.appliesTo(m.cfg.Driver) && migration.Down != nil {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("回滚迁移 %04d_%s 失败: %w", migration.Version, migration.Name, err)
+	}
+	m.log.Warn(fmt.Sprintf("回滚数据库迁移 %04d_%s", migration.Version, migration.Name),
+		zap.Int("version", migration.Version))
+	return nil
+}
+
+// applied 查询已执行的迁移（版本号 → 记录）
+func (m *SchemaMigrator) applied(ctx context.Context) (map[int]SchemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	var records []SchemaMigration
+	if err := db.Order("version ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// statuses 合并程序定义的迁移与数据库中的记录
+func (m *SchemaMigrator) statuses(applied map[int]SchemaMigration) []MigrationStatus {
+	known := make(map[int]bool, len(m.migrations))
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := m.status(migration, false)
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// status 构造单个迁移的状态
+func (m *SchemaMigrator) status(migration Migration, applied bool) MigrationStatus {
+	status := MigrationStatus{
+		Version: migration.Version,
+		Name:    migration.Name,
+		Applied: applied,
+		Skipped: !migration.appliesTo(m.cfg.Driver),
+	}
+	if applied {
+		now := time.Now()
+		status.AppliedAt = &now
+	}
+	return status
+}
+
+// backup 迁移前备份 SQLite 数据库（内存数据库或空库不备份）
+func (m *SchemaMigrator) backup(ctx context.Context, reason string) (string, error) {
+	if m.cfg.Driver != DriverSQLite || isMemorySQLite(m.cfg.Path) {
+		return "", nil
+	}
+	info, err := os.Stat(m.cfg.Path)
+	if err != nil || info.Size() == 0 {
+		return "", nil
+	}
+	// 只有迁移记录表的新库无需备份
+	if !m.db.Migrator().HasTable("jobs") {
+		return "", nil
+	}
+
+	dest := fmt.Sprintf("%s.%s-%s.bak", m.cfg.Path, reason, time.Now().Format("20060102-150405"))
+	if err := vacuumInto(m.db.WithContext(ctx), dest); err != nil {
+		return "", fmt.Errorf("迁移前备份数据库失败: %w", err)
+	}
+	m.log.Info("迁移前已备份数据库", zap.String("path", dest))
+	return dest, nil
+}
+
+// withLock 持有迁移锁执行 fn
+//
+// 锁为 schema_migration_lock 表中 ID 固定的一行，插入成功即获得锁；
+// 超过 migrationLockStale 未释放的锁视为遗留并被清除。
+func (m *SchemaMigrator) withLock(ctx context.Context, fn func() error) error {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&schemaMigrationLock{}); err != nil {
+		return fmt.Errorf("创建迁移锁表失败: %w", err)
+	}
+
+	deadline := time.Now().Add(m.lockWait)
+	for {
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaMigrationLock{
+			ID:       migrationLockID,
+			Owner:    m.owner,
+			LockedAt: time.Now(),
+		})
+		if res.Error != nil {
+			return fmt.Errorf("获取迁移锁失败: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			break
+		}
+
+		stale := db.Where("id = ? AND locked_at < ?", migrationLockID, time.Now().Add(-migrationLockStale)).
+			Delete(&schemaMigrationLock{})
+		if stale.Error == nil && stale.RowsAffected > 0 {
+			m.log.Warn("清除过期的数据库迁移锁")
+			continue
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationLockBackoff):
+		}
+	}
+
+	defer func() {
+		if err := m.db.Where("id = ? AND owner = ?", migrationLockID, m.owner).
+			Delete(&schemaMigrationLock{}).Error; err != nil {
+			m.log.Warn("释放数据库迁移锁失败", zap.Error(err))
+		}
+	}()
+	return fn()
+}
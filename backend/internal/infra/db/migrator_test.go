@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"gorm.io/gorm"
+)
+
+// connectTestSQLite 打开临时 SQLite 文件但不执行迁移
+func connectTestSQLite(t *testing.T) (*gorm.DB, DatabaseConfig) {
+	t.Helper()
+	cfg := DatabaseConfig{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "data.db")}
+	conn, err := Connect(cfg, &LogConfig{})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return conn, cfg
+}
+
+func TestSchemaMigrator_UpDownWithBackup(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	m, err := NewSchemaMigrator(conn, cfg)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	ctx := context.Background()
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Fatalf("expected fresh database to have no applied migrations: %+v", s)
+		}
+	}
+
+	// 新库不备份
+	result, err := m.Up(ctx, 0)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(result.Migrations) != len(schemaMigrations) || result.BackupPath != "" {
+		t.Fatalf("unexpected up result: %+v", result)
+	}
+	if result, err := m.Up(ctx, 0); err != nil || len(result.Migrations) != 0 {
+		t.Fatalf("expected second up to be a no-op, got %+v err=%v", result, err)
+	}
+
+	// 回滚到版本 2：删除映射签名列、移动 STRM 数列、布局映射表、元数据字节数列与日志全文索引，并在回滚前备份
+	result, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(result.Migrations) != 5 || result.Migrations[0].Version != 7 || result.Migrations[4].Version != 3 {
+		t.Fatalf("unexpected down result: %+v", result.Migrations)
+	}
+	if conn.Migrator().HasColumn(&model.TaskRun{}, "MovedFiles") {
+		t.Fatalf("expected task_runs.moved_files dropped")
+	}
+	if conn.Migrator().HasColumn(&model.TaskRun{}, "MetaBytes") {
+		t.Fatalf("expected task_runs.meta_bytes dropped")
+	}
+	if conn.Migrator().HasTable(&model.StrmMapping{}) {
+		t.Fatalf("expected strm_mappings dropped")
+	}
+	if result.BackupPath == "" || !strings.Contains(result.BackupPath, "pre-rollback") {
+		t.Fatalf("expected rollback backup, got %q", result.BackupPath)
+	}
+	if _, err := os.Stat(result.BackupPath); err != nil {
+		t.Fatalf("backup file missing: %v", err)
+	}
+	if conn.Migrator().HasTable(LogSearchTable) {
+		t.Fatalf("expected log search index dropped")
+	}
+
+	// 基线迁移不可回滚：整体拒绝，不做任何修改
+	if _, err := m.Down(ctx, 0); !errors.Is(err, ErrIrreversibleMigration) {
+		t.Fatalf("expected irreversible error, got %v", err)
+	}
+	statuses, _ = m.Status(ctx)
+	if !statuses[0].Applied || !statuses[1].Applied || statuses[2].Applied {
+		t.Fatalf("unexpected statuses after refused rollback: %+v", statuses)
+	}
+
+	result, err = m.Up(ctx, 0)
+	if err != nil || len(result.Migrations) != 5 || !strings.Contains(result.BackupPath, "pre-migrate") {
+		t.Fatalf("unexpected re-apply result: %+v err=%v", result, err)
+	}
+	if !conn.Migrator().HasTable(LogSearchTable) {
+		t.Fatalf("expected log search index recreated")
+	}
+	if !conn.Migrator().HasColumn(&model.StrmMapping{}, "FileID") {
+		t.Fatalf("expected strm_mappings.file_id recreated")
+	}
+}
+
+func TestSchemaMigrator_LaterTablesOwnedByTheirMigration(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	m, _ := NewSchemaMigrator(conn, cfg)
+	ctx := context.Background()
+
+	// 新库与升级库结构一致：基线不创建后续迁移负责的表
+	if _, err := m.Up(ctx, 4); err != nil {
+		t.Fatalf("up to 4: %v", err)
+	}
+	if conn.Migrator().HasTable(&model.StrmMapping{}) {
+		t.Fatalf("expected strm_mappings to be created by migration 5 only")
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if !conn.Migrator().HasTable(&model.StrmMapping{}) {
+		t.Fatalf("expected strm_mappings created")
+	}
+}
+
+func TestSchemaMigrator_FreshDatabaseColumnsFollowVersions(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	m, _ := NewSchemaMigrator(conn, cfg)
+	ctx := context.Background()
+
+	// 新库的列只由添加它们的迁移创建
+	steps := []struct {
+		version int
+		model   interface{}
+		field   string
+	}{
+		{4, &model.TaskRun{}, "MetaBytes"},
+		{6, &model.TaskRun{}, "MovedFiles"},
+		{7, &model.StrmMapping{}, "FileID"},
+	}
+	up := func(target int) {
+		result, err := m.Up(ctx, target)
+		if err != nil {
+			t.Fatalf("up to %d: %v", target, err)
+		}
+		// 同一秒内多次迁移的备份文件同名，逐次删除
+		if result.BackupPath != "" {
+			_ = os.Remove(result.BackupPath)
+		}
+	}
+	for _, step := range steps {
+		up(step.version - 1)
+		if conn.Migrator().HasColumn(step.model, step.field) {
+			t.Fatalf("expected %s to be added by migration %d only", step.field, step.version)
+		}
+		up(step.version)
+		if !conn.Migrator().HasColumn(step.model, step.field) {
+			t.Fatalf("expected %s added by migration %d", step.field, step.version)
+		}
+	}
+
+	// 全部迁移完成后与当前模型一致
+	for _, value := range []interface{}{
+		&model.DataServer{}, &model.MediaServer{}, &model.Job{}, &model.TaskRun{},
+		&model.TaskRunEvent{}, &model.TaskRunRollup{}, &model.MetaFileHash{},
+		&model.StrmMapping{}, &model.WorkerNode{}, &model.LogEntry{}, &model.Setting{},
+	} {
+		stmt := &gorm.Statement{DB: conn}
+		if err := stmt.Parse(value); err != nil {
+			t.Fatalf("parse %T: %v", value, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !conn.Migrator().HasColumn(value, field.DBName) {
+				t.Fatalf("column %s.%s not created by any migration", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestSchemaMigrator_RefusesNewerDatabase(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	m, _ := NewSchemaMigrator(conn, cfg)
+	ctx := context.Background()
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := conn.Create(&SchemaMigration{Version: 999, Name: "future", AppliedAt: time.Now()}).Error; err != nil {
+		t.Fatalf("insert future migration: %v", err)
+	}
+
+	if _, err := m.Up(ctx, 0); err == nil || !strings.Contains(err.Error(), "999") {
+		t.Fatalf("expected newer database error, got %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if last := statuses[len(statuses)-1]; last.Version != 999 || !last.Unknown {
+		t.Fatalf("expected unknown migration in status, got %+v", last)
+	}
+}
+
+func TestSchemaMigrator_FailedMigrationRollsBack(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	m := newSchemaMigrator(conn, cfg, []Migration{
+		{Version: 1, Name: "widgets", Up: func(tx *gorm.DB) error { return tx.AutoMigrate(&widget{}) }},
+		{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+			if err := tx.Create(&widget{Name: "partial"}).Error; err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}},
+	})
+	ctx := context.Background()
+
+	if _, err := m.Up(ctx, 0); err == nil || !strings.Contains(err.Error(), "0002_broken") {
+		t.Fatalf("expected broken migration error, got %v", err)
+	}
+	statuses, _ := m.Status(ctx)
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+	var count int64
+	conn.Model(&widget{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected failed migration changes rolled back, got %d rows", count)
+	}
+}
+
+func TestSchemaMigrator_Lock(t *testing.T) {
+	conn, cfg := connectTestSQLite(t)
+	m, _ := NewSchemaMigrator(conn, cfg)
+	m.lockWait = 50 * time.Millisecond
+	ctx := context.Background()
+
+	if err := conn.AutoMigrate(&schemaMigrationLock{}); err != nil {
+		t.Fatalf("migrate lock table: %v", err)
+	}
+	held := schemaMigrationLock{ID: migrationLockID, Owner: "other", LockedAt: time.Now()}
+	if err := conn.Create(&held).Error; err != nil {
+		t.Fatalf("create lock: %v", err)
+	}
+	if _, err := m.Up(ctx, 0); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+
+	// 进程异常退出遗留的锁过期后被清除
+	if err := conn.Model(&held).Update("locked_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("age lock: %v", err)
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatalf("expected stale lock to be taken over: %v", err)
+	}
+	var remaining int64
+	conn.Model(&schemaMigrationLock{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected lock released, got %d rows", remaining)
+	}
+}
@@ -0,0 +1,222 @@
+// Package repository 提供日志相关的 GORM Repository 实现
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	domainrepo "github.com/strmsync/strmsync/internal/domain/repository"
+	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
+	"gorm.io/gorm"
+)
+
+// logDeleteBatch 单次删除的日志条数，避免长时间占用写锁
+const logDeleteBatch = 5000
+
+// GormLogRepository 是基于 GORM 的 model.LogEntry 数据访问实现
+//
+// 搜索优先使用 logs_fts 全文索引；索引不存在（SQLite 未启用 FTS）时回退为 LIKE 匹配。
+type GormLogRepository struct {
+	db  *gorm.DB
+	fts bool
+}
+
+// NewGormLogRepository 创建 GormLogRepository 实例
+//
+// 参数：
+//   - db: GORM 数据库连接（不能为 nil）
+//
+// 返回：
+//   - *GormLogRepository: Repository 实例
+//   - error: db 为 nil 时返回错误
+func NewGormLogRepository(db *gorm.DB) (*GormLogRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("core: gorm db is nil")
+	}
+	return &GormLogRepository{
+		db:  db,
+		fts: db.Migrator().HasTable(dbpkg.LogSearchTable),
+	}, nil
+}
+
+// List 按 ID 降序返回日志
+//
+// beforeID 大于 0 时只返回 ID 小于 beforeID 的日志（游标翻页），
+// 否则按 offset 翻页。
+func (r *GormLogRepository) List(ctx context.Context, filter domainrepo.LogFilter, beforeID uint, offset, limit int) ([]model.LogEntry, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	query := r.applyFilter(r.db.WithContext(ctx).Model(&model.LogEntry{}), filter)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	} else if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var entries []model.LogEntry
+	if err := query.Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("query logs: %w", err)
+	}
+	return entries, nil
+}
+
+// Count 统计符合条件的日志数量
+func (r *GormLogRepository) Count(ctx context.Context, filter domainrepo.LogFilter) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var total int64
+	if err := r.applyFilter(r.db.WithContext(ctx).Model(&model.LogEntry{}), filter).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("count logs: %w", err)
+	}
+	return total, nil
+}
+
+// ListAfter 按 ID 升序返回 afterID 之后的日志（用于实时追踪）
+func (r *GormLogRepository) ListAfter(ctx context.Context, filter domainrepo.LogFilter, afterID uint, limit int) ([]model.LogEntry, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var entries []model.LogEntry
+	if err := r.applyFilter(r.db.WithContext(ctx).Model(&model.LogEntry{}), filter).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("query logs: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteBefore 分批删除 cutoff 之前的日志，返回删除条数
+func (r *GormLogRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.deleteBatches(ctx, "created_at < ?", cutoff)
+}
+
+// TrimToLimit 仅保留最新的 maxRows 条日志，返回删除条数
+//
+// maxRows 小于等于 0 时不做限制。
+func (r *GormLogRepository) TrimToLimit(ctx context.Context, maxRows int64) (int64, error) {
+	if maxRows <= 0 {
+		return 0, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var threshold model.LogEntry
+	err := r.db.WithContext(ctx).
+		Select("id").
+		Order("id DESC").
+		Offset(int(maxRows)).
+		Limit(1).
+		Take(&threshold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query log threshold: %w", err)
+	}
+	return r.deleteBatches(ctx, "id <= ?", threshold.ID)
+}
+
+// deleteBatches 按条件分批删除日志
+func (r *GormLogRepository) deleteBatches(ctx context.Context, cond string, arg interface{}) (int64, error) {
+	var deleted int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		// 先查询 ID 再删除：MySQL 不支持 IN 子查询中使用 LIMIT
+		var ids []uint
+		if err := r.db.WithContext(ctx).Model(&model.LogEntry{}).
+			Where(cond, arg).
+			Order("id ASC").
+			Limit(logDeleteBatch).
+			Pluck("id", &ids).Error; err != nil {
+			return deleted, fmt.Errorf("query logs to delete: %w", err)
+		}
+		if len(ids) == 0 {
+			return deleted, nil
+		}
+		result := r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.LogEntry{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("delete logs: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < logDeleteBatch {
+			return deleted, nil
+		}
+	}
+}
+
+// applyFilter 将查询条件转换为 SQL 条件
+func (r *GormLogRepository) applyFilter(query *gorm.DB, filter domainrepo.LogFilter) *gorm.DB {
+	if len(filter.Levels) > 0 {
+		query = query.Where("level IN ?", filter.Levels)
+	}
+	if filter.Module != "" {
+		query = query.Where("module = ?", filter.Module)
+	}
+	if filter.JobID != nil {
+		query = query.Where("job_id = ?", *filter.JobID)
+	}
+	if filter.RunID != nil {
+		query = query.Where("run_id = ?", *filter.RunID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.StartAt.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartAt)
+	}
+	if !filter.EndAt.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndAt)
+	}
+	return r.applySearch(query, filter.Search)
+}
+
+// applySearch 处理关键字搜索
+//
+// 由英文字母和数字组成的关键字走全文索引（前缀匹配）；
+// 中文等其他关键字无法被分词器切分，按子串 LIKE 匹配。
+func (r *GormLogRepository) applySearch(query *gorm.DB, search string) *gorm.DB {
+	var tokens []string
+	for _, term := range strings.Fields(search) {
+		if r.fts && isSearchToken(term) {
+			tokens = append(tokens, term+"*")
+			continue
+		}
+		query = query.Where("message LIKE ? ESCAPE '!'", "%"+escapeLike(term)+"%")
+	}
+	if len(tokens) > 0 {
+		query = query.Where("id IN (SELECT docid FROM "+dbpkg.LogSearchTable+" WHERE "+dbpkg.LogSearchTable+" MATCH ?)",
+			strings.Join(tokens, " "))
+	}
+	return query
+}
+
+// isSearchToken 判断关键字是否可直接作为全文索引词
+func isSearchToken(term string) bool {
+	for _, r := range term {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return term != ""
+}
+
+// escapeLike 转义 LIKE 通配符
+//
+// 使用 ! 作为转义字符：MySQL 字符串中的反斜杠本身需要转义，'\' 无法跨数据库通用。
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
@@ -0,0 +1,213 @@
+// Package repository 提供执行历史清理的 GORM Repository 实现
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	domainrepo "github.com/strmsync/strmsync/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+// runEventDeleteBatch 单条语句删除的事件明细条数，避免长时间占用写锁
+const runEventDeleteBatch = 5000
+
+// activeRunStatuses 不参与清理的执行状态
+var activeRunStatuses = []string{"pending", "running"}
+
+// PruneRuns 删除超出保留数量的执行记录及其事件明细，返回删除的执行记录ID与事件数
+//
+// 每次最多删除 limit 条执行记录，调用方循环调用直到返回的ID少于 limit。
+// 只删除开始时间早于汇总进度的记录，确保趋势统计已写入 task_run_rollups。
+func (r *GormRunStatsRepository) PruneRuns(ctx context.Context, policy domainrepo.RunRetentionPolicy, limit int) ([]uint, int64, error) {
+	if policy.KeepPerJob <= 0 || limit <= 0 {
+		return nil, 0, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rolledUntil, err := r.rolledUntil(ctx)
+	if err != nil || rolledUntil.IsZero() {
+		return nil, 0, err
+	}
+
+	var jobIDs []uint
+	if err := r.db.WithContext(ctx).Model(&model.TaskRun{}).
+		Distinct("job_id").
+		Where("status IN ?", finishedRunStatuses).
+		Pluck("job_id", &jobIDs).Error; err != nil {
+		return nil, 0, fmt.Errorf("query task run jobs: %w", err)
+	}
+
+	var candidates []uint
+	for _, jobID := range jobIDs {
+		if len(candidates) >= limit {
+			break
+		}
+		ids, err := r.pruneCandidates(ctx, jobID, policy, rolledUntil, limit-len(candidates))
+		if err != nil {
+			return nil, 0, err
+		}
+		candidates = append(candidates, ids...)
+	}
+	if len(candidates) == 0 {
+		return nil, 0, nil
+	}
+
+	// 查询与删除之间执行记录可能被恢复（断点续传），删除时再次确认状态
+	var deleted []uint
+	if err := r.db.WithContext(ctx).Model(&model.TaskRun{}).
+		Where("id IN ? AND status IN ?", candidates, finishedRunStatuses).
+		Pluck("id", &deleted).Error; err != nil {
+		return nil, 0, fmt.Errorf("query task runs to delete: %w", err)
+	}
+	if len(deleted) == 0 {
+		return nil, 0, nil
+	}
+
+	// 先删除事件明细再删除执行记录：中途失败时执行记录仍在，下次清理会重试，不会留下无主的事件
+	events, err := r.deleteEventBatches(ctx, "task_run_id IN ?", deleted)
+	if err != nil {
+		return nil, events, err
+	}
+	result := r.db.WithContext(ctx).
+		Where("id IN ? AND status IN ?", deleted, finishedRunStatuses).
+		Delete(&model.TaskRun{})
+	if result.Error != nil {
+		return nil, events, fmt.Errorf("delete task runs: %w", result.Error)
+	}
+	if int(result.RowsAffected) < len(deleted) {
+		// 期间被恢复的执行记录未删除，只返回实际删除的ID（调用方据此删除执行日志）
+		var kept []uint
+		if err := r.db.WithContext(ctx).Model(&model.TaskRun{}).
+			Where("id IN ?", deleted).
+			Pluck("id", &kept).Error; err != nil {
+			return nil, events, fmt.Errorf("query kept task runs: %w", err)
+		}
+		deleted = excludeIDs(deleted, kept)
+	}
+	return deleted, events, nil
+}
+
+// excludeIDs 返回 ids 中不在 exclude 里的ID
+func excludeIDs(ids, exclude []uint) []uint {
+	skip := make(map[uint]struct{}, len(exclude))
+	for _, id := range exclude {
+		skip[id] = struct{}{}
+	}
+	result := ids[:0]
+	for _, id := range ids {
+		if _, ok := skip[id]; !ok {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// PruneEvents 删除 before 之前的事件明细，返回删除条数
+//
+// 执行中与等待执行的记录的事件保留；只删除已汇总时间范围内的事件。
+func (r *GormRunStatsRepository) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rolledUntil, err := r.rolledUntil(ctx)
+	if err != nil || rolledUntil.IsZero() {
+		return 0, err
+	}
+	if rolledUntil.Before(before) {
+		before = rolledUntil
+	}
+	active := r.db.Model(&model.TaskRun{}).Select("id").Where("status IN ?", activeRunStatuses)
+	return r.deleteEventBatches(ctx, "created_at < ? AND task_run_id NOT IN (?)", before, active)
+}
+
+// pruneCandidates 返回单个任务中可删除的执行记录ID（最多 limit 条，按ID升序）
+func (r *GormRunStatsRepository) pruneCandidates(ctx context.Context, jobID uint, policy domainrepo.RunRetentionPolicy, rolledUntil time.Time, limit int) ([]uint, error) {
+	// 第 KeepPerJob+1 新的已结束记录及更早的记录可删除
+	var threshold model.TaskRun
+	err := r.db.WithContext(ctx).
+		Select("id").
+		Where("job_id = ? AND status IN ?", jobID, finishedRunStatuses).
+		Order("id DESC").
+		Offset(policy.KeepPerJob).
+		Limit(1).
+		Take(&threshold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query task run threshold: %w", err)
+	}
+
+	query := r.db.WithContext(ctx).Model(&model.TaskRun{}).
+		Where("job_id = ? AND status IN ? AND id <= ? AND started_at < ?", jobID, finishedRunStatuses, threshold.ID, rolledUntil)
+	if policy.KeepLastFailed {
+		var lastFailed model.TaskRun
+		err := r.db.WithContext(ctx).
+			Select("id").
+			Where("job_id = ? AND status = ?", jobID, "failed").
+			Order("id DESC").
+			Take(&lastFailed).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("query last failed task run: %w", err)
+		}
+		if err == nil {
+			query = query.Where("id <> ?", lastFailed.ID)
+		}
+	}
+
+	var ids []uint
+	if err := query.Order("id ASC").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("query task runs to prune: %w", err)
+	}
+	return ids, nil
+}
+
+// rolledUntil 返回小时与天两种粒度都已汇总到的时间点，尚未汇总时返回零值
+func (r *GormRunStatsRepository) rolledUntil(ctx context.Context) (time.Time, error) {
+	var until time.Time
+	for _, granularity := range []string{domainrepo.RunTrendHour, domainrepo.RunTrendDay} {
+		watermark, err := r.watermark(ctx, granularity)
+		if err != nil || watermark.IsZero() {
+			return time.Time{}, err
+		}
+		if until.IsZero() || watermark.Before(until) {
+			until = watermark
+		}
+	}
+	return until, nil
+}
+
+// deleteEventBatches 按条件分批删除事件明细
+func (r *GormRunStatsRepository) deleteEventBatches(ctx context.Context, cond string, args ...interface{}) (int64, error) {
+	var deleted int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		// 先查询 ID 再删除：MySQL 不支持 IN 子查询中使用 LIMIT
+		var ids []uint
+		if err := r.db.WithContext(ctx).Model(&model.TaskRunEvent{}).
+			Where(cond, args...).
+			Order("id ASC").
+			Limit(runEventDeleteBatch).
+			Pluck("id", &ids).Error; err != nil {
+			return deleted, fmt.Errorf("query task run events to delete: %w", err)
+		}
+		if len(ids) == 0 {
+			return deleted, nil
+		}
+		result := r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.TaskRunEvent{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("delete task run events: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < runEventDeleteBatch {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	domainrepo "github.com/strmsync/strmsync/internal/domain/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newRunStatsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.TaskRun{}, &model.TaskRunEvent{}, &model.TaskRunRollup{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	return db
+}
+
+func TestGormRunStatsRepository_Prune(t *testing.T) {
+	db := newRunStatsTestDB(t)
+	repo, err := NewGormRunStatsRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	startedAt := now.AddDate(0, 0, -40)
+
+	// 从旧到新：running 与 5 条已结束记录
+	var runs []model.TaskRun
+	for i, status := range []string{"running", "completed", "failed", "completed", "completed", "completed"} {
+		run := model.TaskRun{
+			JobID:     1,
+			Status:    status,
+			DedupKey:  fmt.Sprintf("run-%d", i),
+			StartedAt: startedAt.Add(time.Duration(i) * time.Hour),
+			Duration:  60,
+		}
+		if err := db.Create(&run).Error; err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		runs = append(runs, run)
+		event := model.TaskRunEvent{TaskRunID: run.ID, JobID: 1, Kind: "strm", Op: "create", Status: "success", CreatedAt: run.StartedAt}
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+	policy := domainrepo.RunRetentionPolicy{KeepPerJob: 2, KeepLastFailed: true}
+
+	// 尚未汇总时不删除
+	if ids, _, err := repo.PruneRuns(ctx, policy, 10); err != nil || len(ids) != 0 {
+		t.Fatalf("expected nothing pruned before rollup, got %v err=%v", ids, err)
+	}
+	if _, err := repo.Rollup(ctx, now); err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+
+	// 分批删除：最新 2 条、最近一次失败与执行中的记录保留
+	ids, events, err := repo.PruneRuns(ctx, policy, 1)
+	if err != nil || len(ids) != 1 || ids[0] != runs[1].ID || events != 1 {
+		t.Fatalf("unexpected first batch: ids=%v events=%d err=%v", ids, events, err)
+	}
+	ids, _, err = repo.PruneRuns(ctx, policy, 10)
+	if err != nil || len(ids) != 1 || ids[0] != runs[3].ID {
+		t.Fatalf("unexpected second batch: ids=%v err=%v", ids, err)
+	}
+	if ids, _, _ := repo.PruneRuns(ctx, policy, 10); len(ids) != 0 {
+		t.Fatalf("expected nothing left to prune, got %v", ids)
+	}
+	var remaining []uint
+	db.Model(&model.TaskRun{}).Order("id").Pluck("id", &remaining)
+	if fmt.Sprint(remaining) != fmt.Sprint([]uint{runs[0].ID, runs[2].ID, runs[4].ID, runs[5].ID}) {
+		t.Fatalf("unexpected remaining runs: %v", remaining)
+	}
+
+	// 事件按天数清理，执行中记录的事件保留
+	deleted, err := repo.PruneEvents(ctx, now.AddDate(0, 0, -30))
+	if err != nil || deleted != 3 {
+		t.Fatalf("expected 3 events pruned, got %d err=%v", deleted, err)
+	}
+	var eventRuns []uint
+	db.Model(&model.TaskRunEvent{}).Pluck("task_run_id", &eventRuns)
+	if len(eventRuns) != 1 || eventRuns[0] != runs[0].ID {
+		t.Fatalf("expected only running run events kept, got %v", eventRuns)
+	}
+
+	// 趋势统计来自汇总表，不受清理影响
+	points, err := repo.Trends(ctx, domainrepo.RunTrendFilter{
+		Granularity: domainrepo.RunTrendDay,
+		From:        startedAt.AddDate(0, 0, -1),
+		To:          now,
+	})
+	if err != nil {
+		t.Fatalf("trends: %v", err)
+	}
+	var total int64
+	for _, point := range points {
+		total += point.Runs
+	}
+	if total != 5 {
+		t.Fatalf("expected 5 runs in trends after prune, got %d", total)
+	}
+}
+
+func TestGormRunStatsRepository_PruneRunsKeepsRunsWhenEventsFail(t *testing.T) {
+	db := newRunStatsTestDB(t)
+	repo, err := NewGormRunStatsRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		run := model.TaskRun{JobID: 1, Status: "completed", DedupKey: fmt.Sprintf("run-%d", i), StartedAt: now.AddDate(0, 0, -10+i)}
+		if err := db.Create(&run).Error; err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		event := model.TaskRunEvent{TaskRunID: run.ID, JobID: 1, Kind: "strm", Op: "create", Status: "success", CreatedAt: run.StartedAt}
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+	if _, err := repo.Rollup(ctx, now); err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+
+	// 删除事件失败时不删除执行记录，下次清理重试
+	failEvents := true
+	if err := db.Callback().Delete().Before("gorm:delete").Register("test:fail_events", func(tx *gorm.DB) {
+		if failEvents && tx.Statement.Table == "task_run_events" {
+			tx.AddError(fmt.Errorf("disk I/O error"))
+		}
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	policy := domainrepo.RunRetentionPolicy{KeepPerJob: 1}
+	if ids, _, err := repo.PruneRuns(ctx, policy, 10); err == nil || len(ids) != 0 {
+		t.Fatalf("expected event delete error, got ids=%v err=%v", ids, err)
+	}
+	var runs, events int64
+	db.Model(&model.TaskRun{}).Count(&runs)
+	db.Model(&model.TaskRunEvent{}).Count(&events)
+	if runs != 3 || events != 3 {
+		t.Fatalf("expected runs and events kept after failure, got runs=%d events=%d", runs, events)
+	}
+
+	failEvents = false
+	ids, deleted, err := repo.PruneRuns(ctx, policy, 10)
+	if err != nil || len(ids) != 2 || deleted != 2 {
+		t.Fatalf("unexpected retry: ids=%v events=%d err=%v", ids, deleted, err)
+	}
+	db.Model(&model.TaskRunEvent{}).Count(&events)
+	if events != 1 {
+		t.Fatalf("expected only the kept run's event left, got %d", events)
+	}
+}
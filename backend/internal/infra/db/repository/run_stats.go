@@ -0,0 +1,309 @@
+// Package repository 提供执行统计相关的 GORM Repository 实现
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	domainrepo "github.com/strmsync/strmsync/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// runRollupDelay 时间桶结束后等待多久才汇总（给跨桶执行的任务留出结束时间）
+	runRollupDelay = 24 * time.Hour
+	// runRollupWindowDays 汇总时每批处理的天数，避免一次加载过多执行记录
+	runRollupWindowDays = 7
+)
+
+// finishedRunStatuses 参与趋势统计的执行状态
+var finishedRunStatuses = []string{"completed", "failed", "cancelled"}
+
+// GormRunStatsRepository 是基于 GORM 的执行统计实现
+type GormRunStatsRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRunStatsRepository 创建 GormRunStatsRepository 实例
+//
+// 参数：
+//   - db: GORM 数据库连接（不能为 nil）
+//
+// 返回：
+//   - *GormRunStatsRepository: Repository 实例
+//   - error: db 为 nil 时返回错误
+func NewGormRunStatsRepository(db *gorm.DB) (*GormRunStatsRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("core: gorm db is nil")
+	}
+	return &GormRunStatsRepository{db: db}, nil
+}
+
+// Trends 按任务与时间桶返回执行趋势（按任务ID、时间桶升序）
+//
+// From 会向下对齐到所在时间桶的起点。
+func (r *GormRunStatsRepository) Trends(ctx context.Context, filter domainrepo.RunTrendFilter) ([]model.TaskRunRollup, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !validRunTrendGranularity(filter.Granularity) {
+		return nil, fmt.Errorf("invalid granularity %q", filter.Granularity)
+	}
+	from := truncateRunBucket(filter.From, filter.Granularity)
+	to := filter.To.In(time.Local)
+
+	watermark, err := r.watermark(ctx, filter.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []model.TaskRunRollup
+	if watermark.After(from) {
+		end := to
+		if watermark.Before(end) {
+			end = watermark
+		}
+		query := r.db.WithContext(ctx).
+			Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", filter.Granularity, from, end)
+		if filter.JobID != nil {
+			query = query.Where("job_id = ?", *filter.JobID)
+		}
+		if err := query.Find(&points).Error; err != nil {
+			return nil, fmt.Errorf("query run rollups: %w", err)
+		}
+		from = watermark
+	}
+	if from.Before(to) {
+		raw, err := r.aggregate(ctx, filter.Granularity, filter.JobID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, raw...)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].JobID != points[j].JobID {
+			return points[i].JobID < points[j].JobID
+		}
+		return points[i].BucketStart.Before(points[j].BucketStart)
+	})
+	return points, nil
+}
+
+// Rollup 汇总已结束的时间桶，返回写入的汇总行数
+//
+// 从上次汇总的位置继续，只处理结束超过 runRollupDelay 的时间桶；首次执行时回填全部历史。
+func (r *GormRunStatsRepository) Rollup(ctx context.Context, now time.Time) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	written := 0
+	for _, granularity := range []string{domainrepo.RunTrendHour, domainrepo.RunTrendDay} {
+		cutoff := truncateRunBucket(now.Add(-runRollupDelay), granularity)
+		start, err := r.watermark(ctx, granularity)
+		if err != nil {
+			return written, err
+		}
+		if start.IsZero() {
+			start, err = r.firstRunStart(ctx)
+			if err != nil {
+				return written, err
+			}
+			if start.IsZero() {
+				continue
+			}
+			start = truncateRunBucket(start, granularity)
+		}
+
+		for windowStart := start; windowStart.Before(cutoff); {
+			windowEnd := windowStart.AddDate(0, 0, runRollupWindowDays)
+			if windowEnd.After(cutoff) {
+				windowEnd = cutoff
+			}
+			points, err := r.aggregate(ctx, granularity, nil, windowStart, windowEnd)
+			if err != nil {
+				return written, err
+			}
+			if len(points) > 0 {
+				if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+					Columns: []clause.Column{{Name: "job_id"}, {Name: "granularity"}, {Name: "bucket_start"}},
+					DoUpdates: clause.AssignmentColumns([]string{
+						"runs", "completed", "failed", "cancelled", "duration_p50", "duration_p95",
+						"created_files", "updated_files", "deleted_files", "failed_files",
+						"meta_bytes", "api_errors", "updated_at",
+					}),
+				}).Create(&points).Error; err != nil {
+					return written, fmt.Errorf("save run rollups: %w", err)
+				}
+				written += len(points)
+			}
+			windowStart = windowEnd
+		}
+	}
+	return written, nil
+}
+
+// watermark 返回已汇总区间的终点（最后一个汇总时间桶的结束时间），尚未汇总时返回零值
+func (r *GormRunStatsRepository) watermark(ctx context.Context, granularity string) (time.Time, error) {
+	var last model.TaskRunRollup
+	err := r.db.WithContext(ctx).
+		Where("granularity = ?", granularity).
+		Order("bucket_start DESC").
+		Take(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query run rollup watermark: %w", err)
+	}
+	return nextRunBucket(truncateRunBucket(last.BucketStart, granularity), granularity), nil
+}
+
+// firstRunStart 返回最早一条已结束执行记录的开始时间，没有时返回零值
+func (r *GormRunStatsRepository) firstRunStart(ctx context.Context) (time.Time, error) {
+	var first model.TaskRun
+	err := r.db.WithContext(ctx).
+		Select("started_at").
+		Where("status IN ? AND started_at >= ?", finishedRunStatuses, time.Unix(0, 0)).
+		Order("started_at ASC").
+		Take(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query first task run: %w", err)
+	}
+	return first.StartedAt, nil
+}
+
+// aggregate 从 task_runs 与 task_run_events 实时计算 [from, to) 内的时间桶
+func (r *GormRunStatsRepository) aggregate(ctx context.Context, granularity string, jobID *uint, from, to time.Time) ([]model.TaskRunRollup, error) {
+	runsQuery := func() *gorm.DB {
+		query := r.db.WithContext(ctx).Model(&model.TaskRun{}).
+			Where("status IN ? AND started_at >= ? AND started_at < ?", finishedRunStatuses, from, to)
+		if jobID != nil {
+			query = query.Where("job_id = ?", *jobID)
+		}
+		return query
+	}
+
+	var runs []model.TaskRun
+	if err := runsQuery().
+		Select("id", "job_id", "status", "started_at", "duration", "created_files", "updated_files", "failed_files", "api_errors").
+		Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("query task runs: %w", err)
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+
+	// 删除数与元数据字节数来自事件明细
+	type eventTotals struct {
+		TaskRunID uint
+		Deleted   int64
+		Bytes     int64
+	}
+	var totals []eventTotals
+	if err := r.db.WithContext(ctx).Model(&model.TaskRunEvent{}).
+		Select("task_run_id, "+
+			"SUM(CASE WHEN kind = 'strm' AND op = 'delete' THEN 1 ELSE 0 END) AS deleted, "+
+			"SUM(CASE WHEN kind = 'meta' THEN bytes ELSE 0 END) AS bytes").
+		Where("status = ? AND task_run_id IN (?)", "success", runsQuery().Select("id")).
+		Group("task_run_id").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("aggregate task run events: %w", err)
+	}
+	eventsByRun := make(map[uint]eventTotals, len(totals))
+	for _, t := range totals {
+		eventsByRun[t.TaskRunID] = t
+	}
+
+	type bucketKey struct {
+		jobID uint
+		start int64
+	}
+	buckets := make(map[bucketKey]*model.TaskRunRollup)
+	durations := make(map[bucketKey][]int64)
+	now := time.Now()
+	for _, run := range runs {
+		start := truncateRunBucket(run.StartedAt, granularity)
+		key := bucketKey{jobID: run.JobID, start: start.Unix()}
+		point, ok := buckets[key]
+		if !ok {
+			point = &model.TaskRunRollup{
+				JobID:       run.JobID,
+				Granularity: granularity,
+				BucketStart: start,
+				UpdatedAt:   now,
+			}
+			buckets[key] = point
+		}
+		point.Runs++
+		switch run.Status {
+		case "completed":
+			point.Completed++
+		case "failed":
+			point.Failed++
+		case "cancelled":
+			point.Cancelled++
+		}
+		point.CreatedFiles += int64(run.CreatedFiles)
+		point.UpdatedFiles += int64(run.UpdatedFiles)
+		point.FailedFiles += int64(run.FailedFiles)
+		point.APIErrors += int64(run.APIErrors)
+		if events, ok := eventsByRun[run.ID]; ok {
+			point.DeletedFiles += events.Deleted
+			point.MetaBytes += events.Bytes
+		}
+		durations[key] = append(durations[key], run.Duration)
+	}
+
+	points := make([]model.TaskRunRollup, 0, len(buckets))
+	for key, point := range buckets {
+		values := durations[key]
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		point.DurationP50 = percentile(values, 0.50)
+		point.DurationP95 = percentile(values, 0.95)
+		points = append(points, *point)
+	}
+	return points, nil
+}
+
+// percentile 使用最近秩法计算已排序数据的分位数
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// truncateRunBucket 返回时间所在时间桶的起点（本地时区）
+func truncateRunBucket(t time.Time, granularity string) time.Time {
+	t = t.In(time.Local)
+	if granularity == domainrepo.RunTrendHour {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// nextRunBucket 返回下一个时间桶的起点
+func nextRunBucket(start time.Time, granularity string) time.Time {
+	if granularity == domainrepo.RunTrendHour {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func validRunTrendGranularity(granularity string) bool {
+	return granularity == domainrepo.RunTrendHour || granularity == domainrepo.RunTrendDay
+}
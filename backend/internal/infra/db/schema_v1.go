@@ -0,0 +1,218 @@
+package db
+
+import "time"
+
+// 迁移使用的冻结表结构
+//
+// 迁移创建表时不能使用 model 包中的当前模型：模型会随后续迁移增加字段，
+// 直接使用会让新库在早期迁移中就得到后续版本的列，与升级库的执行过程不一致。
+// 以下结构与对应迁移版本发布时的模型一致（只保留 gorm 标签与关联字段），不要修改；
+// 模型新增字段时追加迁移添加列。
+
+// v1DataServer 版本 1 的 data_servers 表
+type v1DataServer struct {
+	ID                  uint   `gorm:"primaryKey"`
+	UID                 string `gorm:"size:64;uniqueIndex"`
+	Name                string `gorm:"uniqueIndex;not null"`
+	Type                string `gorm:"index;not null"`
+	Host                string `gorm:"not null"`
+	Port                int    `gorm:"not null"`
+	APIKey              string `gorm:"type:text"`
+	Enabled             bool   `gorm:"not null;default:true"`
+	Options             string `gorm:"type:text"`
+	DownloadRatePerSec  int    `gorm:"not null;default:0"`
+	APIRate             int    `gorm:"not null;default:0"`
+	APIRetryMax         int    `gorm:"not null;default:0"`
+	APIRetryIntervalSec int    `gorm:"not null;default:0"`
+	MaxConcurrentRuns   int    `gorm:"not null;default:0"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// v1MediaServer 版本 1 的 media_servers 表
+type v1MediaServer struct {
+	ID                  uint   `gorm:"primaryKey"`
+	UID                 string `gorm:"size:64;uniqueIndex"`
+	Name                string `gorm:"uniqueIndex;not null"`
+	Type                string `gorm:"index;not null"`
+	Host                string `gorm:"not null"`
+	Port                int    `gorm:"not null"`
+	APIKey              string `gorm:"type:text"`
+	Enabled             bool   `gorm:"not null;default:true"`
+	Options             string `gorm:"type:text"`
+	DownloadRatePerSec  int    `gorm:"not null;default:0"`
+	APIRate             int    `gorm:"not null;default:0"`
+	APIRetryMax         int    `gorm:"not null;default:0"`
+	APIRetryIntervalSec int    `gorm:"not null;default:0"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// v1Job 版本 1 的 jobs 表
+type v1Job struct {
+	ID                uint   `gorm:"primaryKey"`
+	Name              string `gorm:"uniqueIndex;not null"`
+	Enabled           bool   `gorm:"not null;default:true"`
+	Cron              string `gorm:"type:text"`
+	WatchMode         string `gorm:"not null;default:'local'"`
+	SourcePath        string `gorm:"not null"`
+	RemoteRoot        string `gorm:"type:text"`
+	TargetPath        string `gorm:"not null"`
+	STRMPath          string `gorm:"not null"`
+	DataServerID      *uint  `gorm:"index"`
+	MediaServerID     *uint  `gorm:"index"`
+	Options           string `gorm:"type:text"`
+	MaxConcurrentRuns int    `gorm:"not null;default:0"`
+	WorkerGroup       string `gorm:"size:64;not null;default:''"`
+	Status            string `gorm:"default:'idle'"`
+	LastRunAt         *time.Time
+	ErrorMessage      string    `gorm:"type:text"`
+	CreatedAt         time.Time `gorm:"index:idx_jobs_created_at,sort:desc"`
+	UpdatedAt         time.Time
+
+	DataServer  *v1DataServer    `gorm:"foreignKey:DataServerID"`
+	MediaServer *v1MediaServer   `gorm:"foreignKey:MediaServerID"`
+	TaskRuns    []v1TaskRun      `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE"`
+	MetaHashes  []v1MetaFileHash `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE"`
+}
+
+// v1TaskRun 版本 1 的 task_runs 表
+type v1TaskRun struct {
+	ID                 uint       `gorm:"primaryKey"`
+	JobID              uint       `gorm:"index;not null"`
+	Status             string     `gorm:"not null;index:idx_task_runs_status_priority_available,priority:1"`
+	Priority           int        `gorm:"not null;default:2;index:idx_task_runs_status_priority_available,priority:2"`
+	AvailableAt        time.Time  `gorm:"index:idx_task_runs_status_priority_available,priority:3"`
+	Attempts           int        `gorm:"not null;default:0"`
+	MaxAttempts        int        `gorm:"not null;default:3"`
+	DedupKey           string     `gorm:"uniqueIndex;not null"`
+	WorkerID           string     `gorm:"index"`
+	LeaseExpiresAt     *time.Time `gorm:"index"`
+	FailureKind        string     `gorm:"index"`
+	StartedAt          time.Time  `gorm:"index"`
+	EndedAt            *time.Time
+	Duration           int64  `gorm:"default:0"`
+	Progress           int    `gorm:"default:0"`
+	TotalFiles         int    `gorm:"default:0"`
+	ProcessedFiles     int    `gorm:"default:0"`
+	FailedFiles        int    `gorm:"default:0"`
+	CreatedFiles       int    `gorm:"default:0"`
+	UpdatedFiles       int    `gorm:"default:0"`
+	SkippedFiles       int    `gorm:"default:0"`
+	FilteredFiles      int    `gorm:"default:0"`
+	MetaTotalFiles     int    `gorm:"default:0"`
+	MetaCreatedFiles   int    `gorm:"default:0"`
+	MetaUpdatedFiles   int    `gorm:"default:0"`
+	MetaProcessedFiles int    `gorm:"default:0"`
+	MetaFailedFiles    int    `gorm:"default:0"`
+	APIErrors          int    `gorm:"default:0"`
+	ErrorMessage       string `gorm:"type:text"`
+	Payload            string `gorm:"type:text"`
+	Checkpoint         string `gorm:"type:text"`
+
+	Job *v1Job `gorm:"foreignKey:JobID"`
+}
+
+// v1TaskRunEvent 版本 1 的 task_run_events 表
+type v1TaskRunEvent struct {
+	ID           uint      `gorm:"primaryKey"`
+	TaskRunID    uint      `gorm:"index;not null"`
+	JobID        uint      `gorm:"index;not null"`
+	Kind         string    `gorm:"index;not null"`
+	Op           string    `gorm:"index;not null"`
+	Status       string    `gorm:"index;not null"`
+	SourcePath   string    `gorm:"type:text"`
+	TargetPath   string    `gorm:"type:text"`
+	ErrorMessage string    `gorm:"type:text"`
+	MediaItem    string    `gorm:"index"`
+	PreviousPath string    `gorm:"type:text"`
+	Bytes        int64     `gorm:"default:0"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+// v1TaskRunRollup 版本 1 的 task_run_rollups 表
+type v1TaskRunRollup struct {
+	ID           uint      `gorm:"primaryKey"`
+	JobID        uint      `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:1"`
+	Granularity  string    `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:2"`
+	BucketStart  time.Time `gorm:"not null;uniqueIndex:idx_task_run_rollups_bucket,priority:3;index"`
+	Runs         int64     `gorm:"default:0"`
+	Completed    int64     `gorm:"default:0"`
+	Failed       int64     `gorm:"default:0"`
+	Cancelled    int64     `gorm:"default:0"`
+	DurationP50  int64     `gorm:"default:0"`
+	DurationP95  int64     `gorm:"default:0"`
+	CreatedFiles int64     `gorm:"default:0"`
+	UpdatedFiles int64     `gorm:"default:0"`
+	DeletedFiles int64     `gorm:"default:0"`
+	FailedFiles  int64     `gorm:"default:0"`
+	MetaBytes    int64     `gorm:"default:0"`
+	APIErrors    int64     `gorm:"default:0"`
+	UpdatedAt    time.Time
+}
+
+// v1MetaFileHash 版本 1 的 meta_file_hashes 表
+type v1MetaFileHash struct {
+	ID         uint   `gorm:"primaryKey"`
+	JobID      uint   `gorm:"not null;uniqueIndex:idx_meta_file_hashes_job_target,priority:1"`
+	TargetPath string `gorm:"not null;uniqueIndex:idx_meta_file_hashes_job_target,priority:2"`
+	SourceHash string `gorm:"not null"`
+	Size       int64  `gorm:"default:0"`
+	ModTime    time.Time
+	UpdatedAt  time.Time
+}
+
+// v1WorkerNode 版本 1 的 worker_nodes 表
+type v1WorkerNode struct {
+	ID          string `gorm:"primaryKey;size:128"`
+	Group       string `gorm:"column:worker_group;size:64;index"`
+	Hostname    string
+	Concurrency int `gorm:"default:0"`
+	Reserved    int `gorm:"default:0"`
+	InFlight    int `gorm:"default:0"`
+	StartedAt   time.Time
+	HeartbeatAt time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+	StoppedAt   *time.Time
+}
+
+// v1LogEntry 版本 1 的 logs 表
+type v1LogEntry struct {
+	ID         uint      `gorm:"primaryKey"`
+	Level      string    `gorm:"index:idx_logs_level_created_at,priority:1;not null"`
+	Module     *string   `gorm:"index"`
+	Message    string    `gorm:"type:text;not null"`
+	RequestID  *string   `gorm:"index"`
+	UserAction *string   `gorm:"index"`
+	JobID      *uint     `gorm:"index"`
+	RunID      *uint     `gorm:"index"`
+	CreatedAt  time.Time `gorm:"index:idx_logs_level_created_at,priority:2;index"`
+}
+
+// v1Setting 版本 1 的 settings 表
+type v1Setting struct {
+	Key       string `gorm:"primaryKey"`
+	Value     string `gorm:"type:text;not null"`
+	UpdatedAt time.Time
+}
+
+// v5StrmMapping 版本 5 的 strm_mappings 表（不含版本 7 的签名列）
+type v5StrmMapping struct {
+	ID         uint   `gorm:"primaryKey"`
+	JobID      uint   `gorm:"not null;uniqueIndex:idx_strm_mappings_job_source,priority:1"`
+	SourcePath string `gorm:"not null;uniqueIndex:idx_strm_mappings_job_source,priority:2"`
+	OutputPath string `gorm:"not null"`
+	UpdatedAt  time.Time
+}
+
+func (v1DataServer) TableName() string    { return "data_servers" }
+func (v1MediaServer) TableName() string   { return "media_servers" }
+func (v1Job) TableName() string           { return "jobs" }
+func (v1TaskRun) TableName() string       { return "task_runs" }
+func (v1TaskRunEvent) TableName() string  { return "task_run_events" }
+func (v1TaskRunRollup) TableName() string { return "task_run_rollups" }
+func (v1MetaFileHash) TableName() string  { return "meta_file_hashes" }
+func (v1WorkerNode) TableName() string    { return "worker_nodes" }
+func (v1LogEntry) TableName() string      { return "logs" }
+func (v1Setting) TableName() string       { return "settings" }
+func (v5StrmMapping) TableName() string   { return "strm_mappings" }
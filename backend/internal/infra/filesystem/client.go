@@ -37,6 +37,35 @@ type Provider interface {
 	Download(ctx context.Context, remotePath string, w io.Writer) error
 }
 
+// StreamProvider 支持流式列目录的 Provider（可选）
+//
+// 实现需按深度优先、名称排序的顺序回调（参见 WalkOrdered）。
+// 未实现时 ClientImpl 会降级为 List + 排序后逐个回调。
+type StreamProvider interface {
+	ListStream(ctx context.Context, path string, recursive bool, maxDepth int, fn func(RemoteFile) error) error
+}
+
+// StrmProvider 自行生成 HTTP 模式 STRM 内容的 Provider（可选）
+//
+// 未实现时 ClientImpl 使用通用的 /d/<path> 下载链接。
+type StrmProvider interface {
+	BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmRequest) (syncengine.StrmInfo, error)
+}
+
+// RunFinisher 同步结束时需要清理的 Provider（可选）
+//
+// Worker 在每次执行结束后对本次构建的驱动调用 FinishRun，ClientImpl 与 Adapter 逐层转发。
+type RunFinisher interface {
+	FinishRun()
+}
+
+// RangeProvider 支持从指定偏移量下载的 Provider（可选，用于断点续传）
+//
+// 实现需从 offset 处开始写入 w；服务端忽略 Range 请求时应丢弃前 offset 字节。
+type RangeProvider interface {
+	DownloadRange(ctx context.Context, remotePath string, offset int64, w io.Writer) error
+}
+
 type providerFactory func(*ClientImpl) (Provider, error)
 
 var providerRegistry = map[Type]providerFactory{}
@@ -53,6 +82,10 @@ type ClientImpl struct {
 	HTTPClient *http.Client
 	Logger     *zap.Logger
 	Provider   Provider
+	// ListLimiter 目录列出并发限制器（同一服务器共享）
+	ListLimiter *ListLimiter
+	// DownloadLimiter 下载并发与限速器（同一服务器共享）
+	DownloadLimiter *DownloadLimiter
 }
 
 // Option 客户端可选配置
@@ -110,6 +143,14 @@ func NewClient(config Config, opts ...Option) (Client, error) {
 	if !config.STRMMode.IsValid() {
 		return nil, fmt.Errorf("filesystem: invalid strm_mode: %s", config.STRMMode)
 	}
+	if config.LinkMode != "" && !config.LinkMode.IsValid() {
+		return nil, fmt.Errorf("filesystem: invalid link_mode: %s", config.LinkMode)
+	}
+	if strings.TrimSpace(config.PickCodeURL) != "" {
+		if err := validatePickCodeURL(strings.TrimSpace(config.PickCodeURL)); err != nil {
+			return nil, err
+		}
+	}
 	if config.STRMMode == STRMModeMount {
 		mountPath := strings.TrimSpace(config.MountPath)
 		strmMountPath := strings.TrimSpace(config.StrmMountPath)
@@ -126,10 +167,12 @@ func NewClient(config Config, opts ...Option) (Client, error) {
 
 	// 创建客户端实例
 	client := &ClientImpl{
-		Config:     config,
-		BaseURL:    parsedURL,
-		HTTPClient: &http.Client{Timeout: timeout},
-		Logger:     logger.L(),
+		Config:          config,
+		BaseURL:         parsedURL,
+		HTTPClient:      &http.Client{Timeout: timeout},
+		Logger:          logger.L(),
+		ListLimiter:     serverListLimiter(config),
+		DownloadLimiter: serverDownloadLimiter(config),
 	}
 
 	// 应用可选配置
@@ -182,6 +225,42 @@ func (c *ClientImpl) List(ctx context.Context, listPath string, recursive bool,
 	return c.Provider.List(ctx, listPath, recursive, maxDepth)
 }
 
+// ListStream 流式列出目录内容
+func (c *ClientImpl) ListStream(ctx context.Context, listPath string, recursive bool, maxDepth int, fn func(RemoteFile) error) error {
+	// 防御 nil context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if fn == nil {
+		return fmt.Errorf("filesystem: ListStream callback is nil")
+	}
+
+	// 默认路径为根目录
+	if strings.TrimSpace(listPath) == "" {
+		listPath = "/"
+	}
+
+	if c.Provider == nil {
+		return fmt.Errorf("filesystem: Provider not initialized")
+	}
+	if streamer, ok := c.Provider.(StreamProvider); ok {
+		return streamer.ListStream(ctx, listPath, recursive, maxDepth, fn)
+	}
+
+	// 降级：一次性列出后按遍历顺序回调
+	files, err := c.Provider.List(ctx, listPath, recursive, maxDepth)
+	if err != nil {
+		return err
+	}
+	sortRemoteFiles(files)
+	for _, f := range files {
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Watch 监控目录变化
 func (c *ClientImpl) Watch(ctx context.Context, path string) (<-chan FileEvent, error) {
 	if c.Provider == nil {
@@ -211,7 +290,8 @@ func (c *ClientImpl) BuildStreamURL(ctx context.Context, serverID uint, filePath
 
 // BuildStrmInfo 构建结构化的 STRM 信息
 // - mount 模式：返回本地路径（BaseURL 为 nil）
-// - http 模式：返回 HTTP URL 并解析元信息
+// - http 模式（文件带有 PickCode 且配置了 PickCodeURL）：使用 PickCode 链接
+// - http 模式（其他情况）：Provider 实现 StrmProvider 时由其生成，否则返回 HTTP URL 并解析元信息
 func (c *ClientImpl) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmRequest) (syncengine.StrmInfo, error) {
 	if c.Provider == nil {
 		return syncengine.StrmInfo{}, fmt.Errorf("filesystem: Provider not initialized")
@@ -232,6 +312,14 @@ func (c *ClientImpl) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrm
 		}, nil
 	}
 
+	if tmpl := strings.TrimSpace(c.Config.PickCodeURL); tmpl != "" && req.RemoteMeta != nil && req.RemoteMeta.PickCode != "" {
+		return buildPickCodeStrmInfo(tmpl, req.RemoteMeta.PickCode, req.RemotePath)
+	}
+
+	if builder, ok := c.Provider.(StrmProvider); ok {
+		return builder.BuildStrmInfo(ctx, req)
+	}
+
 	rawURL, err := c.BuildStreamURL(ctx, req.ServerID, req.RemotePath)
 	if err != nil {
 		return syncengine.StrmInfo{}, fmt.Errorf("filesystem: 构建流媒体 URL 失败: %w", err)
@@ -256,6 +344,13 @@ func (c *ClientImpl) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrm
 	return info, nil
 }
 
+// FinishRun 实现 RunFinisher（转发给 Provider）
+func (c *ClientImpl) FinishRun() {
+	if finisher, ok := c.Provider.(RunFinisher); ok {
+		finisher.FinishRun()
+	}
+}
+
 // TestConnection 测试连接
 func (c *ClientImpl) TestConnection(ctx context.Context) error {
 	// 防御 nil context
@@ -386,7 +481,16 @@ func (c *ClientImpl) ResolveAccessPath(ctx context.Context, remotePath string) (
 }
 
 // Download 下载文件内容到writer
+//
+// 下载受服务器共享的 DownloadLimiter 约束（并发数、每秒下载数、带宽）。
 func (c *ClientImpl) Download(ctx context.Context, remotePath string, w io.Writer) error {
+	return c.DownloadRange(ctx, remotePath, 0, w)
+}
+
+// DownloadRange 从 offset 处下载文件内容到writer（用于断点续传）
+//
+// offset > 0 且 Provider 不支持 RangeProvider 时返回 ErrNotSupported。
+func (c *ClientImpl) DownloadRange(ctx context.Context, remotePath string, offset int64, w io.Writer) error {
 	// 防御 nil context
 	if ctx == nil {
 		ctx = context.Background()
@@ -395,6 +499,35 @@ func (c *ClientImpl) Download(ctx context.Context, remotePath string, w io.Write
 	if c.Provider == nil {
 		return fmt.Errorf("filesystem: Provider not initialized")
 	}
+	ranger, ok := c.Provider.(RangeProvider)
+	if offset > 0 && !ok {
+		return fmt.Errorf("filesystem: range download: %w", ErrNotSupported)
+	}
+
+	if c.DownloadLimiter != nil {
+		if err := c.DownloadLimiter.Acquire(ctx); err != nil {
+			return err
+		}
+		defer c.DownloadLimiter.Release()
+		w = c.DownloadLimiter.Writer(ctx, w)
+	}
 
+	if offset > 0 {
+		return ranger.DownloadRange(ctx, remotePath, offset, w)
+	}
 	return c.Provider.Download(ctx, remotePath, w)
 }
+
+// SupportsRange 判断 Provider 是否支持断点续传
+func (c *ClientImpl) SupportsRange() bool {
+	_, ok := c.Provider.(RangeProvider)
+	return ok
+}
+
+// DownloadConcurrency 返回服务器的并发下载数上限
+func (c *ClientImpl) DownloadConcurrency() int {
+	if c.DownloadLimiter == nil {
+		return 1
+	}
+	return c.DownloadLimiter.Cap()
+}
@@ -8,7 +8,9 @@
 //   import _ "github.com/strmsync/strmsync/internal/infra/filesystem/clouddrive2"
 //
 // The CloudDrive2 provider uses gRPC to communicate with CloudDrive2 server.
-// It supports both streaming and HTTP STRM modes.
+// It supports both streaming and HTTP STRM modes. HTTP STRM content comes from
+// the GetDownloadUrlPath RPC, either as a CloudDrive2-proxied /static/... URL
+// (link_mode=proxy) or as a direct cloud URL (link_mode=direct).
 //
 // Exports:
 //   - NewCloudDrive2Provider: Creates a Provider implementation (used by registration)
@@ -30,19 +32,29 @@ import (
 	syncengine "github.com/strmsync/strmsync/internal/engine"
 	"github.com/strmsync/strmsync/internal/infra/filesystem"
 	cd2sdk "github.com/strmsync/strmsync/internal/pkg/sdk/clouddrive2"
+	pb "github.com/strmsync/strmsync/internal/pkg/sdk/clouddrive2/proto"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // ---------- CloudDrive2 Provider Implementation ----------
 
+// downloadURLClient 获取下载链接的 CloudDrive2 接口（便于测试替换）
+type downloadURLClient interface {
+	GetDownloadUrlPath(ctx context.Context, path string, preview, lazyRead, getDirect bool) (*pb.DownloadUrlPathInfo, error)
+}
+
 // cloudDrive2Provider CloudDrive2文件系统实现
 type cloudDrive2Provider struct {
 	config     filesystem.Config
 	logger     *zap.Logger
 	client     *cd2sdk.CloudDrive2Client
+	urls       downloadURLClient
 	baseURL    *url.URL
 	httpClient *http.Client
+	limiter    *filesystem.ListLimiter
+	linkMode   filesystem.LinkMode
+	links      *linkCache
 }
 
 // NewCloudDrive2Provider 创建CloudDrive2 filesystem.Provider
@@ -60,12 +72,21 @@ func NewCloudDrive2Provider(c *filesystem.ClientImpl) (filesystem.Provider, erro
 	// 创建CloudDrive2客户端（Password字段存储API Token）
 	client := cd2sdk.NewCloudDrive2Client(target, c.Config.Password, cd2sdk.WithTimeout(c.Config.Timeout))
 
+	linkMode := c.Config.LinkMode
+	if linkMode == "" {
+		linkMode = filesystem.LinkModeProxy
+	}
+
 	return &cloudDrive2Provider{
 		config:     c.Config,
 		logger:     c.Logger,
 		client:     client,
+		urls:       client,
 		baseURL:    c.BaseURL,
 		httpClient: c.HTTPClient,
+		limiter:    c.ListLimiter,
+		linkMode:   linkMode,
+		links:      serverLinkCache(c.Config, linkMode),
 	}, nil
 }
 
@@ -77,7 +98,41 @@ func (p *cloudDrive2Provider) List(ctx context.Context, listPath string, recursi
 	if strings.TrimSpace(listPath) == "" {
 		listPath = "/"
 	}
-	return p.listCloudDrive2(ctx, listPath, recursive, maxDepth)
+	var results []filesystem.RemoteFile
+	err := p.ListStream(ctx, listPath, recursive, maxDepth, func(f filesystem.RemoteFile) error {
+		results = append(results, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListStream 流式列出目录内容（目录并行列出，按遍历顺序回调）
+func (p *cloudDrive2Provider) ListStream(ctx context.Context, listPath string, recursive bool, maxDepth int, fn func(filesystem.RemoteFile) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if strings.TrimSpace(listPath) == "" {
+		listPath = "/"
+	}
+
+	var count int
+	err := filesystem.WalkOrdered(ctx, listPath, recursive, maxDepth, p.limiter, p.listDir, func(f filesystem.RemoteFile) error {
+		count++
+		return fn(f)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("CloudDrive2目录列出完成",
+		zap.String("root", listPath),
+		zap.Bool("recursive", recursive),
+		zap.Int("max_depth", maxDepth),
+		zap.Int("count", count))
+	return nil
 }
 
 // Watch 监控目录变化（CloudDrive2不支持）
@@ -105,6 +160,13 @@ func (p *cloudDrive2Provider) TestConnection(ctx context.Context) error {
 
 // Download 下载文件内容到writer
 func (p *cloudDrive2Provider) Download(ctx context.Context, remotePath string, w io.Writer) error {
+	return p.DownloadRange(ctx, remotePath, 0, w)
+}
+
+// DownloadRange 从 offset 处下载文件内容到writer（实现 filesystem.RangeProvider）
+//
+// 服务端忽略 Range 返回完整内容时，丢弃前 offset 字节后再写入。
+func (p *cloudDrive2Provider) DownloadRange(ctx context.Context, remotePath string, offset int64, w io.Writer) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -116,10 +178,10 @@ func (p *cloudDrive2Provider) Download(ctx context.Context, remotePath string, w
 	}
 
 	cleanPath := filesystem.CleanRemotePath(remotePath)
-	p.logger.Debug("CloudDrive2 Download", zap.String("path", cleanPath))
+	p.logger.Debug("CloudDrive2 Download", zap.String("path", cleanPath), zap.Int64("offset", offset))
 
 	// 调用gRPC获取下载URL信息
-	info, err := p.client.GetDownloadUrlPath(ctx, cleanPath, false, true, true)
+	info, err := p.urls.GetDownloadUrlPath(ctx, cleanPath, false, true, true)
 	if err != nil {
 		return fmt.Errorf("clouddrive2: get download url path failed: %w", err)
 	}
@@ -128,14 +190,7 @@ func (p *cloudDrive2Provider) Download(ctx context.Context, remotePath string, w
 	downloadURL := info.GetDirectUrl()
 	if downloadURL == "" {
 		// 使用downloadUrlPath模板构建URL
-		downloadPath := info.GetDownloadUrlPath()
-		replacer := strings.NewReplacer(
-			"{SCHEME}", p.baseURL.Scheme,
-			"{HOST}", p.baseURL.Host,
-			"{PREVIEW}", "0",
-		)
-		downloadPath = replacer.Replace(downloadPath)
-		downloadURL = strings.TrimRight(p.baseURL.String(), "/") + downloadPath
+		downloadURL = p.proxyURL(info.GetDownloadUrlPath())
 	}
 
 	p.logger.Debug("CloudDrive2 Download URL", zap.String("url", downloadURL))
@@ -153,6 +208,9 @@ func (p *cloudDrive2Provider) Download(ctx context.Context, remotePath string, w
 	for k, v := range info.GetAdditionalHeaders() {
 		req.Header.Set(k, v)
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	// 执行下载
 	resp, err := p.httpClient.Do(req)
@@ -165,6 +223,12 @@ func (p *cloudDrive2Provider) Download(ctx context.Context, remotePath string, w
 	if resp.StatusCode/100 != 2 {
 		return fmt.Errorf("clouddrive2: download status %d", resp.StatusCode)
 	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// 服务端不支持 Range：跳过已下载部分
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			return fmt.Errorf("clouddrive2: skip downloaded bytes: %w", err)
+		}
+	}
 
 	// 将响应写入writer
 	if _, err := io.Copy(w, resp.Body); err != nil {
@@ -227,11 +291,13 @@ func (p *cloudDrive2Provider) Stat(ctx context.Context, targetPath string) (file
 	modTime := parseProtoTimestamp(info.WriteTime)
 
 	result := filesystem.RemoteFile{
-		Path:    fullPath,
-		Name:    info.Name,
-		Size:    info.Size,
-		ModTime: modTime,
-		IsDir:   info.IsDirectory,
+		Path:     fullPath,
+		Name:     info.Name,
+		Size:     info.Size,
+		ModTime:  modTime,
+		IsDir:    info.IsDirectory,
+		Hash:     fileHash(info.GetFileHashes()),
+		PickCode: pickCode(info),
 	}
 
 	p.logger.Debug("CloudDrive2 Stat 完成",
@@ -245,114 +311,137 @@ func (p *cloudDrive2Provider) Stat(ctx context.Context, targetPath string) (file
 // BuildStrmInfo 构建结构化的 STRM 信息
 //
 // 实现说明：
-// - 生成基于 HTTP 的流媒体 URL
-// - CloudDrive2 使用 HTTP 协议直接访问文件
-// - scheme 默认使用 http（CloudDrive2 通常不使用 https）
-// - 返回的 StrmInfo 包含 RawURL、BaseURL 和 Path 字段
+// - 通过 GetDownloadUrlPath RPC 获取下载链接
+// - proxy 模式：使用 CloudDrive2 中转的 /static/... 地址（基于配置的 baseURL），长期有效
+// - direct 模式：使用云盘直链并按 expiresIn 填充 ExpiresAt
+// - 云盘不提供直链，或直链需要额外请求头（播放器无法携带）时回退为 proxy 地址
+// - 结果按路径缓存（同一服务器的多次同步共享），文件未变化且链接未到刷新时间时不再调用 RPC
 //
 // 参数：
-//   - ctx: 上下文（当前未使用，保留用于未来扩展）
+//   - ctx: 上下文
 //   - req: BuildStrmRequest 包含 ServerID、RemotePath 和可选的 RemoteMeta
 //
 // 返回：
 //   - StrmInfo: 结构化的 STRM 元数据
-//   - error: 输入无效或构建失败时返回错误
+//   - error: 输入无效或获取链接失败时返回错误
 func (p *cloudDrive2Provider) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmRequest) (syncengine.StrmInfo, error) {
-	_ = ctx // 保留用于未来的取消或追踪
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
 	// 验证输入
 	if strings.TrimSpace(req.RemotePath) == "" {
 		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: remote path 不能为空: %w", syncengine.ErrInvalidInput)
 	}
+	if p.baseURL == nil {
+		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: baseURL 未配置")
+	}
 
 	cleanPath := filesystem.CleanRemotePath(req.RemotePath)
-	host := strings.TrimSpace(p.client.Target())
-	if host == "" {
-		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: 主机地址为空")
+	if info, ok := p.links.get(cleanPath, req.RemoteMeta, time.Now()); ok {
+		return info, nil
 	}
 
-	// CloudDrive2 默认使用 http（gRPC 使用 h2c，HTTP API 也通常是 http）
-	scheme := "http"
+	direct := p.linkMode == filesystem.LinkModeDirect
+	result, err := p.urls.GetDownloadUrlPath(ctx, cleanPath, false, true, direct)
+	if err != nil {
+		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: 获取 %s 下载链接失败: %w", cleanPath, err)
+	}
 
-	// 构建 URL
-	rawURL := fmt.Sprintf("%s://%s%s", scheme, host, cleanPath)
+	now := time.Now()
+	info, err := p.strmInfoFromDownloadURL(cleanPath, result, direct, now)
+	if err != nil {
+		return syncengine.StrmInfo{}, err
+	}
+	p.links.put(cleanPath, req.RemoteMeta, info, now)
 
 	p.logger.Debug("CloudDrive2 BuildStrmInfo",
 		zap.String("remote_file_path", cleanPath),
-		zap.String("scheme", scheme),
-		zap.String("raw_url", rawURL))
+		zap.String("link_mode", p.linkMode.String()),
+		zap.String("raw_url", info.RawURL),
+		zap.Time("expires_at", info.ExpiresAt))
 
-	return syncengine.StrmInfo{
-		RawURL:  rawURL,
-		BaseURL: &url.URL{Scheme: scheme, Host: host},
-		Path:    cleanPath,
-	}, nil
+	return info, nil
 }
 
-// listCloudDrive2 递归列出CloudDrive2目录（使用BFS，支持深度限制）
-func (p *cloudDrive2Provider) listCloudDrive2(ctx context.Context, root string, recursive bool, maxDepth int) ([]filesystem.RemoteFile, error) {
-	var results []filesystem.RemoteFile
-
-	// 使用BFS队列遍历目录树，队列中存储路径和当前深度
-	type queueItem struct {
-		path  string
-		depth int
-	}
-	queue := []queueItem{{path: filesystem.CleanRemotePath(root), depth: 0}}
+// FinishRun 实现 filesystem.RunFinisher：同步结束时清理链接缓存中过期与闲置的条目
+func (p *cloudDrive2Provider) FinishRun() {
+	p.links.sweep(time.Now())
+}
 
-	for len(queue) > 0 {
-		// 检查context取消
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+// strmInfoFromDownloadURL 将 GetDownloadUrlPath 结果转换为 StrmInfo
+func (p *cloudDrive2Provider) strmInfoFromDownloadURL(cleanPath string, result *pb.DownloadUrlPathInfo, direct bool, now time.Time) (syncengine.StrmInfo, error) {
+	var rawURL string
+	var expiresAt time.Time
+	if direct {
+		directURL := strings.TrimSpace(result.GetDirectUrl())
+		switch {
+		case directURL == "":
+			p.logger.Debug("CloudDrive2 未提供直链，使用代理链接", zap.String("remote_file_path", cleanPath))
+		case result.GetUserAgent() != "" || len(result.GetAdditionalHeaders()) > 0:
+			p.logger.Debug("CloudDrive2 直链需要额外请求头，使用代理链接", zap.String("remote_file_path", cleanPath))
+		default:
+			rawURL = directURL
+			if result.ExpiresIn != nil {
+				expiresAt = now.Add(time.Duration(result.GetExpiresIn()) * time.Second)
+			}
 		}
-
-		// 取出队头目录
-		item := queue[0]
-		dir := item.path
-		queue = queue[1:]
-
-		// 调用GetSubFiles列出当前目录
-		files, err := p.client.GetSubFiles(ctx, dir, false)
-		if err != nil {
-			return nil, fmt.Errorf("list directory %s: %w", dir, err)
+	}
+	if rawURL == "" {
+		if strings.TrimSpace(result.GetDownloadUrlPath()) == "" {
+			return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: %s 未返回下载链接", cleanPath)
 		}
+		rawURL = p.proxyURL(result.GetDownloadUrlPath())
+	}
 
-		// 处理每个项目
-		for _, file := range files {
-			if file == nil {
-				continue
-			}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: 解析下载链接失败: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return syncengine.StrmInfo{}, fmt.Errorf("clouddrive2: 下载链接无效: %s", rawURL)
+	}
 
-			fullPath := filesystem.JoinRemotePath(dir, file.Name)
-			modTime := parseProtoTimestamp(file.WriteTime)
+	return syncengine.StrmInfo{
+		RawURL:    rawURL,
+		BaseURL:   &url.URL{Scheme: parsed.Scheme, Host: parsed.Host},
+		Path:      parsed.Path,
+		ExpiresAt: expiresAt,
+	}, nil
+}
 
-			// 转换为RemoteFile
-			remoteFile := filesystem.RemoteFile{
-				Path:    fullPath,
-				Name:    file.Name,
-				Size:    file.Size,
-				ModTime: modTime,
-				IsDir:   file.IsDirectory,
-			}
+// proxyURL 使用 downloadUrlPath 模板构建 CloudDrive2 中转地址
+func (p *cloudDrive2Provider) proxyURL(downloadPath string) string {
+	replacer := strings.NewReplacer(
+		"{SCHEME}", p.baseURL.Scheme,
+		"{HOST}", p.baseURL.Host,
+		"{PREVIEW}", "false",
+	)
+	return strings.TrimRight(p.baseURL.String(), "/") + replacer.Replace(downloadPath)
+}
 
-			// 将所有项目（文件和目录）加入结果
-			results = append(results, remoteFile)
+// listDir 列出单个CloudDrive2目录（非递归）
+func (p *cloudDrive2Provider) listDir(ctx context.Context, dir string) ([]filesystem.RemoteFile, error) {
+	files, err := p.client.GetSubFiles(ctx, dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("list directory %s: %w", dir, err)
+	}
 
-			// 递归模式：将子目录加入队列（深度控制）
-			// 只有当子目录的内容深度(item.depth+2)不超过maxDepth时才入队
-			// 即：item.depth + 1 < maxDepth
-			if file.IsDirectory && recursive && item.depth+1 < maxDepth {
-				queue = append(queue, queueItem{path: fullPath, depth: item.depth + 1})
-			}
+	results := make([]filesystem.RemoteFile, 0, len(files))
+	for _, file := range files {
+		if file == nil {
+			continue
 		}
+		results = append(results, filesystem.RemoteFile{
+			Path:     filesystem.JoinRemotePath(dir, file.Name),
+			Name:     file.Name,
+			Size:     file.Size,
+			ModTime:  parseProtoTimestamp(file.WriteTime),
+			IsDir:    file.IsDirectory,
+			Hash:     fileHash(file.GetFileHashes()),
+			PickCode: pickCode(file),
+		})
 	}
-
-	p.logger.Info("CloudDrive2目录列出完成",
-		zap.String("root", root),
-		zap.Bool("recursive", recursive),
-		zap.Int("max_depth", maxDepth),
-		zap.Int("count", len(results)))
-
 	return results, nil
 }
 
@@ -364,6 +453,41 @@ func parseProtoTimestamp(ts *timestamppb.Timestamp) time.Time {
 	return ts.AsTime()
 }
 
+// fileHash 从 CloudDrive2 返回的哈希表中选取内容哈希
+//
+// 按 SHA1 > MD5 > PikPakSha1 的优先级选取，返回 "算法:十六进制值"（统一小写），
+// 云盘未提供哈希时返回空字符串。
+func fileHash(hashes map[uint32]string) string {
+	candidates := []struct {
+		kind pb.CloudDriveFile_HashType
+		name string
+	}{
+		{pb.CloudDriveFile_Sha1, "sha1"},
+		{pb.CloudDriveFile_Md5, "md5"},
+		{pb.CloudDriveFile_PikPakSha1, "pikpaksha1"},
+	}
+	for _, c := range candidates {
+		if value := strings.TrimSpace(hashes[uint32(c.kind)]); value != "" {
+			return c.name + ":" + strings.ToLower(value)
+		}
+	}
+	return ""
+}
+
+// pickCode 从 CloudDrive2 文件 ID 中识别 115 PickCode
+//
+// CloudDrive2 以云盘文件 ID 作为 CloudDriveFile.Id，115 云盘的 ID 即 PickCode；
+// 明确属于其他云盘（CloudAPI 名称不含 115）的文件不识别。
+func pickCode(file *pb.CloudDriveFile) string {
+	if file.IsDirectory || !filesystem.IsPickCode(file.Id) {
+		return ""
+	}
+	if api := file.GetCloudAPI(); api != nil && api.Name != "" && !strings.Contains(api.Name, "115") {
+		return ""
+	}
+	return file.Id
+}
+
 func init() {
 	filesystem.RegisterProvider(filesystem.TypeCloudDrive2, func(c *filesystem.ClientImpl) (filesystem.Provider, error) {
 		return NewCloudDrive2Provider(c)
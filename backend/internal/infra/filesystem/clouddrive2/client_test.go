@@ -0,0 +1,163 @@
+package filesystem
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+	"github.com/strmsync/strmsync/internal/infra/filesystem"
+	pb "github.com/strmsync/strmsync/internal/pkg/sdk/clouddrive2/proto"
+	"go.uber.org/zap"
+)
+
+// fakeDownloadURLs 记录调用的 GetDownloadUrlPath 桩
+type fakeDownloadURLs struct {
+	info   *pb.DownloadUrlPathInfo
+	calls  int
+	direct bool
+}
+
+func (f *fakeDownloadURLs) GetDownloadUrlPath(_ context.Context, _ string, _, _, getDirect bool) (*pb.DownloadUrlPathInfo, error) {
+	f.calls++
+	f.direct = getDirect
+	return f.info, nil
+}
+
+func newTestProvider(t *testing.T, mode filesystem.LinkMode, urls downloadURLClient) *cloudDrive2Provider {
+	t.Helper()
+	baseURL, err := url.Parse("http://cd2.local:19798")
+	if err != nil {
+		t.Fatalf("parse base url: %v", err)
+	}
+	return &cloudDrive2Provider{
+		logger:   zap.NewNop(),
+		urls:     urls,
+		baseURL:  baseURL,
+		linkMode: mode,
+		links:    &linkCache{entries: make(map[string]linkCacheEntry)},
+	}
+}
+
+func strPtr(v string) *string { return &v }
+
+func uint64Ptr(v uint64) *uint64 { return &v }
+
+func TestBuildStrmInfo_ProxyLinkCached(t *testing.T) {
+	fake := &fakeDownloadURLs{info: &pb.DownloadUrlPathInfo{
+		DownloadUrlPath: "/static/{SCHEME}/{HOST}/{PREVIEW}/Movies/a.mkv?token=abc",
+	}}
+	p := newTestProvider(t, filesystem.LinkModeProxy, fake)
+	ctx := context.Background()
+	meta := &syncengine.RemoteEntry{Path: "/Movies/a.mkv", Size: 100, ModTime: time.Unix(1700000000, 0)}
+	req := syncengine.BuildStrmRequest{RemotePath: "/Movies/a.mkv", RemoteMeta: meta}
+
+	info, err := p.BuildStrmInfo(ctx, req)
+	if err != nil {
+		t.Fatalf("build strm info: %v", err)
+	}
+	want := "http://cd2.local:19798/static/http/cd2.local:19798/false/Movies/a.mkv?token=abc"
+	if info.RawURL != want || fake.direct || !info.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected proxy info: %+v (direct=%v)", info, fake.direct)
+	}
+	if info.BaseURL.Host != "cd2.local:19798" || info.Path != "/static/http/cd2.local:19798/false/Movies/a.mkv" {
+		t.Fatalf("unexpected parsed fields: base=%v path=%s", info.BaseURL, info.Path)
+	}
+
+	// 文件未变化时复用缓存
+	if _, err := p.BuildStrmInfo(ctx, req); err != nil || fake.calls != 1 {
+		t.Fatalf("expected cached link, calls=%d err=%v", fake.calls, err)
+	}
+
+	// 文件变化后重新获取
+	changed := *meta
+	changed.ModTime = meta.ModTime.Add(time.Hour)
+	req.RemoteMeta = &changed
+	if _, err := p.BuildStrmInfo(ctx, req); err != nil || fake.calls != 2 {
+		t.Fatalf("expected refetch after change, calls=%d err=%v", fake.calls, err)
+	}
+}
+
+func TestBuildStrmInfo_DirectLink(t *testing.T) {
+	fake := &fakeDownloadURLs{info: &pb.DownloadUrlPathInfo{
+		DownloadUrlPath: "/static/{SCHEME}/{HOST}/{PREVIEW}/Movies/a.mkv",
+		DirectUrl:       strPtr("https://cdn.example.com/a.mkv?sig=1"),
+		ExpiresIn:       uint64Ptr(3600),
+	}}
+	p := newTestProvider(t, filesystem.LinkModeDirect, fake)
+
+	before := time.Now()
+	info, err := p.BuildStrmInfo(context.Background(), syncengine.BuildStrmRequest{RemotePath: "/Movies/a.mkv"})
+	if err != nil {
+		t.Fatalf("build strm info: %v", err)
+	}
+	if !fake.direct || info.RawURL != "https://cdn.example.com/a.mkv?sig=1" {
+		t.Fatalf("expected direct link, got %+v", info)
+	}
+	if info.ExpiresAt.Before(before.Add(time.Hour)) || info.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("unexpected expires_at: %v", info.ExpiresAt)
+	}
+
+	// 直链需要额外请求头时回退为代理链接
+	fake.info.UserAgent = strPtr("pan-client")
+	info, err = p.BuildStrmInfo(context.Background(), syncengine.BuildStrmRequest{RemotePath: "/Movies/b.mkv"})
+	if err != nil {
+		t.Fatalf("build strm info: %v", err)
+	}
+	if info.RawURL != "http://cd2.local:19798/static/http/cd2.local:19798/false/Movies/a.mkv" || !info.ExpiresAt.IsZero() {
+		t.Fatalf("expected proxy fallback, got %+v", info)
+	}
+}
+
+func TestLinkCache_RefreshBeforeExpiry(t *testing.T) {
+	cache := &linkCache{entries: make(map[string]linkCacheEntry)}
+	now := time.Now()
+	cache.put("/a.mkv", nil, syncengine.StrmInfo{RawURL: "https://cdn/a", ExpiresAt: now.Add(4 * time.Hour)}, now)
+
+	if _, ok := cache.get("/a.mkv", nil, now.Add(2*time.Hour)); !ok {
+		t.Fatal("expected link to be reused before refresh time")
+	}
+	if _, ok := cache.get("/a.mkv", nil, now.Add(3*time.Hour+time.Minute)); ok {
+		t.Fatal("expected link to be refreshed in the last quarter of its lifetime")
+	}
+}
+
+func TestLinkCache_SweepAtRunEnd(t *testing.T) {
+	cache := &linkCache{entries: make(map[string]linkCacheEntry)}
+	now := time.Now()
+	cache.put("/used.mkv", nil, syncengine.StrmInfo{RawURL: "http://cd2/static/used"}, now)
+	cache.put("/idle.mkv", nil, syncengine.StrmInfo{RawURL: "http://cd2/static/idle"}, now)
+	cache.put("/expiring.mkv", nil, syncengine.StrmInfo{RawURL: "https://cdn/expiring", ExpiresAt: now.Add(4 * time.Hour)}, now)
+
+	// 命中会刷新使用时间
+	later := now.Add(linkCacheIdleTTL)
+	if _, ok := cache.get("/used.mkv", nil, later); !ok {
+		t.Fatal("expected cached link")
+	}
+	cache.sweep(later.Add(time.Hour))
+	if _, ok := cache.entries["/used.mkv"]; !ok {
+		t.Fatal("expected recently used link to be kept")
+	}
+	if _, ok := cache.entries["/idle.mkv"]; ok {
+		t.Fatal("expected idle link to be evicted")
+	}
+	if _, ok := cache.entries["/expiring.mkv"]; ok {
+		t.Fatal("expected link past its refresh time to be evicted")
+	}
+}
+
+func TestPickCode(t *testing.T) {
+	file := &pb.CloudDriveFile{Id: "ecjq9ichcb40lzlvx", Name: "a.mkv"}
+	if got := pickCode(file); got != "ecjq9ichcb40lzlvx" {
+		t.Fatalf("expected pickcode, got %q", got)
+	}
+	file.CloudAPI = &pb.CloudAPI{Name: "Aliyundrive"}
+	if got := pickCode(file); got != "" {
+		t.Fatalf("expected no pickcode for other clouds, got %q", got)
+	}
+	dir := &pb.CloudDriveFile{Id: "ecjq9ichcb40lzlvx", IsDirectory: true}
+	if got := pickCode(dir); got != "" {
+		t.Fatalf("expected no pickcode for directories, got %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package filesystem
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+	"github.com/strmsync/strmsync/internal/infra/filesystem"
+)
+
+// maxLinkCacheEntries 单个服务器缓存的 STRM 链接上限
+//
+// 超过上限时先清理已到刷新时间或长期未使用的条目，仍然超出则整体清空（下次同步重新获取）。
+const maxLinkCacheEntries = 200000
+
+// linkCacheIdleTTL 缓存条目的最长闲置时间
+//
+// 每次同步结束时清理超过该时间未被使用的条目（远端已删除或已不属于任何任务的文件）。
+const linkCacheIdleTTL = 7 * 24 * time.Hour
+
+// linkCacheEntry 缓存的 STRM 链接
+type linkCacheEntry struct {
+	info    syncengine.StrmInfo
+	size    int64
+	modTime time.Time
+	// refreshAt 链接需要刷新的时间（零值表示不过期）
+	refreshAt time.Time
+	// usedAt 最近一次写入或命中的时间
+	usedAt time.Time
+}
+
+// linkCache 按路径缓存 GetDownloadUrlPath 的结果
+//
+// 同一服务器的多次同步共享缓存：文件未变化且链接未到刷新时间时直接复用，
+// 避免未变化的目录树每次同步都对每个文件调用一次 RPC，也避免直链每次同步都被改写。
+// 缓存只在内存中：服务重启后为空，首次同步重新获取全部链接（direct 模式会改写全部直链 STRM）。
+// 条目数受 maxLinkCacheEntries 限制，每次同步结束时清理过期与闲置的条目（见 sweep）。
+type linkCache struct {
+	mu      sync.Mutex
+	entries map[string]linkCacheEntry
+}
+
+var (
+	linkCachesMu sync.Mutex
+	linkCaches   = map[string]*linkCache{}
+)
+
+// serverLinkCache 返回指定服务器与链接类型共享的缓存
+func serverLinkCache(config filesystem.Config, mode filesystem.LinkMode) *linkCache {
+	key := strings.Join([]string{
+		strings.TrimRight(strings.TrimSpace(config.BaseURL), "/"),
+		mode.String(),
+	}, "|")
+
+	linkCachesMu.Lock()
+	defer linkCachesMu.Unlock()
+	if existing, ok := linkCaches[key]; ok {
+		return existing
+	}
+	cache := &linkCache{entries: make(map[string]linkCacheEntry)}
+	linkCaches[key] = cache
+	return cache
+}
+
+// get 返回仍然有效的缓存链接
+//
+// meta 非空时要求大小和修改时间一致，文件变化后重新获取。
+func (c *linkCache) get(remotePath string, meta *syncengine.RemoteEntry, now time.Time) (syncengine.StrmInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[remotePath]
+	if !ok {
+		return syncengine.StrmInfo{}, false
+	}
+	if meta != nil && (entry.size != meta.Size || !entry.modTime.Equal(meta.ModTime)) {
+		delete(c.entries, remotePath)
+		return syncengine.StrmInfo{}, false
+	}
+	if !entry.refreshAt.IsZero() && !now.Before(entry.refreshAt) {
+		delete(c.entries, remotePath)
+		return syncengine.StrmInfo{}, false
+	}
+	entry.usedAt = now
+	c.entries[remotePath] = entry
+	return entry.info, true
+}
+
+// put 缓存链接
+//
+// 带过期时间的链接在剩余 1/4 有效期时刷新，给播放留出余量。
+func (c *linkCache) put(remotePath string, meta *syncengine.RemoteEntry, info syncengine.StrmInfo, now time.Time) {
+	entry := linkCacheEntry{info: info, usedAt: now}
+	if meta != nil {
+		entry.size = meta.Size
+		entry.modTime = meta.ModTime
+	}
+	if !info.ExpiresAt.IsZero() {
+		if !info.ExpiresAt.After(now) {
+			return
+		}
+		entry.refreshAt = now.Add(info.ExpiresAt.Sub(now) * 3 / 4)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxLinkCacheEntries {
+		c.sweepLocked(now)
+		if len(c.entries) >= maxLinkCacheEntries {
+			c.entries = make(map[string]linkCacheEntry)
+		}
+	}
+	c.entries[remotePath] = entry
+}
+
+// sweep 清理已到刷新时间或闲置超过 linkCacheIdleTTL 的条目（同步结束时调用）
+func (c *linkCache) sweep(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweepLocked(now)
+}
+
+func (c *linkCache) sweepLocked(now time.Time) {
+	for key, entry := range c.entries {
+		expired := !entry.refreshAt.IsZero() && !now.Before(entry.refreshAt)
+		if expired || now.Sub(entry.usedAt) > linkCacheIdleTTL {
+			delete(c.entries, key)
+		}
+	}
+}
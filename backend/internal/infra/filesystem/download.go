@@ -0,0 +1,198 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDownloadConcurrency 默认每个服务器的并发下载数
+	defaultDownloadConcurrency = 4
+	// maxDownloadConcurrency 每个服务器并发下载数上限
+	maxDownloadConcurrency = 32
+	// rateChunkSize 限速写入时每次申请的最大字节数
+	rateChunkSize = 32 * 1024
+)
+
+// DownloadLimiter 下载限制器
+//
+// 同一数据服务器（类型 + 地址）的所有客户端共享一个限制器，包含：
+// - 并发下载数上限
+// - 每秒开始的下载数（DownloadRatePerSec）
+// - 下载带宽（字节/秒令牌桶）
+type DownloadLimiter struct {
+	semaphore
+	files *tokenBucket // 速率为 0 表示不限制
+	bytes *tokenBucket // 速率为 0 表示不限制
+}
+
+// NewDownloadLimiter 创建下载限制器
+//
+// 参数：
+//   - concurrency: 并发下载数（<=0 时使用默认值）
+//   - filesPerSec: 每秒开始的下载数（<=0 表示不限制）
+//   - bytesPerSec: 下载带宽（字节/秒，<=0 表示不限制）
+func NewDownloadLimiter(concurrency, filesPerSec int, bytesPerSec int64) *DownloadLimiter {
+	l := &DownloadLimiter{
+		semaphore: newSemaphore(downloadConcurrency(concurrency)),
+		files:     &tokenBucket{},
+		bytes:     &tokenBucket{},
+	}
+	l.resize(concurrency, filesPerSec, bytesPerSec)
+	return l
+}
+
+// downloadConcurrency 规范化并发下载数
+func downloadConcurrency(n int) int {
+	if n <= 0 {
+		n = defaultDownloadConcurrency
+	}
+	if n > maxDownloadConcurrency {
+		n = maxDownloadConcurrency
+	}
+	return n
+}
+
+// resize 调整限制（参数含义同 NewDownloadLimiter）
+//
+// 速率与带宽限制对进行中的下载立即生效；并发数调整规则见 semaphore。
+func (l *DownloadLimiter) resize(concurrency, filesPerSec int, bytesPerSec int64) {
+	l.semaphore.resize(downloadConcurrency(concurrency))
+	l.files.setRate(float64(filesPerSec), float64(filesPerSec))
+	// 突发容量至少一个写入块，避免单次申请超过桶容量
+	burst := float64(bytesPerSec)
+	if burst < rateChunkSize {
+		burst = rateChunkSize
+	}
+	l.bytes.setRate(float64(bytesPerSec), burst)
+}
+
+// Acquire 获取一个下载槽位（可被 context 取消），受每秒下载数限制
+func (l *DownloadLimiter) Acquire(ctx context.Context) error {
+	if err := l.semaphore.Acquire(ctx); err != nil {
+		return err
+	}
+	if err := l.files.wait(ctx, 1); err != nil {
+		l.Release()
+		return err
+	}
+	return nil
+}
+
+// Writer 返回受带宽限制的 writer（带宽限制调整后对已返回的 writer 同样生效）
+func (l *DownloadLimiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &rateWriter{ctx: ctx, w: w, bucket: l.bytes}
+}
+
+// downloadLimiters 按服务器共享的下载限制器
+var downloadLimiters limiterRegistry[*DownloadLimiter]
+
+// serverDownloadLimiter 返回指定服务器共享的下载限制器（限制配置变化时原地调整）
+func serverDownloadLimiter(config Config) *DownloadLimiter {
+	return downloadLimiters.get(config, func() *DownloadLimiter {
+		return NewDownloadLimiter(config.DownloadConcurrency, config.DownloadRatePerSec, config.DownloadBytesPerSec)
+	}, func(l *DownloadLimiter) {
+		l.resize(config.DownloadConcurrency, config.DownloadRatePerSec, config.DownloadBytesPerSec)
+	})
+}
+
+// tokenBucket 令牌桶
+//
+// 令牌允许透支：并发申请者按申请顺序依次等待，总速率不超过 rate。
+// rate <= 0 表示不限制。
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶容量
+	tokens float64
+	last   time.Time
+}
+
+// setRate 调整速率与桶容量（rate <= 0 表示不限制）
+//
+// 从不限制切换为限制时桶为满；其余情况保留已累积的令牌（不超过新容量）与透支。
+func (b *tokenBucket) setRate(rate, burst float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rate <= 0 {
+		b.rate = 0
+		return
+	}
+	if b.rate <= 0 {
+		b.tokens = burst
+	} else {
+		b.refill(time.Now())
+	}
+	b.rate = rate
+	b.burst = burst
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = time.Now()
+}
+
+// refill 按经过的时间补充令牌（调用方持有 mu）
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// wait 申请 n 个令牌，令牌不足时等待（可被 context 取消）
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	if b.rate <= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateWriter 按令牌桶限速的 writer
+type rateWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	bucket *tokenBucket
+}
+
+func (r *rateWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > rateChunkSize {
+			chunk = chunk[:rateChunkSize]
+		}
+		if err := r.bucket.wait(r.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := r.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
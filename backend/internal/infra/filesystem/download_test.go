@@ -0,0 +1,92 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDownloadLimiter_BoundsConcurrency(t *testing.T) {
+	l := NewDownloadLimiter(1, 0, 0)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx); err == nil {
+		t.Fatalf("expected second acquire to block until timeout")
+	}
+	l.Release()
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("expected acquire after release: %v", err)
+	}
+}
+
+func TestDownloadLimiter_ThrottlesBandwidth(t *testing.T) {
+	// 桶容量 64KiB：前 64KiB 立即写入，剩余 32KiB 约需 0.5 秒
+	l := NewDownloadLimiter(0, 0, 64*1024)
+	var buf bytes.Buffer
+	start := time.Now()
+	n, err := l.Writer(context.Background(), &buf).Write(make([]byte, 96*1024))
+	if err != nil || n != 96*1024 {
+		t.Fatalf("unexpected write n=%d err=%v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected throttled write, took %v", elapsed)
+	}
+
+	// 取消 context 时立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Writer(ctx, &buf).Write(make([]byte, 128*1024)); err == nil {
+		t.Fatalf("expected cancelled write to fail")
+	}
+}
+
+func TestServerDownloadLimiter_SharedPerServer(t *testing.T) {
+	cfg := Config{Type: TypeOpenList, BaseURL: "http://shared-download:5244", DownloadConcurrency: 2}
+	a := serverDownloadLimiter(cfg)
+	if b := serverDownloadLimiter(cfg); a != b {
+		t.Fatalf("expected limiter shared by the same server")
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := a.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tryAcquire := func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return a.Acquire(ctx) == nil
+	}
+
+	// 限制变化时原地调整：进行中的下载仍计入新的并发上限
+	cfg.DownloadConcurrency = 1
+	cfg.DownloadBytesPerSec = 64 * 1024
+	if c := serverDownloadLimiter(cfg); c != a || a.Cap() != 1 {
+		t.Fatalf("expected limiter adjusted in place, cap=%d", a.Cap())
+	}
+	a.Release()
+	if tryAcquire() {
+		t.Fatalf("expected acquire to wait while in-flight downloads exceed the new limit")
+	}
+	a.Release()
+	if !tryAcquire() {
+		t.Fatalf("expected acquire once in-flight downloads drop below the new limit")
+	}
+
+	// 调整前取得的 writer 同样受新的带宽限制
+	var buf bytes.Buffer
+	w := a.Writer(ctx, &buf)
+	cfg.DownloadBytesPerSec = 32 * 1024
+	serverDownloadLimiter(cfg)
+	start := time.Now()
+	if _, err := w.Write(make([]byte, 64*1024)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("expected write throttled by the adjusted bandwidth, took %v", elapsed)
+	}
+}
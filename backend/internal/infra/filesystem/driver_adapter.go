@@ -63,11 +63,13 @@ func (a *Adapter) Type() syncengine.DriverType {
 //
 // CloudDrive2:
 //   - StrmHTTP: true（支持HTTP流媒体）
-//   - Watch, PickCode, SignURL: false（当前未实现）
+//   - PickCode: true（115 云盘文件提供 PickCode）
+//   - Watch, SignURL: false（当前未实现）
 //
 // OpenList:
 //   - StrmHTTP: true
-//   - Watch, PickCode, SignURL: false
+//   - PickCode: true（以 PickCode 作为对象 ID 的 115 存储）
+//   - Watch, SignURL: false
 //
 // Local:
 //   - StrmMount: true（使用本地挂载路径）
@@ -79,7 +81,7 @@ func (a *Adapter) Capabilities() syncengine.DriverCapability {
 			Watch:     false, // filesystem CloudDrive2 provider 当前不支持 Watch
 			StrmHTTP:  true,
 			StrmMount: false,
-			PickCode:  false, // 未来可扩展支持
+			PickCode:  true,
 			SignURL:   false, // 未来可扩展支持
 		}
 	case syncengine.DriverOpenList:
@@ -87,7 +89,7 @@ func (a *Adapter) Capabilities() syncengine.DriverCapability {
 			Watch:     false,
 			StrmHTTP:  true,
 			StrmMount: false,
-			PickCode:  false,
+			PickCode:  true,
 			SignURL:   false,
 		}
 	case syncengine.DriverLocal:
@@ -127,16 +129,51 @@ func (a *Adapter) List(ctx context.Context, listPath string, opt syncengine.List
 	entries := make([]syncengine.RemoteEntry, 0, len(files))
 	for _, f := range files {
 		entries = append(entries, syncengine.RemoteEntry{
-			Path:    f.Path,
-			Name:    f.Name,
-			Size:    f.Size,
-			ModTime: f.ModTime,
-			IsDir:   f.IsDir,
+			Path:     f.Path,
+			Name:     f.Name,
+			Size:     f.Size,
+			ModTime:  f.ModTime,
+			IsDir:    f.IsDir,
+			Hash:     f.Hash,
+			PickCode: f.PickCode,
 		})
 	}
 	return entries, nil
 }
 
+// ListStream 使用 filesystem.Client 流式列出远程文件
+//
+// 实现说明：
+// - 调用 filesystem.Client.ListStream，边遍历边回调
+// - 将 filesystem.RemoteFile 转换为 syncengine.RemoteEntry
+// - 回调顺序与 syncengine.ComparePaths 一致
+func (a *Adapter) ListStream(ctx context.Context, listPath string, opt syncengine.ListOptions, fn func(syncengine.RemoteEntry) error) error {
+	normalizedPath := listPath
+	if a.typ == syncengine.DriverLocal {
+		var err error
+		normalizedPath, err = normalizeLocalListPath(a.client, listPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := a.client.ListStream(ctx, normalizedPath, opt.Recursive, opt.MaxDepth, func(f RemoteFile) error {
+		return fn(syncengine.RemoteEntry{
+			Path:     f.Path,
+			Name:     f.Name,
+			Size:     f.Size,
+			ModTime:  f.ModTime,
+			IsDir:    f.IsDir,
+			Hash:     f.Hash,
+			PickCode: f.PickCode,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("filesystem: 流式列出 %s 失败: %w", listPath, err)
+	}
+	return nil
+}
+
 // Watch 订阅远程文件变更事件（如果支持）
 //
 // 实现说明：
@@ -201,11 +238,13 @@ func (a *Adapter) Stat(ctx context.Context, targetPath string) (syncengine.Remot
 			return syncengine.RemoteEntry{}, fmt.Errorf("filesystem: stat %s 失败: %w", targetPath, err)
 		}
 		return syncengine.RemoteEntry{
-			Path:    file.Path,
-			Name:    file.Name,
-			Size:    file.Size,
-			ModTime: file.ModTime,
-			IsDir:   file.IsDir,
+			Path:     file.Path,
+			Name:     file.Name,
+			Size:     file.Size,
+			ModTime:  file.ModTime,
+			IsDir:    file.IsDir,
+			Hash:     file.Hash,
+			PickCode: file.PickCode,
 		}, nil
 	}
 
@@ -224,11 +263,13 @@ func (a *Adapter) Stat(ctx context.Context, targetPath string) (syncengine.Remot
 	for _, file := range files {
 		if file.Name == baseName || file.Path == targetPath {
 			return syncengine.RemoteEntry{
-				Path:    file.Path,
-				Name:    file.Name,
-				Size:    file.Size,
-				ModTime: file.ModTime,
-				IsDir:   file.IsDir,
+				Path:     file.Path,
+				Name:     file.Name,
+				Size:     file.Size,
+				ModTime:  file.ModTime,
+				IsDir:    file.IsDir,
+				Hash:     file.Hash,
+				PickCode: file.PickCode,
 			}, nil
 		}
 	}
@@ -288,6 +329,13 @@ func (a *Adapter) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmReq
 	return info, nil
 }
 
+// FinishRun 实现 RunFinisher（转发给底层 Client）
+func (a *Adapter) FinishRun() {
+	if finisher, ok := a.client.(RunFinisher); ok {
+		finisher.FinishRun()
+	}
+}
+
 // normalizeLocalListPath 兼容本地驱动的绝对路径输入
 // - 若 listPath 位于 mount_path 之下，则转换为相对路径
 // - 若 listPath 以 "/" 开头但不是绝对路径（虚拟路径），移除前导 "/"
@@ -387,10 +435,12 @@ func normalizeLocalBuildRequest(client Client, driverType syncengine.DriverType,
 // 1. 空内容 → NeedUpdate
 // 2. 解析失败 → NeedUpdate
 // 3. BaseURL 不匹配 → NeedUpdate
-// 4. Path 不匹配（规范化后） → NeedUpdate
-// 5. PickCode 不匹配（如果 PickCode 能力启用）→ NeedUpdate
-// 6. Sign 缺失或过期（如果 SignURL 能力启用）→ NeedUpdate
-// 7. 所有检查通过 → Equal
+// 4. 期望内容带 PickCode（如果 PickCode 能力启用）→ 只比对 PickCode，文件改名或移动不触发更新
+// 5. Path 不匹配（规范化后） → NeedUpdate
+// 6. PickCode 不匹配（如果 PickCode 能力启用）→ NeedUpdate
+// 7. 期望内容带过期时间（如云盘直链）且与实际内容不完全一致 → NeedUpdate
+// 8. Sign 缺失或过期（如果 SignURL 能力启用）→ NeedUpdate
+// 9. 所有检查通过 → Equal
 //
 // 实现细节：
 // - 忽略前后空白
@@ -446,6 +496,24 @@ func (a *Adapter) CompareStrm(ctx context.Context, input syncengine.CompareInput
 		}, nil
 	}
 
+	cap := a.Capabilities()
+
+	// PickCode 链接不依赖路径，仅在 PickCode 变化时更新
+	if cap.PickCode && expected.PickCode != "" {
+		if !strmHasPickCode(actualURL, expected.PickCode) {
+			return syncengine.CompareResult{
+				Equal:      false,
+				NeedUpdate: true,
+				Reason:     "PickCode 不匹配",
+			}, nil
+		}
+		return syncengine.CompareResult{
+			Equal:      true,
+			NeedUpdate: false,
+			Reason:     "PickCode 一致",
+		}, nil
+	}
+
 	// 检查 Path（规范化后）
 	expectedPath := cleanRemotePath(expected.Path)
 	actualPath := cleanRemotePath(actualURL.Path)
@@ -457,7 +525,6 @@ func (a *Adapter) CompareStrm(ctx context.Context, input syncengine.CompareInput
 		}, nil
 	}
 
-	cap := a.Capabilities()
 	query := actualURL.Query()
 
 	// 检查 PickCode（如果驱动支持）
@@ -472,6 +539,16 @@ func (a *Adapter) CompareStrm(ctx context.Context, input syncengine.CompareInput
 		}
 	}
 
+	// 带过期时间的链接刷新后查询参数会变化，需要完整比对
+	if !expected.ExpiresAt.IsZero() && strings.TrimSpace(expected.RawURL) != "" &&
+		actualRaw != strings.TrimSpace(expected.RawURL) {
+		return syncengine.CompareResult{
+			Equal:      false,
+			NeedUpdate: true,
+			Reason:     "链接已刷新",
+		}, nil
+	}
+
 	// 检查 Sign（如果驱动支持）
 	if cap.SignURL {
 		actualSign := query.Get("sign")
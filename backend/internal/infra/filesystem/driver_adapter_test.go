@@ -226,6 +226,39 @@ func TestAdapterCompareStrm(t *testing.T) {
 	}
 }
 
+// TestAdapterCompareStrm_ExpiringLink 测试带过期时间的链接需要完整比对
+func TestAdapterCompareStrm_ExpiringLink(t *testing.T) {
+	client, err := NewClient(Config{Type: TypeLocal, MountPath: t.TempDir(), STRMMode: STRMModeMount})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	adapter, err := NewAdapter(client, syncengine.DriverCloudDrive2)
+	if err != nil {
+		t.Fatalf("创建适配器失败: %v", err)
+	}
+
+	expected := syncengine.StrmInfo{
+		RawURL:    "https://cdn.example.com/a.mkv?sig=2",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	ctx := context.Background()
+	result, err := adapter.CompareStrm(ctx, syncengine.CompareInput{Expected: expected, ActualRaw: "https://cdn.example.com/a.mkv?sig=1"})
+	if err != nil {
+		t.Fatalf("CompareStrm() 失败: %v", err)
+	}
+	if result.Equal || !result.NeedUpdate {
+		t.Errorf("刷新后的直链应触发更新, got %+v", result)
+	}
+
+	result, err = adapter.CompareStrm(ctx, syncengine.CompareInput{Expected: expected, ActualRaw: expected.RawURL})
+	if err != nil {
+		t.Fatalf("CompareStrm() 失败: %v", err)
+	}
+	if !result.Equal {
+		t.Errorf("相同直链应视为一致, got %+v", result)
+	}
+}
+
 // TestAdapterWatch 测试适配器 Watch 方法（不支持）
 func TestAdapterWatch(t *testing.T) {
 	// 创建临时目录
@@ -12,6 +12,10 @@ type Client interface {
 	// maxDepth: 递归最大深度，0表示非递归，>0表示递归的最大层级
 	List(ctx context.Context, path string, recursive bool, maxDepth int) ([]RemoteFile, error)
 
+	// ListStream 流式列出目录内容
+	// 按深度优先、名称排序的顺序逐个回调 fn，回调返回错误时终止遍历
+	ListStream(ctx context.Context, path string, recursive bool, maxDepth int, fn func(RemoteFile) error) error
+
 	// Watch 监控目录变化（如果支持）
 	Watch(ctx context.Context, path string) (<-chan FileEvent, error)
 
@@ -33,3 +37,20 @@ type Client interface {
 	// 用于无法通过挂载路径访问文件时的回退方案
 	Download(ctx context.Context, remotePath string, w io.Writer) error
 }
+
+// RangeDownloader 支持断点续传的客户端（可选）
+//
+// 调用方通过类型断言检测。
+type RangeDownloader interface {
+	// DownloadRange 从 offset 处下载文件内容到writer
+	DownloadRange(ctx context.Context, remotePath string, offset int64, w io.Writer) error
+
+	// SupportsRange 判断当前数据源是否支持断点续传
+	SupportsRange() bool
+}
+
+// DownloadPool 提供并发下载数上限的客户端（可选）
+type DownloadPool interface {
+	// DownloadConcurrency 返回服务器的并发下载数上限
+	DownloadConcurrency() int
+}
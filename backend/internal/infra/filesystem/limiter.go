@@ -0,0 +1,104 @@
+package filesystem
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// semaphore 可原地调整上限的并发信号量（ListLimiter 与 DownloadLimiter 共用）
+//
+// 调低上限时不打断已占用的槽位，占用数降到新上限以下后才分配新槽位，
+// 因此上限调整前后获取的槽位始终受同一上限约束。
+type semaphore struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	wake   chan struct{} // 槽位释放或上限调整时关闭并替换，唤醒等待者
+}
+
+func newSemaphore(n int) semaphore {
+	return semaphore{limit: n, wake: make(chan struct{})}
+}
+
+// Acquire 获取一个并发槽位（可被 context 取消）
+func (s *semaphore) Acquire(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.active < s.limit {
+			s.active++
+			s.mu.Unlock()
+			return nil
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release 释放并发槽位
+func (s *semaphore) Release() {
+	s.mu.Lock()
+	s.active--
+	s.broadcast()
+	s.mu.Unlock()
+}
+
+// Cap 返回并发上限
+func (s *semaphore) Cap() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// resize 调整并发上限
+func (s *semaphore) resize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n != s.limit {
+		s.limit = n
+		s.broadcast()
+	}
+}
+
+// broadcast 唤醒所有等待者（调用方持有 mu）
+func (s *semaphore) broadcast() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// limiterRegistry 按服务器共享的限制器
+//
+// 以 类型 + BaseURL + MountPath 作为服务器标识；限制配置变化时调用 update 原地调整
+// 已有的限制器，而不是替换它，使调整前后的请求仍受同一组限制约束。
+type limiterRegistry[T any] struct {
+	mu    sync.Mutex
+	items map[string]T
+}
+
+// get 返回服务器共享的限制器：不存在时调用 create 创建，已存在时调用 update 调整
+func (r *limiterRegistry[T]) get(config Config, create func() T, update func(T)) T {
+	key := strings.Join([]string{
+		config.Type.String(),
+		strings.TrimRight(strings.TrimSpace(config.BaseURL), "/"),
+		strings.TrimSpace(config.MountPath),
+	}, "|")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.items[key]; ok {
+		update(existing)
+		return existing
+	}
+	if r.items == nil {
+		r.items = make(map[string]T)
+	}
+	limiter := create()
+	r.items[key] = limiter
+	return limiter
+}
@@ -51,6 +51,22 @@ func NewLocalProvider(c *filesystem.ClientImpl) (filesystem.Provider, error) {
 
 // List 列出本地目录内容
 func (p *localProvider) List(ctx context.Context, listPath string, recursive bool, maxDepth int) ([]filesystem.RemoteFile, error) {
+	var results []filesystem.RemoteFile
+	err := p.ListStream(ctx, listPath, recursive, maxDepth, func(f filesystem.RemoteFile) error {
+		results = append(results, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListStream 流式列出本地目录内容
+//
+// filepath.WalkDir / os.ReadDir 均按文件名排序遍历，回调顺序与
+// syncengine.ComparePaths 一致。本地磁盘无需并行列出。
+func (p *localProvider) ListStream(ctx context.Context, listPath string, recursive bool, maxDepth int, fn func(filesystem.RemoteFile) error) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -58,7 +74,7 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 	// 规范化listPath为相对路径
 	normalizedListPath, err := normalizeListPath(listPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	mountRoot := strings.TrimSpace(p.config.MountPath)
@@ -66,7 +82,7 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 		mountRoot = strings.TrimSpace(p.config.StrmMountPath)
 	}
 	if mountRoot == "" {
-		return nil, fmt.Errorf("local: mount_path is required: %w", syncengine.ErrInvalidInput)
+		return fmt.Errorf("local: mount_path is required: %w", syncengine.ErrInvalidInput)
 	}
 	mountRoot = filepath.Clean(mountRoot)
 
@@ -75,10 +91,10 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 
 	// 验证路径在挂载点内
 	if err := ensureUnderMount(mountRoot, fullPath); err != nil {
-		return nil, err
+		return err
 	}
 
-	var results []filesystem.RemoteFile
+	count := 0
 
 	if recursive {
 		// 递归遍历（带深度控制）
@@ -113,14 +129,17 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 			relPath = filepath.ToSlash(relPath) // 转换为 Unix 路径
 			virtualPath := path.Clean("/" + relPath)
 
-			// 记录当前文件/目录
-			results = append(results, filesystem.RemoteFile{
+			// 回调当前文件/目录
+			count++
+			if err := fn(filesystem.RemoteFile{
 				Path:    virtualPath,
 				Name:    info.Name(),
 				Size:    info.Size(),
 				ModTime: info.ModTime(),
 				IsDir:   d.IsDir(),
-			})
+			}); err != nil {
+				return err
+			}
 
 			// 深度控制：如果是目录且已达最大深度，跳过其子项（但目录本身已被记录）
 			if d.IsDir() && currentDepth >= maxDepth && filePath != fullPath {
@@ -131,19 +150,19 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 		})
 
 		if err != nil {
-			return nil, fmt.Errorf("filesystem: walk directory: %w", err)
+			return fmt.Errorf("filesystem: walk directory: %w", err)
 		}
 	} else {
 		// 只列出当前目录
 		entries, err := os.ReadDir(fullPath)
 		if err != nil {
-			return nil, fmt.Errorf("filesystem: read directory: %w", err)
+			return fmt.Errorf("filesystem: read directory: %w", err)
 		}
 
 		for _, entry := range entries {
 			// 检查 context 取消
 			if ctx.Err() != nil {
-				return nil, ctx.Err()
+				return ctx.Err()
 			}
 
 			info, err := entry.Info()
@@ -155,22 +174,25 @@ func (p *localProvider) List(ctx context.Context, listPath string, recursive boo
 			relPath := filepath.ToSlash(filepath.Join(normalizedListPath, entry.Name()))
 			virtualPath := path.Join("/", relPath)
 
-			results = append(results, filesystem.RemoteFile{
+			count++
+			if err := fn(filesystem.RemoteFile{
 				Path:    virtualPath,
 				Name:    info.Name(),
 				Size:    info.Size(),
 				ModTime: info.ModTime(),
 				IsDir:   entry.IsDir(),
-			})
+			}); err != nil {
+				return err
+			}
 		}
 	}
 
 	p.logger.Info("本地目录列出完成",
 		zap.String("path", fullPath),
 		zap.Bool("recursive", recursive),
-		zap.Int("count", len(results)))
+		zap.Int("count", count))
 
-	return results, nil
+	return nil
 }
 
 // Watch 监控本地目录变化（暂不支持）
@@ -24,6 +24,7 @@ type openListProvider struct {
 	baseURL *url.URL
 	client  *openlistsdk.Client
 	logger  *zap.Logger
+	limiter *filesystem.ListLimiter
 }
 
 // NewOpenListProvider 创建OpenList filesystem.Provider
@@ -45,6 +46,7 @@ func NewOpenListProvider(c *filesystem.ClientImpl) (filesystem.Provider, error)
 		baseURL: c.BaseURL,
 		client:  client,
 		logger:  c.Logger,
+		limiter: c.ListLimiter,
 	}, nil
 }
 
@@ -56,7 +58,41 @@ func (p *openListProvider) List(ctx context.Context, listPath string, recursive
 	if strings.TrimSpace(listPath) == "" {
 		listPath = "/"
 	}
-	return p.listOpenList(ctx, listPath, recursive, maxDepth)
+	var results []filesystem.RemoteFile
+	err := p.ListStream(ctx, listPath, recursive, maxDepth, func(f filesystem.RemoteFile) error {
+		results = append(results, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListStream 流式列出目录内容（目录并行列出，按遍历顺序回调）
+func (p *openListProvider) ListStream(ctx context.Context, listPath string, recursive bool, maxDepth int, fn func(filesystem.RemoteFile) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if strings.TrimSpace(listPath) == "" {
+		listPath = "/"
+	}
+
+	var count int
+	err := filesystem.WalkOrdered(ctx, listPath, recursive, maxDepth, p.limiter, p.listDir, func(f filesystem.RemoteFile) error {
+		count++
+		return fn(f)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("OpenList 目录列出完成",
+		zap.String("root", listPath),
+		zap.Bool("recursive", recursive),
+		zap.Int("max_depth", maxDepth),
+		zap.Int("count", count))
+	return nil
 }
 
 // Watch 监控目录变化（OpenList不支持）
@@ -94,6 +130,21 @@ func (p *openListProvider) Download(ctx context.Context, remotePath string, w io
 	return p.client.Download(ctx, cleanPath, w)
 }
 
+// DownloadRange 从 offset 处下载文件内容到writer（实现 filesystem.RangeProvider）
+func (p *openListProvider) DownloadRange(ctx context.Context, remotePath string, offset int64, w io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if strings.TrimSpace(remotePath) == "" {
+		return fmt.Errorf("openlist: remote path cannot be empty")
+	}
+
+	cleanPath := filesystem.CleanRemotePath(remotePath)
+	p.logger.Debug("OpenList DownloadRange", zap.String("path", cleanPath), zap.Int64("offset", offset))
+
+	return p.client.DownloadRange(ctx, cleanPath, offset, w)
+}
+
 // Stat 获取单个路径的元数据
 func (p *openListProvider) Stat(ctx context.Context, targetPath string) (filesystem.RemoteFile, error) {
 	if ctx == nil {
@@ -130,11 +181,12 @@ func (p *openListProvider) Stat(ctx context.Context, targetPath string) (filesys
 		if item.Name == baseName {
 			fullPath := filesystem.JoinRemotePath(parentPath, item.Name)
 			result := filesystem.RemoteFile{
-				Path:    fullPath,
-				Name:    item.Name,
-				Size:    item.Size,
-				ModTime: item.Modified,
-				IsDir:   item.IsDir,
+				Path:     fullPath,
+				Name:     item.Name,
+				Size:     item.Size,
+				ModTime:  item.Modified,
+				IsDir:    item.IsDir,
+				PickCode: pickCode(item),
 			}
 			p.logger.Debug("OpenList Stat 完成",
 				zap.String("path", fullPath),
@@ -148,6 +200,8 @@ func (p *openListProvider) Stat(ctx context.Context, targetPath string) (filesys
 }
 
 // BuildStrmInfo 构建结构化的 STRM 信息
+//
+// 生成 OpenList 下载链接：<baseURL>/d/<path>（保留 baseURL 的路径前缀）
 func (p *openListProvider) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmRequest) (syncengine.StrmInfo, error) {
 	_ = ctx // 保留用于未来的取消或追踪
 
@@ -175,7 +229,11 @@ func (p *openListProvider) BuildStrmInfo(ctx context.Context, req syncengine.Bui
 	}
 
 	// 构建 URL
-	rawURL := fmt.Sprintf("%s://%s%s", scheme, host, cleanPath)
+	result := *p.baseURL
+	result.Scheme = scheme
+	result.Path = filesystem.JoinURLPath(result.Path, "/d")
+	result.Path = filesystem.JoinURLPath(result.Path, cleanPath)
+	rawURL := result.String()
 
 	p.logger.Debug("OpenList BuildStrmInfo",
 		zap.String("remote_file_path", cleanPath),
@@ -185,70 +243,41 @@ func (p *openListProvider) BuildStrmInfo(ctx context.Context, req syncengine.Bui
 	return syncengine.StrmInfo{
 		RawURL:  rawURL,
 		BaseURL: &url.URL{Scheme: scheme, Host: host},
-		Path:    cleanPath,
+		Path:    result.Path,
 	}, nil
 }
 
-// listOpenList 递归列出 OpenList 目录（使用 BFS，支持深度限制）
-func (p *openListProvider) listOpenList(ctx context.Context, root string, recursive bool, maxDepth int) ([]filesystem.RemoteFile, error) {
-	var results []filesystem.RemoteFile
-
-	// 使用 BFS 队列遍历目录树
-	type queueItem struct {
-		path  string
-		depth int
+// listDir 列出单个 OpenList 目录（非递归）
+func (p *openListProvider) listDir(ctx context.Context, dir string) ([]filesystem.RemoteFile, error) {
+	items, err := p.client.List(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("list directory %s: %w", dir, err)
 	}
-	queue := []queueItem{{path: filesystem.CleanRemotePath(root), depth: 0}}
-
-	for len(queue) > 0 {
-		// 检查 context 取消
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-
-		// 取出队头目录
-		item := queue[0]
-		dir := item.path
-		queue = queue[1:]
-
-		// 列出当前目录
-		items, err := p.client.List(ctx, dir)
-		if err != nil {
-			return nil, fmt.Errorf("list directory %s: %w", dir, err)
-		}
 
-		// 处理每个项目
-		for _, sdkItem := range items {
-			fullPath := filesystem.JoinRemotePath(dir, sdkItem.Name)
-
-			// 转换为RemoteFile
-			remoteFile := filesystem.RemoteFile{
-				Path:    fullPath,
-				Name:    sdkItem.Name,
-				Size:    sdkItem.Size,
-				ModTime: sdkItem.Modified,
-				IsDir:   sdkItem.IsDir,
-			}
-
-			// 将所有项目（文件和目录）加入结果
-			results = append(results, remoteFile)
-
-			// 递归模式：将子目录加入队列（深度控制）
-			if sdkItem.IsDir && recursive && item.depth+1 < maxDepth {
-				queue = append(queue, queueItem{path: fullPath, depth: item.depth + 1})
-			}
-		}
+	results := make([]filesystem.RemoteFile, 0, len(items))
+	for _, sdkItem := range items {
+		results = append(results, filesystem.RemoteFile{
+			Path:     filesystem.JoinRemotePath(dir, sdkItem.Name),
+			Name:     sdkItem.Name,
+			Size:     sdkItem.Size,
+			ModTime:  sdkItem.Modified,
+			IsDir:    sdkItem.IsDir,
+			PickCode: pickCode(sdkItem),
+		})
 	}
-
-	p.logger.Info("OpenList 目录列出完成",
-		zap.String("root", root),
-		zap.Bool("recursive", recursive),
-		zap.Int("max_depth", maxDepth),
-		zap.Int("count", len(results)))
-
 	return results, nil
 }
 
+// pickCode 从 OpenList 对象 ID 中识别 115 PickCode
+//
+// OpenList 的列表接口只返回存储驱动的对象 ID，以 PickCode 作为 ID 的 115 驱动可直接使用。
+func pickCode(item openlistsdk.FileItem) string {
+	if item.IsDir || !filesystem.IsPickCode(item.ID) {
+		return ""
+	}
+	return item.ID
+}
+
 func init() {
 	filesystem.RegisterProvider(filesystem.TypeOpenList, func(c *filesystem.ClientImpl) (filesystem.Provider, error) {
 		return NewOpenListProvider(c)
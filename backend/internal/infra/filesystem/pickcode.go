@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+)
+
+const (
+	// PickCodePlaceholder PickCode 链接模板中的 PickCode 占位符
+	PickCodePlaceholder = "{pickcode}"
+	// PickCodeNamePlaceholder PickCode 链接模板中的文件名占位符（URL 编码后替换）
+	PickCodeNamePlaceholder = "{name}"
+)
+
+// pickCodePattern 115 PickCode 格式：16-20 位小写字母与数字
+var pickCodePattern = regexp.MustCompile(`^[a-z0-9]{16,20}$`)
+
+// IsPickCode 判断云盘文件 ID 是否为 115 PickCode
+//
+// 纯数字的 ID（115 文件 ID、百度 fs_id 等）与更长的十六进制 ID（阿里云盘、夸克等）不会被识别。
+func IsPickCode(id string) bool {
+	return pickCodePattern.MatchString(id) && strings.IndexFunc(id, isLetter) >= 0
+}
+
+// isLetter 判断是否为小写字母
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z'
+}
+
+// validatePickCodeURL 校验 PickCode 链接模板
+func validatePickCodeURL(tmpl string) error {
+	if !strings.Contains(tmpl, PickCodePlaceholder) {
+		return fmt.Errorf("filesystem: pickcode_url must contain %s", PickCodePlaceholder)
+	}
+	sample := strings.NewReplacer(PickCodePlaceholder, "pickcode", PickCodeNamePlaceholder, "name").Replace(tmpl)
+	parsed, err := url.Parse(sample)
+	if err != nil {
+		return fmt.Errorf("filesystem: parse pickcode_url: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("filesystem: invalid pickcode_url (missing scheme or host): %s", tmpl)
+	}
+	return nil
+}
+
+// buildPickCodeStrmInfo 使用模板生成基于 PickCode 的 STRM 信息
+func buildPickCodeStrmInfo(tmpl, pickCode, remotePath string) (syncengine.StrmInfo, error) {
+	rawURL := strings.NewReplacer(
+		PickCodePlaceholder, url.PathEscape(pickCode),
+		PickCodeNamePlaceholder, url.PathEscape(path.Base(cleanRemotePath(remotePath))),
+	).Replace(tmpl)
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return syncengine.StrmInfo{}, fmt.Errorf("filesystem: 解析 PickCode 链接失败: %w", err)
+	}
+	return syncengine.StrmInfo{
+		RawURL:   rawURL,
+		BaseURL:  &url.URL{Scheme: parsed.Scheme, Host: parsed.Host},
+		Path:     parsed.Path,
+		PickCode: pickCode,
+	}, nil
+}
+
+// strmHasPickCode 判断 STRM 链接是否指向指定 PickCode
+//
+// 依次检查 pickcode/pick_code 查询参数和路径中的各段，兼容不同 302 代理的链接格式。
+func strmHasPickCode(u *url.URL, pickCode string) bool {
+	query := u.Query()
+	if actual := firstNonEmpty(query.Get("pickcode"), query.Get("pick_code")); actual != "" {
+		return actual == pickCode
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == pickCode {
+			return true
+		}
+	}
+	return false
+}
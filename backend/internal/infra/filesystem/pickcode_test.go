@@ -0,0 +1,102 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+)
+
+func TestIsPickCode(t *testing.T) {
+	cases := map[string]bool{
+		"ecjq9ichcb40lzlvx":                        true,
+		"a4gfjgt5eb4xqblbz":                        true,
+		"2593017462318412345":                      false, // 115 文件 ID
+		"64a5c1e0f1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6": false, // 阿里云盘
+		"VNabcdefghijklmnopqrstuv":                 false,
+		"short1":                                   false,
+	}
+	for id, want := range cases {
+		if got := IsPickCode(id); got != want {
+			t.Errorf("IsPickCode(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestClientBuildStrmInfo_PickCode(t *testing.T) {
+	client := &ClientImpl{
+		Config: Config{
+			Type:        TypeLocal,
+			STRMMode:    STRMModeHTTP,
+			PickCodeURL: "http://115proxy:9000/d/{pickcode}/{name}",
+		},
+		Provider: &testLocalProvider{},
+	}
+	ctx := context.Background()
+	meta := &syncengine.RemoteEntry{Path: "/电影/A Movie.mkv", PickCode: "ecjq9ichcb40lzlvx"}
+
+	info, err := client.BuildStrmInfo(ctx, syncengine.BuildStrmRequest{RemotePath: meta.Path, RemoteMeta: meta})
+	if err != nil {
+		t.Fatalf("BuildStrmInfo() 失败: %v", err)
+	}
+	if info.RawURL != "http://115proxy:9000/d/ecjq9ichcb40lzlvx/A%20Movie.mkv" {
+		t.Errorf("RawURL = %s", info.RawURL)
+	}
+	if info.PickCode != meta.PickCode || info.BaseURL == nil || info.BaseURL.Host != "115proxy:9000" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	// 无 PickCode 的文件使用 Provider 生成的链接
+	meta.PickCode = ""
+	info, err = client.BuildStrmInfo(ctx, syncengine.BuildStrmRequest{RemotePath: meta.Path, RemoteMeta: meta})
+	if err != nil {
+		t.Fatalf("BuildStrmInfo() 失败: %v", err)
+	}
+	if info.PickCode != "" {
+		t.Errorf("expected fallback link without pickcode, got %+v", info)
+	}
+}
+
+func TestNewClient_InvalidPickCodeURL(t *testing.T) {
+	for _, tmpl := range []string{"http://115proxy:9000/d/", "/d/{pickcode}"} {
+		_, err := NewClient(Config{Type: TypeLocal, MountPath: t.TempDir(), STRMMode: STRMModeMount, PickCodeURL: tmpl})
+		if err == nil {
+			t.Errorf("expected error for pickcode_url %q", tmpl)
+		}
+	}
+}
+
+func TestAdapterCompareStrm_PickCode(t *testing.T) {
+	client, err := NewClient(Config{Type: TypeLocal, MountPath: t.TempDir(), STRMMode: STRMModeMount})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	adapter, err := NewAdapter(client, syncengine.DriverCloudDrive2)
+	if err != nil {
+		t.Fatalf("创建适配器失败: %v", err)
+	}
+	info, err := buildPickCodeStrmInfo("http://115proxy:9000/d/{pickcode}/{name}", "ecjq9ichcb40lzlvx", "/电影/新名字.mkv")
+	if err != nil {
+		t.Fatalf("build pickcode info: %v", err)
+	}
+
+	cases := []struct {
+		actual string
+		equal  bool
+	}{
+		{"http://115proxy:9000/d/ecjq9ichcb40lzlvx/%E6%97%A7%E5%90%8D%E5%AD%97.mkv", true}, // 改名不更新
+		{"http://115proxy:9000/play?pickcode=ecjq9ichcb40lzlvx", true},
+		{"http://115proxy:9000/d/a4gfjgt5eb4xqblbz/%E6%97%A7%E5%90%8D%E5%AD%97.mkv", false},
+		{"http://115proxy:9000/play?pickcode=a4gfjgt5eb4xqblbz&x=ecjq9ichcb40lzlvx", false},
+		{"http://cd2:19798/static/http/cd2:19798/false/电影/新名字.mkv", false},
+	}
+	for _, tc := range cases {
+		result, err := adapter.CompareStrm(context.Background(), syncengine.CompareInput{Expected: info, ActualRaw: tc.actual})
+		if err != nil {
+			t.Fatalf("CompareStrm(%s) 失败: %v", tc.actual, err)
+		}
+		if result.Equal != tc.equal {
+			t.Errorf("CompareStrm(%s) Equal = %v, want %v (%s)", tc.actual, result.Equal, tc.equal, result.Reason)
+		}
+	}
+}
@@ -56,6 +56,31 @@ func (m STRMMode) IsValid() bool {
 	}
 }
 
+// LinkMode HTTP STRM 链接类型（目前仅 CloudDrive2 支持选择）
+type LinkMode string
+
+const (
+	// LinkModeProxy 表示经数据服务器中转的下载链接（CloudDrive2 的 /static/... 地址）
+	LinkModeProxy LinkMode = "proxy"
+	// LinkModeDirect 表示云盘直链（通常带过期时间，过期前需要刷新 STRM）
+	LinkModeDirect LinkMode = "direct"
+)
+
+// String 返回字符串表示
+func (m LinkMode) String() string {
+	return string(m)
+}
+
+// IsValid 验证LinkMode是否有效
+func (m LinkMode) IsValid() bool {
+	switch m {
+	case LinkModeProxy, LinkModeDirect:
+		return true
+	default:
+		return false
+	}
+}
+
 // Config 数据服务器配置
 type Config struct {
 	Type     Type     // 数据服务器类型
@@ -63,6 +88,12 @@ type Config struct {
 	Username string   // 用户名（用于登录认证）
 	Password string   // 密码（用于登录认证或目录密码）
 	STRMMode STRMMode // STRM模式
+	// LinkMode HTTP 模式下的链接类型（仅 CloudDrive2，默认 proxy）
+	LinkMode LinkMode
+	// PickCodeURL HTTP 模式下 115 文件的 STRM 链接模板（可选）
+	// 包含 {pickcode} 占位符，可选 {name}（文件名），例如 http://115proxy:9000/d/{pickcode}
+	// 文件带有 PickCode 时使用该模板，否则使用普通链接
+	PickCodeURL string
 	// MountPath 本地挂载路径（mount模式必需）
 	// 例如：/mnt/openlist 或 D:\mnt\openlist
 	MountPath string
@@ -70,6 +101,14 @@ type Config struct {
 	// 为空时默认使用 MountPath
 	StrmMountPath string
 	Timeout       time.Duration // 请求超时时间（默认10秒）
+	// ListConcurrency 目录并发列出数（同一服务器共享，默认4）
+	ListConcurrency int
+	// DownloadConcurrency 并发下载数（同一服务器共享，默认4）
+	DownloadConcurrency int
+	// DownloadRatePerSec 每秒开始的下载数（同一服务器共享，0=不限制）
+	DownloadRatePerSec int
+	// DownloadBytesPerSec 下载带宽上限（字节/秒，同一服务器共享，0=不限制）
+	DownloadBytesPerSec int64
 }
 
 // RemoteFile 远程文件信息
@@ -79,6 +118,9 @@ type RemoteFile struct {
 	Size    int64     // 文件大小
 	ModTime time.Time // 修改时间
 	IsDir   bool      // 是否为目录
+	Hash    string    // 内容哈希（"算法:十六进制值"，提供者不支持时为空）
+	// PickCode 115 云盘文件的 PickCode（其他云盘为空）
+	PickCode string
 }
 
 // FileEvent 文件事件
@@ -0,0 +1,177 @@
+// Package filesystem 实现远程文件系统客户端
+package filesystem
+
+import (
+	"context"
+	"sort"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+)
+
+const (
+	// defaultListConcurrency 默认每个服务器的目录并发列出数
+	defaultListConcurrency = 4
+	// maxListConcurrency 每个服务器目录并发列出数上限
+	maxListConcurrency = 32
+)
+
+// ListLimiter 目录列出并发限制器
+//
+// 同一数据服务器（类型 + 地址）的所有客户端共享一个限制器，
+// 确保多个任务同时扫描同一服务器时总并发仍然有界。
+type ListLimiter struct {
+	semaphore
+}
+
+// NewListLimiter 创建并发限制器（n <= 0 时使用默认值）
+func NewListLimiter(n int) *ListLimiter {
+	return &ListLimiter{semaphore: newSemaphore(listConcurrency(n))}
+}
+
+// listConcurrency 规范化并发上限
+func listConcurrency(n int) int {
+	if n <= 0 {
+		n = defaultListConcurrency
+	}
+	if n > maxListConcurrency {
+		n = maxListConcurrency
+	}
+	return n
+}
+
+// listLimiters 按服务器共享的目录列出限制器
+var listLimiters limiterRegistry[*ListLimiter]
+
+// serverListLimiter 返回指定服务器共享的限制器（并发上限变化时原地调整）
+func serverListLimiter(config Config) *ListLimiter {
+	return listLimiters.get(config, func() *ListLimiter {
+		return NewListLimiter(config.ListConcurrency)
+	}, func(l *ListLimiter) {
+		l.resize(listConcurrency(config.ListConcurrency))
+	})
+}
+
+// ListDirFunc 列出单个目录的直接子项（非递归）
+type ListDirFunc func(ctx context.Context, dir string) ([]RemoteFile, error)
+
+// walkNode 目录列出结果（异步填充）
+type walkNode struct {
+	path    string
+	depth   int
+	done    chan struct{}
+	entries []RemoteFile
+	err     error
+}
+
+// WalkOrdered 并行列出目录树，并按确定顺序流式回调
+//
+// 回调顺序为深度优先前序、同级按名称排序，与 syncengine.ComparePaths 一致，
+// 因此调用方可以基于"已完成前缀"实现断点续传。
+//
+// 并行策略：进入某个目录时，预取其后若干个兄弟子目录的列表
+// （预取窗口为限制器容量的 2 倍），实际并发由 limiter 控制。
+// 回调在调用方 goroutine 中串行执行，回调阻塞即形成背压。
+//
+// 深度语义与 List 一致：根目录深度为 0，仅当 recursive 且 depth+1 < maxDepth 时
+// 才继续列出子目录。
+func WalkOrdered(ctx context.Context, root string, recursive bool, maxDepth int, limiter *ListLimiter, listDir ListDirFunc, fn func(RemoteFile) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limiter == nil {
+		limiter = NewListLimiter(0)
+	}
+
+	// 回调提前结束时，取消尚未完成的预取
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := func(dir string, depth int) *walkNode {
+		node := &walkNode{path: dir, depth: depth, done: make(chan struct{})}
+		go func() {
+			defer close(node.done)
+			if err := limiter.Acquire(ctx); err != nil {
+				node.err = err
+				return
+			}
+			defer limiter.Release()
+
+			entries, err := listDir(ctx, dir)
+			if err != nil {
+				node.err = err
+				return
+			}
+			sort.SliceStable(entries, func(i, j int) bool {
+				return entries[i].Name < entries[j].Name
+			})
+			node.entries = entries
+		}()
+		return node
+	}
+
+	prefetch := limiter.Cap() * 2
+
+	var visit func(node *walkNode) error
+	visit = func(node *walkNode) error {
+		select {
+		case <-node.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if node.err != nil {
+			return node.err
+		}
+
+		descend := recursive && node.depth+1 < maxDepth
+
+		// 收集子目录下标，便于按窗口预取
+		var dirIdx []int
+		if descend {
+			for i, entry := range node.entries {
+				if entry.IsDir {
+					dirIdx = append(dirIdx, i)
+				}
+			}
+		}
+		children := make(map[int]*walkNode, len(dirIdx))
+		started := 0
+		ensureStarted := func(upTo int) {
+			for started < len(dirIdx) && started < upTo {
+				idx := dirIdx[started]
+				children[idx] = start(node.entries[idx].Path, node.depth+1)
+				started++
+			}
+		}
+		ensureStarted(prefetch)
+
+		nextDir := 0
+		for i, entry := range node.entries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+			child, ok := children[i]
+			if !ok {
+				continue
+			}
+			nextDir++
+			ensureStarted(nextDir + prefetch)
+			delete(children, i)
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return visit(start(CleanRemotePath(root), 0))
+}
+
+// sortRemoteFiles 按 WalkOrdered 的遍历顺序排序
+func sortRemoteFiles(files []RemoteFile) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return syncengine.ComparePaths(files[i].Path, files[j].Path) < 0
+	})
+}
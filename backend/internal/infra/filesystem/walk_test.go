@@ -0,0 +1,158 @@
+// Package filesystem 并行目录遍历测试
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeTree 测试用的内存目录树：目录路径 -> 子项名称（以 "/" 结尾表示目录）
+type fakeTree map[string][]string
+
+func (tree fakeTree) listDir(inFlight, peak *int64) ListDirFunc {
+	return func(ctx context.Context, dir string) ([]RemoteFile, error) {
+		n := atomic.AddInt64(inFlight, 1)
+		defer atomic.AddInt64(inFlight, -1)
+		for {
+			old := atomic.LoadInt64(peak)
+			if n <= old || atomic.CompareAndSwapInt64(peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+
+		names, ok := tree[dir]
+		if !ok {
+			return nil, errors.New("not found: " + dir)
+		}
+		files := make([]RemoteFile, 0, len(names))
+		for _, name := range names {
+			isDir := name[len(name)-1] == '/'
+			if isDir {
+				name = name[:len(name)-1]
+			}
+			files = append(files, RemoteFile{Path: path.Join(dir, name), Name: name, IsDir: isDir})
+		}
+		return files, nil
+	}
+}
+
+func TestWalkOrdered_OrderAndConcurrency(t *testing.T) {
+	// 子项故意乱序，验证输出按名称排序
+	tree := fakeTree{
+		"/":      {"b/", "a b/", "a/", "z.mkv"},
+		"/a":     {"2.mp4", "1.mp4", "sub/"},
+		"/a/sub": {"x.mp4"},
+		"/a b":   {"c.mp4"},
+		"/b":     {"d.mp4", "e/", "f/", "g/"},
+		"/b/e":   {"e.mp4"},
+		"/b/f":   {"f.mp4"},
+		"/b/g":   {"g.mp4"},
+	}
+
+	var inFlight, peak int64
+	limiter := NewListLimiter(2)
+	var got []string
+	err := WalkOrdered(context.Background(), "/", true, 100, limiter, tree.listDir(&inFlight, &peak), func(f RemoteFile) error {
+		got = append(got, f.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkOrdered: %v", err)
+	}
+
+	want := []string{
+		"/a", "/a/1.mp4", "/a/2.mp4", "/a/sub", "/a/sub/x.mp4",
+		"/a b", "/a b/c.mp4",
+		"/b", "/b/d.mp4", "/b/e", "/b/e/e.mp4", "/b/f", "/b/f/f.mp4", "/b/g", "/b/g/g.mp4",
+		"/z.mkv",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("order mismatch\n got: %v\nwant: %v", got, want)
+	}
+	if peak > int64(limiter.Cap()) {
+		t.Errorf("concurrency exceeded limit: peak=%d cap=%d", peak, limiter.Cap())
+	}
+}
+
+func TestWalkOrdered_MaxDepthAndStop(t *testing.T) {
+	tree := fakeTree{
+		"/":    {"a/", "b.mp4"},
+		"/a":   {"c/"},
+		"/a/c": {"d.mp4"},
+	}
+	var inFlight, peak int64
+
+	// maxDepth=1：只列出根目录
+	var got []string
+	err := WalkOrdered(context.Background(), "/", true, 1, nil, tree.listDir(&inFlight, &peak), func(f RemoteFile) error {
+		got = append(got, f.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkOrdered: %v", err)
+	}
+	if want := []string{"/a", "/b.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("maxDepth=1 got %v, want %v", got, want)
+	}
+
+	// 回调返回错误时终止遍历
+	stop := errors.New("stop")
+	count := 0
+	err = WalkOrdered(context.Background(), "/", true, 100, nil, tree.listDir(&inFlight, &peak), func(f RemoteFile) error {
+		count++
+		if f.Path == "/a/c" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected stop error, got %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 callbacks before stop, got %d", count)
+	}
+}
+
+func TestServerListLimiter_ResizesInPlace(t *testing.T) {
+	cfg := Config{Type: TypeOpenList, BaseURL: "http://shared-list:5244", ListConcurrency: 2}
+	l := serverListLimiter(cfg)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := l.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tryAcquire := func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return l.Acquire(ctx) == nil
+	}
+
+	// 调高上限：同一限制器立即放行
+	cfg.ListConcurrency = 3
+	if got := serverListLimiter(cfg); got != l || l.Cap() != 3 {
+		t.Fatalf("expected limiter resized in place, cap=%d", l.Cap())
+	}
+	if !tryAcquire() {
+		t.Fatalf("expected acquire after raising the limit")
+	}
+
+	// 调低上限：已占用的槽位仍计入，释放到新上限以下才放行
+	cfg.ListConcurrency = 1
+	serverListLimiter(cfg)
+	l.Release()
+	l.Release()
+	if tryAcquire() {
+		t.Fatalf("expected acquire to wait while in-flight lists exceed the new limit")
+	}
+	l.Release()
+	if !tryAcquire() {
+		t.Fatalf("expected acquire once in-flight lists drop below the new limit")
+	}
+}
@@ -0,0 +1,146 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// streamMagic 流式加密文件头
+var streamMagic = []byte("STRMENC1")
+
+// streamChunkSize 流式加密的分块大小（明文）
+const streamChunkSize = 64 * 1024
+
+// IsEncryptedStream 判断数据是否以流式加密文件头开始
+func IsEncryptedStream(header []byte) bool {
+	return bytes.HasPrefix(header, streamMagic)
+}
+
+// EncryptStream 使用AES-256-GCM分块加密src并写入dst
+// 适用于无法一次性读入内存的大文件（如数据库备份），密钥派生方式与Encrypt相同
+//
+// 格式：文件头 + 若干分块（4字节密文长度 + nonce + 密文）。
+// 每块以序号和是否为末块作为附加数据，防止分块被重排、截断或拼接。
+func EncryptStream(dst io.Writer, src io.Reader, key string) error {
+	gcm, err := newStreamGCM(key)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(streamMagic); err != nil {
+		return fmt.Errorf("写入文件头失败: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(src, streamChunkSize)
+	buf := make([]byte, streamChunkSize)
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("读取明文失败: %w", err)
+		}
+		// 读不满一块或后面没有数据时当前块为末块（空输入也会写出一个空的末块）
+		final := err != nil
+		if !final {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				final = true
+			} else if peekErr != nil {
+				return fmt.Errorf("读取明文失败: %w", peekErr)
+			}
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return fmt.Errorf("读取随机数失败: %w", err)
+		}
+		sealed := gcm.Seal(nonce, nonce, buf[:n], streamAAD(index, final))
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+		if _, err := dst.Write(size[:]); err != nil {
+			return fmt.Errorf("写入密文失败: %w", err)
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return fmt.Errorf("写入密文失败: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptStream 解密由EncryptStream生成的数据并写入dst
+func DecryptStream(dst io.Writer, src io.Reader, key string) error {
+	gcm, err := newStreamGCM(key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(streamMagic))
+	if _, err := io.ReadFull(src, header); err != nil || !IsEncryptedStream(header) {
+		return errors.New("不是有效的加密文件")
+	}
+
+	maxSealed := gcm.NonceSize() + streamChunkSize + gcm.Overhead()
+	for index := uint64(0); ; index++ {
+		var size [4]byte
+		if _, err := io.ReadFull(src, size[:]); err != nil {
+			return errors.New("加密文件被截断")
+		}
+		length := int(binary.BigEndian.Uint32(size[:]))
+		if length < gcm.NonceSize()+gcm.Overhead() || length > maxSealed {
+			return errors.New("加密文件已损坏")
+		}
+		sealed := make([]byte, length)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return errors.New("加密文件被截断")
+		}
+
+		nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		plain, err := gcm.Open(nil, nonce, data, streamAAD(index, false))
+		final := false
+		if err != nil {
+			plain, err = gcm.Open(nil, nonce, data, streamAAD(index, true))
+			if err != nil {
+				return fmt.Errorf("解密失败: %w", err)
+			}
+			final = true
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return fmt.Errorf("写入明文失败: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// newStreamGCM 使用派生密钥创建GCM
+func newStreamGCM(key string) (cipher.AEAD, error) {
+	k, err := deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("创建密码器失败: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建GCM失败: %w", err)
+	}
+	return gcm, nil
+}
+
+// streamAAD 分块附加数据：8字节序号 + 1字节末块标记
+func streamAAD(index uint64, final bool) []byte {
+	aad := make([]byte, 9)
+	binary.BigEndian.PutUint64(aad, index)
+	if final {
+		aad[8] = 1
+	}
+	return aad
+}
@@ -5,8 +5,11 @@
 // # 工具模块
 //
 //   - crypto: 加密工具（AES 加解密）
+//   - fileutil: 文件工具（原子写入、临时文件清理）
 //   - hash: 哈希工具（MD5/SHA256）
+//   - imageutil: 图片工具（缩放、重新编码）
 //   - logger: 日志工具（zap 封装）
+//   - medianame: 影视文件名解析（标题、年份、季集、分辨率）
 //   - path: 路径工具（路径规范化）
 //   - requestid: 请求 ID 生成
 //
@@ -0,0 +1,234 @@
+// Package fileutil 提供原子文件写入工具
+//
+// 写入先落到同目录下的隐藏临时文件，完成后再重命名为目标文件，
+// 进程崩溃或磁盘写满时目标路径要么是旧内容，要么是完整的新内容，
+// 不会留下被媒体服务器刮削的半截文件。
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// TempPrefix 临时文件名前缀（以点开头，媒体服务器扫描时会忽略隐藏文件）
+	TempPrefix = ".strmsync-"
+	// TempSuffix 临时文件名后缀
+	TempSuffix = ".tmp"
+	// PartSuffix 可续传部分文件的后缀（见 WriteFileResumable）
+	PartSuffix = ".part"
+
+	// maxTempBaseLen 临时文件名中保留的目标文件名最大字节数，避免超出文件名长度限制
+	maxTempBaseLen = 64
+	// partMaxAge 可续传部分文件的保留时间
+	partMaxAge = 7 * 24 * time.Hour
+)
+
+// ErrModTime 文件内容已写入，但设置修改时间失败
+//
+// 调用方可通过 errors.Is 判断并按警告处理。
+var ErrModTime = errors.New("设置文件修改时间失败")
+
+// WriteOptions 原子写入选项
+type WriteOptions struct {
+	Perm    os.FileMode // 文件权限（默认 0o644）
+	DirPerm os.FileMode // 创建父目录时使用的权限（默认 0o755）
+	Sync    bool        // 重命名前 fsync 文件，重命名后 fsync 父目录
+	ModTime time.Time   // 文件修改时间（零值表示不设置）
+}
+
+// WriteFile 原子写入数据到 path
+func WriteFile(path string, data []byte, opts WriteOptions) error {
+	_, err := WriteFileFunc(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}, opts)
+	return err
+}
+
+// WriteFileFrom 原子写入 r 的全部内容到 path，返回写入的字节数
+func WriteFileFrom(path string, r io.Reader, opts WriteOptions) (int64, error) {
+	return WriteFileFunc(path, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}, opts)
+}
+
+// WriteFileFunc 原子写入 write 产生的内容到 path，返回写入的字节数
+//
+// 流程：在目标目录创建临时文件 → write → 可选 fsync → 设置权限与修改时间 → 重命名。
+// 重命名前任一步骤失败都会删除临时文件，目标文件保持不变。
+// 仅设置修改时间失败时文件仍会提交，返回包装 ErrModTime 的错误。
+func WriteFileFunc(path string, write func(w io.Writer) error, opts WriteOptions) (int64, error) {
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0o644
+	}
+	dirPerm := opts.DirPerm
+	if dirPerm == 0 {
+		dirPerm = 0o755
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return 0, fmt.Errorf("create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, TempPrefix+tempBase(filepath.Base(path))+"-*"+TempSuffix)
+	if err != nil {
+		return 0, fmt.Errorf("create temp file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return 0, fmt.Errorf("write temp file %s: %w", tmpPath, err)
+	}
+	size, err := commitTemp(tmp, path, perm, opts)
+	if err != nil && !errors.Is(err, ErrModTime) {
+		return 0, err
+	}
+	committed = true
+	return size, err
+}
+
+// commitTemp 提交写入完成的临时文件：可选 fsync → 设置权限与修改时间 → 重命名为 path
+//
+// 仅设置修改时间失败时文件仍会提交，返回包装 ErrModTime 的错误；
+// 其他错误返回时临时文件未被重命名（可能已关闭），由调用方决定是否删除。
+func commitTemp(tmp *os.File, path string, perm os.FileMode, opts WriteOptions) (int64, error) {
+	tmpPath := tmp.Name()
+	info, err := tmp.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat temp file %s: %w", tmpPath, err)
+	}
+	if opts.Sync {
+		if err := tmp.Sync(); err != nil {
+			return 0, fmt.Errorf("sync temp file %s: %w", tmpPath, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("close temp file %s: %w", tmpPath, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return 0, fmt.Errorf("chmod temp file %s: %w", tmpPath, err)
+	}
+
+	// 在重命名前设置修改时间，目标文件出现时即带有正确的时间
+	var modTimeErr error
+	if !opts.ModTime.IsZero() {
+		if err := os.Chtimes(tmpPath, opts.ModTime, opts.ModTime); err != nil {
+			modTimeErr = err
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, fmt.Errorf("rename %s -> %s: %w", tmpPath, path, err)
+	}
+
+	if opts.Sync {
+		// 部分文件系统不支持对目录 fsync，失败时忽略
+		_ = syncDir(filepath.Dir(path))
+	}
+	if modTimeErr != nil {
+		return info.Size(), fmt.Errorf("%w: %s: %v", ErrModTime, path, modTimeErr)
+	}
+	return info.Size(), nil
+}
+
+// IsTempFile 判断文件名是否为原子写入产生的临时文件（包括可续传的部分文件）
+func IsTempFile(name string) bool {
+	return strings.HasPrefix(name, TempPrefix) && (strings.HasSuffix(name, TempSuffix) || strings.HasSuffix(name, PartSuffix))
+}
+
+// isPartFile 判断文件名是否为可续传的部分文件
+func isPartFile(name string) bool {
+	return strings.HasPrefix(name, TempPrefix) && strings.HasSuffix(name, PartSuffix)
+}
+
+// CleanupTempFiles 删除 root 下修改时间早于 olderThan 之前的临时文件
+//
+// 用于清理进程崩溃遗留的临时文件；olderThan 用于避开其他实例正在写入的文件。
+// 可续传的部分文件保留到 partMaxAge 之后，以便重启后继续下载。
+// root 不存在时直接返回。单个文件删除失败不会中断遍历，返回第一个错误。
+func CleanupTempFiles(root string, olderThan time.Duration) (int, error) {
+	// 目标目录可能是指向实际目录的符号链接（暂存同步的 symlink 切换方式）
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	cutoff := time.Now().Add(-olderThan)
+	partCutoff := time.Now().Add(-partMaxAge)
+	removed := 0
+	var firstErr error
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || !IsTempFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if isPartFile(d.Name()) && info.ModTime().After(partCutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("remove temp file %s: %w", path, err)
+			}
+			return nil
+		}
+		removed++
+		return nil
+	})
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return removed, firstErr
+}
+
+// tempBase 截断过长的文件名（按 UTF-8 字符边界）
+func tempBase(name string) string {
+	if len(name) <= maxTempBaseLen {
+		return name
+	}
+	cut := maxTempBaseLen
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut]
+}
+
+// syncDir fsync 目录，确保重命名写入磁盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,128 @@
+package fileutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFile_ReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "movies", "Movie (2024).strm")
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := WriteFile(target, []byte("http://a"), WriteOptions{Sync: true, ModTime: modTime}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := WriteFile(target, []byte("http://b"), WriteOptions{ModTime: modTime}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "http://b" {
+		t.Fatalf("unexpected content %q err=%v", data, err)
+	}
+	info, _ := os.Stat(target)
+	if !info.ModTime().Equal(modTime) {
+		t.Fatalf("expected modtime %v, got %v", modTime, info.ModTime())
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Fatalf("expected 0644, got %v", info.Mode().Perm())
+	}
+	assertNoTempFiles(t, filepath.Dir(target))
+}
+
+func TestWriteFileFunc_RollbackKeepsOldContent(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "poster.jpg")
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := WriteFileFunc(target, func(w io.Writer) error {
+		_, _ = w.Write([]byte("half"))
+		return errors.New("disk full")
+	}, WriteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected write error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(target)
+	if string(data) != "old" {
+		t.Fatalf("expected old content kept, got %q", data)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileFrom_ReturnsSize(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "movie.nfo")
+	n, err := WriteFileFrom(target, strings.NewReader("<movie/>"), WriteOptions{})
+	if err != nil || n != int64(len("<movie/>")) {
+		t.Fatalf("unexpected result n=%d err=%v", n, err)
+	}
+}
+
+func TestCleanupTempFiles(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "tv", "Show")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(sub, TempPrefix+"S01E01.strm-123"+TempSuffix)
+	fresh := filepath.Join(sub, TempPrefix+"S01E02.strm-456"+TempSuffix)
+	keep := filepath.Join(sub, "S01E01.strm.tmp")
+	for _, p := range []string{stale, fresh, keep} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(stale, old, old)
+	_ = os.Chtimes(keep, old, old)
+
+	removed, err := CleanupTempFiles(root, 10*time.Minute)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 removed, got %d err=%v", removed, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale temp file removed")
+	}
+	for _, p := range []string{fresh, keep} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected %s kept: %v", p, err)
+		}
+	}
+
+	if removed, err := CleanupTempFiles(filepath.Join(root, "missing"), 0); err != nil || removed != 0 {
+		t.Fatalf("expected missing root to be ignored, got %d err=%v", removed, err)
+	}
+}
+
+func TestTempBase_TruncatesOnRuneBoundary(t *testing.T) {
+	name := strings.Repeat("电影", 20) + ".strm"
+	base := tempBase(name)
+	if len(base) > maxTempBaseLen || !strings.HasPrefix(name, base) {
+		t.Fatalf("unexpected base %q", base)
+	}
+	if !strings.HasSuffix(base, "电") && !strings.HasSuffix(base, "影") {
+		t.Fatalf("expected base to end on a rune boundary, got %q", base)
+	}
+}
+
+// assertNoTempFiles 确认目录中没有遗留临时文件
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if IsTempFile(entry.Name()) {
+			t.Fatalf("unexpected temp file left behind: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package fileutil
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// PartPath 返回 path 的可续传部分文件路径
+//
+// version 标识源文件版本（如大小与修改时间），源文件变化后不会续传旧版本的部分文件。
+func PartPath(path, version string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(version))
+	name := fmt.Sprintf("%s%s-%08x%s", TempPrefix, tempBase(filepath.Base(path)), h.Sum32(), PartSuffix)
+	return filepath.Join(filepath.Dir(path), name)
+}
+
+// WriteFileResumable 可断点续传的原子写入，返回本次调用写入的字节数
+//
+// 内容追加到同目录的部分文件（见 PartPath）：write 失败时保留部分文件，
+// 下次调用以已写入的字节数作为 offset 继续；全部写入后与 WriteFileFunc 相同地提交到 path。
+// size > 0 时校验最终大小：部分文件超过 size 时从头写入，写入完成后大小不符则删除部分文件并返回错误。
+func WriteFileResumable(path, version string, size int64, write func(w io.Writer, offset int64) error, opts WriteOptions) (int64, error) {
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0o644
+	}
+	dirPerm := opts.DirPerm
+	if dirPerm == 0 {
+		dirPerm = 0o755
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return 0, fmt.Errorf("create directory %s: %w", dir, err)
+	}
+
+	partPath := PartPath(path, version)
+	part, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("open partial file %s: %w", partPath, err)
+	}
+	// commitTemp 成功时已关闭文件，重复关闭的错误可忽略
+	defer part.Close()
+
+	info, err := part.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat partial file %s: %w", partPath, err)
+	}
+	offset := info.Size()
+	if size > 0 && offset > size {
+		if err := part.Truncate(0); err != nil {
+			return 0, fmt.Errorf("truncate partial file %s: %w", partPath, err)
+		}
+		offset = 0
+	}
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek partial file %s: %w", partPath, err)
+	}
+
+	counter := &countingWriter{w: part}
+	if size <= 0 || offset < size {
+		if err := write(counter, offset); err != nil {
+			return counter.n, fmt.Errorf("write partial file %s: %w", partPath, err)
+		}
+	}
+	if total := offset + counter.n; size > 0 && total != size {
+		_ = part.Close()
+		_ = os.Remove(partPath)
+		return counter.n, fmt.Errorf("partial file %s size mismatch: got %d, want %d", partPath, total, size)
+	}
+
+	_, err = commitTemp(part, path, perm, opts)
+	return counter.n, err
+}
+
+// countingWriter 统计写入字节数的 writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,90 @@
+package fileutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileResumable_ResumesFromPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "extras", "trailer.mkv")
+	content := "0123456789abcdef"
+	modTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	version := "16@1709251200"
+
+	// 首次下载写入一半后中断：保留部分文件，目标文件不存在
+	n, err := WriteFileResumable(target, version, int64(len(content)), func(w io.Writer, offset int64) error {
+		if offset != 0 {
+			t.Fatalf("expected offset 0, got %d", offset)
+		}
+		_, _ = io.WriteString(w, content[:6])
+		return errors.New("connection reset")
+	}, WriteOptions{ModTime: modTime})
+	if err == nil || n != 6 {
+		t.Fatalf("expected interrupted write of 6 bytes, got n=%d err=%v", n, err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected target not created, got %v", err)
+	}
+	if _, err := os.Stat(PartPath(target, version)); err != nil {
+		t.Fatalf("expected partial file kept: %v", err)
+	}
+
+	// 再次下载从断点继续
+	n, err = WriteFileResumable(target, version, int64(len(content)), func(w io.Writer, offset int64) error {
+		_, err := io.WriteString(w, content[offset:])
+		return err
+	}, WriteOptions{ModTime: modTime})
+	if err != nil || n != int64(len(content)-6) {
+		t.Fatalf("expected resumed write of %d bytes, got n=%d err=%v", len(content)-6, n, err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != content {
+		t.Fatalf("unexpected content %q", data)
+	}
+	info, _ := os.Stat(target)
+	if !info.ModTime().Equal(modTime) || info.Mode().Perm() != 0o644 {
+		t.Fatalf("unexpected file info mtime=%v perm=%v", info.ModTime(), info.Mode().Perm())
+	}
+	assertNoTempFiles(t, filepath.Dir(target))
+}
+
+func TestWriteFileResumable_SizeMismatchDiscardsPartialFile(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "fanart.jpg")
+	_, err := WriteFileResumable(target, "v1", 10, func(w io.Writer, offset int64) error {
+		_, err := io.WriteString(w, "short")
+		return err
+	}, WriteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "size mismatch") {
+		t.Fatalf("expected size mismatch, got %v", err)
+	}
+	assertNoTempFiles(t, filepath.Dir(target))
+}
+
+func TestCleanupTempFiles_KeepsRecentPartialFiles(t *testing.T) {
+	root := t.TempDir()
+	recent := PartPath(filepath.Join(root, "a.mkv"), "v1")
+	expired := PartPath(filepath.Join(root, "b.mkv"), "v1")
+	for _, p := range []string{recent, expired} {
+		if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(recent, hourAgo, hourAgo)
+	longAgo := time.Now().Add(-partMaxAge - time.Hour)
+	_ = os.Chtimes(expired, longAgo, longAgo)
+
+	removed, err := CleanupTempFiles(root, 10*time.Minute)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 removed, got %d err=%v", removed, err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Fatalf("expected recent partial file kept: %v", err)
+	}
+}
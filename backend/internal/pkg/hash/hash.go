@@ -61,6 +61,49 @@ func FastFileHash(path string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// FullFileHash 计算完整文件内容的 SHA-256 哈希
+//
+// 适用于元数据等小文件：任何字节变化都会反映到哈希中。
+func FullFileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FileHash 按文件大小选择哈希方式，返回带前缀的哈希值
+//
+// 文件大小不超过 fullMaxSize 时计算完整哈希（前缀 "full:"），
+// 否则计算快速哈希（前缀 "fast:"）。fullMaxSize <= 0 表示始终使用快速哈希。
+// 前缀用于区分两种哈希，避免切换方式后误判为相同。
+func FileHash(path string, fullMaxSize int64) (string, error) {
+	if fullMaxSize > 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() <= fullMaxSize {
+			sum, err := FullFileHash(path)
+			if err != nil {
+				return "", err
+			}
+			return "full:" + sum, nil
+		}
+	}
+	sum, err := FastFileHash(path)
+	if err != nil {
+		return "", err
+	}
+	return "fast:" + sum, nil
+}
+
 func writeChunk(w io.Writer, r io.Reader, n int64) error {
 	if _, err := io.CopyN(w, r, n); err != nil && err != io.EOF {
 		return err
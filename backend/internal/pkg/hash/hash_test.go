@@ -0,0 +1,44 @@
+package hash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileHash_FullAndFast(t *testing.T) {
+	dir := t.TempDir()
+	// 3MB 文件：中间部分的修改只能被完整哈希发现
+	data := bytes.Repeat([]byte("a"), 3<<20)
+	file := filepath.Join(dir, "fanart.jpg")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fast1, err := FileHash(file, 0)
+	if err != nil {
+		t.Fatalf("FileHash: %v", err)
+	}
+	full1, err := FileHash(file, 4<<20)
+	if err != nil {
+		t.Fatalf("FileHash: %v", err)
+	}
+	if !strings.HasPrefix(fast1, "fast:") || !strings.HasPrefix(full1, "full:") {
+		t.Fatalf("unexpected prefixes: %s %s", fast1, full1)
+	}
+
+	data[len(data)/2] = 'b'
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fast2, _ := FileHash(file, 0)
+	full2, _ := FileHash(file, 4<<20)
+	if fast1 != fast2 {
+		t.Errorf("fast hash should ignore middle bytes")
+	}
+	if full1 == full2 {
+		t.Errorf("full hash should detect middle change")
+	}
+}
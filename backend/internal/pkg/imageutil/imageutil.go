@@ -0,0 +1,192 @@
+// Package imageutil 提供图片缩放与重新编码工具（仅依赖标准库）
+//
+// 支持 JPEG 与 PNG：JPEG 按指定质量重新编码，PNG 保持 PNG 格式以保留透明通道。
+// 标准库没有 WebP/GIF 等格式的编码器，这些格式返回 ErrUnsupportedFormat，由调用方原样保留。
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+const (
+	// DefaultJPEGQuality 缩放后重新编码 JPEG 的默认质量
+	DefaultJPEGQuality = 85
+	// maxPixels 允许解码的最大像素数，避免超大图片占用过多内存
+	maxPixels = 64 << 20
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的图片格式
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrTooLarge 图片像素数超过解码上限
+	ErrTooLarge = errors.New("image too large to decode")
+)
+
+// Options 图片处理选项
+type Options struct {
+	MaxWidth    int // 最大宽度（0 表示不限制）
+	MaxHeight   int // 最大高度（0 表示不限制）
+	JPEGQuality int // JPEG 重新编码质量（1-100，0 表示仅在缩放时以默认质量编码）
+}
+
+// Validate 校验选项
+func (o Options) Validate() error {
+	if o.MaxWidth < 0 || o.MaxHeight < 0 {
+		return errors.New("max width/height must not be negative")
+	}
+	if o.JPEGQuality < 0 || o.JPEGQuality > 100 {
+		return errors.New("jpeg quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Fingerprint 返回选项指纹，选项变化后已处理的图片需要重新处理
+func (o Options) Fingerprint() string {
+	return fmt.Sprintf("w%d-h%d-q%d", o.MaxWidth, o.MaxHeight, o.JPEGQuality)
+}
+
+// Process 按选项处理图片，返回处理后的内容以及内容是否发生变化
+//
+// 图片在最大宽高内且无需重新编码，或重新编码后体积没有减小时，返回原始内容。
+func Process(data []byte, opts Options) ([]byte, bool, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, false, ErrUnsupportedFormat
+		}
+		return nil, false, fmt.Errorf("decode image config: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, false, ErrTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	width, height, resized := fitSize(bounds.Dx(), bounds.Dy(), opts.MaxWidth, opts.MaxHeight)
+	if resized {
+		img = resize(img, width, height)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		if !resized && opts.JPEGQuality <= 0 {
+			return data, false, nil
+		}
+		quality := opts.JPEGQuality
+		if quality <= 0 {
+			quality = DefaultJPEGQuality
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, false, fmt.Errorf("encode jpeg: %w", err)
+		}
+	case "png":
+		if !resized {
+			return data, false, nil
+		}
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(&buf, img); err != nil {
+			return nil, false, fmt.Errorf("encode png: %w", err)
+		}
+	default:
+		return nil, false, ErrUnsupportedFormat
+	}
+
+	if !resized && buf.Len() >= len(data) {
+		return data, false, nil
+	}
+	return buf.Bytes(), true, nil
+}
+
+// fitSize 计算等比缩放到最大宽高以内的尺寸（不放大）
+func fitSize(width, height, maxWidth, maxHeight int) (int, int, bool) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = math.Min(scale, float64(maxWidth)/float64(width))
+	}
+	if maxHeight > 0 && height > maxHeight {
+		scale = math.Min(scale, float64(maxHeight)/float64(height))
+	}
+	if scale >= 1 {
+		return width, height, false
+	}
+	w := int(math.Round(float64(width) * scale))
+	h := int(math.Round(float64(height) * scale))
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h, true
+}
+
+// resize 使用区域平均（box filter）缩小图片
+//
+// 每个目标像素取其覆盖的源像素区域的平均值（预乘 alpha），适合大比例缩小。
+func resize(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	xs := spans(srcW, width)
+	ys := spans(srcH, height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sums := make([]uint64, 4*width)
+
+	for y := 0; y < height; y++ {
+		for i := range sums {
+			sums[i] = 0
+		}
+		for sy := ys[y]; sy < ys[y+1]; sy++ {
+			row := rgba.Pix[sy*rgba.Stride:]
+			for x := 0; x < width; x++ {
+				s := sums[4*x : 4*x+4]
+				for sx := xs[x]; sx < xs[x+1]; sx++ {
+					p := row[4*sx : 4*sx+4]
+					s[0] += uint64(p[0])
+					s[1] += uint64(p[1])
+					s[2] += uint64(p[2])
+					s[3] += uint64(p[3])
+				}
+			}
+		}
+		rows := uint64(ys[y+1] - ys[y])
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < width; x++ {
+			n := rows * uint64(xs[x+1]-xs[x])
+			for c := 0; c < 4; c++ {
+				out[4*x+c] = uint8((sums[4*x+c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// spans 将 src 个源像素划分为 dst 段，返回 dst+1 个边界（每段至少一个像素）
+func spans(src, dst int) []int {
+	bounds := make([]int, dst+1)
+	for i := 1; i <= dst; i++ {
+		bounds[i] = i * src / dst
+		if bounds[i] <= bounds[i-1] {
+			bounds[i] = bounds[i-1] + 1
+		}
+	}
+	bounds[dst] = src
+	return bounds
+}
@@ -0,0 +1,93 @@
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess_ResizePNGKeepsFormatAndAlpha(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 128})
+		}
+	}
+
+	out, changed, err := Process(encodePNG(t, src), Options{MaxWidth: 100, MaxHeight: 100})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !changed {
+		t.Fatal("图片应被缩放")
+	}
+	img, format, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" {
+		t.Fatalf("format = %s, want png", format)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("size = %dx%d, want 100x50", b.Dx(), b.Dy())
+	}
+	if _, _, _, a := img.At(50, 25).RGBA(); a>>8 < 120 || a>>8 > 136 {
+		t.Fatalf("alpha = %d, want ~128", a>>8)
+	}
+}
+
+func TestProcess_JPEGQualityAndPassthrough(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x ^ y) * 4), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// 尺寸在范围内且未指定质量：原样返回
+	out, changed, err := Process(data, Options{MaxWidth: 128})
+	if err != nil || changed || !bytes.Equal(out, data) {
+		t.Fatalf("应原样返回: changed=%v err=%v", changed, err)
+	}
+
+	// 指定质量：重新编码且体积减小
+	out, changed, err = Process(data, Options{JPEGQuality: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(out) >= len(data) {
+		t.Fatalf("应重新编码并减小体积: changed=%v %d -> %d", changed, len(data), len(out))
+	}
+
+	// PNG 未缩放时不重新编码
+	pngData := encodePNG(t, src)
+	out, changed, err = Process(pngData, Options{MaxWidth: 64, JPEGQuality: 50})
+	if err != nil || changed || !bytes.Equal(out, pngData) {
+		t.Fatalf("PNG 应原样返回: changed=%v err=%v", changed, err)
+	}
+}
+
+func TestProcess_UnsupportedFormat(t *testing.T) {
+	_, _, err := Process([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), Options{MaxWidth: 10})
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("err = %v, want ErrUnsupportedFormat", err)
+	}
+}
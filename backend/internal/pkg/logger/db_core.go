@@ -68,7 +68,7 @@ func (c *dbCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 		allFields = append(allFields, fields...)
 	}
 
-	module, requestID, userAction, jobID := extractLogMeta(entry, allFields)
+	module, requestID, userAction, jobID, runID := extractLogMeta(entry, allFields)
 	message := strings.TrimSpace(entry.Message)
 	if message == "" {
 		message = entry.Level.String()
@@ -81,6 +81,7 @@ func (c *dbCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 		RequestID:  requestID,
 		UserAction: userAction,
 		JobID:      jobID,
+		RunID:      runID,
 		CreatedAt:  entry.Time,
 	})
 
@@ -121,12 +122,15 @@ func AttachDBWriter(db *gorm.DB, level string) error {
 	return nil
 }
 
-func extractLogMeta(entry zapcore.Entry, fields []zapcore.Field) (*string, *string, *string, *uint) {
+// extractLogMeta 从日志字段中提取可索引的元数据
+// 执行记录ID 兼容 worker 使用的 task_id 与 sync 执行器使用的 task_run_id
+func extractLogMeta(entry zapcore.Entry, fields []zapcore.Field) (*string, *string, *string, *uint, *uint) {
 	var (
 		module     *string
 		requestID  *string
 		userAction *string
 		jobID      *uint
+		runID      *uint
 	)
 
 	for _, field := range fields {
@@ -159,6 +163,13 @@ func extractLogMeta(entry zapcore.Entry, fields []zapcore.Field) (*string, *stri
 					jobID = &v
 				}
 			}
+		case "run_id", "task_id", "task_run_id":
+			if runID == nil {
+				if value, ok := readUintField(field); ok {
+					v := value
+					runID = &v
+				}
+			}
 		}
 	}
 
@@ -168,7 +179,7 @@ func extractLogMeta(entry zapcore.Entry, fields []zapcore.Field) (*string, *stri
 		}
 	}
 
-	return module, requestID, userAction, jobID
+	return module, requestID, userAction, jobID, runID
 }
 
 func readStringField(field zapcore.Field) string {
@@ -15,11 +15,13 @@ import (
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var (
-	mu       sync.RWMutex
-	instance *zap.Logger
+	mu        sync.RWMutex
+	instance  *zap.Logger
+	fileLevel = zapcore.InfoLevel // 日志文件级别（执行记录日志沿用）
 
 	// 数据库日志写入相关
 	logDBMu      sync.Mutex
@@ -47,12 +49,7 @@ func InitLogger(level string, dir string, rotate RotateConfig) error {
 		return fmt.Errorf("创建日志目录失败: %w", err)
 	}
 
-	encoderCfg := zap.NewProductionEncoderConfig()
-	encoderCfg.TimeKey = "ts"
-	encoderCfg.CallerKey = "caller"
-	encoderCfg.EncodeCaller = zapcore.ShortCallerEncoder
-	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoderCfg.EncodeLevel = zapcore.LowercaseLevelEncoder // 统一使用小写level
+	encoderCfg := fileEncoderConfig()
 
 	// 控制台输出：更适合人类阅读的格式
 	consoleCfg := zap.NewProductionEncoderConfig()
@@ -92,11 +89,23 @@ func InitLogger(level string, dir string, rotate RotateConfig) error {
 
 	mu.Lock()
 	instance = l
+	fileLevel = parsed
 	mu.Unlock()
 
 	return nil
 }
 
+// fileEncoderConfig 返回日志文件使用的 JSON 编码配置
+func fileEncoderConfig() zapcore.EncoderConfig {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "ts"
+	encoderCfg.CallerKey = "caller"
+	encoderCfg.EncodeCaller = zapcore.ShortCallerEncoder
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderCfg.EncodeLevel = zapcore.LowercaseLevelEncoder // 统一使用小写level
+	return encoderCfg
+}
+
 // RotateConfig 日志分割与压缩配置
 type RotateConfig struct {
 	MaxSizeMB  int
@@ -233,12 +242,14 @@ func WriteLogToDB(db *gorm.DB, entry *model.LogEntry) {
 	// 初始化异步写入worker（只执行一次）
 	logDBOnce.Do(func() {
 		logDBChan = make(chan *model.LogEntry, logDBBuffer)
+		// 写日志的 SQL 不再记录日志，避免开启 SQL 日志时循环写入
+		silent := db.Session(&gorm.Session{Logger: gormlogger.Discard})
 		go func() {
 			for e := range logDBChan {
 				if e == nil {
 					continue
 				}
-				if err := db.Create(e).Error; err != nil {
+				if err := silent.Create(e).Error; err != nil {
 					logDBWarn("写入日志到数据库失败", err)
 				}
 			}
@@ -0,0 +1,96 @@
+// Package logger 提供单次执行（TaskRun）的独立日志捕获
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RunLogFile 执行记录日志文件名
+const RunLogFile = "run.log"
+
+// RunLogDir 返回执行记录的日志目录：<root>/<runID>
+func RunLogDir(root string, runID uint) string {
+	return filepath.Join(root, strconv.FormatUint(uint64(runID), 10))
+}
+
+// OpenRunLog 为单次执行打开独立日志
+//
+// 返回的日志器在 base 的基础上附加 task_run_id 字段，并把日志同时写入
+// <root>/<runID>/run.log（JSON 行格式，与 app.log 一致）。重试会追加到同一文件。
+// close 关闭日志文件，之后通过该日志器写入的内容只进入 base。
+func OpenRunLog(base *zap.Logger, root string, runID uint) (*zap.Logger, func() error, error) {
+	if base == nil {
+		return nil, nil, errors.New("日志器为空")
+	}
+	if strings.TrimSpace(root) == "" {
+		return nil, nil, errors.New("执行记录日志目录为空")
+	}
+
+	dir := RunLogDir(root, runID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("创建执行记录日志目录失败: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, RunLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开执行记录日志失败: %w", err)
+	}
+
+	mu.RLock()
+	level := fileLevel
+	mu.RUnlock()
+
+	sink := &runLogSink{file: file}
+	runCore := zapcore.NewCore(zapcore.NewJSONEncoder(fileEncoderConfig()), sink, level)
+	l := base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, runCore)
+	})).With(zap.Uint("task_run_id", runID))
+
+	return l, sink.Close, nil
+}
+
+// runLogSink 执行记录日志文件，关闭后丢弃写入
+//
+// 驱动等组件可能在执行结束后仍持有日志器（如后台刷新），关闭后静默丢弃避免写入错误。
+type runLogSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (s *runLogSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return len(p), nil
+	}
+	return s.file.Write(p)
+}
+
+func (s *runLogSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+// Close 关闭日志文件（可重复调用）
+func (s *runLogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
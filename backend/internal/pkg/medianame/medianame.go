@@ -0,0 +1,229 @@
+// Package medianame 从影视文件名解析标题、年份、季集、分辨率与分段
+//
+// 解析基于常见的发布命名习惯（Title.2019.1080p.BluRay.x264、Show.S01E02.720p 等），
+// 只依赖文件名与上级目录名，不访问外部刮削服务。
+package medianame
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Info 文件名解析结果
+type Info struct {
+	Title      string // 标题（已去掉年份与发布标签）
+	Year       int    // 年份（0 表示未识别）
+	Season     int    // 季号（仅剧集）
+	Episode    int    // 集号（0 表示不是剧集）
+	Resolution string // 分辨率（如 2160p、1080p，空表示未识别）
+	Part       int    // 分段序号（cd1、part2 等，0 表示不是分段文件）
+}
+
+// IsEpisode 判断是否识别为剧集
+func (i Info) IsEpisode() bool {
+	return i.Episode > 0
+}
+
+var (
+	// 开头的发布组标签：[Group] / 【字幕组】
+	leadingGroupRe = regexp.MustCompile(`^\s*(?:\[[^\]]*\]|【[^】]*】)\s*`)
+	// 分隔符统一为空格
+	separatorRe = regexp.MustCompile(`[._\s]+`)
+	spaceRe     = regexp.MustCompile(`\s{2,}`)
+
+	// S01E02 / S1 E2 / 1x02 / 第2集
+	episodeRe   = regexp.MustCompile(`(?i)\bS(\d{1,2})\s?E(\d{1,3})\b`)
+	crossEpRe   = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	chineseEpRe = regexp.MustCompile(`第\s*(\d{1,3})\s*[集话話]`)
+
+	yearRe       = regexp.MustCompile(`[(\[]?\b((?:19|20)\d{2})\b[)\]]?`)
+	resolutionRe = regexp.MustCompile(`(?i)\b(2160p|1080p|1080i|720p|576p|480p|4k|uhd)\b`)
+	releaseTagRe = regexp.MustCompile(`(?i)\b(blu-?ray|bdrip|brrip|web-?dl|webrip|hdtv|dvdrip|remux|x26[45]|h ?26[45]|hevc|avc|hdr(?:10)?|dovi|dts(?:-hd)?|truehd|aac|ac3|atmos|10bit|proper|repack|extended|unrated)\b`)
+
+	// 分段：cd1 / part2 / disc1 / pt1 / dvd2（文件名或目录名）
+	partRe    = regexp.MustCompile(`(?i)\b(?:cd|dvd|part|pt|disc|disk)\s?(\d{1,2})\b`)
+	partDirRe = regexp.MustCompile(`(?i)^(?:cd|dvd|part|pt|disc|disk)\s?(\d{1,2})$`)
+
+	// 季目录：Season 01 / S01 / 第1季 / Specials
+	seasonDirRe = regexp.MustCompile(`(?i)^(?:season\s*(\d{1,2})|s(\d{1,2})|第\s*(\d{1,2})\s*季|specials?)$`)
+)
+
+// unknownSeason 文件名只有集号（第2集）时的季号占位，解析结束时默认为第 1 季
+const unknownSeason = -1
+
+// Parse 解析文件名（含扩展名）
+func Parse(name string) Info {
+	info := parseStem(strings.TrimSuffix(name, path.Ext(name)))
+	if info.Season == unknownSeason {
+		info.Season = 1
+	}
+	return info
+}
+
+// ParsePath 解析远端路径，文件名信息不足时参考上级目录
+//
+// 规则：
+//   - 分段目录（CD1、Disc 2）提供缺失的分段序号，之后以其上级目录为准
+//   - 剧集：季目录（Season 01、S01、第1季）提供缺失的季号，剧集目录提供缺失的标题与年份
+//   - 电影：文件名没有年份时，若上级目录名带年份（Title (2019)/xxx.mkv），使用目录的标题与年份
+func ParsePath(p string) Info {
+	name := path.Base(p)
+	info := parseStem(strings.TrimSuffix(name, path.Ext(name)))
+	dir := path.Dir(p)
+
+	if part, ok := ParsePartDir(path.Base(dir)); ok {
+		if info.Part == 0 {
+			info.Part = part
+		}
+		dir = path.Dir(dir)
+	}
+
+	if info.IsEpisode() {
+		if season, ok := parseSeasonDir(path.Base(dir)); ok {
+			if info.Season == unknownSeason {
+				info.Season = season
+			}
+			dir = path.Dir(dir)
+		}
+		if info.Season == unknownSeason {
+			info.Season = 1
+		}
+		if info.Title == "" && !isRootDir(dir) {
+			show := parseStem(path.Base(dir))
+			info.Title = show.Title
+			if info.Year == 0 {
+				info.Year = show.Year
+			}
+		}
+		return info
+	}
+
+	if info.Year == 0 && !isRootDir(dir) {
+		if parent := parseStem(path.Base(dir)); parent.Year > 0 && parent.Title != "" {
+			info.Title = parent.Title
+			info.Year = parent.Year
+		}
+	}
+	return info
+}
+
+// parseStem 解析去掉扩展名的名称
+func parseStem(stem string) Info {
+	s := leadingGroupRe.ReplaceAllString(stem, "")
+	s = separatorRe.ReplaceAllString(s, " ")
+
+	var info Info
+	cut := len(s)
+	cutAt := func(idx int) {
+		if idx < cut {
+			cut = idx
+		}
+	}
+
+	switch {
+	case episodeRe.MatchString(s):
+		m := episodeRe.FindStringSubmatchIndex(s)
+		info.Season = atoi(s[m[2]:m[3]])
+		info.Episode = atoi(s[m[4]:m[5]])
+		cutAt(m[0])
+	case crossEpRe.MatchString(s):
+		m := crossEpRe.FindStringSubmatchIndex(s)
+		info.Season = atoi(s[m[2]:m[3]])
+		info.Episode = atoi(s[m[4]:m[5]])
+		cutAt(m[0])
+	case chineseEpRe.MatchString(s):
+		m := chineseEpRe.FindStringSubmatchIndex(s)
+		info.Season = unknownSeason
+		info.Episode = atoi(s[m[2]:m[3]])
+		cutAt(m[0])
+	}
+
+	if m := resolutionRe.FindStringSubmatchIndex(s); m != nil {
+		info.Resolution = normalizeResolution(s[m[2]:m[3]])
+		cutAt(m[0])
+	}
+	if m := releaseTagRe.FindStringIndex(s); m != nil {
+		cutAt(m[0])
+	}
+	// 分段标记需位于发布标签之后或名称末尾，避免误判标题中的 Part 1（如 Deathly Hallows Part 1 2010）
+	if m := partRe.FindStringSubmatchIndex(s); m != nil && (m[0] >= cut || strings.TrimSpace(s[m[1]:]) == "") {
+		info.Part = atoi(s[m[2]:m[3]])
+		cutAt(m[0])
+	}
+
+	// 年份取标签之前的最后一个（Blade Runner 2049 2017 → 2017），不能位于开头
+	yearAt := -1
+	for _, m := range yearRe.FindAllStringSubmatchIndex(s, -1) {
+		if m[0] == 0 || m[0] >= cut {
+			continue
+		}
+		info.Year = atoi(s[m[2]:m[3]])
+		yearAt = m[0]
+	}
+	if yearAt >= 0 {
+		cut = yearAt
+	}
+
+	info.Title = cleanTitle(s[:cut])
+	if info.Title == "" && !info.IsEpisode() {
+		info.Title = cleanTitle(s)
+	}
+	return info
+}
+
+// IsSeasonDir 判断目录名是否是季目录（Season 01、S01、第1季、Specials）
+func IsSeasonDir(name string) bool {
+	_, ok := parseSeasonDir(name)
+	return ok
+}
+
+// ParsePartDir 解析分段目录名（CD1、Disc 2 等），返回分段序号
+func ParsePartDir(name string) (int, bool) {
+	m := partDirRe.FindStringSubmatch(strings.TrimSpace(name))
+	if m == nil {
+		return 0, false
+	}
+	return atoi(m[1]), true
+}
+
+// parseSeasonDir 解析季目录名
+func parseSeasonDir(name string) (int, bool) {
+	m := seasonDirRe.FindStringSubmatch(strings.TrimSpace(name))
+	if m == nil {
+		return 0, false
+	}
+	for _, group := range m[1:] {
+		if group != "" {
+			return atoi(group), true
+		}
+	}
+	// Specials
+	return 0, true
+}
+
+// normalizeResolution 统一分辨率写法（4K/UHD → 2160p）
+func normalizeResolution(value string) string {
+	value = strings.ToLower(value)
+	switch value {
+	case "4k", "uhd":
+		return "2160p"
+	}
+	return value
+}
+
+// cleanTitle 去掉标题首尾的分隔符与括号
+func cleanTitle(s string) string {
+	s = spaceRe.ReplaceAllString(s, " ")
+	return strings.Trim(s, " -–([{")
+}
+
+func isRootDir(dir string) bool {
+	return dir == "/" || dir == "." || dir == ""
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
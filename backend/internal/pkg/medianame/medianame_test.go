@@ -0,0 +1,49 @@
+package medianame
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		want Info
+	}{
+		{"The.Matrix.1999.1080p.BluRay.x264.mkv", Info{Title: "The Matrix", Year: 1999, Resolution: "1080p"}},
+		{"Blade Runner 2049 (2017) 2160p.mkv", Info{Title: "Blade Runner 2049", Year: 2017, Resolution: "2160p"}},
+		{"[Group] 流浪地球.2019.4K.HDR.mp4", Info{Title: "流浪地球", Year: 2019, Resolution: "2160p"}},
+		{"Breaking.Bad.S01E02.720p.WEB-DL.mkv", Info{Title: "Breaking Bad", Season: 1, Episode: 2, Resolution: "720p"}},
+		{"Doctor Who 2005 - 3x10 - Blink.mkv", Info{Title: "Doctor Who", Year: 2005, Season: 3, Episode: 10}},
+		{"Show.S00E01.mkv", Info{Title: "Show", Season: 0, Episode: 1}},
+		{"三体 第08集.mp4", Info{Title: "三体", Season: 1, Episode: 8}},
+		{"1917.mkv", Info{Title: "1917"}},
+		{"home_video.mp4", Info{Title: "home video"}},
+		{"Movie.2160p.mkv", Info{Title: "Movie", Resolution: "2160p"}},
+		{"Heat.1995.1080p.BluRay.cd2.mkv", Info{Title: "Heat", Year: 1995, Resolution: "1080p", Part: 2}},
+		{"Old Movie (1960) part 1.avi", Info{Title: "Old Movie", Year: 1960, Part: 1}},
+		{"Harry.Potter.and.the.Deathly.Hallows.Part.1.2010.1080p.mkv", Info{Title: "Harry Potter and the Deathly Hallows Part 1", Year: 2010, Resolution: "1080p"}},
+	}
+	for _, tc := range cases {
+		if got := Parse(tc.name); got != tc.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParsePath_UsesParentDirectories(t *testing.T) {
+	cases := []struct {
+		path string
+		want Info
+	}{
+		{"/tv/Breaking Bad (2008)/Season 02/S02E03.mkv", Info{Title: "Breaking Bad", Year: 2008, Season: 2, Episode: 3}},
+		{"/tv/三体/第1季/第08集.mp4", Info{Title: "三体", Season: 1, Episode: 8}},
+		{"/tv/Show/Specials/S00E01.mkv", Info{Title: "Show", Season: 0, Episode: 1}},
+		{"/movies/Inception (2010)/inception-1080p.mkv", Info{Title: "Inception", Year: 2010, Resolution: "1080p"}},
+		{"/movies/Misc/clip.mp4", Info{Title: "clip"}},
+		{"/movies/Heat (1995)/CD2/heat.avi", Info{Title: "Heat", Year: 1995, Part: 2}},
+		{"/S01E01.mkv", Info{Season: 1, Episode: 1}},
+	}
+	for _, tc := range cases {
+		if got := ParsePath(tc.path); got != tc.want {
+			t.Errorf("ParsePath(%q) = %+v, want %+v", tc.path, got, tc.want)
+		}
+	}
+}
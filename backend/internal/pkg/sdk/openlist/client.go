@@ -93,6 +93,7 @@ func NewClient(cfg Config) (*Client, error) {
 
 // FileItem 表示 OpenList 返回的文件/目录项
 type FileItem struct {
+	ID       string    // 存储驱动的对象 ID（部分驱动为空）
 	Name     string    // 文件/目录名称
 	Size     int64     // 文件大小（字节）
 	IsDir    bool      // 是否为目录
@@ -172,13 +173,14 @@ func (c *Client) List(ctx context.Context, listPath string) ([]FileItem, error)
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 	if out.Code != http.StatusOK {
-		return nil, fmt.Errorf("api error: code=%d message=%s", out.Code, out.Message)
+		return nil, apiError(out.Code, out.Message)
 	}
 
 	// 转换为 FileItem
 	var results []FileItem
 	for _, item := range out.Data.Content {
 		results = append(results, FileItem{
+			ID:       item.ID,
 			Name:     item.Name,
 			Size:     item.Size,
 			IsDir:    item.IsDir,
@@ -199,6 +201,13 @@ func (c *Client) List(ctx context.Context, listPath string) ([]FileItem, error)
 // 返回：
 //   - error: 下载失败时返回错误
 func (c *Client) Download(ctx context.Context, filePath string, w io.Writer) error {
+	return c.DownloadRange(ctx, filePath, 0, w)
+}
+
+// DownloadRange 从 offset 处下载文件内容到writer（HTTP Range 请求）
+//
+// 服务端忽略 Range 返回完整内容时，丢弃前 offset 字节后再写入。
+func (c *Client) DownloadRange(ctx context.Context, filePath string, offset int64, w io.Writer) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -229,6 +238,9 @@ func (c *Client) Download(ctx context.Context, filePath string, w io.Writer) err
 	if token != "" {
 		req.Header.Set("Authorization", token)
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	// 执行请求
 	resp, err := c.httpClient.Do(req)
@@ -238,7 +250,17 @@ func (c *Client) Download(ctx context.Context, filePath string, w io.Writer) err
 	defer resp.Body.Close()
 
 	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// 服务端从 offset 处返回内容
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			// 服务端不支持 Range：跳过已下载部分
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				return fmt.Errorf("skip downloaded bytes: %w", err)
+			}
+		}
+	default:
 		// 认证失败：清空 token
 		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
 			c.clearToken()
@@ -368,6 +390,7 @@ type listData struct {
 
 // listItem OpenList 文件/目录项
 type listItem struct {
+	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Size     int64  `json:"size"`
 	IsDir    bool   `json:"is_dir"`
@@ -0,0 +1,168 @@
+package openlist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 文件或目录不存在
+//
+// OpenList 对不存在的路径返回 HTTP 200 + code=500 + "object not found"，
+// 调用方可通过 errors.Is 判断。
+var ErrNotFound = errors.New("openlist: object not found")
+
+// Get 获取文件或目录信息（/api/fs/get）
+//
+// 路径不存在时返回包装 ErrNotFound 的错误。
+func (c *Client) Get(ctx context.Context, filePath string) (FileItem, error) {
+	var data listItem
+	err := c.post(ctx, "/api/fs/get", map[string]any{
+		"path":     cleanPath(filePath),
+		"password": c.password,
+	}, &data)
+	if err != nil {
+		return FileItem{}, err
+	}
+	return FileItem{
+		ID:       data.ID,
+		Name:     data.Name,
+		Size:     data.Size,
+		IsDir:    data.IsDir,
+		Modified: parseTime(data.Modified),
+	}, nil
+}
+
+// Put 上传文件（/api/fs/put），目标已存在时覆盖
+//
+// 参数：
+//   - filePath: 目标文件路径（父目录由服务端自动创建）
+//   - r: 文件内容
+//   - size: 内容长度
+//   - modTime: 修改时间（零值表示不设置；是否生效取决于存储驱动）
+func (c *Client) Put(ctx context.Context, filePath string, r io.Reader, size int64, modTime time.Time) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := c.ensureToken(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.buildAPIPath("/api/fs/put"), r)
+	if err != nil {
+		return fmt.Errorf("create put request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("File-Path", url.PathEscape(cleanPath(filePath)))
+	req.Header.Set("As-Task", "false")
+	if !modTime.IsZero() {
+		req.Header.Set("Last-Modified", strconv.FormatInt(modTime.UnixMilli(), 10))
+	}
+	return c.doJSON(req, nil)
+}
+
+// Mkdir 创建目录（/api/fs/mkdir），会自动创建上级目录，目录已存在不算错误
+func (c *Client) Mkdir(ctx context.Context, dirPath string) error {
+	return c.post(ctx, "/api/fs/mkdir", map[string]any{"path": cleanPath(dirPath)}, nil)
+}
+
+// Remove 删除 dir 下的文件或目录（/api/fs/remove，目录递归删除）
+func (c *Client) Remove(ctx context.Context, dir string, names []string) error {
+	return c.post(ctx, "/api/fs/remove", map[string]any{
+		"dir":   cleanPath(dir),
+		"names": names,
+	}, nil)
+}
+
+// Move 将 srcDir 下的文件或目录移动到 dstDir（/api/fs/move）
+func (c *Client) Move(ctx context.Context, srcDir, dstDir string, names []string) error {
+	return c.post(ctx, "/api/fs/move", map[string]any{
+		"src_dir": cleanPath(srcDir),
+		"dst_dir": cleanPath(dstDir),
+		"names":   names,
+	}, nil)
+}
+
+// Rename 重命名文件或目录（/api/fs/rename）
+func (c *Client) Rename(ctx context.Context, filePath, newName string) error {
+	return c.post(ctx, "/api/fs/rename", map[string]any{
+		"path": cleanPath(filePath),
+		"name": newName,
+	}, nil)
+}
+
+// post 发送 JSON 请求并解析响应中的 data（out 为 nil 时忽略 data）
+func (c *Client) post(ctx context.Context, endpoint string, body any, out any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := c.ensureToken(ctx); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildAPIPath(endpoint), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doJSON(req, out)
+}
+
+// doJSON 添加认证 token、执行请求并解析统一响应格式
+func (c *Client) doJSON(req *http.Request, out any) error {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			c.clearToken()
+			return fmt.Errorf("openlist: unauthorized")
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyLen))
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result response[json.RawMessage]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if result.Code != http.StatusOK {
+		return apiError(result.Code, result.Message)
+	}
+	if out != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("decode response data: %w", err)
+		}
+	}
+	return nil
+}
+
+// apiError 构造 API 错误，路径不存在时包装 ErrNotFound
+func apiError(code int, message string) error {
+	if strings.Contains(strings.ToLower(message), "not found") {
+		return fmt.Errorf("api error: code=%d message=%s: %w", code, message, ErrNotFound)
+	}
+	return fmt.Errorf("api error: code=%d message=%s", code, message)
+}
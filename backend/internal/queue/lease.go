@@ -0,0 +1,189 @@
+package syncqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultLeaseDuration 默认执行租约时长
+//
+// Worker 领取任务后需在租约到期前续期；进程退出或失联的 Worker 持有的任务
+// 最迟在租约到期后被其他 Worker 回收。
+const DefaultLeaseDuration = 30 * time.Second
+
+// interruptedMessage 租约过期回收任务时写入的错误信息
+const interruptedMessage = "Worker 租约过期，任务中断"
+
+// RenewLease 续期执行租约
+//
+// 仅当任务仍处于 Running 且由该 Worker 持有时续期。任务已被取消、已结束
+// 或租约过期被回收时返回 ErrLeaseLost，Worker 应停止执行且不再回写状态。
+// 取消通过数据库传播：Cancel 将任务置为 Cancelled 后，持有任务的 Worker
+// 在下一次续期时即可感知。
+//
+// 参数：
+//   - ctx: 上下文
+//   - taskID: 任务ID
+//   - workerID: 持有任务的 Worker 标识
+//   - lease: 续期时长（0 表示 DefaultLeaseDuration）
+//
+// 返回：
+//   - error: 租约丢失返回 ErrLeaseLost，其他失败返回数据库错误
+func (q *SyncQueue) RenewLease(ctx context.Context, taskID uint, workerID string, lease time.Duration) error {
+	if q == nil || q.db == nil {
+		return fmt.Errorf("syncqueue: db not initialized")
+	}
+	if workerID == "" {
+		return ErrMissingWorkerID
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if lease <= 0 {
+		lease = DefaultLeaseDuration
+	}
+
+	res := q.db.WithContext(ctx).Model(&model.TaskRun{}).
+		Where("id = ? AND status = ? AND worker_id = ?", taskID, string(TaskRunning), workerID).
+		Update("lease_expires_at", time.Now().Add(lease))
+	if res.Error != nil {
+		return fmt.Errorf("renew lease: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// RecoverExpired 回收租约已过期的任务
+//
+// 回收 Running 状态且租约已过期（或没有租约，即升级前遗留）的任务；
+// ownerID 非空时同时回收该 Worker 持有的全部任务（用于同一 Worker 重启后立即接管）。
+// 未超过最大重试次数的任务重新置为 Pending（保留断点，领取后继续执行），
+// 否则置为 Failed，失败类型为 interrupted。
+//
+// 参数：
+//   - ctx: 上下文
+//   - ownerID: 已确认退出的 Worker 标识（可选）
+//
+// 返回：
+//   - []model.TaskRun: 被回收的任务（Status 为回收后的状态）
+//   - error: 查询失败时返回错误
+func (q *SyncQueue) RecoverExpired(ctx context.Context, ownerID string) ([]model.TaskRun, error) {
+	if q == nil || q.db == nil {
+		return nil, fmt.Errorf("syncqueue: db not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now()
+	expired := func(db *gorm.DB) *gorm.DB {
+		cond := q.db.Where("lease_expires_at IS NULL OR lease_expires_at < ?", now)
+		if ownerID != "" {
+			cond = cond.Or("worker_id = ?", ownerID)
+		}
+		return db.Where("status = ?", string(TaskRunning)).Where(cond)
+	}
+
+	var tasks []model.TaskRun
+	if err := expired(q.db.WithContext(ctx)).Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("query expired tasks: %w", err)
+	}
+
+	recovered := make([]model.TaskRun, 0, len(tasks))
+	for _, task := range tasks {
+		attempts := task.Attempts + 1
+		maxAttempts := task.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = 3
+		}
+
+		updates := map[string]any{
+			"attempts":         attempts,
+			"failure_kind":     string(FailureInterrupted),
+			"error_message":    interruptedMessage,
+			"worker_id":        "",
+			"lease_expires_at": nil,
+		}
+		if attempts < maxAttempts {
+			updates["status"] = string(TaskPending)
+			updates["available_at"] = now
+			updates["started_at"] = time.Time{}
+			updates["ended_at"] = nil
+			updates["duration"] = int64(0)
+		} else {
+			duration := int64(0)
+			if !task.StartedAt.IsZero() {
+				duration = int64(now.Sub(task.StartedAt).Seconds())
+			}
+			updates["status"] = string(TaskFailed)
+			updates["ended_at"] = now
+			updates["duration"] = duration
+		}
+
+		// 条件更新：回收期间被续期或已结束的任务不受影响
+		res := expired(q.db.WithContext(ctx).Model(&model.TaskRun{})).
+			Where("id = ? AND worker_id = ?", task.ID, task.WorkerID).
+			Updates(updates)
+		if res.Error != nil {
+			q.log.Warn("recover expired task failed", zap.Uint("task_id", task.ID), zap.Error(res.Error))
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		task.Status = updates["status"].(string)
+		task.Attempts = attempts
+		task.FailureKind = string(FailureInterrupted)
+		task.ErrorMessage = interruptedMessage
+		q.log.Warn("task lease expired",
+			zap.Uint("task_id", task.ID),
+			zap.Uint("job_id", task.JobID),
+			zap.String("job_name", extractJobName(task.Payload)),
+			zap.String("worker_id", task.WorkerID),
+			zap.String("status", task.Status),
+			zap.Int("attempts", attempts))
+		recovered = append(recovered, task)
+	}
+
+	return recovered, nil
+}
+
+// RegisterWorker 写入 Worker 实例心跳（不存在时创建）
+//
+// 参数：
+//   - ctx: 上下文
+//   - node: Worker 实例信息（ID 不能为空）
+//
+// 返回：
+//   - error: 写入失败时返回错误
+func (q *SyncQueue) RegisterWorker(ctx context.Context, node model.WorkerNode) error {
+	if q == nil || q.db == nil {
+		return fmt.Errorf("syncqueue: db not initialized")
+	}
+	if node.ID == "" {
+		return ErrMissingWorkerID
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := q.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"worker_group", "hostname", "concurrency", "reserved", "in_flight",
+			"started_at", "heartbeat_at", "expires_at", "stopped_at",
+		}),
+	}).Create(&node).Error; err != nil {
+		return fmt.Errorf("register worker: %w", err)
+	}
+	return nil
+}
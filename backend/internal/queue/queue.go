@@ -2,16 +2,19 @@ package syncqueue
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/pkg/logger"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // 队列相关错误定义
@@ -27,8 +30,44 @@ var (
 	// ErrMissingWorkerID Worker ID 缺失错误
 	// 当 ClaimNext 没有提供 WorkerID 时返回
 	ErrMissingWorkerID = errors.New("worker id is required")
+
+	// ErrLeaseLost 执行租约丢失错误
+	// 当续期时任务已不属于该 Worker（已取消、已结束或租约过期被回收）时返回
+	ErrLeaseLost = errors.New("task lease lost")
 )
 
+// claimConcurrencyCondition 领取任务时的并发限制条件（task_runs 为待领取的任务）
+//
+// 任务不存在或上限为 0 时按上限 1 处理；未关联数据服务器的任务只受任务级限制。
+const claimConcurrencyCondition = `(SELECT COUNT(*) FROM task_runs AS running
+		WHERE running.status = 'running' AND running.job_id = task_runs.job_id)
+	< COALESCE((SELECT CASE WHEN job.max_concurrent_runs > 0 THEN job.max_concurrent_runs ELSE 1 END
+		FROM jobs AS job WHERE job.id = task_runs.job_id), 1)
+AND NOT EXISTS (SELECT 1 FROM jobs AS job
+	JOIN data_servers AS server ON server.id = job.data_server_id
+	WHERE job.id = task_runs.job_id
+	AND (SELECT COUNT(*) FROM task_runs AS running
+		JOIN jobs AS running_job ON running_job.id = running.job_id
+		WHERE running.status = 'running' AND running_job.data_server_id = server.id)
+		>= CASE WHEN server.max_concurrent_runs > 0 THEN server.max_concurrent_runs ELSE 1 END)`
+
+// claimGroupCondition 领取任务时的 Worker 分组条件：只领取未分组或与 Worker 同分组的任务
+const claimGroupCondition = `COALESCE((SELECT job.worker_group FROM jobs AS job WHERE job.id = task_runs.job_id), '') IN ('', ?)`
+
+// ClaimOptions 领取任务的附加条件
+type ClaimOptions struct {
+	// MaxPriority 只领取优先级数值不大于该值的任务（0 表示不限制）
+	// 例如 TaskPriorityHigh 表示只领取高优先级任务
+	MaxPriority TaskPriority
+
+	// Group Worker 分组：只领取未分组或 jobs.worker_group 与之相同的任务
+	Group string
+
+	// LeaseDuration 执行租约时长（0 表示 DefaultLeaseDuration）
+	// Worker 需在租约到期前调用 RenewLease 续期，否则任务会被 RecoverExpired 回收
+	LeaseDuration time.Duration
+}
+
 // TaskFilter 任务查询过滤器
 //
 // 用于过滤和分页查询任务列表。
@@ -78,8 +117,9 @@ type TaskFilter struct {
 //	    return err
 //	}
 type SyncQueue struct {
-	db  *gorm.DB
-	log *zap.Logger
+	db     *gorm.DB
+	log    *zap.Logger
+	claims sync.RWMutex // 领取持有读锁，PauseClaims 持有写锁
 }
 
 // NewSyncQueue 创建任务队列
@@ -101,6 +141,16 @@ func NewSyncQueue(db *gorm.DB) (*SyncQueue, error) {
 	}, nil
 }
 
+// PauseClaims 暂停领取任务，等待进行中的领取完成后返回
+//
+// 暂停期间 ClaimNext 返回 nil（视为没有可用任务），直到调用返回的 resume 恢复领取；
+// 用于恢复备份等不能与任务执行并发的维护操作。resume 可重复调用。
+func (q *SyncQueue) PauseClaims() (resume func()) {
+	q.claims.Lock()
+	var once sync.Once
+	return func() { once.Do(q.claims.Unlock) }
+}
+
 // ClaimNext 原子领取下一个待执行任务
 //
 // 这是队列的核心方法，实现了原子的任务领取逻辑：
@@ -111,9 +161,18 @@ func NewSyncQueue(db *gorm.DB) (*SyncQueue, error) {
 //
 // 如果没有可用任务，返回 (nil, nil)。
 //
+// 领取时设置执行租约（lease_expires_at），Worker 需定期调用 RenewLease 续期。
+//
+// 并发限制：
+// - 同一任务（Job）同时执行的数量不超过 jobs.max_concurrent_runs（0 表示默认 1）
+// - 同一数据服务器同时执行的数量不超过 data_servers.max_concurrent_runs（0 表示默认 1）
+// 已达上限的任务会被跳过，领取下一个可执行的任务。
+//
 // 并发安全性：
 // - 使用数据库事务保证原子性
-// - WHERE 条件中包含 status 检查，防止并发领取
+// - WHERE 条件中包含 status 与并发限制检查，防止并发领取及超出上限
+// - PostgreSQL/MySQL 使用 SELECT ... FOR UPDATE SKIP LOCKED 选取任务，并发领取时跳过已锁定的行
+// - PostgreSQL/MySQL 的并发限制通过锁定 Job 与数据服务器行后重新计数保证
 //
 // 参数：
 //   - ctx: 上下文，用于取消
@@ -123,6 +182,14 @@ func NewSyncQueue(db *gorm.DB) (*SyncQueue, error) {
 //   - *model.TaskRun: 领取到的任务，如果没有可用任务则返回 nil
 //   - error: 领取失败时返回错误
 func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.TaskRun, error) {
+	return q.ClaimNextWithOptions(ctx, workerID, ClaimOptions{})
+}
+
+// ClaimNextWithOptions 按附加条件原子领取下一个待执行任务
+//
+// 行为与 ClaimNext 相同，opts.MaxPriority 用于只领取高优先级任务（预留执行槽位），
+// opts.Group 用于只领取分配给该 Worker 分组的任务，opts.LeaseDuration 指定租约时长。
+func (q *SyncQueue) ClaimNextWithOptions(ctx context.Context, workerID string, opts ClaimOptions) (*model.TaskRun, error) {
 	if q == nil || q.db == nil {
 		return nil, fmt.Errorf("syncqueue: db not initialized")
 	}
@@ -132,18 +199,43 @@ func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.Task
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	// 领取已暂停时按"没有可用任务"处理，Worker 在下个轮询周期重试
+	if !q.claims.TryRLock() {
+		return nil, nil
+	}
+	defer q.claims.RUnlock()
 
+	lease := opts.LeaseDuration
+	if lease <= 0 {
+		lease = DefaultLeaseDuration
+	}
 	now := time.Now()
+	leaseExpiresAt := now.Add(lease)
 
-	// 开始事务
-	tx := q.db.WithContext(ctx).Begin()
+	// 开始事务（行锁模式使用读已提交，保证加锁后重新计数能看到其他事务已提交的领取）
+	skipLocked := supportsSkipLocked(q.db)
+	var tx *gorm.DB
+	if skipLocked {
+		tx = q.db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	} else {
+		tx = q.db.WithContext(ctx).Begin()
+	}
 	if tx.Error != nil {
 		return nil, fmt.Errorf("claim next begin transaction: %w", tx.Error)
 	}
 
-	// 查询一个待执行任务
+	// 查询一个待执行、属于该 Worker 分组且未达并发上限的任务
+	query := tx.Where("status = ? AND available_at <= ?", string(TaskPending), now).
+		Where(claimGroupCondition, opts.Group).
+		Where(claimConcurrencyCondition)
+	if opts.MaxPriority > 0 {
+		query = query.Where("priority <= ?", int(opts.MaxPriority))
+	}
+	if skipLocked {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	}
 	var task model.TaskRun
-	if err := tx.Where("status = ? AND available_at <= ?", string(TaskPending), now).
+	if err := query.
 		Order("priority asc, available_at asc, id asc").
 		Limit(1).
 		Take(&task).Error; err != nil {
@@ -170,14 +262,32 @@ func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.Task
 
 	// 更新任务状态
 	updates := map[string]any{
-		"status":     string(TaskRunning),
-		"worker_id":  workerID,
-		"started_at": now,
+		"status":           string(TaskRunning),
+		"worker_id":        workerID,
+		"started_at":       now,
+		"lease_expires_at": leaseExpiresAt,
+	}
+
+	update := tx.Model(&model.TaskRun{}).
+		Where("id = ? AND status = ?", task.ID, string(TaskPending))
+	if skipLocked {
+		// 任务行已锁定；锁定 Job 与数据服务器行后重新检查并发限制
+		// （MySQL 不允许 UPDATE 的子查询引用被更新的表，因此不在更新语句中检查）
+		ok, err := lockClaimLimits(tx, &task)
+		if err != nil {
+			_ = tx.Rollback().Error
+			q.log.Error("claim next lock limits failed", zap.Error(err))
+			return nil, fmt.Errorf("claim next lock limits: %w", err)
+		}
+		if !ok {
+			_ = tx.Rollback().Error
+			return nil, nil
+		}
+	} else {
+		// 并发限制在更新语句中再次检查，保证与其他 Worker 的领取互斥
+		update = update.Where(claimConcurrencyCondition)
 	}
-
-	res := tx.Model(&model.TaskRun{}).
-		Where("id = ? AND status = ?", task.ID, string(TaskPending)).
-		Updates(updates)
+	res := update.Updates(updates)
 
 	if res.Error != nil {
 		_ = tx.Rollback().Error
@@ -185,7 +295,7 @@ func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.Task
 		return nil, fmt.Errorf("claim next update: %w", res.Error)
 	}
 
-	// 如果没有更新任何行（可能被其他 Worker 抢先了），返回 nil
+	// 如果没有更新任何行（可能被其他 Worker 抢先了或已达并发上限），返回 nil
 	if res.RowsAffected == 0 {
 		_ = tx.Rollback().Error
 		return nil, nil
@@ -200,6 +310,7 @@ func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.Task
 	task.Status = string(TaskRunning)
 	task.WorkerID = workerID
 	task.StartedAt = now
+	task.LeaseExpiresAt = &leaseExpiresAt
 
 	jobName := extractJobName(task.Payload)
 	q.log.Debug("claimed task",
@@ -212,6 +323,57 @@ func (q *SyncQueue) ClaimNext(ctx context.Context, workerID string) (*model.Task
 	return &task, nil
 }
 
+// supportsSkipLocked 数据库是否支持 SELECT ... FOR UPDATE SKIP LOCKED
+//
+// SQLite 写事务本身串行，不需要（也不支持）行锁。MySQL 需 8.0 及以上版本。
+func supportsSkipLocked(db *gorm.DB) bool {
+	switch db.Dialector.Name() {
+	case "postgres", "mysql":
+		return true
+	default:
+		return false
+	}
+}
+
+// lockClaimLimits 锁定待领取任务所属的 Job 与数据服务器行，并重新检查并发限制
+//
+// 同一 Job（或同一数据服务器）的领取在行锁上串行，后领取的事务在锁释放后
+// 重新计数即可看到先提交的 Running 任务，避免并发领取超出上限。
+// 加锁顺序固定为 Job → 数据服务器，不会产生死锁。
+//
+// 返回：
+//   - bool: 未达并发上限返回 true
+//   - error: 查询失败时返回错误
+func lockClaimLimits(tx *gorm.DB, task *model.TaskRun) (bool, error) {
+	var job model.Job
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "data_server_id").
+		Where("id = ?", task.JobID).
+		Take(&job).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("lock job: %w", err)
+	}
+	if err == nil && job.DataServerID != nil {
+		var server model.DataServer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", *job.DataServerID).
+			Take(&server).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("lock data server: %w", err)
+		}
+	}
+
+	var count int64
+	if err := tx.Model(&model.TaskRun{}).
+		Where("id = ?", task.ID).
+		Where(claimConcurrencyCondition).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("recheck concurrency: %w", err)
+	}
+	return count > 0, nil
+}
+
 // Complete 标记任务完成
 //
 // 将任务状态从 Running 转换为 Completed，并记录结束时间和执行时长。
@@ -368,6 +530,7 @@ func (q *SyncQueue) Fail(ctx context.Context, taskID uint, err error) error {
 		updates["ended_at"] = nil
 		updates["duration"] = int64(0)
 		updates["worker_id"] = "" // 清除 WorkerID
+		updates["lease_expires_at"] = nil
 
 		q.log.Warn("task will retry",
 			zap.Uint("task_id", taskID),
@@ -505,6 +668,86 @@ func (q *SyncQueue) Cancel(ctx context.Context, taskID uint) error {
 	return nil
 }
 
+// Resume 恢复失败的任务
+//
+// 将 Failed 状态的任务重新置为 Pending 并立即可执行。
+// 保留任务上的同步断点（Checkpoint），Worker 领取后会从断点继续，
+// 跳过已完成的文件；重试次数清零。
+//
+// 参数：
+//   - ctx: 上下文
+//   - taskID: 任务ID
+//
+// 返回：
+//   - error: 操作失败时返回错误（非 Failed 状态返回 ErrInvalidTransition）
+func (q *SyncQueue) Resume(ctx context.Context, taskID uint) error {
+	if q == nil || q.db == nil {
+		return fmt.Errorf("syncqueue: db not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx := q.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("resume begin transaction: %w", tx.Error)
+	}
+
+	// 加载任务
+	var task model.TaskRun
+	if err := tx.First(&task, taskID).Error; err != nil {
+		_ = tx.Rollback().Error
+		return fmt.Errorf("resume load task: %w", err)
+	}
+
+	// 仅允许 Failed -> Pending
+	if TaskStatus(task.Status) != TaskFailed || !TaskStatus(task.Status).CanTransitionTo(TaskPending) {
+		_ = tx.Rollback().Error
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, task.Status, TaskPending)
+	}
+
+	updates := map[string]any{
+		"status":           string(TaskPending),
+		"available_at":     time.Now(),
+		"attempts":         0,
+		"started_at":       time.Time{},
+		"ended_at":         nil,
+		"duration":         int64(0),
+		"worker_id":        "",
+		"lease_expires_at": nil,
+		"failure_kind":     "",
+		"error_message":    "",
+	}
+
+	res := tx.Model(&model.TaskRun{}).
+		Where("id = ? AND status = ?", taskID, string(TaskFailed)).
+		Updates(updates)
+
+	if res.Error != nil {
+		_ = tx.Rollback().Error
+		q.log.Error("resume update failed", zap.Error(res.Error))
+		return fmt.Errorf("resume update: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		_ = tx.Rollback().Error
+		return fmt.Errorf("resume update: no rows affected")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("resume commit: %w", err)
+	}
+
+	jobName := extractJobName(task.Payload)
+	q.log.Info("task resumed",
+		zap.Uint("task_id", taskID),
+		zap.Uint("job_id", task.JobID),
+		zap.String("job_name", jobName),
+		zap.Bool("has_checkpoint", strings.TrimSpace(task.Checkpoint) != ""))
+
+	return nil
+}
+
 // Enqueue 入队新任务
 //
 // 创建一个新任务并加入队列。
@@ -624,6 +867,60 @@ func (q *SyncQueue) List(ctx context.Context, filter TaskFilter) ([]model.TaskRu
 	return tasks, nil
 }
 
+// Backlog 统计队列积压情况（用于健康检查）
+//
+// 参数：
+//   - ctx: 上下文
+//
+// 返回：
+//   - QueueBacklog: 积压统计
+//   - error: 查询失败时返回错误
+func (q *SyncQueue) Backlog(ctx context.Context) (QueueBacklog, error) {
+	if q == nil || q.db == nil {
+		return QueueBacklog{}, fmt.Errorf("syncqueue: db not initialized")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now()
+	db := q.db.WithContext(ctx)
+	var backlog QueueBacklog
+
+	if err := db.Model(&model.TaskRun{}).
+		Where("status = ? AND available_at <= ?", string(TaskPending), now).
+		Count(&backlog.Pending).Error; err != nil {
+		return QueueBacklog{}, fmt.Errorf("count pending tasks: %w", err)
+	}
+	if err := db.Model(&model.TaskRun{}).
+		Where("status = ? AND available_at > ?", string(TaskPending), now).
+		Count(&backlog.Delayed).Error; err != nil {
+		return QueueBacklog{}, fmt.Errorf("count delayed tasks: %w", err)
+	}
+	if err := db.Model(&model.TaskRun{}).
+		Where("status = ?", string(TaskRunning)).
+		Count(&backlog.Running).Error; err != nil {
+		return QueueBacklog{}, fmt.Errorf("count running tasks: %w", err)
+	}
+
+	if backlog.Pending > 0 {
+		var oldest model.TaskRun
+		if err := db.Select("id", "available_at").
+			Where("status = ? AND available_at <= ?", string(TaskPending), now).
+			Order("available_at asc, id asc").
+			Take(&oldest).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return QueueBacklog{}, fmt.Errorf("query oldest pending task: %w", err)
+		}
+		if !oldest.AvailableAt.IsZero() {
+			at := oldest.AvailableAt
+			backlog.OldestPendingAt = &at
+			backlog.OldestPendingAge = now.Sub(at)
+		}
+	}
+
+	return backlog, nil
+}
+
 // retryDelay 计算重试延迟时间
 //
 // 使用指数退避策略，最长延迟不超过 5 分钟。
@@ -4,11 +4,16 @@ import (
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"os"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
+	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -23,11 +28,15 @@ func newTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("open sqlite: %v", err)
 	}
-	if err := db.AutoMigrate(&model.TaskRun{}); err != nil {
+	// 领取任务时按 jobs/data_servers 检查并发限制
+	if err := db.AutoMigrate(&model.DataServer{}, &model.Job{}, &model.TaskRun{}, &model.WorkerNode{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 	// 每次测试前清空数据
 	db.Exec("DELETE FROM task_runs")
+	db.Exec("DELETE FROM jobs")
+	db.Exec("DELETE FROM data_servers")
+	db.Exec("DELETE FROM worker_nodes")
 	return db
 }
 
@@ -298,6 +307,37 @@ func TestClaimNext_EmptyQueue(t *testing.T) {
 	}
 }
 
+func TestClaimNext_PausedClaims(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, &model.TaskRun{JobID: 1}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	resume := q.PauseClaims()
+	claimed, err := q.ClaimNext(ctx, "worker-1")
+	if err != nil {
+		t.Fatalf("claim next while paused: %v", err)
+	}
+	if claimed != nil {
+		t.Fatalf("expected no claim while paused, got %+v", claimed)
+	}
+
+	resume()
+	resume()
+	claimed, err = q.ClaimNext(ctx, "worker-1")
+	if err != nil {
+		t.Fatalf("claim next after resume: %v", err)
+	}
+	if claimed == nil {
+		t.Fatalf("expected claim after resume")
+	}
+}
+
 func TestClaimNext_RespectsAvailableAt(t *testing.T) {
 	db := newTestDB(t)
 	q, err := NewSyncQueue(db)
@@ -608,6 +648,89 @@ func TestCancel_InvalidTransition(t *testing.T) {
 	}
 }
 
+// =============================================================
+// Resume 测试
+// =============================================================
+
+func TestResume_FailedKeepsCheckpoint(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+
+	endedAt := time.Now()
+	task := model.TaskRun{
+		JobID:        1,
+		Status:       string(TaskFailed),
+		Priority:     int(TaskPriorityNormal),
+		DedupKey:     "resume-failed",
+		Attempts:     3,
+		MaxAttempts:  3,
+		FailureKind:  "interrupted",
+		ErrorMessage: "服务重启，任务中断",
+		EndedAt:      &endedAt,
+		Checkpoint:   `{"remote_root":"/","last_path":"/a.mp4","processed_files":1}`,
+	}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	if err := q.Resume(context.Background(), task.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+
+	var stored model.TaskRun
+	if err := db.First(&stored, task.ID).Error; err != nil {
+		t.Fatalf("load task: %v", err)
+	}
+	if stored.Status != string(TaskPending) {
+		t.Errorf("expected pending, got %s", stored.Status)
+	}
+	if stored.Attempts != 0 {
+		t.Errorf("expected attempts reset, got %d", stored.Attempts)
+	}
+	if stored.EndedAt != nil || stored.ErrorMessage != "" || stored.FailureKind != "" {
+		t.Errorf("expected failure fields cleared, got ended_at=%v error=%q kind=%q",
+			stored.EndedAt, stored.ErrorMessage, stored.FailureKind)
+	}
+	if stored.Checkpoint != task.Checkpoint {
+		t.Errorf("checkpoint should be kept, got %q", stored.Checkpoint)
+	}
+
+	// 恢复后可被立即领取
+	claimed, err := q.ClaimNext(context.Background(), "worker-1")
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claimed == nil || claimed.ID != task.ID {
+		t.Fatalf("expected resumed task to be claimed, got %+v", claimed)
+	}
+}
+
+func TestResume_InvalidTransition(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+
+	task := model.TaskRun{
+		JobID:    1,
+		Status:   string(TaskCompleted),
+		Priority: int(TaskPriorityNormal),
+		DedupKey: "resume-completed",
+	}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	err = q.Resume(context.Background(), task.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
 // =============================================================
 // List 测试
 // =============================================================
@@ -747,3 +870,343 @@ func TestFullWorkflow_EnqueueClaimFailRetry(t *testing.T) {
 		t.Errorf("attempts: expected 1, got %d", stored.Attempts)
 	}
 }
+
+func TestBacklog(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	tasks := []*model.TaskRun{
+		{JobID: 1, DedupKey: "backlog-old", AvailableAt: now.Add(-10 * time.Minute)},
+		{JobID: 2, DedupKey: "backlog-new", AvailableAt: now.Add(-time.Minute)},
+		{JobID: 3, DedupKey: "backlog-delayed", AvailableAt: now.Add(time.Hour)},
+		{JobID: 4, DedupKey: "backlog-running", AvailableAt: now.Add(-20 * time.Minute)},
+	}
+	for _, task := range tasks {
+		if err := q.Enqueue(ctx, task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	if err := db.Model(&model.TaskRun{}).Where("id = ?", tasks[3].ID).
+		Update("status", string(TaskRunning)).Error; err != nil {
+		t.Fatalf("mark running: %v", err)
+	}
+
+	backlog, err := q.Backlog(ctx)
+	if err != nil {
+		t.Fatalf("backlog: %v", err)
+	}
+	if backlog.Pending != 2 || backlog.Delayed != 1 || backlog.Running != 1 {
+		t.Errorf("unexpected counts: %+v", backlog)
+	}
+	if backlog.OldestPendingAt == nil || backlog.OldestPendingAge < 9*time.Minute {
+		t.Errorf("oldest pending should be ~10m old, got %+v", backlog)
+	}
+}
+
+func TestClaimNext_ConcurrencyLimits(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+
+	server := model.DataServer{Name: "cd2", Type: "clouddrive2", Host: "127.0.0.1", Port: 19798}
+	if err := db.Create(&server).Error; err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	jobs := []model.Job{
+		{Name: "movies", SourcePath: "/a", TargetPath: "/t/a", STRMPath: "/a", DataServerID: &server.ID},
+		{Name: "shows", SourcePath: "/b", TargetPath: "/t/b", STRMPath: "/b", DataServerID: &server.ID},
+		{Name: "local", SourcePath: "/c", TargetPath: "/t/c", STRMPath: "/c", MaxConcurrentRuns: 2},
+	}
+	for i := range jobs {
+		if err := db.Create(&jobs[i]).Error; err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+	}
+
+	now := time.Now().Add(-time.Minute)
+	enqueue := func(jobID uint, key string, priority TaskPriority) *model.TaskRun {
+		t.Helper()
+		task := &model.TaskRun{JobID: jobID, DedupKey: key, Priority: int(priority), AvailableAt: now}
+		if err := q.Enqueue(ctx, task); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		return task
+	}
+	movies1 := enqueue(jobs[0].ID, "movies-1", TaskPriorityNormal)
+	movies2 := enqueue(jobs[0].ID, "movies-2", TaskPriorityNormal)
+	enqueue(jobs[1].ID, "shows-1", TaskPriorityNormal)
+	local1 := enqueue(jobs[2].ID, "local-1", TaskPriorityNormal)
+	local2 := enqueue(jobs[2].ID, "local-2", TaskPriorityNormal)
+	enqueue(jobs[2].ID, "local-3", TaskPriorityNormal)
+
+	claim := func() *model.TaskRun {
+		t.Helper()
+		task, err := q.ClaimNext(ctx, "worker-1")
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		return task
+	}
+
+	// movies-2 受任务级限制，shows-1 受服务器级限制，local 任务允许同时执行 2 个
+	for _, want := range []*model.TaskRun{movies1, local1, local2} {
+		if got := claim(); got == nil || got.ID != want.ID {
+			t.Fatalf("expected task %d, got %+v", want.ID, got)
+		}
+	}
+	if got := claim(); got != nil {
+		t.Fatalf("expected no claimable task, got %d (%s)", got.ID, got.DedupKey)
+	}
+
+	// movies-1 完成后释放服务器与任务槽位
+	if err := q.Complete(ctx, movies1.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got := claim(); got == nil || got.ID != movies2.ID {
+		t.Fatalf("expected movies-2 after release, got %+v", got)
+	}
+
+	// 仅领取高优先级任务
+	if err := q.Complete(ctx, movies2.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	high, err := q.ClaimNextWithOptions(ctx, "worker-2", ClaimOptions{MaxPriority: TaskPriorityHigh})
+	if err != nil || high != nil {
+		t.Fatalf("expected no high priority task, got %+v err=%v", high, err)
+	}
+}
+
+func TestLease_RenewCancelAndRecover(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+
+	task := &model.TaskRun{JobID: 1, DedupKey: "lease-1", MaxAttempts: 2, Checkpoint: `{"done":1}`}
+	if err := q.Enqueue(ctx, task); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	claimed, err := q.ClaimNextWithOptions(ctx, "worker-a", ClaimOptions{LeaseDuration: time.Millisecond})
+	if err != nil || claimed == nil || claimed.LeaseExpiresAt == nil {
+		t.Fatalf("claim: task=%+v err=%v", claimed, err)
+	}
+	if err := q.RenewLease(ctx, task.ID, "worker-b", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost for other worker, got %v", err)
+	}
+
+	// 租约过期：回收为 Pending，保留断点
+	time.Sleep(5 * time.Millisecond)
+	recovered, err := q.RecoverExpired(ctx, "")
+	if err != nil || len(recovered) != 1 || recovered[0].Status != string(TaskPending) {
+		t.Fatalf("recover: tasks=%+v err=%v", recovered, err)
+	}
+	var stored model.TaskRun
+	db.First(&stored, task.ID)
+	if stored.Status != string(TaskPending) || stored.Attempts != 1 || stored.WorkerID != "" ||
+		stored.LeaseExpiresAt != nil || stored.Checkpoint == "" || stored.FailureKind != string(FailureInterrupted) {
+		t.Fatalf("unexpected recovered task: %+v", stored)
+	}
+	if err := q.RenewLease(ctx, task.ID, "worker-a", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost after recovery, got %v", err)
+	}
+
+	// 续期中的任务不会被回收；ownerID 可立即回收指定 Worker 的任务，超过重试次数置为失败
+	if claimed, err = q.ClaimNext(ctx, "worker-b"); err != nil || claimed == nil {
+		t.Fatalf("reclaim: task=%+v err=%v", claimed, err)
+	}
+	if err := q.RenewLease(ctx, task.ID, "worker-b", time.Minute); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if recovered, _ := q.RecoverExpired(ctx, ""); len(recovered) != 0 {
+		t.Fatalf("expected live lease to be kept, got %+v", recovered)
+	}
+	recovered, err = q.RecoverExpired(ctx, "worker-b")
+	if err != nil || len(recovered) != 1 || recovered[0].Status != string(TaskFailed) {
+		t.Fatalf("recover owner: tasks=%+v err=%v", recovered, err)
+	}
+
+	// 取消通过数据库传播给持有任务的 Worker
+	other := &model.TaskRun{JobID: 1, DedupKey: "lease-2"}
+	if err := q.Enqueue(ctx, other); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if claimed, err = q.ClaimNext(ctx, "worker-a"); err != nil || claimed == nil {
+		t.Fatalf("claim: task=%+v err=%v", claimed, err)
+	}
+	if err := q.Cancel(ctx, other.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := q.RenewLease(ctx, other.ID, "worker-a", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost after cancel, got %v", err)
+	}
+}
+
+func TestClaimNext_WorkerGroup(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+
+	job := model.Job{Name: "nas", SourcePath: "/a", TargetPath: "/t/a", STRMPath: "/a", WorkerGroup: "nas"}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	task := &model.TaskRun{JobID: job.ID, DedupKey: "group-1"}
+	if err := q.Enqueue(ctx, task); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	for _, group := range []string{"", "other"} {
+		if got, err := q.ClaimNextWithOptions(ctx, "worker-1", ClaimOptions{Group: group}); err != nil || got != nil {
+			t.Fatalf("group %q: expected no task, got %+v err=%v", group, got, err)
+		}
+	}
+	got, err := q.ClaimNextWithOptions(ctx, "worker-1", ClaimOptions{Group: "nas"})
+	if err != nil || got == nil || got.ID != task.ID {
+		t.Fatalf("expected grouped task, got %+v err=%v", got, err)
+	}
+}
+
+func TestRegisterWorker_Upsert(t *testing.T) {
+	db := newTestDB(t)
+	q, err := NewSyncQueue(db)
+	if err != nil {
+		t.Fatalf("new queue: %v", err)
+	}
+	ctx := context.Background()
+
+	now := time.Now()
+	node := model.WorkerNode{ID: "worker-1", Group: "nas", Concurrency: 4, HeartbeatAt: now, ExpiresAt: now.Add(time.Minute)}
+	if err := q.RegisterWorker(ctx, node); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	node.InFlight = 2
+	node.StoppedAt = &now
+	if err := q.RegisterWorker(ctx, node); err != nil {
+		t.Fatalf("register again: %v", err)
+	}
+
+	var nodes []model.WorkerNode
+	db.Find(&nodes)
+	if len(nodes) != 1 || nodes[0].InFlight != 2 || nodes[0].Group != "nas" || nodes[0].StoppedAt == nil {
+		t.Fatalf("unexpected worker nodes: %+v", nodes)
+	}
+	if err := q.RegisterWorker(ctx, model.WorkerNode{}); !errors.Is(err, ErrMissingWorkerID) {
+		t.Fatalf("expected ErrMissingWorkerID, got %v", err)
+	}
+}
+
+// TestClaimNext_SkipLockedConcurrent 多个 Worker 并发领取：不重复领取且不超出并发上限
+//
+// SQLite 总是测试；设置 TEST_POSTGRES_DSN / TEST_MYSQL_DSN 时同时测试
+// SELECT ... FOR UPDATE SKIP LOCKED 领取路径。
+func TestClaimNext_SkipLockedConcurrent(t *testing.T) {
+	dbs := map[string]*gorm.DB{"sqlite": newTestDB(t)}
+	for driver, env := range map[string]string{dbpkg.DriverPostgres: "TEST_POSTGRES_DSN", dbpkg.DriverMySQL: "TEST_MYSQL_DSN"} {
+		dsn := os.Getenv(env)
+		if dsn == "" {
+			continue
+		}
+		db, err := dbpkg.Open(dbpkg.DatabaseConfig{Driver: driver, DSN: dsn}, &dbpkg.LogConfig{})
+		if err != nil {
+			t.Fatalf("open %s: %v", driver, err)
+		}
+		for _, table := range []string{"task_runs", "jobs", "data_servers"} {
+			db.Exec("DELETE FROM " + table)
+		}
+		dbs[driver] = db
+	}
+
+	for name, db := range dbs {
+		t.Run(name, func(t *testing.T) {
+			if name != "sqlite" && !supportsSkipLocked(db) {
+				t.Fatalf("expected skip locked support on %s", name)
+			}
+			q, err := NewSyncQueue(db)
+			if err != nil {
+				t.Fatalf("new queue: %v", err)
+			}
+			ctx := context.Background()
+
+			server := model.DataServer{Name: "cd2-" + name, Type: "clouddrive2", Host: "127.0.0.1", Port: 19798, MaxConcurrentRuns: 3}
+			if err := db.Create(&server).Error; err != nil {
+				t.Fatalf("create server: %v", err)
+			}
+			var jobs []model.Job
+			for i := 0; i < 4; i++ {
+				job := model.Job{
+					Name:              fmt.Sprintf("job-%s-%d", name, i),
+					SourcePath:        "/src",
+					TargetPath:        "/dst",
+					STRMPath:          "/src",
+					DataServerID:      &server.ID,
+					MaxConcurrentRuns: 2,
+				}
+				if err := db.Create(&job).Error; err != nil {
+					t.Fatalf("create job: %v", err)
+				}
+				jobs = append(jobs, job)
+			}
+			for i := 0; i < 20; i++ {
+				task := &model.TaskRun{
+					JobID:       jobs[i%len(jobs)].ID,
+					DedupKey:    fmt.Sprintf("%s-%d", name, i),
+					AvailableAt: time.Now().Add(-time.Minute),
+				}
+				if err := q.Enqueue(ctx, task); err != nil {
+					t.Fatalf("enqueue: %v", err)
+				}
+			}
+
+			var (
+				mu      sync.Mutex
+				claimed = make(map[uint]string)
+				wg      sync.WaitGroup
+			)
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func(workerID string) {
+					defer wg.Done()
+					for i := 0; i < 5; i++ {
+						task, err := q.ClaimNext(ctx, workerID)
+						if err != nil && !strings.Contains(err.Error(), "locked") {
+							t.Errorf("claim: %v", err)
+							return
+						}
+						if task == nil {
+							continue
+						}
+						mu.Lock()
+						if prev, ok := claimed[task.ID]; ok {
+							t.Errorf("task %d claimed by %s and %s", task.ID, prev, workerID)
+						}
+						claimed[task.ID] = workerID
+						mu.Unlock()
+					}
+				}(fmt.Sprintf("worker-%d", w))
+			}
+			wg.Wait()
+
+			// 服务器级上限 3：无论多少 Worker 并发领取，执行中的任务都不超过 3 个
+			var running int64
+			if err := db.Model(&model.TaskRun{}).Where("status = ?", string(TaskRunning)).Count(&running).Error; err != nil {
+				t.Fatalf("count running: %v", err)
+			}
+			if running != int64(len(claimed)) || running != 3 {
+				t.Fatalf("expected 3 running tasks, got running=%d claimed=%d", running, len(claimed))
+			}
+		})
+	}
+}
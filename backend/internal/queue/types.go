@@ -8,7 +8,10 @@
 // - 并发安全的任务领取
 package syncqueue
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // TaskStatus 任务状态
 //
@@ -113,6 +116,7 @@ const (
 // - Retryable: 临时失败，可以重试（如网络超时、临时IO错误）
 // - Permanent: 永久失败，不应重试（如配置错误、权限不足）
 // - Cancelled: 任务被取消（如用户取消、超时取消）
+// - Interrupted: 执行中断（Worker 租约过期）
 type FailureKind string
 
 const (
@@ -130,6 +134,10 @@ const (
 	// 表示任务被用户或系统主动取消
 	// 例如：用户取消、context.Canceled、超时
 	FailureCancelled FailureKind = "cancelled"
+
+	// FailureInterrupted 任务执行中断
+	// 表示执行任务的 Worker 未按时续期租约（进程退出、宕机或失联）
+	FailureInterrupted FailureKind = "interrupted"
 )
 
 // String 返回失败类型的字符串表示
@@ -174,3 +182,14 @@ func (e *TaskError) Unwrap() error {
 	}
 	return e.Err
 }
+
+// QueueBacklog 队列积压统计
+//
+// Pending 只统计已到可执行时间的待执行任务；等待重试退避的任务计入 Delayed。
+type QueueBacklog struct {
+	Pending          int64         `json:"pending"`                     // 等待领取的任务数
+	Delayed          int64         `json:"delayed"`                     // 等待重试（未到可执行时间）的任务数
+	Running          int64         `json:"running"`                     // 执行中的任务数
+	OldestPendingAt  *time.Time    `json:"oldest_pending_at,omitempty"` // 最早可执行的待执行任务时间
+	OldestPendingAge time.Duration `json:"-"`                           // 最早待执行任务的等待时长
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -247,6 +248,34 @@ func (s *CronScheduler) RemoveJob(ctx context.Context, jobID uint) error {
 	return nil
 }
 
+// Status 返回调度器运行状态快照（用于健康检查）
+//
+// 条目按 JobID 排序，包含 Cron 引擎计算的下次/上次触发时间。
+func (s *CronScheduler) Status() SchedulerStatus {
+	if s == nil {
+		return SchedulerStatus{}
+	}
+	status := SchedulerStatus{Running: s.running.Load()}
+
+	s.mu.RLock()
+	status.Entries = make([]ScheduledJob, 0, len(s.entries))
+	for jobID, entry := range s.entries {
+		job := ScheduledJob{JobID: jobID, Spec: entry.Spec}
+		if s.cron != nil {
+			cronEntry := s.cron.Entry(entry.ID)
+			job.Next = cronEntry.Next
+			job.Prev = cronEntry.Prev
+		}
+		status.Entries = append(status.Entries, job)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(status.Entries, func(i, j int) bool {
+		return status.Entries[i].JobID < status.Entries[j].JobID
+	})
+	return status
+}
+
 // loadEnabledJobs 加载并注册所有启用的 Job
 //
 // 错误处理：单个任务注册失败不影响其他任务
@@ -200,3 +200,20 @@ type CronEntry struct {
 	// 用于判断 Cron 表达式是否变更。
 	Spec string
 }
+
+// SchedulerStatus 表示调度器的运行状态快照
+type SchedulerStatus struct {
+	// Running 调度器是否运行中
+	Running bool `json:"running"`
+
+	// Entries 已注册的 Cron 条目
+	Entries []ScheduledJob `json:"entries"`
+}
+
+// ScheduledJob 表示一个已注册任务的调度信息
+type ScheduledJob struct {
+	JobID uint      `json:"job_id"`
+	Spec  string    `json:"spec"`
+	Next  time.Time `json:"next"` // 下次触发时间（调度器未启动时为零值）
+	Prev  time.Time `json:"prev"` // 上次触发时间（尚未触发时为零值）
+}
@@ -6,6 +6,8 @@
 //
 //   - StrmWriter: STRM 写入器接口
 //   - LocalWriter: 本地 STRM 写入器
+//   - WebDAVWriter: WebDAV 远程写入器（媒体服务器位于其他主机时使用）
+//   - OpenListWriter: 通过 /api/fs/put 上传到 OpenList 的写入器
 //   - ContentBuilder: STRM 内容构建器
 //
 // # 支持的 STRM 格式
@@ -36,6 +38,6 @@
 //
 // # 依赖关系
 //
-//	strmwriter 不依赖其他内部包（纯工具库）
+//	strmwriter 仅依赖 internal/pkg 下的工具包（fileutil、OpenList SDK）
 //	engine 使用 strmwriter.StrmWriter
 package strmwriter
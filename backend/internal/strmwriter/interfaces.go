@@ -8,6 +8,7 @@ package strmwriter
 
 import (
 	"context"
+	"io/fs"
 	"time"
 )
 
@@ -21,7 +22,8 @@ import (
 //
 // 实现示例：
 // - LocalWriter: 本地文件系统写入
-// - RemoteWriter: 远程文件系统写入（未来扩展）
+// - WebDAVWriter: 写入 WebDAV 服务
+// - OpenListWriter: 上传到 OpenList 存储
 // - MockWriter: 测试用 mock 实现
 type StrmWriter interface {
 	// Read 读取 STRM 文件的原始内容
@@ -79,4 +81,24 @@ type StrmWriter interface {
 	// 用途：
 	// - 清理孤儿文件（源文件已删除但 STRM 仍存在）
 	Delete(ctx context.Context, path string) error
+
+	// Stat 获取文件或目录的元信息
+	//
+	// 返回：
+	//   - fs.FileInfo: 元信息；无法保留修改时间的远程实现返回零值 ModTime
+	//   - error: 不存在时返回 errors.Is(err, fs.ErrNotExist)==true 的错误
+	//
+	// 用途：
+	// - 增量同步判定（存在性 + 修改时间）
+	Stat(ctx context.Context, path string) (fs.FileInfo, error)
+
+	// Walk 遍历 root 下的文件和目录
+	//
+	// 行为：
+	// - 语义与 filepath.WalkDir 相同（按名称排序、支持 fs.SkipDir/fs.SkipAll）
+	// - root 不存在时以 fs.ErrNotExist 调用一次 fn
+	//
+	// 用途：
+	// - 孤儿清理、移动检测与附属文件处理
+	Walk(ctx context.Context, root string, fn fs.WalkDirFunc) error
 }
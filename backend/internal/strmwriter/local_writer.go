@@ -4,10 +4,13 @@ package strmwriter
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
 )
 
 const (
@@ -34,6 +37,7 @@ type LocalWriter struct {
 	enforceRoot bool        // 是否强制路径必须在 root 之下
 	dirPerm     os.FileMode // 创建目录时使用的权限
 	filePerm    os.FileMode // 创建文件时使用的权限
+	fsync       bool        // 写入后是否 fsync
 }
 
 // Option 是 LocalWriter 的配置选项函数
@@ -69,6 +73,16 @@ func WithPermissions(dirPerm, filePerm os.FileMode) Option {
 	}
 }
 
+// WithFsync 控制写入 STRM 文件后是否 fsync
+//
+// 开启后重命名前同步文件内容、重命名后同步父目录，
+// 断电时不会留下空文件，但会降低大批量写入的速度。默认关闭。
+func WithFsync(enabled bool) Option {
+	return func(w *LocalWriter) {
+		w.fsync = enabled
+	}
+}
+
 // NewLocalWriter 创建一个本地文件系统写入器
 //
 // 参数：
@@ -150,13 +164,13 @@ func (w *LocalWriter) Read(ctx context.Context, targetPath string) (string, erro
 // 实现说明：
 // - 检查 context 是否已取消
 // - 验证路径（如果 enforceRoot=true）
-// - 确保父目录存在（使用 os.MkdirAll）
-// - 写入文件内容（使用 os.WriteFile）
-// - 如果 modTime 非零，设置文件修改时间（使用 os.Chtimes）
+// - 先写入同目录下的临时文件，再重命名为目标文件（fileutil.WriteFile）
+// - 如果 modTime 非零，在重命名前设置文件修改时间
 //
 // 注意：
-// - 文件存在则覆盖
+// - 文件存在则覆盖；写入失败时删除临时文件，原文件保持不变
 // - 目录和文件的权限由配置决定
+// - 修改时间设置失败时文件已写入，返回包装 fileutil.ErrModTime 的错误
 func (w *LocalWriter) Write(ctx context.Context, targetPath string, content string, modTime time.Time) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -165,24 +179,15 @@ func (w *LocalWriter) Write(ctx context.Context, targetPath string, content stri
 		return err
 	}
 
-	// 确保父目录存在
-	targetDir := filepath.Dir(targetPath)
-	if err := os.MkdirAll(targetDir, w.dirPerm); err != nil {
-		return fmt.Errorf("strmwriter: 创建目录 %s 失败: %w", targetDir, err)
-	}
-
-	// 写入文件
-	if err := os.WriteFile(targetPath, []byte(content), w.filePerm); err != nil {
+	err := fileutil.WriteFile(targetPath, []byte(content), fileutil.WriteOptions{
+		Perm:    w.filePerm,
+		DirPerm: w.dirPerm,
+		Sync:    w.fsync,
+		ModTime: modTime,
+	})
+	if err != nil {
 		return fmt.Errorf("strmwriter: 写入 %s 失败: %w", targetPath, err)
 	}
-
-	// 设置修改时间（如果提供）
-	// 注意：修改时间设置失败不会阻断流程，但会记录在日志中（未来实现）
-	if !modTime.IsZero() {
-		_ = os.Chtimes(targetPath, modTime, modTime)
-		// 忽略 Chtimes 错误：某些文件系统不支持修改时间
-	}
-
 	return nil
 }
 
@@ -206,6 +211,75 @@ func (w *LocalWriter) Delete(ctx context.Context, targetPath string) error {
 	return nil
 }
 
+// Stat 获取文件或目录的元信息
+//
+// 文件不存在时返回 os.IsNotExist(err)==true 的错误。
+func (w *LocalWriter) Stat(ctx context.Context, targetPath string) (fs.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := w.validatePath(targetPath); err != nil {
+		return nil, err
+	}
+	return os.Stat(targetPath)
+}
+
+// Walk 使用 filepath.WalkDir 遍历 root，遍历过程中尊重 ctx 取消
+func (w *LocalWriter) Walk(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	if err := w.validatePath(root); err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fn(p, d, err)
+	})
+}
+
+// Rename 移动文件或目录，自动创建目标父目录
+func (w *LocalWriter) Rename(ctx context.Context, from, to string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := w.validatePath(from); err != nil {
+		return err
+	}
+	if err := w.validatePath(to); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), w.dirPerm); err != nil {
+		return fmt.Errorf("strmwriter: 创建目录 %s 失败: %w", filepath.Dir(to), err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, err)
+	}
+	return nil
+}
+
+// RemoveDir 删除目录
+//
+// recursive 为 false 时使用 os.Remove，目录非空时返回错误；为 true 时整体删除。
+// 目录不存在时不返回错误。
+func (w *LocalWriter) RemoveDir(ctx context.Context, targetPath string, recursive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := w.validatePath(targetPath); err != nil {
+		return err
+	}
+	var err error
+	if recursive {
+		err = os.RemoveAll(targetPath)
+	} else {
+		err = os.Remove(targetPath)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("strmwriter: 删除目录 %s 失败: %w", targetPath, err)
+	}
+	return nil
+}
+
 // validatePath 验证路径是否在允许的范围内
 //
 // 安全检查：
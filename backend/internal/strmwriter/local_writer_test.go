@@ -0,0 +1,66 @@
+package strmwriter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+)
+
+func TestLocalWriter_WriteIsAtomic(t *testing.T) {
+	root := t.TempDir()
+	writer, err := NewLocalWriter(root, WithFsync(true))
+	if err != nil {
+		t.Fatalf("NewLocalWriter: %v", err)
+	}
+	target := filepath.Join(root, "Movies", "Movie.strm")
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx := context.Background()
+
+	for _, content := range []string{"http://a/Movie.mkv", "http://b/Movie.mkv"} {
+		if err := writer.Write(ctx, target, content, modTime); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	got, err := writer.Read(ctx, target)
+	if err != nil || got != "http://b/Movie.mkv" {
+		t.Fatalf("unexpected content %q err=%v", got, err)
+	}
+	info, _ := os.Stat(target)
+	if !info.ModTime().Equal(modTime) {
+		t.Fatalf("expected modtime %v, got %v", modTime, info.ModTime())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	for _, entry := range entries {
+		if fileutil.IsTempFile(entry.Name()) {
+			t.Fatalf("temp file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestLocalWriter_WriteFailureRemovesTempFile(t *testing.T) {
+	root := t.TempDir()
+	writer, _ := NewLocalWriter(root)
+	// 目标是非空目录时重命名失败，临时文件应被删除，目录保持不变
+	target := filepath.Join(root, "folder.strm")
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Write(context.Background(), target, "new", time.Time{}); err == nil {
+		t.Fatalf("expected write onto directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(target, "child")); err != nil {
+		t.Fatalf("expected existing directory untouched: %v", err)
+	}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if fileutil.IsTempFile(entry.Name()) {
+			t.Fatalf("temp file left behind: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package strmwriter 提供上传到 OpenList 的 STRM 写入器实现
+package strmwriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	openlistsdk "github.com/strmsync/strmsync/internal/pkg/sdk/openlist"
+)
+
+// OpenListWriter 通过 OpenList API 将 STRM 文件上传回 OpenList 存储
+//
+// 设计要点：
+// - 引擎传入的路径即 OpenList 中的路径，必须位于 root 之下
+// - 写入使用 /api/fs/put（服务端自动创建父目录），修改时间通过 Last-Modified 请求头传递
+// - 存储驱动不保留修改时间时 Stat 返回零值 ModTime，引擎只按内容判定更新
+// - 并发安全（共享的 SDK 客户端并发安全）
+type OpenListWriter struct {
+	client  *openlistsdk.Client
+	root    remoteRoot
+	modTime modTimeSupport
+}
+
+// NewOpenListWriter 创建 OpenList 上传写入器
+//
+// 参数：
+//   - root: OpenList 中的输出根目录（如 "/local/strm"）
+//   - client: OpenList SDK 客户端
+func NewOpenListWriter(root string, client *openlistsdk.Client) (*OpenListWriter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("strmwriter: openlist client 不能为 nil")
+	}
+	remote, err := newRemoteRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenListWriter{client: client, root: remote}, nil
+}
+
+// Read 读取 STRM 文件内容
+func (w *OpenListWriter) Read(ctx context.Context, targetPath string) (string, error) {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := w.client.Download(ctx, remotePath, &buf); err != nil {
+		return "", fmt.Errorf("strmwriter: 读取 %s 失败: %w", targetPath, notExist(err))
+	}
+	return buf.String(), nil
+}
+
+// Write 上传 STRM 文件（已存在时覆盖）
+//
+// 修改时间未能保留时文件已写入，返回包装 fileutil.ErrModTime 的错误（每个写入器实例只报告一次）。
+func (w *OpenListWriter) Write(ctx context.Context, targetPath string, content string, modTime time.Time) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if err := w.client.Put(ctx, remotePath, strings.NewReader(content), int64(len(content)), modTime); err != nil {
+		return fmt.Errorf("strmwriter: 写入 %s 失败: %w", targetPath, err)
+	}
+	return w.modTime.check(ctx, targetPath, modTime, w.stat)
+}
+
+// Delete 删除 STRM 文件（文件不存在不算错误）
+func (w *OpenListWriter) Delete(ctx context.Context, targetPath string) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if err := w.remove(ctx, remotePath); err != nil {
+		return fmt.Errorf("strmwriter: 删除 %s 失败: %w", targetPath, err)
+	}
+	return nil
+}
+
+// Stat 获取文件或目录的元信息（/api/fs/get）
+func (w *OpenListWriter) Stat(ctx context.Context, targetPath string) (fs.FileInfo, error) {
+	info, err := w.stat(ctx, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	return w.modTime.filter(info), nil
+}
+
+// Walk 遍历 root 下的文件和目录（逐级 /api/fs/list）
+func (w *OpenListWriter) Walk(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	remotePath, err := w.root.resolve(root)
+	if err != nil {
+		return err
+	}
+	return walkRemote(ctx, remotePath, w.Stat, w.list, fn)
+}
+
+// Rename 移动文件或目录
+//
+// OpenList 的移动与重命名是两个接口：跨目录时先移动到目标目录，再按需重命名。
+func (w *OpenListWriter) Rename(ctx context.Context, from, to string) error {
+	fromPath, err := w.root.resolve(from)
+	if err != nil {
+		return err
+	}
+	toPath, err := w.root.resolve(to)
+	if err != nil {
+		return err
+	}
+
+	current := fromPath
+	if path.Dir(fromPath) != path.Dir(toPath) {
+		if err := w.client.Mkdir(ctx, path.Dir(toPath)); err != nil {
+			return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, err)
+		}
+		if err := w.client.Move(ctx, path.Dir(fromPath), path.Dir(toPath), []string{path.Base(fromPath)}); err != nil {
+			return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, notExist(err))
+		}
+		current = path.Join(path.Dir(toPath), path.Base(fromPath))
+	}
+	if path.Base(current) != path.Base(toPath) {
+		if err := w.client.Rename(ctx, current, path.Base(toPath)); err != nil {
+			return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, notExist(err))
+		}
+	}
+	return nil
+}
+
+// RemoveDir 删除目录
+//
+// OpenList 的删除总是递归的，因此 recursive 为 false 时先确认目录为空。
+func (w *OpenListWriter) RemoveDir(ctx context.Context, targetPath string, recursive bool) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if !recursive {
+		children, err := w.list(ctx, remotePath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("strmwriter: 删除目录 %s 失败: %w", targetPath, err)
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("strmwriter: 删除目录 %s 失败: 目录非空", targetPath)
+		}
+	}
+	if err := w.remove(ctx, remotePath); err != nil {
+		return fmt.Errorf("strmwriter: 删除目录 %s 失败: %w", targetPath, err)
+	}
+	return nil
+}
+
+// stat 查询路径信息（不过滤修改时间）
+func (w *OpenListWriter) stat(ctx context.Context, targetPath string) (fs.FileInfo, error) {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	item, err := w.client.Get(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("strmwriter: 获取 %s 信息失败: %w", targetPath, notExist(err))
+	}
+	return remoteInfo{
+		name:    path.Base(remotePath),
+		size:    item.Size,
+		modTime: item.Modified,
+		isDir:   item.IsDir,
+	}, nil
+}
+
+// list 列出目录的直接子项
+func (w *OpenListWriter) list(ctx context.Context, dir string) ([]fs.FileInfo, error) {
+	items, err := w.client.List(ctx, dir)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	children := make([]fs.FileInfo, 0, len(items))
+	for _, item := range items {
+		children = append(children, w.modTime.filter(remoteInfo{
+			name:    item.Name,
+			size:    item.Size,
+			modTime: item.Modified,
+			isDir:   item.IsDir,
+		}))
+	}
+	return children, nil
+}
+
+// remove 删除文件或目录（不存在不算错误）
+func (w *OpenListWriter) remove(ctx context.Context, remotePath string) error {
+	err := w.client.Remove(ctx, path.Dir(remotePath), []string{path.Base(remotePath)})
+	if err != nil && !errors.Is(err, openlistsdk.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// notExist 将 OpenList 的不存在错误转换为可被 errors.Is(err, fs.ErrNotExist) 识别的错误
+func notExist(err error) error {
+	if errors.Is(err, openlistsdk.ErrNotFound) {
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	return err
+}
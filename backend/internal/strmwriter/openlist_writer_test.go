@@ -0,0 +1,216 @@
+package strmwriter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+	openlistsdk "github.com/strmsync/strmsync/internal/pkg/sdk/openlist"
+)
+
+// fakeOpenList 内存中的 OpenList 服务（目录以 "/" 结尾的键表示）
+type fakeOpenList struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func newFakeOpenList(t *testing.T) (*fakeOpenList, *openlistsdk.Client) {
+	t.Helper()
+	f := &fakeOpenList{files: map[string]string{"/": ""}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client, err := openlistsdk.NewClient(openlistsdk.Config{BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, client
+}
+
+func (f *fakeOpenList) mkdirAll(dir string) {
+	for d := dir; d != "/"; d = path.Dir(d) {
+		f.files[d+"/"] = ""
+	}
+}
+
+func (f *fakeOpenList) exists(p string) (isDir, ok bool) {
+	if p == "/" {
+		return true, true
+	}
+	if _, ok := f.files[p+"/"]; ok {
+		return true, true
+	}
+	_, ok = f.files[p]
+	return false, ok
+}
+
+func (f *fakeOpenList) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(data any) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 200, "message": "success", "data": data})
+	}
+	notFound := func() {
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 500, "message": "failed get dir: object not found"})
+	}
+	var req struct {
+		Path   string   `json:"path"`
+		Name   string   `json:"name"`
+		Dir    string   `json:"dir"`
+		SrcDir string   `json:"src_dir"`
+		DstDir string   `json:"dst_dir"`
+		Names  []string `json:"names"`
+	}
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+	}
+	item := func(p string, isDir bool) map[string]any {
+		return map[string]any{"name": path.Base(p), "size": len(f.files[p]), "is_dir": isDir, "modified": "2024-01-01T00:00:00Z"}
+	}
+
+	switch {
+	case r.URL.Path == "/api/fs/get":
+		isDir, ok := f.exists(req.Path)
+		if !ok {
+			notFound()
+			return
+		}
+		reply(item(req.Path, isDir))
+	case r.URL.Path == "/api/fs/list":
+		if isDir, ok := f.exists(req.Path); !ok || !isDir {
+			notFound()
+			return
+		}
+		prefix := strings.TrimSuffix(req.Path, "/") + "/"
+		var content []map[string]any
+		for key := range f.files {
+			rest := strings.TrimPrefix(key, prefix)
+			if key == prefix || !strings.HasPrefix(key, prefix) || strings.Contains(strings.TrimSuffix(rest, "/"), "/") {
+				continue
+			}
+			content = append(content, item(strings.TrimSuffix(key, "/"), strings.HasSuffix(key, "/")))
+		}
+		sort.Slice(content, func(i, j int) bool { return content[i]["name"].(string) < content[j]["name"].(string) })
+		reply(map[string]any{"content": content, "total": len(content)})
+	case r.URL.Path == "/api/fs/put":
+		p, _ := url.PathUnescape(r.Header.Get("File-Path"))
+		data, _ := io.ReadAll(r.Body)
+		f.mkdirAll(path.Dir(p))
+		f.files[p] = string(data)
+		reply(nil)
+	case r.URL.Path == "/api/fs/mkdir":
+		f.mkdirAll(req.Path)
+		reply(nil)
+	case r.URL.Path == "/api/fs/remove":
+		for _, name := range req.Names {
+			target := path.Join(req.Dir, name)
+			for key := range f.files {
+				if key == target || strings.HasPrefix(key, target+"/") {
+					delete(f.files, key)
+				}
+			}
+		}
+		reply(nil)
+	case r.URL.Path == "/api/fs/move", r.URL.Path == "/api/fs/rename":
+		from, to := req.Path, path.Join(path.Dir(req.Path), req.Name)
+		if r.URL.Path == "/api/fs/move" {
+			from, to = path.Join(req.SrcDir, req.Names[0]), path.Join(req.DstDir, req.Names[0])
+		}
+		for key, value := range f.files {
+			if key == from || strings.HasPrefix(key, from+"/") {
+				delete(f.files, key)
+				f.files[to+strings.TrimPrefix(key, from)] = value
+			}
+		}
+		reply(nil)
+	case strings.HasPrefix(r.URL.Path, "/d/"):
+		content, ok := f.files[strings.TrimPrefix(r.URL.Path, "/d")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestOpenListWriter_Operations(t *testing.T) {
+	fake, client := newFakeOpenList(t)
+	writer, err := NewOpenListWriter("/local/strm", client)
+	if err != nil {
+		t.Fatalf("NewOpenListWriter: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := writer.Stat(ctx, "/local/strm/A/a.strm"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+	// 假服务端不保留修改时间：首次写入报告 ErrModTime
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := writer.Write(ctx, "/local/strm/A/a.strm", "http://a", modTime); !errors.Is(err, fileutil.ErrModTime) {
+		t.Fatalf("expected ErrModTime, got %v", err)
+	}
+	for _, p := range []string{"/local/strm/A/a.nfo", "/local/strm/B/b.strm"} {
+		if err := writer.Write(ctx, p, "x", modTime); err != nil {
+			t.Fatalf("Write %s: %v", p, err)
+		}
+	}
+	if got, err := writer.Read(ctx, "/local/strm/A/a.strm"); err != nil || got != "http://a" {
+		t.Fatalf("unexpected content %q err=%v", got, err)
+	}
+	if info, err := writer.Stat(ctx, "/local/strm/A/a.strm"); err != nil || !info.ModTime().IsZero() {
+		t.Fatalf("expected zero modtime after detection, info=%v err=%v", info, err)
+	}
+
+	var walked []string
+	if err := writer.Walk(ctx, "/local/strm", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, p)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if strings.Join(walked, ",") != "/local/strm,/local/strm/A,/local/strm/A/a.nfo,/local/strm/A/a.strm,/local/strm/B,/local/strm/B/b.strm" {
+		t.Fatalf("unexpected walk order %v", walked)
+	}
+
+	if err := writer.Rename(ctx, "/local/strm/A/a.strm", "/local/strm/C/c.strm"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if fake.files["/local/strm/C/c.strm"] != "http://a" {
+		t.Fatalf("expected file moved and renamed, files=%v", fake.files)
+	}
+	if err := writer.RemoveDir(ctx, "/local/strm/A", false); err == nil {
+		t.Fatalf("expected non-empty dir removal to fail")
+	}
+	if err := writer.Delete(ctx, "/local/strm/A/a.nfo"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := writer.Delete(ctx, "/local/strm/A/a.nfo"); err != nil {
+		t.Fatalf("Delete should be idempotent: %v", err)
+	}
+	if err := writer.RemoveDir(ctx, "/local/strm/A", false); err != nil {
+		t.Fatalf("RemoveDir: %v", err)
+	}
+	if _, ok := fake.files["/local/strm/A/"]; ok {
+		t.Fatalf("expected directory removed")
+	}
+	if err := writer.Write(ctx, "/local/other.strm", "x", time.Time{}); err == nil {
+		t.Fatalf("expected path outside root to be rejected")
+	}
+}
@@ -0,0 +1,177 @@
+// Package strmwriter 远程写入器的公共实现
+package strmwriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+)
+
+// modTimeTolerance 判断远端是否保留修改时间时允许的误差（部分 WebDAV 服务只精确到秒）
+const modTimeTolerance = 2 * time.Second
+
+// remoteInfo 远端文件或目录的元信息，实现 fs.FileInfo
+type remoteInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (i remoteInfo) Name() string       { return i.name }
+func (i remoteInfo) Size() int64        { return i.size }
+func (i remoteInfo) ModTime() time.Time { return i.modTime }
+func (i remoteInfo) IsDir() bool        { return i.isDir }
+func (i remoteInfo) Sys() any           { return nil }
+
+func (i remoteInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// remoteRoot 远程写入器的根路径与路径校验
+//
+// 引擎传入的路径即远端路径（以 "/" 分隔），必须位于根路径之下。
+type remoteRoot string
+
+// newRemoteRoot 规范化远端根路径
+func newRemoteRoot(root string) (remoteRoot, error) {
+	trimmed := strings.TrimSpace(root)
+	if trimmed == "" {
+		return "", fmt.Errorf("strmwriter: 远端根路径不能为空")
+	}
+	return remoteRoot(cleanRemotePath(filepath.ToSlash(trimmed))), nil
+}
+
+// resolve 校验并规范化远端路径
+func (r remoteRoot) resolve(targetPath string) (string, error) {
+	cleaned := cleanRemotePath(filepath.ToSlash(targetPath))
+	root := string(r)
+	if cleaned != root && root != "/" && !strings.HasPrefix(cleaned, root+"/") {
+		return "", fmt.Errorf("strmwriter: 路径 %s 逃逸了根目录 %s", targetPath, root)
+	}
+	return cleaned, nil
+}
+
+// remoteLister 列出远端目录的直接子项
+type remoteLister func(ctx context.Context, dir string) ([]fs.FileInfo, error)
+
+// walkRemote 按 filepath.WalkDir 的语义遍历远端目录（子项按名称排序）
+func walkRemote(ctx context.Context, root string, stat func(context.Context, string) (fs.FileInfo, error), list remoteLister, fn fs.WalkDirFunc) error {
+	info, err := stat(ctx, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkRemoteDir(ctx, root, fs.FileInfoToDirEntry(info), list, fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkRemoteDir(ctx context.Context, dir string, entry fs.DirEntry, list remoteLister, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(dir, entry, nil); err != nil || !entry.IsDir() {
+		if errors.Is(err, fs.SkipDir) && entry.IsDir() {
+			return nil
+		}
+		return err
+	}
+
+	children, err := list(ctx, dir)
+	if err != nil {
+		// 与 filepath.WalkDir 一致：以目录本身再次回调并报告错误
+		if err := fn(dir, entry, err); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				return nil
+			}
+			return err
+		}
+		return nil
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+
+	for _, child := range children {
+		childPath := path.Join(dir, child.Name())
+		if err := walkRemoteDir(ctx, childPath, fs.FileInfoToDirEntry(child), list, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				// 文件回调返回 SkipDir 表示跳过所在目录的剩余子项
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// modTimeSupport 记录远端是否保留写入时指定的修改时间
+//
+// 多数远端上传接口无法设置修改时间。首次带修改时间写入后校验一次：
+// 远端未保留时，Stat 返回零值 ModTime，引擎只按内容判定是否更新，避免每次同步都重写全部 STRM。
+type modTimeSupport struct {
+	state atomic.Int32 // 0=未知 1=保留 2=不保留
+}
+
+const (
+	modTimeUnknown int32 = iota
+	modTimeKept
+	modTimeIgnored
+)
+
+// ignored 远端是否已确认不保留修改时间
+func (m *modTimeSupport) ignored() bool {
+	return m.state.Load() == modTimeIgnored
+}
+
+// filter 远端不保留修改时间时清空 info 的 ModTime
+func (m *modTimeSupport) filter(info fs.FileInfo) fs.FileInfo {
+	if !m.ignored() {
+		return info
+	}
+	return remoteInfo{name: info.Name(), size: info.Size(), isDir: info.IsDir()}
+}
+
+// check 写入后校验修改时间
+//
+// 仅在状态未知时调用 stat；首次确认远端不保留时返回包装 fileutil.ErrModTime 的错误，之后不再报告。
+func (m *modTimeSupport) check(ctx context.Context, targetPath string, want time.Time, stat func(context.Context, string) (fs.FileInfo, error)) error {
+	if want.IsZero() || m.state.Load() != modTimeUnknown {
+		return nil
+	}
+	info, err := stat(ctx, targetPath)
+	if err != nil {
+		// 无法确认时保持未知，下次写入再校验
+		return nil
+	}
+	delta := info.ModTime().Sub(want)
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= modTimeTolerance {
+		m.state.CompareAndSwap(modTimeUnknown, modTimeKept)
+		return nil
+	}
+	if m.state.CompareAndSwap(modTimeUnknown, modTimeIgnored) {
+		return fmt.Errorf("%w: %s: 远端不保留修改时间，将仅按内容判定更新", fileutil.ErrModTime, targetPath)
+	}
+	return nil
+}
+
+// markKept 远端明确确认已设置修改时间
+func (m *modTimeSupport) markKept() {
+	m.state.CompareAndSwap(modTimeUnknown, modTimeKept)
+}
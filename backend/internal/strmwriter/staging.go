@@ -0,0 +1,479 @@
+// Package strmwriter 提供暂存目录同步（生成新一代目录后整体切换）
+package strmwriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+)
+
+// SwapMode 暂存目录的切换方式
+type SwapMode string
+
+const (
+	// SwapModeRename 目标目录重命名为上一代，暂存目录重命名为目标目录
+	//
+	// 两次重命名之间目标路径短暂不存在（微秒级）。
+	SwapModeRename SwapMode = "rename"
+	// SwapModeSymlink 目标路径为指向某一代目录的符号链接，切换时原子替换链接
+	//
+	// 首次切换时将已有的目标目录移入世代目录并替换为符号链接。
+	SwapModeSymlink SwapMode = "symlink"
+)
+
+// String 返回字符串表示
+func (m SwapMode) String() string {
+	return string(m)
+}
+
+// IsValid 检查切换方式是否有效
+func (m SwapMode) IsValid() bool {
+	switch m {
+	case SwapModeRename, SwapModeSymlink:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrNoPreviousGeneration 没有保留的上一代目录可供回滚
+var ErrNoPreviousGeneration = errors.New("strmwriter: 没有可回滚的上一代目录")
+
+// 暂存相关目录均位于目标目录的父目录下，以点开头避免被媒体服务器扫描
+const (
+	stagingSuffix     = ".staging-"
+	previousSuffix    = ".previous"
+	generationsSuffix = ".generations"
+	linkTempSuffix    = ".link-tmp"
+)
+
+// Staging 一次暂存同步
+//
+// 同步写入 Dir() 返回的暂存目录，完成后调用 Commit 整体切换到目标路径。
+// 暂存目录以当前生效的目录为基础通过硬链接建立（不支持硬链接时复制），
+// 未变化的文件不会重新写入；写入器均为“临时文件 + 重命名”，不会修改共享的文件内容。
+type Staging struct {
+	target string
+	mode   SwapMode
+	dir    string
+	runID  string
+	// resumed 暂存目录是否沿用自同一执行的上次尝试
+	resumed bool
+}
+
+// PrepareStaging 准备暂存目录
+//
+// 参数：
+//   - targetPath: 任务目标目录
+//   - mode: 切换方式
+//   - runID: 本次执行标识（同一执行的重试复用同一暂存目录，从断点继续）
+//
+// 其他执行遗留的暂存目录会被删除。
+func PrepareStaging(targetPath string, mode SwapMode, runID string) (*Staging, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("strmwriter: 无效的暂存切换方式: %s", mode)
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" || strings.ContainsAny(runID, `/\`) {
+		return nil, fmt.Errorf("strmwriter: 无效的执行标识: %q", runID)
+	}
+	target, err := cleanTargetPath(targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(target)
+	switch {
+	case err == nil:
+		isLink := info.Mode()&os.ModeSymlink != 0
+		if mode == SwapModeRename && isLink {
+			return nil, fmt.Errorf("strmwriter: 目标路径 %s 是符号链接，请使用 symlink 切换方式", target)
+		}
+		if !isLink && !info.IsDir() {
+			return nil, fmt.Errorf("strmwriter: 目标路径 %s 不是目录", target)
+		}
+		if isLink {
+			// 只接管指向世代目录的链接，避免替换用户自建的符号链接
+			if _, err := currentGeneration(target); err != nil {
+				return nil, err
+			}
+		}
+	case os.IsNotExist(err):
+	default:
+		return nil, fmt.Errorf("strmwriter: 读取目标路径 %s 失败: %w", target, err)
+	}
+
+	s := &Staging{
+		target: target,
+		mode:   mode,
+		dir:    siblingPath(target, stagingSuffix+runID),
+		runID:  runID,
+	}
+	if err := s.removeStaleStaging(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(s.dir); err == nil {
+		// 同一执行的重试：保留已写入的内容
+		s.resumed = true
+		return s, nil
+	}
+	// 先建立到临时目录，完成后再重命名，避免中断后把不完整的暂存目录当作可续传
+	seeding := s.dir + ".seeding"
+	if err := os.RemoveAll(seeding); err != nil {
+		return nil, fmt.Errorf("strmwriter: 删除遗留暂存目录 %s 失败: %w", seeding, err)
+	}
+	if err := seedFromLive(target, seeding); err != nil {
+		_ = os.RemoveAll(seeding)
+		return nil, err
+	}
+	if err := os.Rename(seeding, s.dir); err != nil {
+		_ = os.RemoveAll(seeding)
+		return nil, fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", seeding, s.dir, err)
+	}
+	return s, nil
+}
+
+// Resumed 暂存目录是否沿用自同一执行的上次尝试
+//
+// 为 false 时暂存目录刚以当前生效目录为基础建立，调用方不应再按断点跳过文件。
+func (s *Staging) Resumed() bool {
+	return s.resumed
+}
+
+// Dir 返回暂存目录
+func (s *Staging) Dir() string {
+	return s.dir
+}
+
+// Target 返回目标路径
+func (s *Staging) Target() string {
+	return s.target
+}
+
+// Discard 删除暂存目录
+func (s *Staging) Discard() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("strmwriter: 删除暂存目录 %s 失败: %w", s.dir, err)
+	}
+	return nil
+}
+
+// Commit 将暂存目录切换为目标目录，并保留上一代目录用于回滚
+func (s *Staging) Commit() error {
+	if s.mode == SwapModeSymlink {
+		return s.commitSymlink()
+	}
+	return s.commitRename()
+}
+
+// commitRename 重命名切换：目标 -> 上一代，暂存 -> 目标
+func (s *Staging) commitRename() error {
+	previous := siblingPath(s.target, previousSuffix)
+	if _, err := os.Lstat(s.target); err == nil {
+		if err := os.RemoveAll(previous); err != nil {
+			return fmt.Errorf("strmwriter: 删除上一代目录 %s 失败: %w", previous, err)
+		}
+		if err := os.Rename(s.target, previous); err != nil {
+			return fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", s.target, previous, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("strmwriter: 读取目标路径 %s 失败: %w", s.target, err)
+	}
+	if err := os.Rename(s.dir, s.target); err != nil {
+		// 还原目标目录
+		if _, statErr := os.Lstat(previous); statErr == nil {
+			_ = os.Rename(previous, s.target)
+		}
+		return fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", s.dir, s.target, err)
+	}
+	return nil
+}
+
+// commitSymlink 符号链接切换：暂存目录移入世代目录，再原子替换目标链接
+func (s *Staging) commitSymlink() error {
+	generations := siblingPath(s.target, generationsSuffix)
+	if err := os.MkdirAll(generations, defaultDirPerm); err != nil {
+		return fmt.Errorf("strmwriter: 创建世代目录 %s 失败: %w", generations, err)
+	}
+
+	current, err := currentGeneration(s.target)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		// 目标为普通目录（首次切换）：移入世代目录作为上一代
+		if _, err := os.Lstat(s.target); err == nil {
+			current = generationName("initial")
+			if err := os.Rename(s.target, filepath.Join(generations, current)); err != nil {
+				return fmt.Errorf("strmwriter: 移动目标目录 %s 失败: %w", s.target, err)
+			}
+		}
+	}
+
+	name := generationName(s.runID)
+	if err := os.Rename(s.dir, filepath.Join(generations, name)); err != nil {
+		return fmt.Errorf("strmwriter: 移动暂存目录 %s 失败: %w", s.dir, err)
+	}
+	if err := flipSymlink(s.target, name); err != nil {
+		return err
+	}
+	return pruneGenerations(generations, name, current)
+}
+
+// RollbackStaging 回滚到保留的上一代目录
+//
+// 当前目录与上一代目录互换，再次调用可撤销回滚。
+func RollbackStaging(targetPath string, mode SwapMode) error {
+	if !mode.IsValid() {
+		return fmt.Errorf("strmwriter: 无效的暂存切换方式: %s", mode)
+	}
+	target, err := cleanTargetPath(targetPath)
+	if err != nil {
+		return err
+	}
+
+	if mode == SwapModeSymlink {
+		generations := siblingPath(target, generationsSuffix)
+		current, err := currentGeneration(target)
+		if err != nil {
+			return err
+		}
+		if current == "" {
+			return ErrNoPreviousGeneration
+		}
+		previous, err := otherGeneration(generations, current)
+		if err != nil {
+			return err
+		}
+		return flipSymlink(target, previous)
+	}
+
+	previous := siblingPath(target, previousSuffix)
+	if _, err := os.Lstat(previous); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNoPreviousGeneration
+		}
+		return fmt.Errorf("strmwriter: 读取上一代目录 %s 失败: %w", previous, err)
+	}
+	swap := siblingPath(target, ".rollback")
+	if err := os.Rename(target, swap); err != nil {
+		return fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", target, swap, err)
+	}
+	if err := os.Rename(previous, target); err != nil {
+		_ = os.Rename(swap, target)
+		return fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", previous, target, err)
+	}
+	if err := os.Rename(swap, previous); err != nil {
+		return fmt.Errorf("strmwriter: 重命名 %s -> %s 失败: %w", swap, previous, err)
+	}
+	return nil
+}
+
+// removeStaleStaging 删除其他执行遗留的暂存目录
+func (s *Staging) removeStaleStaging() error {
+	parent := filepath.Dir(s.target)
+	prefix := "." + filepath.Base(s.target) + stagingSuffix
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("strmwriter: 读取目录 %s 失败: %w", parent, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(parent, entry.Name())
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || path == s.dir {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("strmwriter: 删除遗留暂存目录 %s 失败: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// seedFromLive 以当前生效目录为基础建立暂存目录
+//
+// 文件优先使用硬链接，跨文件系统或不支持硬链接时复制内容并保留修改时间。
+func seedFromLive(target, staging string) error {
+	live, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(staging, defaultDirPerm); err != nil {
+				return fmt.Errorf("strmwriter: 创建暂存目录 %s 失败: %w", staging, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("strmwriter: 解析目标路径 %s 失败: %w", target, err)
+	}
+
+	err = filepath.WalkDir(live, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(live, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(staging, rel)
+		switch {
+		case d.IsDir():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(dst, info.Mode().Perm())
+		case d.Type()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		case !d.Type().IsRegular() || fileutil.IsTempFile(d.Name()):
+			return nil
+		}
+		if err := os.Link(path, dst); err == nil {
+			return nil
+		}
+		return copyFile(path, dst)
+	})
+	if err != nil {
+		return fmt.Errorf("strmwriter: 建立暂存目录 %s 失败: %w", staging, err)
+	}
+	return nil
+}
+
+// copyFile 复制文件内容、权限与修改时间
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = fileutil.WriteFileFunc(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	}, fileutil.WriteOptions{Perm: info.Mode().Perm(), ModTime: info.ModTime()})
+	return err
+}
+
+// currentGeneration 返回目标链接当前指向的世代名称（目标不是链接时返回空字符串）
+func currentGeneration(target string) (string, error) {
+	info, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("strmwriter: 读取目标路径 %s 失败: %w", target, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+	link, err := os.Readlink(target)
+	if err != nil {
+		return "", fmt.Errorf("strmwriter: 读取符号链接 %s 失败: %w", target, err)
+	}
+	if filepath.IsAbs(link) {
+		link, err = filepath.Rel(filepath.Dir(target), link)
+		if err != nil {
+			return "", fmt.Errorf("strmwriter: 解析符号链接 %s 失败: %w", target, err)
+		}
+	}
+	generations := filepath.Base(siblingPath(target, generationsSuffix))
+	dir, name := filepath.Split(filepath.Clean(link))
+	if filepath.Clean(dir) != generations {
+		return "", fmt.Errorf("strmwriter: 目标链接 %s 未指向世代目录", target)
+	}
+	return name, nil
+}
+
+// otherGeneration 返回世代目录中除当前代以外的上一代
+func otherGeneration(generations, current string) (string, error) {
+	entries, err := os.ReadDir(generations)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("strmwriter: 读取世代目录 %s 失败: %w", generations, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != current {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", ErrNoPreviousGeneration
+	}
+	sort.Strings(names)
+	return names[len(names)-1], nil
+}
+
+// flipSymlink 原子替换目标链接，使其指向指定世代
+func flipSymlink(target, generation string) error {
+	link := filepath.Join(filepath.Base(siblingPath(target, generationsSuffix)), generation)
+	tmp := siblingPath(target, linkTempSuffix)
+	_ = os.Remove(tmp)
+	if err := os.Symlink(link, tmp); err != nil {
+		return fmt.Errorf("strmwriter: 创建符号链接 %s 失败: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("strmwriter: 替换符号链接 %s 失败: %w", target, err)
+	}
+	return nil
+}
+
+// pruneGenerations 只保留当前代与上一代
+func pruneGenerations(generations, current, previous string) error {
+	entries, err := os.ReadDir(generations)
+	if err != nil {
+		return fmt.Errorf("strmwriter: 读取世代目录 %s 失败: %w", generations, err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == current || entry.Name() == previous {
+			continue
+		}
+		path := filepath.Join(generations, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("strmwriter: 删除旧世代目录 %s 失败: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// cleanTargetPath 规范化目标路径（必须为非根目录的绝对路径）
+func cleanTargetPath(targetPath string) (string, error) {
+	trimmed := strings.TrimSpace(targetPath)
+	if trimmed == "" {
+		return "", fmt.Errorf("strmwriter: 目标路径不能为空")
+	}
+	target, err := filepath.Abs(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("strmwriter: 解析目标路径 %s 失败: %w", targetPath, err)
+	}
+	if filepath.Dir(target) == target {
+		return "", fmt.Errorf("strmwriter: 目标路径不能是根目录")
+	}
+	return target, nil
+}
+
+// siblingPath 返回目标目录旁的隐藏路径：<父目录>/.<目录名><suffix>
+func siblingPath(target, suffix string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+suffix)
+}
+
+// generationName 生成世代目录名（按时间排序）
+func generationName(runID string) string {
+	return time.Now().Format("20060102-150405") + "-" + runID
+}
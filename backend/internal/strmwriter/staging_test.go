@@ -0,0 +1,177 @@
+package strmwriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTree 在 root 下写入文件（路径 -> 内容）
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestStaging_RenameCommitAndRollback(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "Movies")
+	writeTree(t, target, map[string]string{
+		"A/A.strm":   "http://a",
+		"A/A.nfo":    "<movie/>",
+		"Old/B.strm": "http://b",
+	})
+
+	staging, err := PrepareStaging(target, SwapModeRename, "task-1")
+	if err != nil {
+		t.Fatalf("PrepareStaging: %v", err)
+	}
+	if staging.Resumed() {
+		t.Fatalf("expected fresh staging")
+	}
+	// 暂存目录以当前目录为基础建立
+	if got := readFile(t, filepath.Join(staging.Dir(), "A", "A.nfo")); got != "<movie/>" {
+		t.Fatalf("unexpected seeded content %q", got)
+	}
+
+	// 在暂存目录中更新与删除，不影响当前目录
+	writer, _ := NewLocalWriter(staging.Dir())
+	ctx := context.Background()
+	if err := writer.Write(ctx, filepath.Join(staging.Dir(), "A", "A.strm"), "http://a2", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Delete(ctx, filepath.Join(staging.Dir(), "Old", "B.strm")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(target, "A", "A.strm")); got != "http://a" {
+		t.Fatalf("live file modified through hardlink: %q", got)
+	}
+
+	if err := staging.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := readFile(t, filepath.Join(target, "A", "A.strm")); got != "http://a2" {
+		t.Fatalf("unexpected committed content %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(target, "Old", "B.strm")); !os.IsNotExist(err) {
+		t.Fatalf("expected removed file absent after commit")
+	}
+	if _, err := os.Stat(staging.Dir()); !os.IsNotExist(err) {
+		t.Fatalf("expected staging dir consumed by commit")
+	}
+
+	// 回滚后恢复上一代，再次回滚撤销
+	if err := RollbackStaging(target, SwapModeRename); err != nil {
+		t.Fatalf("RollbackStaging: %v", err)
+	}
+	if got := readFile(t, filepath.Join(target, "A", "A.strm")); got != "http://a" {
+		t.Fatalf("unexpected content after rollback %q", got)
+	}
+	if err := RollbackStaging(target, SwapModeRename); err != nil {
+		t.Fatalf("RollbackStaging: %v", err)
+	}
+	if got := readFile(t, filepath.Join(target, "A", "A.strm")); got != "http://a2" {
+		t.Fatalf("unexpected content after second rollback %q", got)
+	}
+}
+
+func TestStaging_SymlinkGenerations(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "TV")
+	writeTree(t, target, map[string]string{"S/E1.strm": "v1"})
+
+	for i, content := range []string{"v2", "v3"} {
+		staging, err := PrepareStaging(target, SwapModeSymlink, fmt.Sprintf("task-%d", i+1))
+		if err != nil {
+			t.Fatalf("PrepareStaging: %v", err)
+		}
+		// 暂存文件与上一代共享硬链接，必须通过写入器替换而不是原地修改
+		writer, _ := NewLocalWriter(staging.Dir())
+		if err := writer.Write(context.Background(), filepath.Join(staging.Dir(), "S", "E1.strm"), content, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := staging.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if got := readFile(t, filepath.Join(target, "S", "E1.strm")); got != content {
+			t.Fatalf("expected %q, got %q", content, got)
+		}
+	}
+
+	info, err := os.Lstat(target)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected target to be a symlink: %v", err)
+	}
+	entries, _ := os.ReadDir(siblingPath(target, generationsSuffix))
+	if len(entries) != 2 {
+		t.Fatalf("expected current and previous generations kept, got %d", len(entries))
+	}
+
+	if err := RollbackStaging(target, SwapModeSymlink); err != nil {
+		t.Fatalf("RollbackStaging: %v", err)
+	}
+	if got := readFile(t, filepath.Join(target, "S", "E1.strm")); got != "v2" {
+		t.Fatalf("expected previous generation after rollback, got %q", got)
+	}
+}
+
+func TestStaging_ResumeAndStaleCleanup(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "Movies")
+	writeTree(t, target, map[string]string{"A.strm": "a"})
+
+	first, err := PrepareStaging(target, SwapModeRename, "task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, first.Dir(), map[string]string{"B.strm": "b"})
+
+	// 同一执行重试：沿用已写入的内容
+	again, err := PrepareStaging(target, SwapModeRename, "task-1")
+	if err != nil || !again.Resumed() {
+		t.Fatalf("expected resumed staging, err=%v", err)
+	}
+	if got := readFile(t, filepath.Join(again.Dir(), "B.strm")); got != "b" {
+		t.Fatalf("unexpected resumed content %q", got)
+	}
+
+	// 新的执行删除遗留的暂存目录
+	next, err := PrepareStaging(target, SwapModeRename, "task-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(first.Dir()); !os.IsNotExist(err) {
+		t.Fatalf("expected stale staging removed")
+	}
+	if _, err := os.Stat(filepath.Join(next.Dir(), "B.strm")); !os.IsNotExist(err) {
+		t.Fatalf("expected fresh staging seeded from live target only")
+	}
+}
+
+func TestRollbackStaging_NoPreviousGeneration(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "Movies")
+	writeTree(t, target, map[string]string{"A.strm": "a"})
+	for _, mode := range []SwapMode{SwapModeRename, SwapModeSymlink} {
+		if err := RollbackStaging(target, mode); !errors.Is(err, ErrNoPreviousGeneration) {
+			t.Fatalf("%s: expected ErrNoPreviousGeneration, got %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// Package strmwriter 提供 WebDAV 远程 STRM 写入器实现
+package strmwriter
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxWebDAVErrorBody 读取错误响应体的最大字节数
+	maxWebDAVErrorBody = 4096
+
+	// propfindBody 只请求引擎需要的属性
+	propfindBody = `<?xml version="1.0" encoding="utf-8"?>` +
+		`<d:propfind xmlns:d="DAV:"><d:prop>` +
+		`<d:resourcetype/><d:getcontentlength/><d:getlastmodified/>` +
+		`</d:prop></d:propfind>`
+)
+
+// WebDAVConfig WebDAV 写入器配置
+type WebDAVConfig struct {
+	URL        string        // WebDAV 服务地址（如 "http://emby-host:8080/dav"）
+	Username   string        // Basic 认证用户名（可选）
+	Password   string        // Basic 认证密码
+	Timeout    time.Duration // 请求超时（默认 30 秒）
+	HTTPClient *http.Client  // 可选的自定义 HTTP 客户端
+}
+
+// WebDAVWriter 将 STRM 文件写入 WebDAV 服务（媒体服务器位于其他主机时使用）
+//
+// 设计要点：
+// - 引擎传入的路径即 WebDAV 服务上的路径，必须位于 root 之下
+// - 写入使用 PUT（服务端整体替换，不会出现半截文件），父目录按需 MKCOL
+// - 通过 X-OC-Mtime 请求头设置修改时间（Nextcloud、rclone 等支持）
+// - 服务端不保留修改时间时 Stat 返回零值 ModTime，引擎只按内容判定更新
+// - 并发安全
+type WebDAVWriter struct {
+	baseURL  *url.URL
+	root     remoteRoot
+	username string
+	password string
+	client   *http.Client
+
+	modTime modTimeSupport
+	dirs    sync.Map // 已确认存在的目录
+}
+
+// NewWebDAVWriter 创建 WebDAV 写入器
+//
+// 参数：
+//   - root: WebDAV 服务上的输出根目录（如 "/strm/movies"）
+//   - cfg: 连接配置
+func NewWebDAVWriter(root string, cfg WebDAVConfig) (*WebDAVWriter, error) {
+	baseURL, err := url.Parse(strings.TrimSpace(cfg.URL))
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return nil, fmt.Errorf("strmwriter: 无效的 WebDAV 地址: %s", cfg.URL)
+	}
+	remote, err := newRemoteRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &WebDAVWriter{
+		baseURL:  baseURL,
+		root:     remote,
+		username: strings.TrimSpace(cfg.Username),
+		password: cfg.Password,
+		client:   client,
+	}, nil
+}
+
+// Read 读取 STRM 文件内容（GET）
+func (w *WebDAVWriter) Read(ctx context.Context, targetPath string) (string, error) {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return "", err
+	}
+	resp, err := w.do(ctx, http.MethodGet, remotePath, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("strmwriter: 读取 %s 失败: %w", targetPath, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, http.StatusOK); err != nil {
+		return "", fmt.Errorf("strmwriter: 读取 %s 失败: %w", targetPath, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("strmwriter: 读取 %s 失败: %w", targetPath, err)
+	}
+	return string(data), nil
+}
+
+// Write 创建或覆盖 STRM 文件（PUT）
+//
+// 修改时间未能保留时文件已写入，返回包装 fileutil.ErrModTime 的错误（每个写入器实例只报告一次）。
+func (w *WebDAVWriter) Write(ctx context.Context, targetPath string, content string, modTime time.Time) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if err := w.mkdirAll(ctx, path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("strmwriter: 写入 %s 失败: %w", targetPath, err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	if !modTime.IsZero() && !w.modTime.ignored() {
+		header.Set("X-OC-Mtime", strconv.FormatInt(modTime.Unix(), 10))
+	}
+	resp, err := w.do(ctx, http.MethodPut, remotePath, header, strings.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("strmwriter: 写入 %s 失败: %w", targetPath, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, http.StatusOK, http.StatusCreated, http.StatusNoContent); err != nil {
+		return fmt.Errorf("strmwriter: 写入 %s 失败: %w", targetPath, err)
+	}
+
+	if strings.EqualFold(resp.Header.Get("X-OC-Mtime"), "accepted") {
+		w.modTime.markKept()
+		return nil
+	}
+	return w.modTime.check(ctx, targetPath, modTime, w.stat)
+}
+
+// Delete 删除 STRM 文件（DELETE，文件不存在不算错误）
+func (w *WebDAVWriter) Delete(ctx context.Context, targetPath string) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if err := w.delete(ctx, remotePath); err != nil {
+		return fmt.Errorf("strmwriter: 删除 %s 失败: %w", targetPath, err)
+	}
+	return nil
+}
+
+// Stat 获取文件或目录的元信息（PROPFIND Depth: 0）
+func (w *WebDAVWriter) Stat(ctx context.Context, targetPath string) (fs.FileInfo, error) {
+	info, err := w.stat(ctx, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	return w.modTime.filter(info), nil
+}
+
+// Walk 遍历 root 下的文件和目录（逐级 PROPFIND Depth: 1）
+func (w *WebDAVWriter) Walk(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	remotePath, err := w.root.resolve(root)
+	if err != nil {
+		return err
+	}
+	return walkRemote(ctx, remotePath, w.Stat, w.list, fn)
+}
+
+// Rename 移动文件或目录（MOVE，不覆盖已存在的目标）
+func (w *WebDAVWriter) Rename(ctx context.Context, from, to string) error {
+	fromPath, err := w.root.resolve(from)
+	if err != nil {
+		return err
+	}
+	toPath, err := w.root.resolve(to)
+	if err != nil {
+		return err
+	}
+	if err := w.mkdirAll(ctx, path.Dir(toPath)); err != nil {
+		return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, err)
+	}
+
+	header := http.Header{}
+	header.Set("Destination", w.url(toPath))
+	header.Set("Overwrite", "F")
+	resp, err := w.do(ctx, "MOVE", fromPath, header, nil)
+	if err != nil {
+		return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, http.StatusCreated, http.StatusNoContent); err != nil {
+		return fmt.Errorf("strmwriter: 移动 %s 失败: %w", from, err)
+	}
+	w.dirs.Delete(fromPath)
+	return nil
+}
+
+// RemoveDir 删除目录
+//
+// WebDAV 的 DELETE 总是递归删除，因此 recursive 为 false 时先确认目录为空。
+func (w *WebDAVWriter) RemoveDir(ctx context.Context, targetPath string, recursive bool) error {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return err
+	}
+	if !recursive {
+		children, err := w.list(ctx, remotePath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("strmwriter: 删除目录 %s 失败: %w", targetPath, err)
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("strmwriter: 删除目录 %s 失败: 目录非空", targetPath)
+		}
+	}
+	if err := w.delete(ctx, remotePath); err != nil {
+		return fmt.Errorf("strmwriter: 删除目录 %s 失败: %w", targetPath, err)
+	}
+	w.dirs.Delete(remotePath)
+	return nil
+}
+
+// stat 执行 PROPFIND Depth: 0（不过滤修改时间）
+func (w *WebDAVWriter) stat(ctx context.Context, targetPath string) (fs.FileInfo, error) {
+	remotePath, err := w.root.resolve(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := w.propfind(ctx, remotePath, "0")
+	if err != nil {
+		return nil, fmt.Errorf("strmwriter: 获取 %s 信息失败: %w", targetPath, err)
+	}
+	for _, info := range infos {
+		if info.self {
+			return info.remoteInfo, nil
+		}
+	}
+	return nil, fmt.Errorf("strmwriter: 获取 %s 信息失败: 响应中缺少该路径", targetPath)
+}
+
+// list 列出目录的直接子项（PROPFIND Depth: 1）
+func (w *WebDAVWriter) list(ctx context.Context, dir string) ([]fs.FileInfo, error) {
+	infos, err := w.propfind(ctx, dir, "1")
+	if err != nil {
+		return nil, err
+	}
+	children := make([]fs.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if !info.self {
+			children = append(children, w.modTime.filter(info.remoteInfo))
+		}
+	}
+	return children, nil
+}
+
+// propfindEntry PROPFIND 响应中的一项
+type propfindEntry struct {
+	remoteInfo
+	self bool // 是否为请求的路径本身
+}
+
+// propfind 查询路径属性
+func (w *WebDAVWriter) propfind(ctx context.Context, remotePath, depth string) ([]propfindEntry, error) {
+	header := http.Header{}
+	header.Set("Depth", depth)
+	header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := w.do(ctx, "PROPFIND", remotePath, header, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, http.StatusMultiStatus); err != nil {
+		return nil, err
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("decode propfind response: %w", err)
+	}
+
+	requested := strings.TrimSuffix(w.urlPath(remotePath), "/")
+	entries := make([]propfindEntry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		hrefPath := r.Href
+		if u, err := url.Parse(r.Href); err == nil {
+			hrefPath = u.Path
+		}
+		hrefPath = strings.TrimSuffix(hrefPath, "/")
+		prop, ok := r.okProp()
+		if !ok {
+			continue
+		}
+		modTime, _ := http.ParseTime(prop.LastModified)
+		size, _ := strconv.ParseInt(strings.TrimSpace(prop.ContentLength), 10, 64)
+		entries = append(entries, propfindEntry{
+			remoteInfo: remoteInfo{
+				name:    path.Base(hrefPath),
+				size:    size,
+				modTime: modTime,
+				isDir:   prop.ResourceType.Collection != nil,
+			},
+			self: hrefPath == requested,
+		})
+	}
+	return entries, nil
+}
+
+// mkdirAll 逐级创建目录（MKCOL），已确认存在的目录会被缓存
+func (w *WebDAVWriter) mkdirAll(ctx context.Context, dir string) error {
+	if dir == "/" || dir == "." {
+		return nil
+	}
+	if _, ok := w.dirs.Load(dir); ok {
+		return nil
+	}
+	if err := w.mkdirAll(ctx, path.Dir(dir)); err != nil {
+		return err
+	}
+
+	resp, err := w.do(ctx, "MKCOL", dir, nil, nil)
+	if err != nil {
+		return fmt.Errorf("create directory %s: %w", dir, err)
+	}
+	defer resp.Body.Close()
+	// 405：目录已存在
+	if err := checkStatus(resp, http.StatusCreated, http.StatusMethodNotAllowed); err != nil {
+		return fmt.Errorf("create directory %s: %w", dir, err)
+	}
+	w.dirs.Store(dir, struct{}{})
+	return nil
+}
+
+// delete 执行 DELETE（不存在不算错误）
+func (w *WebDAVWriter) delete(ctx context.Context, remotePath string) error {
+	resp, err := w.do(ctx, http.MethodDelete, remotePath, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkStatus(resp, http.StatusOK, http.StatusNoContent)
+}
+
+// do 发送 WebDAV 请求
+func (w *WebDAVWriter) do(ctx context.Context, method, remotePath string, header http.Header, body io.Reader) (*http.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.url(remotePath), body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+// urlPath 远端路径对应的 URL 路径（拼接服务地址中的路径前缀）
+func (w *WebDAVWriter) urlPath(remotePath string) string {
+	return strings.TrimRight(w.baseURL.Path, "/") + remotePath
+}
+
+// url 远端路径对应的完整 URL
+func (w *WebDAVWriter) url(remotePath string) string {
+	u := *w.baseURL
+	u.Path = w.urlPath(remotePath)
+	u.RawPath = ""
+	return u.String()
+}
+
+// checkStatus 检查响应状态码，404 返回可被 errors.Is(err, fs.ErrNotExist) 识别的错误
+func checkStatus(resp *http.Response, expected ...int) error {
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("http status 404: %w", fs.ErrNotExist)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebDAVErrorBody))
+	return fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// davMultistatus PROPFIND 响应
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"href"`
+	Propstat []davPropstat `xml:"propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"collection"`
+	} `xml:"resourcetype"`
+	ContentLength string `xml:"getcontentlength"`
+	LastModified  string `xml:"getlastmodified"`
+}
+
+// okProp 返回状态为 200 的属性集合
+func (r davResponse) okProp() (davProp, bool) {
+	for _, ps := range r.Propstat {
+		if strings.Contains(ps.Status, " 200") {
+			return ps.Prop, true
+		}
+	}
+	return davProp{}, false
+}
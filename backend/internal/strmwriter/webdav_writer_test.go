@@ -0,0 +1,178 @@
+package strmwriter
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/pkg/fileutil"
+	"golang.org/x/net/webdav"
+)
+
+// newWebDAVServer 启动以临时目录为存储的 WebDAV 服务，返回服务地址与存储目录
+func newWebDAVServer(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(dir),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL + "/dav", dir
+}
+
+func TestWebDAVWriter_ReadWriteStatDelete(t *testing.T) {
+	url, storage := newWebDAVServer(t)
+	writer, err := NewWebDAVWriter("/strm", WebDAVConfig{URL: url})
+	if err != nil {
+		t.Fatalf("NewWebDAVWriter: %v", err)
+	}
+	ctx := context.Background()
+	target := "/strm/Movies/电影 (2024)/电影 (2024).strm"
+
+	if _, err := writer.Stat(ctx, target); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist before write, got %v", err)
+	}
+	if _, err := writer.Read(ctx, target); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist on read, got %v", err)
+	}
+
+	// x/net/webdav 不支持 X-OC-Mtime：首次写入报告 ErrModTime，之后 Stat 返回零值 ModTime
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := writer.Write(ctx, target, "http://a/movie.mkv", modTime); !errors.Is(err, fileutil.ErrModTime) {
+		t.Fatalf("expected ErrModTime on first write, got %v", err)
+	}
+	if err := writer.Write(ctx, target, "http://b/movie.mkv", modTime); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(storage, "strm", "Movies", "电影 (2024)", "电影 (2024).strm"))
+	if err != nil || string(data) != "http://b/movie.mkv" {
+		t.Fatalf("unexpected stored content %q err=%v", data, err)
+	}
+	got, err := writer.Read(ctx, target)
+	if err != nil || got != "http://b/movie.mkv" {
+		t.Fatalf("unexpected content %q err=%v", got, err)
+	}
+	info, err := writer.Stat(ctx, target)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.IsDir() || info.Size() != int64(len("http://b/movie.mkv")) || !info.ModTime().IsZero() {
+		t.Fatalf("unexpected info: dir=%v size=%d mtime=%v", info.IsDir(), info.Size(), info.ModTime())
+	}
+
+	if err := writer.Delete(ctx, target); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := writer.Delete(ctx, target); err != nil {
+		t.Fatalf("Delete should be idempotent: %v", err)
+	}
+	if err := writer.Write(ctx, "/other/escape.strm", "x", time.Time{}); err == nil {
+		t.Fatalf("expected path outside root to be rejected")
+	}
+}
+
+func TestWebDAVWriter_WalkRenameRemoveDir(t *testing.T) {
+	url, storage := newWebDAVServer(t)
+	writer, _ := NewWebDAVWriter("/strm", WebDAVConfig{URL: url})
+	ctx := context.Background()
+	for _, p := range []string{"/strm/B/b.strm", "/strm/A/a.strm", "/strm/A/a.nfo", "/strm/A/extrafanart/1.jpg"} {
+		if err := writer.Write(ctx, p, "x", time.Time{}); err != nil {
+			t.Fatalf("Write %s: %v", p, err)
+		}
+	}
+
+	var walked []string
+	err := writer.Walk(ctx, "/strm", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "extrafanart" {
+			return fs.SkipDir
+		}
+		walked = append(walked, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want := []string{"/strm", "/strm/A", "/strm/A/a.nfo", "/strm/A/a.strm", "/strm/B", "/strm/B/b.strm"}
+	if len(walked) != len(want) {
+		t.Fatalf("expected %v, got %v", want, walked)
+	}
+	for i := range want {
+		if walked[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, walked)
+		}
+	}
+
+	// 根目录不存在时以 ErrNotExist 回调
+	var missingErr error
+	_ = writer.Walk(ctx, "/strm/missing", func(_ string, _ fs.DirEntry, err error) error {
+		missingErr = err
+		return nil
+	})
+	if !errors.Is(missingErr, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist for missing root, got %v", missingErr)
+	}
+
+	if err := writer.Rename(ctx, "/strm/A/a.strm", "/strm/C/D/a.strm"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storage, "strm", "C", "D", "a.strm")); err != nil {
+		t.Fatalf("expected moved file: %v", err)
+	}
+
+	if err := writer.RemoveDir(ctx, "/strm/A", false); err == nil {
+		t.Fatalf("expected non-recursive removal of non-empty dir to fail")
+	}
+	if err := writer.RemoveDir(ctx, "/strm/A/extrafanart", true); err != nil {
+		t.Fatalf("RemoveDir recursive: %v", err)
+	}
+	if err := writer.Delete(ctx, "/strm/A/a.nfo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.RemoveDir(ctx, "/strm/A", false); err != nil {
+		t.Fatalf("RemoveDir empty: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storage, "strm", "A")); !os.IsNotExist(err) {
+		t.Fatalf("expected directory removed, got %v", err)
+	}
+}
+
+func TestWebDAVWriter_KeepsModTimeWhenAccepted(t *testing.T) {
+	// 模拟支持 X-OC-Mtime 的服务端：PUT 后设置文件时间并确认
+	modTime := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	var stored string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.Header.Get("X-OC-Mtime") != "" {
+			w.Header().Set("X-OC-Mtime", "accepted")
+			stored = r.URL.Path
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if r.Method == "MKCOL" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Error(w, "unexpected", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	writer, _ := NewWebDAVWriter("/strm", WebDAVConfig{URL: server.URL + "/dav"})
+	if err := writer.Write(context.Background(), "/strm/a.strm", "x", modTime); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if stored != "/dav/strm/a.strm" || writer.modTime.ignored() {
+		t.Fatalf("unexpected state: stored=%q ignored=%v", stored, writer.modTime.ignored())
+	}
+}
@@ -0,0 +1,139 @@
+// Package http 提供HTTP API处理器
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
+	"go.uber.org/zap"
+)
+
+// BackupService 数据库备份服务接口（用于注入）
+type BackupService interface {
+	List(ctx context.Context) ([]dbpkg.BackupInfo, error)
+	Create(ctx context.Context, reason string) (*dbpkg.BackupInfo, error)
+	Path(name string) (string, error)
+	Restore(ctx context.Context, name string) (*dbpkg.RestoreResult, error)
+}
+
+// SchedulerReloader 重新加载调度计划（可选能力，通过类型断言检测）
+type SchedulerReloader interface {
+	Reload(ctx context.Context) error
+}
+
+// BackupHandler 数据库备份处理器
+type BackupHandler struct {
+	backups   BackupService
+	scheduler SchedulerReloader
+	logger    *zap.Logger
+}
+
+// NewBackupHandler 创建数据库备份处理器
+// backups 为 nil 表示当前数据库不支持内置备份；scheduler 可为 nil
+func NewBackupHandler(backups BackupService, scheduler SchedulerReloader, logger *zap.Logger) *BackupHandler {
+	return &BackupHandler{
+		backups:   backups,
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// ListBackups 获取备份列表（按时间倒序）
+// GET /api/backups
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	backups, err := h.backups.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("查询备份列表失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "backup_error", "查询备份失败", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+		"total":   len(backups),
+	})
+}
+
+// CreateBackup 立即备份数据库
+// POST /api/backups
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	backup, err := h.backups.Create(c.Request.Context(), dbpkg.BackupReasonManual)
+	if err != nil {
+		h.logger.Error("备份数据库失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "backup_error", "备份失败: "+err.Error(), nil)
+		return
+	}
+	h.logger.Info("手动备份数据库成功", zap.String("name", backup.Name))
+	c.JSON(http.StatusCreated, backup)
+}
+
+// DownloadBackup 下载备份文件
+// GET /api/backups/:name/download
+func (h *BackupHandler) DownloadBackup(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	name := strings.TrimSpace(c.Param("name"))
+	path, err := h.backups.Path(name)
+	if err != nil {
+		h.respondBackupError(c, err, "查询备份失败")
+		return
+	}
+	c.FileAttachment(path, name)
+}
+
+// RestoreBackup 从备份恢复数据库
+// POST /api/backups/:name/restore
+//
+// 有任务正在执行时返回 409；恢复前会自动备份当前数据库（响应中的 pre_restore），
+// 恢复后重新加载任务调度计划。
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	name := strings.TrimSpace(c.Param("name"))
+	result, err := h.backups.Restore(c.Request.Context(), name)
+	if err != nil {
+		h.respondBackupError(c, err, "恢复失败")
+		return
+	}
+
+	if h.scheduler != nil {
+		if err := h.scheduler.Reload(c.Request.Context()); err != nil {
+			h.logger.Warn("恢复后重新加载调度计划失败", zap.Error(err))
+		}
+	}
+	h.logger.Info("从备份恢复数据库成功", zap.String("name", name))
+	c.JSON(http.StatusOK, result)
+}
+
+// available 当前数据库不支持内置备份时返回 501
+func (h *BackupHandler) available(c *gin.Context) bool {
+	if h.backups == nil {
+		respondError(c, http.StatusNotImplemented, "backup_unsupported", "当前数据库不支持内置备份（仅支持 SQLite）", nil)
+		return false
+	}
+	return true
+}
+
+// respondBackupError 将备份服务错误转换为响应
+func (h *BackupHandler) respondBackupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, dbpkg.ErrBackupNotFound):
+		respondError(c, http.StatusNotFound, "not_found", "备份不存在", nil)
+	case errors.Is(err, dbpkg.ErrBackupBusy):
+		respondError(c, http.StatusConflict, "backup_busy", err.Error(), nil)
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("name", c.Param("name")))
+		respondError(c, http.StatusInternalServerError, "backup_error", message+": "+err.Error(), nil)
+	}
+}
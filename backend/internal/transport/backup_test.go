@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	dbpkg "github.com/strmsync/strmsync/internal/infra/db"
+	"go.uber.org/zap"
+)
+
+type fakeBackupService struct {
+	dir      string
+	backups  []dbpkg.BackupInfo
+	restored string
+	err      error
+}
+
+func (f *fakeBackupService) List(ctx context.Context) ([]dbpkg.BackupInfo, error) {
+	return f.backups, nil
+}
+
+func (f *fakeBackupService) Create(ctx context.Context, reason string) (*dbpkg.BackupInfo, error) {
+	info := dbpkg.BackupInfo{Name: "strmsync-20260101-030000.000-" + reason + ".db", Reason: reason}
+	f.backups = append(f.backups, info)
+	return &info, nil
+}
+
+func (f *fakeBackupService) Path(name string) (string, error) {
+	for _, b := range f.backups {
+		if b.Name == name {
+			return filepath.Join(f.dir, name), nil
+		}
+	}
+	return "", dbpkg.ErrBackupNotFound
+}
+
+func (f *fakeBackupService) Restore(ctx context.Context, name string) (*dbpkg.RestoreResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if _, err := f.Path(name); err != nil {
+		return nil, err
+	}
+	f.restored = name
+	return &dbpkg.RestoreResult{Backup: dbpkg.BackupInfo{Name: name}}, nil
+}
+
+type fakeReloader struct{ reloads int }
+
+func (f *fakeReloader) Reload(ctx context.Context) error {
+	f.reloads++
+	return nil
+}
+
+func newBackupTestRouter(h *BackupHandler) *gin.Engine {
+	r := gin.New()
+	r.GET("/api/backups", h.ListBackups)
+	r.POST("/api/backups", h.CreateBackup)
+	r.GET("/api/backups/:name/download", h.DownloadBackup)
+	r.POST("/api/backups/:name/restore", h.RestoreBackup)
+	return r
+}
+
+func TestBackupHandler(t *testing.T) {
+	service := &fakeBackupService{dir: t.TempDir()}
+	reloader := &fakeReloader{}
+	r := newBackupTestRouter(NewBackupHandler(service, reloader, zap.NewNop()))
+
+	w := doReq(r, http.MethodPost, "/api/backups", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status=%d body=%s", w.Code, w.Body.String())
+	}
+	var created dbpkg.BackupInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.Reason != dbpkg.BackupReasonManual {
+		t.Fatalf("unexpected create response: %s", w.Body.String())
+	}
+	if err := os.WriteFile(filepath.Join(service.dir, created.Name), []byte("backup"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	w = doReq(r, http.MethodGet, "/api/backups", nil)
+	var list struct {
+		Backups []dbpkg.BackupInfo `json:"backups"`
+		Total   int                `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.Total != 1 {
+		t.Fatalf("unexpected list response: %s", w.Body.String())
+	}
+
+	w = doReq(r, http.MethodGet, "/api/backups/"+created.Name+"/download", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "backup" {
+		t.Fatalf("download status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w := doReq(r, http.MethodGet, "/api/backups/missing.db/download", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing backup, got %d", w.Code)
+	}
+
+	w = doReq(r, http.MethodPost, "/api/backups/"+created.Name+"/restore", nil)
+	if w.Code != http.StatusOK || service.restored != created.Name || reloader.reloads != 1 {
+		t.Fatalf("restore status=%d body=%s reloads=%d", w.Code, w.Body.String(), reloader.reloads)
+	}
+
+	service.err = dbpkg.ErrBackupBusy
+	if w := doReq(r, http.MethodPost, "/api/backups/"+created.Name+"/restore", nil); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when tasks are running, got %d", w.Code)
+	}
+}
+
+func TestBackupHandler_Unsupported(t *testing.T) {
+	r := newBackupTestRouter(NewBackupHandler(nil, nil, zap.NewNop()))
+	if w := doReq(r, http.MethodGet, "/api/backups", nil); w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", w.Code)
+	}
+}
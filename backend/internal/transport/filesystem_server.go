@@ -38,6 +38,15 @@ type dataServerRequest struct {
 	APIRate             *int `json:"api_rate,omitempty"`
 	APIRetryMax         *int `json:"api_retry_max,omitempty"`
 	APIRetryIntervalSec *int `json:"api_retry_interval_sec,omitempty"`
+	// MaxConcurrentRuns 同时执行的任务数上限（0 表示默认 1）
+	MaxConcurrentRuns *int `json:"max_concurrent_runs,omitempty"`
+}
+
+// validateMaxConcurrentRuns 校验并发上限（可选，不能为负数）
+func validateMaxConcurrentRuns(value *int, fieldErrors *[]FieldError) {
+	if value != nil && *value < 0 {
+		*fieldErrors = append(*fieldErrors, FieldError{Field: "max_concurrent_runs", Message: "不能为负数"})
+	}
 }
 
 // NewDataServerHandler 创建数据服务器处理器
@@ -60,6 +69,7 @@ func (h *DataServerHandler) CreateDataServer(c *gin.Context) {
 
 	// 参数验证（使用类型特定验证器）
 	fieldErrors := validateDataServerRequest(req.Name, req.Type, req.Host, req.Port, req.APIKey, req.Options)
+	validateMaxConcurrentRuns(req.MaxConcurrentRuns, &fieldErrors)
 	if len(fieldErrors) > 0 {
 		respondValidationError(c, fieldErrors)
 		return
@@ -102,6 +112,10 @@ func (h *DataServerHandler) CreateDataServer(c *gin.Context) {
 	if req.APIRetryIntervalSec != nil {
 		apiRetryIntervalSec = *req.APIRetryIntervalSec
 	}
+	maxConcurrentRuns := 0
+	if req.MaxConcurrentRuns != nil {
+		maxConcurrentRuns = *req.MaxConcurrentRuns
+	}
 
 	// 创建数据服务器
 	server := model.DataServer{
@@ -116,6 +130,7 @@ func (h *DataServerHandler) CreateDataServer(c *gin.Context) {
 		APIRate:             apiRate,
 		APIRetryMax:         apiRetryMax,
 		APIRetryIntervalSec: apiRetryIntervalSec,
+		MaxConcurrentRuns:   maxConcurrentRuns,
 	}
 
 	// Local 类型特殊处理：强制设置 host 和 port
@@ -136,12 +151,12 @@ func (h *DataServerHandler) CreateDataServer(c *gin.Context) {
 			"api_rate":               server.APIRate,
 			"api_retry_max":          server.APIRetryMax,
 			"api_retry_interval_sec": server.APIRetryIntervalSec,
+			"max_concurrent_runs":    server.MaxConcurrentRuns,
 		})))
 
 	if err := h.db.Create(&server).Error; err != nil {
 		// 检查是否为唯一约束错误
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			// 区分是名称重复还是配置重复（UID冲突）
 			if strings.Contains(err.Error(), "uid") || strings.Contains(err.Error(), "UID") {
 				respondError(c, http.StatusConflict, "duplicate_config", "相同配置的服务器已存在，请修改服务器配置", nil)
@@ -264,6 +279,7 @@ func (h *DataServerHandler) UpdateDataServer(c *gin.Context) {
 
 	// 参数验证（使用类型特定验证器）
 	fieldErrors := validateDataServerRequest(req.Name, req.Type, req.Host, req.Port, req.APIKey, req.Options)
+	validateMaxConcurrentRuns(req.MaxConcurrentRuns, &fieldErrors)
 	if len(fieldErrors) > 0 {
 		respondValidationError(c, fieldErrors)
 		return
@@ -323,6 +339,9 @@ func (h *DataServerHandler) UpdateDataServer(c *gin.Context) {
 	if req.APIRetryIntervalSec != nil {
 		server.APIRetryIntervalSec = *req.APIRetryIntervalSec
 	}
+	if req.MaxConcurrentRuns != nil {
+		server.MaxConcurrentRuns = *req.MaxConcurrentRuns
+	}
 
 	// Local 类型特殊处理：强制设置 host 和 port
 	if strings.EqualFold(server.Type, "local") {
@@ -343,12 +362,12 @@ func (h *DataServerHandler) UpdateDataServer(c *gin.Context) {
 			"api_rate":               server.APIRate,
 			"api_retry_max":          server.APIRetryMax,
 			"api_retry_interval_sec": server.APIRetryIntervalSec,
+			"max_concurrent_runs":    server.MaxConcurrentRuns,
 		})))
 
 	if err := h.db.Save(&server).Error; err != nil {
 		// 检查是否为唯一约束错误
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			// 区分是名称重复还是配置重复（UID冲突）
 			if strings.Contains(err.Error(), "uid") || strings.Contains(err.Error(), "UID") {
 				respondError(c, http.StatusConflict, "duplicate_config", "相同配置的服务器已存在，请修改服务器配置", nil)
@@ -504,9 +523,9 @@ func (h *DataServerHandler) TestDataServerConnection(c *gin.Context) {
 	var result ConnectionTestResult
 	switch strings.TrimSpace(server.Type) {
 	case "clouddrive2":
-		result = testCloudDrive2Connection(server, h.logger)
+		result = testCloudDrive2Connection(c.Request.Context(), server, h.logger)
 	case "openlist":
-		result = testOpenListConnection(server, h.logger)
+		result = testOpenListConnection(c.Request.Context(), server, h.logger)
 	default:
 		respondError(c, http.StatusBadRequest, "invalid_type", "不支持的服务器类型", nil)
 		return
@@ -567,9 +586,9 @@ func (h *DataServerHandler) TestDataServerTemp(c *gin.Context) {
 	var result ConnectionTestResult
 	switch strings.TrimSpace(server.Type) {
 	case "clouddrive2":
-		result = testCloudDrive2Connection(server, h.logger)
+		result = testCloudDrive2Connection(c.Request.Context(), server, h.logger)
 	case "openlist":
-		result = testOpenListConnection(server, h.logger)
+		result = testOpenListConnection(c.Request.Context(), server, h.logger)
 	case "local":
 		// local 类型无需远程连接，直接返回成功
 		result = ConnectionTestResult{
@@ -593,7 +612,7 @@ func (h *DataServerHandler) TestDataServerTemp(c *gin.Context) {
 //
 // CloudDrive2 使用 gRPC/HTTP2 协议，通过调用 GetSystemInfo（公开接口）
 // 来验证服务器连接和认证信息
-func testCloudDrive2Connection(server model.DataServer, logger *zap.Logger) ConnectionTestResult {
+func testCloudDrive2Connection(ctx context.Context, server model.DataServer, logger *zap.Logger) ConnectionTestResult {
 	start := time.Now()
 	target := fmt.Sprintf("%s:%d", server.Host, server.Port)
 
@@ -610,7 +629,6 @@ func testCloudDrive2Connection(server model.DataServer, logger *zap.Logger) Conn
 	}()
 
 	// 调用 GetSystemInfo（公开接口，可验证服务器是否可达）
-	ctx := context.Background()
 	info, err := client.GetSystemInfo(ctx)
 	if err != nil {
 		logger.Warn("CloudDrive2连接失败",
@@ -661,7 +679,7 @@ func testCloudDrive2Connection(server model.DataServer, logger *zap.Logger) Conn
 }
 
 // testOpenListConnection 测试OpenList连接
-func testOpenListConnection(server model.DataServer, logger *zap.Logger) ConnectionTestResult {
+func testOpenListConnection(ctx context.Context, server model.DataServer, logger *zap.Logger) ConnectionTestResult {
 	start := time.Now()
 	apiURL := fmt.Sprintf("http://%s:%d/api/fs/list", server.Host, server.Port)
 
@@ -675,7 +693,7 @@ func testOpenListConnection(server model.DataServer, logger *zap.Logger) Connect
 		}
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		logger.Error("创建OpenList测试请求失败", zap.Error(err))
 		return ConnectionTestResult{
@@ -0,0 +1,412 @@
+// Package http 提供HTTP API处理器
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	syncqueue "github.com/strmsync/strmsync/internal/queue"
+	"github.com/strmsync/strmsync/internal/scheduler"
+	"github.com/strmsync/strmsync/internal/worker"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultServerCheckTTL 服务器连接测试结果默认缓存时间
+	defaultServerCheckTTL = 60 * time.Second
+	// defaultServerCheckTimeout 单个服务器连接测试默认超时
+	defaultServerCheckTimeout = 5 * time.Second
+)
+
+// WorkerStatusProvider 提供 Worker 池状态（用于健康检查）
+type WorkerStatusProvider interface {
+	Status() worker.PoolStatus
+}
+
+// SchedulerStatusProvider 提供调度器状态（可选能力，通过类型断言检测）
+type SchedulerStatusProvider interface {
+	Status() scheduler.SchedulerStatus
+}
+
+// QueueBacklogProvider 提供队列积压统计（可选能力，通过类型断言检测）
+type QueueBacklogProvider interface {
+	Backlog(ctx context.Context) (syncqueue.QueueBacklog, error)
+}
+
+// HealthOptions 就绪检查阈值
+//
+// 数值阈值为 0 表示不检查。
+type HealthOptions struct {
+	MaxPendingTasks     int64         // 等待领取的任务数上限
+	MaxPendingAge       time.Duration // 最早待执行任务的最长等待时间
+	ServerCheckTTL      time.Duration // 服务器连接测试结果缓存时间，过期后在后台刷新（默认 60s）
+	ServerCheckTimeout  time.Duration // 单个服务器连接测试超时（默认 5s）
+	RequireDataServers  bool          // 启用的数据服务器连接失败时判定未就绪
+	RequireMediaServers bool          // 启用的媒体服务器连接失败时判定未就绪
+}
+
+// HealthHandler 存活/就绪检查处理器
+type HealthHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	workers   WorkerStatusProvider
+	scheduler SchedulerStatusProvider
+	queue     QueueBacklogProvider
+	opts      HealthOptions
+	startedAt time.Time
+
+	// 连接测试函数（测试时可替换）
+	testDataServer  func(ctx context.Context, server model.DataServer, logger *zap.Logger) ConnectionTestResult
+	testMediaServer func(ctx context.Context, server model.MediaServer, logger *zap.Logger) ConnectionTestResult
+
+	mu     sync.Mutex
+	checks map[string]serverCheck // 连接测试结果缓存（键：data:<id> / media:<id>）
+	flight singleflight.Group     // 同一服务器同时只执行一个连接测试
+}
+
+// serverCheck 缓存的服务器连接测试结果
+type serverCheck struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Success    bool      `json:"success"`
+	Message    string    `json:"message"`
+	LatencyMs  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	AgeSeconds int64     `json:"age_seconds"` // 结果距今的时长
+
+	updatedAt time.Time // 服务器配置更新时间，变化时缓存失效
+}
+
+// serverTarget 待测试的服务器
+type serverTarget struct {
+	key        string
+	id         uint
+	name       string
+	serverType string
+	updatedAt  time.Time
+	test       func(ctx context.Context) ConnectionTestResult
+}
+
+// NewHealthHandler 创建健康检查处理器
+//
+// workers/scheduler/queue 均可为 nil（对应检查项不输出）
+func NewHealthHandler(db *gorm.DB, logger *zap.Logger, workers WorkerStatusProvider, scheduler SchedulerStatusProvider, queue QueueBacklogProvider, opts HealthOptions) *HealthHandler {
+	if opts.ServerCheckTTL <= 0 {
+		opts.ServerCheckTTL = defaultServerCheckTTL
+	}
+	if opts.ServerCheckTimeout <= 0 {
+		opts.ServerCheckTimeout = defaultServerCheckTimeout
+	}
+	return &HealthHandler{
+		db:              db,
+		logger:          logger,
+		workers:         workers,
+		scheduler:       scheduler,
+		queue:           queue,
+		opts:            opts,
+		startedAt:       time.Now(),
+		testDataServer:  runDataServerTest,
+		testMediaServer: runMediaServerTest,
+		checks:          make(map[string]serverCheck),
+	}
+}
+
+// Live 存活检查
+// GET /api/health/live
+//
+// 只检查进程本身：Worker 池已启动但所有 worker goroutine 都已退出时返回 503，
+// 其余情况返回 200（依赖项故障由就绪检查反映，不应触发重启）。
+func (h *HealthHandler) Live(c *gin.Context) {
+	status := "alive"
+	httpStatus := http.StatusOK
+	resp := gin.H{
+		"timestamp":      time.Now().Unix(),
+		"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+	}
+	if h.workers != nil {
+		pool := h.workers.Status()
+		resp["workers"] = gin.H{"running": pool.Running, "alive": pool.Alive, "concurrency": pool.Concurrency}
+		if pool.Running && pool.Alive == 0 {
+			status = "dead"
+			httpStatus = http.StatusServiceUnavailable
+		}
+	}
+	resp["status"] = status
+	c.JSON(httpStatus, resp)
+}
+
+// Ready 就绪检查
+// GET /api/health/ready
+//
+// 检查数据库、Worker 池、调度器、队列积压以及启用的数据/媒体服务器连接，
+// 任一检查未通过时返回 503，failures 列出未通过的原因。
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx := c.Request.Context()
+	var failures []string
+	resp := gin.H{"timestamp": time.Now().Unix()}
+
+	// 数据库
+	dbStatus := "ok"
+	if err := h.pingDB(ctx); err != nil {
+		dbStatus = "error"
+		failures = append(failures, fmt.Sprintf("数据库不可用: %v", err))
+	}
+	resp["database"] = dbStatus
+
+	// Worker 池
+	if h.workers != nil {
+		pool := h.workers.Status()
+		resp["workers"] = pool
+		if !pool.Running {
+			failures = append(failures, "任务执行器未运行")
+		} else if pool.Alive < pool.Concurrency {
+			failures = append(failures, fmt.Sprintf("任务执行器存活数不足: %d/%d", pool.Alive, pool.Concurrency))
+		}
+	}
+
+	// 调度器
+	if h.scheduler != nil {
+		sched := h.scheduler.Status()
+		resp["scheduler"] = sched
+		if !sched.Running {
+			failures = append(failures, "定时调度器未运行")
+		}
+	}
+
+	// 队列积压
+	if h.queue != nil {
+		backlog, err := h.queue.Backlog(ctx)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("查询队列积压失败: %v", err))
+		} else {
+			resp["queue"] = gin.H{
+				"pending":                    backlog.Pending,
+				"delayed":                    backlog.Delayed,
+				"running":                    backlog.Running,
+				"oldest_pending_at":          backlog.OldestPendingAt,
+				"oldest_pending_age_seconds": int64(backlog.OldestPendingAge.Seconds()),
+			}
+			if h.opts.MaxPendingTasks > 0 && backlog.Pending > h.opts.MaxPendingTasks {
+				failures = append(failures, fmt.Sprintf("待执行任务积压: %d > %d", backlog.Pending, h.opts.MaxPendingTasks))
+			}
+			if h.opts.MaxPendingAge > 0 && backlog.OldestPendingAge > h.opts.MaxPendingAge {
+				failures = append(failures, fmt.Sprintf("待执行任务等待过久: %s > %s",
+					backlog.OldestPendingAge.Truncate(time.Second), h.opts.MaxPendingAge))
+			}
+		}
+	}
+
+	// 服务器连接（返回缓存结果，过期后在后台刷新）
+	if dbStatus == "ok" {
+		dataChecks, mediaChecks, err := h.serverChecks(ctx)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("查询服务器列表失败: %v", err))
+		} else {
+			resp["data_servers"] = dataChecks
+			resp["media_servers"] = mediaChecks
+			if h.opts.RequireDataServers {
+				failures = appendServerFailures(failures, "数据服务器", dataChecks)
+			}
+			if h.opts.RequireMediaServers {
+				failures = appendServerFailures(failures, "媒体服务器", mediaChecks)
+			}
+		}
+	}
+
+	status := "ready"
+	httpStatus := http.StatusOK
+	if len(failures) > 0 {
+		status = "not_ready"
+		httpStatus = http.StatusServiceUnavailable
+		h.logger.Warn("就绪检查未通过", zap.Strings("failures", failures))
+	}
+	resp["status"] = status
+	resp["failures"] = failures
+	c.JSON(httpStatus, resp)
+}
+
+// pingDB 检查数据库连接
+func (h *HealthHandler) pingDB(ctx context.Context) error {
+	if h.db == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// serverChecks 返回启用的数据/媒体服务器连接测试结果
+//
+// 有缓存时直接返回（附结果时长），缓存过期则在后台刷新；从未测试过或配置已变化的服务器
+// 等待本次测试完成。每个连接测试受 ServerCheckTimeout 限制，同一服务器同时只执行一个，
+// 就绪探针并发或频繁请求时不会重复测试。
+func (h *HealthHandler) serverChecks(ctx context.Context) ([]serverCheck, []serverCheck, error) {
+	var dataServers []model.DataServer
+	if err := h.db.WithContext(ctx).Where("enabled = ?", true).Order("id asc").Find(&dataServers).Error; err != nil {
+		return nil, nil, err
+	}
+	var mediaServers []model.MediaServer
+	if err := h.db.WithContext(ctx).Where("enabled = ?", true).Order("id asc").Find(&mediaServers).Error; err != nil {
+		return nil, nil, err
+	}
+
+	dataChecks := make([]serverCheck, len(dataServers))
+	mediaChecks := make([]serverCheck, len(mediaServers))
+	var wg sync.WaitGroup
+	for i, server := range dataServers {
+		h.collectCheck(ctx, &wg, &dataChecks[i], serverTarget{
+			key:        fmt.Sprintf("data:%d", server.ID),
+			id:         server.ID,
+			name:       server.Name,
+			serverType: server.Type,
+			updatedAt:  server.UpdatedAt,
+			test: func(ctx context.Context) ConnectionTestResult {
+				return h.testDataServer(ctx, server, h.logger)
+			},
+		})
+	}
+	for i, server := range mediaServers {
+		h.collectCheck(ctx, &wg, &mediaChecks[i], serverTarget{
+			key:        fmt.Sprintf("media:%d", server.ID),
+			id:         server.ID,
+			name:       server.Name,
+			serverType: server.Type,
+			updatedAt:  server.UpdatedAt,
+			test: func(ctx context.Context) ConnectionTestResult {
+				return h.testMediaServer(ctx, server, h.logger)
+			},
+		})
+	}
+	wg.Wait()
+	return dataChecks, mediaChecks, nil
+}
+
+// collectCheck 填充一个服务器的连接测试结果
+//
+// 有缓存时立即填充（过期则触发后台刷新），否则等待测试完成或请求取消。
+func (h *HealthHandler) collectCheck(ctx context.Context, wg *sync.WaitGroup, dst *serverCheck, target serverTarget) {
+	if check, fresh, ok := h.cachedCheck(target.key, target.updatedAt); ok {
+		if !fresh {
+			h.refreshCheck(target)
+		}
+		*dst = check.withAge()
+		return
+	}
+
+	results := h.refreshCheck(target)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case result := <-results:
+			*dst = result.Val.(serverCheck).withAge()
+		case <-ctx.Done():
+			*dst = serverCheck{
+				ID:        target.id,
+				Name:      target.name,
+				Type:      target.serverType,
+				Message:   fmt.Sprintf("连接测试未完成: %v", ctx.Err()),
+				CheckedAt: time.Now(),
+			}
+		}
+	}()
+}
+
+// refreshCheck 在后台执行连接测试并更新缓存，返回测试结果通道
+//
+// 同一服务器（同一配置）已有测试在执行时复用该测试。测试不使用请求上下文，
+// 请求结束后仍会完成并写入缓存。
+func (h *HealthHandler) refreshCheck(target serverTarget) <-chan singleflight.Result {
+	key := fmt.Sprintf("%s@%d", target.key, target.updatedAt.UnixNano())
+	return h.flight.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), h.opts.ServerCheckTimeout)
+		defer cancel()
+		return h.storeCheck(target, target.test(ctx)), nil
+	})
+}
+
+// cachedCheck 返回配置未变化的缓存结果，fresh 表示结果未超过 ServerCheckTTL
+func (h *HealthHandler) cachedCheck(key string, updatedAt time.Time) (check serverCheck, fresh bool, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	check, ok = h.checks[key]
+	if !ok || !check.updatedAt.Equal(updatedAt) {
+		return serverCheck{}, false, false
+	}
+	return check, time.Since(check.CheckedAt) <= h.opts.ServerCheckTTL, true
+}
+
+// storeCheck 缓存连接测试结果
+func (h *HealthHandler) storeCheck(target serverTarget, result ConnectionTestResult) serverCheck {
+	check := serverCheck{
+		ID:        target.id,
+		Name:      target.name,
+		Type:      target.serverType,
+		Success:   result.Success,
+		Message:   result.Message,
+		LatencyMs: result.LatencyMs,
+		CheckedAt: time.Now(),
+		updatedAt: target.updatedAt,
+	}
+	h.mu.Lock()
+	h.checks[target.key] = check
+	h.mu.Unlock()
+	return check
+}
+
+// withAge 填充结果距今的时长
+func (c serverCheck) withAge() serverCheck {
+	c.AgeSeconds = int64(time.Since(c.CheckedAt).Seconds())
+	return c
+}
+
+// appendServerFailures 将连接失败的服务器加入未就绪原因
+func appendServerFailures(failures []string, label string, checks []serverCheck) []string {
+	for _, check := range checks {
+		if !check.Success {
+			failures = append(failures, fmt.Sprintf("%s %s 连接失败: %s", label, check.Name, check.Message))
+		}
+	}
+	return failures
+}
+
+// runDataServerTest 按类型测试数据服务器连接
+func runDataServerTest(ctx context.Context, server model.DataServer, logger *zap.Logger) ConnectionTestResult {
+	switch strings.TrimSpace(server.Type) {
+	case "clouddrive2":
+		return testCloudDrive2Connection(ctx, server, logger)
+	case "openlist":
+		return testOpenListConnection(ctx, server, logger)
+	case "local":
+		return ConnectionTestResult{Success: true, Message: "本地数据源无需测试连接"}
+	default:
+		return ConnectionTestResult{Success: false, Message: "不支持的服务器类型"}
+	}
+}
+
+// runMediaServerTest 按类型测试媒体服务器连接
+func runMediaServerTest(ctx context.Context, server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
+	switch strings.TrimSpace(server.Type) {
+	case "emby":
+		return testEmbyConnection(ctx, server, logger)
+	case "jellyfin":
+		return testJellyfinConnection(ctx, server, logger)
+	case "plex":
+		return testPlexConnection(ctx, server, logger)
+	default:
+		return ConnectionTestResult{Success: false, Message: "不支持的服务器类型"}
+	}
+}
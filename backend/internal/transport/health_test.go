@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	syncqueue "github.com/strmsync/strmsync/internal/queue"
+	"github.com/strmsync/strmsync/internal/scheduler"
+	"github.com/strmsync/strmsync/internal/worker"
+	"go.uber.org/zap"
+)
+
+type testWorkerStatus struct{ status worker.PoolStatus }
+
+func (w *testWorkerStatus) Status() worker.PoolStatus { return w.status }
+
+type testSchedulerStatus struct{ status scheduler.SchedulerStatus }
+
+func (s *testSchedulerStatus) Status() scheduler.SchedulerStatus { return s.status }
+
+type testBacklog struct{ backlog syncqueue.QueueBacklog }
+
+func (b *testBacklog) Backlog(context.Context) (syncqueue.QueueBacklog, error) { return b.backlog, nil }
+
+type readyResponse struct {
+	Status      string        `json:"status"`
+	Failures    []string      `json:"failures"`
+	DataServers []serverCheck `json:"data_servers"`
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	db := newJobTestDB(t)
+	insertDataServer(t, db, "cd2", "clouddrive2", "127.0.0.1", 19798)
+
+	workers := &testWorkerStatus{status: worker.PoolStatus{Running: true, Concurrency: 2, Alive: 2}}
+	sched := &testSchedulerStatus{status: scheduler.SchedulerStatus{Running: true}}
+	backlog := &testBacklog{}
+	h := NewHealthHandler(db, zap.NewNop(), workers, sched, backlog, HealthOptions{
+		MaxPendingTasks:    5,
+		MaxPendingAge:      10 * time.Minute,
+		RequireDataServers: true,
+	})
+	var tests int32
+	var serverDown atomic.Bool
+	h.testDataServer = func(context.Context, model.DataServer, *zap.Logger) ConnectionTestResult {
+		atomic.AddInt32(&tests, 1)
+		return ConnectionTestResult{Success: !serverDown.Load(), Message: "test"}
+	}
+
+	r := gin.New()
+	r.GET("/api/health/ready", h.Ready)
+	r.GET("/api/health/live", h.Live)
+
+	ready := func() (int, readyResponse) {
+		t.Helper()
+		w := doReq(r, http.MethodGet, "/api/health/ready", nil)
+		var resp readyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v body=%s", err, w.Body.String())
+		}
+		return w.Code, resp
+	}
+
+	code, resp := ready()
+	if code != http.StatusOK || resp.Status != "ready" {
+		t.Fatalf("expected ready, got %d %+v", code, resp)
+	}
+	if len(resp.DataServers) != 1 || !resp.DataServers[0].Success {
+		t.Fatalf("unexpected data server checks: %+v", resp.DataServers)
+	}
+
+	// 连接测试结果在 TTL 内复用缓存
+	serverDown.Store(true)
+	if code, _ = ready(); code != http.StatusOK || atomic.LoadInt32(&tests) != 1 {
+		t.Fatalf("expected cached result, code=%d tests=%d", code, tests)
+	}
+
+	// 队列积压超过阈值
+	backlog.backlog = syncqueue.QueueBacklog{Pending: 6, OldestPendingAge: 20 * time.Minute}
+	code, resp = ready()
+	if code != http.StatusServiceUnavailable || resp.Status != "not_ready" || len(resp.Failures) != 2 {
+		t.Fatalf("expected backlog failures, got %d %+v", code, resp)
+	}
+
+	// worker goroutine 意外退出
+	backlog.backlog = syncqueue.QueueBacklog{}
+	workers.status.Alive = 0
+	if code, _ = ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready when workers died, got %d", code)
+	}
+	if w := doReq(r, http.MethodGet, "/api/health/live", nil); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected live to fail when workers died, got %d", w.Code)
+	}
+
+	// 缓存过期后先返回旧结果并在后台重新测试，连接失败翻转就绪状态
+	workers.status.Alive = 2
+	h.opts.ServerCheckTTL = time.Nanosecond
+	if code, resp = ready(); code != http.StatusOK || !resp.DataServers[0].Success {
+		t.Fatalf("expected stale cached result, got %d %+v", code, resp)
+	}
+	h.opts.ServerCheckTTL = time.Hour
+	deadline := time.Now().Add(time.Second)
+	for {
+		code, resp = ready()
+		if code == http.StatusServiceUnavailable || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if code != http.StatusServiceUnavailable || len(resp.DataServers) != 1 || resp.DataServers[0].Success {
+		t.Fatalf("expected data server failure, got %d %+v", code, resp)
+	}
+}
+
+func TestHealthHandler_ServerChecksBoundedAndShared(t *testing.T) {
+	db := newJobTestDB(t)
+	insertDataServer(t, db, "cd2", "clouddrive2", "127.0.0.1", 19798)
+
+	h := NewHealthHandler(db, zap.NewNop(), nil, nil, nil, HealthOptions{
+		RequireDataServers: true,
+		ServerCheckTimeout: 50 * time.Millisecond,
+	})
+	var tests int32
+	h.testDataServer = func(ctx context.Context, _ model.DataServer, _ *zap.Logger) ConnectionTestResult {
+		atomic.AddInt32(&tests, 1)
+		<-ctx.Done() // 服务器无响应，直到超时
+		return ConnectionTestResult{Success: false, Message: ctx.Err().Error()}
+	}
+	r := gin.New()
+	r.GET("/api/health/ready", h.Ready)
+
+	// 并发的就绪请求共享同一次连接测试，测试受超时限制
+	start := time.Now()
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = doReq(r, http.MethodGet, "/api/health/ready", nil).Code
+		}(i)
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("server checks should be bounded by the timeout, took %s", elapsed)
+	}
+	for _, code := range codes {
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected not ready, got %v", codes)
+		}
+	}
+	if n := atomic.LoadInt32(&tests); n != 1 {
+		t.Fatalf("expected one shared connection test, got %d", n)
+	}
+}
@@ -55,6 +55,18 @@ func respondValidationError(c *gin.Context, fieldErrors []FieldError) {
 	})
 }
 
+// isDuplicateKeyError 判断是否为唯一约束冲突错误
+// 兼容 SQLite（UNIQUE constraint failed）、PostgreSQL（duplicate key）与 MySQL（Duplicate entry）
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint failed") ||
+		strings.Contains(msg, "duplicate key") ||
+		strings.Contains(msg, "duplicate entry")
+}
+
 // ================== 分页参数 ==================
 
 // Pagination 分页参数结构
@@ -16,6 +16,8 @@ import (
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/infra/filesystem"
 	"github.com/strmsync/strmsync/internal/queue"
+	"github.com/strmsync/strmsync/internal/strmwriter"
+	"github.com/strmsync/strmsync/internal/worker"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -83,6 +85,7 @@ type JobHandler struct {
 
 	scheduler JobScheduler
 	queue     TaskQueue
+	layouts   LayoutPreviewer
 }
 
 // JobScheduler 任务调度器接口（用于注入）
@@ -97,13 +100,19 @@ type TaskQueue interface {
 	Cancel(ctx context.Context, taskID uint) error
 }
 
+// LayoutPreviewer 输出布局预览接口（用于注入，由 worker.WorkerPool 实现，使用 Worker 的驱动工厂）
+type LayoutPreviewer interface {
+	PreviewLayout(ctx context.Context, job model.Job, server model.DataServer, mappings map[string]string, limit int) ([]worker.LayoutPreviewItem, bool, error)
+}
+
 // NewJobHandler 创建任务处理器
-func NewJobHandler(db *gorm.DB, logger *zap.Logger, scheduler JobScheduler, queue TaskQueue) *JobHandler {
+func NewJobHandler(db *gorm.DB, logger *zap.Logger, scheduler JobScheduler, queue TaskQueue, layouts LayoutPreviewer) *JobHandler {
 	return &JobHandler{
 		db:        db,
 		logger:    logger,
 		scheduler: scheduler,
 		queue:     queue,
+		layouts:   layouts,
 	}
 }
 
@@ -120,6 +129,10 @@ type jobRequest struct {
 	DataServerID  *uint  `json:"data_server_id"`
 	MediaServerID *uint  `json:"media_server_id"`
 	Options       string `json:"options"`
+	// MaxConcurrentRuns 同时执行的数量上限（可选，0 表示默认 1）
+	MaxConcurrentRuns *int `json:"max_concurrent_runs,omitempty"`
+	// WorkerGroup 执行的Worker分组（可选，空表示任意Worker；更新时不传则保持不变）
+	WorkerGroup *string `json:"worker_group,omitempty"`
 }
 
 func buildJobLogPayload(req jobRequest) map[string]interface{} {
@@ -135,6 +148,12 @@ func buildJobLogPayload(req jobRequest) map[string]interface{} {
 		"data_server_id":  req.DataServerID,
 		"media_server_id": req.MediaServerID,
 	}
+	if req.MaxConcurrentRuns != nil {
+		payload["max_concurrent_runs"] = *req.MaxConcurrentRuns
+	}
+	if req.WorkerGroup != nil {
+		payload["worker_group"] = strings.TrimSpace(*req.WorkerGroup)
+	}
 
 	rawOptions := strings.TrimSpace(req.Options)
 	if rawOptions == "" {
@@ -201,6 +220,10 @@ func validateJobRequest(req *jobRequest) []FieldError {
 	validateEnum("watch_mode", req.WatchMode, allowedJobWatchModes, &fieldErrors)
 	validateJSONString("options", req.Options, &fieldErrors)
 	validateCronSpec(req.Cron, &fieldErrors)
+	validateMaxConcurrentRuns(req.MaxConcurrentRuns, &fieldErrors)
+	if req.WorkerGroup != nil && len(strings.TrimSpace(*req.WorkerGroup)) > 64 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "worker_group", Message: "长度不能超过64"})
+	}
 
 	watchMode := JobWatchMode(strings.TrimSpace(req.WatchMode))
 	if watchMode == JobWatchModeAPI {
@@ -355,10 +378,15 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 		Options:       strings.TrimSpace(req.Options),
 		Status:        string(JobStatusIdle),
 	}
+	if req.MaxConcurrentRuns != nil {
+		job.MaxConcurrentRuns = *req.MaxConcurrentRuns
+	}
+	if req.WorkerGroup != nil {
+		job.WorkerGroup = strings.TrimSpace(*req.WorkerGroup)
+	}
 
 	if err := h.db.Create(&job).Error; err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			respondError(c, http.StatusConflict, "duplicate_name", "任务名称已存在", nil)
 			return
 		}
@@ -606,10 +634,15 @@ func (h *JobHandler) UpdateJob(c *gin.Context) {
 	job.DataServerID = req.DataServerID
 	job.MediaServerID = req.MediaServerID
 	job.Options = strings.TrimSpace(req.Options)
+	if req.MaxConcurrentRuns != nil {
+		job.MaxConcurrentRuns = *req.MaxConcurrentRuns
+	}
+	if req.WorkerGroup != nil {
+		job.WorkerGroup = strings.TrimSpace(*req.WorkerGroup)
+	}
 
 	if err := h.db.Save(&job).Error; err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			respondError(c, http.StatusConflict, "duplicate_name", "任务名称已存在", nil)
 			return
 		}
@@ -760,10 +793,10 @@ func (h *JobHandler) RunJob(c *gin.Context) {
 		return
 	}
 
-	// 入队任务（手动触发：立即可执行）
+	// 入队任务（手动触发：立即可执行，高优先级可使用 Worker 预留槽位）
 	taskRun := &model.TaskRun{
 		JobID:       job.ID,
-		Priority:    int(syncqueue.TaskPriorityNormal),
+		Priority:    int(syncqueue.TaskPriorityHigh),
 		AvailableAt: time.Now(),
 		Payload:     buildManualRunPayload(job),
 	}
@@ -875,6 +908,142 @@ func (h *JobHandler) StopJob(c *gin.Context) {
 	})
 }
 
+// RollbackJob 将暂存同步任务的目标目录回滚到保留的上一代
+// POST /api/jobs/:id/rollback
+//
+// 当前目录与上一代目录互换，再次调用可撤销回滚。目标目录需在本实例上可访问。
+func (h *JobHandler) RollbackJob(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return
+	}
+
+	var job model.Job
+	if err := h.db.First(&job, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondError(c, http.StatusNotFound, "not_found", "任务不存在", nil)
+			return
+		}
+		h.logger.Error("查询任务失败", zap.Error(err), zap.Uint64("id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	mode, enabled, err := worker.JobStagingMode(job)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_options", err.Error(), nil)
+		return
+	}
+	if !enabled {
+		respondError(c, http.StatusBadRequest, "staging_disabled", "任务未启用暂存目录同步", nil)
+		return
+	}
+
+	// 执行中的任务正在写入暂存目录并可能随时切换，回滚需等待其结束
+	var runningCount int64
+	if err := h.db.Model(&model.TaskRun{}).
+		Where("job_id = ? AND status IN ?", job.ID, []string{string(syncqueue.TaskPending), string(syncqueue.TaskRunning)}).
+		Count(&runningCount).Error; err != nil {
+		h.logger.Error("检查任务运行状态失败", zap.Error(err), zap.Uint64("id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "数据库错误", nil)
+		return
+	}
+	if runningCount > 0 {
+		respondError(c, http.StatusConflict, "job_running", "任务正在运行，无法回滚", nil)
+		return
+	}
+
+	if err := strmwriter.RollbackStaging(job.TargetPath, mode); err != nil {
+		if errors.Is(err, strmwriter.ErrNoPreviousGeneration) {
+			respondError(c, http.StatusConflict, "no_previous_generation", "没有可回滚的上一代目录", nil)
+			return
+		}
+		h.logger.Error(fmt.Sprintf("回滚任务「%s」目标目录失败", job.Name), zap.Error(err), zap.Uint("job_id", job.ID))
+		respondError(c, http.StatusInternalServerError, "rollback_failed", "回滚失败", nil)
+		return
+	}
+
+	h.logger.Info(fmt.Sprintf("回滚任务「%s」目标目录成功", job.Name),
+		zap.Uint("job_id", job.ID),
+		zap.String("target_path", job.TargetPath),
+		zap.String("swap_mode", mode.String()))
+
+	c.JSON(http.StatusOK, gin.H{"message": "已回滚到上一代目录"})
+}
+
+// PreviewJobLayout 预览媒体布局下的 STRM 输出路径
+// GET /api/jobs/:id/layout-preview?limit=200
+//
+// 列出数据服务器上的视频并按任务的布局配置与已保存的映射计算输出路径，不写入任何文件；
+// 收集到 limit 条后停止遍历，more 表示之后还有视频。
+func (h *JobHandler) PreviewJobLayout(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return
+	}
+
+	var job model.Job
+	if err := h.db.First(&job, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondError(c, http.StatusNotFound, "not_found", "任务不存在", nil)
+			return
+		}
+		h.logger.Error("查询任务失败", zap.Error(err), zap.Uint64("id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+	if job.DataServerID == nil {
+		respondError(c, http.StatusBadRequest, "missing_data_server", "任务未配置数据服务器", nil)
+		return
+	}
+
+	var server model.DataServer
+	if err := h.db.First(&server, *job.DataServerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondError(c, http.StatusNotFound, "not_found", "数据服务器不存在", nil)
+			return
+		}
+		h.logger.Error("查询数据服务器失败", zap.Error(err), zap.Uint("server_id", *job.DataServerID))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	var records []model.StrmMapping
+	if err := h.db.Where("job_id = ?", job.ID).Find(&records).Error; err != nil {
+		h.logger.Error("查询输出布局映射失败", zap.Error(err), zap.Uint("job_id", job.ID))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+	mappings := make(map[string]string, len(records))
+	for _, record := range records {
+		mappings[record.SourcePath] = record.OutputPath
+	}
+
+	limit := parseIntQuery(c, "limit", 200)
+	if limit <= 0 || limit > 2000 {
+		limit = 200
+	}
+	var items []worker.LayoutPreviewItem
+	var more bool
+	if h.layouts != nil {
+		items, more, err = h.layouts.PreviewLayout(c.Request.Context(), job, server, mappings, limit)
+	} else {
+		items, more, err = worker.PreviewLayout(c.Request.Context(), nil, job, server, mappings, limit)
+	}
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("预览任务「%s」输出布局失败", job.Name), zap.Error(err), zap.Uint("job_id", job.ID))
+		respondError(c, http.StatusBadRequest, "preview_failed", err.Error(), nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"more":  more,
+	})
+}
+
 // EnableJob 启用任务
 // PUT /api/jobs/:id/enable
 func (h *JobHandler) EnableJob(c *gin.Context) {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -118,6 +120,7 @@ func setupJobRouter(h *JobHandler) *gin.Engine {
 	r.DELETE("/api/jobs/:id", h.DeleteJob)
 	r.POST("/api/jobs/:id/run", h.RunJob)
 	r.POST("/api/jobs/:id/stop", h.StopJob)
+	r.POST("/api/jobs/:id/rollback", h.RollbackJob)
 	return r
 }
 
@@ -200,7 +203,7 @@ func insertDataServer(t *testing.T, db *gorm.DB, name, dsType, host string, port
 func TestJobHandler_CreateJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
 	scheduler := &testScheduler{}
-	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil)
+	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil, nil)
 	router := setupJobRouter(handler)
 
 	// 创建关联的数据服务器（直接写 DB，绕过 SSRF 限制）
@@ -236,7 +239,7 @@ func TestJobHandler_CreateJob_Success(t *testing.T) {
 }
 
 func TestJobHandler_CreateJob_ValidationError(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	// 缺少 name、watch_mode、source_path 等必填字段
@@ -261,7 +264,7 @@ func TestJobHandler_CreateJob_ValidationError(t *testing.T) {
 }
 
 func TestJobHandler_CreateJob_APIWatchModeRequiresDataServerID(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	payload := map[string]interface{}{
@@ -298,7 +301,7 @@ func TestJobHandler_CreateJob_APIWatchModeRequiresDataServerID(t *testing.T) {
 }
 
 func TestJobHandler_CreateJob_InvalidOptionsJSON(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	payload := map[string]interface{}{
@@ -319,7 +322,7 @@ func TestJobHandler_CreateJob_InvalidOptionsJSON(t *testing.T) {
 
 func TestJobHandler_CreateJob_DuplicateName(t *testing.T) {
 	db := newJobTestDB(t)
-	handler := NewJobHandler(db, zap.NewNop(), nil, nil)
+	handler := NewJobHandler(db, zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	insertJobRaw(t, db, "dup-job", true)
@@ -345,7 +348,7 @@ func TestJobHandler_CreateJob_DuplicateName(t *testing.T) {
 // ---------------------
 
 func TestJobHandler_ListJobs_Empty(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodGet, "/api/jobs", nil)
@@ -366,7 +369,7 @@ func TestJobHandler_ListJobs_Empty(t *testing.T) {
 
 func TestJobHandler_ListJobs_FilterByEnabled(t *testing.T) {
 	db := newJobTestDB(t)
-	handler := NewJobHandler(db, zap.NewNop(), nil, nil)
+	handler := NewJobHandler(db, zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	insertJobRaw(t, db, "enabled-job", true)
@@ -388,7 +391,7 @@ func TestJobHandler_ListJobs_FilterByEnabled(t *testing.T) {
 }
 
 func TestJobHandler_ListJobs_InvalidEnabledParam(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodGet, "/api/jobs?enabled=maybe", nil)
@@ -399,7 +402,7 @@ func TestJobHandler_ListJobs_InvalidEnabledParam(t *testing.T) {
 }
 
 func TestJobHandler_ListJobs_InvalidWatchModeParam(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodGet, "/api/jobs?watch_mode=invalid", nil)
@@ -415,7 +418,7 @@ func TestJobHandler_ListJobs_InvalidWatchModeParam(t *testing.T) {
 
 func TestJobHandler_GetJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
-	handler := NewJobHandler(db, zap.NewNop(), nil, nil)
+	handler := NewJobHandler(db, zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "get-job", true)
@@ -440,7 +443,7 @@ func TestJobHandler_GetJob_Success(t *testing.T) {
 }
 
 func TestJobHandler_GetJob_NotFound(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodGet, "/api/jobs/9999", nil)
@@ -451,7 +454,7 @@ func TestJobHandler_GetJob_NotFound(t *testing.T) {
 }
 
 func TestJobHandler_GetJob_InvalidID(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodGet, "/api/jobs/abc", nil)
@@ -468,7 +471,7 @@ func TestJobHandler_GetJob_InvalidID(t *testing.T) {
 func TestJobHandler_UpdateJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
 	scheduler := &testScheduler{}
-	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil)
+	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "old-name", true)
@@ -506,7 +509,7 @@ func TestJobHandler_UpdateJob_Success(t *testing.T) {
 }
 
 func TestJobHandler_UpdateJob_NotFound(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	payload := map[string]interface{}{
@@ -527,7 +530,7 @@ func TestJobHandler_UpdateJob_NotFound(t *testing.T) {
 
 func TestJobHandler_UpdateJob_DuplicateName(t *testing.T) {
 	db := newJobTestDB(t)
-	handler := NewJobHandler(db, zap.NewNop(), nil, nil)
+	handler := NewJobHandler(db, zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	insertJobRaw(t, db, "job-a", true)
@@ -557,7 +560,7 @@ func TestJobHandler_UpdateJob_DuplicateName(t *testing.T) {
 func TestJobHandler_DeleteJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
 	scheduler := &testScheduler{}
-	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil)
+	handler := NewJobHandler(db, zap.NewNop(), scheduler, nil, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "delete-ok", true)
@@ -580,7 +583,7 @@ func TestJobHandler_DeleteJob_Success(t *testing.T) {
 }
 
 func TestJobHandler_DeleteJob_NotFound(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodDelete, "/api/jobs/9999", nil)
@@ -592,7 +595,7 @@ func TestJobHandler_DeleteJob_NotFound(t *testing.T) {
 
 func TestJobHandler_DeleteJob_RunningConflict(t *testing.T) {
 	db := newJobTestDB(t)
-	handler := NewJobHandler(db, zap.NewNop(), nil, nil)
+	handler := NewJobHandler(db, zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "delete-running", true)
@@ -612,7 +615,7 @@ func TestJobHandler_DeleteJob_RunningConflict(t *testing.T) {
 func TestJobHandler_RunJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
 	queue := &testQueue{}
-	handler := NewJobHandler(db, zap.NewNop(), nil, queue)
+	handler := NewJobHandler(db, zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "run-ok", true)
@@ -639,7 +642,7 @@ func TestJobHandler_RunJob_Success(t *testing.T) {
 func TestJobHandler_RunJob_Disabled(t *testing.T) {
 	db := newJobTestDB(t)
 	queue := &testQueue{}
-	handler := NewJobHandler(db, zap.NewNop(), nil, queue)
+	handler := NewJobHandler(db, zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	// 必须使用 insertJobRaw，否则 GORM 会跳过 Enabled=false
@@ -658,7 +661,7 @@ func TestJobHandler_RunJob_Disabled(t *testing.T) {
 func TestJobHandler_RunJob_AlreadyRunning(t *testing.T) {
 	db := newJobTestDB(t)
 	queue := &testQueue{}
-	handler := NewJobHandler(db, zap.NewNop(), nil, queue)
+	handler := NewJobHandler(db, zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "run-conflict", true)
@@ -674,7 +677,7 @@ func TestJobHandler_RunJob_AlreadyRunning(t *testing.T) {
 
 func TestJobHandler_RunJob_NotFound(t *testing.T) {
 	queue := &testQueue{}
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, queue)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodPost, "/api/jobs/9999/run", nil)
@@ -686,7 +689,7 @@ func TestJobHandler_RunJob_NotFound(t *testing.T) {
 
 func TestJobHandler_RunJob_QueueNotInitialized(t *testing.T) {
 	// queue=nil 时应返回 500
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodPost, "/api/jobs/1/run", nil)
@@ -704,7 +707,7 @@ func TestJobHandler_StopJob_Success(t *testing.T) {
 	db := newJobTestDB(t)
 	// 将 db 传入 queue，使 Cancel 能把 DB 中的任务状态更新为 cancelled
 	queue := &testQueue{db: db}
-	handler := NewJobHandler(db, zap.NewNop(), nil, queue)
+	handler := NewJobHandler(db, zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "stop-ok", true)
@@ -733,7 +736,7 @@ func TestJobHandler_StopJob_Success(t *testing.T) {
 func TestJobHandler_StopJob_NoActiveTasks(t *testing.T) {
 	db := newJobTestDB(t)
 	queue := &testQueue{db: db}
-	handler := NewJobHandler(db, zap.NewNop(), nil, queue)
+	handler := NewJobHandler(db, zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	job := insertJobRaw(t, db, "stop-none", true)
@@ -747,7 +750,7 @@ func TestJobHandler_StopJob_NoActiveTasks(t *testing.T) {
 
 func TestJobHandler_StopJob_NotFound(t *testing.T) {
 	queue := &testQueue{}
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, queue)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, queue, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodPost, "/api/jobs/9999/stop", nil)
@@ -758,7 +761,7 @@ func TestJobHandler_StopJob_NotFound(t *testing.T) {
 }
 
 func TestJobHandler_StopJob_QueueNotInitialized(t *testing.T) {
-	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil)
+	handler := NewJobHandler(newJobTestDB(t), zap.NewNop(), nil, nil, nil)
 	router := setupJobRouter(handler)
 
 	resp := doReq(router, http.MethodPost, "/api/jobs/1/stop", nil)
@@ -767,3 +770,57 @@ func TestJobHandler_StopJob_QueueNotInitialized(t *testing.T) {
 		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, resp.Code, resp.Body)
 	}
 }
+
+// ---------------------
+// RollbackJob 测试
+// ---------------------
+
+func TestJobHandler_RollbackJob(t *testing.T) {
+	db := newJobTestDB(t)
+	handler := NewJobHandler(db, zap.NewNop(), nil, &testQueue{}, nil)
+	router := setupJobRouter(handler)
+
+	parent := t.TempDir()
+	target := filepath.Join(parent, "Movies")
+	previous := filepath.Join(parent, ".Movies.previous")
+	for dir, content := range map[string]string{target: "new", previous: "old"} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "A.strm"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := insertJobRaw(t, db, "rollback", true)
+	plain := insertJobRaw(t, db, "rollback-plain", true)
+	if err := db.Model(&job).Updates(map[string]interface{}{
+		"target_path": target,
+		"options":     `{"staging_mode":"rename"}`,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 未启用暂存同步的任务不能回滚
+	resp := doReq(router, http.MethodPost, fmt.Sprintf("/api/jobs/%d/rollback", plain.ID), nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body)
+	}
+
+	// 执行中的任务不能回滚
+	running := insertTaskRun(t, db, job.ID, string(syncqueue.TaskRunning), "dedup-rollback")
+	resp = doReq(router, http.MethodPost, fmt.Sprintf("/api/jobs/%d/rollback", job.ID), nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, resp.Code, resp.Body)
+	}
+	db.Model(&running).Update("status", string(syncqueue.TaskCompleted))
+
+	resp = doReq(router, http.MethodPost, fmt.Sprintf("/api/jobs/%d/rollback", job.ID), nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, resp.Code, resp.Body)
+	}
+	data, _ := os.ReadFile(filepath.Join(target, "A.strm"))
+	if string(data) != "old" {
+		t.Fatalf("expected previous generation restored, got %q", data)
+	}
+}
@@ -2,137 +2,227 @@
 package http
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/domain/repository"
 	"go.uber.org/zap"
 )
 
+const (
+	logStreamPollInterval = time.Second      // 实时日志轮询间隔
+	logStreamHeartbeat    = 15 * time.Second // 实时日志心跳间隔（保持连接不被代理断开）
+	logStreamBatch        = 200              // 单次轮询最多推送的日志条数
+	logStreamMaxTail      = 1000             // 建立连接时最多回放的历史日志条数
+)
+
 // LogHandler 日志查询处理器
 type LogHandler struct {
-	logDir string
-	logger *zap.Logger
+	logs         repository.LogRepository
+	logger       *zap.Logger
+	pollInterval time.Duration
 }
 
 // NewLogHandler 创建日志处理器
-func NewLogHandler(logDir string, logger *zap.Logger) *LogHandler {
+func NewLogHandler(logs repository.LogRepository, logger *zap.Logger) *LogHandler {
 	return &LogHandler{
-		logDir: strings.TrimSpace(logDir),
-		logger: logger,
+		logs:         logs,
+		logger:       logger,
+		pollInterval: logStreamPollInterval,
 	}
 }
 
 // ListLogs 获取日志列表
 // GET /api/logs
+//
+// 过滤参数：level（逗号分隔多个级别）、module、job_id、run_id、request_id、
+// search（空格分隔的关键字，需全部命中）、start_at、end_at。
+// 传入 cursor（上一页返回的 next_cursor）时按游标翻页，不再统计总数；
+// 否则按 page/page_size 翻页。
 func (h *LogHandler) ListLogs(c *gin.Context) {
-	page := parseIntQuery(c, "page", 1)
-	pageSize := parseIntQuery(c, "page_size", 50)
-	if pageSize > 200 {
-		pageSize = 200
+	pagination := parsePagination(c, 1, 50, 200)
+
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
 	}
 
-	entries, err := h.readLogEntries()
+	var cursor uint
+	if raw := strings.TrimSpace(c.Query("cursor")); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || parsed == 0 {
+			respondError(c, http.StatusBadRequest, "invalid_request", "无效的cursor参数", nil)
+			return
+		}
+		cursor = uint(parsed)
+	}
+
+	ctx := c.Request.Context()
+	entries, err := h.logs.List(ctx, filter, cursor, pagination.Offset, pagination.PageSize)
 	if err != nil {
-		logger.LogError("读取日志文件失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取日志失败"})
+		h.logger.Error("查询日志失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询日志失败", nil)
 		return
 	}
 
-	levelFilter := strings.ToLower(strings.TrimSpace(c.Query("level")))
-	moduleFilter := strings.TrimSpace(c.Query("module"))
-	search := strings.TrimSpace(c.Query("search"))
-	var jobIDFilter *uint
-	if jobIDStr := strings.TrimSpace(c.Query("job_id")); jobIDStr != "" {
-		if id, err := strconv.ParseUint(jobIDStr, 10, 64); err == nil && id > 0 {
-			parsed := uint(id)
-			jobIDFilter = &parsed
+	resp := gin.H{
+		"logs":        entries,
+		"page_size":   pagination.PageSize,
+		"next_cursor": nil,
+	}
+	if len(entries) == pagination.PageSize {
+		resp["next_cursor"] = entries[len(entries)-1].ID
+	}
+	if cursor == 0 {
+		total, err := h.logs.Count(ctx, filter)
+		if err != nil {
+			h.logger.Error("统计日志失败", zap.Error(err))
+			respondError(c, http.StatusInternalServerError, "db_error", "查询日志失败", nil)
+			return
 		}
+		resp["total"] = total
+		resp["page"] = pagination.Page
 	}
-	startAt, _ := parseTime(strings.TrimSpace(c.Query("start_at")))
-	endAt, _ := parseTime(strings.TrimSpace(c.Query("end_at")))
 
-	filtered := make([]logEntry, 0, len(entries))
-	for _, entry := range entries {
-		if levelFilter != "" && entry.Level != levelFilter {
-			continue
-		}
-		if moduleFilter != "" && entry.Module != moduleFilter {
-			continue
-		}
-		if jobIDFilter != nil {
-			if entry.JobID == nil || *entry.JobID != *jobIDFilter {
-				continue
-			}
+	c.JSON(http.StatusOK, resp)
+}
+
+// StreamLogs 实时追踪日志（Server-Sent Events）
+// GET /api/logs/stream
+//
+// 支持与 ListLogs 相同的过滤参数。连接建立时先回放最近 tail 条（默认 100）日志，
+// 之后持续推送新日志；断线重连时通过 after_id 或 Last-Event-ID 从断点继续。
+func (h *LogHandler) StreamLogs(c *gin.Context) {
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
+	}
+
+	afterID, hasAfter, err := parseStreamAfterID(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的after_id参数", nil)
+		return
+	}
+
+	ctx := c.Request.Context()
+	var backlog []model.LogEntry
+	if !hasAfter {
+		// 以当前最新日志为界回放历史，之后只推送新日志
+		latest, err := h.logs.List(ctx, repository.LogFilter{}, 0, 0, 1)
+		if err != nil {
+			h.logger.Error("查询日志失败", zap.Error(err))
+			respondError(c, http.StatusInternalServerError, "db_error", "查询日志失败", nil)
+			return
 		}
-		if search != "" && !strings.Contains(entry.Message, search) {
-			continue
+		if len(latest) > 0 {
+			afterID = latest[0].ID
 		}
-		if !startAt.IsZero() && entry.CreatedAt.Before(startAt) {
-			continue
+		tail := parseIntQuery(c, "tail", 100)
+		if tail > logStreamMaxTail {
+			tail = logStreamMaxTail
 		}
-		if !endAt.IsZero() && entry.CreatedAt.After(endAt) {
-			continue
+		if tail > 0 && afterID > 0 {
+			backlog, err = h.logs.List(ctx, filter, afterID+1, 0, tail)
+			if err != nil {
+				h.logger.Error("查询日志失败", zap.Error(err))
+				respondError(c, http.StatusInternalServerError, "db_error", "查询日志失败", nil)
+				return
+			}
 		}
-		filtered = append(filtered, entry)
 	}
 
-	sort.SliceStable(filtered, func(i, j int) bool {
-		return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
-	})
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
 
-	total := len(filtered)
-	start := (page - 1) * pageSize
-	if start < 0 {
-		start = 0
-	}
-	end := start + pageSize
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
+	// 历史日志按时间正序推送
+	for i := len(backlog) - 1; i >= 0; i-- {
+		if err := writeLogEvent(c, backlog[i]); err != nil {
+			return
+		}
 	}
+	c.Writer.Flush()
 
-	c.JSON(http.StatusOK, gin.H{
-		"logs":      filtered[start:end],
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	})
+	poll := time.NewTicker(h.pollInterval)
+	defer poll.Stop()
+	lastWrite := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+
+		entries, err := h.logs.ListAfter(ctx, filter, afterID, logStreamBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Warn("实时日志查询失败", zap.Error(err))
+			}
+			return
+		}
+		for _, entry := range entries {
+			if err := writeLogEvent(c, entry); err != nil {
+				return
+			}
+			afterID = entry.ID
+		}
+
+		if len(entries) > 0 {
+			lastWrite = time.Now()
+		} else if time.Since(lastWrite) >= logStreamHeartbeat {
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			lastWrite = time.Now()
+		} else {
+			continue
+		}
+		c.Writer.Flush()
+	}
 }
 
 // CleanupLogs 清理日志
 // POST /api/logs/cleanup
 // 请求体: {"days": 30} - 清理30天前的日志
+//
+// 日常清理由保留策略（LOG_RETENTION_DAYS / LOG_RETENTION_MAX_ROWS）定期执行，
+// 此接口用于立即清理更早的日志。
 func (h *LogHandler) CleanupLogs(c *gin.Context) {
 	var req struct {
 		Days int `json:"days" binding:"required,min=1"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效"})
+		respondError(c, http.StatusBadRequest, "invalid_request", "请求参数无效", nil)
 		return
 	}
 
+	ctx := c.Request.Context()
 	cutoff := time.Now().AddDate(0, 0, -req.Days)
-	kept, deleted, err := h.cleanupLogFile(cutoff)
+	deleted, err := h.logs.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		h.logger.Error("清理日志失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "清理失败", nil)
+		return
+	}
+	kept, err := h.logs.Count(ctx, repository.LogFilter{})
 	if err != nil {
-		logger.LogError("清理日志失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理失败"})
+		h.logger.Error("统计日志失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "清理失败", nil)
 		return
 	}
 
-	logger.LogInfo("清理日志成功", zap.Int("days", req.Days), zap.Int("deleted", deleted))
+	h.logger.Info("清理日志成功", zap.Int("days", req.Days), zap.Int64("deleted", deleted))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "清理成功",
 		"deleted": deleted,
@@ -140,293 +230,92 @@ func (h *LogHandler) CleanupLogs(c *gin.Context) {
 	})
 }
 
-// parseTime 解析时间字符串
-func parseTime(s string) (time.Time, error) {
-	formats := []string{
-		time.RFC3339,
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02 15:04:05",
-		"2006-01-02",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, s); err == nil {
-			return t, nil
-		}
+// parseFilter 解析日志过滤参数，参数无效时写入 400 响应并返回 false
+func (h *LogHandler) parseFilter(c *gin.Context) (repository.LogFilter, bool) {
+	filter := repository.LogFilter{
+		Module:    strings.TrimSpace(c.Query("module")),
+		RequestID: strings.TrimSpace(c.Query("request_id")),
+		Search:    strings.TrimSpace(c.Query("search")),
 	}
 
-	return time.Time{}, nil
-}
-
-type logEntry struct {
-	ID         int64     `json:"id"`
-	Level      string    `json:"level"`
-	Module     string    `json:"module,omitempty"`
-	Message    string    `json:"message"`
-	JobID      *uint     `json:"job_id,omitempty"`
-	RequestID  *string   `json:"request_id,omitempty"`
-	UserAction *string   `json:"user_action,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-}
-
-type rawLogEntry struct {
-	Level      string `json:"level"`
-	TS         string `json:"ts"`
-	Time       string `json:"time"`
-	Msg        string `json:"msg"`
-	Message    string `json:"message"`
-	Module     string `json:"module"`
-	Component  string `json:"component"`
-	Logger     string `json:"logger"`
-	JobID      *uint  `json:"job_id"`
-	RequestID  string `json:"request_id"`
-	UserAction string `json:"user_action"`
-}
-
-type logLine struct {
-	Raw     string
-	HasTime bool
-	Time    time.Time
-}
-
-func (h *LogHandler) logFilePath() string {
-	dir, filePath := logger.ResolveLogFilePath(h.logDir)
-	if filePath != "" {
-		return filePath
-	}
-	if dir == "" {
-		dir = "."
-	}
-	return filepath.Join(dir, "app.log")
-}
-
-func (h *LogHandler) readLogEntries() ([]logEntry, error) {
-	lines, err := h.readLogLines()
-	if err != nil {
-		return nil, err
-	}
-
-	entries := make([]logEntry, 0, len(lines))
-	var id int64
-	for _, line := range lines {
-		var raw rawLogEntry
-		if err := json.Unmarshal([]byte(line.Raw), &raw); err != nil {
-			fallback := buildFallbackEntry(line)
-			if fallback == nil {
-				continue
+	if raw := strings.TrimSpace(c.Query("level")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			level := normalizeLogLevel(part)
+			if level == "" {
+				respondError(c, http.StatusBadRequest, "invalid_request", "level必须是debug/info/warn/error之一", nil)
+				return filter, false
 			}
-			id++
-			fallback.ID = id
-			entries = append(entries, *fallback)
-			continue
-		}
-
-		id++
-		level := strings.ToLower(strings.TrimSpace(raw.Level))
-		if level == "" {
-			level = "info"
-		}
-
-		message := strings.TrimSpace(raw.Msg)
-		if message == "" {
-			message = strings.TrimSpace(raw.Message)
-		}
-		if message == "" {
-			message = level
-		}
-
-		module := strings.TrimSpace(raw.Module)
-		if module == "" {
-			module = strings.TrimSpace(raw.Component)
-		}
-		if module == "" {
-			module = strings.TrimSpace(raw.Logger)
-		}
-
-		requestID := strings.TrimSpace(raw.RequestID)
-		var requestPtr *string
-		if requestID != "" {
-			requestPtr = &requestID
+			filter.Levels = append(filter.Levels, level)
 		}
-		userAction := strings.TrimSpace(raw.UserAction)
-		var userPtr *string
-		if userAction != "" {
-			userPtr = &userAction
-		}
-
-		createdAt := line.Time
-		if !line.HasTime {
-			createdAt = time.Time{}
-		}
-
-		entries = append(entries, logEntry{
-			ID:         id,
-			Level:      level,
-			Module:     module,
-			Message:    message,
-			JobID:      raw.JobID,
-			RequestID:  requestPtr,
-			UserAction: userPtr,
-			CreatedAt:  createdAt,
-		})
-	}
-
-	return entries, nil
-}
-
-func (h *LogHandler) readLogLines() ([]logLine, error) {
-	filePath := h.logFilePath()
-	file, err := os.Open(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []logLine{}, nil
-		}
-		return nil, fmt.Errorf("open log file: %w", err)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
-
-	lines := make([]logLine, 0)
-	for scanner.Scan() {
-		text := strings.TrimSpace(scanner.Text())
-		if text == "" {
+	for _, item := range []struct {
+		key    string
+		target **uint
+	}{
+		{"job_id", &filter.JobID},
+		{"run_id", &filter.RunID},
+	} {
+		raw := strings.TrimSpace(c.Query(item.key))
+		if raw == "" {
 			continue
 		}
-		line := logLine{Raw: text}
-		var raw rawLogEntry
-		if err := json.Unmarshal([]byte(text), &raw); err == nil {
-			if ts, ok := parseLogTime(raw.TS); ok {
-				line.HasTime = true
-				line.Time = ts
-			} else if ts, ok := parseLogTime(raw.Time); ok {
-				line.HasTime = true
-				line.Time = ts
-			}
-		} else {
-			parts := strings.Fields(text)
-			if len(parts) > 0 {
-				if ts, ok := parseLogTime(parts[0]); ok {
-					line.HasTime = true
-					line.Time = ts
-				}
-			}
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || id == 0 {
+			respondError(c, http.StatusBadRequest, "invalid_request", fmt.Sprintf("无效的%s参数", item.key), nil)
+			return filter, false
 		}
-		lines = append(lines, line)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan log file: %w", err)
+		parsed := uint(id)
+		*item.target = &parsed
 	}
 
-	return lines, nil
+	filter.StartAt = parseTime(strings.TrimSpace(c.Query("start_at")))
+	filter.EndAt = parseTime(strings.TrimSpace(c.Query("end_at")))
+	return filter, true
 }
 
-func (h *LogHandler) cleanupLogFile(cutoff time.Time) (int, int, error) {
-	lines, err := h.readLogLines()
-	if err != nil {
-		return 0, 0, err
-	}
-	if len(lines) == 0 {
-		return 0, 0, nil
+// parseStreamAfterID 读取实时日志的断点（after_id 参数优先于 Last-Event-ID 请求头）
+func parseStreamAfterID(c *gin.Context) (uint, bool, error) {
+	raw := strings.TrimSpace(c.Query("after_id"))
+	if raw == "" {
+		raw = strings.TrimSpace(c.GetHeader("Last-Event-ID"))
 	}
-
-	keptLines := make([]string, 0, len(lines))
-	deleted := 0
-	for _, line := range lines {
-		if line.HasTime && line.Time.Before(cutoff) {
-			deleted++
-			continue
-		}
-		keptLines = append(keptLines, line.Raw)
+	if raw == "" {
+		return 0, false, nil
 	}
-
-	filePath := h.logFilePath()
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0o644)
+	id, err := strconv.ParseUint(raw, 10, 64)
 	if err != nil {
-		return 0, 0, fmt.Errorf("open log file for cleanup: %w", err)
+		return 0, false, err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	for i, line := range keptLines {
-		if i > 0 {
-			if _, err := writer.WriteString("\n"); err != nil {
-				return 0, 0, fmt.Errorf("write log file: %w", err)
-			}
-		}
-		if _, err := writer.WriteString(line); err != nil {
-			return 0, 0, fmt.Errorf("write log file: %w", err)
-		}
-	}
-	if err := writer.Flush(); err != nil {
-		return 0, 0, fmt.Errorf("flush log file: %w", err)
-	}
-
-	return len(keptLines), deleted, nil
+	return uint(id), true, nil
 }
 
-func parseLogTime(value string) (time.Time, bool) {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return time.Time{}, false
+// writeLogEvent 以 SSE 格式写入一条日志
+func writeLogEvent(c *gin.Context, entry model.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
 	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: log\ndata: %s\n\n", entry.ID, data)
+	return err
+}
 
+// parseTime 解析时间字符串，无法解析时返回零值
+func parseTime(s string) time.Time {
 	formats := []string{
-		"2006-01-02T15:04:05.000Z0700",
-		"2006-01-02T15:04:05Z0700",
 		time.RFC3339,
 		"2006-01-02T15:04:05Z07:00",
 		"2006-01-02 15:04:05",
+		"2006-01-02",
 	}
 
 	for _, format := range formats {
-		if t, err := time.Parse(format, trimmed); err == nil {
-			return t, true
+		if t, err := time.ParseInLocation(format, s, time.Local); err == nil {
+			return t
 		}
 	}
-	return time.Time{}, false
-}
-
-func buildFallbackEntry(line logLine) *logEntry {
-	raw := strings.TrimSpace(line.Raw)
-	if raw == "" {
-		return nil
-	}
-
-	parts := strings.Fields(raw)
-	start := 0
-	if line.HasTime {
-		start = 1
-	}
-
-	level := ""
-	if len(parts) > start {
-		level = normalizeLogLevel(parts[start])
-	}
-	if level == "" {
-		level = "info"
-	}
-
-	message := raw
-	if len(parts) > start+1 {
-		message = strings.TrimSpace(strings.Join(parts[start+1:], " "))
-	}
-	if message == "" {
-		message = level
-	}
-
-	createdAt := time.Time{}
-	if line.HasTime {
-		createdAt = line.Time
-	}
 
-	return &logEntry{
-		Level:     level,
-		Message:   message,
-		CreatedAt: createdAt,
-	}
+	return time.Time{}
 }
 
 func normalizeLogLevel(value string) string {
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/infra/db/repository"
+	"go.uber.org/zap"
+)
+
+func TestLogHandler_ListAndStream(t *testing.T) {
+	db := newJobTestDB(t)
+	if err := db.AutoMigrate(&model.LogEntry{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	// 内存数据库每个连接独立，实时追踪与并发写入需共用同一连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	repo, err := repository.NewGormLogRepository(db)
+	if err != nil {
+		t.Fatalf("new log repository: %v", err)
+	}
+
+	jobID, runID := uint(7), uint(42)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		entry := model.LogEntry{
+			Level:     "info",
+			Message:   fmt.Sprintf("同步完成 batch%d", i),
+			JobID:     &jobID,
+			RunID:     &runID,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if i == 4 {
+			entry.Level = "error"
+			entry.Message = "写入 STRM 失败"
+			entry.RunID = nil
+		}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+
+	h := NewLogHandler(repo, zap.NewNop())
+	h.pollInterval = 10 * time.Millisecond
+	r := gin.New()
+	r.GET("/api/logs", h.ListLogs)
+	r.GET("/api/logs/stream", h.StreamLogs)
+
+	type listResp struct {
+		Logs       []model.LogEntry `json:"logs"`
+		Total      int64            `json:"total"`
+		NextCursor *uint            `json:"next_cursor"`
+	}
+	list := func(query string) listResp {
+		t.Helper()
+		w := doReq(r, http.MethodGet, "/api/logs?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status=%d body=%s", query, w.Code, w.Body.String())
+		}
+		var resp listResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	// 游标翻页：按 ID 降序，两页覆盖同一执行记录的 4 条日志
+	first := list(fmt.Sprintf("run_id=%d&page_size=2", runID))
+	if first.Total != 4 || len(first.Logs) != 2 || first.NextCursor == nil {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	second := list(fmt.Sprintf("run_id=%d&page_size=2&cursor=%d", runID, *first.NextCursor))
+	if len(second.Logs) != 2 || second.Logs[0].ID >= first.Logs[1].ID {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	// 级别 + 关键字（中文按子串匹配）
+	if resp := list("level=warn,error&search=" + "STRM%20失败"); resp.Total != 1 || resp.Logs[0].Level != "error" {
+		t.Fatalf("unexpected search result: %+v", resp)
+	}
+	if resp := list("search=batch"); resp.Total != 4 {
+		t.Fatalf("expected 4 prefix matches, got %+v", resp)
+	}
+	if w := doReq(r, http.MethodGet, "/api/logs?level=verbose", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid level, got %d", w.Code)
+	}
+
+	// 实时追踪：回放最近 2 条后推送新日志
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/logs/stream?job_id=%d&tail=2", jobID), nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		db.Create(&model.LogEntry{Level: "warn", Message: "新日志", JobID: &jobID, CreatedAt: time.Now()})
+	}()
+	r.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if got := strings.Count(body, "event: log"); got != 3 {
+		t.Fatalf("expected 3 streamed events, got %d: %s", got, body)
+	}
+	if !strings.Contains(body, "id: 6\n") || strings.Index(body, "id: 4\n") > strings.Index(body, "id: 5\n") {
+		t.Fatalf("unexpected stream order: %s", body)
+	}
+}
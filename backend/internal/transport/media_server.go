@@ -2,6 +2,7 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -127,8 +128,7 @@ func (h *MediaServerHandler) CreateMediaServer(c *gin.Context) {
 
 	if err := h.db.Create(&server).Error; err != nil {
 		// 检查是否为唯一约束错误
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			respondError(c, http.StatusConflict, "duplicate_name", "服务器名称已存在", nil)
 			return
 		}
@@ -336,8 +336,7 @@ func (h *MediaServerHandler) UpdateMediaServer(c *gin.Context) {
 
 	if err := h.db.Save(&server).Error; err != nil {
 		// 检查是否为唯一约束错误
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") ||
-			strings.Contains(err.Error(), "duplicate key") {
+		if isDuplicateKeyError(err) {
 			respondError(c, http.StatusConflict, "duplicate_name", "服务器名称已存在", nil)
 			return
 		}
@@ -488,11 +487,11 @@ func (h *MediaServerHandler) TestMediaServerConnection(c *gin.Context) {
 	var result ConnectionTestResult
 	switch strings.TrimSpace(server.Type) {
 	case "emby":
-		result = testEmbyConnection(server, h.logger)
+		result = testEmbyConnection(c.Request.Context(), server, h.logger)
 	case "jellyfin":
-		result = testJellyfinConnection(server, h.logger)
+		result = testJellyfinConnection(c.Request.Context(), server, h.logger)
 	case "plex":
-		result = testPlexConnection(server, h.logger)
+		result = testPlexConnection(c.Request.Context(), server, h.logger)
 	default:
 		respondError(c, http.StatusBadRequest, "invalid_type", "不支持的服务器类型", nil)
 		return
@@ -508,11 +507,11 @@ func (h *MediaServerHandler) TestMediaServerConnection(c *gin.Context) {
 }
 
 // testEmbyConnection 测试Emby连接
-func testEmbyConnection(server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
+func testEmbyConnection(ctx context.Context, server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
 	start := time.Now()
 	apiURL := fmt.Sprintf("http://%s:%d/System/Info/Public", server.Host, server.Port)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		logger.Error("创建Emby测试请求失败", zap.Error(err))
 		return ConnectionTestResult{
@@ -567,11 +566,11 @@ func testEmbyConnection(server model.MediaServer, logger *zap.Logger) Connection
 }
 
 // testJellyfinConnection 测试Jellyfin连接
-func testJellyfinConnection(server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
+func testJellyfinConnection(ctx context.Context, server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
 	start := time.Now()
 	apiURL := fmt.Sprintf("http://%s:%d/System/Info/Public", server.Host, server.Port)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		logger.Error("创建Jellyfin测试请求失败", zap.Error(err))
 		return ConnectionTestResult{
@@ -628,11 +627,11 @@ func testJellyfinConnection(server model.MediaServer, logger *zap.Logger) Connec
 }
 
 // testPlexConnection 测试Plex连接
-func testPlexConnection(server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
+func testPlexConnection(ctx context.Context, server model.MediaServer, logger *zap.Logger) ConnectionTestResult {
 	start := time.Now()
 	apiURL := fmt.Sprintf("http://%s:%d/identity", server.Host, server.Port)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		logger.Error("创建Plex测试请求失败", zap.Error(err))
 		return ConnectionTestResult{
@@ -757,11 +756,11 @@ func (h *MediaServerHandler) TestMediaServerTemp(c *gin.Context) {
 	var result ConnectionTestResult
 	switch strings.TrimSpace(server.Type) {
 	case "emby":
-		result = testEmbyConnection(server, h.logger)
+		result = testEmbyConnection(c.Request.Context(), server, h.logger)
 	case "jellyfin":
-		result = testJellyfinConnection(server, h.logger)
+		result = testJellyfinConnection(c.Request.Context(), server, h.logger)
 	case "plex":
-		result = testPlexConnection(server, h.logger)
+		result = testPlexConnection(c.Request.Context(), server, h.logger)
 	default:
 		respondError(c, http.StatusBadRequest, "invalid_type", "不支持的服务器类型", nil)
 		return
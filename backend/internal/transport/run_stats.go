@@ -0,0 +1,153 @@
+// Package http 提供HTTP API处理器
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/domain/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 趋势查询的默认与最大时间范围
+const (
+	runTrendHourDefault = 48 * time.Hour
+	runTrendHourMax     = 31 * 24 * time.Hour
+	runTrendDayDefault  = 30 * 24 * time.Hour
+	runTrendDayMax      = 731 * 24 * time.Hour
+)
+
+// RunStatsHandler 执行趋势统计处理器
+type RunStatsHandler struct {
+	db     *gorm.DB
+	stats  repository.RunStatsRepository
+	logger *zap.Logger
+}
+
+// NewRunStatsHandler 创建执行趋势统计处理器
+func NewRunStatsHandler(db *gorm.DB, stats repository.RunStatsRepository, logger *zap.Logger) *RunStatsHandler {
+	return &RunStatsHandler{
+		db:     db,
+		stats:  stats,
+		logger: logger,
+	}
+}
+
+// runTrendPoint 单个时间桶的统计
+type runTrendPoint struct {
+	model.TaskRunRollup
+	FailureRate float64 `json:"failure_rate"` // 失败率：failed / (completed + failed)，不含取消
+}
+
+// runTrendSeries 单个任务的时间序列
+type runTrendSeries struct {
+	JobID   uint            `json:"job_id"`
+	JobName string          `json:"job_name"`
+	Points  []runTrendPoint `json:"points"`
+}
+
+// GetRunTrends 获取执行趋势（按任务与小时/天汇总）
+// GET /api/runs/trends
+//
+// 参数：granularity（hour/day，默认 day）、job_id、from、to。
+// 默认时间范围：hour 为最近 48 小时（最长 31 天），day 为最近 30 天（最长 731 天）。
+// 每个时间桶包含执行次数、时长 P50/P95（秒）、STRM 新建/更新/删除数、失败率、
+// 元数据传输字节数与数据服务器 API 错误数；没有执行记录的时间桶不返回。
+func (h *RunStatsHandler) GetRunTrends(c *gin.Context) {
+	filter := repository.RunTrendFilter{
+		Granularity: strings.ToLower(strings.TrimSpace(c.DefaultQuery("granularity", repository.RunTrendDay))),
+	}
+	var defaultRange, maxRange time.Duration
+	switch filter.Granularity {
+	case repository.RunTrendHour:
+		defaultRange, maxRange = runTrendHourDefault, runTrendHourMax
+	case repository.RunTrendDay:
+		defaultRange, maxRange = runTrendDayDefault, runTrendDayMax
+	default:
+		respondError(c, http.StatusBadRequest, "invalid_request", "granularity必须是hour或day", nil)
+		return
+	}
+
+	if raw := strings.TrimSpace(c.Query("job_id")); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || id == 0 {
+			respondError(c, http.StatusBadRequest, "invalid_request", "无效的job_id参数", nil)
+			return
+		}
+		jobID := uint(id)
+		filter.JobID = &jobID
+	}
+
+	filter.To = time.Now()
+	if raw := strings.TrimSpace(c.Query("to")); raw != "" {
+		if filter.To = parseTime(raw); filter.To.IsZero() {
+			respondError(c, http.StatusBadRequest, "invalid_request", "无效的to参数", nil)
+			return
+		}
+	}
+	filter.From = filter.To.Add(-defaultRange)
+	if raw := strings.TrimSpace(c.Query("from")); raw != "" {
+		if filter.From = parseTime(raw); filter.From.IsZero() {
+			respondError(c, http.StatusBadRequest, "invalid_request", "无效的from参数", nil)
+			return
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		respondError(c, http.StatusBadRequest, "invalid_request", "from必须早于to", nil)
+		return
+	}
+	if filter.To.Sub(filter.From) > maxRange {
+		respondError(c, http.StatusBadRequest, "invalid_request", "时间范围过大", nil)
+		return
+	}
+
+	points, err := h.stats.Trends(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("查询执行趋势失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	series := make([]runTrendSeries, 0)
+	var jobIDs []uint
+	for _, point := range points {
+		if len(series) == 0 || series[len(series)-1].JobID != point.JobID {
+			series = append(series, runTrendSeries{JobID: point.JobID, Points: []runTrendPoint{}})
+			jobIDs = append(jobIDs, point.JobID)
+		}
+		item := runTrendPoint{TaskRunRollup: point}
+		if finished := point.Completed + point.Failed; finished > 0 {
+			item.FailureRate = float64(point.Failed) / float64(finished)
+		}
+		current := &series[len(series)-1]
+		current.Points = append(current.Points, item)
+	}
+
+	if len(jobIDs) > 0 {
+		var jobs []model.Job
+		if err := h.db.Select("id", "name").Where("id IN ?", jobIDs).Find(&jobs).Error; err != nil {
+			h.logger.Error("查询任务名称失败", zap.Error(err))
+			respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+			return
+		}
+		names := make(map[uint]string, len(jobs))
+		for _, job := range jobs {
+			names[job.ID] = job.Name
+		}
+		for i := range series {
+			series[i].JobName = names[series[i].JobID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"granularity": filter.Granularity,
+		"from":        filter.From,
+		"to":          filter.To,
+		"series":      series,
+	})
+}
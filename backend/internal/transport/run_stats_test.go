@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/infra/db/repository"
+	"go.uber.org/zap"
+)
+
+func TestRunStatsHandler_GetRunTrends(t *testing.T) {
+	db := newJobTestDB(t)
+	if err := db.AutoMigrate(&model.TaskRunEvent{}, &model.TaskRunRollup{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	repo, err := repository.NewGormRunStatsRepository(db)
+	if err != nil {
+		t.Fatalf("new run stats repository: %v", err)
+	}
+
+	job := insertJobRaw(t, db, "电影", true)
+	now := time.Now()
+	insertRun := func(key, status string, startedAt time.Time, duration int64, created, apiErrors int) model.TaskRun {
+		t.Helper()
+		run := insertTaskRun(t, db, job.ID, status, key)
+		if err := db.Model(&run).Updates(map[string]any{
+			"started_at":    startedAt,
+			"duration":      duration,
+			"created_files": created,
+			"api_errors":    apiErrors,
+		}).Error; err != nil {
+			t.Fatalf("update run: %v", err)
+		}
+		return run
+	}
+
+	// 较早的执行记录：汇总后删除原始记录，趋势仍可从汇总表读取
+	old := now.AddDate(0, 0, -10)
+	insertRun("old-1", "completed", old, 10, 5, 1)
+	insertRun("old-2", "completed", old, 30, 5, 1)
+	if written, err := repo.Rollup(context.Background(), now); err != nil || written == 0 {
+		t.Fatalf("rollup: written=%d err=%v", written, err)
+	}
+	if err := db.Where("1 = 1").Delete(&model.TaskRun{}).Error; err != nil {
+		t.Fatalf("delete runs: %v", err)
+	}
+
+	// 最近的执行记录：实时计算，删除数与元数据字节数来自事件明细
+	recent := now.Add(-time.Minute)
+	first := insertRun("new-1", "completed", recent, 10, 1, 0)
+	insertRun("new-2", "completed", recent, 20, 1, 0)
+	insertRun("new-3", "failed", recent, 100, 0, 3)
+	insertRun("new-4", "running", recent, 0, 0, 0)
+	for _, event := range []model.TaskRunEvent{
+		{TaskRunID: first.ID, JobID: job.ID, Kind: "strm", Op: "delete", Status: "success"},
+		{TaskRunID: first.ID, JobID: job.ID, Kind: "strm", Op: "delete", Status: "failed"},
+		{TaskRunID: first.ID, JobID: job.ID, Kind: "meta", Op: "copy", Status: "success", Bytes: 1024},
+	} {
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+
+	h := NewRunStatsHandler(db, repo, zap.NewNop())
+	r := gin.New()
+	r.GET("/api/runs/trends", h.GetRunTrends)
+
+	w := doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/trends?granularity=day&job_id=%d&from=%s", job.ID, now.AddDate(0, 0, -14).Format("2006-01-02")), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Series []struct {
+			JobID   uint   `json:"job_id"`
+			JobName string `json:"job_name"`
+			Points  []struct {
+				Runs         int64   `json:"runs"`
+				DurationP50  int64   `json:"duration_p50"`
+				DurationP95  int64   `json:"duration_p95"`
+				CreatedFiles int64   `json:"created_files"`
+				DeletedFiles int64   `json:"deleted_files"`
+				MetaBytes    int64   `json:"meta_bytes"`
+				APIErrors    int64   `json:"api_errors"`
+				FailureRate  float64 `json:"failure_rate"`
+			} `json:"points"`
+		} `json:"series"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Series) != 1 || resp.Series[0].JobName != "电影" || len(resp.Series[0].Points) != 2 {
+		t.Fatalf("unexpected series: %s", w.Body.String())
+	}
+
+	rolled, live := resp.Series[0].Points[0], resp.Series[0].Points[1]
+	if rolled.Runs != 2 || rolled.DurationP50 != 10 || rolled.DurationP95 != 30 || rolled.CreatedFiles != 10 || rolled.APIErrors != 2 {
+		t.Fatalf("unexpected rolled up point: %+v", rolled)
+	}
+	if live.Runs != 3 || live.DurationP50 != 20 || live.DurationP95 != 100 ||
+		live.DeletedFiles != 1 || live.MetaBytes != 1024 || live.APIErrors != 3 {
+		t.Fatalf("unexpected live point: %+v", live)
+	}
+	if live.FailureRate < 0.33 || live.FailureRate > 0.34 {
+		t.Fatalf("unexpected failure rate: %v", live.FailureRate)
+	}
+
+	if w := doReq(r, http.MethodGet, "/api/runs/trends?granularity=week", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid granularity, got %d", w.Code)
+	}
+	if w := doReq(r, http.MethodGet, "/api/runs/trends?granularity=hour&from=2020-01-01", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for oversized range, got %d", w.Code)
+	}
+}
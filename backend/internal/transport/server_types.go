@@ -175,7 +175,7 @@ func cloudDrive2ServerTypeDef() ServerTypeDef {
 		Label:        "CloudDrive2",
 		Category:     "data",
 		Description:  "云盘挂载服务（gRPC）",
-		RulesVersion: 2,
+		RulesVersion: 4,
 		Sections: []ServerSectionDef{
 			{
 				ID:     "auth",
@@ -241,14 +241,42 @@ func cloudDrive2ServerTypeDef() ServerTypeDef {
 					},
 				},
 			},
+			{
+				ID:    "strm",
+				Label: "STRM 链接",
+				Fields: []ServerFieldDef{
+					{
+						Name:  "link_mode",
+						Type:  FieldTypeRadio,
+						Label: "链接类型",
+						Help:  "代理链接经 CloudDrive2 中转，长期有效；直链由云盘提供，会过期，需定期同步刷新",
+						Options: []FieldOption{
+							{Label: "CloudDrive2 代理", Value: "proxy"},
+							{Label: "云盘直链", Value: "direct"},
+						},
+						Default:  "proxy",
+						Required: false,
+					},
+					{
+						Name:        "pickcode_url",
+						Type:        FieldTypeText,
+						Label:       "PickCode 链接模板",
+						Placeholder: "http://115proxy:9000/d/{pickcode}",
+						Help:        "可选，115 文件使用 PickCode 生成 STRM（需 302 代理），支持 {pickcode} 与 {name} 占位符",
+						Required:    false,
+					},
+				},
+			},
 		},
 		Storage: map[string]string{
-			"host":        "root",
-			"port":        "root",
-			"api_token":   "api_key",
-			"access_path": "options",
-			"mount_path":  "options",
-			"remote_root": "options",
+			"host":         "root",
+			"port":         "root",
+			"api_token":    "api_key",
+			"access_path":  "options",
+			"mount_path":   "options",
+			"remote_root":  "options",
+			"link_mode":    "options",
+			"pickcode_url": "options",
 		},
 	}
 }
@@ -263,7 +291,7 @@ func openListServerTypeDef() ServerTypeDef {
 		Label:        "OpenList",
 		Category:     "data",
 		Description:  "OpenList API 服务",
-		RulesVersion: 2,
+		RulesVersion: 3,
 		Sections: []ServerSectionDef{
 			{
 				ID:     "auth",
@@ -338,15 +366,30 @@ func openListServerTypeDef() ServerTypeDef {
 					},
 				},
 			},
+			{
+				ID:    "strm",
+				Label: "STRM 链接",
+				Fields: []ServerFieldDef{
+					{
+						Name:        "pickcode_url",
+						Type:        FieldTypeText,
+						Label:       "PickCode 链接模板",
+						Placeholder: "http://115proxy:9000/d/{pickcode}",
+						Help:        "可选，以 PickCode 作为对象 ID 的 115 存储使用 PickCode 生成 STRM（需 302 代理），支持 {pickcode} 与 {name} 占位符",
+						Required:    false,
+					},
+				},
+			},
 		},
 		Storage: map[string]string{
-			"host":        "root",
-			"port":        "root",
-			"username":    "options",
-			"password":    "options",
-			"access_path": "options",
-			"mount_path":  "options",
-			"remote_root": "options",
+			"host":         "root",
+			"port":         "root",
+			"username":     "options",
+			"password":     "options",
+			"access_path":  "options",
+			"mount_path":   "options",
+			"remote_root":  "options",
+			"pickcode_url": "options",
 		},
 	}
 }
@@ -2,12 +2,16 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/queue"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -20,19 +24,26 @@ func isTaskRunDeletable(status string) bool {
 	return status != "running" && status != "pending"
 }
 
+// TaskResumer 支持恢复失败任务的队列（可选能力，通过类型断言检测）
+type TaskResumer interface {
+	Resume(ctx context.Context, taskID uint) error
+}
+
 // TaskRunHandler 任务执行记录处理器
 type TaskRunHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
-	queue  TaskQueue
+	db        *gorm.DB
+	logger    *zap.Logger
+	queue     TaskQueue
+	runLogDir string // 执行记录日志根目录（为空表示未启用捕获）
 }
 
 // NewTaskRunHandler 创建任务执行记录处理器
-func NewTaskRunHandler(db *gorm.DB, logger *zap.Logger, queue TaskQueue) *TaskRunHandler {
+func NewTaskRunHandler(db *gorm.DB, logger *zap.Logger, queue TaskQueue, runLogDir string) *TaskRunHandler {
 	return &TaskRunHandler{
-		db:     db,
-		logger: logger,
-		queue:  queue,
+		db:        db,
+		logger:    logger,
+		queue:     queue,
+		runLogDir: strings.TrimSpace(runLogDir),
 	}
 }
 
@@ -170,6 +181,7 @@ func (h *TaskRunHandler) DeleteTaskRun(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "db_error", "删除失败", nil)
 		return
 	}
+	h.removeRunLogs(run.ID)
 
 	c.JSON(http.StatusOK, gin.H{"deleted": 1})
 }
@@ -223,6 +235,9 @@ func (h *TaskRunHandler) BatchDeleteTaskRuns(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "db_error", "删除失败", nil)
 		return
 	}
+	for _, run := range runs {
+		h.removeRunLogs(run.ID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"deleted": len(runs)})
 }
@@ -266,6 +281,9 @@ func (h *TaskRunHandler) ListRunEvents(c *gin.Context) {
 	if status := strings.TrimSpace(c.Query("status")); status != "" {
 		query = query.Where("status = ?", status)
 	}
+	if _, ok := c.GetQuery("media_item"); ok {
+		query = query.Where("media_item = ?", strings.TrimSpace(c.Query("media_item")))
+	}
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -292,8 +310,77 @@ func (h *TaskRunHandler) ListRunEvents(c *gin.Context) {
 	})
 }
 
+// runMediaItem 按媒体条目汇总的执行事件
+type runMediaItem struct {
+	MediaItem string `json:"media_item"`
+	Total     int64  `json:"total"`
+	Success   int64  `json:"success"`
+	Failed    int64  `json:"failed"`
+	Skipped   int64  `json:"skipped"`
+	Strm      int64  `json:"strm"`
+	Meta      int64  `json:"meta"`
+}
+
+// ListRunMediaItems 按媒体条目汇总执行事件（视频及其字幕、NFO、海报等附属文件）
+// GET /api/runs/:id/items
+//
+// 查询参数：
+//   - failed_only: 仅返回包含失败事件的条目
+//
+// 单个条目的事件明细可通过 GET /api/runs/:id/events?media_item=... 查询
+func (h *TaskRunHandler) ListRunMediaItems(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return
+	}
+
+	pagination := parsePagination(c, 1, 50, 500)
+
+	query := h.db.Model(&model.TaskRunEvent{}).
+		Select(`media_item,
+			COUNT(*) AS total,
+			SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS success,
+			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) AS failed,
+			SUM(CASE WHEN status = 'skipped' THEN 1 ELSE 0 END) AS skipped,
+			SUM(CASE WHEN kind = 'strm' THEN 1 ELSE 0 END) AS strm,
+			SUM(CASE WHEN kind = 'meta' THEN 1 ELSE 0 END) AS meta`).
+		Where("task_run_id = ?", id).
+		Group("media_item")
+	if c.Query("failed_only") == "true" {
+		query = query.Having("SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) > 0")
+	}
+
+	var total int64
+	if err := h.db.Table("(?) AS grouped", query).Count(&total).Error; err != nil {
+		h.logger.Error("统计媒体条目失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	var items []runMediaItem
+	if err := query.Order("failed DESC").
+		Order("media_item ASC").
+		Offset(pagination.Offset).
+		Limit(pagination.PageSize).
+		Scan(&items).Error; err != nil {
+		h.logger.Error("查询媒体条目失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":     items,
+		"total":     total,
+		"page":      pagination.Page,
+		"page_size": pagination.PageSize,
+	})
+}
+
 // CancelRun 取消正在运行的任务
 // POST /api/runs/:id/cancel
+//
+// 取消通过数据库传播：执行该任务的 Worker（可能位于其他实例）在下一次续期租约时停止执行。
 func (h *TaskRunHandler) CancelRun(c *gin.Context) {
 	if h.queue == nil {
 		respondError(c, http.StatusInternalServerError, "queue_not_ready", "任务队列未初始化", nil)
@@ -335,6 +422,85 @@ func (h *TaskRunHandler) CancelRun(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "任务已取消"})
 }
 
+// ResumeRun 从断点恢复失败的任务
+// POST /api/runs/:id/resume
+//
+// 将失败的执行记录重新置为待执行，Worker 领取后从保存的断点继续，
+// 跳过已处理的文件；没有断点时等价于重新执行。
+func (h *TaskRunHandler) ResumeRun(c *gin.Context) {
+	resumer, ok := h.queue.(TaskResumer)
+	if !ok {
+		respondError(c, http.StatusInternalServerError, "queue_not_ready", "任务队列未初始化", nil)
+		return
+	}
+
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return
+	}
+
+	var run model.TaskRun
+	if err := h.db.First(&run, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondError(c, http.StatusNotFound, "not_found", "执行记录不存在", nil)
+			return
+		}
+		h.logger.Error("查询执行记录失败", zap.Error(err), zap.Uint64("id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	if run.Status != string(syncqueue.TaskFailed) {
+		respondError(c, http.StatusConflict, "invalid_status", "只能恢复失败的任务", nil)
+		return
+	}
+
+	// 防止重复运行：同一 Job 存在 pending/running 任务时拒绝恢复
+	var activeCount int64
+	if err := h.db.Model(&model.TaskRun{}).
+		Where("job_id = ? AND status IN ?", run.JobID, []string{string(syncqueue.TaskPending), string(syncqueue.TaskRunning)}).
+		Count(&activeCount).Error; err != nil {
+		h.logger.Error("检查任务运行状态失败", zap.Error(err), zap.Uint("job_id", run.JobID))
+		respondError(c, http.StatusInternalServerError, "db_error", "数据库错误", nil)
+		return
+	}
+	if activeCount > 0 {
+		respondError(c, http.StatusConflict, "job_running", "任务已在运行中", nil)
+		return
+	}
+
+	if err := resumer.Resume(c.Request.Context(), run.ID); err != nil {
+		if errors.Is(err, syncqueue.ErrInvalidTransition) {
+			respondError(c, http.StatusConflict, "invalid_status", "只能恢复失败的任务", nil)
+			return
+		}
+		h.logger.Error("恢复任务失败", zap.Error(err), zap.Uint("run_id", run.ID))
+		respondError(c, http.StatusInternalServerError, "queue_error", "恢复失败", nil)
+		return
+	}
+
+	// 更新Job运行状态
+	now := time.Now()
+	if err := h.db.Model(&model.Job{}).Where("id = ?", run.JobID).Updates(map[string]interface{}{
+		"last_run_at": &now,
+		"status":      "running",
+	}).Error; err != nil {
+		h.logger.Warn("更新任务状态失败", zap.Error(err), zap.Uint("job_id", run.JobID))
+	}
+
+	hasCheckpoint := strings.TrimSpace(run.Checkpoint) != ""
+	h.logger.Info("恢复任务成功",
+		zap.Uint("run_id", run.ID),
+		zap.Uint("job_id", run.JobID),
+		zap.Bool("has_checkpoint", hasCheckpoint))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "任务已恢复",
+		"has_checkpoint": hasCheckpoint,
+	})
+}
+
 // GetRunStats 获取运行统计信息
 // GET /api/runs/stats
 func (h *TaskRunHandler) GetRunStats(c *gin.Context) {
@@ -0,0 +1,265 @@
+// Package http 提供HTTP API处理器
+package http
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"github.com/strmsync/strmsync/internal/worker"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// runEventsCSVBatch 导出事件明细时每批读取的条数
+const runEventsCSVBatch = 1000
+
+// GetRunLog 下载执行记录日志
+// GET /api/runs/:id/log
+func (h *TaskRunHandler) GetRunLog(c *gin.Context) {
+	run, ok := h.loadRunForLog(c)
+	if !ok {
+		return
+	}
+
+	path := filepath.Join(logger.RunLogDir(h.runLogDir, run.ID), logger.RunLogFile)
+	if _, err := os.Stat(path); err != nil {
+		respondError(c, http.StatusNotFound, "not_found", "该执行记录没有日志", nil)
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("run-%d.log", run.ID))
+}
+
+// DownloadRunBundle 下载执行记录调试包（zip）
+// GET /api/runs/:id/bundle
+//
+// 包含：run.json（执行记录）、job.json（任务与服务器配置，敏感字段已脱敏）、
+// run.log、engine_options.json、stats.json（执行时保存，可能缺失）与 events.csv（事件明细）。
+func (h *TaskRunHandler) DownloadRunBundle(c *gin.Context) {
+	run, ok := h.loadRunForLog(c)
+	if !ok {
+		return
+	}
+
+	var job model.Job
+	jobErr := h.db.Preload("DataServer").Preload("MediaServer").First(&job, run.JobID).Error
+	if jobErr != nil && !errors.Is(jobErr, gorm.ErrRecordNotFound) {
+		h.logger.Error("查询任务失败", zap.Error(jobErr), zap.Uint("job_id", run.JobID))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%d-debug.zip"`, run.ID))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	err := writeZipJSON(zw, "run.json", run)
+	if err == nil && jobErr == nil {
+		err = writeZipJSON(zw, "job.json", redactJob(job))
+	}
+	if err == nil {
+		dir := logger.RunLogDir(h.runLogDir, run.ID)
+		for _, name := range []string{logger.RunLogFile, worker.RunEngineOptionsFile, worker.RunStatsFile} {
+			if err = writeZipFile(zw, name, filepath.Join(dir, name)); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = h.writeRunEventsCSV(zw, run.ID)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		// 响应头已发送，只能中断下载并记录日志
+		h.logger.Error("生成执行记录调试包失败", zap.Error(err), zap.Uint("run_id", run.ID))
+	}
+}
+
+// loadRunForLog 查询执行记录（不预加载关联），失败时写入错误响应
+func (h *TaskRunHandler) loadRunForLog(c *gin.Context) (model.TaskRun, bool) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return model.TaskRun{}, false
+	}
+
+	var run model.TaskRun
+	if err := h.db.First(&run, uint(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "not_found", "执行记录不存在", nil)
+			return model.TaskRun{}, false
+		}
+		h.logger.Error("查询执行记录失败", zap.Error(err), zap.Uint64("id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return model.TaskRun{}, false
+	}
+	if h.runLogDir == "" {
+		respondError(c, http.StatusNotFound, "not_found", "未启用执行记录日志", nil)
+		return model.TaskRun{}, false
+	}
+	return run, true
+}
+
+// writeRunEventsCSV 分批导出事件明细
+func (h *TaskRunHandler) writeRunEventsCSV(zw *zip.Writer, runID uint) error {
+	w, err := zw.Create("events.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "created_at", "kind", "op", "status", "media_item", "source_path", "target_path", "previous_path", "error_message"}); err != nil {
+		return err
+	}
+
+	var lastID uint
+	for {
+		var events []model.TaskRunEvent
+		if err := h.db.Where("task_run_id = ? AND id > ?", runID, lastID).
+			Order("id ASC").
+			Limit(runEventsCSVBatch).
+			Find(&events).Error; err != nil {
+			return fmt.Errorf("query run events: %w", err)
+		}
+		for _, event := range events {
+			if err := cw.Write([]string{
+				strconv.FormatUint(uint64(event.ID), 10),
+				event.CreatedAt.Format(time.RFC3339),
+				event.Kind,
+				event.Op,
+				event.Status,
+				event.MediaItem,
+				event.SourcePath,
+				event.TargetPath,
+				event.PreviousPath,
+				event.ErrorMessage,
+			}); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+		if len(events) < runEventsCSVBatch {
+			break
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// removeRunLogs 删除执行记录的日志目录（失败只记录警告）
+func (h *TaskRunHandler) removeRunLogs(runID uint) {
+	if h.runLogDir == "" {
+		return
+	}
+	if err := os.RemoveAll(logger.RunLogDir(h.runLogDir, runID)); err != nil {
+		h.logger.Warn("删除执行记录日志失败", zap.Error(err), zap.Uint("run_id", runID))
+	}
+}
+
+// redactJob 脱敏任务及关联服务器配置中的密钥
+func redactJob(job model.Job) model.Job {
+	job.Options = redactOptionsJSON(job.Options)
+	if job.DataServer != nil {
+		server := *job.DataServer
+		server.APIKey = redactSecret("api_key", server.APIKey)
+		server.Options = redactOptionsJSON(server.Options)
+		job.DataServer = &server
+	}
+	if job.MediaServer != nil {
+		server := *job.MediaServer
+		server.APIKey = redactSecret("api_key", server.APIKey)
+		server.Options = redactOptionsJSON(server.Options)
+		job.MediaServer = &server
+	}
+	return job
+}
+
+// redactOptionsJSON 脱敏 JSON 扩展字段中的敏感键（无法解析时整体隐藏）
+func redactOptionsJSON(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return "****"
+	}
+	data, err := json.Marshal(redactValue("", value))
+	if err != nil {
+		return "****"
+	}
+	return string(data)
+}
+
+// redactValue 递归脱敏 JSON 值
+func redactValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = redactValue(k, item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(key, item)
+		}
+		return v
+	case string:
+		return redactSecret(key, v)
+	default:
+		return v
+	}
+}
+
+// redactSecret 脱敏敏感字段，空值保持为空
+func redactSecret(key, value string) string {
+	if value == "" {
+		return value
+	}
+	return redactSensitive(key, value)
+}
+
+// writeZipJSON 将对象以 JSON 写入 zip
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeZipFile 将本地文件写入 zip（文件不存在时跳过）
+func writeZipFile(zw *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
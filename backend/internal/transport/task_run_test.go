@@ -0,0 +1,159 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"go.uber.org/zap"
+)
+
+func TestTaskRunHandler_ListRunMediaItems(t *testing.T) {
+	db := newJobTestDB(t)
+	if err := db.AutoMigrate(&model.TaskRunEvent{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	job := insertJobRaw(t, db, "media-items", true)
+	run := insertTaskRun(t, db, job.ID, "completed", "media-items-1")
+
+	events := []model.TaskRunEvent{
+		{Kind: "strm", Op: "create", Status: "success", MediaItem: "/movies/A/A"},
+		{Kind: "meta", Op: "copy", Status: "success", MediaItem: "/movies/A/A"},
+		{Kind: "meta", Op: "copy", Status: "failed", MediaItem: "/movies/A/A"},
+		{Kind: "strm", Op: "skip", Status: "skipped", MediaItem: "/movies/B/B"},
+	}
+	for i := range events {
+		events[i].TaskRunID = run.ID
+		events[i].JobID = job.ID
+		if err := db.Create(&events[i]).Error; err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+
+	h := NewTaskRunHandler(db, zap.NewNop(), nil, "")
+	r := gin.New()
+	r.GET("/api/runs/:id/items", h.ListRunMediaItems)
+	r.GET("/api/runs/:id/events", h.ListRunEvents)
+
+	var resp struct {
+		Items []runMediaItem `json:"items"`
+		Total int64          `json:"total"`
+	}
+	w := doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/%d/items", run.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Items) != 2 {
+		t.Fatalf("expected 2 media items, got total=%d items=%+v", resp.Total, resp.Items)
+	}
+	first := resp.Items[0] // 含失败事件的条目排在前面
+	if first.MediaItem != "/movies/A/A" || first.Total != 3 || first.Failed != 1 || first.Strm != 1 || first.Meta != 2 {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+
+	w = doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/%d/items?failed_only=true", run.ID), nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Items) != 1 || resp.Items[0].MediaItem != "/movies/A/A" {
+		t.Errorf("failed_only: unexpected result total=%d items=%+v", resp.Total, resp.Items)
+	}
+
+	var eventsResp struct {
+		Total int64 `json:"total"`
+	}
+	w = doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/%d/events?media_item=/movies/B/B", run.ID), nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &eventsResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if eventsResp.Total != 1 {
+		t.Errorf("media_item filter: expected 1 event, got %d", eventsResp.Total)
+	}
+}
+
+func TestTaskRunHandler_DownloadRunBundle(t *testing.T) {
+	db := newJobTestDB(t)
+	if err := db.AutoMigrate(&model.TaskRunEvent{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	server := insertDataServer(t, db, "cd2", "clouddrive2", "127.0.0.1", 19798)
+	if err := db.Model(&server).Updates(map[string]any{
+		"api_key": "secret-token-1234",
+		"options": `{"password":"hunter22","base_url":"http://cd2"}`,
+	}).Error; err != nil {
+		t.Fatalf("update server: %v", err)
+	}
+	job := insertJobRaw(t, db, "bundle", true)
+	if err := db.Model(&job).Update("data_server_id", server.ID).Error; err != nil {
+		t.Fatalf("update job: %v", err)
+	}
+	run := insertTaskRun(t, db, job.ID, "failed", "bundle-1")
+	if err := db.Create(&model.TaskRunEvent{TaskRunID: run.ID, JobID: job.ID, Kind: "strm", Op: "create", Status: "failed", SourcePath: "/a.mkv", ErrorMessage: "boom"}).Error; err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+
+	root := t.TempDir()
+	runDir := filepath.Join(root, strconv.FormatUint(uint64(run.ID), 10))
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "run.log"), []byte(`{"msg":"hello"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write run log: %v", err)
+	}
+
+	h := NewTaskRunHandler(db, zap.NewNop(), nil, root)
+	r := gin.New()
+	r.GET("/api/runs/:id/log", h.GetRunLog)
+	r.GET("/api/runs/:id/bundle", h.DownloadRunBundle)
+
+	if w := doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/%d/log", run.ID), nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "hello") {
+		t.Fatalf("log: status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w := doReq(r, http.MethodGet, fmt.Sprintf("/api/runs/%d/bundle", run.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bundle: status=%d body=%s", w.Code, w.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"run.json", "job.json", "run.log", "events.csv"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("bundle missing %s, got %v", name, files)
+		}
+	}
+	if strings.Contains(files["job.json"], "secret-token") || strings.Contains(files["job.json"], "hunter22") {
+		t.Fatalf("secrets not redacted: %s", files["job.json"])
+	}
+	if !strings.Contains(files["job.json"], "http://cd2") {
+		t.Fatalf("non-secret options lost: %s", files["job.json"])
+	}
+	if !strings.Contains(files["events.csv"], "/a.mkv") || !strings.Contains(files["events.csv"], "boom") {
+		t.Fatalf("unexpected events.csv: %s", files["events.csv"])
+	}
+}
@@ -0,0 +1,147 @@
+// Package http 提供HTTP API处理器
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Worker 实例状态
+const (
+	WorkerNodeOnline  = "online"  // 心跳有效
+	WorkerNodeOffline = "offline" // 心跳过期（进程异常退出或失联）
+	WorkerNodeStopped = "stopped" // 正常停止
+)
+
+// WorkerNodeHandler Worker 注册表处理器
+//
+// 多个实例共享同一数据库时，每个实例的 Worker 池定期写入心跳（worker_nodes 表），
+// 注册表用于查看各实例的在线状态与正在执行的任务。
+type WorkerNodeHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewWorkerNodeHandler 创建 Worker 注册表处理器
+func NewWorkerNodeHandler(db *gorm.DB, logger *zap.Logger) *WorkerNodeHandler {
+	return &WorkerNodeHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// workerNodeResponse Worker 实例信息
+type workerNodeResponse struct {
+	model.WorkerNode
+	Status       string `json:"status"`        // 实例状态: online/offline/stopped
+	RunningTasks int64  `json:"running_tasks"` // 持有的执行中任务数（来自 task_runs）
+}
+
+// workerNodeStatus 根据心跳计算实例状态
+func workerNodeStatus(node model.WorkerNode, now time.Time) string {
+	if node.StoppedAt != nil {
+		return WorkerNodeStopped
+	}
+	if node.ExpiresAt.After(now) {
+		return WorkerNodeOnline
+	}
+	return WorkerNodeOffline
+}
+
+// ListWorkers 获取 Worker 实例列表
+// GET /api/workers
+//
+// 按分组、ID 排序；running_tasks 统计 task_runs 中由该实例持有的 running 任务，
+// 离线实例持有的任务会在租约到期后被其他实例回收。
+func (h *WorkerNodeHandler) ListWorkers(c *gin.Context) {
+	var nodes []model.WorkerNode
+	if err := h.db.Order("worker_group ASC, id ASC").Find(&nodes).Error; err != nil {
+		h.logger.Error("查询Worker列表失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+
+	type runningCount struct {
+		WorkerID string
+		Count    int64
+	}
+	var counts []runningCount
+	if err := h.db.Model(&model.TaskRun{}).
+		Select("worker_id, COUNT(*) AS count").
+		Where("status = ? AND worker_id <> ''", "running").
+		Group("worker_id").
+		Scan(&counts).Error; err != nil {
+		h.logger.Error("统计Worker执行中任务失败", zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+	running := make(map[string]int64, len(counts))
+	for _, item := range counts {
+		running[item.WorkerID] = item.Count
+	}
+
+	now := time.Now()
+	workers := make([]workerNodeResponse, 0, len(nodes))
+	online := 0
+	for _, node := range nodes {
+		item := workerNodeResponse{
+			WorkerNode:   node,
+			Status:       workerNodeStatus(node, now),
+			RunningTasks: running[node.ID],
+		}
+		if item.Status == WorkerNodeOnline {
+			online++
+		}
+		workers = append(workers, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workers": workers,
+		"total":   len(workers),
+		"online":  online,
+	})
+}
+
+// DeleteWorker 从注册表移除 Worker 实例
+// DELETE /api/workers/:id
+//
+// 只能移除已停止或离线的实例；实例重新启动后会再次注册。
+func (h *WorkerNodeHandler) DeleteWorker(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		respondError(c, http.StatusBadRequest, "invalid_request", "无效的ID参数", nil)
+		return
+	}
+
+	var node model.WorkerNode
+	if err := h.db.Where("id = ?", id).Take(&node).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "not_found", "Worker不存在", nil)
+			return
+		}
+		h.logger.Error("查询Worker失败", zap.Error(err), zap.String("worker_id", id))
+		respondError(c, http.StatusInternalServerError, "db_error", "查询失败", nil)
+		return
+	}
+	if workerNodeStatus(node, time.Now()) == WorkerNodeOnline {
+		respondError(c, http.StatusConflict, "worker_online", "Worker在线，无法移除", nil)
+		return
+	}
+
+	if err := h.db.Where("id = ?", id).Delete(&model.WorkerNode{}).Error; err != nil {
+		h.logger.Error(fmt.Sprintf("移除Worker「%s」失败", id), zap.Error(err))
+		respondError(c, http.StatusInternalServerError, "db_error", "删除失败", nil)
+		return
+	}
+
+	h.logger.Info(fmt.Sprintf("移除Worker「%s」成功", id))
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"go.uber.org/zap"
+)
+
+func TestWorkerNodeHandler_ListAndDelete(t *testing.T) {
+	db := newJobTestDB(t)
+	if err := db.AutoMigrate(&model.WorkerNode{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+
+	now := time.Now()
+	stoppedAt := now.Add(-time.Hour)
+	for _, node := range []model.WorkerNode{
+		{ID: "nas-1", Group: "nas", Concurrency: 4, HeartbeatAt: now, ExpiresAt: now.Add(time.Minute)},
+		{ID: "seedbox", Concurrency: 2, HeartbeatAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Hour)},
+		{ID: "old", Concurrency: 2, HeartbeatAt: stoppedAt, ExpiresAt: stoppedAt, StoppedAt: &stoppedAt},
+	} {
+		if err := db.Create(&node).Error; err != nil {
+			t.Fatalf("create worker node: %v", err)
+		}
+	}
+	job := insertJobRaw(t, db, "电影", true)
+	run := insertTaskRun(t, db, job.ID, "running", "run-1")
+	if err := db.Model(&run).Update("worker_id", "nas-1").Error; err != nil {
+		t.Fatalf("update run: %v", err)
+	}
+
+	h := NewWorkerNodeHandler(db, zap.NewNop())
+	r := gin.New()
+	r.GET("/api/workers", h.ListWorkers)
+	r.DELETE("/api/workers/:id", h.DeleteWorker)
+
+	w := doReq(r, http.MethodGet, "/api/workers", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Workers []struct {
+			ID           string `json:"id"`
+			Status       string `json:"status"`
+			RunningTasks int64  `json:"running_tasks"`
+		} `json:"workers"`
+		Online int `json:"online"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, item := range resp.Workers {
+		statuses[item.ID] = item.Status
+		if item.ID == "nas-1" && item.RunningTasks != 1 {
+			t.Fatalf("expected 1 running task on nas-1, got %d", item.RunningTasks)
+		}
+	}
+	if resp.Online != 1 || statuses["nas-1"] != WorkerNodeOnline ||
+		statuses["seedbox"] != WorkerNodeOffline || statuses["old"] != WorkerNodeStopped {
+		t.Fatalf("unexpected workers: %s", w.Body.String())
+	}
+
+	if w := doReq(r, http.MethodDelete, "/api/workers/nas-1", nil); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for online worker, got %d", w.Code)
+	}
+	if w := doReq(r, http.MethodDelete, "/api/workers/seedbox", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for offline worker, got %d body=%s", w.Code, w.Body.String())
+	}
+	if w := doReq(r, http.MethodDelete, "/api/workers/seedbox", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
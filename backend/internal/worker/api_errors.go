@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+)
+
+// apiErrorDriverFactory 为构建出的驱动统计 API 调用失败次数
+//
+// 单次执行内构建的所有驱动（STRM、远程列表、元数据列表）共用同一计数器。
+type apiErrorDriverFactory struct {
+	next   DriverFactory
+	errors *atomic.Int64
+}
+
+// Build 实现 DriverFactory
+func (f *apiErrorDriverFactory) Build(ctx context.Context, server model.DataServer) (syncengine.Driver, error) {
+	driver, err := f.next.Build(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	return &apiErrorDriver{Driver: driver, errors: f.errors}, nil
+}
+
+// apiErrorDriver 统计驱动调用失败次数的包装
+//
+// 取消、不支持的操作与路径不存在不计入；CompareStrm 只做本地比较，不计入。
+type apiErrorDriver struct {
+	syncengine.Driver
+	errors *atomic.Int64
+}
+
+func (d *apiErrorDriver) List(ctx context.Context, path string, opt syncengine.ListOptions) ([]syncengine.RemoteEntry, error) {
+	entries, err := d.Driver.List(ctx, path, opt)
+	d.record(ctx, err)
+	return entries, err
+}
+
+func (d *apiErrorDriver) ListStream(ctx context.Context, path string, opt syncengine.ListOptions, fn func(syncengine.RemoteEntry) error) error {
+	// 回调返回的错误来自调用方，不属于 API 错误
+	var callbackErr error
+	err := d.Driver.ListStream(ctx, path, opt, func(entry syncengine.RemoteEntry) error {
+		if err := fn(entry); err != nil {
+			callbackErr = err
+			return err
+		}
+		return nil
+	})
+	if callbackErr == nil || !errors.Is(err, callbackErr) {
+		d.record(ctx, err)
+	}
+	return err
+}
+
+func (d *apiErrorDriver) Watch(ctx context.Context, path string, opt syncengine.WatchOptions) (<-chan syncengine.DriverEvent, error) {
+	events, err := d.Driver.Watch(ctx, path, opt)
+	d.record(ctx, err)
+	return events, err
+}
+
+func (d *apiErrorDriver) Stat(ctx context.Context, path string) (syncengine.RemoteEntry, error) {
+	entry, err := d.Driver.Stat(ctx, path)
+	d.record(ctx, err)
+	return entry, err
+}
+
+func (d *apiErrorDriver) BuildStrmInfo(ctx context.Context, req syncengine.BuildStrmRequest) (syncengine.StrmInfo, error) {
+	info, err := d.Driver.BuildStrmInfo(ctx, req)
+	d.record(ctx, err)
+	return info, err
+}
+
+func (d *apiErrorDriver) TestConnection(ctx context.Context) error {
+	err := d.Driver.TestConnection(ctx)
+	d.record(ctx, err)
+	return err
+}
+
+// record 记录一次失败调用
+func (d *apiErrorDriver) record(ctx context.Context, err error) {
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	if errors.Is(err, syncengine.ErrNotSupported) || errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	d.errors.Add(1)
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"fmt"
+
+	appsync "github.com/strmsync/strmsync/internal/app/sync"
+	"github.com/strmsync/strmsync/internal/pkg/imageutil"
+)
+
+// artworkOptions 元数据图片处理配置（Job 选项 artwork）
+//
+// 复制/下载海报、背景图时按最大宽高缩小并重新编码，减小 STRM 库体积。
+// 处理后的图片与源文件内容不同，按源文件指纹记录（见 metaHashChecker），源图片变化时才重新处理。
+type artworkOptions struct {
+	Enabled          bool     `json:"enabled"`
+	MaxWidth         int      `json:"max_width"`           // 最大宽度（0=不限制）
+	MaxHeight        int      `json:"max_height"`          // 最大高度（0=不限制）
+	Quality          int      `json:"quality"`             // JPEG 质量（1-100，0=仅缩放时以默认质量编码）
+	SkipLargerThanMB int64    `json:"skip_larger_than_mb"` // 源图片超过该大小（MB）时不复制（0=不限制）
+	Exts             []string `json:"exts"`                // 处理的扩展名（默认 .jpg/.jpeg/.png）
+}
+
+// resolveArtwork 解析元数据图片处理配置；未启用时返回 nil
+func resolveArtwork(extra jobOptions) (*appsync.ArtworkOptions, error) {
+	opts := extra.Artwork
+	if !opts.Enabled {
+		return nil, nil
+	}
+	if opts.SkipLargerThanMB < 0 {
+		return nil, fmt.Errorf("invalid artwork options: skip_larger_than_mb must not be negative")
+	}
+	image := imageutil.Options{
+		MaxWidth:    opts.MaxWidth,
+		MaxHeight:   opts.MaxHeight,
+		JPEGQuality: opts.Quality,
+	}
+	if err := image.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid artwork options: %w", err)
+	}
+	return &appsync.ArtworkOptions{
+		Image:   image,
+		Exts:    normalizeExtensions(opts.Exts, appsync.DefaultArtworkExtensions()),
+		MaxSize: opts.SkipLargerThanMB * 1024 * 1024,
+	}, nil
+}
@@ -11,6 +11,8 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	appports "github.com/strmsync/strmsync/internal/app/ports"
@@ -24,6 +26,7 @@ import (
 	"github.com/strmsync/strmsync/internal/strmwriter"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Executor 执行单个 TaskRun 的同步逻辑
@@ -49,9 +52,13 @@ type ExecutorConfig struct {
 	DataServers   DataServerRepository
 	TaskRuns      TaskRunRepository
 	TaskRunEvents TaskRunEventRepository
+	MetaHashes    MetaHashRepository
+	Mappings      StrmMappingRepository
 	DriverFactory DriverFactory
 	WriterFactory WriterFactory
 	Logger        *zap.Logger
+	RunLogDir     string // 执行记录日志根目录（为空时不单独捕获）
+	Fsync         bool   // 写入 STRM 与元数据文件后是否 fsync
 }
 
 // NewExecutor 创建 Executor 实例
@@ -78,7 +85,7 @@ func NewExecutor(cfg ExecutorConfig) (*Executor, error) {
 		cfg.DriverFactory = DefaultDriverFactory{Logger: cfg.Logger}
 	}
 	if cfg.WriterFactory == nil {
-		cfg.WriterFactory = DefaultWriterFactory{Logger: cfg.Logger}
+		cfg.WriterFactory = DefaultWriterFactory{Logger: cfg.Logger, Fsync: cfg.Fsync, DataServers: cfg.DataServers}
 	}
 
 	// 设置日志器
@@ -98,7 +105,7 @@ func NewExecutor(cfg ExecutorConfig) (*Executor, error) {
 // 1. 加载 Job 配置
 // 2. 加载 DataServer 配置
 // 3. 构建 Driver 和 Writer
-// 4. 构建 EngineOptions
+// 4. 构建 EngineOptions（含断点续传配置）
 // 5. 创建 Engine 实例
 // 6. 执行 Engine.RunOnce
 // 7. 更新 TaskRun 进度
@@ -106,6 +113,9 @@ func NewExecutor(cfg ExecutorConfig) (*Executor, error) {
 // 错误处理：
 // - 配置错误返回永久失败
 // - 执行错误根据类型返回可重试或永久失败
+//
+// 配置了 RunLogDir 时，本次执行的引擎、驱动与元数据日志会额外写入独立的执行记录日志，
+// 并保存生效的 EngineOptions 与统计信息，供下载调试包使用。
 func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.SyncStats, error) {
 	if e == nil {
 		return syncengine.SyncStats{}, fmt.Errorf("worker: executor is nil")
@@ -117,6 +127,60 @@ func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.Syn
 		ctx = context.Background()
 	}
 
+	capture := e.openRunCapture(task.ID)
+	defer capture.close()
+
+	// 使用执行记录日志器的副本执行，避免影响并发执行的其他任务
+	scoped := *e
+	scoped.log = capture.log
+	if factory, ok := e.cfg.DriverFactory.(LoggerScopedDriverFactory); ok {
+		scoped.cfg.DriverFactory = factory.WithLogger(capture.log.With(zap.String("component", "driver")))
+	}
+	drivers := &runDriverFactory{next: scoped.cfg.DriverFactory}
+	defer drivers.finish()
+	scoped.cfg.DriverFactory = &apiErrorDriverFactory{next: drivers, errors: &capture.apiErrors}
+
+	stats, err := scoped.run(ctx, task, capture)
+	if err != nil {
+		capture.log.Warn("同步任务执行失败", zap.Uint("task_id", task.ID), zap.Error(err))
+	}
+	return stats, err
+}
+
+// runDriverFactory 记录单次执行内构建的驱动，执行结束后通知实现 filesystem.RunFinisher 的驱动
+type runDriverFactory struct {
+	next    DriverFactory
+	mu      sync.Mutex
+	drivers []filesystem.RunFinisher
+}
+
+// Build 实现 DriverFactory
+func (f *runDriverFactory) Build(ctx context.Context, server model.DataServer) (syncengine.Driver, error) {
+	driver, err := f.next.Build(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	if finisher, ok := driver.(filesystem.RunFinisher); ok {
+		f.mu.Lock()
+		f.drivers = append(f.drivers, finisher)
+		f.mu.Unlock()
+	}
+	return driver, nil
+}
+
+// finish 通知本次执行构建的驱动执行已结束
+func (f *runDriverFactory) finish() {
+	f.mu.Lock()
+	drivers := f.drivers
+	f.drivers = nil
+	f.mu.Unlock()
+	for _, driver := range drivers {
+		driver.FinishRun()
+	}
+}
+
+// run 执行同步流程（由 Run 注入执行记录日志器）
+func (e *Executor) run(ctx context.Context, task *model.TaskRun, capture *runCapture) (syncengine.SyncStats, error) {
 	// 1. 加载 Job 配置
 	job, err := e.cfg.JobRepo.GetByID(ctx, task.JobID)
 	if err != nil {
@@ -197,18 +261,50 @@ func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.Syn
 		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("build driver for job %s: %w", job.Name, err))
 	}
 
-	writer, err := e.cfg.WriterFactory.Build(ctx, job)
+	// 远程输出（WebDAV/OpenList）：target_path 为远端路径，不支持依赖本地文件系统的暂存同步
+	writerType, err := resolveWriterType(extra)
+	if err != nil {
+		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("resolve writer: %w", err))
+	}
+	remoteOutput := writerType != writerTypeLocal
+	if _, stagingEnabled, _ := resolveStagingMode(extra); remoteOutput && stagingEnabled {
+		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("staging_mode is not supported with writer type %s", writerType))
+	}
+
+	// 暂存目录同步：写入目标目录旁的暂存目录，完成后整体切换，媒体服务器不会看到同步到一半的媒体库
+	outputJob := job
+	staging, err := prepareStaging(job, extra, task.ID)
+	if err != nil {
+		return syncengine.SyncStats{}, wrapTaskError(fmt.Errorf("prepare staging for job %s: %w", job.Name, err))
+	}
+	if staging != nil {
+		outputJob.TargetPath = staging.Dir()
+		execLog.Info("使用暂存目录同步",
+			zap.String("staging_dir", staging.Dir()),
+			zap.String("swap_mode", extra.StagingMode),
+			zap.Bool("resumed", staging.Resumed()))
+	} else if strings.TrimSpace(extra.StagingMode) != "" && extra.DryRun {
+		execLog.Info("干运行模式不使用暂存目录")
+	}
+
+	writer, err := e.cfg.WriterFactory.Build(ctx, outputJob)
 	if err != nil {
 		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("build writer for job %s: %w", job.Name, err))
 	}
 	execLog.Info("构建同步组件完成",
-		zap.String("driver_type", driver.Type().String()))
+		zap.String("driver_type", driver.Type().String()),
+		zap.String("writer_type", writerType))
 
 	// 4. 构建 EngineOptions
-	engineOpts, err := buildEngineOptions(job, extra)
+	engineOpts, err := buildEngineOptions(outputJob, extra)
 	if err != nil {
 		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("build engine options: %w", err))
 	}
+	if staging != nil {
+		// 暂存目录以当前目录为基础建立，清理孤儿后即与远端一致
+		engineOpts.EnableOrphanCleanup = true
+		engineOpts.OrphanCleanupDryRun = false
+	}
 	// 设置挂载路径映射（系统级基线转换，在用户替换规则之前执行）
 	if useLocalStrm {
 		accessPath := filepath.Clean(strings.TrimSpace(getAccessPathFromServer(serverForDriver)))
@@ -260,6 +356,49 @@ func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.Syn
 		engineOpts.EventSink = eventSink
 	}
 
+	// 断点续传：重试或手动恢复的任务从上次保存的断点继续
+	if staging != nil && !staging.Resumed() && strings.TrimSpace(task.Checkpoint) != "" {
+		// 暂存目录重新建立，断点之前的文件并未写入其中
+		execLog.Info("暂存目录已重新建立，忽略同步断点")
+	} else if checkpoint, ok := parseCheckpoint(task.Checkpoint); ok {
+		engineOpts.ResumeFrom = &checkpoint
+		execLog.Info("检测到同步断点，将从断点继续",
+			zap.String("last_path", checkpoint.LastPath),
+			zap.Int64("processed_files", checkpoint.ProcessedFiles))
+	} else if strings.TrimSpace(task.Checkpoint) != "" {
+		execLog.Warn("同步断点无效，将从头开始")
+	}
+	if sink := newTaskRunCheckpointSink(e.cfg.TaskRuns, task.ID, execLog); sink != nil {
+		engineOpts.CheckpointSink = sink
+	}
+
+	// 媒体布局：按解析出的标题、年份、季集整理输出目录，映射表跨次同步保持输出路径；
+	// 移动检测：映射表同时记录每个 STRM 对应的远端文件签名
+	if _, err := resolveLayout(extra); err != nil {
+		return syncengine.SyncStats{}, permanentTaskError(fmt.Errorf("resolve layout: %w", err))
+	}
+	records, err := e.loadStrmRecords(ctx, job, extra, engineOpts.DetectMoves)
+	if err != nil {
+		return syncengine.SyncStats{}, wrapTaskError(err)
+	}
+	var moveIndex *syncengine.MoveIndex
+	if engineOpts.DetectMoves {
+		moveIndex = syncengine.NewMoveIndex(records)
+		engineOpts.MoveIndex = moveIndex
+	}
+	layout := loadLayout(extra, records)
+	if layout != nil {
+		engineOpts.Layout = layout
+		execLog.Info("使用媒体输出布局",
+			zap.String("mode", extra.Layout.Mode),
+			zap.String("movies_dir", extra.Layout.MoviesDir),
+			zap.String("shows_dir", extra.Layout.ShowsDir),
+			zap.Bool("versions", extra.Layout.Versions),
+			zap.Bool("parts", extra.Layout.Parts))
+	}
+
+	capture.writeEngineOptions(engineOpts)
+
 	// 5. 创建 Engine 实例
 	engine, err := syncengine.NewEngine(driver, writer, e.log.With(
 		zap.Uint("job_id", job.ID),
@@ -279,41 +418,69 @@ func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.Syn
 	execLog.Info("开始执行同步任务",
 		zap.String("remote_root", remotePath))
 	stats, runErr := engine.RunOnce(ctx, remotePath)
+	if !extra.DryRun {
+		e.saveStrmRecords(ctx, job, layout, moveIndex, runErr == nil && stats.ResumedFiles == 0, execLog)
+	}
 
 	metaStats := metadataStats{}
 	var metaErr error
-	if runErr == nil {
-		metaStats, metaErr = e.syncMetadata(ctx, job, serverForDriver, driver, extra, remotePath, eventSink)
+	if runErr == nil && remoteOutput {
+		// 元数据复制依赖本地目标目录
+		execLog.Info("远程输出目录不同步元数据文件", zap.String("writer_type", writerType))
+	} else if runErr == nil {
+		metaStats, metaErr = e.syncMetadata(ctx, job, outputJob.TargetPath, serverForDriver, driver, extra, remotePath, layout, eventSink)
 	}
 	if metaErr != nil {
 		execLog.Warn("元数据同步失败", zap.Error(metaErr))
 	}
 
 	// 7. 更新 TaskRun 进度
-	if updateErr := e.cfg.TaskRuns.UpdateProgress(ctx, task.ID, progressFromStats(stats, metaStats)); updateErr != nil {
+	progress := progressFromStats(stats, metaStats)
+	progress.APIErrors = clampInt64(capture.apiErrors.Load())
+	if updateErr := e.cfg.TaskRuns.UpdateProgress(ctx, task.ID, progress); updateErr != nil {
 		e.log.Warn("update task progress failed",
 			zap.Uint("task_id", task.ID),
 			zap.Error(updateErr))
 	}
+	capture.writeStats(stats, metaStats, runErr, metaErr)
 
 	if runErr != nil {
 		return stats, wrapTaskError(runErr)
 	}
+
+	// STRM 同步已完整结束，清除断点，避免下次重试跳过文件
+	if updateErr := e.cfg.TaskRuns.UpdateCheckpoint(ctx, task.ID, ""); updateErr != nil {
+		e.log.Warn("clear task checkpoint failed",
+			zap.Uint("task_id", task.ID),
+			zap.Error(updateErr))
+	}
+
 	if metaErr != nil {
 		return stats, wrapTaskError(metaErr)
 	}
 
+	if staging != nil {
+		if err := staging.Commit(); err != nil {
+			return stats, wrapTaskError(fmt.Errorf("commit staging: %w", err))
+		}
+		execLog.Info("暂存目录已切换为目标目录",
+			zap.String("target_path", staging.Target()),
+			zap.String("swap_mode", extra.StagingMode))
+	}
+
 	execLog.Info("同步任务执行完成",
 		zap.Int64("total_files", stats.TotalFiles),
 		zap.Int64("processed_files", stats.ProcessedFiles),
 		zap.Int64("created_files", stats.CreatedFiles),
 		zap.Int64("updated_files", stats.UpdatedFiles),
+		zap.Int64("moved_files", stats.MovedFiles),
 		zap.Int64("skipped_files", stats.SkippedFiles),
 		zap.Int64("filtered_files", stats.FilteredFiles),
 		zap.Int64("failed_files", stats.FailedFiles),
 		zap.Int64("meta_total_files", metaStats.Total),
 		zap.Int64("meta_processed_files", metaStats.Processed),
 		zap.Int64("meta_failed_files", metaStats.Failed),
+		zap.Int64("meta_bytes", metaStats.Bytes),
 		zap.Duration("duration", stats.Duration))
 
 	return stats, nil
@@ -331,6 +498,8 @@ func (e *Executor) Run(ctx context.Context, task *model.TaskRun) (syncengine.Syn
 // - ModTimeEpsilonSeconds: ModTime 容差（秒）
 // - EnableOrphanCleanup: 启用孤儿文件清理
 // - OrphanCleanupDryRun: 孤儿清理干运行模式
+// - KeepSidecars: 删除 STRM 时保留附属文件（默认随 STRM 一起删除）
+// - DisableMoveDetection: 关闭远端移动/重命名检测（默认开启）
 // - StrmReplaceRules: STRM 替换规则
 func buildEngineOptions(job model.Job, extra jobOptions) (syncengine.EngineOptions, error) {
 	if strings.TrimSpace(job.TargetPath) == "" {
@@ -356,6 +525,8 @@ func buildEngineOptions(job model.Job, extra jobOptions) (syncengine.EngineOptio
 	opts.SkipExisting = extra.SkipExisting
 	opts.EnableOrphanCleanup = extra.EnableOrphanCleanup
 	opts.OrphanCleanupDryRun = extra.OrphanCleanupDryRun
+	opts.CleanupSidecars = !extra.KeepSidecars
+	opts.DetectMoves = !extra.DisableMoveDetection
 	if extra.ModTimeEpsilonSeconds > 0 {
 		opts.ModTimeEpsilon = time.Duration(extra.ModTimeEpsilonSeconds) * time.Second
 	}
@@ -628,10 +799,19 @@ type jobOptions struct {
 	ModTimeEpsilonSeconds int               `json:"mod_time_epsilon_seconds"`
 	EnableOrphanCleanup   bool              `json:"enable_orphan_cleanup"`
 	OrphanCleanupDryRun   bool              `json:"orphan_cleanup_dry_run"`
+	KeepSidecars          bool              `json:"keep_sidecars"`
+	DisableMoveDetection  bool              `json:"disable_move_detection"`
 	MetadataMode          string            `json:"metadata_mode"`
+	MetaHashMode          string            `json:"meta_hash_mode"`
+	MetaFullHashMaxMB     int64             `json:"meta_full_hash_max_mb"`
 	SyncOpts              syncOpts          `json:"sync_opts"`
 	STRMMode              string            `json:"strm_mode"`
 	StrmReplaceRules      []strmReplaceRule `json:"strm_replace_rules"`
+	StagingMode           string            `json:"staging_mode"`
+	Writer                writerOptions     `json:"writer"`
+	Artwork               artworkOptions    `json:"artwork"`
+	GenerateNFO           bool              `json:"generate_nfo"`
+	Layout                layoutOptions     `json:"layout"`
 }
 
 type syncOpts struct {
@@ -652,6 +832,7 @@ type metadataStats struct {
 	Updated   int64
 	Processed int64
 	Failed    int64
+	Bytes     int64 // 复制/下载传输的字节数
 }
 
 type metaStrategy int
@@ -869,7 +1050,8 @@ func buildLocalMetadataClient(job model.Job, log *zap.Logger) (filesystem.Client
 	return filesystem.NewClient(cfg, filesystem.WithLogger(log))
 }
 
-func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model.DataServer, driver syncengine.Driver, extra jobOptions, remotePath string, eventSink *taskRunEventSink) (metadataStats, error) {
+// syncMetadata 同步元数据文件到 targetRoot（暂存同步时为暂存目录，否则为任务目标目录）
+func (e *Executor) syncMetadata(ctx context.Context, job model.Job, targetRoot string, server model.DataServer, driver syncengine.Driver, extra jobOptions, remotePath string, layout *syncengine.MediaLayout, eventSink *taskRunEventSink) (metadataStats, error) {
 	metaExts := normalizeExtensions(extra.MetaExts, appconfig.DefaultMetaExtensions())
 	if len(metaExts) == 0 {
 		return metadataStats{}, nil
@@ -949,14 +1131,35 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 		return metadataStats{}, fmt.Errorf("build metadata client: %w", err)
 	}
 
+	artwork, err := resolveArtwork(extra)
+	if err != nil {
+		return metadataStats{}, err
+	}
+
 	preferMount := mode != "download"
+	hashes, err := e.newMetaHashChecker(ctx, job, extra, artwork, client, preferMount, metaLogger)
+	if err != nil {
+		return metadataStats{}, err
+	}
+	if hashes != nil {
+		hashes.recordRoot(targetRoot, job.TargetPath)
+	}
+
 	options := []appsync.MetadataReplicatorOption{
 		appsync.WithPreferMount(preferMount),
+		appsync.WithFsync(e.cfg.Fsync),
+		appsync.WithArtwork(artwork),
 	}
+	var metaSink appsync.MetaEventSink
 	if eventSink != nil {
-		options = append(options, appsync.WithEventSink(eventSink))
+		metaSink = eventSink
+	}
+	if hashes != nil {
+		metaSink = &metaHashEventSink{checker: hashes, next: metaSink}
 	}
-	replicator := appsync.NewMetadataReplicator(client, job.TargetPath, metaLogger, options...)
+	transferred := &metaBytesSink{next: metaSink}
+	options = append(options, appsync.WithEventSink(transferred))
+	replicator := appsync.NewMetadataReplicator(client, targetRoot, metaLogger, options...)
 
 	strategy := resolveMetaStrategy(extra.SyncOpts)
 	metaLogger.Info("开始同步元数据",
@@ -965,6 +1168,15 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 		zap.String("mode", mode),
 		zap.String("strategy", strategy.String()))
 
+	// 按视频归属附属文件（字幕、NFO、海报等），事件按媒体条目汇总
+	mediaExts := normalizeExtensions(extra.MediaExts, nil)
+	if len(mediaExts) == 0 {
+		mediaExts = appconfig.DefaultMediaExtensions()
+	}
+	mediaIndex := syncengine.NewMediaIndex(entries, mediaExts)
+	videos := filterVideos(entries, remotePath, excludeDirs, mediaExts, normalizeMinFileSize(extra.MinFileSize))
+	targets := newMetaTargetResolver(targetRoot, layout, mediaIndex, videos)
+
 	items := make(chan appports.SyncPlanItem, 100)
 	stats := metadataStats{}
 	preFailed := int64(0)
@@ -989,7 +1201,7 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 
 			stats.Total++
 
-			targetPath, err := buildTargetMetaPath(job.TargetPath, entry.Path)
+			targetPath, err := targets.targetPath(entry.Path)
 			if err != nil {
 				preFailed++
 				continue
@@ -1003,6 +1215,21 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 
 			exists := statErr == nil
 			same := metaFileSame(info, entry, 2*time.Second)
+			mediaItem := mediaIndex.MediaItemOf(entry.Path)
+
+			if artwork.Match(entry.Path) && artwork.TooLarge(entry.Size) {
+				if eventSink != nil {
+					eventSink.OnMetaEvent(ctx, appsync.MetaEvent{
+						Op:           "skip",
+						Status:       "skipped",
+						SourcePath:   entry.Path,
+						TargetPath:   targetPath,
+						ErrorMessage: "artwork_too_large",
+						MediaItem:    mediaItem,
+					})
+				}
+				continue
+			}
 
 			switch strategy {
 			case metaStrategySkip:
@@ -1014,12 +1241,17 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 							SourcePath:   entry.Path,
 							TargetPath:   targetPath,
 							ErrorMessage: "skip_existing",
+							MediaItem:    mediaItem,
 						})
 					}
 					continue
 				}
 			case metaStrategyUpdate:
-				if exists && same {
+				unchanged := exists && same
+				if exists && hashes != nil {
+					unchanged = hashes.evaluate(ctx, entry, targetPath, info, same)
+				}
+				if unchanged {
 					if eventSink != nil {
 						eventSink.OnMetaEvent(ctx, appsync.MetaEvent{
 							Op:           "skip",
@@ -1027,6 +1259,7 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 							SourcePath:   entry.Path,
 							TargetPath:   targetPath,
 							ErrorMessage: "unchanged",
+							MediaItem:    mediaItem,
 						})
 					}
 					continue
@@ -1035,6 +1268,10 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 				// 总是覆盖
 			}
 
+			if hashes != nil {
+				hashes.track(ctx, entry, targetPath)
+			}
+
 			op := appports.SyncOpCreate
 			if exists {
 				op = appports.SyncOpUpdate
@@ -1052,6 +1289,7 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 				TargetMetaPath: targetPath,
 				Size:           entry.Size,
 				ModTime:        entry.ModTime,
+				MediaItem:      mediaItem,
 			}
 			planned++
 		}
@@ -1060,20 +1298,63 @@ func (e *Executor) syncMetadata(ctx context.Context, job model.Job, server model
 	_, failed, err := replicator.Apply(ctx, items)
 	stats.Processed = planned + preFailed
 	stats.Failed = preFailed + int64(failed)
+	stats.Bytes = transferred.bytes.Load()
 	if err != nil {
 		return stats, err
 	}
 
+	// 为源端没有 NFO 的视频生成最小化 NFO
+	if extra.GenerateNFO {
+		nfoItems := planNFOItems(entries, videos, remotePath, excludeDirs, mediaIndex, targets)
+		var nfoSink appsync.MetaEventSink
+		if eventSink != nil {
+			nfoSink = eventSink
+		}
+		generator := appsync.NewNFOGenerator(metaLogger,
+			appsync.WithNFOEventSink(nfoSink),
+			appsync.WithNFOFsync(e.cfg.Fsync),
+			appsync.WithNFODryRun(extra.DryRun))
+		created, updated, nfoFailed := generator.Generate(ctx, nfoItems)
+		stats.Total += int64(len(nfoItems))
+		stats.Created += int64(created)
+		stats.Updated += int64(updated)
+		stats.Processed += int64(created + updated + nfoFailed)
+		stats.Failed += int64(nfoFailed)
+		metaLogger.Info("NFO 生成完成",
+			zap.Int("candidates", len(nfoItems)),
+			zap.Int("created", created),
+			zap.Int("updated", updated),
+			zap.Int("failed", nfoFailed))
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+	}
+
 	metaLogger.Info("元数据同步完成",
 		zap.Int64("total", stats.Total),
 		zap.Int64("created", stats.Created),
 		zap.Int64("updated", stats.Updated),
 		zap.Int64("processed", stats.Processed),
-		zap.Int64("failed", stats.Failed))
+		zap.Int64("failed", stats.Failed),
+		zap.Int64("bytes", stats.Bytes))
 
 	return stats, nil
 }
 
+// metaBytesSink 统计元数据复制/下载传输的字节数，并转发事件
+type metaBytesSink struct {
+	bytes atomic.Int64
+	next  appsync.MetaEventSink
+}
+
+// OnMetaEvent 实现 appsync.MetaEventSink
+func (s *metaBytesSink) OnMetaEvent(ctx context.Context, event appsync.MetaEvent) {
+	s.bytes.Add(event.Bytes)
+	if s.next != nil {
+		s.next.OnMetaEvent(ctx, event)
+	}
+}
+
 // progressFromStats 生成 TaskRunProgress
 //
 // 计算进度百分比：
@@ -1104,11 +1385,13 @@ func progressFromStats(stats syncengine.SyncStats, meta metadataStats) TaskRunPr
 		UpdatedFiles:       clampInt64(stats.UpdatedFiles),
 		SkippedFiles:       clampInt64(stats.SkippedFiles),
 		FilteredFiles:      clampInt64(stats.FilteredFiles),
+		MovedFiles:         clampInt64(stats.MovedFiles),
 		MetaTotalFiles:     clampInt64(meta.Total),
 		MetaCreatedFiles:   clampInt64(meta.Created),
 		MetaUpdatedFiles:   clampInt64(meta.Updated),
 		MetaProcessedFiles: clampInt64(meta.Processed),
 		MetaFailedFiles:    clampInt64(meta.Failed),
+		MetaBytes:          meta.Bytes,
 		Progress:           progress,
 	}
 }
@@ -1207,6 +1490,12 @@ func (f DefaultDriverFactory) Build(ctx context.Context, server model.DataServer
 	return adapter, nil
 }
 
+// WithLogger 返回使用指定日志器的工厂副本
+func (f DefaultDriverFactory) WithLogger(log *zap.Logger) DriverFactory {
+	f.Logger = log
+	return f
+}
+
 func (f DefaultDriverFactory) logger() *zap.Logger {
 	if f.Logger != nil {
 		return f.Logger
@@ -1214,14 +1503,17 @@ func (f DefaultDriverFactory) logger() *zap.Logger {
 	return logger.With(zap.String("component", "worker-driver-factory"))
 }
 
-// DefaultWriterFactory 构建本地 STRM 写入器
+// DefaultWriterFactory 构建 STRM 写入器
 type DefaultWriterFactory struct {
-	Logger *zap.Logger
+	Logger      *zap.Logger
+	Fsync       bool                 // 写入后是否 fsync
+	DataServers DataServerRepository // 上传到 OpenList 时加载数据服务器（可选）
 }
 
 // Build 创建 Writer 实例
 //
-// 使用 strmwriter.NewLocalWriter 创建本地文件系统写入器。
+// 默认使用 strmwriter.NewLocalWriter 创建本地文件系统写入器；
+// Job 选项 writer.type 为 webdav/openlist 时创建对应的远程写入器。
 func (f DefaultWriterFactory) Build(ctx context.Context, job model.Job) (syncengine.Writer, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -1230,7 +1522,21 @@ func (f DefaultWriterFactory) Build(ctx context.Context, job model.Job) (synceng
 		return nil, fmt.Errorf("writer factory: job %d target_path is empty", job.ID)
 	}
 
-	writer, err := strmwriter.NewLocalWriter(job.TargetPath, strmwriter.WithEnforceRoot(true))
+	extra, err := parseJobOptions(job.Options)
+	if err != nil {
+		return nil, fmt.Errorf("writer factory: parse job options: %w", err)
+	}
+	writerType, err := resolveWriterType(extra)
+	if err != nil {
+		return nil, fmt.Errorf("writer factory: %w", err)
+	}
+	if writerType != writerTypeLocal {
+		return f.buildRemoteWriter(ctx, job, writerType, extra.Writer)
+	}
+
+	writer, err := strmwriter.NewLocalWriter(job.TargetPath,
+		strmwriter.WithEnforceRoot(true),
+		strmwriter.WithFsync(f.Fsync))
 	if err != nil {
 		return nil, fmt.Errorf("writer factory: new local writer: %w", err)
 	}
@@ -1242,8 +1548,11 @@ func (f DefaultWriterFactory) Build(ctx context.Context, job model.Job) (synceng
 // 从 DataServer.Options (JSON) 解析可选配置：
 // - BaseURL: 服务器基础 URL
 // - STRMMode: STRM 模式（http/mount）
+// - LinkMode: HTTP 模式的链接类型（proxy/direct，仅 CloudDrive2）
+// - PickCodeURL: 115 文件的 PickCode 链接模板
 // - MountPath: 挂载路径
 // - TimeoutSeconds: 请求超时（秒）
+// - ListConcurrency: 目录并发列出数（同一服务器共享）
 // - Username: 用户名
 // - Password: 密码
 func buildFilesystemConfig(server model.DataServer) (filesystem.Config, error) {
@@ -1282,6 +1591,12 @@ func buildFilesystemConfig(server model.DataServer) (filesystem.Config, error) {
 		strmMode = filesystem.STRMModeMount
 	}
 
+	// 解析 LinkMode（为空时由 filesystem 使用默认值）
+	linkMode := filesystem.LinkMode(strings.TrimSpace(opts.LinkMode))
+	if linkMode != "" && !linkMode.IsValid() {
+		return filesystem.Config{}, fmt.Errorf("invalid link_mode: %s (valid: proxy, direct)", opts.LinkMode)
+	}
+
 	// 解析 Timeout
 	timeout := time.Duration(opts.TimeoutSeconds) * time.Second
 	if timeout <= 0 {
@@ -1314,21 +1629,34 @@ func buildFilesystemConfig(server model.DataServer) (filesystem.Config, error) {
 		Username:      opts.Username,
 		Password:      password,
 		STRMMode:      strmMode,
+		LinkMode:      linkMode,
+		PickCodeURL:   strings.TrimSpace(opts.PickCodeURL),
 		MountPath:     scanRoot,
 		StrmMountPath: strmMount,
 		Timeout:       timeout,
+		// 目录并发列出数，<=0 时由 filesystem 使用默认值
+		ListConcurrency: opts.ListConcurrency,
+		// 下载并发与限速（同一服务器共享）
+		DownloadConcurrency: opts.DownloadConcurrency,
+		DownloadRatePerSec:  server.DownloadRatePerSec,
+		DownloadBytesPerSec: opts.DownloadBytesPerSec,
 	}, nil
 }
 
 // dataServerOptions 表示 DataServer.Options 的可选字段
 type dataServerOptions struct {
-	BaseURL        string `json:"base_url"`
-	AccessPath     string `json:"access_path"`
-	STRMMode       string `json:"strm_mode"`
-	MountPath      string `json:"mount_path"`
-	TimeoutSeconds int    `json:"timeout_seconds"`
-	Username       string `json:"username"`
-	Password       string `json:"password"`
+	BaseURL             string `json:"base_url"`
+	AccessPath          string `json:"access_path"`
+	STRMMode            string `json:"strm_mode"`
+	LinkMode            string `json:"link_mode"`
+	PickCodeURL         string `json:"pickcode_url"`
+	MountPath           string `json:"mount_path"`
+	TimeoutSeconds      int    `json:"timeout_seconds"`
+	ListConcurrency     int    `json:"list_concurrency"`
+	DownloadConcurrency int    `json:"download_concurrency"`   // 并发下载数（同一服务器共享，默认4）
+	DownloadBytesPerSec int64  `json:"download_bytes_per_sec"` // 下载带宽上限（字节/秒，0=不限制）
+	Username            string `json:"username"`
+	Password            string `json:"password"`
 }
 
 // GormTaskRunRepository 是基于 GORM 的 TaskRunRepository 实现
@@ -1357,11 +1685,14 @@ func (r *GormTaskRunRepository) UpdateProgress(ctx context.Context, taskID uint,
 		"updated_files":        progress.UpdatedFiles,
 		"skipped_files":        progress.SkippedFiles,
 		"filtered_files":       progress.FilteredFiles,
+		"moved_files":          progress.MovedFiles,
 		"meta_total_files":     progress.MetaTotalFiles,
 		"meta_created_files":   progress.MetaCreatedFiles,
 		"meta_updated_files":   progress.MetaUpdatedFiles,
 		"meta_processed_files": progress.MetaProcessedFiles,
 		"meta_failed_files":    progress.MetaFailedFiles,
+		"meta_bytes":           progress.MetaBytes,
+		"api_errors":           progress.APIErrors,
 		"progress":             progress.Progress,
 	}
 	return r.db.WithContext(ctx).Model(&model.TaskRun{}).
@@ -1369,6 +1700,16 @@ func (r *GormTaskRunRepository) UpdateProgress(ctx context.Context, taskID uint,
 		Updates(updates).Error
 }
 
+// UpdateCheckpoint 更新 TaskRun 同步断点
+func (r *GormTaskRunRepository) UpdateCheckpoint(ctx context.Context, taskID uint, checkpoint string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.db.WithContext(ctx).Model(&model.TaskRun{}).
+		Where("id = ?", taskID).
+		Update("checkpoint", checkpoint).Error
+}
+
 // GormTaskRunEventRepository 是基于 GORM 的 TaskRunEventRepository 实现
 type GormTaskRunEventRepository struct {
 	db *gorm.DB
@@ -1396,6 +1737,157 @@ func (r *GormTaskRunEventRepository) Create(ctx context.Context, event *model.Ta
 	return r.db.WithContext(ctx).Create(event).Error
 }
 
+// GormMetaHashRepository 是基于 GORM 的 MetaHashRepository 实现
+type GormMetaHashRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMetaHashRepository 创建 GormMetaHashRepository
+func NewGormMetaHashRepository(db *gorm.DB) (*GormMetaHashRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("worker: gorm db is nil")
+	}
+	return &GormMetaHashRepository{db: db}, nil
+}
+
+// ListByJob 获取指定 Job 的全部哈希记录
+func (r *GormMetaHashRepository) ListByJob(ctx context.Context, jobID uint) (map[string]model.MetaFileHash, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var records []model.MetaFileHash
+	if err := r.db.WithContext(ctx).Where("job_id = ?", jobID).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]model.MetaFileHash, len(records))
+	for _, record := range records {
+		result[record.TargetPath] = record
+	}
+	return result, nil
+}
+
+// Upsert 写入或更新单条哈希记录
+func (r *GormMetaHashRepository) Upsert(ctx context.Context, record *model.MetaFileHash) error {
+	if record == nil {
+		return fmt.Errorf("worker: meta hash record is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}, {Name: "target_path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"source_hash", "size", "mod_time", "updated_at"}),
+	}).Create(record).Error
+}
+
+// GormStrmMappingRepository 是基于 GORM 的 StrmMappingRepository 实现
+type GormStrmMappingRepository struct {
+	db *gorm.DB
+}
+
+// NewGormStrmMappingRepository 创建 GormStrmMappingRepository
+func NewGormStrmMappingRepository(db *gorm.DB) (*GormStrmMappingRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("worker: gorm db is nil")
+	}
+	return &GormStrmMappingRepository{db: db}, nil
+}
+
+// ListByJob 获取指定 Job 的全部记录
+func (r *GormStrmMappingRepository) ListByJob(ctx context.Context, jobID uint) (map[string]syncengine.MoveRecord, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var rows []model.StrmMapping
+	if err := r.db.WithContext(ctx).Where("job_id = ?", jobID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]syncengine.MoveRecord, len(rows))
+	for _, row := range rows {
+		result[row.SourcePath] = syncengine.MoveRecord{
+			Output:  row.OutputPath,
+			Size:    row.Size,
+			ModTime: row.ModTime,
+			FileID:  row.FileID,
+		}
+	}
+	return result, nil
+}
+
+// ReplaceByJob 在事务中删除旧记录并写入新记录
+func (r *GormStrmMappingRepository) ReplaceByJob(ctx context.Context, jobID uint, records map[string]syncengine.MoveRecord) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rows := make([]model.StrmMapping, 0, len(records))
+	for source, record := range records {
+		rows = append(rows, model.StrmMapping{
+			JobID:      jobID,
+			SourcePath: source,
+			OutputPath: record.Output,
+			Size:       record.Size,
+			ModTime:    record.ModTime,
+			FileID:     record.FileID,
+		})
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", jobID).Delete(&model.StrmMapping{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+}
+
+// taskRunCheckpointSink 将引擎断点持久化到 TaskRun
+type taskRunCheckpointSink struct {
+	repo   TaskRunRepository
+	task   uint
+	logger *zap.Logger
+}
+
+func newTaskRunCheckpointSink(repo TaskRunRepository, taskID uint, logger *zap.Logger) *taskRunCheckpointSink {
+	if repo == nil {
+		return nil
+	}
+	return &taskRunCheckpointSink{repo: repo, task: taskID, logger: logger}
+}
+
+// SaveCheckpoint 保存断点（失败只记录日志，不影响同步）
+func (s *taskRunCheckpointSink) SaveCheckpoint(ctx context.Context, checkpoint syncengine.SyncCheckpoint) {
+	if s == nil || s.repo == nil {
+		return
+	}
+	raw, err := json.Marshal(checkpoint)
+	if err != nil {
+		s.logger.Warn("序列化同步断点失败", zap.Error(err))
+		return
+	}
+	if err := s.repo.UpdateCheckpoint(ctx, s.task, string(raw)); err != nil {
+		s.logger.Warn("保存同步断点失败",
+			zap.Uint("task_id", s.task),
+			zap.Error(err))
+	}
+}
+
+// parseCheckpoint 解析 TaskRun 上保存的断点 JSON
+func parseCheckpoint(raw string) (syncengine.SyncCheckpoint, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return syncengine.SyncCheckpoint{}, false
+	}
+	var checkpoint syncengine.SyncCheckpoint
+	if err := json.Unmarshal([]byte(raw), &checkpoint); err != nil {
+		return syncengine.SyncCheckpoint{}, false
+	}
+	if checkpoint.IsZero() {
+		return syncengine.SyncCheckpoint{}, false
+	}
+	return checkpoint, true
+}
+
 type taskRunEventSink struct {
 	repo    TaskRunEventRepository
 	now     func() time.Time
@@ -1451,6 +1943,12 @@ func formatEventAction(kind string, op string) string {
 		if op == "skip" {
 			return "跳过元数据"
 		}
+		if op == "move" {
+			return "移动元数据"
+		}
+		if op == "generate" {
+			return "生成NFO"
+		}
 	default:
 		if op == "create" {
 			return "生成STRM"
@@ -1464,6 +1962,9 @@ func formatEventAction(kind string, op string) string {
 		if op == "skip" {
 			return "跳过STRM"
 		}
+		if op == "move" {
+			return "移动STRM"
+		}
 	}
 	if op == "" {
 		return "操作"
@@ -1493,6 +1994,8 @@ func formatEventMessage(jobName string, action string, source string, target str
 			reason = "已存在,跳过"
 		case "unchanged":
 			reason = "内容未变化,跳过"
+		case "artwork_too_large":
+			reason = "图片超过大小上限,跳过"
 		case "dry_run":
 			reason = "dry_run,跳过"
 		default:
@@ -1531,19 +2034,31 @@ func (s *taskRunEventSink) OnStrmEvent(ctx context.Context, event syncengine.Str
 		status = "success"
 	}
 	errMsg := strings.TrimSpace(event.ErrorMessage)
+	// 随 STRM 删除/移动的附属文件按元数据事件记录
+	kind := "strm"
+	if event.Sidecar {
+		kind = "meta"
+	}
 	record := &model.TaskRunEvent{
 		TaskRunID:    s.task,
 		JobID:        s.job,
-		Kind:         "strm",
+		Kind:         kind,
 		Op:           op,
 		Status:       status,
 		SourcePath:   strings.TrimSpace(event.SourcePath),
 		TargetPath:   strings.TrimSpace(event.TargetPath),
 		ErrorMessage: errMsg,
+		MediaItem:    strings.TrimSpace(event.MediaItem),
+		PreviousPath: strings.TrimSpace(event.PreviousPath),
 		CreatedAt:    s.now(),
 	}
 	_ = s.repo.Create(ctx, record)
-	s.logEvent("strm", op, status, record.SourcePath, record.TargetPath, errMsg)
+	// 移动事件在日志中展示为"原路径 -> 新路径"
+	source := record.SourcePath
+	if op == "move" && record.PreviousPath != "" {
+		source = record.PreviousPath
+	}
+	s.logEvent(kind, op, status, source, record.TargetPath, errMsg)
 }
 
 func (s *taskRunEventSink) OnMetaEvent(ctx context.Context, event appsync.MetaEvent) {
@@ -1568,6 +2083,8 @@ func (s *taskRunEventSink) OnMetaEvent(ctx context.Context, event appsync.MetaEv
 		SourcePath:   strings.TrimSpace(event.SourcePath),
 		TargetPath:   strings.TrimSpace(event.TargetPath),
 		ErrorMessage: errMsg,
+		MediaItem:    strings.TrimSpace(event.MediaItem),
+		Bytes:        event.Bytes,
 		CreatedAt:    s.now(),
 	}
 	_ = s.repo.Create(ctx, record)
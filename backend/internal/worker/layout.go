@@ -0,0 +1,347 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	appconfig "github.com/strmsync/strmsync/internal/config"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/engine"
+	"github.com/strmsync/strmsync/internal/pkg/medianame"
+	"go.uber.org/zap"
+)
+
+// 输出布局模式
+const (
+	layoutModeMirror = "mirror" // 镜像远端目录结构（默认）
+	layoutModeMedia  = "media"  // 按标题/年份/季集整理为电影、剧集目录
+)
+
+// layoutOptions 输出布局配置（Job 选项 layout）
+type layoutOptions struct {
+	Mode      string `json:"mode"`       // mirror（默认）/ media
+	MoviesDir string `json:"movies_dir"` // 电影目录（相对目标目录，默认 Movies）
+	ShowsDir  string `json:"shows_dir"`  // 剧集目录（相对目标目录，默认 Shows）
+	Versions  bool   `json:"versions"`   // 多版本命名（Title (Year) - 2160p）
+	Parts     bool   `json:"parts"`      // 分段命名（Title (Year)-part1）
+}
+
+// mediaLayoutOptions 转换为引擎的媒体布局选项
+func (o layoutOptions) mediaLayoutOptions() syncengine.MediaLayoutOptions {
+	return syncengine.MediaLayoutOptions{
+		Organize:  strings.EqualFold(strings.TrimSpace(o.Mode), layoutModeMedia),
+		MoviesDir: o.MoviesDir,
+		ShowsDir:  o.ShowsDir,
+		Versions:  o.Versions,
+		Parts:     o.Parts,
+	}
+}
+
+// resolveLayout 校验输出布局配置，返回是否启用媒体布局
+//
+// mode=media，或镜像布局下开启 versions/parts 时启用（后者保持远端目录，只调整多版本与分段文件名）。
+func resolveLayout(extra jobOptions) (bool, error) {
+	opts := extra.Layout
+	switch strings.ToLower(strings.TrimSpace(opts.Mode)) {
+	case "", layoutModeMirror:
+		return opts.Versions || opts.Parts, nil
+	case layoutModeMedia:
+	default:
+		return false, fmt.Errorf("unsupported layout mode %q", opts.Mode)
+	}
+
+	movies := layoutDir(opts.MoviesDir, syncengine.DefaultMoviesDir)
+	shows := layoutDir(opts.ShowsDir, syncengine.DefaultShowsDir)
+	for _, dir := range []string{opts.MoviesDir, opts.ShowsDir} {
+		for _, part := range strings.Split(filepath.ToSlash(dir), "/") {
+			if part == ".." {
+				return false, fmt.Errorf("invalid layout dir %q: must stay within target path", dir)
+			}
+		}
+	}
+	if strings.EqualFold(movies, shows) {
+		return false, fmt.Errorf("invalid layout options: movies_dir and shows_dir must differ")
+	}
+	return true, nil
+}
+
+// layoutDir 规范化布局目录（与 MediaLayout 一致），用于校验
+func layoutDir(dir, fallback string) string {
+	dir = strings.Trim(path.Clean("/"+filepath.ToSlash(strings.TrimSpace(dir))), "/")
+	if dir == "" {
+		return fallback
+	}
+	return dir
+}
+
+// loadStrmRecords 读取上次保存的远端文件与 STRM 对应记录；未启用媒体布局与移动检测时返回 nil
+func (e *Executor) loadStrmRecords(ctx context.Context, job model.Job, extra jobOptions, detectMoves bool) (map[string]syncengine.MoveRecord, error) {
+	enabled, err := resolveLayout(extra)
+	if err != nil {
+		return nil, err
+	}
+	if (!enabled && !detectMoves) || e.cfg.Mappings == nil {
+		return nil, nil
+	}
+	records, err := e.cfg.Mappings.ListByJob(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load strm mappings: %w", err)
+	}
+	return records, nil
+}
+
+// loadLayout 创建媒体布局并加载记录中的输出路径；未启用媒体布局时返回 nil
+func loadLayout(extra jobOptions, records map[string]syncengine.MoveRecord) *syncengine.MediaLayout {
+	if enabled, err := resolveLayout(extra); err != nil || !enabled {
+		return nil
+	}
+	mappings := make(map[string]string, len(records))
+	for source, record := range records {
+		mappings[source] = record.Output
+	}
+	return syncengine.NewMediaLayout(extra.Layout.mediaLayoutOptions(), mappings)
+}
+
+// saveStrmRecords 保存远端文件与 STRM 的对应记录（媒体布局映射与移动检测签名）
+//
+// 签名取自移动检测（完整扫描后已清除不存在的文件），输出路径以媒体布局为准。
+// complete 为 true（完整扫描且没有从断点跳过文件）时只保存本次出现的布局映射，
+// 清理已删除文件的映射；否则保留未出现的映射，避免部分扫描丢失对应关系。
+func (e *Executor) saveStrmRecords(ctx context.Context, job model.Job, layout *syncengine.MediaLayout, moves *syncengine.MoveIndex, complete bool, log *zap.Logger) {
+	if (layout == nil && moves == nil) || e.cfg.Mappings == nil {
+		return
+	}
+	records := make(map[string]syncengine.MoveRecord)
+	if moves != nil {
+		records = moves.Records()
+	}
+	if layout != nil {
+		for source, output := range layout.Mappings(complete) {
+			record := records[source]
+			record.Output = output
+			records[source] = record
+		}
+	}
+	if err := e.cfg.Mappings.ReplaceByJob(context.WithoutCancel(ctx), job.ID, records); err != nil {
+		log.Warn("保存 STRM 映射失败", zap.Error(err))
+		return
+	}
+	log.Info("保存 STRM 映射",
+		zap.Int("mappings", len(records)),
+		zap.Bool("layout", layout != nil),
+		zap.Bool("signatures", moves != nil),
+		zap.Bool("complete", complete))
+}
+
+// metaTargetResolver 计算元数据文件的目标路径
+//
+// 未启用媒体布局时镜像远端路径；启用时跟随所属视频的 STRM：
+//   - 同名附属文件（movie.zh.srt、movie-poster.jpg）：视频输出路径加上原文件名中视频名之后的部分
+//   - 归属单个视频的目录级附属文件（folder.jpg、extrafanart/）：视频输出目录
+//   - 其他目录级文件（剧集目录的 poster.jpg、tvshow.nfo、分段电影目录的海报等）：目录内视频共同对应的输出目录
+//   - 无法确定归属的文件：镜像路径
+type metaTargetResolver struct {
+	targetRoot string
+	layout     *syncengine.MediaLayout
+	index      *syncengine.MediaIndex
+	videos     map[string]string // 媒体条目 -> 视频远端路径
+	dirs       map[string]string // 远端目录 -> 输出相对目录（空字符串表示目录内视频去向不一致）
+}
+
+// newMetaTargetResolver 创建元数据目标路径解析器
+//
+// videos 为生成 STRM 的视频文件（已按排除目录、扩展名与大小过滤）。
+func newMetaTargetResolver(targetRoot string, layout *syncengine.MediaLayout, index *syncengine.MediaIndex, videos []syncengine.RemoteEntry) *metaTargetResolver {
+	r := &metaTargetResolver{targetRoot: targetRoot, layout: layout, index: index}
+	if layout == nil {
+		return r
+	}
+	r.videos = make(map[string]string, len(videos))
+	r.dirs = make(map[string]string)
+	setDir := func(dir, outDir string) {
+		if current, ok := r.dirs[dir]; ok && current != outDir {
+			r.dirs[dir] = ""
+			return
+		}
+		r.dirs[dir] = outDir
+	}
+	for _, video := range videos {
+		r.videos[syncengine.MediaItemKey(video.Path)] = video.Path
+		output := layout.OutputPath(video.Path)
+		dir := path.Dir(video.Path)
+		if !layout.IsEpisodeOutput(output) {
+			setDir(dir, path.Dir(output))
+			if _, ok := medianame.ParsePartDir(path.Base(dir)); ok {
+				// 分段目录（CD1/）的上级目录即电影目录
+				setDir(path.Dir(dir), path.Dir(output))
+			}
+			continue
+		}
+		season := path.Dir(output)
+		if medianame.IsSeasonDir(path.Base(dir)) {
+			setDir(dir, season)
+			setDir(path.Dir(dir), path.Dir(season))
+		} else {
+			setDir(dir, path.Dir(season))
+		}
+	}
+	return r
+}
+
+// targetPath 返回远端元数据文件的目标路径
+func (r *metaTargetResolver) targetPath(remotePath string) (string, error) {
+	if r.layout == nil {
+		return buildTargetMetaPath(r.targetRoot, remotePath)
+	}
+
+	name := path.Base(remotePath)
+	folder := path.Dir(remotePath)
+	rel := name
+	if syncengine.IsFolderSidecarDir(path.Base(folder)) {
+		rel = path.Base(folder) + "/" + name
+		folder = path.Dir(folder)
+	}
+
+	if video, ok := r.videos[r.index.MediaItemOf(remotePath)]; ok {
+		output := r.layout.OutputPath(video)
+		videoName := path.Base(video)
+		if path.Dir(remotePath) == path.Dir(video) && syncengine.IsSidecarOf(videoName, name) {
+			stem := strings.TrimSuffix(videoName, path.Ext(videoName))
+			return buildTargetMetaPath(r.targetRoot, output+name[len(stem):])
+		}
+		return buildTargetMetaPath(r.targetRoot, path.Join(path.Dir(output), rel))
+	}
+	if outDir := r.dirs[folder]; outDir != "" {
+		return buildTargetMetaPath(r.targetRoot, path.Join(outDir, rel))
+	}
+	return buildTargetMetaPath(r.targetRoot, remotePath)
+}
+
+// LayoutPreviewItem 输出布局预览条目
+type LayoutPreviewItem struct {
+	SourcePath string `json:"source_path"`        // 远端视频路径
+	OutputPath string `json:"output_path"`        // 媒体布局下的 STRM 路径（相对目标目录）
+	Previous   string `json:"previous,omitempty"` // 映射表中的 STRM 路径（没有记录时为空）
+}
+
+// errPreviewLimit 预览已收集到足够的视频，停止遍历
+var errPreviewLimit = errors.New("layout preview limit reached")
+
+// PreviewLayout 列出任务的远端视频并计算媒体布局下的输出路径（不写入任何文件）
+//
+// 使用任务当前的布局配置（未启用时按 mode=media 与默认目录预览）与已保存的映射，
+// 冲突处理与同步时一致。按扫描顺序返回前 limit 条（limit<=0 表示全部），
+// 收集到 limit 条后遇到下一个视频即停止遍历，more 表示之后还有视频。
+// 遍历中止时不确定本次未出现的映射是否已失效，被占用的路径按同名冲突追加原文件名。
+// factory 为 nil 时使用 DefaultDriverFactory。
+func PreviewLayout(ctx context.Context, factory DriverFactory, job model.Job, server model.DataServer, mappings map[string]string, limit int) (items []LayoutPreviewItem, more bool, err error) {
+	extra, err := parseJobOptions(job.Options)
+	if err != nil {
+		return nil, false, fmt.Errorf("parse job options: %w", err)
+	}
+	enabled, err := resolveLayout(extra)
+	if err != nil {
+		return nil, false, err
+	}
+	layoutOpts := extra.Layout.mediaLayoutOptions()
+	if !enabled {
+		layoutOpts.Organize = true
+	}
+
+	serverForDriver, err := applyJobStrmMode(server, extra)
+	if err != nil {
+		return nil, false, fmt.Errorf("apply job strm_mode: %w", err)
+	}
+	driverServer := serverForDriver
+	if shouldUseLocalStrmDriver(serverForDriver, extra) {
+		driverServer, err = buildLocalDriverServer(job, serverForDriver)
+		if err != nil {
+			return nil, false, fmt.Errorf("build local driver server: %w", err)
+		}
+	}
+	if factory == nil {
+		factory = DefaultDriverFactory{}
+	}
+	driver, err := factory.Build(ctx, driverServer)
+	if err != nil {
+		return nil, false, fmt.Errorf("build driver: %w", err)
+	}
+	remotePath, err := resolveEngineRemotePath(job, serverForDriver)
+	if err != nil {
+		return nil, false, fmt.Errorf("resolve remote path: %w", err)
+	}
+
+	mediaExts := normalizeExtensions(extra.MediaExts, nil)
+	if len(mediaExts) == 0 {
+		mediaExts = appconfig.DefaultMediaExtensions()
+	}
+	isVideo := newVideoFilter(remotePath, syncengine.NormalizeExcludeDirs(extra.ExcludeDirs), mediaExts, normalizeMinFileSize(extra.MinFileSize))
+
+	// 与同步一致：按扫描顺序分配，完整列出后再确定等待中的路径
+	layout := syncengine.NewMediaLayout(layoutOpts, mappings)
+	var videos []string
+	err = driver.ListStream(ctx, remotePath, syncengine.ListOptions{Recursive: true, MaxDepth: 100}, func(entry syncengine.RemoteEntry) error {
+		if !isVideo(entry) {
+			return nil
+		}
+		if limit > 0 && len(videos) == limit {
+			more = true
+			return errPreviewLimit
+		}
+		layout.Claim(entry.Path)
+		videos = append(videos, entry.Path)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPreviewLimit) {
+		return nil, false, fmt.Errorf("list remote files: %w", err)
+	}
+	if !more {
+		layout.Settle()
+	}
+
+	items = make([]LayoutPreviewItem, 0, len(videos))
+	for _, video := range videos {
+		item := LayoutPreviewItem{
+			SourcePath: video,
+			OutputPath: layout.OutputPath(video) + ".strm",
+		}
+		if previous, ok := mappings[video]; ok {
+			item.Previous = previous + ".strm"
+		}
+		items = append(items, item)
+	}
+	return items, more, nil
+}
+
+// filterVideos 按排除目录、扩展名与最小大小选出生成 STRM 的视频（与引擎过滤规则一致）
+func filterVideos(entries []syncengine.RemoteEntry, remotePath string, excludeDirs, mediaExts []string, minSize int64) []syncengine.RemoteEntry {
+	isVideo := newVideoFilter(remotePath, excludeDirs, mediaExts, minSize)
+	var videos []syncengine.RemoteEntry
+	for _, entry := range entries {
+		if isVideo(entry) {
+			videos = append(videos, entry)
+		}
+	}
+	return videos
+}
+
+// newVideoFilter 返回判断远端条目是否为生成 STRM 的视频的函数（规则同 filterVideos）
+func newVideoFilter(remotePath string, excludeDirs, mediaExts []string, minSize int64) func(syncengine.RemoteEntry) bool {
+	mediaSet := make(map[string]struct{}, len(mediaExts))
+	for _, ext := range mediaExts {
+		mediaSet[ext] = struct{}{}
+	}
+	return func(entry syncengine.RemoteEntry) bool {
+		if entry.IsDir || syncengine.IsExcludedPath(remotePath, entry.Path, excludeDirs) {
+			return false
+		}
+		if _, ok := mediaSet[strings.ToLower(path.Ext(entry.Name))]; !ok {
+			return false
+		}
+		return minSize <= 0 || entry.Size <= 0 || entry.Size >= minSize
+	}
+}
@@ -0,0 +1,319 @@
+// Package worker 提供 Worker 执行器实现
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	appsync "github.com/strmsync/strmsync/internal/app/sync"
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/engine"
+	"github.com/strmsync/strmsync/internal/infra/filesystem"
+	"github.com/strmsync/strmsync/internal/pkg/hash"
+	"go.uber.org/zap"
+)
+
+// 元数据变更检测模式（Job 选项 meta_hash_mode）
+const (
+	metaHashModeOff  = "mtime" // 默认：比较大小与修改时间
+	metaHashModeFast = "fast"  // 快速哈希（大小 + 首尾各 1MB）
+	metaHashModeFull = "full"  // 小文件完整哈希，超过阈值的文件使用快速哈希
+)
+
+// defaultMetaFullHashMaxMB 完整哈希的默认文件大小上限（MB）
+const defaultMetaFullHashMaxMB = 16
+
+// resolveMetaHashMode 解析元数据变更检测模式
+func resolveMetaHashMode(extra jobOptions) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(extra.MetaHashMode))
+	switch mode {
+	case "", metaHashModeOff:
+		return metaHashModeOff, nil
+	case metaHashModeFast, metaHashModeFull:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported meta_hash_mode %q", extra.MetaHashMode)
+	}
+}
+
+// metaHashChecker 基于内容哈希的元数据变更检测
+//
+// 源文件哈希的来源：
+//   - 复制模式：在源挂载路径上计算本地哈希（pkg/hash）
+//   - 下载模式：使用提供者返回的远端哈希（如 CloudDrive2），不可用时回退到大小/修改时间比较
+//
+// 每个目标文件写入成功后记录其对应的源哈希与写入后的大小/修改时间，
+// 下次同步时源哈希一致且目标文件未被外部修改即视为未变化。
+//
+// 启用图片处理（Job 选项 artwork）时，处理后的图片无法与源文件直接比较：
+// 其源哈希附加处理参数指纹，mtime 模式下以源文件大小/修改时间作为源指纹。
+type metaHashChecker struct {
+	jobID       uint
+	useMount    bool
+	hashAll     bool // false 时仅记录处理后的图片（meta_hash_mode=mtime）
+	fullMaxSize int64
+	client      filesystem.Client
+	repo        MetaHashRepository
+	logger      *zap.Logger
+
+	artwork   *appsync.ArtworkOptions
+	artworkFP string
+
+	records map[string]model.MetaFileHash // 已持久化的记录（仅生产者 goroutine 读取）
+
+	// 暂存同步时文件写入暂存目录，记录仍按任务目标目录下的路径保存
+	writeRoot string
+	keyRoot   string
+
+	mu      sync.Mutex
+	pending map[string]string // 目标路径 -> 待写入的源哈希（写入成功后落库）
+}
+
+// newMetaHashChecker 创建哈希检测器；模式为 mtime 且未启用图片处理时返回 nil
+func (e *Executor) newMetaHashChecker(ctx context.Context, job model.Job, extra jobOptions, artwork *appsync.ArtworkOptions, client filesystem.Client, useMount bool, logger *zap.Logger) (*metaHashChecker, error) {
+	mode, err := resolveMetaHashMode(extra)
+	if err != nil {
+		return nil, err
+	}
+	if mode == metaHashModeOff && artwork == nil {
+		return nil, nil
+	}
+
+	checker := &metaHashChecker{
+		jobID:    job.ID,
+		useMount: useMount,
+		hashAll:  mode != metaHashModeOff,
+		client:   client,
+		repo:     e.cfg.MetaHashes,
+		logger:   logger,
+		artwork:  artwork,
+		records:  map[string]model.MetaFileHash{},
+		pending:  map[string]string{},
+	}
+	if artwork != nil {
+		checker.artworkFP = "artwork:" + artwork.Image.Fingerprint()
+	}
+	if mode == metaHashModeFull {
+		maxMB := extra.MetaFullHashMaxMB
+		if maxMB <= 0 {
+			maxMB = defaultMetaFullHashMaxMB
+		}
+		checker.fullMaxSize = maxMB * 1024 * 1024
+	}
+
+	if checker.repo != nil {
+		records, err := checker.repo.ListByJob(ctx, job.ID)
+		if err != nil {
+			// 记录不可用时仍可在复制模式下直接比对内容
+			logger.Warn("加载元数据哈希记录失败", zap.Error(err))
+		} else {
+			checker.records = records
+		}
+	}
+
+	logger.Info("启用元数据哈希比对",
+		zap.String("hash_mode", mode),
+		zap.Bool("use_mount", useMount),
+		zap.Bool("artwork", artwork != nil),
+		zap.Int("records", len(checker.records)))
+	return checker, nil
+}
+
+// recordRoot 设置写入目录与记录目录的映射（两者相同时不做转换）
+func (c *metaHashChecker) recordRoot(writeRoot, keyRoot string) {
+	if filepath.Clean(writeRoot) == filepath.Clean(keyRoot) {
+		return
+	}
+	c.writeRoot = filepath.Clean(writeRoot)
+	c.keyRoot = filepath.Clean(keyRoot)
+}
+
+// recordKey 返回目标文件对应的记录路径
+func (c *metaHashChecker) recordKey(targetPath string) string {
+	if c.writeRoot == "" {
+		return targetPath
+	}
+	rel, err := filepath.Rel(c.writeRoot, targetPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return targetPath
+	}
+	return filepath.Join(c.keyRoot, rel)
+}
+
+// processed 判断源文件写入时是否经过图片处理
+func (c *metaHashChecker) processed(entry syncengine.RemoteEntry) bool {
+	return c.artwork.Match(entry.Path)
+}
+
+// sourceHash 获取源文件哈希；返回空字符串表示不可用
+//
+// 经过图片处理的文件附加处理参数指纹，参数变化后重新处理。
+func (c *metaHashChecker) sourceHash(ctx context.Context, entry syncengine.RemoteEntry) string {
+	processed := c.processed(entry)
+	if !processed {
+		if !c.hashAll {
+			return ""
+		}
+		return c.contentHash(ctx, entry)
+	}
+	sum := ""
+	if c.hashAll {
+		sum = c.contentHash(ctx, entry)
+	}
+	if sum == "" {
+		sum = fmt.Sprintf("stat:%d:%d", entry.Size, entry.ModTime.Unix())
+	}
+	return sum + "|" + c.artworkFP
+}
+
+// contentHash 获取源文件内容哈希；返回空字符串表示不可用
+func (c *metaHashChecker) contentHash(ctx context.Context, entry syncengine.RemoteEntry) string {
+	if !c.useMount {
+		return entry.Hash
+	}
+	accessPath, err := c.client.ResolveAccessPath(ctx, entry.Path)
+	if err != nil {
+		c.logger.Debug("解析源访问路径失败，回退到大小/时间比较",
+			zap.String("path", entry.Path),
+			zap.Error(err))
+		return ""
+	}
+	sum, err := hash.FileHash(accessPath, c.fullMaxSize)
+	if err != nil {
+		c.logger.Debug("计算源文件哈希失败，回退到大小/时间比较",
+			zap.String("path", accessPath),
+			zap.Error(err))
+		return ""
+	}
+	return sum
+}
+
+// evaluate 判断已存在的目标文件是否与源文件内容一致
+//
+// 参数：
+//   - info: 目标文件信息（必须存在）
+//   - same: 大小/修改时间比较结果，用于无法计算哈希时回退
+//
+// 返回：true 表示未变化，可跳过
+func (c *metaHashChecker) evaluate(ctx context.Context, entry syncengine.RemoteEntry, targetPath string, info os.FileInfo, same bool) bool {
+	srcHash := c.sourceHash(ctx, entry)
+	if srcHash == "" {
+		return same
+	}
+
+	if record, ok := c.records[c.recordKey(targetPath)]; ok {
+		if record.SourceHash == srcHash && targetUnchanged(info, record) {
+			return true
+		}
+		c.setPending(targetPath, srcHash)
+		return false
+	}
+
+	// 没有历史记录：复制模式直接比对目标文件内容；
+	// 下载模式的远端哈希无法与本地文件比对，沿用大小/修改时间结果作为基线；
+	// 需要图片处理的文件无法确认是否已处理，重新处理一次以建立记录
+	adopt := same
+	switch {
+	case c.processed(entry):
+		adopt = false
+	case c.useMount:
+		targetHash, err := hash.FileHash(targetPath, c.fullMaxSize)
+		adopt = err == nil && targetHash == srcHash
+	}
+	if !adopt {
+		c.setPending(targetPath, srcHash)
+		return false
+	}
+	c.save(ctx, targetPath, srcHash, info)
+	return true
+}
+
+// track 为即将写入的文件准备源哈希（evaluate 已计算过时跳过）
+func (c *metaHashChecker) track(ctx context.Context, entry syncengine.RemoteEntry, targetPath string) {
+	c.mu.Lock()
+	_, ok := c.pending[targetPath]
+	c.mu.Unlock()
+	if ok {
+		return
+	}
+	if srcHash := c.sourceHash(ctx, entry); srcHash != "" {
+		c.setPending(targetPath, srcHash)
+	}
+}
+
+func (c *metaHashChecker) setPending(targetPath, srcHash string) {
+	c.mu.Lock()
+	c.pending[targetPath] = srcHash
+	c.mu.Unlock()
+}
+
+// commit 目标文件写入成功后持久化哈希记录
+func (c *metaHashChecker) commit(ctx context.Context, targetPath string) {
+	c.mu.Lock()
+	srcHash, ok := c.pending[targetPath]
+	delete(c.pending, targetPath)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		c.logger.Debug("读取目标文件信息失败，跳过哈希记录",
+			zap.String("path", targetPath),
+			zap.Error(err))
+		return
+	}
+	c.save(ctx, targetPath, srcHash, info)
+}
+
+// save 写入哈希记录（失败仅记录日志，下次同步会重新比对）
+func (c *metaHashChecker) save(ctx context.Context, targetPath, srcHash string, info os.FileInfo) {
+	if c.repo == nil {
+		return
+	}
+	record := &model.MetaFileHash{
+		JobID:      c.jobID,
+		TargetPath: c.recordKey(targetPath),
+		SourceHash: srcHash,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+	}
+	if err := c.repo.Upsert(ctx, record); err != nil {
+		c.logger.Warn("保存元数据哈希记录失败",
+			zap.String("path", targetPath),
+			zap.Error(err))
+	}
+}
+
+// targetUnchanged 判断目标文件在上次写入后是否被外部修改
+func targetUnchanged(info os.FileInfo, record model.MetaFileHash) bool {
+	if info.Size() != record.Size {
+		return false
+	}
+	diff := info.ModTime().Sub(record.ModTime)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Second
+}
+
+// metaHashEventSink 在元数据写入成功时提交哈希记录，并转发事件
+type metaHashEventSink struct {
+	checker *metaHashChecker
+	next    appsync.MetaEventSink
+}
+
+// OnMetaEvent 实现 appsync.MetaEventSink
+func (s *metaHashEventSink) OnMetaEvent(ctx context.Context, event appsync.MetaEvent) {
+	if event.Status == "success" && event.Op != "delete" {
+		s.checker.commit(ctx, event.TargetPath)
+	}
+	if s.next != nil {
+		s.next.OnMetaEvent(ctx, event)
+	}
+}
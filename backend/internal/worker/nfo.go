@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"path"
+	"strings"
+
+	appsync "github.com/strmsync/strmsync/internal/app/sync"
+	"github.com/strmsync/strmsync/internal/engine"
+)
+
+// planNFOItems 选出源端没有 NFO 的视频，返回需要生成的 NFO（Job 选项 generate_nfo）
+//
+// 源端 NFO 按 MediaIndex 归属：同名 NFO（movie.nfo 对应 movie.mkv）或目录内唯一视频的
+// 目录级 movie.nfo 视为已有 NFO。目标文件与 STRM 同名，扩展名为 .nfo（启用媒体布局时跟随 STRM 的输出路径）。
+func planNFOItems(entries, videos []syncengine.RemoteEntry, remotePath string, excludeDirs []string, mediaIndex *syncengine.MediaIndex, targets *metaTargetResolver) []appsync.NFOItem {
+	hasNFO := make(map[string]struct{})
+	for _, entry := range entries {
+		if entry.IsDir || syncengine.IsExcludedPath(remotePath, entry.Path, excludeDirs) {
+			continue
+		}
+		if strings.ToLower(path.Ext(entry.Name)) == ".nfo" {
+			hasNFO[mediaIndex.MediaItemOf(entry.Path)] = struct{}{}
+		}
+	}
+
+	items := make([]appsync.NFOItem, 0, len(videos))
+	for _, video := range videos {
+		mediaItem := syncengine.MediaItemKey(video.Path)
+		if _, ok := hasNFO[mediaItem]; ok {
+			continue
+		}
+		targetPath, err := targets.targetPath(mediaItem + ".nfo")
+		if err != nil {
+			continue
+		}
+		items = append(items, appsync.NFOItem{
+			SourcePath: video.Path,
+			TargetPath: targetPath,
+			Size:       video.Size,
+			MediaItem:  mediaItem,
+		})
+	}
+	return items
+}
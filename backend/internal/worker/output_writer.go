@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/engine"
+	openlistsdk "github.com/strmsync/strmsync/internal/pkg/sdk/openlist"
+	"github.com/strmsync/strmsync/internal/strmwriter"
+)
+
+// 输出写入器类型（Job 选项 writer.type）
+const (
+	writerTypeLocal    = "local"
+	writerTypeWebDAV   = "webdav"
+	writerTypeOpenList = "openlist"
+)
+
+// writerOptions 输出写入器配置（Job 选项 writer）
+//
+// 默认写入本地目录；媒体服务器位于其他主机时可写入 WebDAV 或上传回 OpenList，
+// 此时 target_path 为远端路径。
+type writerOptions struct {
+	Type           string `json:"type"`            // local（默认）/webdav/openlist
+	URL            string `json:"url"`             // webdav：服务地址
+	Username       string `json:"username"`        // webdav：用户名
+	Password       string `json:"password"`        // webdav：密码
+	DataServerID   uint   `json:"data_server_id"`  // openlist：上传目标数据服务器（默认为任务的数据服务器）
+	TimeoutSeconds int    `json:"timeout_seconds"` // 请求超时（秒）
+}
+
+// resolveWriterType 解析输出写入器类型
+func resolveWriterType(extra jobOptions) (string, error) {
+	raw := strings.ToLower(strings.TrimSpace(extra.Writer.Type))
+	switch raw {
+	case "", writerTypeLocal:
+		return writerTypeLocal, nil
+	case writerTypeWebDAV, writerTypeOpenList:
+		return raw, nil
+	default:
+		return "", fmt.Errorf("unsupported writer type %q (valid: local, webdav, openlist)", extra.Writer.Type)
+	}
+}
+
+// JobUsesRemoteWriter 判断任务是否输出到远程存储（target_path 不是本地路径）
+func JobUsesRemoteWriter(job model.Job) bool {
+	extra, err := parseJobOptions(job.Options)
+	if err != nil {
+		return false
+	}
+	writerType, err := resolveWriterType(extra)
+	return err == nil && writerType != writerTypeLocal
+}
+
+// buildRemoteWriter 构建远程输出写入器
+func (f DefaultWriterFactory) buildRemoteWriter(ctx context.Context, job model.Job, writerType string, opts writerOptions) (syncengine.Writer, error) {
+	timeout := time.Duration(opts.TimeoutSeconds) * time.Second
+
+	switch writerType {
+	case writerTypeWebDAV:
+		writer, err := strmwriter.NewWebDAVWriter(job.TargetPath, strmwriter.WebDAVConfig{
+			URL:      opts.URL,
+			Username: opts.Username,
+			Password: opts.Password,
+			Timeout:  timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("writer factory: new webdav writer: %w", err)
+		}
+		return writer, nil
+
+	case writerTypeOpenList:
+		if f.DataServers == nil {
+			return nil, fmt.Errorf("writer factory: openlist writer requires data server repository")
+		}
+		serverID := opts.DataServerID
+		if serverID == 0 && job.DataServerID != nil {
+			serverID = *job.DataServerID
+		}
+		if serverID == 0 {
+			return nil, fmt.Errorf("writer factory: openlist writer requires data_server_id")
+		}
+		server, err := f.DataServers.GetByID(ctx, serverID)
+		if err != nil {
+			return nil, fmt.Errorf("writer factory: load data server %d: %w", serverID, err)
+		}
+		if !strings.EqualFold(strings.TrimSpace(server.Type), writerTypeOpenList) {
+			return nil, fmt.Errorf("writer factory: data server %d is %s, not openlist", serverID, server.Type)
+		}
+		fsCfg, err := buildFilesystemConfig(server)
+		if err != nil {
+			return nil, fmt.Errorf("writer factory: %w", err)
+		}
+		if timeout <= 0 {
+			timeout = fsCfg.Timeout
+		}
+		client, err := openlistsdk.NewClient(openlistsdk.Config{
+			BaseURL:  fsCfg.BaseURL,
+			Username: fsCfg.Username,
+			Password: fsCfg.Password,
+			Timeout:  timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("writer factory: new openlist client: %w", err)
+		}
+		writer, err := strmwriter.NewOpenListWriter(job.TargetPath, client)
+		if err != nil {
+			return nil, fmt.Errorf("writer factory: new openlist writer: %w", err)
+		}
+		return writer, nil
+	}
+	return nil, fmt.Errorf("writer factory: unsupported writer type %q", writerType)
+}
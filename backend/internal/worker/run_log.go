@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	syncengine "github.com/strmsync/strmsync/internal/engine"
+	"github.com/strmsync/strmsync/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 执行记录目录中的调试产物文件名
+const (
+	RunEngineOptionsFile = "engine_options.json" // 生效的 EngineOptions
+	RunStatsFile         = "stats.json"          // 同步统计与错误
+)
+
+// runEngineOptions 执行时生效的 EngineOptions 快照
+//
+// 仅包含可序列化的配置项，回调与接口字段以是否启用表示。
+type runEngineOptions struct {
+	MaxConcurrency      int                          `json:"max_concurrency"`
+	OutputRoot          string                       `json:"output_root"`
+	FileExtensions      []string                     `json:"file_extensions"`
+	MinFileSize         int64                        `json:"min_file_size"`
+	DryRun              bool                         `json:"dry_run"`
+	ForceUpdate         bool                         `json:"force_update"`
+	SkipExisting        bool                         `json:"skip_existing"`
+	ModTimeEpsilon      string                       `json:"mod_time_epsilon"`
+	EnableOrphanCleanup bool                         `json:"enable_orphan_cleanup"`
+	OrphanCleanupDryRun bool                         `json:"orphan_cleanup_dry_run"`
+	CleanupSidecars     bool                         `json:"cleanup_sidecars"`
+	DetectMoves         bool                         `json:"detect_moves"`
+	MountPathMapping    *syncengine.MountPathMapping `json:"mount_path_mapping,omitempty"`
+	StrmReplaceRules    []syncengine.StrmReplaceRule `json:"strm_replace_rules,omitempty"`
+	ExcludeDirs         []string                     `json:"exclude_dirs,omitempty"`
+	RemoteListOverride  bool                         `json:"remote_list_override"`
+	ResumeFrom          *syncengine.SyncCheckpoint   `json:"resume_from,omitempty"`
+	CheckpointInterval  int                          `json:"checkpoint_interval"`
+}
+
+// runStats 执行结束时的统计快照
+type runStats struct {
+	Stats         syncengine.SyncStats `json:"stats"`
+	Metadata      metadataStats        `json:"metadata"`
+	Error         string               `json:"error,omitempty"`
+	MetadataError string               `json:"metadata_error,omitempty"`
+	APIErrors     int64                `json:"api_errors"`
+	FinishedAt    time.Time            `json:"finished_at"`
+}
+
+// runCapture 单次执行的日志器与调试产物目录
+type runCapture struct {
+	log      *zap.Logger
+	dir      string       // 为空表示未启用捕获
+	closeLog func() error // 关闭执行记录日志文件
+
+	apiErrors atomic.Int64 // 数据服务器 API 调用失败次数
+}
+
+// openRunCapture 为执行记录打开独立日志；未配置或打开失败时退化为 Executor 日志器
+func (e *Executor) openRunCapture(taskID uint) *runCapture {
+	capture := &runCapture{log: e.log}
+	if e.cfg.RunLogDir == "" {
+		return capture
+	}
+	runLog, closeLog, err := logger.OpenRunLog(e.log, e.cfg.RunLogDir, taskID)
+	if err != nil {
+		e.log.Warn("打开执行记录日志失败",
+			zap.Uint("task_id", taskID),
+			zap.Error(err))
+		return capture
+	}
+	capture.log = runLog
+	capture.dir = logger.RunLogDir(e.cfg.RunLogDir, taskID)
+	capture.closeLog = closeLog
+	return capture
+}
+
+// close 关闭执行记录日志
+func (c *runCapture) close() {
+	if c.closeLog == nil {
+		return
+	}
+	_ = c.log.Sync()
+	if err := c.closeLog(); err != nil {
+		c.log.Warn("关闭执行记录日志失败", zap.Error(err))
+	}
+}
+
+// writeEngineOptions 保存生效的 EngineOptions
+func (c *runCapture) writeEngineOptions(opts syncengine.EngineOptions) {
+	c.writeArtifact(RunEngineOptionsFile, runEngineOptions{
+		MaxConcurrency:      opts.MaxConcurrency,
+		OutputRoot:          opts.OutputRoot,
+		FileExtensions:      opts.FileExtensions,
+		MinFileSize:         opts.MinFileSize,
+		DryRun:              opts.DryRun,
+		ForceUpdate:         opts.ForceUpdate,
+		SkipExisting:        opts.SkipExisting,
+		ModTimeEpsilon:      opts.ModTimeEpsilon.String(),
+		EnableOrphanCleanup: opts.EnableOrphanCleanup,
+		OrphanCleanupDryRun: opts.OrphanCleanupDryRun,
+		CleanupSidecars:     opts.CleanupSidecars,
+		DetectMoves:         opts.DetectMoves,
+		MountPathMapping:    opts.MountPathMapping,
+		StrmReplaceRules:    opts.StrmReplaceRules,
+		ExcludeDirs:         opts.ExcludeDirs,
+		RemoteListOverride:  opts.ListOverride != nil,
+		ResumeFrom:          opts.ResumeFrom,
+		CheckpointInterval:  opts.CheckpointInterval,
+	})
+}
+
+// writeStats 保存执行统计
+func (c *runCapture) writeStats(stats syncengine.SyncStats, meta metadataStats, runErr, metaErr error) {
+	snapshot := runStats{
+		Stats:      stats,
+		Metadata:   meta,
+		APIErrors:  c.apiErrors.Load(),
+		FinishedAt: time.Now(),
+	}
+	if runErr != nil {
+		snapshot.Error = runErr.Error()
+	}
+	if metaErr != nil {
+		snapshot.MetadataError = metaErr.Error()
+	}
+	c.writeArtifact(RunStatsFile, snapshot)
+}
+
+// writeArtifact 以 JSON 写入调试产物（失败只记录警告）
+func (c *runCapture) writeArtifact(name string, v interface{}) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(c.dir, name), data, 0o644)
+	}
+	if err != nil {
+		c.log.Warn("保存执行记录调试信息失败",
+			zap.String("file", name),
+			zap.Error(err))
+	}
+}
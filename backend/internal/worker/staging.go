@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/strmsync/strmsync/internal/domain/model"
+	"github.com/strmsync/strmsync/internal/strmwriter"
+)
+
+// resolveStagingMode 解析暂存同步切换方式（Job 选项 staging_mode）
+//
+// 返回 false 表示未启用暂存同步。
+func resolveStagingMode(extra jobOptions) (strmwriter.SwapMode, bool, error) {
+	raw := strings.ToLower(strings.TrimSpace(extra.StagingMode))
+	if raw == "" || raw == "off" {
+		return "", false, nil
+	}
+	mode := strmwriter.SwapMode(raw)
+	if !mode.IsValid() {
+		return "", false, fmt.Errorf("unsupported staging_mode %q", extra.StagingMode)
+	}
+	return mode, true, nil
+}
+
+// JobStagingMode 返回任务配置的暂存同步切换方式
+//
+// 返回 false 表示任务未启用暂存同步。
+func JobStagingMode(job model.Job) (strmwriter.SwapMode, bool, error) {
+	extra, err := parseJobOptions(job.Options)
+	if err != nil {
+		return "", false, fmt.Errorf("parse job options: %w", err)
+	}
+	return resolveStagingMode(extra)
+}
+
+// prepareStaging 为启用暂存同步的任务准备暂存目录
+//
+// 未启用或干运行时返回 nil。同一执行的重试复用同一暂存目录。
+func prepareStaging(job model.Job, extra jobOptions, taskID uint) (*strmwriter.Staging, error) {
+	mode, enabled, err := resolveStagingMode(extra)
+	if err != nil {
+		return nil, permanentTaskError(err)
+	}
+	if !enabled || extra.DryRun {
+		return nil, nil
+	}
+	return strmwriter.PrepareStaging(job.TargetPath, mode, fmt.Sprintf("task-%d", taskID))
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/engine"
+	syncqueue "github.com/strmsync/strmsync/internal/queue"
 	"go.uber.org/zap"
 )
 
@@ -177,6 +178,9 @@ type TaskRunProgress struct {
 	// FilteredFiles 过滤文件数
 	FilteredFiles int
 
+	// MovedFiles 因远端移动/重命名而移动的 STRM 数
+	MovedFiles int
+
 	// MetaTotalFiles 元数据总数
 	MetaTotalFiles int
 
@@ -192,6 +196,12 @@ type TaskRunProgress struct {
 	// MetaFailedFiles 元数据失败数
 	MetaFailedFiles int
 
+	// MetaBytes 元数据传输字节数
+	MetaBytes int64
+
+	// APIErrors 数据服务器 API 调用失败次数
+	APIErrors int
+
 	// Progress 进度百分比（0-100）
 	Progress int
 }
@@ -208,6 +218,17 @@ type TaskRunRepository interface {
 	// 返回：
 	//   - error: 更新失败时返回错误
 	UpdateProgress(ctx context.Context, taskID uint, progress TaskRunProgress) error
+
+	// UpdateCheckpoint 更新 TaskRun 的同步断点
+	//
+	// 参数：
+	//   - ctx: 上下文
+	//   - taskID: 任务 ID
+	//   - checkpoint: 断点 JSON（空字符串表示清除断点）
+	//
+	// 返回：
+	//   - error: 更新失败时返回错误
+	UpdateCheckpoint(ctx context.Context, taskID uint, checkpoint string) error
 }
 
 // TaskRunEventRepository 定义 TaskRunEvent 写入接口
@@ -216,6 +237,33 @@ type TaskRunEventRepository interface {
 	Create(ctx context.Context, event *model.TaskRunEvent) error
 }
 
+// MetaHashRepository 定义元数据哈希记录的读写接口
+//
+// 用于基于内容哈希的元数据变更检测（Job 选项 meta_hash_mode）。
+type MetaHashRepository interface {
+	// ListByJob 获取指定 Job 的全部哈希记录
+	//
+	// 返回：
+	//   - map[string]model.MetaFileHash: 以目标文件路径为键的记录
+	//   - error: 查询失败时返回错误
+	ListByJob(ctx context.Context, jobID uint) (map[string]model.MetaFileHash, error)
+
+	// Upsert 写入或更新单条哈希记录（按 JobID + TargetPath 去重）
+	Upsert(ctx context.Context, record *model.MetaFileHash) error
+}
+
+// StrmMappingRepository 定义远端文件与 STRM 对应记录的读写接口
+//
+// 用于媒体布局（Job 选项 layout）在多次同步之间保持远端文件与输出路径的对应关系，
+// 以及移动检测比对远端文件签名。
+type StrmMappingRepository interface {
+	// ListByJob 获取指定 Job 的全部记录（远端路径 -> 记录）
+	ListByJob(ctx context.Context, jobID uint) (map[string]syncengine.MoveRecord, error)
+
+	// ReplaceByJob 以 records 替换指定 Job 的全部记录
+	ReplaceByJob(ctx context.Context, jobID uint, records map[string]syncengine.MoveRecord) error
+}
+
 // DriverFactory 根据 DataServer 构建 Driver 实例
 //
 // 用于构建不同类型的数据源驱动（CloudDrive2、OpenList 等）。
@@ -248,6 +296,42 @@ type WriterFactory interface {
 	Build(ctx context.Context, job model.Job) (syncengine.Writer, error)
 }
 
+// PriorityTaskQueue 支持按附加条件领取任务的队列（可选能力）
+//
+// WorkerPool 通过类型断言检测，用于为高优先级任务预留执行槽位、
+// 按 Worker 分组领取任务并指定执行租约时长。
+type PriorityTaskQueue interface {
+	// ClaimNextWithOptions 按附加条件领取下一个待执行任务
+	ClaimNextWithOptions(ctx context.Context, workerID string, opts syncqueue.ClaimOptions) (*model.TaskRun, error)
+}
+
+// LeaseTaskQueue 支持执行租约与 Worker 注册的队列（可选能力）
+//
+// WorkerPool 通过类型断言检测。多个实例共享同一队列时：
+// - 执行中的任务定期续期租约，租约丢失（已取消或被回收）时停止执行
+// - 定期回收租约过期的任务（其他实例退出或失联）
+// - 定期写入 Worker 实例心跳，供 Worker 注册表查询
+type LeaseTaskQueue interface {
+	// RenewLease 续期执行租约，任务不再属于该 Worker 时返回 syncqueue.ErrLeaseLost
+	RenewLease(ctx context.Context, taskID uint, workerID string, lease time.Duration) error
+
+	// RecoverExpired 回收租约过期的任务；ownerID 非空时同时回收该 Worker 持有的任务
+	RecoverExpired(ctx context.Context, ownerID string) ([]model.TaskRun, error)
+
+	// RegisterWorker 写入 Worker 实例心跳
+	RegisterWorker(ctx context.Context, node model.WorkerNode) error
+}
+
+// LoggerScopedDriverFactory 支持为单次执行指定日志器的驱动工厂（可选能力）
+//
+// Executor 通过类型断言检测，用于将驱动日志写入执行记录日志。
+type LoggerScopedDriverFactory interface {
+	DriverFactory
+
+	// WithLogger 返回使用指定日志器的工厂
+	WithLogger(log *zap.Logger) DriverFactory
+}
+
 // WorkerConfig 描述 Worker 运行参数
 //
 // 所有可选字段都有合理的默认值。
@@ -277,6 +361,17 @@ type WorkerConfig struct {
 	// Worker 通过此仓储写入执行事件明细。
 	TaskRunEvents TaskRunEventRepository
 
+	// MetaHashes 元数据哈希记录仓储（可选）
+	//
+	// 未配置时，哈希模式仅在复制模式下直接比对源文件与目标文件内容。
+	MetaHashes MetaHashRepository
+
+	// Mappings 远端文件与 STRM 对应记录仓储（可选）
+	//
+	// 未配置时，媒体布局每次同步重新分配输出路径，且没有签名记录可供移动检测比对，
+	// 远端重命名的文件按新文件处理。
+	Mappings StrmMappingRepository
+
 	// DriverFactory 驱动工厂（可选，默认使用 DefaultDriverFactory）
 	//
 	// 用于构建数据源驱动。
@@ -292,11 +387,44 @@ type WorkerConfig struct {
 	// 用于记录 Worker 的运行日志。
 	Logger *zap.Logger
 
+	// RunLogDir 执行记录日志根目录（可选）
+	//
+	// 配置后每次执行的日志写入 <RunLogDir>/<TaskRunID>/run.log，
+	// 并保存生效的 EngineOptions 与统计信息。为空时不单独捕获。
+	RunLogDir string
+
+	// Fsync 写入 STRM 与元数据文件后是否 fsync（可选，默认关闭）
+	//
+	// 开启后断电也不会留下空文件，但会降低大批量写入的速度。
+	Fsync bool
+
 	// Concurrency Worker 并发数（可选，默认 4）
 	//
 	// 控制同时执行的任务数量。
 	Concurrency int
 
+	// ReservedHighSlots 为高优先级任务（手动触发）预留的执行槽位（可选，默认 1）
+	//
+	// 预留槽位只领取高优先级任务，避免长时间运行的定时任务占满执行池。
+	// 负数表示不预留；至少保留 1 个普通槽位。需要队列实现 PriorityTaskQueue。
+	ReservedHighSlots int
+
+	// Group Worker 分组（可选）
+	//
+	// 只领取未分组或分组与之相同的任务（jobs.worker_group），
+	// 用于让任务在挂载了目标目录的实例上执行。需要队列实现 PriorityTaskQueue。
+	Group string
+
+	// LeaseDuration 执行租约时长（可选，默认 syncqueue.DefaultLeaseDuration）
+	//
+	// 需要队列实现 LeaseTaskQueue。实例退出后，其持有的任务最迟在租约到期后被回收。
+	LeaseDuration time.Duration
+
+	// HeartbeatInterval 租约续期与实例心跳间隔（可选，默认 5s，不超过租约时长的 1/3）
+	//
+	// 同时决定取消操作传播到执行中任务的最长延迟。
+	HeartbeatInterval time.Duration
+
 	// PollInterval 无任务时轮询间隔（可选，默认 3s）
 	//
 	// 当没有可用任务时，Worker 会等待此时间后再次尝试领取。
@@ -317,3 +445,25 @@ type WorkerConfig struct {
 	// 用于追踪任务执行的 Worker。
 	WorkerID string
 }
+
+// PoolStatus 表示 Worker 池的运行状态快照
+//
+// 用于健康检查：Alive 小于 Concurrency 说明有 worker goroutine 意外退出。
+type PoolStatus struct {
+	WorkerID    string         `json:"worker_id"`   // Worker 标识
+	Group       string         `json:"group"`       // Worker 分组
+	Running     bool           `json:"running"`     // 是否已启动
+	Concurrency int            `json:"concurrency"` // 配置的并发数
+	Reserved    int            `json:"reserved"`    // 为高优先级任务预留的槽位数
+	Alive       int            `json:"alive"`       // 存活的 worker goroutine 数
+	InFlight    int            `json:"in_flight"`   // 正在执行的任务数
+	Tasks       []InFlightTask `json:"tasks"`       // 正在执行的任务
+}
+
+// InFlightTask 表示正在执行的任务
+type InFlightTask struct {
+	TaskID    uint      `json:"task_id"`
+	JobID     uint      `json:"job_id"`
+	JobName   string    `json:"job_name"`
+	StartedAt time.Time `json:"started_at"`
+}
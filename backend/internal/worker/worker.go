@@ -4,7 +4,10 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +16,7 @@ import (
 	"github.com/strmsync/strmsync/internal/domain/model"
 	"github.com/strmsync/strmsync/internal/pkg/logger"
 	"github.com/strmsync/strmsync/internal/pkg/requestid"
+	syncqueue "github.com/strmsync/strmsync/internal/queue"
 	"go.uber.org/zap"
 )
 
@@ -25,12 +29,21 @@ const (
 
 	// defaultClaimTimeout 默认领取超时
 	defaultClaimTimeout = 5 * time.Second
+
+	// defaultReservedHighSlots 默认为高优先级任务预留的槽位数
+	defaultReservedHighSlots = 1
+
+	// defaultHeartbeatInterval 默认租约续期与实例心跳间隔
+	defaultHeartbeatInterval = 5 * time.Second
 )
 
 // WorkerPool 是固定大小的 Worker 执行池
 //
 // 设计要点：
 // - 固定数量 goroutine 轮询 ClaimNext
+// - 末尾的 ReservedHighSlots 个 goroutine 只领取高优先级任务（优先级通道）
+// - 队列支持租约时，执行中的任务定期续期，租约丢失（取消/被回收）时停止执行
+// - 另有一个 goroutine 写入实例心跳并回收其他实例遗留的过期任务
 // - 使用可取消 context 控制退出
 // - 使用结构化日志记录任务生命周期
 //
@@ -51,7 +64,13 @@ type WorkerPool struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	running atomic.Bool
+	running   atomic.Bool
+	hostname  string
+	startedAt time.Time
+
+	alive    atomic.Int64          // 存活的 worker goroutine 数
+	mu       sync.Mutex            // 保护 inFlight
+	inFlight map[uint]InFlightTask // 正在执行的任务
 }
 
 // NewWorker 创建 WorkerPool
@@ -89,9 +108,28 @@ func NewWorker(cfg WorkerConfig) (*WorkerPool, error) {
 	if cfg.ClaimTimeout <= 0 {
 		cfg.ClaimTimeout = defaultClaimTimeout
 	}
+	if cfg.ReservedHighSlots == 0 {
+		cfg.ReservedHighSlots = defaultReservedHighSlots
+	}
+	if _, ok := cfg.Queue.(PriorityTaskQueue); !ok || cfg.ReservedHighSlots < 0 {
+		cfg.ReservedHighSlots = 0
+	}
+	if cfg.ReservedHighSlots > cfg.Concurrency-1 {
+		cfg.ReservedHighSlots = cfg.Concurrency - 1
+	}
 	if cfg.WorkerID == "" {
 		cfg.WorkerID = "worker-" + requestid.NewRequestID()
 	}
+	cfg.Group = strings.TrimSpace(cfg.Group)
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = syncqueue.DefaultLeaseDuration
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if cfg.HeartbeatInterval > cfg.LeaseDuration/3 {
+		cfg.HeartbeatInterval = cfg.LeaseDuration / 3
+	}
 
 	// 创建 Executor
 	executor, err := NewExecutor(ExecutorConfig{
@@ -99,18 +137,25 @@ func NewWorker(cfg WorkerConfig) (*WorkerPool, error) {
 		DataServers:   cfg.DataServers,
 		TaskRuns:      cfg.TaskRuns,
 		TaskRunEvents: cfg.TaskRunEvents,
+		MetaHashes:    cfg.MetaHashes,
+		Mappings:      cfg.Mappings,
 		DriverFactory: cfg.DriverFactory,
 		WriterFactory: cfg.WriterFactory,
 		Logger:        cfg.Logger,
+		RunLogDir:     cfg.RunLogDir,
+		Fsync:         cfg.Fsync,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	hostname, _ := os.Hostname()
 	return &WorkerPool{
 		cfg:      cfg,
 		log:      cfg.Logger.With(zap.String("worker_id", cfg.WorkerID)),
 		executor: executor,
+		hostname: hostname,
+		inFlight: make(map[uint]InFlightTask),
 	}, nil
 }
 
@@ -131,6 +176,14 @@ func (w *WorkerPool) Start(ctx context.Context) error {
 	}
 
 	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.startedAt = time.Now()
+
+	if queue, ok := w.cfg.Queue.(LeaseTaskQueue); ok {
+		// 同一 Worker ID 重启时，上次进程持有的任务已无人执行，领取前立即回收
+		w.recoverExpired(queue, w.cfg.WorkerID)
+		w.wg.Add(1)
+		go w.maintainLoop(queue)
+	}
 
 	// 启动 Worker goroutines
 	for i := 0; i < w.cfg.Concurrency; i++ {
@@ -139,7 +192,9 @@ func (w *WorkerPool) Start(ctx context.Context) error {
 	}
 
 	w.log.Info("任务执行器启动",
-		zap.Int("concurrency", w.cfg.Concurrency))
+		zap.String("group", w.cfg.Group),
+		zap.Int("concurrency", w.cfg.Concurrency),
+		zap.Int("reserved_high_slots", w.cfg.ReservedHighSlots))
 	return nil
 }
 
@@ -177,6 +232,14 @@ func (w *WorkerPool) Stop(ctx context.Context) error {
 		return fmt.Errorf("worker: stop cancelled: %w", ctx.Err())
 	}
 
+	// 标记实例已停止，注册表不再将其视为离线故障
+	if queue, ok := w.cfg.Queue.(LeaseTaskQueue); ok {
+		stoppedAt := time.Now()
+		if err := queue.RegisterWorker(ctx, w.workerNode(stoppedAt)); err != nil {
+			w.log.Warn("更新 Worker 注册信息失败", zap.Error(err))
+		}
+	}
+
 	w.log.Info("任务执行器停止")
 	return nil
 }
@@ -190,8 +253,12 @@ func (w *WorkerPool) Stop(ctx context.Context) error {
 // 4. 如果有任务，执行任务
 func (w *WorkerPool) runLoop(index int) {
 	defer w.wg.Done()
+	w.alive.Add(1)
+	defer w.alive.Add(-1)
 
-	log := w.log.With(zap.Int("worker_index", index))
+	// 预留槽位只领取高优先级任务
+	highOnly := index >= w.cfg.Concurrency-w.cfg.ReservedHighSlots
+	log := w.log.With(zap.Int("worker_index", index), zap.Bool("high_priority_only", highOnly))
 
 	for {
 		// 检查 context 是否取消
@@ -202,7 +269,7 @@ func (w *WorkerPool) runLoop(index int) {
 		}
 
 		// 领取任务
-		task, err := w.claimNext()
+		task, err := w.claimNext(highOnly)
 		if err != nil {
 			log.Warn("claim next failed", zap.Error(err))
 			w.sleepWithContext(w.cfg.PollInterval)
@@ -228,8 +295,9 @@ func (w *WorkerPool) runLoop(index int) {
 
 // claimNext 领取任务
 //
-// 使用 ClaimTimeout 控制超时。
-func (w *WorkerPool) claimNext() (*model.TaskRun, error) {
+// 使用 ClaimTimeout 控制超时；highOnly 为 true 时只领取高优先级任务。
+// 队列支持附加条件时按 Worker 分组领取并设置租约时长。
+func (w *WorkerPool) claimNext(highOnly bool) (*model.TaskRun, error) {
 	claimCtx := w.ctx
 	var cancel context.CancelFunc
 	if w.cfg.ClaimTimeout > 0 {
@@ -239,15 +307,26 @@ func (w *WorkerPool) claimNext() (*model.TaskRun, error) {
 		defer cancel()
 	}
 
+	if queue, ok := w.cfg.Queue.(PriorityTaskQueue); ok {
+		opts := syncqueue.ClaimOptions{
+			Group:         w.cfg.Group,
+			LeaseDuration: w.cfg.LeaseDuration,
+		}
+		if highOnly {
+			opts.MaxPriority = syncqueue.TaskPriorityHigh
+		}
+		return queue.ClaimNextWithOptions(claimCtx, w.cfg.WorkerID, opts)
+	}
 	return w.cfg.Queue.ClaimNext(claimCtx, w.cfg.WorkerID)
 }
 
 // executeTask 执行单个任务并回写队列状态
 //
 // 执行流程：
-// 1. 使用 Executor 执行任务
-// 2. 如果执行失败，调用 Queue.Fail
-// 3. 如果执行成功，调用 Queue.Complete
+// 1. 使用 Executor 执行任务（队列支持租约时同时定期续期）
+// 2. 如果租约丢失（任务已取消或被回收），不再回写状态
+// 3. 如果执行失败，调用 Queue.Fail
+// 4. 如果执行成功，调用 Queue.Complete
 func (w *WorkerPool) executeTask(log *zap.Logger, task *model.TaskRun) error {
 	if task == nil {
 		return nil
@@ -260,17 +339,47 @@ func (w *WorkerPool) executeTask(log *zap.Logger, task *model.TaskRun) error {
 		zap.String("job_name", jobName),
 	)
 
-	execCtx := w.ctx
-	var cancel context.CancelFunc
+	w.trackInFlight(task)
+	defer w.untrackInFlight(task.ID)
+
+	execCtx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
 	if w.cfg.RunTimeout > 0 {
-		execCtx, cancel = context.WithTimeout(w.ctx, w.cfg.RunTimeout)
+		var timeoutCancel context.CancelFunc
+		execCtx, timeoutCancel = context.WithTimeout(execCtx, w.cfg.RunTimeout)
+		defer timeoutCancel()
 	}
-	if cancel != nil {
-		defer cancel()
+
+	// 续期执行租约，租约丢失时取消执行
+	var leaseLost atomic.Bool
+	leaseDone := make(chan struct{})
+	if queue, ok := w.cfg.Queue.(LeaseTaskQueue); ok {
+		go func() {
+			defer close(leaseDone)
+			w.keepLease(execCtx, queue, task.ID, &leaseLost, cancel, taskLog)
+		}()
+	} else {
+		close(leaseDone)
 	}
 
 	// 执行任务
 	stats, err := w.executor.Run(execCtx, task)
+	cancel()
+	<-leaseDone
+
+	if queue, ok := w.cfg.Queue.(LeaseTaskQueue); ok && !leaseLost.Load() {
+		// 回写前确认租约仍有效，避免覆盖已被取消或由其他 Worker 接管的执行
+		renewCtx, renewCancel := context.WithTimeout(context.Background(), w.cfg.ClaimTimeout)
+		if errors.Is(queue.RenewLease(renewCtx, task.ID, w.cfg.WorkerID, w.cfg.LeaseDuration), syncqueue.ErrLeaseLost) {
+			leaseLost.Store(true)
+		}
+		renewCancel()
+	}
+	if leaseLost.Load() {
+		// 任务已被取消或由其他 Worker 回收，状态以数据库为准
+		taskLog.Warn("任务租约丢失，停止执行（任务已取消或被回收）", zap.Error(err))
+		return nil
+	}
 
 	// 回写队列状态使用独立的 context，避免被执行超时影响
 	// 这确保即使任务执行超时，我们仍能成功更新队列状态
@@ -322,6 +431,164 @@ func (w *WorkerPool) executeTask(log *zap.Logger, task *model.TaskRun) error {
 	return nil
 }
 
+// keepLease 按 HeartbeatInterval 续期执行租约，直到 ctx 结束或租约丢失
+//
+// 租约丢失时设置 lost 并调用 cancel 停止执行；续期暂时失败（如数据库繁忙）时仅记录日志，
+// 租约到期前仍有机会续期成功。
+func (w *WorkerPool) keepLease(ctx context.Context, queue LeaseTaskQueue, taskID uint, lost *atomic.Bool, cancel context.CancelFunc, log *zap.Logger) {
+	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, renewCancel := context.WithTimeout(ctx, w.cfg.ClaimTimeout)
+		err := queue.RenewLease(renewCtx, taskID, w.cfg.WorkerID, w.cfg.LeaseDuration)
+		renewCancel()
+		if errors.Is(err, syncqueue.ErrLeaseLost) {
+			lost.Store(true)
+			cancel()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Warn("续期任务租约失败", zap.Error(err))
+		}
+	}
+}
+
+// maintainLoop 定期写入实例心跳并回收过期任务
+func (w *WorkerPool) maintainLoop(queue LeaseTaskQueue) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(w.ctx, w.cfg.ClaimTimeout)
+		if err := queue.RegisterWorker(ctx, w.workerNode(time.Time{})); err != nil && w.ctx.Err() == nil {
+			w.log.Warn("写入 Worker 心跳失败", zap.Error(err))
+		}
+		cancel()
+		w.recoverExpired(queue, "")
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverExpired 回收租约过期的任务，最终失败的任务回写 Job 状态为 error
+func (w *WorkerPool) recoverExpired(queue LeaseTaskQueue, ownerID string) {
+	ctx, cancel := context.WithTimeout(w.ctx, w.cfg.ClaimTimeout)
+	defer cancel()
+
+	tasks, err := queue.RecoverExpired(ctx, ownerID)
+	if err != nil {
+		if w.ctx.Err() == nil {
+			w.log.Warn("回收过期任务失败", zap.Error(err))
+		}
+		return
+	}
+	for _, task := range tasks {
+		w.log.Warn("回收租约过期的任务",
+			zap.Uint("task_id", task.ID),
+			zap.Uint("job_id", task.JobID),
+			zap.String("job_name", extractJobName(task.Payload)),
+			zap.String("previous_worker_id", task.WorkerID),
+			zap.String("status", task.Status))
+		if task.Status != string(syncqueue.TaskFailed) {
+			continue
+		}
+		if err := w.cfg.Jobs.UpdateStatus(ctx, task.JobID, "error"); err != nil {
+			w.log.Warn("update job status to error failed", zap.Uint("job_id", task.JobID), zap.Error(err))
+		}
+	}
+}
+
+// workerNode 构造实例注册信息；stoppedAt 非零表示实例已停止
+func (w *WorkerPool) workerNode(stoppedAt time.Time) model.WorkerNode {
+	now := time.Now()
+	status := w.Status()
+	node := model.WorkerNode{
+		ID:          w.cfg.WorkerID,
+		Group:       w.cfg.Group,
+		Hostname:    w.hostname,
+		Concurrency: w.cfg.Concurrency,
+		Reserved:    w.cfg.ReservedHighSlots,
+		InFlight:    status.InFlight,
+		StartedAt:   w.startedAt,
+		HeartbeatAt: now,
+		ExpiresAt:   now.Add(w.cfg.LeaseDuration),
+	}
+	if !stoppedAt.IsZero() {
+		node.StoppedAt = &stoppedAt
+	}
+	return node
+}
+
+// PreviewLayout 使用 Worker 池的驱动工厂预览任务的输出布局（见 PreviewLayout）
+func (w *WorkerPool) PreviewLayout(ctx context.Context, job model.Job, server model.DataServer, mappings map[string]string, limit int) ([]LayoutPreviewItem, bool, error) {
+	return PreviewLayout(ctx, w.executor.cfg.DriverFactory, job, server, mappings, limit)
+}
+
+// Status 返回 Worker 池运行状态快照（用于健康检查）
+func (w *WorkerPool) Status() PoolStatus {
+	if w == nil {
+		return PoolStatus{}
+	}
+	status := PoolStatus{
+		WorkerID:    w.cfg.WorkerID,
+		Group:       w.cfg.Group,
+		Running:     w.running.Load(),
+		Concurrency: w.cfg.Concurrency,
+		Reserved:    w.cfg.ReservedHighSlots,
+		Alive:       int(w.alive.Load()),
+	}
+
+	w.mu.Lock()
+	status.Tasks = make([]InFlightTask, 0, len(w.inFlight))
+	for _, task := range w.inFlight {
+		status.Tasks = append(status.Tasks, task)
+	}
+	w.mu.Unlock()
+
+	sort.Slice(status.Tasks, func(i, j int) bool {
+		return status.Tasks[i].TaskID < status.Tasks[j].TaskID
+	})
+	status.InFlight = len(status.Tasks)
+	return status
+}
+
+// trackInFlight 记录开始执行的任务
+func (w *WorkerPool) trackInFlight(task *model.TaskRun) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inFlight == nil {
+		w.inFlight = make(map[uint]InFlightTask)
+	}
+	startedAt := task.StartedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	w.inFlight[task.ID] = InFlightTask{
+		TaskID:    task.ID,
+		JobID:     task.JobID,
+		JobName:   extractJobName(task.Payload),
+		StartedAt: startedAt,
+	}
+}
+
+// untrackInFlight 移除执行结束的任务
+func (w *WorkerPool) untrackInFlight(taskID uint) {
+	w.mu.Lock()
+	delete(w.inFlight, taskID)
+	w.mu.Unlock()
+}
+
 // sleepWithContext 支持取消的休眠
 //
 // 在休眠期间如果 context 取消，会立即返回。
@@ -3,12 +3,22 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/strmsync/strmsync/internal/domain/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	appsync "github.com/strmsync/strmsync/internal/app/sync"
 	"github.com/strmsync/strmsync/internal/engine"
+	_ "github.com/strmsync/strmsync/internal/infra/filesystem/local"
 	"github.com/strmsync/strmsync/internal/queue"
+	"github.com/strmsync/strmsync/internal/strmwriter"
+	"go.uber.org/zap"
 )
 
 // =============================================================
@@ -107,6 +117,7 @@ func TestProgressFromStats_Normal(t *testing.T) {
 		TotalFiles:     100,
 		ProcessedFiles: 75,
 		FailedFiles:    5,
+		MovedFiles:     3,
 	}
 
 	meta := metadataStats{
@@ -127,6 +138,9 @@ func TestProgressFromStats_Normal(t *testing.T) {
 	if p.Progress != 75 {
 		t.Errorf("Progress: expected 75, got %d", p.Progress)
 	}
+	if p.MovedFiles != 3 {
+		t.Errorf("MovedFiles: expected 3, got %d", p.MovedFiles)
+	}
 	if p.MetaTotalFiles != 10 {
 		t.Errorf("MetaTotalFiles: expected 10, got %d", p.MetaTotalFiles)
 	}
@@ -412,6 +426,30 @@ func TestBuildFilesystemConfig_InvalidSTRMMode(t *testing.T) {
 	}
 }
 
+func TestBuildFilesystemConfig_LinkMode(t *testing.T) {
+	server := model.DataServer{
+		ID:      1,
+		Name:    "cd2-direct",
+		Type:    "clouddrive2",
+		Host:    "localhost",
+		Port:    19798,
+		Options: `{"link_mode":"direct"}`,
+	}
+
+	cfg, err := buildFilesystemConfig(server)
+	if err != nil {
+		t.Fatalf("build config: %v", err)
+	}
+	if cfg.LinkMode.String() != "direct" {
+		t.Errorf("LinkMode: expected direct, got %s", cfg.LinkMode)
+	}
+
+	server.Options = `{"link_mode":"cdn"}`
+	if _, err := buildFilesystemConfig(server); err == nil {
+		t.Fatal("expected error for invalid link_mode")
+	}
+}
+
 // =============================================================
 // NewWorker 构造测试
 // =============================================================
@@ -440,6 +478,216 @@ func TestNewWorker_NilJobs(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_ReservesHighPriorityLane(t *testing.T) {
+	queue := &mockPriorityQueue{}
+	pool, err := NewWorker(WorkerConfig{
+		Queue:        queue,
+		Jobs:         &mockJobRepo{},
+		DataServers:  &mockDataServerRepo{},
+		TaskRuns:     &mockTaskRunRepo{},
+		Concurrency:  2,
+		PollInterval: 5 * time.Millisecond,
+		Logger:       zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("new worker: %v", err)
+	}
+	if got := pool.Status().Reserved; got != 1 {
+		t.Fatalf("expected 1 reserved slot, got %d", got)
+	}
+
+	if err := pool.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if queue.any.Load() == 0 || queue.highOnly.Load() == 0 {
+		t.Fatalf("expected both lanes to claim, any=%d high=%d", queue.any.Load(), queue.highOnly.Load())
+	}
+
+	// 单并发时不预留，普通任务不会被饿死
+	single, err := NewWorker(WorkerConfig{
+		Queue:       queue,
+		Jobs:        &mockJobRepo{},
+		DataServers: &mockDataServerRepo{},
+		TaskRuns:    &mockTaskRunRepo{},
+		Concurrency: 1,
+	})
+	if err != nil {
+		t.Fatalf("new worker: %v", err)
+	}
+	if got := single.Status().Reserved; got != 0 {
+		t.Fatalf("expected no reserved slot for single worker, got %d", got)
+	}
+}
+
+// =============================================================
+// metaHashChecker 测试
+// =============================================================
+
+func TestMetaHashChecker_CopyModeDetectsSameSizeChange(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	writeFile := func(path, content string) os.FileInfo {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+
+	srcInfo := writeFile(filepath.Join(srcDir, "poster.jpg"), "new-poster")
+	targetPath := filepath.Join(dstDir, "poster.jpg")
+	targetInfo := writeFile(targetPath, "old-poster") // 大小与修改时间都相同
+
+	job := model.Job{ID: 7, SourcePath: srcDir}
+	client, err := buildLocalMetadataClient(job, zap.NewNop())
+	if err != nil {
+		t.Fatalf("build client: %v", err)
+	}
+	repo := &mockMetaHashRepo{records: map[string]model.MetaFileHash{}}
+	executor := &Executor{cfg: ExecutorConfig{MetaHashes: repo}, log: zap.NewNop()}
+	checker, err := executor.newMetaHashChecker(context.Background(), job, jobOptions{MetaHashMode: "full"}, nil, client, true, zap.NewNop())
+	if err != nil || checker == nil {
+		t.Fatalf("new checker: %v", err)
+	}
+
+	entry := syncengine.RemoteEntry{Path: "/poster.jpg", Name: "poster.jpg", Size: srcInfo.Size(), ModTime: modTime}
+	if !metaFileSame(targetInfo, entry, 2*time.Second) {
+		t.Fatal("precondition: size/mtime comparison should report same")
+	}
+	if checker.evaluate(context.Background(), entry, targetPath, targetInfo, true) {
+		t.Fatal("content differs, expected changed")
+	}
+
+	// 模拟复制成功后提交记录
+	targetInfo = writeFile(targetPath, "new-poster")
+	(&metaHashEventSink{checker: checker}).OnMetaEvent(context.Background(), appsync.MetaEvent{
+		Op: "update", Status: "success", TargetPath: targetPath,
+	})
+	record, ok := repo.records[targetPath]
+	if !ok || record.JobID != 7 || record.SourceHash == "" {
+		t.Fatalf("expected hash record, got %+v", record)
+	}
+
+	// 新一轮同步：记录命中，视为未变化
+	checker.records = repo.records
+	if !checker.evaluate(context.Background(), entry, targetPath, targetInfo, true) {
+		t.Fatal("expected unchanged after commit")
+	}
+
+	// 源文件重新编码（大小不变）
+	writeFile(filepath.Join(srcDir, "poster.jpg"), "NEW-poster")
+	if checker.evaluate(context.Background(), entry, targetPath, targetInfo, true) {
+		t.Fatal("expected changed after source re-encode")
+	}
+}
+
+func TestMetaHashChecker_DownloadModeUsesRemoteHash(t *testing.T) {
+	dstDir := t.TempDir()
+	targetPath := filepath.Join(dstDir, "movie.nfo")
+	if err := os.WriteFile(targetPath, []byte("<movie/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(targetPath)
+
+	repo := &mockMetaHashRepo{records: map[string]model.MetaFileHash{
+		targetPath: {JobID: 1, TargetPath: targetPath, SourceHash: "sha1:aaa", Size: info.Size(), ModTime: info.ModTime()},
+	}}
+	executor := &Executor{cfg: ExecutorConfig{MetaHashes: repo}, log: zap.NewNop()}
+	checker, err := executor.newMetaHashChecker(context.Background(), model.Job{ID: 1}, jobOptions{MetaHashMode: "fast"}, nil, nil, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new checker: %v", err)
+	}
+
+	entry := syncengine.RemoteEntry{Path: "/movie.nfo", Hash: "sha1:aaa"}
+	if !checker.evaluate(context.Background(), entry, targetPath, info, false) {
+		t.Error("same remote hash should be unchanged")
+	}
+	entry.Hash = "sha1:bbb"
+	if checker.evaluate(context.Background(), entry, targetPath, info, true) {
+		t.Error("different remote hash should be changed")
+	}
+	// 远端未提供哈希时回退到大小/时间比较
+	entry.Hash = ""
+	if !checker.evaluate(context.Background(), entry, targetPath, info, true) {
+		t.Error("expected fallback to size/mtime result")
+	}
+
+	if _, err := resolveMetaHashMode(jobOptions{MetaHashMode: "sha512"}); err == nil {
+		t.Error("expected error for unsupported meta_hash_mode")
+	}
+}
+
+// =============================================================
+// 执行记录日志捕获测试
+// =============================================================
+
+func TestExecutorRun_CapturesRunLog(t *testing.T) {
+	root := t.TempDir()
+	executor, err := NewExecutor(ExecutorConfig{
+		JobRepo:     &mockJobRepo{},
+		DataServers: &mockDataServerRepo{},
+		TaskRuns:    &mockTaskRunRepo{},
+		Logger:      zap.NewNop(),
+		RunLogDir:   root,
+	})
+	if err != nil {
+		t.Fatalf("new executor: %v", err)
+	}
+
+	if _, err := executor.Run(context.Background(), &model.TaskRun{ID: 9, JobID: 1}); err == nil {
+		t.Fatal("expected run error for missing job")
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "9", "run.log"))
+	if err != nil {
+		t.Fatalf("read run log: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, `"task_run_id":9`) || !strings.Contains(content, "同步任务执行失败") {
+		t.Fatalf("unexpected run log: %s", content)
+	}
+}
+
+// =============================================================
+// API 错误统计测试
+// =============================================================
+
+func TestAPIErrorDriver_CountsRemoteFailures(t *testing.T) {
+	var counter atomic.Int64
+	driver := &apiErrorDriver{Driver: &failingListDriver{}, errors: &counter}
+	ctx := context.Background()
+
+	_, _ = driver.List(ctx, "/broken", syncengine.ListOptions{})
+	_, _ = driver.List(ctx, "/missing", syncengine.ListOptions{})
+	_, _ = driver.List(ctx, "/ok", syncengine.ListOptions{})
+
+	// 回调自身返回的错误不计入
+	stop := errors.New("stop")
+	_ = driver.ListStream(ctx, "/ok", syncengine.ListOptions{}, func(syncengine.RemoteEntry) error { return stop })
+	_ = driver.ListStream(ctx, "/broken", syncengine.ListOptions{}, func(syncengine.RemoteEntry) error { return nil })
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _ = driver.List(cancelled, "/broken", syncengine.ListOptions{})
+
+	if got := counter.Load(); got != 2 {
+		t.Fatalf("expected 2 api errors, got %d", got)
+	}
+}
+
 // =============================================================
 // Mock 实现（仅用于构造测试）
 // =============================================================
@@ -474,6 +722,10 @@ func (r *mockTaskRunRepo) UpdateProgress(ctx context.Context, taskID uint, progr
 	return nil
 }
 
+func (r *mockTaskRunRepo) UpdateCheckpoint(ctx context.Context, taskID uint, checkpoint string) error {
+	return nil
+}
+
 type mockTaskQueue struct{}
 
 func (q *mockTaskQueue) ClaimNext(ctx context.Context, workerID string) (*model.TaskRun, error) {
@@ -487,3 +739,455 @@ func (q *mockTaskQueue) Complete(ctx context.Context, taskID uint) error {
 func (q *mockTaskQueue) Fail(ctx context.Context, taskID uint, err error) error {
 	return nil
 }
+
+// mockPriorityQueue 记录普通领取与高优先级领取的次数
+type mockPriorityQueue struct {
+	mockTaskQueue
+	any      atomic.Int64
+	highOnly atomic.Int64
+}
+
+func (q *mockPriorityQueue) ClaimNext(ctx context.Context, workerID string) (*model.TaskRun, error) {
+	q.any.Add(1)
+	return nil, nil
+}
+
+func (q *mockPriorityQueue) ClaimNextWithOptions(ctx context.Context, workerID string, opts syncqueue.ClaimOptions) (*model.TaskRun, error) {
+	if opts.MaxPriority == syncqueue.TaskPriorityHigh {
+		q.highOnly.Add(1)
+	} else {
+		q.any.Add(1)
+	}
+	return nil, nil
+}
+
+// mockLeaseQueue 续期指定次数后报告租约丢失，并记录回收与心跳调用
+type mockLeaseQueue struct {
+	mockTaskQueue
+	renewals  atomic.Int64
+	lostAfter int64
+	owners    []string
+	nodes     []model.WorkerNode
+	mu        sync.Mutex
+}
+
+func (q *mockLeaseQueue) RenewLease(ctx context.Context, taskID uint, workerID string, lease time.Duration) error {
+	if q.renewals.Add(1) > q.lostAfter {
+		return syncqueue.ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *mockLeaseQueue) RecoverExpired(ctx context.Context, ownerID string) ([]model.TaskRun, error) {
+	q.mu.Lock()
+	q.owners = append(q.owners, ownerID)
+	q.mu.Unlock()
+	return nil, nil
+}
+
+func (q *mockLeaseQueue) RegisterWorker(ctx context.Context, node model.WorkerNode) error {
+	q.mu.Lock()
+	q.nodes = append(q.nodes, node)
+	q.mu.Unlock()
+	return nil
+}
+
+type mockMetaHashRepo struct {
+	records map[string]model.MetaFileHash
+}
+
+func (r *mockMetaHashRepo) ListByJob(ctx context.Context, jobID uint) (map[string]model.MetaFileHash, error) {
+	result := make(map[string]model.MetaFileHash, len(r.records))
+	for k, v := range r.records {
+		result[k] = v
+	}
+	return result, nil
+}
+
+func (r *mockMetaHashRepo) Upsert(ctx context.Context, record *model.MetaFileHash) error {
+	r.records[record.TargetPath] = *record
+	return nil
+}
+
+// failingListDriver 按路径返回固定结果的驱动
+type failingListDriver struct {
+	syncengine.Driver
+}
+
+func (d *failingListDriver) result(path string) error {
+	switch path {
+	case "/broken":
+		return errors.New("502 bad gateway")
+	case "/missing":
+		return fmt.Errorf("stat %s: %w", path, os.ErrNotExist)
+	default:
+		return nil
+	}
+}
+
+func (d *failingListDriver) List(ctx context.Context, path string, opt syncengine.ListOptions) ([]syncengine.RemoteEntry, error) {
+	return nil, d.result(path)
+}
+
+func (d *failingListDriver) ListStream(ctx context.Context, path string, opt syncengine.ListOptions, fn func(syncengine.RemoteEntry) error) error {
+	if err := d.result(path); err != nil {
+		return err
+	}
+	return fn(syncengine.RemoteEntry{Path: path + "/a.mkv"})
+}
+
+func TestWorkerPool_KeepLeaseCancelsOnLeaseLost(t *testing.T) {
+	queue := &mockLeaseQueue{lostAfter: 2}
+	pool, err := NewWorker(WorkerConfig{
+		Queue:             queue,
+		Jobs:              &mockJobRepo{},
+		DataServers:       &mockDataServerRepo{},
+		TaskRuns:          &mockTaskRunRepo{},
+		WorkerID:          "worker-a",
+		Group:             " nas ",
+		PollInterval:      time.Hour,
+		HeartbeatInterval: time.Millisecond,
+		Logger:            zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("new worker: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lost atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.keepLease(ctx, queue, 1, &lost, cancel, zap.NewNop())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("keepLease did not stop after lease lost")
+	}
+	if !lost.Load() || ctx.Err() == nil {
+		t.Fatalf("expected lease lost and execution cancelled, lost=%v ctx=%v", lost.Load(), ctx.Err())
+	}
+	if got := queue.renewals.Load(); got != 3 {
+		t.Fatalf("expected 3 renewals, got %d", got)
+	}
+
+	// 启动时回收同一 Worker ID 遗留的任务，停止时注册表标记为已停止
+	if err := pool.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.owners) == 0 || queue.owners[0] != "worker-a" {
+		t.Fatalf("expected own tasks recovered first, got %v", queue.owners)
+	}
+	last := queue.nodes[len(queue.nodes)-1]
+	if last.ID != "worker-a" || last.Group != "nas" || last.StoppedAt == nil {
+		t.Fatalf("unexpected final worker node: %+v", last)
+	}
+}
+
+func TestResolveStagingMode(t *testing.T) {
+	cases := []struct {
+		raw     string
+		mode    strmwriter.SwapMode
+		enabled bool
+		wantErr bool
+	}{
+		{raw: "", enabled: false},
+		{raw: "off", enabled: false},
+		{raw: "Rename", mode: strmwriter.SwapModeRename, enabled: true},
+		{raw: "symlink", mode: strmwriter.SwapModeSymlink, enabled: true},
+		{raw: "copy", wantErr: true},
+	}
+	for _, tc := range cases {
+		mode, enabled, err := resolveStagingMode(jobOptions{StagingMode: tc.raw})
+		if (err != nil) != tc.wantErr || mode != tc.mode || enabled != tc.enabled {
+			t.Fatalf("%q: got mode=%q enabled=%v err=%v", tc.raw, mode, enabled, err)
+		}
+	}
+}
+
+func TestMetaHashChecker_RecordKeyMapsStagingRoot(t *testing.T) {
+	checker := &metaHashChecker{}
+	checker.recordRoot("/media/.Movies.staging-task-1", "/media/Movies")
+	if got := checker.recordKey("/media/.Movies.staging-task-1/A/poster.jpg"); got != filepath.Join("/media/Movies", "A", "poster.jpg") {
+		t.Fatalf("unexpected record key %q", got)
+	}
+
+	same := &metaHashChecker{}
+	same.recordRoot("/media/Movies", "/media/Movies")
+	if got := same.recordKey("/media/Movies/A/poster.jpg"); got != "/media/Movies/A/poster.jpg" {
+		t.Fatalf("unexpected record key %q", got)
+	}
+}
+
+func TestDefaultWriterFactory_BuildsConfiguredWriter(t *testing.T) {
+	factory := DefaultWriterFactory{}
+	ctx := context.Background()
+
+	local, err := factory.Build(ctx, model.Job{TargetPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("build local writer: %v", err)
+	}
+	if _, ok := local.(*strmwriter.LocalWriter); !ok {
+		t.Fatalf("expected local writer, got %T", local)
+	}
+
+	job := model.Job{
+		TargetPath: "/strm/movies",
+		Options:    `{"writer":{"type":"WebDAV","url":"http://emby-host:8080/dav"}}`,
+	}
+	remote, err := factory.Build(ctx, job)
+	if err != nil {
+		t.Fatalf("build webdav writer: %v", err)
+	}
+	if _, ok := remote.(*strmwriter.WebDAVWriter); !ok {
+		t.Fatalf("expected webdav writer, got %T", remote)
+	}
+	if !JobUsesRemoteWriter(job) {
+		t.Fatalf("expected job to use remote writer")
+	}
+
+	// 上传到 OpenList 需要数据服务器仓储
+	if _, err := factory.Build(ctx, model.Job{TargetPath: "/strm", Options: `{"writer":{"type":"openlist"}}`}); err == nil {
+		t.Fatalf("expected openlist writer without repository to fail")
+	}
+	if _, err := factory.Build(ctx, model.Job{TargetPath: "/strm", Options: `{"writer":{"type":"s3"}}`}); err == nil {
+		t.Fatalf("expected unsupported writer type to fail")
+	}
+}
+
+func TestMetaHashChecker_ArtworkTracksSourceFingerprint(t *testing.T) {
+	dstDir := t.TempDir()
+	targetPath := filepath.Join(dstDir, "fanart.png")
+	if err := os.WriteFile(targetPath, []byte("processed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(targetPath)
+
+	artwork, err := resolveArtwork(jobOptions{Artwork: artworkOptions{Enabled: true, MaxWidth: 1920}})
+	if err != nil || artwork == nil {
+		t.Fatalf("resolve artwork: %v", err)
+	}
+	repo := &mockMetaHashRepo{records: map[string]model.MetaFileHash{}}
+	executor := &Executor{cfg: ExecutorConfig{MetaHashes: repo}, log: zap.NewNop()}
+	checker, err := executor.newMetaHashChecker(context.Background(), model.Job{ID: 3}, jobOptions{}, artwork, nil, false, zap.NewNop())
+	if err != nil || checker == nil {
+		t.Fatalf("artwork should enable checker in mtime mode: %v", err)
+	}
+
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := syncengine.RemoteEntry{Path: "/A/fanart.png", Size: 4 << 20, ModTime: modTime}
+	// 没有记录：即使大小/时间一致也需要处理一次
+	if checker.evaluate(context.Background(), entry, targetPath, info, true) {
+		t.Fatal("expected unprocessed artwork to be changed")
+	}
+	(&metaHashEventSink{checker: checker}).OnMetaEvent(context.Background(), appsync.MetaEvent{
+		Op: "copy", Status: "success", TargetPath: targetPath,
+	})
+	checker.records = repo.records
+
+	// 处理后的目标文件大小与源不同，按源指纹判断未变化
+	if !checker.evaluate(context.Background(), entry, targetPath, info, false) {
+		t.Fatal("expected processed artwork to be unchanged")
+	}
+	entry.ModTime = modTime.Add(time.Hour)
+	if checker.evaluate(context.Background(), entry, targetPath, info, false) {
+		t.Fatal("expected source change to trigger reprocessing")
+	}
+
+	// 非图片文件仍按大小/时间比较
+	nfo := syncengine.RemoteEntry{Path: "/A/movie.nfo"}
+	if checker.evaluate(context.Background(), nfo, targetPath, info, false) {
+		t.Fatal("expected size/mtime fallback for non-artwork")
+	}
+
+	if _, err := resolveArtwork(jobOptions{Artwork: artworkOptions{Enabled: true, Quality: 101}}); err == nil {
+		t.Error("expected error for invalid quality")
+	}
+}
+
+func TestPlanNFOItems_SkipsVideosWithSourceNFO(t *testing.T) {
+	entries := []syncengine.RemoteEntry{
+		{Path: "/movies/A (2019)/A.2019.mkv", Name: "A.2019.mkv", Size: 100},
+		{Path: "/movies/A (2019)/movie.nfo", Name: "movie.nfo"},
+		{Path: "/movies/B/B.mkv", Name: "B.mkv", Size: 100},
+		{Path: "/movies/B/B.nfo", Name: "B.nfo"},
+		{Path: "/tv/Show/S01E01.mkv", Name: "S01E01.mkv", Size: 100},
+		{Path: "/tv/Show/S01E02.mkv", Name: "S01E02.mkv", Size: 1},
+		{Path: "/tv/Show/tvshow.nfo", Name: "tvshow.nfo"},
+	}
+	mediaExts := []string{".mkv"}
+	index := syncengine.NewMediaIndex(entries, mediaExts)
+
+	videos := filterVideos(entries, "/", nil, mediaExts, 10)
+	targets := newMetaTargetResolver("/strm", nil, index, videos)
+	items := planNFOItems(entries, videos, "/", nil, index, targets)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 nfo item, got %+v", items)
+	}
+	want := filepath.Join("/strm", "tv", "Show", "S01E01.nfo")
+	if items[0].SourcePath != "/tv/Show/S01E01.mkv" || items[0].TargetPath != want || items[0].MediaItem != "/tv/Show/S01E01" {
+		t.Fatalf("unexpected nfo item %+v", items[0])
+	}
+}
+
+func TestMetaTargetResolver_FollowsMediaLayout(t *testing.T) {
+	entries := []syncengine.RemoteEntry{
+		{Path: "/dl/Inception.2010.1080p.mkv", Name: "Inception.2010.1080p.mkv", Size: 100},
+		{Path: "/tv/Show/Season 1/Show.S01E01.mkv", Name: "Show.S01E01.mkv", Size: 100},
+		{Path: "/tv/Show/Season 1/Show.S01E02.mkv", Name: "Show.S01E02.mkv", Size: 100},
+		{Path: "/m/Heat (1995)/CD1/heat.mkv", Name: "heat.mkv", Size: 100},
+		{Path: "/m/Heat (1995)/CD2/heat.mkv", Name: "heat.mkv", Size: 100},
+	}
+	mediaExts := []string{".mkv"}
+	index := syncengine.NewMediaIndex(entries, mediaExts)
+	videos := filterVideos(entries, "/", nil, mediaExts, 0)
+	layout := syncengine.NewMediaLayout(syncengine.MediaLayoutOptions{Organize: true, Parts: true}, nil)
+	targets := newMetaTargetResolver("/strm", layout, index, videos)
+
+	cases := map[string]string{
+		"/dl/Inception.2010.1080p.zh.srt":         "Movies/Inception (2010)/Inception (2010).zh.srt",
+		"/dl/poster.jpg":                          "Movies/Inception (2010)/poster.jpg",
+		"/dl/extrafanart/1.jpg":                   "Movies/Inception (2010)/extrafanart/1.jpg",
+		"/tv/Show/Season 1/Show.S01E02-thumb.jpg": "Shows/Show/Season 01/Show - S01E02-thumb.jpg",
+		"/tv/Show/Season 1/folder.jpg":            "Shows/Show/Season 01/folder.jpg",
+		"/tv/Show/tvshow.nfo":                     "Shows/Show/tvshow.nfo",
+		"/misc/readme.txt":                        "misc/readme.txt",
+		"/m/Heat (1995)/CD2/heat.zh.srt":          "Movies/Heat (1995)/Heat (1995)-part2.zh.srt",
+		"/m/Heat (1995)/poster.jpg":               "Movies/Heat (1995)/poster.jpg",
+	}
+	for source, want := range cases {
+		got, err := targets.targetPath(source)
+		if err != nil {
+			t.Fatalf("targetPath(%s): %v", source, err)
+		}
+		if want = filepath.Join("/strm", filepath.FromSlash(want)); got != want {
+			t.Errorf("targetPath(%s) = %s, want %s", source, got, want)
+		}
+	}
+}
+
+func TestResolveLayout_Validates(t *testing.T) {
+	if enabled, err := resolveLayout(jobOptions{}); err != nil || enabled {
+		t.Fatalf("default layout = %v, %v", enabled, err)
+	}
+	if enabled, err := resolveLayout(jobOptions{Layout: layoutOptions{Mode: "media"}}); err != nil || !enabled {
+		t.Fatalf("media layout = %v, %v", enabled, err)
+	}
+	if enabled, err := resolveLayout(jobOptions{Layout: layoutOptions{Parts: true}}); err != nil || !enabled {
+		t.Fatalf("mirror layout with parts = %v, %v", enabled, err)
+	}
+	for _, opts := range []layoutOptions{
+		{Mode: "flat"},
+		{Mode: "media", MoviesDir: "../Movies"},
+		{Mode: "media", MoviesDir: "Library", ShowsDir: "library"},
+	} {
+		if _, err := resolveLayout(jobOptions{Layout: opts}); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+// streamDriverFactory 构建按固定顺序投递条目的驱动，并记录投递数量
+type streamDriverFactory struct {
+	entries   []syncengine.RemoteEntry
+	delivered int
+}
+
+func (f *streamDriverFactory) Build(ctx context.Context, server model.DataServer) (syncengine.Driver, error) {
+	return &streamDriver{factory: f}, nil
+}
+
+type streamDriver struct {
+	syncengine.Driver
+	factory *streamDriverFactory
+}
+
+func (d *streamDriver) ListStream(ctx context.Context, path string, opt syncengine.ListOptions, fn func(syncengine.RemoteEntry) error) error {
+	for _, entry := range d.factory.entries {
+		d.factory.delivered++
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPreviewLayout_StopsAtLimit(t *testing.T) {
+	factory := &streamDriverFactory{entries: []syncengine.RemoteEntry{
+		{Path: "/media/A.2001.mkv", Name: "A.2001.mkv", Size: 100},
+		{Path: "/media/A.2001.nfo", Name: "A.2001.nfo", Size: 1},
+		{Path: "/media/B.2002.mkv", Name: "B.2002.mkv", Size: 100},
+		{Path: "/media/C.2003.mkv", Name: "C.2003.mkv", Size: 100},
+		{Path: "/media/D.2004.mkv", Name: "D.2004.mkv", Size: 100},
+		{Path: "/media/E.2005.mkv", Name: "E.2005.mkv", Size: 100},
+	}}
+	job := model.Job{SourcePath: "/media", Options: `{"layout":{"mode":"media"}}`}
+	server := model.DataServer{Type: "local"}
+	mappings := map[string]string{"/media/B.2002.mkv": "Movies/B (2002)/B (2002)"}
+
+	items, more, err := PreviewLayout(context.Background(), factory, job, server, mappings, 2)
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if !more || len(items) != 2 {
+		t.Fatalf("expected 2 items with more, got %d items more=%v", len(items), more)
+	}
+	// 收集到 limit 条后的第一个视频即停止遍历
+	if factory.delivered != 4 {
+		t.Fatalf("expected walk to stop after 4 entries, delivered %d", factory.delivered)
+	}
+	if items[0].OutputPath != "Movies/A (2001)/A (2001).strm" || items[1].Previous != "Movies/B (2002)/B (2002).strm" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+
+	factory.delivered = 0
+	items, more, err = PreviewLayout(context.Background(), factory, job, server, mappings, 0)
+	if err != nil || more || len(items) != 5 || factory.delivered != len(factory.entries) {
+		t.Fatalf("unlimited preview: %d items more=%v delivered=%d err=%v", len(items), more, factory.delivered, err)
+	}
+}
+
+// finishingDriver 记录 FinishRun 调用次数的驱动
+type finishingDriver struct {
+	syncengine.Driver
+	finished int
+}
+
+func (d *finishingDriver) FinishRun() { d.finished++ }
+
+type finishingDriverFactory struct {
+	built []*finishingDriver
+}
+
+func (f *finishingDriverFactory) Build(ctx context.Context, server model.DataServer) (syncengine.Driver, error) {
+	driver := &finishingDriver{}
+	f.built = append(f.built, driver)
+	return driver, nil
+}
+
+func TestRunDriverFactory_FinishesBuiltDrivers(t *testing.T) {
+	base := &finishingDriverFactory{}
+	drivers := &runDriverFactory{next: base}
+	factory := &apiErrorDriverFactory{next: drivers, errors: &atomic.Int64{}}
+	for i := 0; i < 2; i++ {
+		if _, err := factory.Build(context.Background(), model.DataServer{}); err != nil {
+			t.Fatalf("build: %v", err)
+		}
+	}
+
+	drivers.finish()
+	drivers.finish()
+	for i, driver := range base.built {
+		if driver.finished != 1 {
+			t.Fatalf("driver %d finished %d times, want 1", i, driver.finished)
+		}
+	}
+}